@@ -8,6 +8,7 @@ import (
 	"github.com/chenxull/goGridhub/gridhub/src/common/config/store"
 	"github.com/chenxull/goGridhub/gridhub/src/common/config/store/driver"
 	"github.com/chenxull/goGridhub/gridhub/src/common/http/modifier/auth"
+	"github.com/chenxull/goGridhub/gridhub/src/common/secret"
 	"github.com/chenxull/goGridhub/gridhub/src/common/utils"
 	"log"
 	"os"
@@ -40,6 +41,14 @@ func NewRESTCfgManager(configURL, secret string) *CfgManager {
 	return manager
 }
 
+// NewRESTCfgManagerWithSecrets - create REST config manager signing the requests with the current secret of the store
+func NewRESTCfgManagerWithSecrets(configURL string, secrets *secret.Store) *CfgManager {
+	secAuth := auth.NewSecretStoreAuthorizer(secrets)
+	manager := &CfgManager{
+		store: store.NewConfigStore(driver.NewRESTDriver(configURL, secAuth))}
+	return manager
+}
+
 // InMemoryDriver driver for unit testing
 type InMemoryDriver struct {
 	sync.Mutex
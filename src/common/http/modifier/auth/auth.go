@@ -12,15 +12,20 @@ const HeaderPrefix = "Harbor-Secret "
 // Authorizer is a kind of Modifier used to authorize the requests
 type Authorizer modifier.Modifier
 
-// SecretAuthorizer authorizes the requests with the specified secret
+// SecretAuthorizer authorizes the requests with the current secret of the store
 type SecretAuthorizer struct {
-	secret string
+	store *secret.Store
 }
 
-// NewSecretAuthorizer returns an instance of SecretAuthorizer
-func NewSecretAuthorizer(secret string) *SecretAuthorizer {
+// NewSecretAuthorizer returns an instance of SecretAuthorizer with the static secret
+func NewSecretAuthorizer(s string) *SecretAuthorizer {
+	return NewSecretStoreAuthorizer(secret.NewStore(s))
+}
+
+// NewSecretStoreAuthorizer returns an instance of SecretAuthorizer following the rotation of the secrets in the store
+func NewSecretStoreAuthorizer(store *secret.Store) *SecretAuthorizer {
 	return &SecretAuthorizer{
-		secret: secret,
+		store: store,
 	}
 }
 
@@ -29,6 +34,6 @@ func (s *SecretAuthorizer) Modify(req *http.Request) error {
 	if req == nil {
 		return errors.New("the request is null")
 	}
-	err := secret.AddToRequest(req, s.secret)
+	err := secret.AddToRequest(req, s.store.Current())
 	return err
 }
@@ -0,0 +1,95 @@
+package job
+
+import (
+	"context"
+	"github.com/chenxull/goGridhub/gridhub/src/common/job/models"
+	"net/http"
+	"net/url"
+	"strconv"
+)
+
+// AuditLogOptions defines the filters and the page size of listing the audit log, empty filters match all
+type AuditLogOptions struct {
+	Caller string
+	Action string
+	JobID  string
+	// Page size, 0 means the default one
+	PageSize uint
+}
+
+func (o *AuditLogOptions) values() url.Values {
+	values := url.Values{}
+	size := uint(defaultListPageSize)
+	if o != nil {
+		if len(o.Caller) > 0 {
+			values.Set("caller", o.Caller)
+		}
+		if len(o.Action) > 0 {
+			values.Set("action", o.Action)
+		}
+		if len(o.JobID) > 0 {
+			values.Set("job_id", o.JobID)
+		}
+		if o.PageSize > 0 {
+			size = o.PageSize
+		}
+	}
+	values.Set("page_size", strconv.FormatUint(uint64(size), 10))
+
+	return values
+}
+
+// ListAuditLogs lists the audit log of the job actions from the latest entry.
+// The entries appended during the iteration shift the pages, so the same entry may be returned more than once.
+func (c *APIClient) ListAuditLogs(ctx context.Context, opts *AuditLogOptions) *AuditEntryIterator {
+	return &AuditEntryIterator{
+		ctx:   ctx,
+		pager: newPager(opts.values()),
+		fetch: func(ctx context.Context, values url.Values) ([]*models.AuditEntry, http.Header, error) {
+			var page []*models.AuditEntry
+			header, err := c.do(ctx, http.MethodGet, "/audit-logs", values, nil, nil, http.StatusOK, &page)
+			return page, header, err
+		},
+	}
+}
+
+// AuditEntryIterator iterates the audit log page by page, it's used in the same way as StatsIterator
+type AuditEntryIterator struct {
+	ctx     context.Context
+	pager   *pager
+	fetch   func(ctx context.Context, values url.Values) ([]*models.AuditEntry, http.Header, error)
+	items   []*models.AuditEntry
+	current *models.AuditEntry
+	err     error
+}
+
+// Next moves to the next entry, the next page is fetched if the current one is consumed.
+// It returns false once all the pages are consumed or an error occurs.
+func (it *AuditEntryIterator) Next() bool {
+	for len(it.items) == 0 {
+		if it.err != nil || it.pager.done {
+			return false
+		}
+		items, header, err := it.fetch(it.ctx, it.pager.values)
+		if err != nil {
+			it.err = err
+			return false
+		}
+		it.items = items
+		it.pager.turn(header, len(items))
+	}
+
+	it.current, it.items = it.items[0], it.items[1:]
+
+	return true
+}
+
+// AuditEntry returns the current entry
+func (it *AuditEntryIterator) AuditEntry() *models.AuditEntry {
+	return it.current
+}
+
+// Err returns the error stopping the iteration
+func (it *AuditEntryIterator) Err() error {
+	return it.err
+}
@@ -0,0 +1,365 @@
+package job
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	commonhttp "github.com/chenxull/goGridhub/gridhub/src/common/http"
+	"github.com/chenxull/goGridhub/gridhub/src/common/http/modifier"
+	"github.com/chenxull/goGridhub/gridhub/src/common/http/modifier/auth"
+	"github.com/chenxull/goGridhub/gridhub/src/common/job/models"
+	"github.com/chenxull/goGridhub/gridhub/src/common/secret"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+const (
+	apiV2Prefix = "/api/v2"
+
+	idempotencyKeyHeader = "Idempotency-Key"
+	totalCountHeader     = "Total-Count"
+	nextCursorHeader     = "Next-Cursor"
+	retryAfterHeader     = "Retry-After"
+
+	// Page size of the list operations if it's not specified
+	defaultListPageSize = 25
+)
+
+// APIClient is the typed client of the v2 API of job service.
+// It follows the OpenAPI document served at /api/v2/openapi.yaml, the errors returned by
+// job service are in the type of *APIError.
+type APIClient struct {
+	endpoint string
+	client   *commonhttp.Client
+}
+
+// NewAPIClient returns the client of the v2 API, the modifiers authorize the requests
+func NewAPIClient(endpoint string, modifiers ...modifier.Modifier) *APIClient {
+	return &APIClient{
+		endpoint: strings.TrimRight(endpoint, "/"),
+		client:   commonhttp.NewClient(nil, modifiers...),
+	}
+}
+
+// NewAPIClientWithSecrets returns the client of the v2 API signing the requests with the current secret of the store
+func NewAPIClientWithSecrets(endpoint string, secrets *secret.Store) *APIClient {
+	return NewAPIClient(endpoint, auth.NewSecretStoreAuthorizer(secrets))
+}
+
+// ListOptions defines the filters and the page size of the list operations
+type ListOptions struct {
+	// Filters of the jobs, kind is not supported by the executions and the scheduled jobs
+	Kind   string
+	Name   string
+	Status string
+	// Only list the jobs not stopped, supported by the jobs and the executions
+	NonDeadOnly bool
+	// Page size, 0 means the default one
+	PageSize uint
+}
+
+func (o *ListOptions) values() url.Values {
+	values := url.Values{}
+	size := uint(defaultListPageSize)
+	if o != nil {
+		if len(o.Kind) > 0 {
+			values.Set("kind", o.Kind)
+		}
+		if len(o.Name) > 0 {
+			values.Set("name", o.Name)
+		}
+		if len(o.Status) > 0 {
+			values.Set("status", o.Status)
+		}
+		if o.NonDeadOnly {
+			values.Set("non_dead_only", "true")
+		}
+		if o.PageSize > 0 {
+			size = o.PageSize
+		}
+	}
+	values.Set("page_size", strconv.FormatUint(uint64(size), 10))
+
+	return values
+}
+
+// LaunchJob launches the job, the 'IdempotencyKey' of the job data is sent to avoid launching the same job twice.
+// If the job is launched before, the existing job is returned together with the conflict error if job service knows it.
+func (c *APIClient) LaunchJob(ctx context.Context, jd *models.JobData) (*models.JobStats, error) {
+	header := http.Header{}
+	if len(jd.IdempotencyKey) > 0 {
+		header.Set(idempotencyKeyHeader, jd.IdempotencyKey)
+	}
+
+	stats := &models.JobStats{}
+	_, err := c.do(ctx, http.MethodPost, "/jobs", nil, header, &models.JobRequest{Job: jd}, http.StatusAccepted, stats)
+	if err != nil {
+		if e, ok := err.(*APIError); ok && e.StatusCode == http.StatusConflict && e.existing != nil {
+			return e.existing, err
+		}
+		return nil, err
+	}
+
+	return stats, nil
+}
+
+// GetJob gets the stats of the job
+func (c *APIClient) GetJob(ctx context.Context, jobID string) (*models.JobStats, error) {
+	stats := &models.JobStats{}
+	if _, err := c.do(ctx, http.MethodGet, "/jobs/"+url.PathEscape(jobID), nil, nil, nil, http.StatusOK, stats); err != nil {
+		return nil, err
+	}
+
+	return stats, nil
+}
+
+// StopJob stops the job
+func (c *APIClient) StopJob(ctx context.Context, jobID string) error {
+	return c.jobAction(ctx, jobID, JobActionStop)
+}
+
+// RetryJob retries the failed job
+func (c *APIClient) RetryJob(ctx context.Context, jobID string) error {
+	return c.jobAction(ctx, jobID, JobActionRetry)
+}
+
+func (c *APIClient) jobAction(ctx context.Context, jobID string, action string) error {
+	req := &models.JobActionRequest{Action: action}
+	_, err := c.do(ctx, http.MethodPost, "/jobs/"+url.PathEscape(jobID), nil, nil, req, http.StatusNoContent, nil)
+
+	return err
+}
+
+// GetJobLog gets the log of the job
+func (c *APIClient) GetJobLog(ctx context.Context, jobID string) ([]byte, error) {
+	var data []byte
+	if _, err := c.do(ctx, http.MethodGet, "/jobs/"+url.PathEscape(jobID)+"/log", nil, nil, nil, http.StatusOK, &data); err != nil {
+		return nil, err
+	}
+
+	return data, nil
+}
+
+// ListJobs lists the jobs matched with the options.
+// The jobs are scanned with the cursor except the scheduled ones, so the same job may be returned more than once.
+func (c *APIClient) ListJobs(ctx context.Context, opts *ListOptions) *StatsIterator {
+	return c.listStats(ctx, "/jobs", opts.values())
+}
+
+// ListExecutions lists the executions of the periodic job
+func (c *APIClient) ListExecutions(ctx context.Context, periodicJobID string, opts *ListOptions) *StatsIterator {
+	return c.listStats(ctx, "/jobs/"+url.PathEscape(periodicJobID)+"/executions", opts.values())
+}
+
+// ListScheduledJobs lists the jobs scheduled to run later
+func (c *APIClient) ListScheduledJobs(ctx context.Context, opts *ListOptions) *StatsIterator {
+	return c.listStats(ctx, "/scheduled-jobs", opts.values())
+}
+
+func (c *APIClient) listStats(ctx context.Context, path string, values url.Values) *StatsIterator {
+	return &StatsIterator{
+		ctx:   ctx,
+		pager: newPager(values),
+		fetch: func(ctx context.Context, values url.Values) ([]*models.JobStats, http.Header, error) {
+			var page []*models.JobStats
+			header, err := c.do(ctx, http.MethodGet, path, values, nil, nil, http.StatusOK, &page)
+			return page, header, err
+		},
+	}
+}
+
+// RescheduleJob moves the scheduled job to the new run time in unix timestamp
+func (c *APIClient) RescheduleJob(ctx context.Context, jobID string, runAt int64) (*models.JobStats, error) {
+	return c.scheduledJobAction(ctx, jobID, &models.ScheduledJobActionRequest{
+		Action: ScheduledJobActionReschedule,
+		RunAt:  runAt,
+	})
+}
+
+// RunScheduledJob runs the scheduled job immediately
+func (c *APIClient) RunScheduledJob(ctx context.Context, jobID string) (*models.JobStats, error) {
+	return c.scheduledJobAction(ctx, jobID, &models.ScheduledJobActionRequest{Action: ScheduledJobActionRun})
+}
+
+// CancelScheduledJob cancels the scheduled job before it runs
+func (c *APIClient) CancelScheduledJob(ctx context.Context, jobID string) (*models.JobStats, error) {
+	return c.scheduledJobAction(ctx, jobID, &models.ScheduledJobActionRequest{Action: ScheduledJobActionCancel})
+}
+
+func (c *APIClient) scheduledJobAction(ctx context.Context, jobID string, req *models.ScheduledJobActionRequest) (*models.JobStats, error) {
+	stats := &models.JobStats{}
+	if _, err := c.do(ctx, http.MethodPost, "/scheduled-jobs/"+url.PathEscape(jobID), nil, nil, req, http.StatusOK, stats); err != nil {
+		return nil, err
+	}
+
+	return stats, nil
+}
+
+// ListArchivedJobs lists the jobs archived by the retention from the latest archived one
+func (c *APIClient) ListArchivedJobs(ctx context.Context, opts *ListOptions) *ArchivedJobIterator {
+	return &ArchivedJobIterator{
+		ctx:   ctx,
+		pager: newPager(opts.values()),
+		fetch: func(ctx context.Context, values url.Values) ([]*models.ArchivedJob, http.Header, error) {
+			var page []*models.ArchivedJob
+			header, err := c.do(ctx, http.MethodGet, "/archived-jobs", values, nil, nil, http.StatusOK, &page)
+			return page, header, err
+		},
+	}
+}
+
+// GetArchivedJob gets the archived job
+func (c *APIClient) GetArchivedJob(ctx context.Context, jobID string) (*models.ArchivedJob, error) {
+	record := &models.ArchivedJob{}
+	if _, err := c.do(ctx, http.MethodGet, "/archived-jobs/"+url.PathEscape(jobID), nil, nil, nil, http.StatusOK, record); err != nil {
+		return nil, err
+	}
+
+	return record, nil
+}
+
+// GetJobTypes gets the job types registered in job service
+func (c *APIClient) GetJobTypes(ctx context.Context) ([]*models.JobTypeInfo, error) {
+	var types []*models.JobTypeInfo
+	if _, err := c.do(ctx, http.MethodGet, "/job-types", nil, nil, nil, http.StatusOK, &types); err != nil {
+		return nil, err
+	}
+
+	return types, nil
+}
+
+// GetStats gets the stats of the worker pools
+func (c *APIClient) GetStats(ctx context.Context) (*models.JobPoolStats, error) {
+	stats := &models.JobPoolStats{}
+	if _, err := c.do(ctx, http.MethodGet, "/stats", nil, nil, nil, http.StatusOK, stats); err != nil {
+		return nil, err
+	}
+
+	return stats, nil
+}
+
+// GetNodes gets the nodes of the job service cluster
+func (c *APIClient) GetNodes(ctx context.Context) ([]*models.NodeInfo, error) {
+	var nodes []*models.NodeInfo
+	if _, err := c.do(ctx, http.MethodGet, "/nodes", nil, nil, nil, http.StatusOK, &nodes); err != nil {
+		return nil, err
+	}
+
+	return nodes, nil
+}
+
+// ExportPeriodicPolicies exports all the periodic job policies with their numeric IDs
+func (c *APIClient) ExportPeriodicPolicies(ctx context.Context) (*models.PolicyExport, error) {
+	data := &models.PolicyExport{}
+	if _, err := c.do(ctx, http.MethodGet, "/periodic-policies/export", nil, nil, nil, http.StatusOK, data); err != nil {
+		return nil, err
+	}
+
+	return data, nil
+}
+
+// ImportPeriodicPolicies imports the exported policies, the conflicting ones are skipped and reported.
+// Nothing is changed in the dry run.
+func (c *APIClient) ImportPeriodicPolicies(ctx context.Context, data *models.PolicyExport, dryRun bool) (*models.PolicyImportReport, error) {
+	report := &models.PolicyImportReport{}
+	values := url.Values{}
+	values.Set("dry_run", strconv.FormatBool(dryRun))
+	if _, err := c.do(ctx, http.MethodPost, "/periodic-policies/import", values, nil, data, http.StatusOK, report); err != nil {
+		return nil, err
+	}
+
+	return report, nil
+}
+
+// NodeAction fails or requeues the jobs orphaned by the dead node
+func (c *APIClient) NodeAction(ctx context.Context, nodeID string, action string) (*models.NodeActionResult, error) {
+	res := &models.NodeActionResult{}
+	req := &models.NodeActionRequest{Action: action}
+	if _, err := c.do(ctx, http.MethodPost, "/nodes/"+url.PathEscape(nodeID), nil, nil, req, http.StatusOK, res); err != nil {
+		return nil, err
+	}
+
+	return res, nil
+}
+
+// GetRateLimitRejections gets the counts of the launching requests rejected by the rate limiter
+func (c *APIClient) GetRateLimitRejections(ctx context.Context) (map[string]int64, error) {
+	rejections := make(map[string]int64)
+	if _, err := c.do(ctx, http.MethodGet, "/ratelimit/rejections", nil, nil, nil, http.StatusOK, &rejections); err != nil {
+		return nil, err
+	}
+
+	return rejections, nil
+}
+
+// GetOpenAPISpec gets the OpenAPI document of the v2 API in yaml
+func (c *APIClient) GetOpenAPISpec(ctx context.Context) ([]byte, error) {
+	var data []byte
+	if _, err := c.do(ctx, http.MethodGet, "/openapi.yaml", nil, nil, nil, http.StatusOK, &data); err != nil {
+		return nil, err
+	}
+
+	return data, nil
+}
+
+// do sends the request and decodes the response into the out object if the status code is the expected one.
+// The raw response is kept if the out object is *[]byte.
+func (c *APIClient) do(ctx context.Context, method string, path string, values url.Values, header http.Header,
+	body interface{}, expected int, out interface{}) (http.Header, error) {
+	u := c.endpoint + apiV2Prefix + path
+	if len(values) > 0 {
+		u = u + "?" + values.Encode()
+	}
+
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return nil, err
+		}
+		reader = bytes.NewReader(data)
+	}
+
+	req, err := http.NewRequest(method, u, reader)
+	if err != nil {
+		return nil, err
+	}
+	req = req.WithContext(ctx)
+	for k, v := range header {
+		req.Header[k] = v
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	data, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode != expected {
+		return nil, newAPIError(resp, data)
+	}
+
+	switch o := out.(type) {
+	case nil:
+	case *[]byte:
+		*o = data
+	default:
+		if err := json.Unmarshal(data, out); err != nil {
+			return nil, err
+		}
+	}
+
+	return resp.Header, nil
+}
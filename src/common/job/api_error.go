@@ -0,0 +1,160 @@
+package job
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/chenxull/goGridhub/gridhub/src/common/job/models"
+	"github.com/chenxull/goGridhub/gridhub/src/jobservice/errs"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// APIError is the error returned by job service in the form of the problem details defined in RFC 7807.
+// The code is one of the error codes defined in the errs package of job service and the name is the stable
+// name of it, the code is 0 if the response is not in the form of the job service error, e.g: the 404 of
+// the unknown route.
+type APIError struct {
+	StatusCode int    `json:"status"`
+	Code       uint16 `json:"code"`
+	Name       string `json:"error"`
+	Type       string `json:"type"`
+	Message    string `json:"title"`
+	Details    string `json:"detail,omitempty"`
+	// The path of the failed request
+	Instance string `json:"instance,omitempty"`
+	// The current status of the job and the target one of the status mismatch error
+	CurrentStatus string `json:"current_status,omitempty"`
+	TargetStatus  string `json:"target_status,omitempty"`
+	// How long to wait before retrying the rate limited request
+	RetryAfter time.Duration `json:"-"`
+
+	// The job launched before with the same idempotency key or uniqueness
+	existing *models.JobStats
+}
+
+// Error is implementation of error interface
+func (e *APIError) Error() string {
+	msg := fmt.Sprintf("job service error: status %d, code %d", e.StatusCode, e.Code)
+	if len(e.Name) > 0 {
+		msg = fmt.Sprintf("%s (%s)", msg, e.Name)
+	}
+	msg = fmt.Sprintf("%s, message %s", msg, e.Message)
+	if len(e.Details) > 0 {
+		msg = fmt.Sprintf("%s, details %s", msg, e.Details)
+	}
+
+	return msg
+}
+
+// problem is the error body of job service, the message and details are returned by the earlier versions
+type problem struct {
+	APIError
+	Existing      *models.JobStats `json:"existing,omitempty"`
+	LegacyMessage string           `json:"message,omitempty"`
+	LegacyDetails string           `json:"details,omitempty"`
+}
+
+// newAPIError parses the error from the response of job service
+func newAPIError(resp *http.Response, data []byte) *APIError {
+	p := &problem{}
+	if err := json.Unmarshal(data, p); err != nil || p.Code == 0 {
+		p = &problem{}
+		p.Message = strings.TrimSpace(string(data))
+	} else {
+		if len(p.Message) == 0 {
+			p.Message = p.LegacyMessage
+		}
+		if len(p.Details) == 0 {
+			p.Details = p.LegacyDetails
+		}
+		if p.Existing != nil && p.Existing.Stats != nil {
+			p.existing = p.Existing
+		}
+	}
+	e := &p.APIError
+	e.StatusCode = resp.StatusCode
+
+	// The earlier versions return the existing job instead of the error if the job is launched before
+	if resp.StatusCode == http.StatusConflict && e.Code == 0 {
+		existing := &models.JobStats{}
+		if err := json.Unmarshal(data, existing); err == nil && existing.Stats != nil {
+			e.Code = errs.ResourceConflictsErrorCode
+			e.Message = "job is launched before"
+			e.Details = existing.Stats.JobID
+			e.existing = existing
+		}
+	}
+	if e.Code > 0 && len(e.Name) == 0 {
+		e.Name = errs.CodeName(e.Code)
+	}
+	if len(e.Message) == 0 {
+		e.Message = http.StatusText(resp.StatusCode)
+	}
+	if seconds, err := strconv.ParseInt(resp.Header.Get(retryAfterHeader), 10, 64); err == nil {
+		e.RetryAfter = time.Duration(seconds) * time.Second
+	}
+
+	return e
+}
+
+// is checks if the error is the API error with the code, the HTTP status is checked instead
+// if the response is not in the form of the job service error
+func is(err error, code uint16, statusCode int) bool {
+	e, ok := err.(*APIError)
+	if !ok {
+		return false
+	}
+	if e.Code == 0 {
+		return statusCode > 0 && e.StatusCode == statusCode
+	}
+
+	return e.Code == code
+}
+
+// IsNotFoundError checks if the job, node or archived job is not found
+func IsNotFoundError(err error) bool {
+	return is(err, errs.NoObjectFoundErrorCode, http.StatusNotFound)
+}
+
+// IsConflictError checks if the same job is launched before or the node action conflicts
+func IsConflictError(err error) bool {
+	return is(err, errs.ResourceConflictsErrorCode, http.StatusConflict)
+}
+
+// IsBadRequestError checks if the request is rejected as invalid
+func IsBadRequestError(err error) bool {
+	return is(err, errs.BadRequestErrorCode, http.StatusBadRequest)
+}
+
+// IsStatusMismatchError checks if the job action is not allowed in the current job status
+func IsStatusMismatchError(err error) bool {
+	return is(err, errs.StatusMismatchErrorCode, 0)
+}
+
+// IsUnauthorizedError checks if the request is not authenticated
+func IsUnauthorizedError(err error) bool {
+	return is(err, errs.UnAuthorizedErrorCode, http.StatusUnauthorized)
+}
+
+// IsForbiddenError checks if the caller is not granted the scope of the request
+func IsForbiddenError(err error) bool {
+	return is(err, errs.ForbiddenErrorCode, http.StatusForbidden)
+}
+
+// IsRateLimitedError checks if the job launching is rejected by the rate limiter,
+// the APIError.RetryAfter tells how long to wait
+func IsRateLimitedError(err error) bool {
+	return is(err, errs.RateLimitedErrorCode, http.StatusTooManyRequests)
+}
+
+// IsOverloadedError checks if the job launching is rejected as job service is under pressure
+func IsOverloadedError(err error) bool {
+	return is(err, errs.OverloadedErrorCode, http.StatusServiceUnavailable)
+}
+
+// IsUnknownActionError checks if the action of the job, scheduled job or node is not supported
+func IsUnknownActionError(err error) bool {
+	return is(err, errs.UnknownActionNameErrorCode, http.StatusNotImplemented)
+}
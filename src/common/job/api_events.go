@@ -0,0 +1,142 @@
+package job
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"github.com/chenxull/goGridhub/gridhub/src/common/job/models"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+const (
+	lastEventIDHeader     = "Last-Event-ID"
+	statusChangeEventName = "status_change"
+
+	// Max size of one line of the event stream
+	maxEventLineSize = 1024 * 1024
+)
+
+// StatusEventFilter defines the filters of the status change events, empty means all
+type StatusEventFilter struct {
+	JobID   string
+	JobName string
+	// The ID of the periodic job whose executions are watched
+	UpstreamJobID string
+}
+
+// StatusChangeEvent is the status change event streamed by job service
+type StatusChangeEvent struct {
+	// ID of the event for resuming the stream
+	ID     string
+	Change *models.JobStatusChange
+}
+
+// WatchStatusChanges streams the status change events matched with the filter after the event ID and calls the
+// handler for each of them, empty event ID means the new events from now on. The stream closed by job service is
+// resumed after the last event, the watching is stopped once the context is done or the handler returns error.
+func (c *APIClient) WatchStatusChanges(ctx context.Context, filter *StatusEventFilter, lastEventID string,
+	handler func(evt *StatusChangeEvent) error) error {
+	values := url.Values{}
+	if filter != nil {
+		if len(filter.JobID) > 0 {
+			values.Set("job_id", filter.JobID)
+		}
+		if len(filter.JobName) > 0 {
+			values.Set("name", filter.JobName)
+		}
+		if len(filter.UpstreamJobID) > 0 {
+			values.Set("upstream_job_id", filter.UpstreamJobID)
+		}
+	}
+
+	for {
+		id, err := c.streamStatusChanges(ctx, values, lastEventID, handler)
+		if err != nil {
+			return err
+		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		lastEventID = id
+	}
+}
+
+// streamStatusChanges reads the event stream until it's closed and returns the ID of the last event
+func (c *APIClient) streamStatusChanges(ctx context.Context, values url.Values, lastEventID string,
+	handler func(evt *StatusChangeEvent) error) (string, error) {
+	u := c.endpoint + apiV2Prefix + "/events"
+	if len(values) > 0 {
+		u = u + "?" + values.Encode()
+	}
+
+	req, err := http.NewRequest(http.MethodGet, u, nil)
+	if err != nil {
+		return lastEventID, err
+	}
+	req = req.WithContext(ctx)
+	req.Header.Set("Accept", "text/event-stream")
+	if len(lastEventID) > 0 {
+		req.Header.Set(lastEventIDHeader, lastEventID)
+	}
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return lastEventID, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		data, err := ioutil.ReadAll(resp.Body)
+		if err != nil {
+			return lastEventID, err
+		}
+		return lastEventID, newAPIError(resp, data)
+	}
+
+	scanner := bufio.NewScanner(resp.Body)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxEventLineSize)
+
+	var name, data string
+	for scanner.Scan() {
+		line := scanner.Text()
+		if len(line) == 0 {
+			// Dispatch the event
+			if name == statusChangeEventName && len(data) > 0 {
+				change := &models.JobStatusChange{}
+				if err := json.Unmarshal([]byte(data), change); err != nil {
+					return lastEventID, err
+				}
+				if err := handler(&StatusChangeEvent{ID: lastEventID, Change: change}); err != nil {
+					return lastEventID, err
+				}
+			}
+			name, data = "", ""
+			continue
+		}
+		if strings.HasPrefix(line, ":") {
+			// Comment
+			continue
+		}
+
+		field, value := line, ""
+		if i := strings.Index(line, ":"); i >= 0 {
+			field, value = line[:i], strings.TrimPrefix(line[i+1:], " ")
+		}
+		switch field {
+		case "id":
+			lastEventID = value
+		case "event":
+			name = value
+		case "data":
+			if len(data) > 0 {
+				data += "\n"
+			}
+			data += value
+		}
+	}
+
+	return lastEventID, scanner.Err()
+}
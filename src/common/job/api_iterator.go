@@ -0,0 +1,135 @@
+package job
+
+import (
+	"context"
+	"github.com/chenxull/goGridhub/gridhub/src/common/job/models"
+	"net/http"
+	"net/url"
+	"strconv"
+)
+
+// pager keeps the position of the list operation. The pages are turned by the cursor returned in
+// the 'Next-Cursor' header if job service scans the items, otherwise by the page number until the
+// count in the 'Total-Count' header is reached.
+type pager struct {
+	values url.Values
+	page   uint64
+	done   bool
+}
+
+func newPager(values url.Values) *pager {
+	values.Set("page_number", "1")
+	return &pager{
+		values: values,
+		page:   1,
+	}
+}
+
+// turn to the next page after getting the page with the count of items
+func (p *pager) turn(header http.Header, count int) {
+	if cursor := header.Get(nextCursorHeader); len(cursor) > 0 {
+		// Cursor 0 means the scanning is completed
+		p.done = cursor == "0"
+		p.values.Set("cursor", cursor)
+		return
+	}
+
+	total, err := strconv.ParseUint(header.Get(totalCountHeader), 10, 64)
+	size, _ := strconv.ParseUint(p.values.Get("page_size"), 10, 64)
+	if err != nil || count == 0 || p.page*size >= total {
+		p.done = true
+		return
+	}
+
+	p.page++
+	p.values.Set("page_number", strconv.FormatUint(p.page, 10))
+}
+
+// StatsIterator iterates the job stats of the list operation page by page
+//
+//	it := client.ListJobs(ctx, opts)
+//	for it.Next() {
+//		stats := it.Stats()
+//	}
+//	if err := it.Err(); err != nil {
+//	}
+type StatsIterator struct {
+	ctx     context.Context
+	pager   *pager
+	fetch   func(ctx context.Context, values url.Values) ([]*models.JobStats, http.Header, error)
+	items   []*models.JobStats
+	current *models.JobStats
+	err     error
+}
+
+// Next moves to the next job stats, the next page is fetched if the current one is consumed.
+// It returns false once all the pages are consumed or an error occurs.
+func (it *StatsIterator) Next() bool {
+	for len(it.items) == 0 {
+		if it.err != nil || it.pager.done {
+			return false
+		}
+		items, header, err := it.fetch(it.ctx, it.pager.values)
+		if err != nil {
+			it.err = err
+			return false
+		}
+		it.items = items
+		it.pager.turn(header, len(items))
+	}
+
+	it.current, it.items = it.items[0], it.items[1:]
+
+	return true
+}
+
+// Stats returns the current job stats
+func (it *StatsIterator) Stats() *models.JobStats {
+	return it.current
+}
+
+// Err returns the error stopping the iteration
+func (it *StatsIterator) Err() error {
+	return it.err
+}
+
+// ArchivedJobIterator iterates the archived jobs page by page, it's used in the same way as StatsIterator
+type ArchivedJobIterator struct {
+	ctx     context.Context
+	pager   *pager
+	fetch   func(ctx context.Context, values url.Values) ([]*models.ArchivedJob, http.Header, error)
+	items   []*models.ArchivedJob
+	current *models.ArchivedJob
+	err     error
+}
+
+// Next moves to the next archived job, the next page is fetched if the current one is consumed.
+// It returns false once all the pages are consumed or an error occurs.
+func (it *ArchivedJobIterator) Next() bool {
+	for len(it.items) == 0 {
+		if it.err != nil || it.pager.done {
+			return false
+		}
+		items, header, err := it.fetch(it.ctx, it.pager.values)
+		if err != nil {
+			it.err = err
+			return false
+		}
+		it.items = items
+		it.pager.turn(header, len(items))
+	}
+
+	it.current, it.items = it.items[0], it.items[1:]
+
+	return true
+}
+
+// ArchivedJob returns the current archived job
+func (it *ArchivedJobIterator) ArchivedJob() *models.ArchivedJob {
+	return it.current
+}
+
+// Err returns the error stopping the iteration
+func (it *ArchivedJobIterator) Err() error {
+	return it.err
+}
@@ -7,18 +7,16 @@ import (
 	commonhttp "github.com/chenxull/goGridhub/gridhub/src/common/http"
 	"github.com/chenxull/goGridhub/gridhub/src/common/http/modifier/auth"
 	"github.com/chenxull/goGridhub/gridhub/src/common/job/models"
+	"github.com/chenxull/goGridhub/gridhub/src/common/secret"
 	"github.com/chenxull/goGridhub/gridhub/src/core/config"
 	"github.com/chenxull/goGridhub/gridhub/src/jobservice/job"
 	"io/ioutil"
 	"net/http"
-	"regexp"
 	"strings"
 )
 
 var (
-	GlobalClient             Client
-	statusBehindErrorPattern = "mismatch job status for stopping job: .*, job status (.*) is behind Running"
-	statusBehindErrorReg     = regexp.MustCompile(statusBehindErrorPattern)
+	GlobalClient Client
 )
 
 //Client wraps interface to access jobService
@@ -52,7 +50,7 @@ type DefaultClient struct {
 
 // Init the GlobalClient
 func Init() {
-	GlobalClient = NewDefaultClient(config.InternalJobServiceURL(), config.CoreSecret())
+	GlobalClient = NewDefaultClientWithSecrets(config.InternalJobServiceURL(), config.CoreSecrets())
 }
 
 func NewDefaultClient(endpoint, secret string) *DefaultClient {
@@ -70,6 +68,15 @@ func NewDefaultClient(endpoint, secret string) *DefaultClient {
 	}
 }
 
+// NewDefaultClientWithSecrets returns the client signing the requests with the current secret of the store,
+// so the rotated secret is picked up without restart
+func NewDefaultClientWithSecrets(endpoint string, secrets *secret.Store) *DefaultClient {
+	return &DefaultClient{
+		endpoint: strings.TrimRight(endpoint, "/"),
+		client:   commonhttp.NewClient(nil, auth.NewSecretStoreAuthorizer(secrets)),
+	}
+}
+
 // SubmitJob call jobserivce API to submit a job and returns the job's UUID.
 func (d *DefaultClient) SubmitJob(jd *models.JobData) (string, error) {
 	url := d.endpoint + "/api/v1/jobs"
@@ -85,6 +92,9 @@ func (d *DefaultClient) SubmitJob(jd *models.JobData) (string, error) {
 		return "", err
 	}
 	req.Header.Set("Content-Type", "application/json")
+	if len(jd.IdempotencyKey) > 0 {
+		req.Header.Set("Idempotency-Key", jd.IdempotencyKey)
+	}
 	resp, err := d.client.Do(req)
 	if err != nil {
 		return "", err
@@ -95,10 +105,7 @@ func (d *DefaultClient) SubmitJob(jd *models.JobData) (string, error) {
 		return "", err
 	}
 	if resp.StatusCode != http.StatusAccepted {
-		return "", &commonhttp.Error{
-			Code:    resp.StatusCode,
-			Message: string(data),
-		}
+		return "", newAPIError(resp, data)
 	}
 	stats := &models.JobStats{}
 	if err := json.Unmarshal(data, stats); err != nil {
@@ -124,10 +131,7 @@ func (d *DefaultClient) GetJobLog(uuid string) ([]byte, error) {
 		return nil, err
 	}
 	if resp.StatusCode != http.StatusOK {
-		return nil, &commonhttp.Error{
-			Code:    resp.StatusCode,
-			Message: string(data),
-		}
+		return nil, newAPIError(resp, data)
 	}
 	return data, nil
 }
@@ -149,10 +153,7 @@ func (d *DefaultClient) GetExecutions(periodicJobID string) ([]job.Stats, error)
 		return nil, err
 	}
 	if resp.StatusCode != http.StatusOK {
-		return nil, &commonhttp.Error{
-			Code:    resp.StatusCode,
-			Message: string(data),
-		}
+		return nil, newAPIError(resp, data)
 	}
 	var exes []job.Stats
 	err = json.Unmarshal(data, &exes)
@@ -162,33 +163,39 @@ func (d *DefaultClient) GetExecutions(periodicJobID string) ([]job.Stats, error)
 	return exes, nil
 }
 
-// PostAction call jobservice's API to operate action for job specified by uuid
+// PostAction call jobservice's API to operate action for job specified by uuid.
+// The StatusBehindError is returned if the job to stop is already finished.
 func (d *DefaultClient) PostAction(uuid, action string) error {
 	url := d.endpoint + "/api/v1/jobs/" + uuid
-	req := struct {
-		Action string `json:"action"`
-	}{
-		Action: action,
-	}
-	if err := d.client.Post(url, req); err != nil {
-		status, flag := isStatusBehindError(err)
-		if flag {
-			return &StatusBehindError{
-				status: status,
-			}
-		}
+	b, err := json.Marshal(models.JobActionRequest{Action: action})
+	if err != nil {
 		return err
 	}
-	return nil
-}
 
-func isStatusBehindError(err error) (string, bool) {
-	if err == nil {
-		return "", false
+	req, err := http.NewRequest(http.MethodPost, url, bytes.NewReader(b))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 200 && resp.StatusCode <= 299 {
+		return nil
+	}
+
+	data, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return err
 	}
-	strs := statusBehindErrorReg.FindStringSubmatch(err.Error())
-	if len(strs) != 2 {
-		return "", false
+	apiErr := newAPIError(resp, data)
+	if action == JobActionStop && IsStatusMismatchError(apiErr) && len(apiErr.CurrentStatus) > 0 {
+		return &StatusBehindError{
+			status: apiErr.CurrentStatus,
+		}
 	}
-	return strs[1], true
+
+	return apiErr
 }
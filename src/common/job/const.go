@@ -32,4 +32,18 @@ const (
 
 	// JobActionStop : the action to stop the job
 	JobActionStop = "stop"
+	// JobActionRetry : the action to retry the failed job
+	JobActionRetry = "retry"
+
+	// ScheduledJobActionReschedule : the action to move the scheduled job to the new run time
+	ScheduledJobActionReschedule = "reschedule"
+	// ScheduledJobActionRun : the action to run the scheduled job immediately
+	ScheduledJobActionRun = "run"
+	// ScheduledJobActionCancel : the action to cancel the scheduled job before it runs
+	ScheduledJobActionCancel = "cancel"
+
+	// NodeActionFail : the action to mark the jobs orphaned by the dead node as failed
+	NodeActionFail = "fail"
+	// NodeActionRequeue : the action to put the jobs orphaned by the dead node back to the queue
+	NodeActionRequeue = "requeue"
 )
@@ -1,5 +1,9 @@
 package models
 
+import (
+	"encoding/json"
+)
+
 // Parameters for job execution.
 type Parameters map[string]interface{}
 
@@ -14,6 +18,8 @@ type JobData struct {
 	Parameters Parameters   `json:"parameters"`
 	Metadata   *JobMetadata `json:"metadata"`
 	StatusHook string       `json:"status_hook"`
+	// Sent as the 'Idempotency-Key' header to avoid duplicate jobs when retrying
+	IdempotencyKey string `json:"-"`
 }
 
 // JobMetadata stores the metadata of job.
@@ -22,6 +28,35 @@ type JobMetadata struct {
 	ScheduleDelay uint64 `json:"schedule_delay,omitempty"`
 	Cron          string `json:"cron_spec,omitempty"`
 	IsUnique      bool   `json:"unique"`
+	Priority      string `json:"priority,omitempty"`
+	// Parameter keys defining the uniqueness, empty means all the parameters
+	UniqueKeys []string `json:"unique_keys,omitempty"`
+	// Seconds of keeping the uniqueness, 0 means the default 24 hours
+	UniqueTTL uint64 `json:"unique_ttl,omitempty"`
+	// Parameter keys of the secrets besides the ones declared by the job
+	SecretKeys []string `json:"secret_keys,omitempty"`
+	// Interval, one-off runs and blackout windows of the periodic job
+	Schedule *JobSchedule `json:"schedule,omitempty"`
+}
+
+// JobSchedule defines the interval, one-off runs and blackout windows of the periodic job.
+type JobSchedule struct {
+	Interval  *JobInterval         `json:"interval,omitempty"`
+	RunList   []int64              `json:"run_list,omitempty"`
+	Blackouts []*JobBlackoutWindow `json:"blackouts,omitempty"`
+}
+
+// JobInterval runs the periodic job every fixed seconds.
+type JobInterval struct {
+	EverySeconds uint64 `json:"every_seconds"`
+	StartAt      int64  `json:"start_at,omitempty"`
+}
+
+// JobBlackoutWindow skips the runs of the periodic job in the window.
+type JobBlackoutWindow struct {
+	Start           string `json:"start"`
+	DurationSeconds uint64 `json:"duration_seconds"`
+	TimeZone        string `json:"time_zone,omitempty"`
 }
 
 // JobStats keeps the result of job launching.
@@ -49,11 +84,119 @@ type StatsInfo struct {
 	NumericPID    int64      `json:"numeric_policy_id,omitempty"` // The numeric policy ID of the periodic job
 	Parameters    Parameters `json:"parameters,omitempty"`
 	Revision      int64      `json:"revision,omitempty"` // For differentiating the each retry of the same job
+	NodeID        string     `json:"node_id,omitempty"`  // The node which runs the job
+	// Interval, one-off runs and blackout windows of the periodic job
+	Schedule *JobSchedule `json:"schedule,omitempty"`
+}
+
+// JobTypeInfo describes the job type registered in job service.
+type JobTypeInfo struct {
+	Name        string `json:"name"`
+	MaxFails    uint   `json:"max_fails"`
+	ShouldRetry bool   `json:"should_retry"`
+	// JSON schema of the parameters, empty if the job does not declare it
+	ParametersSchema json.RawMessage `json:"parameters_schema,omitempty"`
+}
+
+// ArchivedJob is the job stats archived by the retention of job service.
+type ArchivedJob struct {
+	ArchivedAt int64     `json:"archived_at"`
+	Job        *JobStats `json:"stats"`
+}
+
+// AuditEntry is the entry of the audit log of the job actions in job service.
+type AuditEntry struct {
+	Time           int64  `json:"time"`
+	Caller         string `json:"caller"`
+	Action         string `json:"action"`
+	JobID          string `json:"job_id,omitempty"`
+	JobName        string `json:"job_name,omitempty"`
+	ParametersHash string `json:"parameters_hash,omitempty"`
+	Result         string `json:"result"`
+	StatusCode     int    `json:"status_code"`
+	Error          string `json:"error,omitempty"`
+}
+
+// PeriodicPolicy is the policy of the periodic job exported from job service.
+type PeriodicPolicy struct {
+	ID         string       `json:"id"`
+	JobName    string       `json:"job_name"`
+	CronSpec   string       `json:"cron_spec"`
+	Parameters Parameters   `json:"job_params,omitempty"`
+	WebHookURL string       `json:"web_hook_url,omitempty"`
+	Schedule   *JobSchedule `json:"schedule,omitempty"`
+}
+
+// ExportedPolicy is the periodic job policy with its numeric ID.
+type ExportedPolicy struct {
+	NumericID int64           `json:"numeric_id"`
+	Policy    *PeriodicPolicy `json:"policy"`
+}
+
+// PolicyExport is the versioned export of the periodic job policies.
+type PolicyExport struct {
+	Version    int               `json:"version"`
+	Namespace  string            `json:"namespace"`
+	ExportedAt int64             `json:"exported_at"`
+	Policies   []*ExportedPolicy `json:"policies"`
+}
+
+// PolicyImportResult is the result of importing one periodic job policy.
+type PolicyImportResult struct {
+	ID        string `json:"id"`
+	JobName   string `json:"job_name"`
+	NumericID int64  `json:"numeric_id"`
+	Result    string `json:"result"`
+	Reason    string `json:"reason,omitempty"`
+}
+
+// PolicyImportReport is the report of importing the periodic job policies.
+type PolicyImportReport struct {
+	DryRun    bool                  `json:"dry_run"`
+	Namespace string                `json:"namespace"`
+	Imported  int                   `json:"imported"`
+	Conflicts int                   `json:"conflicts"`
+	Invalid   int                   `json:"invalid"`
+	Failed    int                   `json:"failed"`
+	Results   []*PolicyImportResult `json:"results"`
+}
+
+// ScheduledJobActionRequest defines for triggering the action of the scheduled job.
+type ScheduledJobActionRequest struct {
+	Action string `json:"action"`
+	// Unix timestamp of the new run time, required by the action 'reschedule'
+	RunAt int64 `json:"run_at,omitempty"`
+}
+
+// NodeInfo represents the node of the job service cluster.
+type NodeInfo struct {
+	NodeID      string   `json:"node_id"`
+	StartedAt   int64    `json:"started_at"`
+	HeartbeatAt int64    `json:"heartbeat_at"`
+	Concurrency uint     `json:"concurrency"`
+	RunningJobs []string `json:"running_jobs"`
+	Load        float64  `json:"load"`
+	Status      string   `json:"status"`
+}
+
+// NodeActionRequest defines for failing or requeuing the jobs orphaned by the dead node.
+type NodeActionRequest struct {
+	Action string `json:"action"`
+}
+
+// NodeActionResult is the result of the node action.
+type NodeActionResult struct {
+	NodeID string   `json:"node_id"`
+	Action string   `json:"action"`
+	Jobs   []string `json:"jobs"`
+	Failed []string `json:"failed,omitempty"`
 }
 
 // JobPoolStats represents the healthy and status of all the running worker pools.
 type JobPoolStats struct {
-	Pools []*JobPoolStatsData `json:"worker_pools"`
+	Pools   []*JobPoolStatsData `json:"worker_pools"`
+	Leader  *JobLeaderData      `json:"periodic_leader,omitempty"`
+	Streams []*JobStreamStats   `json:"streams,omitempty"`
 }
 
 // JobPoolStatsData represent the healthy and status of the worker worker.
@@ -66,6 +209,24 @@ type JobPoolStatsData struct {
 	Status       string   `json:"status"`
 }
 
+// JobLeaderData represents the node which is scheduling the periodic jobs.
+type JobLeaderData struct {
+	NodeID       string `json:"node_id"`
+	FencingToken int64  `json:"fencing_token"`
+	ExpireAt     int64  `json:"expire_at"`
+}
+
+// JobStreamStats represents the metrics of the job stream of the redis streams backend.
+type JobStreamStats struct {
+	JobName   string `json:"job_name"`
+	Length    int64  `json:"length"`
+	Pending   int64  `json:"pending"`
+	Consumers int64  `json:"consumers"`
+	Processed int64  `json:"processed"`
+	Failed    int64  `json:"failed"`
+	Reclaimed int64  `json:"reclaimed"`
+}
+
 // JobActionRequest defines for triggering job action like stop/cancel.
 type JobActionRequest struct {
 	Action string `json:"action"`
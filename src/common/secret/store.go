@@ -1 +1,183 @@
 package secret
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/subtle"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// FileEnvSuffix is appended to the name of the secret env to get the env of the secret file,
+	// e.g: CORE_SECRET_FILE for CORE_SECRET
+	FileEnvSuffix = "_FILE"
+
+	// How often the secret file is checked for changes
+	fileCheckInterval = 10 * time.Second
+)
+
+// Store keeps the active secrets of a component. The current secret is used to sign the
+// outgoing requests, the previous ones are still accepted from the incoming requests until
+// all the components have picked up the new one, so the secret can be rotated without restart.
+//
+// The secrets are either set statically or loaded from a file with one secret per line,
+// the first one is the current secret. Empty lines and lines starting with '#' are ignored.
+// The file is reloaded once it's modified, the secrets loaded before are kept if the new
+// file is broken.
+type Store struct {
+	path      string
+	lock      sync.RWMutex
+	secrets   []string
+	modTime   time.Time
+	checkedAt time.Time
+}
+
+// NewStore returns a store of the static secrets, the first non empty one is the current secret
+func NewStore(secrets ...string) *Store {
+	s := &Store{}
+	for _, sec := range secrets {
+		if len(sec) > 0 {
+			s.secrets = append(s.secrets, sec)
+		}
+	}
+
+	return s
+}
+
+// NewFileStore returns a store of the secrets loaded from the file
+func NewFileStore(path string) (*Store, error) {
+	s := &Store{
+		path: path,
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, fmt.Errorf("stat secret file: %s", err)
+	}
+	if err := s.load(info.ModTime()); err != nil {
+		return nil, err
+	}
+
+	return s, nil
+}
+
+// NewStoreFromEnv returns the store of the secret file in the env '<name>_FILE' if it's set,
+// otherwise the store of the static secret in the env '<name>'.
+func NewStoreFromEnv(name string) (*Store, error) {
+	if path := strings.TrimSpace(os.Getenv(name + FileEnvSuffix)); len(path) > 0 {
+		return NewFileStore(path)
+	}
+
+	return NewStore(os.Getenv(name)), nil
+}
+
+// Current returns the secret used to sign the outgoing requests, empty if there is no secret
+func (s *Store) Current() string {
+	s.refresh()
+
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+
+	if len(s.secrets) == 0 {
+		return ""
+	}
+
+	return s.secrets[0]
+}
+
+// IsValid checks if the secret matches any of the active secrets
+func (s *Store) IsValid(secret string) bool {
+	if len(secret) == 0 {
+		return false
+	}
+
+	s.refresh()
+
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+
+	valid := false
+	// Compare with all the secrets to not leak which one is matched
+	for _, sec := range s.secrets {
+		if subtle.ConstantTimeCompare([]byte(sec), []byte(secret)) == 1 {
+			valid = true
+		}
+	}
+
+	return valid
+}
+
+// Reload the secret file right now, it does nothing for the static secrets
+func (s *Store) Reload() error {
+	if len(s.path) == 0 {
+		return nil
+	}
+
+	info, err := os.Stat(s.path)
+	if err != nil {
+		return fmt.Errorf("stat secret file: %s", err)
+	}
+
+	return s.load(info.ModTime())
+}
+
+// refresh reloads the file if it's modified since the last loading
+func (s *Store) refresh() {
+	if len(s.path) == 0 {
+		return
+	}
+
+	s.lock.Lock()
+	if time.Since(s.checkedAt) < fileCheckInterval {
+		s.lock.Unlock()
+		return
+	}
+	s.checkedAt = time.Now()
+	modTime := s.modTime
+	s.lock.Unlock()
+
+	info, err := os.Stat(s.path)
+	if err != nil || info.ModTime().Equal(modTime) {
+		return
+	}
+
+	// Keep the current secrets if the file is broken
+	_ = s.load(info.ModTime())
+}
+
+func (s *Store) load(modTime time.Time) error {
+	data, err := ioutil.ReadFile(s.path)
+	if err != nil {
+		return fmt.Errorf("read secret file: %s", err)
+	}
+
+	secrets := make([]string, 0, 2)
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if len(line) == 0 || strings.HasPrefix(line, "#") {
+			continue
+		}
+		secrets = append(secrets, line)
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("parse secret file: %s", err)
+	}
+	if len(secrets) == 0 {
+		return fmt.Errorf("no secret in file %s", s.path)
+	}
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	s.secrets = secrets
+	s.modTime = modTime
+	s.checkedAt = time.Now()
+
+	return nil
+}
@@ -3,9 +3,11 @@ package config
 import (
 	"github.com/chenxull/goGridhub/gridhub/src/common"
 	comcfg "github.com/chenxull/goGridhub/gridhub/src/common/config"
+	"github.com/chenxull/goGridhub/gridhub/src/common/secret"
 	"github.com/chenxull/goGridhub/gridhub/src/jobservice/logger"
 	"os"
 	"strings"
+	"sync"
 )
 
 const (
@@ -19,6 +21,10 @@ const (
 var (
 	cfgMgr      *comcfg.CfgManager
 	keyProvider comcfg.KeyProvider
+
+	coreSecrets       *secret.Store
+	jobserviceSecrets *secret.Store
+	secretsOnce       sync.Once
 )
 
 func initKeyProvider() {
@@ -31,17 +37,48 @@ func initKeyProvider() {
 	keyProvider = comcfg.NewFileKeyProvider(path)
 }
 
+// initSecrets loads the secret stores from the secret files or the secret envs
+func initSecrets() {
+	secretsOnce.Do(func() {
+		coreSecrets = loadSecrets("CORE_SECRET")
+		jobserviceSecrets = loadSecrets("JOBSERVICE_SECRET")
+	})
+}
+
+func loadSecrets(name string) *secret.Store {
+	store, err := secret.NewStoreFromEnv(name)
+	if err != nil {
+		logger.Errorf("failed to load secret %s from file: %s, the env %s is used instead", name, err, name)
+		return secret.NewStore(os.Getenv(name))
+	}
+
+	return store
+}
+
+// CoreSecrets returns the store of the active secrets to mark harbor-core when communicate with
+// other component
+func CoreSecrets() *secret.Store {
+	initSecrets()
+	return coreSecrets
+}
+
 // CoreSecret returns a secret to mark harbor-core when communicate with
 // other component
 func CoreSecret() string {
-	return os.Getenv("CORE_SECRET")
+	return CoreSecrets().Current()
+}
+
+// JobserviceSecrets returns the store of the active secrets to mark Jobservice when communicate with
+// other component
+func JobserviceSecrets() *secret.Store {
+	initSecrets()
+	return jobserviceSecrets
 }
 
 // JobserviceSecret returns a secret to mark Jobservice when communicate with
 // other component
-// TODO replace it with method of SecretStore
 func JobserviceSecret() string {
-	return os.Getenv("JOBSERVICE_SECRET")
+	return JobserviceSecrets().Current()
 }
 
 // SecretKey returns the secret key to encrypt the password of target
@@ -0,0 +1,197 @@
+package admission
+
+import (
+	"fmt"
+	"github.com/chenxull/goGridhub/gridhub/src/jobservice/common/rds"
+	"github.com/chenxull/goGridhub/gridhub/src/jobservice/config"
+	"github.com/chenxull/goGridhub/gridhub/src/jobservice/errs"
+	"github.com/chenxull/goGridhub/gridhub/src/jobservice/job"
+	"github.com/chenxull/goGridhub/gridhub/src/jobservice/logger"
+	"github.com/gomodule/redigo/redis"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Cache the memory usage of redis to avoid calling INFO for every request
+const memoryUsageCacheTime = 5 * time.Second
+
+// Controller checks the system pressure before enqueuing the generic jobs.
+type Controller interface {
+	// Admit the job with the specified priority.
+	//
+	// Arguments:
+	//   jobName string : name of the job
+	//   priority string: priority of the job, empty means normal
+	//
+	// Returns:
+	//   The seconds to defer the job, 0 means enqueuing the job now
+	//   Overloaded error if the job is rejected
+	Admit(jobName string, priority string) (uint64, error)
+
+	// Reload the thresholds
+	Reload(cfg *config.AdmissionConfig)
+}
+
+// redisController is the default implementation of Controller based on
+// the queue depth and the memory usage of redis
+type redisController struct {
+	namespace string
+	pool      *redis.Pool
+	// The pool backend queuing the jobs
+	backend string
+	// Protect the fields below
+	lock      sync.RWMutex
+	cfg       *config.AdmissionConfig
+	memUsage  float64
+	checkedAt time.Time
+}
+
+// NewController is constructor of redisController.
+// The backend is one of the redis pool backends, the queue depth is read from the queue of it.
+func NewController(namespace string, pool *redis.Pool, backend string, cfg *config.AdmissionConfig) Controller {
+	rc := &redisController{
+		namespace: namespace,
+		pool:      pool,
+		backend:   backend,
+	}
+	rc.Reload(cfg)
+
+	return rc
+}
+
+// Admit is implementation of Controller.Admit
+func (rc *redisController) Admit(jobName string, priority string) (uint64, error) {
+	cfg := rc.config()
+	if cfg == nil || priority == job.PriorityHigh {
+		return 0, nil
+	}
+
+	reason, err := rc.pressure(cfg, jobName)
+	if err != nil {
+		// Fail open, the admission control should not block the service
+		logger.Errorf("check system pressure for job %s error: %s", jobName, err)
+		return 0, nil
+	}
+
+	if len(reason) == 0 {
+		return 0, nil
+	}
+
+	if priority == job.PriorityLow && cfg.DeferSeconds > 0 {
+		logger.Warningf("Job %s is deferred %d seconds: %s", jobName, cfg.DeferSeconds, reason)
+		return cfg.DeferSeconds, nil
+	}
+
+	return 0, errs.OverloadedError(fmt.Sprintf("job %s is rejected: %s", jobName, reason))
+}
+
+// Reload is implementation of Controller.Reload
+func (rc *redisController) Reload(cfg *config.AdmissionConfig) {
+	rc.lock.Lock()
+	defer rc.lock.Unlock()
+
+	rc.cfg = cfg
+}
+
+func (rc *redisController) config() *config.AdmissionConfig {
+	rc.lock.RLock()
+	defer rc.lock.RUnlock()
+
+	return rc.cfg
+}
+
+// pressure returns the reason if any threshold is exceeded
+func (rc *redisController) pressure(cfg *config.AdmissionConfig, jobName string) (string, error) {
+	conn := rc.pool.Get()
+	defer func() {
+		_ = conn.Close()
+	}()
+
+	maxDepth := cfg.MaxQueueDepth
+	if depth, ok := cfg.QueueDepths[jobName]; ok {
+		maxDepth = depth
+	}
+	if maxDepth > 0 {
+		depth, err := rc.queueDepth(conn, jobName)
+		if err != nil {
+			return "", err
+		}
+
+		if depth >= maxDepth {
+			return fmt.Sprintf("queue depth %d reaches the limit %d", depth, maxDepth), nil
+		}
+	}
+
+	if cfg.MaxMemoryUsage > 0 {
+		usage, err := rc.memoryUsage(conn)
+		if err != nil {
+			return "", err
+		}
+
+		if usage >= cfg.MaxMemoryUsage {
+			return fmt.Sprintf("redis memory usage %.2f reaches the limit %.2f", usage, cfg.MaxMemoryUsage), nil
+		}
+	}
+
+	return "", nil
+}
+
+// queueDepth returns the count of the jobs queued by the backend.
+// The entries of the job stream are deleted once acknowledged, so the length of the stream
+// also counts the ones being processed.
+func (rc *redisController) queueDepth(conn redis.Conn, jobName string) (int64, error) {
+	if rc.backend == config.JobServicePoolBackendRedisStreams {
+		return redis.Int64(conn.Do("XLEN", rds.KeyStream(rc.namespace, jobName)))
+	}
+
+	return redis.Int64(conn.Do("LLEN", rds.RedisKeyJobs(rc.namespace, jobName)))
+}
+
+// memoryUsage returns the ratio of the used memory to the max memory of redis
+func (rc *redisController) memoryUsage(conn redis.Conn) (float64, error) {
+	rc.lock.RLock()
+	if time.Since(rc.checkedAt) < memoryUsageCacheTime {
+		defer rc.lock.RUnlock()
+		return rc.memUsage, nil
+	}
+	rc.lock.RUnlock()
+
+	info, err := redis.String(conn.Do("INFO", "memory"))
+	if err != nil {
+		return 0, err
+	}
+
+	var used, max float64
+	for _, line := range strings.Split(info, "\r\n") {
+		kv := strings.SplitN(line, ":", 2)
+		if len(kv) != 2 {
+			continue
+		}
+
+		switch kv[0] {
+		case "used_memory":
+			used, err = strconv.ParseFloat(kv[1], 64)
+		case "maxmemory":
+			max, err = strconv.ParseFloat(kv[1], 64)
+		}
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	usage := 0.0
+	// No max memory is set
+	if max > 0 {
+		usage = used / max
+	}
+
+	rc.lock.Lock()
+	defer rc.lock.Unlock()
+
+	rc.memUsage = usage
+	rc.checkedAt = time.Now()
+
+	return usage, nil
+}
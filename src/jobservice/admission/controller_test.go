@@ -0,0 +1,91 @@
+package admission
+
+import (
+	"github.com/alicebob/miniredis/v2"
+	"github.com/chenxull/goGridhub/gridhub/src/jobservice/common/rds"
+	"github.com/chenxull/goGridhub/gridhub/src/jobservice/config"
+	"github.com/chenxull/goGridhub/gridhub/src/jobservice/errs"
+	"github.com/chenxull/goGridhub/gridhub/src/jobservice/job"
+	"github.com/gomodule/redigo/redis"
+	"testing"
+)
+
+const testNamespace = "{admission_test}"
+
+func newTestPool(t *testing.T) (*redis.Pool, *miniredis.Miniredis) {
+	t.Helper()
+
+	mr := miniredis.RunT(t)
+	pool := &redis.Pool{
+		Dial: func() (redis.Conn, error) {
+			return redis.Dial("tcp", mr.Addr())
+		},
+	}
+	t.Cleanup(func() {
+		_ = pool.Close()
+	})
+
+	return pool, mr
+}
+
+func TestAdmitQueueDepth(t *testing.T) {
+	cases := []struct {
+		backend string
+		// Queue one job of the backend
+		enqueue func(mr *miniredis.Miniredis, jobName string)
+		// Queue one job of the other backend, it should not be counted
+		other func(mr *miniredis.Miniredis, jobName string)
+	}{
+		{
+			backend: config.JobServicePoolBackendRedis,
+			enqueue: func(mr *miniredis.Miniredis, jobName string) {
+				_, _ = mr.Lpush(rds.RedisKeyJobs(testNamespace, jobName), "{}")
+			},
+			other: func(mr *miniredis.Miniredis, jobName string) {
+				_, _ = mr.XAdd(rds.KeyStream(testNamespace, jobName), "*", []string{"job", "{}"})
+			},
+		},
+		{
+			backend: config.JobServicePoolBackendRedisStreams,
+			enqueue: func(mr *miniredis.Miniredis, jobName string) {
+				_, _ = mr.XAdd(rds.KeyStream(testNamespace, jobName), "*", []string{"job", "{}"})
+			},
+			other: func(mr *miniredis.Miniredis, jobName string) {
+				_, _ = mr.Lpush(rds.RedisKeyJobs(testNamespace, jobName), "{}")
+			},
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.backend, func(t *testing.T) {
+			pool, mr := newTestPool(t)
+			ac := NewController(testNamespace, pool, c.backend, &config.AdmissionConfig{
+				MaxQueueDepth: 2,
+				DeferSeconds:  30,
+			})
+
+			c.enqueue(mr, "demo")
+			c.other(mr, "demo")
+			c.other(mr, "demo")
+			if delay, err := ac.Admit("demo", ""); err != nil || delay != 0 {
+				t.Fatalf("expect the job admitted under the limit but got %d, %v", delay, err)
+			}
+
+			c.enqueue(mr, "demo")
+			if _, err := ac.Admit("demo", ""); !errs.IsOverloadedError(err) {
+				t.Errorf("expect the job rejected at the limit but got %v", err)
+			}
+			if delay, err := ac.Admit("demo", job.PriorityLow); err != nil || delay != 30 {
+				t.Errorf("expect the low priority job deferred but got %d, %v", delay, err)
+			}
+			if delay, err := ac.Admit("demo", job.PriorityHigh); err != nil || delay != 0 {
+				t.Errorf("expect the high priority job admitted but got %d, %v", delay, err)
+			}
+
+			// The depth is counted per job
+			if _, err := ac.Admit("sample", ""); err != nil {
+				t.Errorf("expect the job of another queue admitted but got %v", err)
+			}
+		})
+	}
+}
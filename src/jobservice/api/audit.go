@@ -0,0 +1,134 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"github.com/chenxull/goGridhub/gridhub/src/jobservice/audit"
+	"github.com/chenxull/goGridhub/gridhub/src/jobservice/errs"
+	"github.com/chenxull/goGridhub/gridhub/src/jobservice/job"
+	"github.com/chenxull/goGridhub/gridhub/src/jobservice/logger"
+	"github.com/pkg/errors"
+	"io/ioutil"
+	"net/http"
+	"time"
+)
+
+// audit appends the entry of the job action done by the caller of the request.
+// The code is the status of the done action, the status of the error is used if it's failed.
+// The failure of auditing is logged and does not fail the request as the action is already done.
+func (dh *DefaultHandler) audit(req *http.Request, e *audit.Entry, code int, err error) {
+	appendAudit(dh.auditor, CallerFromRequest(req), req.URL.Path, e, code, err)
+}
+
+// auditLaunch appends the entry of launching the job, the ID of the existing job is kept
+// if the same job is launched before
+func (dh *DefaultHandler) auditLaunch(req *http.Request, jobReq *job.Request, jobStats *job.Stats, err error) {
+	if dh.auditor == nil {
+		return
+	}
+
+	e := launchEntry(jobReq)
+	if jobStats != nil && jobStats.Info != nil {
+		e.JobID = jobStats.Info.JobID
+	} else if existing, ok := errs.ConflictData(err).(*job.Stats); ok && existing.Info != nil {
+		e.JobID = existing.Info.JobID
+	}
+
+	dh.audit(req, e, http.StatusAccepted, err)
+}
+
+// auditAction appends the entry of the action on the tracked job.
+// The job name resolved for checking the permission is reused if it's there.
+func (dh *DefaultHandler) auditAction(req *http.Request, action string, jobID string, err error) {
+	if dh.auditor == nil {
+		return
+	}
+
+	jobName, ok := targetJobFromRequest(req)
+	if !ok {
+		if st, gErr := dh.controller.GetJob(jobID); gErr == nil && st.Info != nil {
+			jobName = st.Info.JobName
+		}
+	}
+
+	dh.audit(req, &audit.Entry{Action: action, JobID: jobID, JobName: jobName}, http.StatusNoContent, err)
+}
+
+// auditRejectedLaunch appends the entry of the launching request rejected by the router
+// with the code, e.g: the unauthorized, forbidden or rate limited ones. Other requests are skipped.
+func (br *BaseRouter) auditRejectedLaunch(req *http.Request, code int, err error) {
+	if br.auditor == nil || br.routeName(req) != routeLaunchJob {
+		return
+	}
+
+	jobReq := &job.Request{}
+	if data, rErr := ioutil.ReadAll(req.Body); rErr == nil {
+		// Restore the body for the next reader
+		req.Body = ioutil.NopCloser(bytes.NewReader(data))
+		// The job name and parameters are left empty for the malformed request
+		_ = json.Unmarshal(data, jobReq)
+	}
+
+	appendAudit(br.auditor, CallerFromRequest(req), req.URL.Path, launchEntry(jobReq), code, err)
+}
+
+// launchEntry returns the audit entry of launching the job of the request
+func launchEntry(jobReq *job.Request) *audit.Entry {
+	e := &audit.Entry{
+		Action: audit.ActionLaunch,
+	}
+	if jobReq.Job != nil {
+		e.JobName = jobReq.Job.Name
+		e.ParametersHash = audit.HashParameters(jobReq.Job.Parameters)
+	}
+
+	return e
+}
+
+// appendAudit appends the entry of the action done by the caller to the sink, nil sink means no auditing.
+// The instance is the path or the method of the request reported in the problem of the failed action.
+// The code is the status of the request, the status of the error is used if it's failed.
+// The error without the status is treated as the server error unless the code is a failure one.
+func appendAudit(sink audit.Sink, caller string, instance string, e *audit.Entry, code int, err error) {
+	if sink == nil {
+		return
+	}
+
+	e.Time = time.Now().Unix()
+	e.Caller = caller
+	e.Result = audit.ResultSuccess
+	e.StatusCode = code
+	if err != nil {
+		status := http.StatusInternalServerError
+		if code >= http.StatusBadRequest {
+			status = code
+		}
+		problem := errs.NewProblem(err, status, instance)
+		e.Result = audit.ResultFailure
+		e.StatusCode = problem.Status
+		e.Error = problem.Name
+	}
+
+	if aErr := sink.Append(e); aErr != nil {
+		logger.Errorf("Append audit log of %s job %s by %s error: %s", e.Action, e.JobID, e.Caller, aErr)
+	}
+}
+
+// HandleGetAuditLogsReq is implementation of method defined in interface 'Handler'.
+// The entries are returned from the latest one and filtered by the caller, action and job ID.
+func (dh *DefaultHandler) HandleGetAuditLogsReq(w http.ResponseWriter, req *http.Request) {
+	if dh.auditor == nil {
+		dh.handleError(w, req, http.StatusBadRequest, errs.BadRequestError(errors.New("audit log is not enabled")))
+		return
+	}
+
+	entries, total, err := dh.auditor.List(extractQuery(req))
+	if err != nil {
+		dh.handleError(w, req, http.StatusInternalServerError, serverError(err, errs.GetAuditLogsError))
+		return
+	}
+
+	w.Header().Add(totalHeaderKey, fmt.Sprintf("%d", total))
+	dh.handleJSONData(w, req, http.StatusOK, entries)
+}
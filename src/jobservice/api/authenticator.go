@@ -1,8 +1,11 @@
 package api
 
 import (
+	"context"
+	"crypto/sha256"
 	"errors"
 	"fmt"
+	"github.com/chenxull/goGridhub/gridhub/src/jobservice/auth"
 	"github.com/chenxull/goGridhub/gridhub/src/jobservice/common/utils"
 	"github.com/chenxull/goGridhub/gridhub/src/jobservice/config"
 	"net/http"
@@ -11,41 +14,172 @@ import (
 
 const (
 	secretPrefix = "Harbor-Secret"
+	bearerPrefix = "Bearer"
 	authHeader   = "Authorization"
+
+	// secretCaller is the identity of the caller authenticated by the shared secret
+	secretCaller = "secret"
 )
 
+type callerContextKey string
+
+// callerKey is the key of the authenticated caller kept in the request context
+const callerKey callerContextKey = "caller"
+
+// targetJobKey is the key of the name of the job targeted by the request kept in the request context
+const targetJobKey callerContextKey = "target-job"
+
 // Authenticator defined behaviors of doing auth checking.
 type Authenticator interface {
-	//Auth incoming request
-	DoAuth(req *http.Request) error
+	//Auth incoming request and return the identity of the caller
+	DoAuth(req *http.Request) (*auth.Identity, error)
 }
 
+// SecretAuthenticator authenticates the caller with the shared secret, all the scopes are granted to it
 type SecretAuthenticator struct {
 }
 
-func (sa *SecretAuthenticator) DoAuth(req *http.Request) error {
+func (sa *SecretAuthenticator) DoAuth(req *http.Request) (*auth.Identity, error) {
 	if req == nil {
-		return errors.New("nil request")
+		return nil, errors.New("nil request")
 	}
 
 	h := strings.TrimSpace(req.Header.Get(authHeader))
 	if utils.IsEmptyStr(h) {
-		return fmt.Errorf("header '%s' missing", authHeader)
+		return nil, fmt.Errorf("header '%s' missing", authHeader)
 	}
 
 	if !strings.HasPrefix(h, secretPrefix) {
-		return fmt.Errorf("'%s' should start with '%s'", authHeader, secretPrefix)
+		return nil, fmt.Errorf("'%s' should start with '%s'", authHeader, secretPrefix)
 	}
 
 	// 从请求中获取加密信息字段，后面的验证需要用到
 	secret := strings.TrimSpace(strings.TrimPrefix(h, secretPrefix))
 	// incase both two are empty
 	if utils.IsEmptyStr(secret) {
-		return errors.New("empty secret is not allowed")
+		return nil, errors.New("empty secret is not allowed")
+	}
+	secrets, err := config.AuthSecrets()
+	if err != nil {
+		return nil, err
+	}
+	// Both the current and the previous secrets are accepted during the rotation
+	if !secrets.IsValid(secret) {
+		return nil, errors.New("unauthorized")
+	}
+	return &auth.Identity{
+		Name:   secretCaller,
+		Grants: []*auth.Grant{{Scope: auth.ScopeAll, Jobs: "*"}},
+	}, nil
+}
+
+// CredentialAuthenticator authenticates the caller with the named static tokens or
+// the JWT bearer tokens besides the shared secret.
+type CredentialAuthenticator struct {
+	secret *SecretAuthenticator
+	// Keyed by the digest of the token
+	tokens map[[sha256.Size]byte]*auth.Identity
+	// Nil if the JWT is not accepted
+	jwt *auth.JWTVerifier
+}
+
+// NewCredentialAuthenticator is constructor of CredentialAuthenticator
+func NewCredentialAuthenticator(cfg *config.AuthConfig) (*CredentialAuthenticator, error) {
+	ca := &CredentialAuthenticator{
+		secret: &SecretAuthenticator{},
+		tokens: make(map[[sha256.Size]byte]*auth.Identity, len(cfg.Credentials)),
+	}
+
+	for _, cc := range cfg.Credentials {
+		grants, err := auth.ParseGrants(cc.Scopes)
+		if err != nil {
+			return nil, err
+		}
+
+		digest := sha256.Sum256([]byte(cc.GetToken()))
+		if _, ok := ca.tokens[digest]; ok {
+			return nil, fmt.Errorf("token of API credential %s is shared with others", cc.Name)
+		}
+		ca.tokens[digest] = &auth.Identity{
+			Name:   cc.Name,
+			Grants: grants,
+		}
+	}
+
+	if j := cfg.JWT; j != nil {
+		keys, err := auth.LoadKeySet(j.JWKSFile)
+		if err != nil {
+			return nil, err
+		}
+		ca.jwt = auth.NewJWTVerifier(keys, j.Issuer, j.Audience, j.ScopesClaim)
+	}
+
+	return ca, nil
+}
+
+// DoAuth implements Authenticator
+func (ca *CredentialAuthenticator) DoAuth(req *http.Request) (*auth.Identity, error) {
+	if req == nil {
+		return nil, errors.New("nil request")
+	}
+
+	h := strings.TrimSpace(req.Header.Get(authHeader))
+	if strings.HasPrefix(h, secretPrefix) {
+		return ca.secret.DoAuth(req)
+	}
+
+	if !strings.HasPrefix(h, bearerPrefix) {
+		return nil, fmt.Errorf("'%s' should start with '%s' or '%s'", authHeader, secretPrefix, bearerPrefix)
+	}
+
+	token := strings.TrimSpace(strings.TrimPrefix(h, bearerPrefix))
+	if utils.IsEmptyStr(token) {
+		return nil, errors.New("empty token is not allowed")
+	}
+
+	// The digest lookup is not affected by the timing of comparing the tokens
+	if id, ok := ca.tokens[sha256.Sum256([]byte(token))]; ok {
+		return id, nil
+	}
+
+	if ca.jwt != nil && auth.IsJWT(token) {
+		return ca.jwt.Verify(token)
 	}
-	expectedSecret := config.GetAuthSecret()
-	if expectedSecret != secret {
-		return errors.New("unauthorized")
+
+	return nil, errors.New("unauthorized")
+}
+
+// CallerFromRequest returns the authenticated caller of the request
+func CallerFromRequest(req *http.Request) string {
+	if id := IdentityFromRequest(req); id != nil {
+		return id.Name
 	}
+
+	return ""
+}
+
+// targetJobFromRequest returns the name of the job targeted by the request which is resolved
+// when checking the permission, false is returned if it's not resolved
+func targetJobFromRequest(req *http.Request) (string, bool) {
+	return targetJobFromContext(req.Context())
+}
+
+// targetJobFromContext returns the name of the targeted job kept in the context
+func targetJobFromContext(ctx context.Context) (string, bool) {
+	name, ok := ctx.Value(targetJobKey).(string)
+	return name, ok
+}
+
+// IdentityFromRequest returns the identity of the authenticated caller of the request
+func IdentityFromRequest(req *http.Request) *auth.Identity {
+	return identityFromContext(req.Context())
+}
+
+// identityFromContext returns the identity of the authenticated caller kept in the context
+func identityFromContext(ctx context.Context) *auth.Identity {
+	if id, ok := ctx.Value(callerKey).(*auth.Identity); ok {
+		return id
+	}
+
 	return nil
 }
@@ -0,0 +1,158 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/chenxull/goGridhub/gridhub/src/jobservice/common/query"
+	"github.com/chenxull/goGridhub/gridhub/src/jobservice/errs"
+	"github.com/chenxull/goGridhub/gridhub/src/jobservice/hook"
+	"github.com/chenxull/goGridhub/gridhub/src/jobservice/job"
+	"github.com/pkg/errors"
+	"net/http"
+	"strings"
+	"time"
+)
+
+const (
+	lastEventIDHeaderKey = "Last-Event-ID"
+	// Name of the server-sent events of the status changes
+	statusChangeEventName = "status_change"
+
+	// How long to wait for the new events before sending the keep-alive comment
+	eventWaitInterval = 5 * time.Second
+	// The stream is closed before reaching the write timeout of the server,
+	// the clients reconnect with the 'Last-Event-ID' to resume it
+	eventStreamDuration = 10 * time.Second
+	// Milliseconds of waiting before reconnecting, sent to the clients
+	eventRetryMillis = 1000
+)
+
+// statusEventFilter matches the status change events with the query parameters
+type statusEventFilter struct {
+	jobID         string
+	jobName       string
+	upstreamJobID string
+}
+
+func (f *statusEventFilter) match(change *job.StatusChange) bool {
+	if len(f.jobID) > 0 && change.JobID != f.jobID {
+		return false
+	}
+
+	if len(f.jobName) == 0 && len(f.upstreamJobID) == 0 {
+		return true
+	}
+	if change.Metadata == nil {
+		return false
+	}
+	if len(f.jobName) > 0 && change.Metadata.JobName != f.jobName {
+		return false
+	}
+	if len(f.upstreamJobID) > 0 && change.Metadata.UpstreamJobID != f.upstreamJobID {
+		return false
+	}
+
+	return true
+}
+
+// HandleStatusEventsReq is implementation of method defined in interface 'Handler'.
+// The status change events are streamed as the server-sent events, the stream is resumed
+// after the event in the 'Last-Event-ID' header or the 'last_event_id' query parameter.
+func (dh *DefaultHandler) HandleStatusEventsReq(w http.ResponseWriter, req *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		dh.handleError(w, req, http.StatusInternalServerError, errs.GetStatusEventsError(errors.New("streaming is not supported")))
+		return
+	}
+
+	queries := req.URL.Query()
+	filter := &statusEventFilter{
+		jobID:         queries.Get("job_id"),
+		jobName:       queries.Get(query.ParamKeyJobName),
+		upstreamJobID: queries.Get("upstream_job_id"),
+	}
+	lastID := strings.TrimSpace(req.Header.Get(lastEventIDHeaderKey))
+	if len(lastID) == 0 {
+		lastID = strings.TrimSpace(queries.Get("last_event_id"))
+	}
+
+	// Read the missed events before streaming, so the invalid event ID is reported with the status code
+	evts, lastID, err := dh.controller.GetStatusEvents(lastID, 0)
+	if err != nil {
+		dh.handleError(w, req, http.StatusInternalServerError, serverError(err, errs.GetStatusEventsError))
+		return
+	}
+
+	dh.log(req, http.StatusOK, "start streaming status change events")
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	// Disable the response buffering of the proxies
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+	writeDate(w, []byte(fmt.Sprintf("retry: %d\n\n", eventRetryMillis)))
+
+	deadline := time.Now().Add(eventStreamDuration)
+	for {
+		if err := writeStatusEvents(w, evts, lastID, filter); err != nil {
+			dh.log(req, http.StatusOK, fmt.Sprintf("stop streaming status change events: %s", err))
+			return
+		}
+		flusher.Flush()
+
+		wait := time.Until(deadline)
+		if wait <= 0 {
+			return
+		}
+		if wait > eventWaitInterval {
+			wait = eventWaitInterval
+		}
+
+		select {
+		case <-req.Context().Done():
+			// The client is gone
+			return
+		default:
+		}
+
+		if evts, lastID, err = dh.controller.GetStatusEvents(lastID, wait); err != nil {
+			dh.log(req, http.StatusOK, fmt.Sprintf("stop streaming status change events: %s", err))
+			return
+		}
+	}
+}
+
+// writeStatusEvents writes the matched events, the ID of the last read event is sent at last even if it's
+// filtered out, so the clients resume after it. The keep-alive comment is sent if there are no events.
+func writeStatusEvents(w http.ResponseWriter, evts []*hook.StatusEvent, lastID string, filter *statusEventFilter) error {
+	if len(evts) == 0 {
+		_, err := w.Write([]byte(": keep-alive\n\n"))
+		return err
+	}
+
+	sentID := ""
+	for _, evt := range evts {
+		if evt.Change == nil || !filter.match(evt.Change) {
+			continue
+		}
+
+		data, err := json.Marshal(evt.Change)
+		if err != nil {
+			return err
+		}
+		if _, err := fmt.Fprintf(w, "id: %s\nevent: %s\ndata: %s\n\n", evt.ID, statusChangeEventName, data); err != nil {
+			return err
+		}
+		sentID = evt.ID
+	}
+
+	if sentID != lastID {
+		// The event without data is not dispatched but updates the last event ID of the client
+		if _, err := fmt.Fprintf(w, "id: %s\n\n", lastID); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,191 @@
+package api
+
+import (
+	"context"
+	"github.com/chenxull/goGridhub/gridhub/src/jobservice/api/proto"
+	"github.com/chenxull/goGridhub/gridhub/src/jobservice/auth"
+	"github.com/chenxull/goGridhub/gridhub/src/jobservice/errs"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+	"net/http"
+)
+
+// grpcTargetFunc returns the name of the job targeted by the request message, empty means all the jobs
+type grpcTargetFunc func(gs *GRPCServer, msg interface{}) (string, error)
+
+// grpcPermission is the scope required by the method on the target job
+type grpcPermission struct {
+	scope  string
+	target grpcTargetFunc
+}
+
+var methodPermissions = map[string]*grpcPermission{
+	proto.JobService_LaunchJob_FullMethodName:          {scope: auth.ScopeJobsLaunch, target: grpcLaunchedJob},
+	proto.JobService_GetJob_FullMethodName:             {scope: auth.ScopeJobsRead, target: grpcTrackedJob},
+	proto.JobService_ListJobs_FullMethodName:           {scope: auth.ScopeJobsRead, target: grpcQueriedJob},
+	proto.JobService_StopJob_FullMethodName:            {scope: auth.ScopeJobsStop, target: grpcTrackedJob},
+	proto.JobService_RetryJob_FullMethodName:           {scope: auth.ScopeJobsStop, target: grpcTrackedJob},
+	proto.JobService_StreamJobLog_FullMethodName:       {scope: auth.ScopeJobsRead, target: grpcTrackedJob},
+	proto.JobService_WatchStatusChanges_FullMethodName: {scope: auth.ScopeJobsRead, target: grpcQueriedJob},
+}
+
+// unaryInterceptor authenticates the caller and checks the scope granted to it before handling the call.
+// The launching calls rejected here are audited.
+func (gs *GRPCServer) unaryInterceptor(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	ctx, id, err := gs.authenticate(ctx)
+	if err != nil {
+		gs.auditRejectedLaunch(ctx, info.FullMethod, req, http.StatusUnauthorized, err)
+		return nil, grpcError(err)
+	}
+
+	if ctx, err = gs.authorize(ctx, id, info.FullMethod, req); err != nil {
+		gs.auditRejectedLaunch(ctx, info.FullMethod, req, http.StatusInternalServerError, err)
+		return nil, grpcError(err)
+	}
+
+	return handler(ctx, req)
+}
+
+// streamInterceptor authenticates the caller of the streaming call, the scope is checked when
+// the request message is received as the target job is only known then.
+func (gs *GRPCServer) streamInterceptor(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	ctx, id, err := gs.authenticate(ss.Context())
+	if err != nil {
+		return grpcError(err)
+	}
+
+	return handler(srv, &authorizedStream{
+		ServerStream: ss,
+		server:       gs,
+		identity:     id,
+		method:       info.FullMethod,
+		ctx:          ctx,
+	})
+}
+
+// authenticate authenticates the caller with the 'authorization' metadata by the authenticator of
+// the HTTP API, the identity of the caller is kept in the returned context.
+func (gs *GRPCServer) authenticate(ctx context.Context) (context.Context, *auth.Identity, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, "/", nil)
+	if err != nil {
+		return ctx, nil, err
+	}
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		for _, v := range md.Get(authMetadataKey) {
+			req.Header.Add(authHeader, v)
+		}
+	}
+
+	id, err := gs.authenticator.DoAuth(req)
+	if err != nil {
+		return ctx, nil, errs.UnauthorizedError(err)
+	}
+
+	return context.WithValue(ctx, callerKey, id), id, nil
+}
+
+// authorize checks if the caller is granted the scope required by the method on the job targeted
+// by the request message. The resolved job name is kept in the returned context for the auditing.
+func (gs *GRPCServer) authorize(ctx context.Context, id *auth.Identity, method string, msg interface{}) (context.Context, error) {
+	p, ok := methodPermissions[method]
+	if !ok {
+		return ctx, nil
+	}
+
+	jobName, err := p.target(gs, msg)
+	if err != nil {
+		// Let the handler report the missing job or the bad request
+		if errs.IsObjectNotFoundError(err) || errs.IsBadRequestError(err) {
+			return ctx, nil
+		}
+		return ctx, err
+	}
+
+	ctx = context.WithValue(ctx, targetJobKey, jobName)
+	if !id.Allowed(p.scope, jobName) {
+		return ctx, errs.ForbiddenError(id.Name, p.scope, jobName)
+	}
+
+	return ctx, nil
+}
+
+// auditRejectedLaunch appends the entry of the launching call rejected by the interceptor with the code,
+// e.g: the unauthorized or forbidden ones. Other calls are skipped.
+func (gs *GRPCServer) auditRejectedLaunch(ctx context.Context, method string, msg interface{}, code int, err error) {
+	req, ok := msg.(*proto.LaunchJobRequest)
+	if gs.auditor == nil || method != proto.JobService_LaunchJob_FullMethodName || !ok {
+		return
+	}
+
+	// The parameters are left empty if they're malformed
+	jobReq, _ := fromLaunchJobRequest(req)
+	appendAudit(gs.auditor, callerFromContext(ctx), method, launchEntry(jobReq), code, err)
+}
+
+// authorizedStream checks the scope granted to the caller when receiving the request message
+type authorizedStream struct {
+	grpc.ServerStream
+
+	server   *GRPCServer
+	identity *auth.Identity
+	method   string
+	ctx      context.Context
+}
+
+// Context returns the context with the identity of the caller and the resolved target job
+func (as *authorizedStream) Context() context.Context {
+	return as.ctx
+}
+
+// RecvMsg receives the request message and checks the scope on the job targeted by it
+func (as *authorizedStream) RecvMsg(m interface{}) error {
+	if err := as.ServerStream.RecvMsg(m); err != nil {
+		return err
+	}
+
+	ctx, err := as.server.authorize(as.ctx, as.identity, as.method, m)
+	if err != nil {
+		return grpcError(err)
+	}
+	as.ctx = ctx
+
+	return nil
+}
+
+// grpcLaunchedJob returns the job name in the launching request
+func grpcLaunchedJob(gs *GRPCServer, msg interface{}) (string, error) {
+	if req, ok := msg.(*proto.LaunchJobRequest); ok {
+		return req.GetName(), nil
+	}
+
+	return "", nil
+}
+
+// grpcQueriedJob returns the job name filter of the listing or watching request.
+// The filter is required for the scope restricted to the job name pattern, as
+// only the unrestricted scope is matched with the empty job name.
+func grpcQueriedJob(gs *GRPCServer, msg interface{}) (string, error) {
+	switch req := msg.(type) {
+	case *proto.ListJobsRequest:
+		return req.GetName(), nil
+	case *proto.WatchStatusChangesRequest:
+		return req.GetJobName(), nil
+	}
+
+	return "", nil
+}
+
+// grpcTrackedJob returns the name of the job with the ID in the request
+func grpcTrackedJob(gs *GRPCServer, msg interface{}) (string, error) {
+	req, ok := msg.(interface{ GetJobId() string })
+	if !ok {
+		return "", nil
+	}
+
+	st, err := gs.controller.GetJob(req.GetJobId())
+	if err != nil {
+		return "", err
+	}
+
+	return st.Info.JobName, nil
+}
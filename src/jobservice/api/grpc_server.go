@@ -0,0 +1,554 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/chenxull/goGridhub/gridhub/src/jobservice/api/proto"
+	"github.com/chenxull/goGridhub/gridhub/src/jobservice/audit"
+	"github.com/chenxull/goGridhub/gridhub/src/jobservice/common/query"
+	"github.com/chenxull/goGridhub/gridhub/src/jobservice/config"
+	"github.com/chenxull/goGridhub/gridhub/src/jobservice/core"
+	"github.com/chenxull/goGridhub/gridhub/src/jobservice/errs"
+	"github.com/chenxull/goGridhub/gridhub/src/jobservice/job"
+	"github.com/chenxull/goGridhub/gridhub/src/jobservice/logger"
+	"github.com/chenxull/goGridhub/gridhub/src/jobservice/ratelimit"
+	"github.com/pkg/errors"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"math"
+	"net"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// Key of the metadata carrying the credential of the caller, same as the header of the HTTP API
+	authMetadataKey = "authorization"
+	// Key of the metadata telling the rate limited caller how many seconds to wait
+	retryAfterMetadataKey = "retry-after"
+
+	// Max size of the log chunk sent in the stream
+	logChunkSize = 64 * 1024
+	// How often the log of the running job is read when following it
+	logFollowInterval = time.Second
+	// How long to wait for the running calls before closing them when stopping the server
+	grpcStopTimeout = 15 * time.Second
+)
+
+// GRPCServer serves the job service over gRPC with the same controller, authenticator,
+// rate limiter and audit sink as the HTTP API.
+type GRPCServer struct {
+	proto.UnimplementedJobServiceServer
+
+	grpcServer    *grpc.Server
+	controller    core.Interface
+	authenticator Authenticator
+	// Limit the rate of launching jobs, nil means no limit
+	limiter ratelimit.Limiter
+	// Sink of the audit log of the job actions, nil means no auditing
+	auditor audit.Sink
+
+	config ServerConfig
+	// Closed when stopping the server to end the following streams
+	closing chan struct{}
+}
+
+// NewGRPCServer is constructor of GRPCServer, the TLS is enabled with the certificate if the protocol is https
+func NewGRPCServer(ctl core.Interface, authenticator Authenticator, limiter ratelimit.Limiter, auditor audit.Sink, cfg ServerConfig) (*GRPCServer, error) {
+	gs := &GRPCServer{
+		controller:    ctl,
+		authenticator: authenticator,
+		limiter:       limiter,
+		auditor:       auditor,
+		config:        cfg,
+		closing:       make(chan struct{}),
+	}
+
+	opts := []grpc.ServerOption{
+		grpc.UnaryInterceptor(gs.unaryInterceptor),
+		grpc.StreamInterceptor(gs.streamInterceptor),
+	}
+	if cfg.Protocol == config.JobServiceProtocolHTTPS {
+		creds, err := credentials.NewServerTLSFromFile(cfg.Cert, cfg.Key)
+		if err != nil {
+			return nil, errors.Wrap(err, "load gRPC server certificate")
+		}
+		opts = append(opts, grpc.Creds(creds))
+	}
+
+	gs.grpcServer = grpc.NewServer(opts...)
+	proto.RegisterJobServiceServer(gs.grpcServer, gs)
+
+	return gs, nil
+}
+
+// Start the server to serve requests.
+// Blocking call
+func (gs *GRPCServer) Start() error {
+	defer func() {
+		logger.Info("gRPC server is stopped")
+	}()
+
+	l, err := net.Listen("tcp", fmt.Sprintf(":%d", gs.config.Port))
+	if err != nil {
+		return err
+	}
+
+	return gs.Serve(l)
+}
+
+// Serve the requests accepted by the listener.
+// Blocking call
+func (gs *GRPCServer) Serve(l net.Listener) error {
+	return gs.grpcServer.Serve(l)
+}
+
+// Stop server gracefully, the calls still running after the timeout are closed.
+func (gs *GRPCServer) Stop() {
+	close(gs.closing)
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		gs.grpcServer.GracefulStop()
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(grpcStopTimeout):
+		gs.grpcServer.Stop()
+	}
+}
+
+// LaunchJob implements proto.JobServiceServer
+func (gs *GRPCServer) LaunchJob(ctx context.Context, req *proto.LaunchJobRequest) (*proto.JobStats, error) {
+	jobReq, err := fromLaunchJobRequest(req)
+	if err != nil {
+		gs.auditLaunch(ctx, jobReq, nil, err)
+		return nil, grpcError(err)
+	}
+
+	jobReq.Caller = callerFromContext(ctx)
+	if len(jobReq.IdempotencyKey) > maxIdempotencyKeyLen {
+		err := errs.BadRequestError(errors.Errorf("idempotency key is longer than %d", maxIdempotencyKeyLen))
+		gs.auditLaunch(ctx, jobReq, nil, err)
+		return nil, grpcError(err)
+	}
+
+	if gs.limiter != nil {
+		ok, wait, err := gs.limiter.Take(jobReq.Caller, jobReq.Job.Name)
+		if err == nil && !ok {
+			limitErr := errs.RateLimitedError(jobReq.Caller, jobReq.Job.Name)
+			_ = grpc.SetHeader(ctx, metadata.Pairs(retryAfterMetadataKey, strconv.FormatInt(int64(math.Ceil(wait.Seconds())), 10)))
+			gs.auditLaunch(ctx, jobReq, nil, limitErr)
+			return nil, grpcError(limitErr)
+		}
+	}
+
+	jobStats, err := gs.controller.LaunchJob(jobReq)
+	gs.auditLaunch(ctx, jobReq, jobStats, err)
+	if err != nil {
+		return nil, grpcError(serverError(err, errs.LaunchJobError))
+	}
+
+	return toJobStats(jobStats), nil
+}
+
+// GetJob implements proto.JobServiceServer
+func (gs *GRPCServer) GetJob(ctx context.Context, req *proto.GetJobRequest) (*proto.JobStats, error) {
+	jobStats, err := gs.controller.GetJob(req.GetJobId())
+	if err != nil {
+		return nil, grpcError(serverError(err, errs.GetJobStatsError))
+	}
+
+	return toJobStats(jobStats), nil
+}
+
+// ListJobs implements proto.JobServiceServer
+func (gs *GRPCServer) ListJobs(ctx context.Context, req *proto.ListJobsRequest) (*proto.ListJobsResponse, error) {
+	q := &query.Parameter{
+		PageNumber: 1,
+		PageSize:   query.DefaultPageSize,
+		Extras:     make(query.ExtraParameters),
+	}
+	if req.GetPageNumber() > 1 {
+		q.PageNumber = uint(req.GetPageNumber())
+	}
+	if req.GetPageSize() > 0 {
+		q.PageSize = uint(req.GetPageSize())
+	}
+	if req.GetNonDeadOnly() {
+		q.Extras.Set(query.ExtraParamKeyNonStoppedOnly, true)
+	}
+	if len(req.GetKind()) > 0 {
+		q.Extras.Set(query.ExtraParamKeyKind, req.GetKind())
+	}
+	if len(req.GetName()) > 0 {
+		q.Extras.Set(query.ExtraParamKeyJobName, req.GetName())
+	}
+	if len(req.GetStatus()) > 0 {
+		q.Extras.Set(query.ExtraParamKeyStatus, req.GetStatus())
+	}
+	if req.GetCursor() > 0 {
+		if req.GetCursor() > math.MaxInt64 {
+			return nil, grpcError(errs.BadRequestError(errors.Errorf("invalid cursor: %d", req.GetCursor())))
+		}
+		q.Extras.Set(query.ExtraParamKeyCursor, int64(req.GetCursor()))
+	}
+
+	jobs, total, err := gs.controller.GetJobs(q)
+	if err != nil {
+		return nil, grpcError(serverError(err, func(err error) error {
+			return errs.GetJobsError(q, err)
+		}))
+	}
+
+	res := &proto.ListJobsResponse{
+		Jobs: make([]*proto.JobStats, 0, len(jobs)),
+	}
+	for _, st := range jobs {
+		res.Jobs = append(res.Jobs, toJobStats(st))
+	}
+
+	// The jobs are scanned with the cursor except the scheduled ones
+	if req.GetKind() == job.KindScheduled {
+		res.Total = total
+	} else {
+		res.NextCursor = uint64(total)
+	}
+
+	return res, nil
+}
+
+// StopJob implements proto.JobServiceServer
+func (gs *GRPCServer) StopJob(ctx context.Context, req *proto.JobActionRequest) (*proto.Empty, error) {
+	err := gs.controller.StopJob(req.GetJobId())
+	gs.auditAction(ctx, audit.ActionStop, req.GetJobId(), err)
+	if err != nil {
+		return nil, grpcError(serverError(err, errs.StopJobError))
+	}
+
+	return &proto.Empty{}, nil
+}
+
+// RetryJob implements proto.JobServiceServer
+func (gs *GRPCServer) RetryJob(ctx context.Context, req *proto.JobActionRequest) (*proto.Empty, error) {
+	err := gs.controller.RetryJob(req.GetJobId())
+	gs.auditAction(ctx, audit.ActionRetry, req.GetJobId(), err)
+	if err != nil {
+		return nil, grpcError(serverError(err, errs.RetryJobError))
+	}
+
+	return &proto.Empty{}, nil
+}
+
+// StreamJobLog implements proto.JobServiceServer.
+// The appended log of the running job is sent until the job is finished if it's followed,
+// the log not existing yet is treated as the empty one while following.
+func (gs *GRPCServer) StreamJobLog(req *proto.StreamJobLogRequest, stream grpc.ServerStreamingServer[proto.LogChunk]) error {
+	jobID := req.GetJobId()
+	if strings.Contains(jobID, "..") || strings.ContainsRune(jobID, os.PathSeparator) {
+		return grpcError(errs.BadRequestError(errors.Errorf("invalid Job ID: %s", jobID)))
+	}
+
+	sent := 0
+	for {
+		// Check if the job is finished before reading the log, so the log written before finishing is all read
+		finished := true
+		if req.GetFollow() {
+			st, err := gs.controller.GetJob(jobID)
+			if err != nil {
+				return grpcError(serverError(err, errs.GetJobStatsError))
+			}
+			finished = job.Status(st.Info.Status).Final()
+		}
+
+		logData, err := gs.controller.GetJobLogData(jobID)
+		if err != nil && !(req.GetFollow() && errs.IsObjectNotFoundError(err)) {
+			return grpcError(serverError(err, errs.GetJobLogError))
+		}
+
+		for sent < len(logData) {
+			end := sent + logChunkSize
+			if end > len(logData) {
+				end = len(logData)
+			}
+			if err := stream.Send(&proto.LogChunk{Data: logData[sent:end]}); err != nil {
+				return err
+			}
+			sent = end
+		}
+
+		if finished {
+			return nil
+		}
+
+		select {
+		case <-stream.Context().Done():
+			return stream.Context().Err()
+		case <-gs.closing:
+			return status.Error(codes.Unavailable, "server is stopping")
+		case <-time.After(logFollowInterval):
+		}
+	}
+}
+
+// WatchStatusChanges implements proto.JobServiceServer.
+// The status changes are streamed until the client is gone, the stream is resumed after the event with the last event ID.
+func (gs *GRPCServer) WatchStatusChanges(req *proto.WatchStatusChangesRequest, stream grpc.ServerStreamingServer[proto.StatusChange]) error {
+	filter := &statusEventFilter{
+		jobID:         req.GetJobId(),
+		jobName:       req.GetJobName(),
+		upstreamJobID: req.GetUpstreamJobId(),
+	}
+
+	// Read the missed events before streaming, so the invalid event ID is reported with the status code
+	evts, lastID, err := gs.controller.GetStatusEvents(strings.TrimSpace(req.GetLastEventId()), 0)
+	if err != nil {
+		return grpcError(serverError(err, errs.GetStatusEventsError))
+	}
+
+	for {
+		for _, evt := range evts {
+			if evt.Change == nil || !filter.match(evt.Change) {
+				continue
+			}
+
+			change := &proto.StatusChange{
+				EventId:  evt.ID,
+				JobId:    evt.Change.JobID,
+				Status:   evt.Change.Status,
+				CheckIn:  evt.Change.CheckIn,
+				Metadata: toJobStats(&job.Stats{Info: evt.Change.Metadata}),
+			}
+			if err := stream.Send(change); err != nil {
+				return err
+			}
+		}
+
+		select {
+		case <-stream.Context().Done():
+			return stream.Context().Err()
+		case <-gs.closing:
+			return status.Error(codes.Unavailable, "server is stopping")
+		default:
+		}
+
+		if evts, lastID, err = gs.controller.GetStatusEvents(lastID, eventWaitInterval); err != nil {
+			return grpcError(serverError(err, errs.GetStatusEventsError))
+		}
+	}
+}
+
+// auditLaunch appends the entry of launching the job, the ID of the existing job is kept
+// if the same job is launched before
+func (gs *GRPCServer) auditLaunch(ctx context.Context, jobReq *job.Request, jobStats *job.Stats, err error) {
+	if gs.auditor == nil {
+		return
+	}
+
+	e := launchEntry(jobReq)
+	if jobStats != nil && jobStats.Info != nil {
+		e.JobID = jobStats.Info.JobID
+	} else if existing, ok := errs.ConflictData(err).(*job.Stats); ok && existing.Info != nil {
+		e.JobID = existing.Info.JobID
+	}
+
+	appendAudit(gs.auditor, callerFromContext(ctx), proto.JobService_LaunchJob_FullMethodName, e, http.StatusAccepted, err)
+}
+
+// auditAction appends the entry of the action on the tracked job.
+// The job name resolved for checking the permission is reused if it's there.
+func (gs *GRPCServer) auditAction(ctx context.Context, action string, jobID string, err error) {
+	if gs.auditor == nil {
+		return
+	}
+
+	jobName, ok := targetJobFromContext(ctx)
+	if !ok {
+		if st, gErr := gs.controller.GetJob(jobID); gErr == nil && st.Info != nil {
+			jobName = st.Info.JobName
+		}
+	}
+
+	method, _ := grpc.Method(ctx)
+	appendAudit(gs.auditor, callerFromContext(ctx), method, &audit.Entry{Action: action, JobID: jobID, JobName: jobName}, http.StatusNoContent, err)
+}
+
+// callerFromContext returns the name of the authenticated caller kept in the context
+func callerFromContext(ctx context.Context) string {
+	if id := identityFromContext(ctx); id != nil {
+		return id.Name
+	}
+
+	return ""
+}
+
+// grpcError converts the error of the errs package to the status error with the mapped code,
+// the errs code is attached to the message in the form of 'code=<code>'
+func grpcError(err error) error {
+	problem := errs.NewProblem(err, http.StatusInternalServerError, "")
+
+	code := codes.Internal
+	switch problem.Status {
+	case http.StatusBadRequest:
+		code = codes.InvalidArgument
+	case http.StatusUnauthorized:
+		code = codes.Unauthenticated
+	case http.StatusForbidden:
+		code = codes.PermissionDenied
+	case http.StatusNotFound:
+		code = codes.NotFound
+	case http.StatusConflict:
+		code = codes.AlreadyExists
+		if problem.Code == errs.StatusMismatchErrorCode {
+			code = codes.FailedPrecondition
+		}
+	case http.StatusTooManyRequests:
+		code = codes.ResourceExhausted
+	case http.StatusNotImplemented:
+		code = codes.Unimplemented
+	case http.StatusServiceUnavailable:
+		code = codes.Unavailable
+	}
+
+	msg := problem.Title
+	if len(problem.Detail) > 0 {
+		msg = fmt.Sprintf("%s: %s", msg, problem.Detail)
+	}
+
+	return status.Errorf(code, "%s code=%d", msg, problem.Code)
+}
+
+// fromLaunchJobRequest converts the launching request to the one of the controller,
+// the request with the job name only is returned with the error for auditing.
+func fromLaunchJobRequest(req *proto.LaunchJobRequest) (*job.Request, error) {
+	jobReq := &job.Request{
+		Job: &job.RequestBody{
+			Name:       req.GetName(),
+			StatusHook: req.GetStatusHook(),
+		},
+		IdempotencyKey: strings.TrimSpace(req.GetIdempotencyKey()),
+	}
+
+	if len(req.GetParameters()) > 0 {
+		params := make(job.Parameters)
+		if err := json.Unmarshal(req.GetParameters(), &params); err != nil {
+			return jobReq, errs.BadRequestError(errors.Wrap(err, "parameters should be a JSON object"))
+		}
+		jobReq.Job.Parameters = params
+	}
+
+	if m := req.GetMetadata(); m != nil {
+		jobReq.Job.Metadata = &job.Metadata{
+			JobKind:       m.GetKind(),
+			ScheduleDelay: m.GetScheduleDelay(),
+			Cron:          m.GetCronSpec(),
+			IsUnique:      m.GetUnique(),
+			Priority:      m.GetPriority(),
+			UniqueKeys:    m.GetUniqueKeys(),
+			UniqueTTL:     m.GetUniqueTtl(),
+			SecretKeys:    m.GetSecretKeys(),
+			Schedule:      fromScheduleSpec(m.GetSchedule()),
+		}
+	}
+
+	return jobReq, nil
+}
+
+func fromScheduleSpec(spec *proto.ScheduleSpec) *job.ScheduleSpec {
+	if spec == nil {
+		return nil
+	}
+
+	s := &job.ScheduleSpec{
+		RunList: spec.GetRunList(),
+	}
+	if i := spec.GetInterval(); i != nil {
+		s.Interval = &job.IntervalSpec{
+			EverySeconds: i.GetEverySeconds(),
+			StartAt:      i.GetStartAt(),
+		}
+	}
+	for _, b := range spec.GetBlackouts() {
+		s.Blackouts = append(s.Blackouts, &job.BlackoutWindow{
+			Start:           b.GetStart(),
+			DurationSeconds: b.GetDurationSeconds(),
+			TimeZone:        b.GetTimeZone(),
+		})
+	}
+
+	return s
+}
+
+// toJobStats converts the job stats with the secret parameters redacted, nil is returned for the empty stats
+func toJobStats(st *job.Stats) *proto.JobStats {
+	if st == nil || st.Info == nil {
+		return nil
+	}
+
+	info := st.Redacted().Info
+	ps := &proto.JobStats{
+		Id:              info.JobID,
+		Status:          info.Status,
+		Name:            info.JobName,
+		Kind:            info.JobKind,
+		Unique:          info.IsUnique,
+		RefLink:         info.RefLink,
+		CronSpec:        info.CronSpec,
+		EnqueueTime:     info.EnqueueTime,
+		UpdateTime:      info.UpdateTime,
+		RunAt:           info.RunAt,
+		CheckIn:         info.CheckIn,
+		CheckInAt:       info.CheckInAt,
+		DieAt:           info.DieAt,
+		WebHookUrl:      info.WebHookURL,
+		UpstreamJobId:   info.UpstreamJobID,
+		NumericPolicyId: info.NumericPID,
+		Revision:        info.Revision,
+		NodeId:          info.NodeID,
+		Schedule:        toScheduleSpec(info.Schedule),
+	}
+	if len(info.Parameters) > 0 {
+		if data, err := json.Marshal(info.Parameters); err == nil {
+			ps.Parameters = data
+		}
+	}
+
+	return ps
+}
+
+func toScheduleSpec(spec *job.ScheduleSpec) *proto.ScheduleSpec {
+	if spec == nil {
+		return nil
+	}
+
+	s := &proto.ScheduleSpec{
+		RunList: spec.RunList,
+	}
+	if spec.Interval != nil {
+		s.Interval = &proto.ScheduleSpec_Interval{
+			EverySeconds: spec.Interval.EverySeconds,
+			StartAt:      spec.Interval.StartAt,
+		}
+	}
+	for _, b := range spec.Blackouts {
+		if b == nil {
+			continue
+		}
+		s.Blackouts = append(s.Blackouts, &proto.ScheduleSpec_BlackoutWindow{
+			Start:           b.Start,
+			DurationSeconds: b.DurationSeconds,
+			TimeZone:        b.TimeZone,
+		})
+	}
+
+	return s
+}
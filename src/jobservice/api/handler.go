@@ -3,12 +3,15 @@ package api
 import (
 	"encoding/json"
 	"fmt"
+	"github.com/chenxull/goGridhub/gridhub/src/jobservice/audit"
+	"github.com/chenxull/goGridhub/gridhub/src/jobservice/auth"
 	"github.com/chenxull/goGridhub/gridhub/src/jobservice/common/query"
 	"github.com/chenxull/goGridhub/gridhub/src/jobservice/common/utils"
 	"github.com/chenxull/goGridhub/gridhub/src/jobservice/core"
 	"github.com/chenxull/goGridhub/gridhub/src/jobservice/errs"
 	"github.com/chenxull/goGridhub/gridhub/src/jobservice/job"
 	"github.com/chenxull/goGridhub/gridhub/src/jobservice/logger"
+	"github.com/chenxull/goGridhub/gridhub/src/jobservice/node"
 	"github.com/gorilla/mux"
 	"github.com/pkg/errors"
 	"io/ioutil"
@@ -19,8 +22,12 @@ import (
 )
 
 const (
-	totalHeaderKey = "Total-Count"
-	nextCursorKey  = "Next-Cursor"
+	totalHeaderKey       = "Total-Count"
+	nextCursorKey        = "Next-Cursor"
+	idempotencyHeaderKey = "Idempotency-Key"
+
+	// Max length of the idempotency key
+	maxIdempotencyKeyLen = 255
 )
 
 // Handler defines approaches to handle the http requests.
@@ -43,6 +50,39 @@ type Handler interface {
 
 	// HandleGetJobsReq is used to handle the request of getting jobs
 	HandleGetJobsReq(w http.ResponseWriter, req *http.Request)
+
+	// HandleGetScheduledJobsReq is used to handle the request of getting the scheduled jobs
+	HandleGetScheduledJobsReq(w http.ResponseWriter, req *http.Request)
+
+	// HandleScheduledJobActionReq is used to handle the scheduled job action requests (reschedule/run/cancel).
+	HandleScheduledJobActionReq(w http.ResponseWriter, req *http.Request)
+
+	// HandleGetArchivedJobsReq is used to handle the request of getting the archived jobs
+	HandleGetArchivedJobsReq(w http.ResponseWriter, req *http.Request)
+
+	// HandleGetArchivedJobReq is used to handle the request of getting the archived job
+	HandleGetArchivedJobReq(w http.ResponseWriter, req *http.Request)
+
+	// HandleGetJobTypesReq is used to handle the request of getting the registered job types
+	HandleGetJobTypesReq(w http.ResponseWriter, req *http.Request)
+
+	// HandleGetNodesReq is used to handle the request of getting the nodes of the cluster
+	HandleGetNodesReq(w http.ResponseWriter, req *http.Request)
+
+	// HandleNodeActionReq is used to handle the node action requests (fail/requeue the orphaned jobs).
+	HandleNodeActionReq(w http.ResponseWriter, req *http.Request)
+
+	// HandleStatusEventsReq is used to handle the request of streaming the status change events
+	HandleStatusEventsReq(w http.ResponseWriter, req *http.Request)
+
+	// HandleGetAuditLogsReq is used to handle the request of getting the audit logs of the job actions
+	HandleGetAuditLogsReq(w http.ResponseWriter, req *http.Request)
+
+	// HandleExportPoliciesReq is used to handle the request of exporting the periodic job policies
+	HandleExportPoliciesReq(w http.ResponseWriter, req *http.Request)
+
+	// HandleImportPoliciesReq is used to handle the request of importing the periodic job policies
+	HandleImportPoliciesReq(w http.ResponseWriter, req *http.Request)
 }
 
 func writeDate(w http.ResponseWriter, byte []byte) {
@@ -54,12 +94,15 @@ func writeDate(w http.ResponseWriter, byte []byte) {
 // DefaultHandler is the default request handler which implements the Handler interface.
 type DefaultHandler struct {
 	controller core.Interface
+	// Sink of the audit log, nil means no auditing
+	auditor audit.Sink
 }
 
 // NewDefaultHandler is constructor of DefaultHandler.
-func NewDefaultHandler(ctl core.Interface) *DefaultHandler {
+func NewDefaultHandler(ctl core.Interface, auditor audit.Sink) *DefaultHandler {
 	return &DefaultHandler{
 		controller: ctl,
+		auditor:    auditor,
 	}
 }
 
@@ -73,22 +116,34 @@ func (dh *DefaultHandler) HandlerLaunchJobReq(w http.ResponseWriter, req *http.R
 	//unmarshal data
 	jobReq := &job.Request{}
 	if err = json.Unmarshal(data, jobReq); err != nil {
-		dh.handleError(w, req, http.StatusInternalServerError, errs.HandleJSONDataError(err))
+		dh.handleError(w, req, http.StatusBadRequest, errs.HandleJSONDataError(err))
 		return
 	}
+
+	jobReq.Caller = CallerFromRequest(req)
+	jobReq.IdempotencyKey = strings.TrimSpace(req.Header.Get(idempotencyHeaderKey))
+	if len(jobReq.IdempotencyKey) > maxIdempotencyKeyLen {
+		dh.handleError(w, req, http.StatusBadRequest, errs.BadRequestError(errors.Errorf("'%s' is longer than %d", idempotencyHeaderKey, maxIdempotencyKeyLen)))
+		return
+	}
+
 	// Pass request to the controller for the follow-up.
 	jobStats, err := dh.controller.LaunchJob(jobReq)
+	dh.auditLaunch(req, jobReq, jobStats, err)
 
 	if err != nil {
-		code := http.StatusInternalServerError
-		if errs.IsBadRequestError(err) {
-			code = http.StatusBadRequest
-		} else if errs.IsConflictError(err) {
-			code = http.StatusConflict
-		} else {
-			err = errs.LaunchJobError(err)
+		// Return the existing job if it's attached
+		if existing := errs.ConflictData(err); existing != nil {
+			if st, ok := existing.(*job.Stats); ok {
+				existing = st.Redacted()
+			}
+			problem := errs.NewProblem(err, http.StatusConflict, req.URL.Path)
+			problem.Existing = existing
+			logger.Errorf("Serve http request '%s %s' error: %d %s", req.Method, req.URL.String(), problem.Status, err.Error())
+			writeProblem(w, problem)
+			return
 		}
-		dh.handleError(w, req, code, err)
+		dh.handleError(w, req, http.StatusInternalServerError, serverError(err, errs.LaunchJobError))
 		return
 	}
 	dh.handleJSONData(w, req, http.StatusAccepted, jobStats)
@@ -100,15 +155,7 @@ func (dh *DefaultHandler) HandleGetJobReq(w http.ResponseWriter, req *http.Reque
 	jobID := vars["job_id"]
 	jobStats, err := dh.controller.GetJob(jobID)
 	if err != nil {
-		code := http.StatusInternalServerError
-		if errs.IsObjectNotFoundError(err) {
-			code = http.StatusNotFound
-		} else if errs.IsBadRequestError(err) {
-			code = http.StatusBadRequest
-		} else {
-			err = errs.GetJobStatsError(err)
-		}
-		dh.handleError(w, req, code, err)
+		dh.handleError(w, req, http.StatusInternalServerError, serverError(err, errs.GetJobStatsError))
 		return
 	}
 	dh.handleJSONData(w, req, http.StatusOK, jobStats)
@@ -128,28 +175,29 @@ func (dh *DefaultHandler) HandleJobActionReq(w http.ResponseWriter, req *http.Re
 	// unmarshal data
 	jobActionReq := &job.ActionRequest{}
 	if err = json.Unmarshal(data, jobActionReq); err != nil {
-		dh.handleError(w, req, http.StatusInternalServerError, errs.HandleJSONDataError(err))
+		dh.handleError(w, req, http.StatusBadRequest, errs.HandleJSONDataError(err))
 		return
 	}
 
-	// Only support stop command now
+	// Support stop and retry commands
 	cmd := job.OPCommand(jobActionReq.Action)
-	if !cmd.IsStop() {
-		dh.handleError(w, req, http.StatusNotImplemented, errs.UnknownActionNameError(errors.Errorf("command: %s", jobActionReq.Action)))
-		return
-	}
-
-	// Stop job
-	if err := dh.controller.StopJob(jobID); err != nil {
-		code := http.StatusInternalServerError
-		if errs.IsObjectNotFoundError(err) {
-			code = http.StatusNotFound
-		} else if errs.IsBadRequestError(err) {
-			code = http.StatusBadRequest
-		} else {
-			err = errs.StopJobError(err)
+	switch {
+	case cmd.IsStop():
+		err := dh.controller.StopJob(jobID)
+		dh.auditAction(req, audit.ActionStop, jobID, err)
+		if err != nil {
+			dh.handleError(w, req, http.StatusInternalServerError, serverError(err, errs.StopJobError))
+			return
+		}
+	case cmd.IsRetry():
+		err := dh.controller.RetryJob(jobID)
+		dh.auditAction(req, audit.ActionRetry, jobID, err)
+		if err != nil {
+			dh.handleError(w, req, http.StatusInternalServerError, serverError(err, errs.RetryJobError))
+			return
 		}
-		dh.handleError(w, req, code, err)
+	default:
+		dh.handleError(w, req, http.StatusNotImplemented, errs.UnknownActionNameError(errors.Errorf("command: %s", jobActionReq.Action)))
 		return
 	}
 
@@ -173,21 +221,13 @@ func (dh *DefaultHandler) HandleJobLogReq(w http.ResponseWriter, req *http.Reque
 	jobID := vars["job_id"]
 
 	if strings.Contains(jobID, "..") || strings.ContainsRune(jobID, os.PathSeparator) {
-		dh.handleError(w, req, http.StatusBadRequest, errors.Errorf("invalid Job ID: %s", jobID))
+		dh.handleError(w, req, http.StatusBadRequest, errs.BadRequestError(errors.Errorf("invalid Job ID: %s", jobID)))
 		return
 	}
 
 	logData, err := dh.controller.GetJobLogData(jobID)
 	if err != nil {
-		code := http.StatusInternalServerError
-		if errs.IsObjectNotFoundError(err) {
-			code = http.StatusNotFound
-		} else if errs.IsBadRequestError(err) {
-			code = http.StatusBadRequest
-		} else {
-			err = errs.GetJobLogError(err)
-		}
-		dh.handleError(w, req, code, err)
+		dh.handleError(w, req, http.StatusInternalServerError, serverError(err, errs.GetJobLogError))
 		return
 	}
 
@@ -207,15 +247,7 @@ func (dh *DefaultHandler) HandlePeriodicExecutions(w http.ResponseWriter, req *h
 
 	executions, total, err := dh.controller.GetPeriodicExecutions(jobID, q)
 	if err != nil {
-		code := http.StatusInternalServerError
-		if errs.IsObjectNotFoundError(err) {
-			code = http.StatusNotFound
-		} else if errs.IsBadRequestError(err) {
-			code = http.StatusBadRequest
-		} else {
-			err = errs.GetPeriodicExecutionError(err)
-		}
-		dh.handleError(w, req, code, err)
+		dh.handleError(w, req, http.StatusInternalServerError, serverError(err, errs.GetPeriodicExecutionError))
 		return
 	}
 
@@ -223,15 +255,189 @@ func (dh *DefaultHandler) HandlePeriodicExecutions(w http.ResponseWriter, req *h
 	dh.handleJSONData(w, req, http.StatusOK, executions)
 }
 
+// HandleGetScheduledJobsReq is implementation of method defined in interface 'Handler'
+func (dh *DefaultHandler) HandleGetScheduledJobsReq(w http.ResponseWriter, req *http.Request) {
+	q := extractQuery(req)
+	jobs, total, err := dh.controller.GetScheduledJobs(q)
+	if err != nil {
+		dh.handleError(w, req, http.StatusInternalServerError, serverError(err, func(err error) error {
+			return errs.GetJobsError(q, err)
+		}))
+		return
+	}
+
+	w.Header().Add(totalHeaderKey, fmt.Sprintf("%d", total))
+	dh.handleJSONData(w, req, http.StatusOK, jobs)
+}
+
+// HandleScheduledJobActionReq is implementation of method defined in interface 'Handler'
+func (dh *DefaultHandler) HandleScheduledJobActionReq(w http.ResponseWriter, req *http.Request) {
+	vars := mux.Vars(req)
+	jobID := vars["job_id"]
+
+	data, err := ioutil.ReadAll(req.Body)
+	if err != nil {
+		dh.handleError(w, req, http.StatusInternalServerError, errs.ReadRequestBodyError(err))
+		return
+	}
+
+	// unmarshal data
+	actionReq := &job.ScheduleActionRequest{}
+	if err = json.Unmarshal(data, actionReq); err != nil {
+		dh.handleError(w, req, http.StatusBadRequest, errs.HandleJSONDataError(err))
+		return
+	}
+
+	switch actionReq.Action {
+	case job.ScheduleActionReschedule, job.ScheduleActionRun, job.ScheduleActionCancel:
+	default:
+		dh.handleError(w, req, http.StatusNotImplemented, errs.UnknownActionNameError(errors.Errorf("action: %s", actionReq.Action)))
+		return
+	}
+
+	stats, err := dh.controller.ScheduledJobAction(jobID, actionReq)
+	entry := &audit.Entry{
+		Action:         actionReq.Action,
+		JobID:          jobID,
+		ParametersHash: audit.HashParameters(actionReq),
+	}
+	if stats != nil && stats.Info != nil {
+		entry.JobName = stats.Info.JobName
+	} else {
+		entry.JobName, _ = targetJobFromRequest(req)
+	}
+	dh.audit(req, entry, http.StatusOK, err)
+	if err != nil {
+		dh.handleError(w, req, http.StatusInternalServerError, serverError(err, errs.ScheduledJobActionError))
+		return
+	}
+
+	dh.handleJSONData(w, req, http.StatusOK, stats)
+}
+
+// HandleGetArchivedJobsReq is implementation of method defined in interface 'Handler'
+func (dh *DefaultHandler) HandleGetArchivedJobsReq(w http.ResponseWriter, req *http.Request) {
+	records, total, err := dh.controller.GetArchivedJobs(extractQuery(req))
+	if err != nil {
+		dh.handleError(w, req, http.StatusInternalServerError, serverError(err, errs.GetArchivedJobsError))
+		return
+	}
+
+	w.Header().Add(totalHeaderKey, fmt.Sprintf("%d", total))
+	dh.handleJSONData(w, req, http.StatusOK, records)
+}
+
+// HandleGetArchivedJobReq is implementation of method defined in interface 'Handler'
+func (dh *DefaultHandler) HandleGetArchivedJobReq(w http.ResponseWriter, req *http.Request) {
+	vars := mux.Vars(req)
+	jobID := vars["job_id"]
+
+	record, err := dh.controller.GetArchivedJob(jobID)
+	if err != nil {
+		dh.handleError(w, req, http.StatusInternalServerError, serverError(err, errs.GetArchivedJobsError))
+		return
+	}
+
+	dh.handleJSONData(w, req, http.StatusOK, record)
+}
+
+// HandleGetJobTypesReq is implementation of method defined in interface 'Handler'
+func (dh *DefaultHandler) HandleGetJobTypesReq(w http.ResponseWriter, req *http.Request) {
+	types, err := dh.controller.GetJobTypes()
+	if err != nil {
+		dh.handleError(w, req, http.StatusInternalServerError, errs.GetJobTypesError(err))
+		return
+	}
+
+	// Only the job types granted to the caller are listed
+	if id := IdentityFromRequest(req); id != nil {
+		granted := make([]*job.TypeInfo, 0, len(types))
+		for _, t := range types {
+			if id.Allowed(auth.ScopeJobsRead, t.Name) {
+				granted = append(granted, t)
+			}
+		}
+		types = granted
+	}
+
+	dh.handleJSONData(w, req, http.StatusOK, types)
+}
+
+// HandleGetNodesReq is implementation of method defined in interface 'Handler'
+func (dh *DefaultHandler) HandleGetNodesReq(w http.ResponseWriter, req *http.Request) {
+	nodes, err := dh.controller.GetNodes()
+	if err != nil {
+		dh.handleError(w, req, http.StatusInternalServerError, errs.GetNodesError(err))
+		return
+	}
+
+	dh.handleJSONData(w, req, http.StatusOK, nodes)
+}
+
+// HandleNodeActionReq is implementation of method defined in interface 'Handler'
+func (dh *DefaultHandler) HandleNodeActionReq(w http.ResponseWriter, req *http.Request) {
+	vars := mux.Vars(req)
+	nodeID := vars["node_id"]
+
+	data, err := ioutil.ReadAll(req.Body)
+	if err != nil {
+		dh.handleError(w, req, http.StatusInternalServerError, errs.ReadRequestBodyError(err))
+		return
+	}
+
+	// unmarshal data
+	actionReq := &node.ActionRequest{}
+	if err = json.Unmarshal(data, actionReq); err != nil {
+		dh.handleError(w, req, http.StatusBadRequest, errs.HandleJSONDataError(err))
+		return
+	}
+
+	res, err := dh.controller.EvictNode(nodeID, actionReq.Action)
+	if err != nil {
+		dh.handleError(w, req, http.StatusInternalServerError, serverError(err, errs.NodeActionError))
+		return
+	}
+
+	dh.handleJSONData(w, req, http.StatusOK, res)
+}
+
 func (dh *DefaultHandler) log(req *http.Request, code int, text string) {
 	logger.Debugf("Serve http request '%s %s': %d %s", req.Method, req.URL.String(), code, text)
 }
 func (dh *DefaultHandler) handleError(w http.ResponseWriter, req *http.Request, code int, err error) {
+	writeError(w, req, code, err)
+}
+
+// writeError logs the error and writes it as the problem details, the status of the known client
+// side errors overrides the given code
+func writeError(w http.ResponseWriter, req *http.Request, code int, err error) {
+	problem := errs.NewProblem(err, code, req.URL.Path)
 	// Log all errors
-	logger.Errorf("Serve http request '%s %s' error: %d %s", req.Method, req.URL.String(), code, err.Error())
+	logger.Errorf("Serve http request '%s %s' error: %d %s", req.Method, req.URL.String(), problem.Status, err.Error())
 
-	w.WriteHeader(code)
-	writeDate(w, []byte(err.Error()))
+	writeProblem(w, problem)
+}
+
+// serverError wraps the error unless it's the client side error which keeps its own status and code
+func serverError(err error, wrap func(error) error) error {
+	if errs.HTTPStatus(err, 0) > 0 {
+		return err
+	}
+
+	return wrap(err)
+}
+
+func writeProblem(w http.ResponseWriter, problem *errs.Problem) {
+	data, err := json.Marshal(problem)
+	if err != nil {
+		// Drop the attached resource which fails the marshaling
+		problem.Existing = nil
+		data, _ = json.Marshal(problem)
+	}
+
+	w.Header().Set(http.CanonicalHeaderKey("content-type"), errs.ProblemContentType)
+	w.WriteHeader(problem.Status)
+	writeDate(w, data)
 }
 
 func (dh *DefaultHandler) handleJSONData(w http.ResponseWriter, req *http.Request, code int, object interface{}) {
@@ -290,6 +496,25 @@ func extractQuery(req *http.Request) *query.Parameter {
 		q.Extras.Set(query.ExtraParamKeyKind, jobKind)
 	}
 
+	// Extra job name and status query params
+	if name := queries.Get(query.ParamKeyJobName); !utils.IsEmptyStr(name) {
+		q.Extras.Set(query.ExtraParamKeyJobName, name)
+	}
+	if status := queries.Get(query.ParamKeyStatus); !utils.IsEmptyStr(status) {
+		q.Extras.Set(query.ExtraParamKeyStatus, status)
+	}
+
+	// Extra audit log query params
+	if caller := queries.Get(query.ParamKeyCaller); !utils.IsEmptyStr(caller) {
+		q.Extras.Set(query.ExtraParamKeyCaller, caller)
+	}
+	if action := queries.Get(query.ParamKeyAction); !utils.IsEmptyStr(action) {
+		q.Extras.Set(query.ExtraParamKeyAction, action)
+	}
+	if jobID := queries.Get(query.ParamKeyJobID); !utils.IsEmptyStr(jobID) {
+		q.Extras.Set(query.ExtraParamKeyJobID, jobID)
+	}
+
 	// Extra query cursor
 	cursorV := queries.Get(query.ParamKeyCursor)
 	if !utils.IsEmptyStr(cursorV) {
@@ -300,6 +525,26 @@ func extractQuery(req *http.Request) *query.Parameter {
 
 	return q
 }
+
+// HandleGetJobsReq is implementation of method defined in interface 'Handler'
 func (dh *DefaultHandler) HandleGetJobsReq(w http.ResponseWriter, req *http.Request) {
-	panic("implement me")
+	q := extractQuery(req)
+	jobs, total, err := dh.controller.GetJobs(q)
+	if err != nil {
+		dh.handleError(w, req, http.StatusInternalServerError, serverError(err, func(err error) error {
+			return errs.GetJobsError(q, err)
+		}))
+		return
+	}
+
+	// The jobs are scanned with the cursor except the scheduled ones
+	key := nextCursorKey
+	if v, ok := q.Extras.Get(query.ExtraParamKeyKind); ok {
+		if kind, ok := v.(string); ok && kind == job.KindScheduled {
+			key = totalHeaderKey
+		}
+	}
+
+	w.Header().Add(key, fmt.Sprintf("%d", total))
+	dh.handleJSONData(w, req, http.StatusOK, jobs)
 }
@@ -0,0 +1,51 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"github.com/chenxull/goGridhub/gridhub/src/jobservice/errs"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestWriteError(t *testing.T) {
+	cases := []struct {
+		name   string
+		code   int
+		err    error
+		status int
+		typ    string
+		title  string
+	}{
+		{name: "not found", code: http.StatusInternalServerError, err: errs.NoObjectFoundError("job-1"), status: http.StatusNotFound, typ: "urn:jobservice:error:not_found", title: "object is not found"},
+		{name: "wrapped server error", code: http.StatusInternalServerError, err: serverError(errors.New("redis is down"), errs.StopJobError), status: http.StatusInternalServerError, typ: "urn:jobservice:error:stop_job", title: "stop job failed with error"},
+		{name: "client error not wrapped", code: http.StatusInternalServerError, err: serverError(errs.BadRequestError("missing name"), errs.StopJobError), status: http.StatusBadRequest, typ: "urn:jobservice:error:bad_request", title: "bad request"},
+		{name: "unknown error", code: http.StatusInternalServerError, err: errors.New("boom"), status: http.StatusInternalServerError, typ: "urn:jobservice:error:internal", title: "Internal Server Error"},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			writeError(rec, httptest.NewRequest(http.MethodPost, "/api/v1/jobs/job-1", nil), c.code, c.err)
+
+			if rec.Code != c.status {
+				t.Errorf("expect status %d but got %d", c.status, rec.Code)
+			}
+			if ct := rec.Header().Get("Content-Type"); ct != errs.ProblemContentType {
+				t.Errorf("expect content type %s but got %s", errs.ProblemContentType, ct)
+			}
+
+			problem := &errs.Problem{}
+			if err := json.Unmarshal(rec.Body.Bytes(), problem); err != nil {
+				t.Fatalf("unmarshal problem error: %s", err)
+			}
+			if problem.Type != c.typ || problem.Title != c.title || problem.Status != c.status {
+				t.Errorf("expect %s %q %d but got %s %q %d", c.typ, c.title, c.status, problem.Type, problem.Title, problem.Status)
+			}
+			if problem.Instance != "/api/v1/jobs/job-1" {
+				t.Errorf("expect the request path as the instance but got %s", problem.Instance)
+			}
+		})
+	}
+}
@@ -0,0 +1,1007 @@
+package api
+
+import (
+	"net/http"
+)
+
+// openAPISpec is the OpenAPI document of the v2 API, served at /api/v2/openapi.yaml.
+// The typed client in common/job follows it, keep them in sync when changing the routes.
+const openAPISpec = `openapi: 3.0.3
+info:
+  title: Jobservice API
+  description: The API of the job service for launching, tracking and managing the jobs.
+  version: "2.0"
+servers:
+  - url: /api/v2
+security:
+  - secret: []
+  - bearer: []
+tags:
+  - name: jobs
+  - name: scheduled-jobs
+  - name: archived-jobs
+  - name: audit
+  - name: periodic-policies
+  - name: system
+paths:
+  /jobs:
+    post:
+      tags: [jobs]
+      operationId: launchJob
+      summary: Launch a job
+      parameters:
+        - $ref: '#/components/parameters/IdempotencyKey'
+      requestBody:
+        required: true
+        content:
+          application/json:
+            schema:
+              $ref: '#/components/schemas/JobRequest'
+      responses:
+        "202":
+          description: The job is launched
+          content:
+            application/json:
+              schema:
+                $ref: '#/components/schemas/JobStats'
+        "400":
+          $ref: '#/components/responses/Error'
+        "409":
+          $ref: '#/components/responses/Error'
+        "429":
+          $ref: '#/components/responses/RateLimited'
+        "503":
+          $ref: '#/components/responses/Error'
+        default:
+          $ref: '#/components/responses/Error'
+    get:
+      tags: [jobs]
+      operationId: listJobs
+      summary: List the jobs, scanned with the cursor except the scheduled ones
+      parameters:
+        - $ref: '#/components/parameters/PageNumber'
+        - $ref: '#/components/parameters/PageSize'
+        - $ref: '#/components/parameters/Cursor'
+        - $ref: '#/components/parameters/Kind'
+        - $ref: '#/components/parameters/Name'
+        - $ref: '#/components/parameters/Status'
+        - $ref: '#/components/parameters/NonDeadOnly'
+      responses:
+        "200":
+          description: The jobs
+          headers:
+            Next-Cursor:
+              description: The cursor of the next scanning, 0 means the scanning is completed
+              schema:
+                type: integer
+            Total-Count:
+              description: The total count of the scheduled jobs
+              schema:
+                type: integer
+          content:
+            application/json:
+              schema:
+                type: array
+                items:
+                  $ref: '#/components/schemas/JobStats'
+        default:
+          $ref: '#/components/responses/Error'
+  /jobs/{job_id}:
+    parameters:
+      - $ref: '#/components/parameters/JobID'
+    get:
+      tags: [jobs]
+      operationId: getJob
+      summary: Get the stats of the job
+      responses:
+        "200":
+          description: The job stats
+          content:
+            application/json:
+              schema:
+                $ref: '#/components/schemas/JobStats'
+        "404":
+          $ref: '#/components/responses/Error'
+        default:
+          $ref: '#/components/responses/Error'
+    post:
+      tags: [jobs]
+      operationId: jobAction
+      summary: Stop or retry the job
+      requestBody:
+        required: true
+        content:
+          application/json:
+            schema:
+              $ref: '#/components/schemas/JobActionRequest'
+      responses:
+        "204":
+          description: The action is done
+        "400":
+          $ref: '#/components/responses/Error'
+        "404":
+          $ref: '#/components/responses/Error'
+        "409":
+          $ref: '#/components/responses/Error'
+        "501":
+          $ref: '#/components/responses/Error'
+        default:
+          $ref: '#/components/responses/Error'
+  /jobs/{job_id}/log:
+    parameters:
+      - $ref: '#/components/parameters/JobID'
+    get:
+      tags: [jobs]
+      operationId: getJobLog
+      summary: Get the log of the job
+      responses:
+        "200":
+          description: The log text
+          content:
+            text/plain:
+              schema:
+                type: string
+        "404":
+          $ref: '#/components/responses/Error'
+        default:
+          $ref: '#/components/responses/Error'
+  /jobs/{job_id}/executions:
+    parameters:
+      - $ref: '#/components/parameters/JobID'
+    get:
+      tags: [jobs]
+      operationId: listExecutions
+      summary: List the executions of the periodic job
+      parameters:
+        - $ref: '#/components/parameters/PageNumber'
+        - $ref: '#/components/parameters/PageSize'
+        - $ref: '#/components/parameters/NonDeadOnly'
+      responses:
+        "200":
+          $ref: '#/components/responses/JobStatsPage'
+        "404":
+          $ref: '#/components/responses/Error'
+        default:
+          $ref: '#/components/responses/Error'
+  /scheduled-jobs:
+    get:
+      tags: [scheduled-jobs]
+      operationId: listScheduledJobs
+      summary: List the jobs scheduled to run later
+      parameters:
+        - $ref: '#/components/parameters/PageNumber'
+        - $ref: '#/components/parameters/PageSize'
+        - $ref: '#/components/parameters/Name'
+      responses:
+        "200":
+          $ref: '#/components/responses/JobStatsPage'
+        default:
+          $ref: '#/components/responses/Error'
+  /scheduled-jobs/{job_id}:
+    parameters:
+      - $ref: '#/components/parameters/JobID'
+    post:
+      tags: [scheduled-jobs]
+      operationId: scheduledJobAction
+      summary: Reschedule, run or cancel the scheduled job
+      requestBody:
+        required: true
+        content:
+          application/json:
+            schema:
+              $ref: '#/components/schemas/ScheduleActionRequest'
+      responses:
+        "200":
+          description: The job stats after the action
+          content:
+            application/json:
+              schema:
+                $ref: '#/components/schemas/JobStats'
+        "400":
+          $ref: '#/components/responses/Error'
+        "404":
+          $ref: '#/components/responses/Error'
+        "409":
+          $ref: '#/components/responses/Error'
+        "501":
+          $ref: '#/components/responses/Error'
+        default:
+          $ref: '#/components/responses/Error'
+  /archived-jobs:
+    get:
+      tags: [archived-jobs]
+      operationId: listArchivedJobs
+      summary: List the jobs archived by the retention
+      parameters:
+        - $ref: '#/components/parameters/PageNumber'
+        - $ref: '#/components/parameters/PageSize'
+        - $ref: '#/components/parameters/Kind'
+        - $ref: '#/components/parameters/Name'
+        - $ref: '#/components/parameters/Status'
+      responses:
+        "200":
+          description: The archived jobs
+          headers:
+            Total-Count:
+              $ref: '#/components/headers/Total-Count'
+          content:
+            application/json:
+              schema:
+                type: array
+                items:
+                  $ref: '#/components/schemas/ArchivedJob'
+        "400":
+          $ref: '#/components/responses/Error'
+        default:
+          $ref: '#/components/responses/Error'
+  /archived-jobs/{job_id}:
+    parameters:
+      - $ref: '#/components/parameters/JobID'
+    get:
+      tags: [archived-jobs]
+      operationId: getArchivedJob
+      summary: Get the archived job
+      responses:
+        "200":
+          description: The archived job
+          content:
+            application/json:
+              schema:
+                $ref: '#/components/schemas/ArchivedJob'
+        "404":
+          $ref: '#/components/responses/Error'
+        default:
+          $ref: '#/components/responses/Error'
+  /job-types:
+    get:
+      tags: [jobs]
+      operationId: getJobTypes
+      summary: Get the registered job types
+      responses:
+        "200":
+          description: The job types
+          content:
+            application/json:
+              schema:
+                type: array
+                items:
+                  $ref: '#/components/schemas/JobType'
+        default:
+          $ref: '#/components/responses/Error'
+  /stats:
+    get:
+      tags: [system]
+      operationId: getStats
+      summary: Check the status of the worker pools, no auth required
+      security: []
+      responses:
+        "200":
+          description: The worker stats
+          content:
+            application/json:
+              schema:
+                $ref: '#/components/schemas/WorkerStats'
+        default:
+          $ref: '#/components/responses/Error'
+  /nodes:
+    get:
+      tags: [system]
+      operationId: getNodes
+      summary: Get the nodes of the cluster
+      responses:
+        "200":
+          description: The nodes
+          content:
+            application/json:
+              schema:
+                type: array
+                items:
+                  $ref: '#/components/schemas/Node'
+        default:
+          $ref: '#/components/responses/Error'
+  /nodes/{node_id}:
+    parameters:
+      - name: node_id
+        in: path
+        required: true
+        schema:
+          type: string
+    post:
+      tags: [system]
+      operationId: nodeAction
+      summary: Fail or requeue the jobs orphaned by the dead node
+      requestBody:
+        required: true
+        content:
+          application/json:
+            schema:
+              $ref: '#/components/schemas/NodeActionRequest'
+      responses:
+        "200":
+          description: The result of the action
+          content:
+            application/json:
+              schema:
+                $ref: '#/components/schemas/NodeActionResult'
+        "400":
+          $ref: '#/components/responses/Error'
+        "404":
+          $ref: '#/components/responses/Error'
+        "409":
+          $ref: '#/components/responses/Error'
+        default:
+          $ref: '#/components/responses/Error'
+  /ratelimit/rejections:
+    get:
+      tags: [system]
+      operationId: getRateLimitRejections
+      summary: Get the counts of the launching requests rejected by the rate limiter
+      description: >-
+        The rejections of the recent 24 hours are counted in the hourly slots.
+        At most 10000 caller and job pairs are counted in a slot, the others are counted under '*:*'.
+      responses:
+        "200":
+          description: The counts keyed by caller and job name
+          content:
+            application/json:
+              schema:
+                type: object
+                additionalProperties:
+                  type: integer
+        default:
+          $ref: '#/components/responses/Error'
+  /events:
+    get:
+      tags: [jobs]
+      operationId: watchStatusChanges
+      summary: Stream the status change events of the jobs as the server-sent events
+      description: >-
+        Each event has the ID, the name 'status_change' and the status change in JSON as the data.
+        The stream is closed periodically, the clients reconnect with the Last-Event-ID header to resume it.
+        Without the event ID, only the new events from now on are streamed.
+      parameters:
+        - name: job_id
+          in: query
+          schema:
+            type: string
+        - $ref: '#/components/parameters/Name'
+        - name: upstream_job_id
+          in: query
+          description: The ID of the periodic job whose executions are watched
+          schema:
+            type: string
+        - name: last_event_id
+          in: query
+          description: Used if the Last-Event-ID header is not set
+          schema:
+            type: string
+        - name: Last-Event-ID
+          in: header
+          description: Resume the stream after the event with the ID
+          schema:
+            type: string
+      responses:
+        "200":
+          description: The stream of the status change events
+          content:
+            text/event-stream:
+              schema:
+                type: string
+        "400":
+          $ref: '#/components/responses/Error'
+        default:
+          $ref: '#/components/responses/Error'
+  /audit-logs:
+    get:
+      tags: [audit]
+      operationId: listAuditLogs
+      summary: List the audit log of the job actions from the latest one
+      description: >-
+        The launching, stopping and retrying of the jobs and the actions of the scheduled jobs are audited
+        if the audit sink is configured. The 'audit:read' scope is required.
+      parameters:
+        - $ref: '#/components/parameters/PageNumber'
+        - $ref: '#/components/parameters/PageSize'
+        - name: caller
+          in: query
+          schema:
+            type: string
+        - name: action
+          in: query
+          schema:
+            type: string
+            enum: [launch, stop, retry, reschedule, run, cancel]
+        - name: job_id
+          in: query
+          schema:
+            type: string
+      responses:
+        "200":
+          description: The audit log entries
+          headers:
+            Total-Count:
+              $ref: '#/components/headers/Total-Count'
+          content:
+            application/json:
+              schema:
+                type: array
+                items:
+                  $ref: '#/components/schemas/AuditEntry'
+        "400":
+          $ref: '#/components/responses/Error'
+        default:
+          $ref: '#/components/responses/Error'
+  /periodic-policies/export:
+    get:
+      tags: [periodic-policies]
+      operationId: exportPeriodicPolicies
+      summary: Export all the periodic job policies with their numeric IDs
+      description: >-
+        The export is returned as the attachment which can be imported by another job service.
+        The secret job parameters are kept encrypted, so the importing job service should share the same
+        parameters key. The 'system:admin' scope is required.
+      responses:
+        "200":
+          description: The exported policies
+          content:
+            application/json:
+              schema:
+                $ref: '#/components/schemas/PolicyExport'
+        "400":
+          $ref: '#/components/responses/Error'
+        default:
+          $ref: '#/components/responses/Error'
+  /periodic-policies/import:
+    post:
+      tags: [periodic-policies]
+      operationId: importPeriodicPolicies
+      summary: Import the exported periodic job policies
+      description: >-
+        The policies conflicting with the existing policies or jobs by the ID or the numeric ID are skipped.
+        Nothing is changed in the dry run and the report tells what would be done.
+        The 'system:admin' scope is required.
+      parameters:
+        - name: dry_run
+          in: query
+          schema:
+            type: boolean
+            default: false
+      requestBody:
+        required: true
+        content:
+          application/json:
+            schema:
+              $ref: '#/components/schemas/PolicyExport'
+      responses:
+        "200":
+          description: The report of the import
+          content:
+            application/json:
+              schema:
+                $ref: '#/components/schemas/PolicyImportReport'
+        "400":
+          $ref: '#/components/responses/Error'
+        default:
+          $ref: '#/components/responses/Error'
+  /openapi.yaml:
+    get:
+      tags: [system]
+      operationId: getOpenAPISpec
+      summary: Get this document, no auth required
+      security: []
+      responses:
+        "200":
+          description: The OpenAPI document
+          content:
+            application/yaml:
+              schema:
+                type: string
+components:
+  securitySchemes:
+    secret:
+      type: apiKey
+      in: header
+      name: Authorization
+      description: The shared secret in the form of 'Harbor-Secret <secret>'
+    bearer:
+      type: http
+      scheme: bearer
+      description: The static token or JWT of the API credential
+  parameters:
+    JobID:
+      name: job_id
+      in: path
+      required: true
+      schema:
+        type: string
+    PageNumber:
+      name: page_number
+      in: query
+      schema:
+        type: integer
+        minimum: 1
+        default: 1
+    PageSize:
+      name: page_size
+      in: query
+      schema:
+        type: integer
+        minimum: 1
+    Cursor:
+      name: cursor
+      in: query
+      description: The cursor returned in the Next-Cursor header of the previous page
+      schema:
+        type: integer
+    Kind:
+      name: kind
+      in: query
+      schema:
+        $ref: '#/components/schemas/JobKind'
+    Name:
+      name: name
+      in: query
+      description: >-
+        Name of the jobs to list. It's required for the callers whose scope is restricted
+        to a job name pattern, and the name should match the pattern.
+      schema:
+        type: string
+    Status:
+      name: status
+      in: query
+      schema:
+        $ref: '#/components/schemas/JobStatus'
+    NonDeadOnly:
+      name: non_dead_only
+      in: query
+      schema:
+        type: boolean
+    IdempotencyKey:
+      name: Idempotency-Key
+      in: header
+      description: The key to avoid launching the same job twice, at most 255 characters
+      schema:
+        type: string
+        maxLength: 255
+  headers:
+    Total-Count:
+      description: The total count of the matched items
+      schema:
+        type: integer
+  responses:
+    Error:
+      description: The error in the form of the problem details defined in RFC 7807
+      content:
+        application/problem+json:
+          schema:
+            $ref: '#/components/schemas/Error'
+    RateLimited:
+      description: The rate limit of the caller is exceeded
+      headers:
+        Retry-After:
+          description: Seconds to wait before retrying
+          schema:
+            type: integer
+      content:
+        application/problem+json:
+          schema:
+            $ref: '#/components/schemas/Error'
+    JobStatsPage:
+      description: The page of the jobs
+      headers:
+        Total-Count:
+          $ref: '#/components/headers/Total-Count'
+      content:
+        application/json:
+          schema:
+            type: array
+            items:
+              $ref: '#/components/schemas/JobStats'
+  schemas:
+    Error:
+      type: object
+      description: |
+        The problem details of the error. The code is one of the error codes defined in the errs package of the
+        job service and the error is the stable name of it, the type is the URI 'urn:jobservice:error:<error>'.
+        The status is 400 for bad request, 404 for not found, 409 for conflict and status mismatch,
+        429 for rate limited and 503 for overloaded.
+      required: [type, title, status, code, error]
+      properties:
+        type:
+          type: string
+          example: urn:jobservice:error:not_found
+        title:
+          type: string
+        status:
+          type: integer
+        detail:
+          type: string
+        instance:
+          type: string
+          description: The path of the request
+        code:
+          type: integer
+          example: 10010
+        error:
+          type: string
+          enum: [read_request_body, handle_json_data, missing_backend_handler, launch_job, check_stats,
+            get_job_stats, stop_job, retry_job, unknown_action, get_job_log, not_found, unauthorized, conflict,
+            bad_request, get_jobs, get_periodic_executions, status_mismatch, get_nodes, node_action, rate_limited,
+            overloaded, get_job_types, get_archived_jobs, scheduled_job_action, forbidden, get_status_events,
+            internal, get_audit_logs, export_policies, import_policies]
+        current_status:
+          description: The current status of the job, only for status_mismatch
+          $ref: '#/components/schemas/JobStatus'
+        target_status:
+          description: The status the job is being set to, only for status_mismatch
+          $ref: '#/components/schemas/JobStatus'
+        existing:
+          description: The job launched before with the same idempotency key or uniqueness, only for conflict
+          $ref: '#/components/schemas/JobStats'
+    JobKind:
+      type: string
+      enum: [Generic, Scheduled, Periodic]
+    JobStatus:
+      type: string
+      enum: [Pending, Running, Stopped, Error, Success, Scheduled]
+    JobRequest:
+      type: object
+      required: [job]
+      properties:
+        job:
+          type: object
+          required: [name, metadata]
+          properties:
+            name:
+              type: string
+            parameters:
+              type: object
+              additionalProperties: true
+            metadata:
+              $ref: '#/components/schemas/JobMetadata'
+            status_hook:
+              type: string
+    JobMetadata:
+      type: object
+      required: [kind]
+      properties:
+        kind:
+          $ref: '#/components/schemas/JobKind'
+        schedule_delay:
+          type: integer
+        cron_spec:
+          type: string
+        unique:
+          type: boolean
+        priority:
+          type: string
+        unique_keys:
+          type: array
+          items:
+            type: string
+        unique_ttl:
+          type: integer
+        secret_keys:
+          type: array
+          items:
+            type: string
+        schedule:
+          $ref: '#/components/schemas/ScheduleSpec'
+    ScheduleSpec:
+      type: object
+      properties:
+        interval:
+          type: object
+          required: [every_seconds]
+          properties:
+            every_seconds:
+              type: integer
+            start_at:
+              type: integer
+        run_list:
+          type: array
+          items:
+            type: integer
+        blackouts:
+          type: array
+          items:
+            type: object
+            required: [start, duration_seconds]
+            properties:
+              start:
+                type: string
+              duration_seconds:
+                type: integer
+              time_zone:
+                type: string
+    JobStats:
+      type: object
+      properties:
+        job:
+          $ref: '#/components/schemas/JobStatsInfo'
+    JobStatsInfo:
+      type: object
+      properties:
+        id:
+          type: string
+        status:
+          $ref: '#/components/schemas/JobStatus'
+        name:
+          type: string
+        kind:
+          $ref: '#/components/schemas/JobKind'
+        unique:
+          type: boolean
+        ref_link:
+          type: string
+        cron_spec:
+          type: string
+        enqueue_time:
+          type: integer
+        update_time:
+          type: integer
+        run_at:
+          type: integer
+        check_in:
+          type: string
+        check_in_at:
+          type: integer
+        die_at:
+          type: integer
+        web_hook_url:
+          type: string
+        upstream_job_id:
+          type: string
+        numeric_policy_id:
+          type: integer
+        parameters:
+          type: object
+          additionalProperties: true
+        revision:
+          type: integer
+        node_id:
+          type: string
+        schedule:
+          $ref: '#/components/schemas/ScheduleSpec'
+    JobActionRequest:
+      type: object
+      required: [action]
+      properties:
+        action:
+          type: string
+          enum: [stop, retry]
+    ScheduleActionRequest:
+      type: object
+      required: [action]
+      properties:
+        action:
+          type: string
+          enum: [reschedule, run, cancel]
+        run_at:
+          type: integer
+          description: Unix timestamp of the new run time, required by the action 'reschedule'
+    ArchivedJob:
+      type: object
+      properties:
+        archived_at:
+          type: integer
+        stats:
+          $ref: '#/components/schemas/JobStats'
+    AuditEntry:
+      type: object
+      properties:
+        time:
+          type: integer
+          description: Unix timestamp of the action
+        caller:
+          type: string
+        action:
+          type: string
+        job_id:
+          type: string
+        job_name:
+          type: string
+        parameters_hash:
+          type: string
+          description: SHA256 of the parameters of the action in JSON
+        result:
+          type: string
+          enum: [success, failure]
+        status_code:
+          type: integer
+        error:
+          type: string
+          description: The error name of the failed action
+    PeriodicPolicy:
+      type: object
+      properties:
+        id:
+          type: string
+        job_name:
+          type: string
+        cron_spec:
+          type: string
+        job_params:
+          type: object
+          additionalProperties: true
+        web_hook_url:
+          type: string
+        schedule:
+          $ref: '#/components/schemas/ScheduleSpec'
+    PolicyExport:
+      type: object
+      required: [version, policies]
+      properties:
+        version:
+          type: integer
+          enum: [1]
+        namespace:
+          type: string
+          description: The namespace the policies are exported from
+        exported_at:
+          type: integer
+        policies:
+          type: array
+          items:
+            type: object
+            properties:
+              numeric_id:
+                type: integer
+                description: The score of the policy in the policy set, kept by the import
+              policy:
+                $ref: '#/components/schemas/PeriodicPolicy'
+    PolicyImportReport:
+      type: object
+      properties:
+        dry_run:
+          type: boolean
+        namespace:
+          type: string
+        imported:
+          type: integer
+          description: Count of the imported policies or the ones would be imported in the dry run
+        conflicts:
+          type: integer
+        invalid:
+          type: integer
+        failed:
+          type: integer
+        results:
+          type: array
+          items:
+            type: object
+            properties:
+              id:
+                type: string
+              job_name:
+                type: string
+              numeric_id:
+                type: integer
+              result:
+                type: string
+                enum: [imported, would_import, conflict, invalid, failed]
+              reason:
+                type: string
+    JobType:
+      type: object
+      properties:
+        name:
+          type: string
+        max_fails:
+          type: integer
+        should_retry:
+          type: boolean
+        parameters_schema:
+          type: object
+          additionalProperties: true
+    WorkerStats:
+      type: object
+      properties:
+        worker_pools:
+          type: array
+          items:
+            type: object
+            properties:
+              worker_pool_id:
+                type: string
+              started_at:
+                type: integer
+              heartbeat_at:
+                type: integer
+              job_names:
+                type: array
+                items:
+                  type: string
+              concurrency:
+                type: integer
+              status:
+                type: string
+        periodic_leader:
+          type: object
+          properties:
+            node_id:
+              type: string
+            fencing_token:
+              type: integer
+            expire_at:
+              type: integer
+        streams:
+          type: array
+          items:
+            type: object
+            properties:
+              job_name:
+                type: string
+              length:
+                type: integer
+              pending:
+                type: integer
+              consumers:
+                type: integer
+              processed:
+                type: integer
+              failed:
+                type: integer
+              reclaimed:
+                type: integer
+    Node:
+      type: object
+      properties:
+        node_id:
+          type: string
+        started_at:
+          type: integer
+        heartbeat_at:
+          type: integer
+        concurrency:
+          type: integer
+        running_jobs:
+          type: array
+          items:
+            type: string
+        load:
+          type: number
+        status:
+          type: string
+          enum: [Healthy, Dead]
+    StatusChange:
+      type: object
+      properties:
+        job_id:
+          type: string
+        status:
+          $ref: '#/components/schemas/JobStatus'
+        check_in:
+          type: string
+        metadata:
+          $ref: '#/components/schemas/JobStatsInfo'
+    NodeActionRequest:
+      type: object
+      required: [action]
+      properties:
+        action:
+          type: string
+          enum: [fail, requeue]
+    NodeActionResult:
+      type: object
+      properties:
+        node_id:
+          type: string
+        action:
+          type: string
+        jobs:
+          type: array
+          items:
+            type: string
+        failed:
+          type: array
+          items:
+            type: string
+`
+
+// handleOpenAPIReq serves the OpenAPI document of the v2 API
+func handleOpenAPIReq(w http.ResponseWriter, req *http.Request) {
+	w.Header().Set("Content-Type", "application/yaml")
+	w.WriteHeader(http.StatusOK)
+	writeDate(w, []byte(openAPISpec))
+}
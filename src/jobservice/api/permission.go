@@ -0,0 +1,164 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"github.com/chenxull/goGridhub/gridhub/src/jobservice/auth"
+	"github.com/chenxull/goGridhub/gridhub/src/jobservice/common/query"
+	"github.com/chenxull/goGridhub/gridhub/src/jobservice/errs"
+	"github.com/chenxull/goGridhub/gridhub/src/jobservice/job"
+	"github.com/gorilla/mux"
+	"io/ioutil"
+	"net/http"
+)
+
+// Names of the routes requiring the permissions
+const (
+	routeLaunchJob          = "launch-job"
+	routeGetJobs            = "get-jobs"
+	routeGetJob             = "get-job"
+	routeJobAction          = "job-action"
+	routeJobLog             = "job-log"
+	routeExecutions         = "periodic-executions"
+	routeScheduledJobs      = "scheduled-jobs"
+	routeScheduledJobAction = "scheduled-job-action"
+	routeArchivedJobs       = "archived-jobs"
+	routeArchivedJob        = "archived-job"
+	routeJobTypes           = "job-types"
+	routeNodes              = "nodes"
+	routeNodeAction         = "node-action"
+	routeRejections         = "ratelimit-rejections"
+	routeStatusEvents       = "status-events"
+	routeAuditLogs          = "audit-logs"
+	routeExportPolicies     = "export-policies"
+	routeImportPolicies     = "import-policies"
+)
+
+// targetFunc returns the name of the job targeted by the request, empty means all the jobs
+type targetFunc func(br *BaseRouter, req *http.Request, vars map[string]string) (string, error)
+
+// permission is the scope required by the route on the target job
+type permission struct {
+	scope  string
+	target targetFunc
+	// The results are filtered by the grants of the caller in the handler,
+	// so the scope granted for any jobs is allowed.
+	filtered bool
+}
+
+var routePermissions = map[string]*permission{
+	routeLaunchJob:          {scope: auth.ScopeJobsLaunch, target: launchedJob},
+	routeGetJobs:            {scope: auth.ScopeJobsRead, target: queriedJob},
+	routeGetJob:             {scope: auth.ScopeJobsRead, target: trackedJob},
+	routeJobAction:          {scope: auth.ScopeJobsStop, target: trackedJob},
+	routeJobLog:             {scope: auth.ScopeJobsRead, target: trackedJob},
+	routeExecutions:         {scope: auth.ScopeJobsRead, target: trackedJob},
+	routeScheduledJobs:      {scope: auth.ScopeJobsRead, target: queriedJob},
+	routeScheduledJobAction: {scope: auth.ScopeJobsStop, target: trackedJob},
+	routeArchivedJobs:       {scope: auth.ScopeJobsRead, target: queriedJob},
+	routeArchivedJob:        {scope: auth.ScopeJobsRead, target: archivedJob},
+	routeJobTypes:           {scope: auth.ScopeJobsRead, filtered: true},
+	routeNodes:              {scope: auth.ScopeSystemAdmin},
+	routeNodeAction:         {scope: auth.ScopeSystemAdmin},
+	routeRejections:         {scope: auth.ScopeSystemAdmin},
+	routeStatusEvents:       {scope: auth.ScopeJobsRead, target: queriedJob},
+	routeAuditLogs:          {scope: auth.ScopeAuditRead},
+	routeExportPolicies:     {scope: auth.ScopeSystemAdmin},
+	routeImportPolicies:     {scope: auth.ScopeSystemAdmin},
+}
+
+// authorize checks if the caller is granted the scope required by the matched route.
+// The name of the job targeted by the request is returned if it's resolved.
+// The request not matched with any route is left to the router to report.
+func (br *BaseRouter) authorize(req *http.Request, id *auth.Identity) (string, bool, error) {
+	match := &mux.RouteMatch{}
+	if !br.router.Match(req, match) || match.Route == nil {
+		return "", false, nil
+	}
+
+	p, ok := routePermissions[match.Route.GetName()]
+	if !ok {
+		return "", false, nil
+	}
+
+	if p.filtered {
+		if !id.Granted(p.scope) {
+			return "", false, errs.ForbiddenError(id.Name, p.scope, "")
+		}
+		return "", false, nil
+	}
+
+	jobName := ""
+	if p.target != nil {
+		name, err := p.target(br, req, match.Vars)
+		if err != nil {
+			// Let the handler report the missing job or the bad request
+			if errs.IsObjectNotFoundError(err) || errs.IsBadRequestError(err) {
+				return "", false, nil
+			}
+			return "", false, err
+		}
+		jobName = name
+	}
+
+	if !id.Allowed(p.scope, jobName) {
+		return jobName, p.target != nil, errs.ForbiddenError(id.Name, p.scope, jobName)
+	}
+
+	return jobName, p.target != nil, nil
+}
+
+// routeName returns the name of the route matched with the request, empty if no route is matched
+func (br *BaseRouter) routeName(req *http.Request) string {
+	match := &mux.RouteMatch{}
+	if !br.router.Match(req, match) || match.Route == nil {
+		return ""
+	}
+
+	return match.Route.GetName()
+}
+
+// launchedJob returns the job name in the launching request
+func launchedJob(br *BaseRouter, req *http.Request, vars map[string]string) (string, error) {
+	data, err := ioutil.ReadAll(req.Body)
+	if err != nil {
+		return "", errs.ReadRequestBodyError(err)
+	}
+	// Restore the body for the next handler
+	req.Body = ioutil.NopCloser(bytes.NewReader(data))
+
+	jobReq := &job.Request{}
+	if err := json.Unmarshal(data, jobReq); err != nil || jobReq.Job == nil {
+		// Only the unrestricted scope is matched with the malformed request
+		return "", nil
+	}
+
+	return jobReq.Job.Name, nil
+}
+
+// queriedJob returns the job name filter in the query of the listing request.
+// The filter is required for the scope restricted to the job name pattern, as
+// only the unrestricted scope is matched with the empty job name.
+func queriedJob(br *BaseRouter, req *http.Request, vars map[string]string) (string, error) {
+	return req.URL.Query().Get(query.ParamKeyJobName), nil
+}
+
+// trackedJob returns the name of the job with the ID in the path
+func trackedJob(br *BaseRouter, req *http.Request, vars map[string]string) (string, error) {
+	st, err := br.controller.GetJob(vars["job_id"])
+	if err != nil {
+		return "", err
+	}
+
+	return st.Info.JobName, nil
+}
+
+// archivedJob returns the name of the archived job with the ID in the path
+func archivedJob(br *BaseRouter, req *http.Request, vars map[string]string) (string, error) {
+	r, err := br.controller.GetArchivedJob(vars["job_id"])
+	if err != nil {
+		return "", err
+	}
+
+	return r.Job.Info.JobName, nil
+}
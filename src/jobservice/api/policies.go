@@ -0,0 +1,63 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/chenxull/goGridhub/gridhub/src/jobservice/errs"
+	"github.com/chenxull/goGridhub/gridhub/src/jobservice/period"
+	"github.com/pkg/errors"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// Query param of importing the policies without any changes
+const paramDryRun = "dry_run"
+
+// HandleExportPoliciesReq is implementation of method defined in interface 'Handler'.
+// The policies are returned as the attachment which can be imported by another job service.
+func (dh *DefaultHandler) HandleExportPoliciesReq(w http.ResponseWriter, req *http.Request) {
+	data, err := dh.controller.ExportPeriodicPolicies()
+	if err != nil {
+		dh.handleError(w, req, http.StatusInternalServerError, serverError(err, errs.ExportPoliciesError))
+		return
+	}
+
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="periodic-policies-%s.json"`, time.Unix(data.ExportedAt, 0).UTC().Format("20060102150405")))
+	dh.handleJSONData(w, req, http.StatusOK, data)
+}
+
+// HandleImportPoliciesReq is implementation of method defined in interface 'Handler'.
+// The report tells the result of each policy, the conflicting ones are skipped.
+func (dh *DefaultHandler) HandleImportPoliciesReq(w http.ResponseWriter, req *http.Request) {
+	dryRun := false
+	if v := req.URL.Query().Get(paramDryRun); len(v) > 0 {
+		b, err := strconv.ParseBool(v)
+		if err != nil {
+			dh.handleError(w, req, http.StatusBadRequest, errs.BadRequestError(errors.Errorf("invalid query param '%s': %s", paramDryRun, v)))
+			return
+		}
+		dryRun = b
+	}
+
+	body, err := ioutil.ReadAll(req.Body)
+	if err != nil {
+		dh.handleError(w, req, http.StatusInternalServerError, errs.ReadRequestBodyError(err))
+		return
+	}
+
+	data := &period.PolicyExport{}
+	if err = json.Unmarshal(body, data); err != nil {
+		dh.handleError(w, req, http.StatusBadRequest, errs.HandleJSONDataError(err))
+		return
+	}
+
+	report, err := dh.controller.ImportPeriodicPolicies(data, dryRun)
+	if err != nil {
+		dh.handleError(w, req, http.StatusInternalServerError, serverError(err, errs.ImportPoliciesError))
+		return
+	}
+
+	dh.handleJSONData(w, req, http.StatusOK, report)
+}
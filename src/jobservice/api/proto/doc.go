@@ -0,0 +1,5 @@
+// Package proto keeps the gRPC stubs of the job service generated from jobservice.proto
+// by protoc-gen-go v1.36.6 and protoc-gen-go-grpc v1.5.1.
+package proto
+
+//go:generate protoc --go_out=. --go_opt=paths=source_relative --go-grpc_out=. --go-grpc_opt=paths=source_relative jobservice.proto
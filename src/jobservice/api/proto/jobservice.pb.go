@@ -0,0 +1,1301 @@
+// The gRPC interface of the job service, served alongside the HTTP API and backed by the same core controller.
+// The calls are authenticated by the same authenticator as the HTTP API with the 'authorization' metadata,
+// e.g: 'Harbor-Secret <secret>' or 'Bearer <token>'.
+//
+// The errors are returned with the gRPC status codes mapped from the errs package of the job service:
+// NOT_FOUND, ALREADY_EXISTS (conflict), INVALID_ARGUMENT (bad request), FAILED_PRECONDITION (status mismatch),
+// RESOURCE_EXHAUSTED (rate limited), UNAVAILABLE (overloaded), UNAUTHENTICATED, PERMISSION_DENIED and INTERNAL.
+// The errs code is attached to the status message in the form of 'code=<code>'.
+
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.36.6
+// 	protoc        (unknown)
+// source: jobservice.proto
+
+package proto
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	reflect "reflect"
+	sync "sync"
+	unsafe "unsafe"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+type Empty struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Empty) Reset() {
+	*x = Empty{}
+	mi := &file_jobservice_proto_msgTypes[0]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Empty) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Empty) ProtoMessage() {}
+
+func (x *Empty) ProtoReflect() protoreflect.Message {
+	mi := &file_jobservice_proto_msgTypes[0]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Empty.ProtoReflect.Descriptor instead.
+func (*Empty) Descriptor() ([]byte, []int) {
+	return file_jobservice_proto_rawDescGZIP(), []int{0}
+}
+
+type ScheduleSpec struct {
+	state         protoimpl.MessageState         `protogen:"open.v1"`
+	Interval      *ScheduleSpec_Interval         `protobuf:"bytes,1,opt,name=interval,proto3" json:"interval,omitempty"`
+	RunList       []int64                        `protobuf:"varint,2,rep,packed,name=run_list,json=runList,proto3" json:"run_list,omitempty"`
+	Blackouts     []*ScheduleSpec_BlackoutWindow `protobuf:"bytes,3,rep,name=blackouts,proto3" json:"blackouts,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ScheduleSpec) Reset() {
+	*x = ScheduleSpec{}
+	mi := &file_jobservice_proto_msgTypes[1]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ScheduleSpec) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ScheduleSpec) ProtoMessage() {}
+
+func (x *ScheduleSpec) ProtoReflect() protoreflect.Message {
+	mi := &file_jobservice_proto_msgTypes[1]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ScheduleSpec.ProtoReflect.Descriptor instead.
+func (*ScheduleSpec) Descriptor() ([]byte, []int) {
+	return file_jobservice_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *ScheduleSpec) GetInterval() *ScheduleSpec_Interval {
+	if x != nil {
+		return x.Interval
+	}
+	return nil
+}
+
+func (x *ScheduleSpec) GetRunList() []int64 {
+	if x != nil {
+		return x.RunList
+	}
+	return nil
+}
+
+func (x *ScheduleSpec) GetBlackouts() []*ScheduleSpec_BlackoutWindow {
+	if x != nil {
+		return x.Blackouts
+	}
+	return nil
+}
+
+type JobMetadata struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Kind          string                 `protobuf:"bytes,1,opt,name=kind,proto3" json:"kind,omitempty"`
+	ScheduleDelay uint64                 `protobuf:"varint,2,opt,name=schedule_delay,json=scheduleDelay,proto3" json:"schedule_delay,omitempty"`
+	CronSpec      string                 `protobuf:"bytes,3,opt,name=cron_spec,json=cronSpec,proto3" json:"cron_spec,omitempty"`
+	Unique        bool                   `protobuf:"varint,4,opt,name=unique,proto3" json:"unique,omitempty"`
+	Priority      string                 `protobuf:"bytes,5,opt,name=priority,proto3" json:"priority,omitempty"`
+	UniqueKeys    []string               `protobuf:"bytes,6,rep,name=unique_keys,json=uniqueKeys,proto3" json:"unique_keys,omitempty"`
+	UniqueTtl     uint64                 `protobuf:"varint,7,opt,name=unique_ttl,json=uniqueTtl,proto3" json:"unique_ttl,omitempty"`
+	SecretKeys    []string               `protobuf:"bytes,8,rep,name=secret_keys,json=secretKeys,proto3" json:"secret_keys,omitempty"`
+	Schedule      *ScheduleSpec          `protobuf:"bytes,9,opt,name=schedule,proto3" json:"schedule,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *JobMetadata) Reset() {
+	*x = JobMetadata{}
+	mi := &file_jobservice_proto_msgTypes[2]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *JobMetadata) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*JobMetadata) ProtoMessage() {}
+
+func (x *JobMetadata) ProtoReflect() protoreflect.Message {
+	mi := &file_jobservice_proto_msgTypes[2]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use JobMetadata.ProtoReflect.Descriptor instead.
+func (*JobMetadata) Descriptor() ([]byte, []int) {
+	return file_jobservice_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *JobMetadata) GetKind() string {
+	if x != nil {
+		return x.Kind
+	}
+	return ""
+}
+
+func (x *JobMetadata) GetScheduleDelay() uint64 {
+	if x != nil {
+		return x.ScheduleDelay
+	}
+	return 0
+}
+
+func (x *JobMetadata) GetCronSpec() string {
+	if x != nil {
+		return x.CronSpec
+	}
+	return ""
+}
+
+func (x *JobMetadata) GetUnique() bool {
+	if x != nil {
+		return x.Unique
+	}
+	return false
+}
+
+func (x *JobMetadata) GetPriority() string {
+	if x != nil {
+		return x.Priority
+	}
+	return ""
+}
+
+func (x *JobMetadata) GetUniqueKeys() []string {
+	if x != nil {
+		return x.UniqueKeys
+	}
+	return nil
+}
+
+func (x *JobMetadata) GetUniqueTtl() uint64 {
+	if x != nil {
+		return x.UniqueTtl
+	}
+	return 0
+}
+
+func (x *JobMetadata) GetSecretKeys() []string {
+	if x != nil {
+		return x.SecretKeys
+	}
+	return nil
+}
+
+func (x *JobMetadata) GetSchedule() *ScheduleSpec {
+	if x != nil {
+		return x.Schedule
+	}
+	return nil
+}
+
+type LaunchJobRequest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	Name  string                 `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
+	// The parameters in JSON object
+	Parameters     []byte       `protobuf:"bytes,2,opt,name=parameters,proto3" json:"parameters,omitempty"`
+	Metadata       *JobMetadata `protobuf:"bytes,3,opt,name=metadata,proto3" json:"metadata,omitempty"`
+	StatusHook     string       `protobuf:"bytes,4,opt,name=status_hook,json=statusHook,proto3" json:"status_hook,omitempty"`
+	IdempotencyKey string       `protobuf:"bytes,5,opt,name=idempotency_key,json=idempotencyKey,proto3" json:"idempotency_key,omitempty"`
+	unknownFields  protoimpl.UnknownFields
+	sizeCache      protoimpl.SizeCache
+}
+
+func (x *LaunchJobRequest) Reset() {
+	*x = LaunchJobRequest{}
+	mi := &file_jobservice_proto_msgTypes[3]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *LaunchJobRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*LaunchJobRequest) ProtoMessage() {}
+
+func (x *LaunchJobRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_jobservice_proto_msgTypes[3]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use LaunchJobRequest.ProtoReflect.Descriptor instead.
+func (*LaunchJobRequest) Descriptor() ([]byte, []int) {
+	return file_jobservice_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *LaunchJobRequest) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *LaunchJobRequest) GetParameters() []byte {
+	if x != nil {
+		return x.Parameters
+	}
+	return nil
+}
+
+func (x *LaunchJobRequest) GetMetadata() *JobMetadata {
+	if x != nil {
+		return x.Metadata
+	}
+	return nil
+}
+
+func (x *LaunchJobRequest) GetStatusHook() string {
+	if x != nil {
+		return x.StatusHook
+	}
+	return ""
+}
+
+func (x *LaunchJobRequest) GetIdempotencyKey() string {
+	if x != nil {
+		return x.IdempotencyKey
+	}
+	return ""
+}
+
+type JobStats struct {
+	state           protoimpl.MessageState `protogen:"open.v1"`
+	Id              string                 `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	Status          string                 `protobuf:"bytes,2,opt,name=status,proto3" json:"status,omitempty"`
+	Name            string                 `protobuf:"bytes,3,opt,name=name,proto3" json:"name,omitempty"`
+	Kind            string                 `protobuf:"bytes,4,opt,name=kind,proto3" json:"kind,omitempty"`
+	Unique          bool                   `protobuf:"varint,5,opt,name=unique,proto3" json:"unique,omitempty"`
+	RefLink         string                 `protobuf:"bytes,6,opt,name=ref_link,json=refLink,proto3" json:"ref_link,omitempty"`
+	CronSpec        string                 `protobuf:"bytes,7,opt,name=cron_spec,json=cronSpec,proto3" json:"cron_spec,omitempty"`
+	EnqueueTime     int64                  `protobuf:"varint,8,opt,name=enqueue_time,json=enqueueTime,proto3" json:"enqueue_time,omitempty"`
+	UpdateTime      int64                  `protobuf:"varint,9,opt,name=update_time,json=updateTime,proto3" json:"update_time,omitempty"`
+	RunAt           int64                  `protobuf:"varint,10,opt,name=run_at,json=runAt,proto3" json:"run_at,omitempty"`
+	CheckIn         string                 `protobuf:"bytes,11,opt,name=check_in,json=checkIn,proto3" json:"check_in,omitempty"`
+	CheckInAt       int64                  `protobuf:"varint,12,opt,name=check_in_at,json=checkInAt,proto3" json:"check_in_at,omitempty"`
+	DieAt           int64                  `protobuf:"varint,13,opt,name=die_at,json=dieAt,proto3" json:"die_at,omitempty"`
+	WebHookUrl      string                 `protobuf:"bytes,14,opt,name=web_hook_url,json=webHookUrl,proto3" json:"web_hook_url,omitempty"`
+	UpstreamJobId   string                 `protobuf:"bytes,15,opt,name=upstream_job_id,json=upstreamJobId,proto3" json:"upstream_job_id,omitempty"`
+	NumericPolicyId int64                  `protobuf:"varint,16,opt,name=numeric_policy_id,json=numericPolicyId,proto3" json:"numeric_policy_id,omitempty"`
+	// The parameters in JSON object, the secret parameters are redacted
+	Parameters    []byte        `protobuf:"bytes,17,opt,name=parameters,proto3" json:"parameters,omitempty"`
+	Revision      int64         `protobuf:"varint,18,opt,name=revision,proto3" json:"revision,omitempty"`
+	NodeId        string        `protobuf:"bytes,19,opt,name=node_id,json=nodeId,proto3" json:"node_id,omitempty"`
+	Schedule      *ScheduleSpec `protobuf:"bytes,20,opt,name=schedule,proto3" json:"schedule,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *JobStats) Reset() {
+	*x = JobStats{}
+	mi := &file_jobservice_proto_msgTypes[4]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *JobStats) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*JobStats) ProtoMessage() {}
+
+func (x *JobStats) ProtoReflect() protoreflect.Message {
+	mi := &file_jobservice_proto_msgTypes[4]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use JobStats.ProtoReflect.Descriptor instead.
+func (*JobStats) Descriptor() ([]byte, []int) {
+	return file_jobservice_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *JobStats) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+func (x *JobStats) GetStatus() string {
+	if x != nil {
+		return x.Status
+	}
+	return ""
+}
+
+func (x *JobStats) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *JobStats) GetKind() string {
+	if x != nil {
+		return x.Kind
+	}
+	return ""
+}
+
+func (x *JobStats) GetUnique() bool {
+	if x != nil {
+		return x.Unique
+	}
+	return false
+}
+
+func (x *JobStats) GetRefLink() string {
+	if x != nil {
+		return x.RefLink
+	}
+	return ""
+}
+
+func (x *JobStats) GetCronSpec() string {
+	if x != nil {
+		return x.CronSpec
+	}
+	return ""
+}
+
+func (x *JobStats) GetEnqueueTime() int64 {
+	if x != nil {
+		return x.EnqueueTime
+	}
+	return 0
+}
+
+func (x *JobStats) GetUpdateTime() int64 {
+	if x != nil {
+		return x.UpdateTime
+	}
+	return 0
+}
+
+func (x *JobStats) GetRunAt() int64 {
+	if x != nil {
+		return x.RunAt
+	}
+	return 0
+}
+
+func (x *JobStats) GetCheckIn() string {
+	if x != nil {
+		return x.CheckIn
+	}
+	return ""
+}
+
+func (x *JobStats) GetCheckInAt() int64 {
+	if x != nil {
+		return x.CheckInAt
+	}
+	return 0
+}
+
+func (x *JobStats) GetDieAt() int64 {
+	if x != nil {
+		return x.DieAt
+	}
+	return 0
+}
+
+func (x *JobStats) GetWebHookUrl() string {
+	if x != nil {
+		return x.WebHookUrl
+	}
+	return ""
+}
+
+func (x *JobStats) GetUpstreamJobId() string {
+	if x != nil {
+		return x.UpstreamJobId
+	}
+	return ""
+}
+
+func (x *JobStats) GetNumericPolicyId() int64 {
+	if x != nil {
+		return x.NumericPolicyId
+	}
+	return 0
+}
+
+func (x *JobStats) GetParameters() []byte {
+	if x != nil {
+		return x.Parameters
+	}
+	return nil
+}
+
+func (x *JobStats) GetRevision() int64 {
+	if x != nil {
+		return x.Revision
+	}
+	return 0
+}
+
+func (x *JobStats) GetNodeId() string {
+	if x != nil {
+		return x.NodeId
+	}
+	return ""
+}
+
+func (x *JobStats) GetSchedule() *ScheduleSpec {
+	if x != nil {
+		return x.Schedule
+	}
+	return nil
+}
+
+type GetJobRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	JobId         string                 `protobuf:"bytes,1,opt,name=job_id,json=jobId,proto3" json:"job_id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetJobRequest) Reset() {
+	*x = GetJobRequest{}
+	mi := &file_jobservice_proto_msgTypes[5]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetJobRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetJobRequest) ProtoMessage() {}
+
+func (x *GetJobRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_jobservice_proto_msgTypes[5]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetJobRequest.ProtoReflect.Descriptor instead.
+func (*GetJobRequest) Descriptor() ([]byte, []int) {
+	return file_jobservice_proto_rawDescGZIP(), []int{5}
+}
+
+func (x *GetJobRequest) GetJobId() string {
+	if x != nil {
+		return x.JobId
+	}
+	return ""
+}
+
+type ListJobsRequest struct {
+	state       protoimpl.MessageState `protogen:"open.v1"`
+	Kind        string                 `protobuf:"bytes,1,opt,name=kind,proto3" json:"kind,omitempty"`
+	Name        string                 `protobuf:"bytes,2,opt,name=name,proto3" json:"name,omitempty"`
+	Status      string                 `protobuf:"bytes,3,opt,name=status,proto3" json:"status,omitempty"`
+	NonDeadOnly bool                   `protobuf:"varint,4,opt,name=non_dead_only,json=nonDeadOnly,proto3" json:"non_dead_only,omitempty"`
+	PageNumber  uint32                 `protobuf:"varint,5,opt,name=page_number,json=pageNumber,proto3" json:"page_number,omitempty"`
+	PageSize    uint32                 `protobuf:"varint,6,opt,name=page_size,json=pageSize,proto3" json:"page_size,omitempty"`
+	// The cursor returned by the previous page, 0 starts the scanning
+	Cursor        uint64 `protobuf:"varint,7,opt,name=cursor,proto3" json:"cursor,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListJobsRequest) Reset() {
+	*x = ListJobsRequest{}
+	mi := &file_jobservice_proto_msgTypes[6]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListJobsRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListJobsRequest) ProtoMessage() {}
+
+func (x *ListJobsRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_jobservice_proto_msgTypes[6]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListJobsRequest.ProtoReflect.Descriptor instead.
+func (*ListJobsRequest) Descriptor() ([]byte, []int) {
+	return file_jobservice_proto_rawDescGZIP(), []int{6}
+}
+
+func (x *ListJobsRequest) GetKind() string {
+	if x != nil {
+		return x.Kind
+	}
+	return ""
+}
+
+func (x *ListJobsRequest) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *ListJobsRequest) GetStatus() string {
+	if x != nil {
+		return x.Status
+	}
+	return ""
+}
+
+func (x *ListJobsRequest) GetNonDeadOnly() bool {
+	if x != nil {
+		return x.NonDeadOnly
+	}
+	return false
+}
+
+func (x *ListJobsRequest) GetPageNumber() uint32 {
+	if x != nil {
+		return x.PageNumber
+	}
+	return 0
+}
+
+func (x *ListJobsRequest) GetPageSize() uint32 {
+	if x != nil {
+		return x.PageSize
+	}
+	return 0
+}
+
+func (x *ListJobsRequest) GetCursor() uint64 {
+	if x != nil {
+		return x.Cursor
+	}
+	return 0
+}
+
+type ListJobsResponse struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	Jobs  []*JobStats            `protobuf:"bytes,1,rep,name=jobs,proto3" json:"jobs,omitempty"`
+	// The cursor of the next scanning, 0 means the scanning is completed
+	NextCursor uint64 `protobuf:"varint,2,opt,name=next_cursor,json=nextCursor,proto3" json:"next_cursor,omitempty"`
+	// The total count of the scheduled jobs
+	Total         int64 `protobuf:"varint,3,opt,name=total,proto3" json:"total,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListJobsResponse) Reset() {
+	*x = ListJobsResponse{}
+	mi := &file_jobservice_proto_msgTypes[7]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListJobsResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListJobsResponse) ProtoMessage() {}
+
+func (x *ListJobsResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_jobservice_proto_msgTypes[7]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListJobsResponse.ProtoReflect.Descriptor instead.
+func (*ListJobsResponse) Descriptor() ([]byte, []int) {
+	return file_jobservice_proto_rawDescGZIP(), []int{7}
+}
+
+func (x *ListJobsResponse) GetJobs() []*JobStats {
+	if x != nil {
+		return x.Jobs
+	}
+	return nil
+}
+
+func (x *ListJobsResponse) GetNextCursor() uint64 {
+	if x != nil {
+		return x.NextCursor
+	}
+	return 0
+}
+
+func (x *ListJobsResponse) GetTotal() int64 {
+	if x != nil {
+		return x.Total
+	}
+	return 0
+}
+
+type JobActionRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	JobId         string                 `protobuf:"bytes,1,opt,name=job_id,json=jobId,proto3" json:"job_id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *JobActionRequest) Reset() {
+	*x = JobActionRequest{}
+	mi := &file_jobservice_proto_msgTypes[8]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *JobActionRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*JobActionRequest) ProtoMessage() {}
+
+func (x *JobActionRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_jobservice_proto_msgTypes[8]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use JobActionRequest.ProtoReflect.Descriptor instead.
+func (*JobActionRequest) Descriptor() ([]byte, []int) {
+	return file_jobservice_proto_rawDescGZIP(), []int{8}
+}
+
+func (x *JobActionRequest) GetJobId() string {
+	if x != nil {
+		return x.JobId
+	}
+	return ""
+}
+
+type StreamJobLogRequest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	JobId string                 `protobuf:"bytes,1,opt,name=job_id,json=jobId,proto3" json:"job_id,omitempty"`
+	// Keep streaming the appended log until the job is finished
+	Follow        bool `protobuf:"varint,2,opt,name=follow,proto3" json:"follow,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *StreamJobLogRequest) Reset() {
+	*x = StreamJobLogRequest{}
+	mi := &file_jobservice_proto_msgTypes[9]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *StreamJobLogRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*StreamJobLogRequest) ProtoMessage() {}
+
+func (x *StreamJobLogRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_jobservice_proto_msgTypes[9]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use StreamJobLogRequest.ProtoReflect.Descriptor instead.
+func (*StreamJobLogRequest) Descriptor() ([]byte, []int) {
+	return file_jobservice_proto_rawDescGZIP(), []int{9}
+}
+
+func (x *StreamJobLogRequest) GetJobId() string {
+	if x != nil {
+		return x.JobId
+	}
+	return ""
+}
+
+func (x *StreamJobLogRequest) GetFollow() bool {
+	if x != nil {
+		return x.Follow
+	}
+	return false
+}
+
+type LogChunk struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Data          []byte                 `protobuf:"bytes,1,opt,name=data,proto3" json:"data,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *LogChunk) Reset() {
+	*x = LogChunk{}
+	mi := &file_jobservice_proto_msgTypes[10]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *LogChunk) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*LogChunk) ProtoMessage() {}
+
+func (x *LogChunk) ProtoReflect() protoreflect.Message {
+	mi := &file_jobservice_proto_msgTypes[10]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use LogChunk.ProtoReflect.Descriptor instead.
+func (*LogChunk) Descriptor() ([]byte, []int) {
+	return file_jobservice_proto_rawDescGZIP(), []int{10}
+}
+
+func (x *LogChunk) GetData() []byte {
+	if x != nil {
+		return x.Data
+	}
+	return nil
+}
+
+type WatchStatusChangesRequest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// Filters of the status changes, empty means all
+	JobId   string `protobuf:"bytes,1,opt,name=job_id,json=jobId,proto3" json:"job_id,omitempty"`
+	JobName string `protobuf:"bytes,2,opt,name=job_name,json=jobName,proto3" json:"job_name,omitempty"`
+	// The ID of the periodic job whose executions are watched
+	UpstreamJobId string `protobuf:"bytes,3,opt,name=upstream_job_id,json=upstreamJobId,proto3" json:"upstream_job_id,omitempty"`
+	// Resume the stream after the event with the ID
+	LastEventId   string `protobuf:"bytes,4,opt,name=last_event_id,json=lastEventId,proto3" json:"last_event_id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *WatchStatusChangesRequest) Reset() {
+	*x = WatchStatusChangesRequest{}
+	mi := &file_jobservice_proto_msgTypes[11]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *WatchStatusChangesRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*WatchStatusChangesRequest) ProtoMessage() {}
+
+func (x *WatchStatusChangesRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_jobservice_proto_msgTypes[11]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use WatchStatusChangesRequest.ProtoReflect.Descriptor instead.
+func (*WatchStatusChangesRequest) Descriptor() ([]byte, []int) {
+	return file_jobservice_proto_rawDescGZIP(), []int{11}
+}
+
+func (x *WatchStatusChangesRequest) GetJobId() string {
+	if x != nil {
+		return x.JobId
+	}
+	return ""
+}
+
+func (x *WatchStatusChangesRequest) GetJobName() string {
+	if x != nil {
+		return x.JobName
+	}
+	return ""
+}
+
+func (x *WatchStatusChangesRequest) GetUpstreamJobId() string {
+	if x != nil {
+		return x.UpstreamJobId
+	}
+	return ""
+}
+
+func (x *WatchStatusChangesRequest) GetLastEventId() string {
+	if x != nil {
+		return x.LastEventId
+	}
+	return ""
+}
+
+type StatusChange struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// The ID of the event for resuming the stream
+	EventId       string    `protobuf:"bytes,1,opt,name=event_id,json=eventId,proto3" json:"event_id,omitempty"`
+	JobId         string    `protobuf:"bytes,2,opt,name=job_id,json=jobId,proto3" json:"job_id,omitempty"`
+	Status        string    `protobuf:"bytes,3,opt,name=status,proto3" json:"status,omitempty"`
+	CheckIn       string    `protobuf:"bytes,4,opt,name=check_in,json=checkIn,proto3" json:"check_in,omitempty"`
+	Metadata      *JobStats `protobuf:"bytes,5,opt,name=metadata,proto3" json:"metadata,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *StatusChange) Reset() {
+	*x = StatusChange{}
+	mi := &file_jobservice_proto_msgTypes[12]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *StatusChange) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*StatusChange) ProtoMessage() {}
+
+func (x *StatusChange) ProtoReflect() protoreflect.Message {
+	mi := &file_jobservice_proto_msgTypes[12]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use StatusChange.ProtoReflect.Descriptor instead.
+func (*StatusChange) Descriptor() ([]byte, []int) {
+	return file_jobservice_proto_rawDescGZIP(), []int{12}
+}
+
+func (x *StatusChange) GetEventId() string {
+	if x != nil {
+		return x.EventId
+	}
+	return ""
+}
+
+func (x *StatusChange) GetJobId() string {
+	if x != nil {
+		return x.JobId
+	}
+	return ""
+}
+
+func (x *StatusChange) GetStatus() string {
+	if x != nil {
+		return x.Status
+	}
+	return ""
+}
+
+func (x *StatusChange) GetCheckIn() string {
+	if x != nil {
+		return x.CheckIn
+	}
+	return ""
+}
+
+func (x *StatusChange) GetMetadata() *JobStats {
+	if x != nil {
+		return x.Metadata
+	}
+	return nil
+}
+
+type ScheduleSpec_Interval struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	EverySeconds  uint64                 `protobuf:"varint,1,opt,name=every_seconds,json=everySeconds,proto3" json:"every_seconds,omitempty"`
+	StartAt       int64                  `protobuf:"varint,2,opt,name=start_at,json=startAt,proto3" json:"start_at,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ScheduleSpec_Interval) Reset() {
+	*x = ScheduleSpec_Interval{}
+	mi := &file_jobservice_proto_msgTypes[13]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ScheduleSpec_Interval) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ScheduleSpec_Interval) ProtoMessage() {}
+
+func (x *ScheduleSpec_Interval) ProtoReflect() protoreflect.Message {
+	mi := &file_jobservice_proto_msgTypes[13]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ScheduleSpec_Interval.ProtoReflect.Descriptor instead.
+func (*ScheduleSpec_Interval) Descriptor() ([]byte, []int) {
+	return file_jobservice_proto_rawDescGZIP(), []int{1, 0}
+}
+
+func (x *ScheduleSpec_Interval) GetEverySeconds() uint64 {
+	if x != nil {
+		return x.EverySeconds
+	}
+	return 0
+}
+
+func (x *ScheduleSpec_Interval) GetStartAt() int64 {
+	if x != nil {
+		return x.StartAt
+	}
+	return 0
+}
+
+type ScheduleSpec_BlackoutWindow struct {
+	state           protoimpl.MessageState `protogen:"open.v1"`
+	Start           string                 `protobuf:"bytes,1,opt,name=start,proto3" json:"start,omitempty"`
+	DurationSeconds uint64                 `protobuf:"varint,2,opt,name=duration_seconds,json=durationSeconds,proto3" json:"duration_seconds,omitempty"`
+	TimeZone        string                 `protobuf:"bytes,3,opt,name=time_zone,json=timeZone,proto3" json:"time_zone,omitempty"`
+	unknownFields   protoimpl.UnknownFields
+	sizeCache       protoimpl.SizeCache
+}
+
+func (x *ScheduleSpec_BlackoutWindow) Reset() {
+	*x = ScheduleSpec_BlackoutWindow{}
+	mi := &file_jobservice_proto_msgTypes[14]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ScheduleSpec_BlackoutWindow) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ScheduleSpec_BlackoutWindow) ProtoMessage() {}
+
+func (x *ScheduleSpec_BlackoutWindow) ProtoReflect() protoreflect.Message {
+	mi := &file_jobservice_proto_msgTypes[14]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ScheduleSpec_BlackoutWindow.ProtoReflect.Descriptor instead.
+func (*ScheduleSpec_BlackoutWindow) Descriptor() ([]byte, []int) {
+	return file_jobservice_proto_rawDescGZIP(), []int{1, 1}
+}
+
+func (x *ScheduleSpec_BlackoutWindow) GetStart() string {
+	if x != nil {
+		return x.Start
+	}
+	return ""
+}
+
+func (x *ScheduleSpec_BlackoutWindow) GetDurationSeconds() uint64 {
+	if x != nil {
+		return x.DurationSeconds
+	}
+	return 0
+}
+
+func (x *ScheduleSpec_BlackoutWindow) GetTimeZone() string {
+	if x != nil {
+		return x.TimeZone
+	}
+	return ""
+}
+
+var File_jobservice_proto protoreflect.FileDescriptor
+
+const file_jobservice_proto_rawDesc = "" +
+	"\n" +
+	"\x10jobservice.proto\x12\rjobservice.v1\"\a\n" +
+	"\x05Empty\"\xf1\x02\n" +
+	"\fScheduleSpec\x12@\n" +
+	"\binterval\x18\x01 \x01(\v2$.jobservice.v1.ScheduleSpec.IntervalR\binterval\x12\x19\n" +
+	"\brun_list\x18\x02 \x03(\x03R\arunList\x12H\n" +
+	"\tblackouts\x18\x03 \x03(\v2*.jobservice.v1.ScheduleSpec.BlackoutWindowR\tblackouts\x1aJ\n" +
+	"\bInterval\x12#\n" +
+	"\revery_seconds\x18\x01 \x01(\x04R\feverySeconds\x12\x19\n" +
+	"\bstart_at\x18\x02 \x01(\x03R\astartAt\x1an\n" +
+	"\x0eBlackoutWindow\x12\x14\n" +
+	"\x05start\x18\x01 \x01(\tR\x05start\x12)\n" +
+	"\x10duration_seconds\x18\x02 \x01(\x04R\x0fdurationSeconds\x12\x1b\n" +
+	"\ttime_zone\x18\x03 \x01(\tR\btimeZone\"\xb3\x02\n" +
+	"\vJobMetadata\x12\x12\n" +
+	"\x04kind\x18\x01 \x01(\tR\x04kind\x12%\n" +
+	"\x0eschedule_delay\x18\x02 \x01(\x04R\rscheduleDelay\x12\x1b\n" +
+	"\tcron_spec\x18\x03 \x01(\tR\bcronSpec\x12\x16\n" +
+	"\x06unique\x18\x04 \x01(\bR\x06unique\x12\x1a\n" +
+	"\bpriority\x18\x05 \x01(\tR\bpriority\x12\x1f\n" +
+	"\vunique_keys\x18\x06 \x03(\tR\n" +
+	"uniqueKeys\x12\x1d\n" +
+	"\n" +
+	"unique_ttl\x18\a \x01(\x04R\tuniqueTtl\x12\x1f\n" +
+	"\vsecret_keys\x18\b \x03(\tR\n" +
+	"secretKeys\x127\n" +
+	"\bschedule\x18\t \x01(\v2\x1b.jobservice.v1.ScheduleSpecR\bschedule\"\xc8\x01\n" +
+	"\x10LaunchJobRequest\x12\x12\n" +
+	"\x04name\x18\x01 \x01(\tR\x04name\x12\x1e\n" +
+	"\n" +
+	"parameters\x18\x02 \x01(\fR\n" +
+	"parameters\x126\n" +
+	"\bmetadata\x18\x03 \x01(\v2\x1a.jobservice.v1.JobMetadataR\bmetadata\x12\x1f\n" +
+	"\vstatus_hook\x18\x04 \x01(\tR\n" +
+	"statusHook\x12'\n" +
+	"\x0fidempotency_key\x18\x05 \x01(\tR\x0eidempotencyKey\"\xdb\x04\n" +
+	"\bJobStats\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\tR\x02id\x12\x16\n" +
+	"\x06status\x18\x02 \x01(\tR\x06status\x12\x12\n" +
+	"\x04name\x18\x03 \x01(\tR\x04name\x12\x12\n" +
+	"\x04kind\x18\x04 \x01(\tR\x04kind\x12\x16\n" +
+	"\x06unique\x18\x05 \x01(\bR\x06unique\x12\x19\n" +
+	"\bref_link\x18\x06 \x01(\tR\arefLink\x12\x1b\n" +
+	"\tcron_spec\x18\a \x01(\tR\bcronSpec\x12!\n" +
+	"\fenqueue_time\x18\b \x01(\x03R\venqueueTime\x12\x1f\n" +
+	"\vupdate_time\x18\t \x01(\x03R\n" +
+	"updateTime\x12\x15\n" +
+	"\x06run_at\x18\n" +
+	" \x01(\x03R\x05runAt\x12\x19\n" +
+	"\bcheck_in\x18\v \x01(\tR\acheckIn\x12\x1e\n" +
+	"\vcheck_in_at\x18\f \x01(\x03R\tcheckInAt\x12\x15\n" +
+	"\x06die_at\x18\r \x01(\x03R\x05dieAt\x12 \n" +
+	"\fweb_hook_url\x18\x0e \x01(\tR\n" +
+	"webHookUrl\x12&\n" +
+	"\x0fupstream_job_id\x18\x0f \x01(\tR\rupstreamJobId\x12*\n" +
+	"\x11numeric_policy_id\x18\x10 \x01(\x03R\x0fnumericPolicyId\x12\x1e\n" +
+	"\n" +
+	"parameters\x18\x11 \x01(\fR\n" +
+	"parameters\x12\x1a\n" +
+	"\brevision\x18\x12 \x01(\x03R\brevision\x12\x17\n" +
+	"\anode_id\x18\x13 \x01(\tR\x06nodeId\x127\n" +
+	"\bschedule\x18\x14 \x01(\v2\x1b.jobservice.v1.ScheduleSpecR\bschedule\"&\n" +
+	"\rGetJobRequest\x12\x15\n" +
+	"\x06job_id\x18\x01 \x01(\tR\x05jobId\"\xcb\x01\n" +
+	"\x0fListJobsRequest\x12\x12\n" +
+	"\x04kind\x18\x01 \x01(\tR\x04kind\x12\x12\n" +
+	"\x04name\x18\x02 \x01(\tR\x04name\x12\x16\n" +
+	"\x06status\x18\x03 \x01(\tR\x06status\x12\"\n" +
+	"\rnon_dead_only\x18\x04 \x01(\bR\vnonDeadOnly\x12\x1f\n" +
+	"\vpage_number\x18\x05 \x01(\rR\n" +
+	"pageNumber\x12\x1b\n" +
+	"\tpage_size\x18\x06 \x01(\rR\bpageSize\x12\x16\n" +
+	"\x06cursor\x18\a \x01(\x04R\x06cursor\"v\n" +
+	"\x10ListJobsResponse\x12+\n" +
+	"\x04jobs\x18\x01 \x03(\v2\x17.jobservice.v1.JobStatsR\x04jobs\x12\x1f\n" +
+	"\vnext_cursor\x18\x02 \x01(\x04R\n" +
+	"nextCursor\x12\x14\n" +
+	"\x05total\x18\x03 \x01(\x03R\x05total\")\n" +
+	"\x10JobActionRequest\x12\x15\n" +
+	"\x06job_id\x18\x01 \x01(\tR\x05jobId\"D\n" +
+	"\x13StreamJobLogRequest\x12\x15\n" +
+	"\x06job_id\x18\x01 \x01(\tR\x05jobId\x12\x16\n" +
+	"\x06follow\x18\x02 \x01(\bR\x06follow\"\x1e\n" +
+	"\bLogChunk\x12\x12\n" +
+	"\x04data\x18\x01 \x01(\fR\x04data\"\x99\x01\n" +
+	"\x19WatchStatusChangesRequest\x12\x15\n" +
+	"\x06job_id\x18\x01 \x01(\tR\x05jobId\x12\x19\n" +
+	"\bjob_name\x18\x02 \x01(\tR\ajobName\x12&\n" +
+	"\x0fupstream_job_id\x18\x03 \x01(\tR\rupstreamJobId\x12\"\n" +
+	"\rlast_event_id\x18\x04 \x01(\tR\vlastEventId\"\xa8\x01\n" +
+	"\fStatusChange\x12\x19\n" +
+	"\bevent_id\x18\x01 \x01(\tR\aeventId\x12\x15\n" +
+	"\x06job_id\x18\x02 \x01(\tR\x05jobId\x12\x16\n" +
+	"\x06status\x18\x03 \x01(\tR\x06status\x12\x19\n" +
+	"\bcheck_in\x18\x04 \x01(\tR\acheckIn\x123\n" +
+	"\bmetadata\x18\x05 \x01(\v2\x17.jobservice.v1.JobStatsR\bmetadata2\x94\x04\n" +
+	"\n" +
+	"JobService\x12E\n" +
+	"\tLaunchJob\x12\x1f.jobservice.v1.LaunchJobRequest\x1a\x17.jobservice.v1.JobStats\x12?\n" +
+	"\x06GetJob\x12\x1c.jobservice.v1.GetJobRequest\x1a\x17.jobservice.v1.JobStats\x12K\n" +
+	"\bListJobs\x12\x1e.jobservice.v1.ListJobsRequest\x1a\x1f.jobservice.v1.ListJobsResponse\x12@\n" +
+	"\aStopJob\x12\x1f.jobservice.v1.JobActionRequest\x1a\x14.jobservice.v1.Empty\x12A\n" +
+	"\bRetryJob\x12\x1f.jobservice.v1.JobActionRequest\x1a\x14.jobservice.v1.Empty\x12M\n" +
+	"\fStreamJobLog\x12\".jobservice.v1.StreamJobLogRequest\x1a\x17.jobservice.v1.LogChunk0\x01\x12]\n" +
+	"\x12WatchStatusChanges\x12(.jobservice.v1.WatchStatusChangesRequest\x1a\x1b.jobservice.v1.StatusChange0\x01BFZDgithub.com/chenxull/goGridhub/gridhub/src/jobservice/api/proto;protob\x06proto3"
+
+var (
+	file_jobservice_proto_rawDescOnce sync.Once
+	file_jobservice_proto_rawDescData []byte
+)
+
+func file_jobservice_proto_rawDescGZIP() []byte {
+	file_jobservice_proto_rawDescOnce.Do(func() {
+		file_jobservice_proto_rawDescData = protoimpl.X.CompressGZIP(unsafe.Slice(unsafe.StringData(file_jobservice_proto_rawDesc), len(file_jobservice_proto_rawDesc)))
+	})
+	return file_jobservice_proto_rawDescData
+}
+
+var file_jobservice_proto_msgTypes = make([]protoimpl.MessageInfo, 15)
+var file_jobservice_proto_goTypes = []any{
+	(*Empty)(nil),                       // 0: jobservice.v1.Empty
+	(*ScheduleSpec)(nil),                // 1: jobservice.v1.ScheduleSpec
+	(*JobMetadata)(nil),                 // 2: jobservice.v1.JobMetadata
+	(*LaunchJobRequest)(nil),            // 3: jobservice.v1.LaunchJobRequest
+	(*JobStats)(nil),                    // 4: jobservice.v1.JobStats
+	(*GetJobRequest)(nil),               // 5: jobservice.v1.GetJobRequest
+	(*ListJobsRequest)(nil),             // 6: jobservice.v1.ListJobsRequest
+	(*ListJobsResponse)(nil),            // 7: jobservice.v1.ListJobsResponse
+	(*JobActionRequest)(nil),            // 8: jobservice.v1.JobActionRequest
+	(*StreamJobLogRequest)(nil),         // 9: jobservice.v1.StreamJobLogRequest
+	(*LogChunk)(nil),                    // 10: jobservice.v1.LogChunk
+	(*WatchStatusChangesRequest)(nil),   // 11: jobservice.v1.WatchStatusChangesRequest
+	(*StatusChange)(nil),                // 12: jobservice.v1.StatusChange
+	(*ScheduleSpec_Interval)(nil),       // 13: jobservice.v1.ScheduleSpec.Interval
+	(*ScheduleSpec_BlackoutWindow)(nil), // 14: jobservice.v1.ScheduleSpec.BlackoutWindow
+}
+var file_jobservice_proto_depIdxs = []int32{
+	13, // 0: jobservice.v1.ScheduleSpec.interval:type_name -> jobservice.v1.ScheduleSpec.Interval
+	14, // 1: jobservice.v1.ScheduleSpec.blackouts:type_name -> jobservice.v1.ScheduleSpec.BlackoutWindow
+	1,  // 2: jobservice.v1.JobMetadata.schedule:type_name -> jobservice.v1.ScheduleSpec
+	2,  // 3: jobservice.v1.LaunchJobRequest.metadata:type_name -> jobservice.v1.JobMetadata
+	1,  // 4: jobservice.v1.JobStats.schedule:type_name -> jobservice.v1.ScheduleSpec
+	4,  // 5: jobservice.v1.ListJobsResponse.jobs:type_name -> jobservice.v1.JobStats
+	4,  // 6: jobservice.v1.StatusChange.metadata:type_name -> jobservice.v1.JobStats
+	3,  // 7: jobservice.v1.JobService.LaunchJob:input_type -> jobservice.v1.LaunchJobRequest
+	5,  // 8: jobservice.v1.JobService.GetJob:input_type -> jobservice.v1.GetJobRequest
+	6,  // 9: jobservice.v1.JobService.ListJobs:input_type -> jobservice.v1.ListJobsRequest
+	8,  // 10: jobservice.v1.JobService.StopJob:input_type -> jobservice.v1.JobActionRequest
+	8,  // 11: jobservice.v1.JobService.RetryJob:input_type -> jobservice.v1.JobActionRequest
+	9,  // 12: jobservice.v1.JobService.StreamJobLog:input_type -> jobservice.v1.StreamJobLogRequest
+	11, // 13: jobservice.v1.JobService.WatchStatusChanges:input_type -> jobservice.v1.WatchStatusChangesRequest
+	4,  // 14: jobservice.v1.JobService.LaunchJob:output_type -> jobservice.v1.JobStats
+	4,  // 15: jobservice.v1.JobService.GetJob:output_type -> jobservice.v1.JobStats
+	7,  // 16: jobservice.v1.JobService.ListJobs:output_type -> jobservice.v1.ListJobsResponse
+	0,  // 17: jobservice.v1.JobService.StopJob:output_type -> jobservice.v1.Empty
+	0,  // 18: jobservice.v1.JobService.RetryJob:output_type -> jobservice.v1.Empty
+	10, // 19: jobservice.v1.JobService.StreamJobLog:output_type -> jobservice.v1.LogChunk
+	12, // 20: jobservice.v1.JobService.WatchStatusChanges:output_type -> jobservice.v1.StatusChange
+	14, // [14:21] is the sub-list for method output_type
+	7,  // [7:14] is the sub-list for method input_type
+	7,  // [7:7] is the sub-list for extension type_name
+	7,  // [7:7] is the sub-list for extension extendee
+	0,  // [0:7] is the sub-list for field type_name
+}
+
+func init() { file_jobservice_proto_init() }
+func file_jobservice_proto_init() {
+	if File_jobservice_proto != nil {
+		return
+	}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: unsafe.Slice(unsafe.StringData(file_jobservice_proto_rawDesc), len(file_jobservice_proto_rawDesc)),
+			NumEnums:      0,
+			NumMessages:   15,
+			NumExtensions: 0,
+			NumServices:   1,
+		},
+		GoTypes:           file_jobservice_proto_goTypes,
+		DependencyIndexes: file_jobservice_proto_depIdxs,
+		MessageInfos:      file_jobservice_proto_msgTypes,
+	}.Build()
+	File_jobservice_proto = out.File
+	file_jobservice_proto_goTypes = nil
+	file_jobservice_proto_depIdxs = nil
+}
@@ -0,0 +1,379 @@
+// The gRPC interface of the job service, served alongside the HTTP API and backed by the same core controller.
+// The calls are authenticated by the same authenticator as the HTTP API with the 'authorization' metadata,
+// e.g: 'Harbor-Secret <secret>' or 'Bearer <token>'.
+//
+// The errors are returned with the gRPC status codes mapped from the errs package of the job service:
+// NOT_FOUND, ALREADY_EXISTS (conflict), INVALID_ARGUMENT (bad request), FAILED_PRECONDITION (status mismatch),
+// RESOURCE_EXHAUSTED (rate limited), UNAVAILABLE (overloaded), UNAUTHENTICATED, PERMISSION_DENIED and INTERNAL.
+// The errs code is attached to the status message in the form of 'code=<code>'.
+
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.5.1
+// - protoc             (unknown)
+// source: jobservice.proto
+
+package proto
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.64.0 or later.
+const _ = grpc.SupportPackageIsVersion9
+
+const (
+	JobService_LaunchJob_FullMethodName          = "/jobservice.v1.JobService/LaunchJob"
+	JobService_GetJob_FullMethodName             = "/jobservice.v1.JobService/GetJob"
+	JobService_ListJobs_FullMethodName           = "/jobservice.v1.JobService/ListJobs"
+	JobService_StopJob_FullMethodName            = "/jobservice.v1.JobService/StopJob"
+	JobService_RetryJob_FullMethodName           = "/jobservice.v1.JobService/RetryJob"
+	JobService_StreamJobLog_FullMethodName       = "/jobservice.v1.JobService/StreamJobLog"
+	JobService_WatchStatusChanges_FullMethodName = "/jobservice.v1.JobService/WatchStatusChanges"
+)
+
+// JobServiceClient is the client API for JobService service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+type JobServiceClient interface {
+	// Launch the job, the idempotency key avoids launching the same job twice
+	LaunchJob(ctx context.Context, in *LaunchJobRequest, opts ...grpc.CallOption) (*JobStats, error)
+	// Get the stats of the job
+	GetJob(ctx context.Context, in *GetJobRequest, opts ...grpc.CallOption) (*JobStats, error)
+	// List the jobs, the jobs are scanned with the cursor except the scheduled ones
+	ListJobs(ctx context.Context, in *ListJobsRequest, opts ...grpc.CallOption) (*ListJobsResponse, error)
+	// Stop the job
+	StopJob(ctx context.Context, in *JobActionRequest, opts ...grpc.CallOption) (*Empty, error)
+	// Retry the failed job
+	RetryJob(ctx context.Context, in *JobActionRequest, opts ...grpc.CallOption) (*Empty, error)
+	// Stream the log of the job, the stream is kept open to follow the running job if 'follow' is set
+	StreamJobLog(ctx context.Context, in *StreamJobLogRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[LogChunk], error)
+	// Stream the status changes of the jobs matched with the filter
+	WatchStatusChanges(ctx context.Context, in *WatchStatusChangesRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[StatusChange], error)
+}
+
+type jobServiceClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewJobServiceClient(cc grpc.ClientConnInterface) JobServiceClient {
+	return &jobServiceClient{cc}
+}
+
+func (c *jobServiceClient) LaunchJob(ctx context.Context, in *LaunchJobRequest, opts ...grpc.CallOption) (*JobStats, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(JobStats)
+	err := c.cc.Invoke(ctx, JobService_LaunchJob_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *jobServiceClient) GetJob(ctx context.Context, in *GetJobRequest, opts ...grpc.CallOption) (*JobStats, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(JobStats)
+	err := c.cc.Invoke(ctx, JobService_GetJob_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *jobServiceClient) ListJobs(ctx context.Context, in *ListJobsRequest, opts ...grpc.CallOption) (*ListJobsResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ListJobsResponse)
+	err := c.cc.Invoke(ctx, JobService_ListJobs_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *jobServiceClient) StopJob(ctx context.Context, in *JobActionRequest, opts ...grpc.CallOption) (*Empty, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Empty)
+	err := c.cc.Invoke(ctx, JobService_StopJob_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *jobServiceClient) RetryJob(ctx context.Context, in *JobActionRequest, opts ...grpc.CallOption) (*Empty, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Empty)
+	err := c.cc.Invoke(ctx, JobService_RetryJob_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *jobServiceClient) StreamJobLog(ctx context.Context, in *StreamJobLogRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[LogChunk], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &JobService_ServiceDesc.Streams[0], JobService_StreamJobLog_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[StreamJobLogRequest, LogChunk]{ClientStream: stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type JobService_StreamJobLogClient = grpc.ServerStreamingClient[LogChunk]
+
+func (c *jobServiceClient) WatchStatusChanges(ctx context.Context, in *WatchStatusChangesRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[StatusChange], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &JobService_ServiceDesc.Streams[1], JobService_WatchStatusChanges_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[WatchStatusChangesRequest, StatusChange]{ClientStream: stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type JobService_WatchStatusChangesClient = grpc.ServerStreamingClient[StatusChange]
+
+// JobServiceServer is the server API for JobService service.
+// All implementations must embed UnimplementedJobServiceServer
+// for forward compatibility.
+type JobServiceServer interface {
+	// Launch the job, the idempotency key avoids launching the same job twice
+	LaunchJob(context.Context, *LaunchJobRequest) (*JobStats, error)
+	// Get the stats of the job
+	GetJob(context.Context, *GetJobRequest) (*JobStats, error)
+	// List the jobs, the jobs are scanned with the cursor except the scheduled ones
+	ListJobs(context.Context, *ListJobsRequest) (*ListJobsResponse, error)
+	// Stop the job
+	StopJob(context.Context, *JobActionRequest) (*Empty, error)
+	// Retry the failed job
+	RetryJob(context.Context, *JobActionRequest) (*Empty, error)
+	// Stream the log of the job, the stream is kept open to follow the running job if 'follow' is set
+	StreamJobLog(*StreamJobLogRequest, grpc.ServerStreamingServer[LogChunk]) error
+	// Stream the status changes of the jobs matched with the filter
+	WatchStatusChanges(*WatchStatusChangesRequest, grpc.ServerStreamingServer[StatusChange]) error
+	mustEmbedUnimplementedJobServiceServer()
+}
+
+// UnimplementedJobServiceServer must be embedded to have
+// forward compatible implementations.
+//
+// NOTE: this should be embedded by value instead of pointer to avoid a nil
+// pointer dereference when methods are called.
+type UnimplementedJobServiceServer struct{}
+
+func (UnimplementedJobServiceServer) LaunchJob(context.Context, *LaunchJobRequest) (*JobStats, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method LaunchJob not implemented")
+}
+func (UnimplementedJobServiceServer) GetJob(context.Context, *GetJobRequest) (*JobStats, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetJob not implemented")
+}
+func (UnimplementedJobServiceServer) ListJobs(context.Context, *ListJobsRequest) (*ListJobsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListJobs not implemented")
+}
+func (UnimplementedJobServiceServer) StopJob(context.Context, *JobActionRequest) (*Empty, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method StopJob not implemented")
+}
+func (UnimplementedJobServiceServer) RetryJob(context.Context, *JobActionRequest) (*Empty, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method RetryJob not implemented")
+}
+func (UnimplementedJobServiceServer) StreamJobLog(*StreamJobLogRequest, grpc.ServerStreamingServer[LogChunk]) error {
+	return status.Errorf(codes.Unimplemented, "method StreamJobLog not implemented")
+}
+func (UnimplementedJobServiceServer) WatchStatusChanges(*WatchStatusChangesRequest, grpc.ServerStreamingServer[StatusChange]) error {
+	return status.Errorf(codes.Unimplemented, "method WatchStatusChanges not implemented")
+}
+func (UnimplementedJobServiceServer) mustEmbedUnimplementedJobServiceServer() {}
+func (UnimplementedJobServiceServer) testEmbeddedByValue()                    {}
+
+// UnsafeJobServiceServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to JobServiceServer will
+// result in compilation errors.
+type UnsafeJobServiceServer interface {
+	mustEmbedUnimplementedJobServiceServer()
+}
+
+func RegisterJobServiceServer(s grpc.ServiceRegistrar, srv JobServiceServer) {
+	// If the following call pancis, it indicates UnimplementedJobServiceServer was
+	// embedded by pointer and is nil.  This will cause panics if an
+	// unimplemented method is ever invoked, so we test this at initialization
+	// time to prevent it from happening at runtime later due to I/O.
+	if t, ok := srv.(interface{ testEmbeddedByValue() }); ok {
+		t.testEmbeddedByValue()
+	}
+	s.RegisterService(&JobService_ServiceDesc, srv)
+}
+
+func _JobService_LaunchJob_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(LaunchJobRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(JobServiceServer).LaunchJob(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: JobService_LaunchJob_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(JobServiceServer).LaunchJob(ctx, req.(*LaunchJobRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _JobService_GetJob_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetJobRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(JobServiceServer).GetJob(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: JobService_GetJob_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(JobServiceServer).GetJob(ctx, req.(*GetJobRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _JobService_ListJobs_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListJobsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(JobServiceServer).ListJobs(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: JobService_ListJobs_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(JobServiceServer).ListJobs(ctx, req.(*ListJobsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _JobService_StopJob_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(JobActionRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(JobServiceServer).StopJob(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: JobService_StopJob_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(JobServiceServer).StopJob(ctx, req.(*JobActionRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _JobService_RetryJob_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(JobActionRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(JobServiceServer).RetryJob(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: JobService_RetryJob_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(JobServiceServer).RetryJob(ctx, req.(*JobActionRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _JobService_StreamJobLog_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(StreamJobLogRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(JobServiceServer).StreamJobLog(m, &grpc.GenericServerStream[StreamJobLogRequest, LogChunk]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type JobService_StreamJobLogServer = grpc.ServerStreamingServer[LogChunk]
+
+func _JobService_WatchStatusChanges_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(WatchStatusChangesRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(JobServiceServer).WatchStatusChanges(m, &grpc.GenericServerStream[WatchStatusChangesRequest, StatusChange]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type JobService_WatchStatusChangesServer = grpc.ServerStreamingServer[StatusChange]
+
+// JobService_ServiceDesc is the grpc.ServiceDesc for JobService service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var JobService_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "jobservice.v1.JobService",
+	HandlerType: (*JobServiceServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "LaunchJob",
+			Handler:    _JobService_LaunchJob_Handler,
+		},
+		{
+			MethodName: "GetJob",
+			Handler:    _JobService_GetJob_Handler,
+		},
+		{
+			MethodName: "ListJobs",
+			Handler:    _JobService_ListJobs_Handler,
+		},
+		{
+			MethodName: "StopJob",
+			Handler:    _JobService_StopJob_Handler,
+		},
+		{
+			MethodName: "RetryJob",
+			Handler:    _JobService_RetryJob_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "StreamJobLog",
+			Handler:       _JobService_StreamJobLog_Handler,
+			ServerStreams: true,
+		},
+		{
+			StreamName:    "WatchStatusChanges",
+			Handler:       _JobService_WatchStatusChanges_Handler,
+			ServerStreams: true,
+		},
+	},
+	Metadata: "jobservice.proto",
+}
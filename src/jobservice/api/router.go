@@ -1,19 +1,35 @@
 package api
 
 import (
-	"fmt"
+	"bytes"
+	"context"
+	"encoding/json"
+	"github.com/chenxull/goGridhub/gridhub/src/jobservice/audit"
+	"github.com/chenxull/goGridhub/gridhub/src/jobservice/core"
 	"github.com/chenxull/goGridhub/gridhub/src/jobservice/errs"
-	"github.com/chenxull/goGridhub/gridhub/src/jobservice/logger"
+	"github.com/chenxull/goGridhub/gridhub/src/jobservice/job"
+	"github.com/chenxull/goGridhub/gridhub/src/jobservice/ratelimit"
 	"github.com/gorilla/mux"
 	"github.com/pkg/errors"
+	"io/ioutil"
+	"math"
 	"net/http"
+	"strconv"
 )
 
 const (
-	baseRoute  = "/api"
-	apiVersion = "/v1"
+	baseRoute    = "/api"
+	apiVersion   = "/v1"
+	apiVersionV2 = "/v2"
 )
 
+// The paths served without auth, /stats is the health check endpoint
+var publicPaths = map[string]bool{
+	baseRoute + apiVersion + "/stats":          true,
+	baseRoute + apiVersionV2 + "/stats":        true,
+	baseRoute + apiVersionV2 + "/openapi.yaml": true,
+}
+
 //Router defines the related routes for the job service and directs the request to the right handler
 //method
 
@@ -31,14 +47,26 @@ type BaseRouter struct {
 
 	//Do Auth
 	authenticator Authenticator
+
+	// Limit the rate of launching jobs, nil means no limit
+	limiter ratelimit.Limiter
+
+	// Look up the jobs targeted by the requests for checking the permissions
+	controller core.Interface
+
+	// Sink of the audit log for the launching requests rejected by the router, nil means no auditing
+	auditor audit.Sink
 }
 
 //NewBaseRouter is the constructor of BaseRouter
-func NewBaseRouter(handler Handler, authenticator Authenticator) Router {
+func NewBaseRouter(handler Handler, authenticator Authenticator, limiter ratelimit.Limiter, ctl core.Interface, auditor audit.Sink) Router {
 	br := &BaseRouter{
 		router:        mux.NewRouter(),
 		handler:       handler,
 		authenticator: authenticator,
+		limiter:       limiter,
+		controller:    ctl,
+		auditor:       auditor,
 	}
 
 	//Register routes here
@@ -47,21 +75,37 @@ func NewBaseRouter(handler Handler, authenticator Authenticator) Router {
 }
 
 // ServeHTTP is the implementation of Router interface.
-func (br *BaseRouter) ServerHTTP(w http.ResponseWriter, req *http.Request) {
-	// No auth required for /stats as it is a health check endpoint
+func (br *BaseRouter) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	// No auth required for the public paths
 	// Do auth for other services
-	if req.URL.String() != fmt.Sprintf("%s/%s/stats", baseRoute, apiVersion) {
-		if err := br.authenticator.DoAuth(req); err != nil {
+	if !publicPaths[req.URL.Path] {
+		identity, err := br.authenticator.DoAuth(req)
+		if err != nil {
 			authErr := errs.UnauthorizedError(err)
 			if authErr == nil {
 				authErr = errors.Errorf("unauthorized: %s", err)
 			}
-			logger.Errorf("Serve http request '%s %s' failed with error: %s", req.Method, req.URL.String(), authErr.Error())
-			w.WriteHeader(http.StatusUnauthorized)
-			writeDate(w, []byte(authErr.Error()))
+			br.auditRejectedLaunch(req, http.StatusUnauthorized, authErr)
+			writeError(w, req, http.StatusUnauthorized, authErr)
+
+			return
+		}
+
+		// Keep the caller for the follow-up checks
+		req = req.WithContext(context.WithValue(req.Context(), callerKey, identity))
+
+		// Check the scope granted to the caller
+		jobName, resolved, err := br.authorize(req, identity)
+		if err != nil {
+			br.auditRejectedLaunch(req, http.StatusInternalServerError, err)
+			writeError(w, req, http.StatusInternalServerError, err)
 
 			return
 		}
+		if resolved {
+			// Keep the target job for the auditing
+			req = req.WithContext(context.WithValue(req.Context(), targetJobKey, jobName))
+		}
 	}
 
 	// Directly pass requests to the server mux
@@ -69,15 +113,96 @@ func (br *BaseRouter) ServerHTTP(w http.ResponseWriter, req *http.Request) {
 }
 
 func (br *BaseRouter) registerRoutes() {
+	// The v2 API keeps the routes of v1 and is described by the OpenAPI document
+	br.registerVersionRoutes(apiVersion)
+	v2 := br.registerVersionRoutes(apiVersionV2)
+	v2.HandleFunc("/openapi.yaml", handleOpenAPIReq).Methods(http.MethodGet)
+}
+
+// registerVersionRoutes registers the job routes under the prefix of the API version
+func (br *BaseRouter) registerVersionRoutes(version string) *mux.Router {
 	// remove the prefix of of the request router
-	subRouter := br.router.PathPrefix(fmt.Sprintf("%s/%s", baseRoute, apiVersion)).Subrouter()
+	subRouter := br.router.PathPrefix(baseRoute + version).Subrouter()
 
-	subRouter.HandleFunc("/jobs", br.handler.HandlerLaunchJobReq).Methods(http.MethodPost)
-	subRouter.HandleFunc("/jobs", br.handler.HandleGetJobsReq).Methods(http.MethodGet)
-	subRouter.HandleFunc("/jobs/{job_id}", br.handler.HandleGetJobReq).Methods(http.MethodGet)
-	subRouter.HandleFunc("/jobs/{job_id}", br.handler.HandleJobActionReq).Methods(http.MethodPost)
-	subRouter.HandleFunc("/jobs/{job_id}/log", br.handler.HandleJobLogReq).Methods(http.MethodGet)
+	subRouter.HandleFunc("/jobs", br.limitRate(br.handler.HandlerLaunchJobReq)).Methods(http.MethodPost).Name(routeLaunchJob)
+	subRouter.HandleFunc("/jobs", br.handler.HandleGetJobsReq).Methods(http.MethodGet).Name(routeGetJobs)
+	subRouter.HandleFunc("/jobs/{job_id}", br.handler.HandleGetJobReq).Methods(http.MethodGet).Name(routeGetJob)
+	subRouter.HandleFunc("/jobs/{job_id}", br.handler.HandleJobActionReq).Methods(http.MethodPost).Name(routeJobAction)
+	subRouter.HandleFunc("/jobs/{job_id}/log", br.handler.HandleJobLogReq).Methods(http.MethodGet).Name(routeJobLog)
 	subRouter.HandleFunc("/stats", br.handler.HandleCheckStatusReq).Methods(http.MethodGet)
-	subRouter.HandleFunc("/jobs/{job_id}/executions", br.handler.HandlePeriodicExecutions).Methods(http.MethodGet)
+	subRouter.HandleFunc("/jobs/{job_id}/executions", br.handler.HandlePeriodicExecutions).Methods(http.MethodGet).Name(routeExecutions)
+	subRouter.HandleFunc("/scheduled-jobs", br.handler.HandleGetScheduledJobsReq).Methods(http.MethodGet).Name(routeScheduledJobs)
+	subRouter.HandleFunc("/scheduled-jobs/{job_id}", br.handler.HandleScheduledJobActionReq).Methods(http.MethodPost).Name(routeScheduledJobAction)
+	subRouter.HandleFunc("/archived-jobs", br.handler.HandleGetArchivedJobsReq).Methods(http.MethodGet).Name(routeArchivedJobs)
+	subRouter.HandleFunc("/archived-jobs/{job_id}", br.handler.HandleGetArchivedJobReq).Methods(http.MethodGet).Name(routeArchivedJob)
+	subRouter.HandleFunc("/job-types", br.handler.HandleGetJobTypesReq).Methods(http.MethodGet).Name(routeJobTypes)
+	subRouter.HandleFunc("/nodes", br.handler.HandleGetNodesReq).Methods(http.MethodGet).Name(routeNodes)
+	subRouter.HandleFunc("/nodes/{node_id}", br.handler.HandleNodeActionReq).Methods(http.MethodPost).Name(routeNodeAction)
+	subRouter.HandleFunc("/ratelimit/rejections", br.handleRejectionsReq).Methods(http.MethodGet).Name(routeRejections)
+	subRouter.HandleFunc("/events", br.handler.HandleStatusEventsReq).Methods(http.MethodGet).Name(routeStatusEvents)
+	subRouter.HandleFunc("/audit-logs", br.handler.HandleGetAuditLogsReq).Methods(http.MethodGet).Name(routeAuditLogs)
+	subRouter.HandleFunc("/periodic-policies/export", br.handler.HandleExportPoliciesReq).Methods(http.MethodGet).Name(routeExportPolicies)
+	subRouter.HandleFunc("/periodic-policies/import", br.handler.HandleImportPoliciesReq).Methods(http.MethodPost).Name(routeImportPolicies)
+
+	return subRouter
+}
+
+// limitRate rejects the job launching request with 429 if the rate limit of the caller is exceeded
+func (br *BaseRouter) limitRate(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request) {
+		if br.limiter == nil {
+			next(w, req)
+			return
+		}
+
+		data, err := ioutil.ReadAll(req.Body)
+		if err != nil {
+			writeError(w, req, http.StatusInternalServerError, errs.ReadRequestBodyError(err))
+			return
+		}
+		// Restore the body for the next handler
+		req.Body = ioutil.NopCloser(bytes.NewReader(data))
+
+		jobReq := &job.Request{}
+		if err := json.Unmarshal(data, jobReq); err != nil || jobReq.Job == nil {
+			// Let the next handler report the malformed request
+			next(w, req)
+			return
+		}
+
+		caller := CallerFromRequest(req)
+		ok, wait, err := br.limiter.Take(caller, jobReq.Job.Name)
+		if err == nil && !ok {
+			limitErr := errs.RateLimitedError(caller, jobReq.Job.Name)
+			w.Header().Set("Retry-After", strconv.FormatInt(int64(math.Ceil(wait.Seconds())), 10))
+			br.auditRejectedLaunch(req, http.StatusTooManyRequests, limitErr)
+			writeError(w, req, http.StatusTooManyRequests, limitErr)
+			return
+		}
+
+		next(w, req)
+	}
+}
+
+// handleRejectionsReq returns the counts of the requests rejected by the rate limiter
+func (br *BaseRouter) handleRejectionsReq(w http.ResponseWriter, req *http.Request) {
+	rejections := make(map[string]int64)
+	if br.limiter != nil {
+		values, err := br.limiter.Rejections()
+		if err != nil {
+			writeError(w, req, http.StatusInternalServerError, errs.CheckStatsError(err))
+			return
+		}
+		rejections = values
+	}
+
+	data, err := json.Marshal(rejections)
+	if err != nil {
+		writeError(w, req, http.StatusInternalServerError, errs.HandleJSONDataError(err))
+		return
+	}
 
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	writeDate(w, data)
 }
@@ -0,0 +1,117 @@
+package audit
+
+import (
+	"database/sql"
+	"fmt"
+	"github.com/chenxull/goGridhub/gridhub/src/jobservice/common/query"
+	_ "github.com/lib/pq" // register pgsql driver
+	"github.com/pkg/errors"
+	"strings"
+)
+
+const createAuditTable = `CREATE TABLE IF NOT EXISTS job_audit_log (
+	id BIGSERIAL PRIMARY KEY,
+	time BIGINT NOT NULL,
+	caller VARCHAR(256) NOT NULL,
+	action VARCHAR(32) NOT NULL,
+	job_id VARCHAR(64) NOT NULL,
+	job_name VARCHAR(256) NOT NULL,
+	parameters_hash VARCHAR(64) NOT NULL,
+	result VARCHAR(16) NOT NULL,
+	status_code INT NOT NULL,
+	error VARCHAR(64) NOT NULL
+);
+CREATE INDEX IF NOT EXISTS idx_job_audit_log_job_id ON job_audit_log (job_id);`
+
+// dbSink keeps the audit log in the PostgreSQL table 'job_audit_log', the rows are only inserted
+type dbSink struct {
+	db *sql.DB
+}
+
+// NewDatabaseSink is constructor of the sink based on PostgreSQL
+func NewDatabaseSink(dsn string) (Sink, error) {
+	db, err := sql.Open("postgres", dsn)
+	if err != nil {
+		return nil, errors.Wrap(err, "open audit database")
+	}
+
+	if _, err := db.Exec(createAuditTable); err != nil {
+		_ = db.Close()
+		return nil, errors.Wrap(err, "create audit table")
+	}
+
+	return &dbSink{
+		db: db,
+	}, nil
+}
+
+// Append implements Sink
+func (ds *dbSink) Append(e *Entry) error {
+	_, err := ds.db.Exec(
+		`INSERT INTO job_audit_log (time, caller, action, job_id, job_name, parameters_hash, result, status_code, error)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)`,
+		e.Time, e.Caller, e.Action, e.JobID, e.JobName, e.ParametersHash, e.Result, e.StatusCode, e.Error,
+	)
+
+	return err
+}
+
+// List implements Sink
+func (ds *dbSink) List(q *query.Parameter) ([]*Entry, int64, error) {
+	f := newFilter(q)
+
+	conditions := make([]string, 0)
+	args := make([]interface{}, 0)
+	if len(f.caller) > 0 {
+		args = append(args, f.caller)
+		conditions = append(conditions, fmt.Sprintf("caller = $%d", len(args)))
+	}
+	if len(f.action) > 0 {
+		args = append(args, f.action)
+		conditions = append(conditions, fmt.Sprintf("action = $%d", len(args)))
+	}
+	if len(f.jobID) > 0 {
+		args = append(args, f.jobID)
+		conditions = append(conditions, fmt.Sprintf("job_id = $%d", len(args)))
+	}
+
+	where := ""
+	if len(conditions) > 0 {
+		where = "WHERE " + strings.Join(conditions, " AND ")
+	}
+
+	var total int64
+	if err := ds.db.QueryRow(fmt.Sprintf(`SELECT COUNT(*) FROM job_audit_log %s`, where), args...).Scan(&total); err != nil {
+		return nil, 0, err
+	}
+
+	pageNumber, pageSize := page(q)
+	args = append(args, pageSize, (pageNumber-1)*pageSize)
+	rows, err := ds.db.Query(
+		fmt.Sprintf(`SELECT time, caller, action, job_id, job_name, parameters_hash, result, status_code, error
+		FROM job_audit_log %s ORDER BY id DESC LIMIT $%d OFFSET $%d`, where, len(args)-1, len(args)),
+		args...,
+	)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer func() {
+		_ = rows.Close()
+	}()
+
+	entries := make([]*Entry, 0)
+	for rows.Next() {
+		e := &Entry{}
+		if err := rows.Scan(&e.Time, &e.Caller, &e.Action, &e.JobID, &e.JobName, &e.ParametersHash, &e.Result, &e.StatusCode, &e.Error); err != nil {
+			return nil, 0, err
+		}
+		entries = append(entries, e)
+	}
+
+	return entries, total, rows.Err()
+}
+
+// Close implements Sink
+func (ds *dbSink) Close() error {
+	return ds.db.Close()
+}
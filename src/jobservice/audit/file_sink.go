@@ -0,0 +1,136 @@
+package audit
+
+import (
+	"bufio"
+	"encoding/json"
+	"github.com/chenxull/goGridhub/gridhub/src/jobservice/common/query"
+	"github.com/pkg/errors"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// Max size of one entry in the file
+const maxEntrySize = 64 * 1024
+
+// fileSink appends the entries to the file as JSON lines
+type fileSink struct {
+	path string
+	lock sync.Mutex
+	file *os.File
+}
+
+// NewFileSink is constructor of the sink based on the file
+func NewFileSink(path string) (Sink, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, errors.Wrap(err, "create audit directory")
+	}
+
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0600)
+	if err != nil {
+		return nil, errors.Wrap(err, "open audit file")
+	}
+
+	return &fileSink{
+		path: path,
+		file: f,
+	}, nil
+}
+
+// Append implements Sink
+func (fs *fileSink) Append(e *Entry) error {
+	data, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	data = append(data, '\n')
+
+	fs.lock.Lock()
+	defer fs.lock.Unlock()
+
+	if _, err := fs.file.Write(data); err != nil {
+		return err
+	}
+
+	return fs.file.Sync()
+}
+
+// List implements Sink
+func (fs *fileSink) List(q *query.Parameter) ([]*Entry, int64, error) {
+	f := newFilter(q)
+
+	// Count the matched entries first to get the page from the end of the file
+	var total int64
+	if err := fs.scan(func(e *Entry) {
+		if f.match(e) {
+			total++
+		}
+	}); err != nil {
+		return nil, 0, err
+	}
+
+	pageNumber, pageSize := page(q)
+	// Index range of the page in the file order
+	to := total - int64((pageNumber-1)*pageSize)
+	from := to - int64(pageSize)
+	if from < 0 {
+		from = 0
+	}
+
+	entries := make([]*Entry, 0)
+	if to <= 0 {
+		return entries, total, nil
+	}
+
+	var index int64
+	if err := fs.scan(func(e *Entry) {
+		if !f.match(e) {
+			return
+		}
+		if index >= from && index < to {
+			entries = append(entries, e)
+		}
+		index++
+	}); err != nil {
+		return nil, 0, err
+	}
+
+	// Latest first
+	for i, j := 0, len(entries)-1; i < j; i, j = i+1, j-1 {
+		entries[i], entries[j] = entries[j], entries[i]
+	}
+
+	return entries, total, nil
+}
+
+// Close implements Sink
+func (fs *fileSink) Close() error {
+	fs.lock.Lock()
+	defer fs.lock.Unlock()
+
+	return fs.file.Close()
+}
+
+// scan all the entries in the file
+func (fs *fileSink) scan(handle func(e *Entry)) error {
+	f, err := os.Open(fs.path)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = f.Close()
+	}()
+
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 0, 4*1024), maxEntrySize)
+	for scanner.Scan() {
+		e := &Entry{}
+		// Skip the broken line, e.g: the last line partially written
+		if err := json.Unmarshal(scanner.Bytes(), e); err != nil || len(e.Action) == 0 {
+			continue
+		}
+		handle(e)
+	}
+
+	return scanner.Err()
+}
@@ -0,0 +1,133 @@
+// Package audit keeps the append-only trail of the API actions on the jobs.
+package audit
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"github.com/chenxull/goGridhub/gridhub/src/jobservice/common/query"
+	"github.com/chenxull/goGridhub/gridhub/src/jobservice/common/utils"
+	"github.com/chenxull/goGridhub/gridhub/src/jobservice/config"
+	"github.com/pkg/errors"
+)
+
+// Audited actions
+const (
+	ActionLaunch     = "launch"
+	ActionStop       = "stop"
+	ActionRetry      = "retry"
+	ActionReschedule = "reschedule"
+	ActionRun        = "run"
+	ActionCancel     = "cancel"
+
+	// ResultSuccess means the action is done
+	ResultSuccess = "success"
+	// ResultFailure means the action is failed or rejected
+	ResultFailure = "failure"
+)
+
+// Entry is one record of the audit log
+type Entry struct {
+	// Unix timestamp of the action
+	Time int64 `json:"time"`
+	// Name of the caller identified by the authenticator
+	Caller string `json:"caller"`
+	Action string `json:"action"`
+	JobID  string `json:"job_id,omitempty"`
+	// Name of the job if it's known by the action
+	JobName string `json:"job_name,omitempty"`
+	// SHA256 of the parameters of the action in JSON, empty if there are no parameters
+	ParametersHash string `json:"parameters_hash,omitempty"`
+	Result         string `json:"result"`
+	// HTTP status code and the error name of the failed action
+	StatusCode int    `json:"status_code"`
+	Error      string `json:"error,omitempty"`
+}
+
+// Sink keeps the audit log, the entries are only appended
+type Sink interface {
+	// Append the entry to the audit log
+	Append(e *Entry) error
+
+	// List the entries matched with the query from the latest one.
+	// The caller, action and job ID in the extra parameters are used as the filters.
+	List(q *query.Parameter) ([]*Entry, int64, error)
+
+	// Close the sink
+	Close() error
+}
+
+// NewSink creates the sink with the configuration
+func NewSink(cfg *config.AuditConfig) (Sink, error) {
+	switch cfg.Sink {
+	case config.AuditSinkFile:
+		return NewFileSink(cfg.Path)
+	case config.AuditSinkDatabase:
+		return NewDatabaseSink(cfg.DSN)
+	default:
+		return nil, errors.Errorf("audit sink %s is not supported", cfg.Sink)
+	}
+}
+
+// HashParameters returns the SHA256 of the parameters in JSON, the keys of the maps are sorted
+// by the JSON encoding so the same parameters always have the same hash
+func HashParameters(params interface{}) string {
+	if params == nil {
+		return ""
+	}
+
+	data, err := json.Marshal(params)
+	if err != nil {
+		return ""
+	}
+	sum := sha256.Sum256(data)
+
+	return hex.EncodeToString(sum[:])
+}
+
+// filter keeps the conditions of listing the entries
+type filter struct {
+	caller string
+	action string
+	jobID  string
+}
+
+func newFilter(q *query.Parameter) *filter {
+	f := &filter{}
+	if q == nil || q.Extras == nil {
+		return f
+	}
+
+	if v, ok := q.Extras.Get(query.ExtraParamKeyCaller); ok {
+		f.caller, _ = v.(string)
+	}
+	if v, ok := q.Extras.Get(query.ExtraParamKeyAction); ok {
+		f.action, _ = v.(string)
+	}
+	if v, ok := q.Extras.Get(query.ExtraParamKeyJobID); ok {
+		f.jobID, _ = v.(string)
+	}
+
+	return f
+}
+
+func (f *filter) match(e *Entry) bool {
+	return (utils.IsEmptyStr(f.caller) || f.caller == e.Caller) &&
+		(utils.IsEmptyStr(f.action) || f.action == e.Action) &&
+		(utils.IsEmptyStr(f.jobID) || f.jobID == e.JobID)
+}
+
+// page returns the page number and size of the query
+func page(q *query.Parameter) (uint, uint) {
+	pageNumber, pageSize := uint(1), query.DefaultPageSize
+	if q != nil {
+		if q.PageNumber > 0 {
+			pageNumber = q.PageNumber
+		}
+		if q.PageSize > 0 {
+			pageSize = q.PageSize
+		}
+	}
+
+	return pageNumber, pageSize
+}
@@ -0,0 +1,211 @@
+package auth
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"github.com/pkg/errors"
+	"io/ioutil"
+	"math/big"
+	"os"
+	"sync"
+	"time"
+)
+
+// How often the key set file is checked for changes
+const keySetCheckInterval = 30 * time.Second
+
+// jsonWebKey is the key in the JWKS file, see RFC 7517
+type jsonWebKey struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Alg string `json:"alg"`
+	Use string `json:"use"`
+	// RSA
+	N string `json:"n"`
+	E string `json:"e"`
+	// EC
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+	// Symmetric
+	K string `json:"k"`
+}
+
+// verifyKey is the parsed key verifying the token signatures
+type verifyKey struct {
+	kid string
+	// Algorithm the key is restricted to, empty means any algorithm of the key type
+	alg string
+	// *rsa.PublicKey, *ecdsa.PublicKey or []byte
+	public interface{}
+}
+
+// KeySet is the JSON web key set loaded from the local file.
+// The file is reloaded once it's modified, the keys loaded before are kept if the new file is broken.
+type KeySet struct {
+	path      string
+	lock      sync.RWMutex
+	keys      []*verifyKey
+	modTime   time.Time
+	checkedAt time.Time
+}
+
+// LoadKeySet loads the key set from the JWKS file
+func LoadKeySet(path string) (*KeySet, error) {
+	ks := &KeySet{
+		path: path,
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, errors.Wrap(err, "stat JWKS file")
+	}
+	if err := ks.load(info.ModTime()); err != nil {
+		return nil, err
+	}
+
+	return ks, nil
+}
+
+// find the keys matched with the key ID, all the keys are returned if the key ID is empty
+func (ks *KeySet) find(kid string) []*verifyKey {
+	ks.refresh()
+
+	ks.lock.RLock()
+	defer ks.lock.RUnlock()
+
+	matched := make([]*verifyKey, 0, 1)
+	for _, k := range ks.keys {
+		if len(kid) == 0 || k.kid == kid {
+			matched = append(matched, k)
+		}
+	}
+
+	return matched
+}
+
+// refresh reloads the file if it's modified since the last loading
+func (ks *KeySet) refresh() {
+	ks.lock.Lock()
+	if time.Since(ks.checkedAt) < keySetCheckInterval {
+		ks.lock.Unlock()
+		return
+	}
+	ks.checkedAt = time.Now()
+	modTime := ks.modTime
+	ks.lock.Unlock()
+
+	info, err := os.Stat(ks.path)
+	if err != nil || info.ModTime().Equal(modTime) {
+		return
+	}
+
+	// Keep the current keys if the file is broken
+	_ = ks.load(info.ModTime())
+}
+
+func (ks *KeySet) load(modTime time.Time) error {
+	data, err := ioutil.ReadFile(ks.path)
+	if err != nil {
+		return errors.Wrap(err, "read JWKS file")
+	}
+
+	set := &struct {
+		Keys []*jsonWebKey `json:"keys"`
+	}{}
+	if err := json.Unmarshal(data, set); err != nil {
+		return errors.Wrap(err, "parse JWKS file")
+	}
+
+	keys := make([]*verifyKey, 0, len(set.Keys))
+	for _, jwk := range set.Keys {
+		// Skip the keys for encryption
+		if jwk.Use == "enc" {
+			continue
+		}
+
+		k, err := parseKey(jwk)
+		if err != nil {
+			return errors.Wrapf(err, "parse key %s", jwk.Kid)
+		}
+		keys = append(keys, k)
+	}
+
+	ks.lock.Lock()
+	defer ks.lock.Unlock()
+
+	ks.keys = keys
+	ks.modTime = modTime
+	ks.checkedAt = time.Now()
+
+	return nil
+}
+
+func parseKey(jwk *jsonWebKey) (*verifyKey, error) {
+	k := &verifyKey{
+		kid: jwk.Kid,
+		alg: jwk.Alg,
+	}
+
+	switch jwk.Kty {
+	case "RSA":
+		n, err := decodeBigInt(jwk.N)
+		if err != nil {
+			return nil, err
+		}
+		e, err := decodeBigInt(jwk.E)
+		if err != nil {
+			return nil, err
+		}
+		if !e.IsInt64() || e.Int64() < 3 {
+			return nil, errors.New("invalid RSA exponent")
+		}
+		k.public = &rsa.PublicKey{N: n, E: int(e.Int64())}
+	case "EC":
+		var curve elliptic.Curve
+		switch jwk.Crv {
+		case "P-256":
+			curve = elliptic.P256()
+		case "P-384":
+			curve = elliptic.P384()
+		case "P-521":
+			curve = elliptic.P521()
+		default:
+			return nil, errors.Errorf("unsupported curve %s", jwk.Crv)
+		}
+		x, err := decodeBigInt(jwk.X)
+		if err != nil {
+			return nil, err
+		}
+		y, err := decodeBigInt(jwk.Y)
+		if err != nil {
+			return nil, err
+		}
+		if !curve.IsOnCurve(x, y) {
+			return nil, errors.New("EC point is not on the curve")
+		}
+		k.public = &ecdsa.PublicKey{Curve: curve, X: x, Y: y}
+	case "oct":
+		secret, err := base64.RawURLEncoding.DecodeString(jwk.K)
+		if err != nil || len(secret) == 0 {
+			return nil, errors.New("invalid symmetric key")
+		}
+		k.public = secret
+	default:
+		return nil, errors.Errorf("unsupported key type %s", jwk.Kty)
+	}
+
+	return k, nil
+}
+
+func decodeBigInt(v string) (*big.Int, error) {
+	data, err := base64.RawURLEncoding.DecodeString(v)
+	if err != nil || len(data) == 0 {
+		return nil, errors.New("invalid base64url encoded integer")
+	}
+
+	return new(big.Int).SetBytes(data), nil
+}
@@ -0,0 +1,247 @@
+package auth
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/hmac"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/sha512"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/json"
+	"github.com/pkg/errors"
+	"hash"
+	"math/big"
+	"strings"
+	"time"
+)
+
+const (
+	// DefaultScopesClaim is the default claim keeping the scopes of the token
+	DefaultScopesClaim = "scope"
+	// Tolerance of the clock skew when checking the time claims
+	clockSkew = 60 * time.Second
+)
+
+// JWTVerifier validates the JWT bearer tokens signed by the keys in the local key set
+type JWTVerifier struct {
+	keys        *KeySet
+	issuer      string
+	audience    string
+	scopesClaim string
+}
+
+// NewJWTVerifier is constructor of JWTVerifier.
+// The issuer and audience are not checked if they're empty.
+func NewJWTVerifier(keys *KeySet, issuer string, audience string, scopesClaim string) *JWTVerifier {
+	if len(scopesClaim) == 0 {
+		scopesClaim = DefaultScopesClaim
+	}
+
+	return &JWTVerifier{
+		keys:        keys,
+		issuer:      issuer,
+		audience:    audience,
+		scopesClaim: scopesClaim,
+	}
+}
+
+// IsJWT checks if the token looks like a JWT in the compact serialization
+func IsJWT(token string) bool {
+	return strings.Count(token, ".") == 2
+}
+
+// Verify the token and return the identity of its subject with the granted scopes
+func (v *JWTVerifier) Verify(token string) (*Identity, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, errors.New("malformed JWT")
+	}
+
+	header := &struct {
+		Alg string `json:"alg"`
+		Kid string `json:"kid"`
+	}{}
+	if err := decodeSegment(parts[0], header); err != nil {
+		return nil, errors.Wrap(err, "decode JWT header")
+	}
+
+	sig, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, errors.New("malformed JWT signature")
+	}
+
+	signed := []byte(parts[0] + "." + parts[1])
+	verified := false
+	for _, k := range v.keys.find(header.Kid) {
+		if err := verifySignature(header.Alg, k, signed, sig); err == nil {
+			verified = true
+			break
+		}
+	}
+	if !verified {
+		return nil, errors.New("invalid JWT signature")
+	}
+
+	claims := make(map[string]interface{})
+	if err := decodeSegment(parts[1], &claims); err != nil {
+		return nil, errors.Wrap(err, "decode JWT claims")
+	}
+	if err := v.validateClaims(claims); err != nil {
+		return nil, err
+	}
+
+	sub, _ := claims["sub"].(string)
+	if len(sub) == 0 {
+		return nil, errors.New("missing subject of JWT")
+	}
+
+	grants := make([]*Grant, 0)
+	for _, s := range stringsClaim(claims[v.scopesClaim], true) {
+		// Skip the scopes of the other services
+		if g, err := ParseGrant(s); err == nil {
+			grants = append(grants, g)
+		}
+	}
+
+	return &Identity{
+		Name:   sub,
+		Grants: grants,
+	}, nil
+}
+
+func (v *JWTVerifier) validateClaims(claims map[string]interface{}) error {
+	now := time.Now()
+
+	exp, ok := claims["exp"].(float64)
+	if !ok {
+		return errors.New("missing expiration of JWT")
+	}
+	if now.Add(-clockSkew).After(time.Unix(int64(exp), 0)) {
+		return errors.New("JWT is expired")
+	}
+	if nbf, ok := claims["nbf"].(float64); ok && now.Add(clockSkew).Before(time.Unix(int64(nbf), 0)) {
+		return errors.New("JWT is not valid yet")
+	}
+
+	if len(v.issuer) > 0 {
+		if iss, _ := claims["iss"].(string); iss != v.issuer {
+			return errors.Errorf("unexpected issuer of JWT: %s", iss)
+		}
+	}
+
+	if len(v.audience) > 0 {
+		matched := false
+		for _, aud := range stringsClaim(claims["aud"], false) {
+			if aud == v.audience {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			return errors.New("unexpected audience of JWT")
+		}
+	}
+
+	return nil
+}
+
+// stringsClaim returns the claim of a string or a string array,
+// the string is split by spaces if it's a space-delimited list.
+func stringsClaim(v interface{}, spaceDelimited bool) []string {
+	switch c := v.(type) {
+	case string:
+		if spaceDelimited {
+			return strings.Fields(c)
+		}
+		return []string{c}
+	case []interface{}:
+		list := make([]string, 0, len(c))
+		for _, item := range c {
+			if s, ok := item.(string); ok {
+				list = append(list, s)
+			}
+		}
+		return list
+	default:
+		return nil
+	}
+}
+
+func decodeSegment(seg string, v interface{}) error {
+	data, err := base64.RawURLEncoding.DecodeString(seg)
+	if err != nil {
+		return err
+	}
+
+	return json.Unmarshal(data, v)
+}
+
+func verifySignature(alg string, k *verifyKey, signed []byte, sig []byte) error {
+	if len(k.alg) > 0 && k.alg != alg {
+		return errors.Errorf("key %s is restricted to %s", k.kid, k.alg)
+	}
+	if len(alg) != 5 {
+		return errors.Errorf("unsupported algorithm %s", alg)
+	}
+
+	var (
+		h       crypto.Hash
+		newHash func() hash.Hash
+	)
+	switch alg[2:] {
+	case "256":
+		h, newHash = crypto.SHA256, sha256.New
+	case "384":
+		h, newHash = crypto.SHA384, sha512.New384
+	case "512":
+		h, newHash = crypto.SHA512, sha512.New
+	default:
+		return errors.Errorf("unsupported algorithm %s", alg)
+	}
+
+	switch alg[:2] {
+	case "HS":
+		secret, ok := k.public.([]byte)
+		if !ok {
+			return errors.New("key type mismatch")
+		}
+		mac := hmac.New(newHash, secret)
+		_, _ = mac.Write(signed)
+		if subtle.ConstantTimeCompare(mac.Sum(nil), sig) != 1 {
+			return errors.New("signature mismatch")
+		}
+		return nil
+	case "RS", "PS":
+		pub, ok := k.public.(*rsa.PublicKey)
+		if !ok {
+			return errors.New("key type mismatch")
+		}
+		digest := newHash()
+		_, _ = digest.Write(signed)
+		if alg[:2] == "RS" {
+			return rsa.VerifyPKCS1v15(pub, h, digest.Sum(nil), sig)
+		}
+		return rsa.VerifyPSS(pub, h, digest.Sum(nil), sig, nil)
+	case "ES":
+		pub, ok := k.public.(*ecdsa.PublicKey)
+		if !ok {
+			return errors.New("key type mismatch")
+		}
+		size := (pub.Curve.Params().BitSize + 7) / 8
+		if len(sig) != 2*size {
+			return errors.New("invalid ECDSA signature size")
+		}
+		digest := newHash()
+		_, _ = digest.Write(signed)
+		r := new(big.Int).SetBytes(sig[:size])
+		s := new(big.Int).SetBytes(sig[size:])
+		if !ecdsa.Verify(pub, digest.Sum(nil), r, s) {
+			return errors.New("signature mismatch")
+		}
+		return nil
+	default:
+		return errors.Errorf("unsupported algorithm %s", alg)
+	}
+}
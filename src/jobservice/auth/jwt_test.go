@@ -0,0 +1,187 @@
+package auth
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+var hmacSecret = []byte("0123456789abcdef0123456789abcdef")
+
+// testKeys writes the key set with an HS256 key and an ES256 key, and returns the EC private key
+func testKeys(t *testing.T) (*KeySet, *ecdsa.PrivateKey) {
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("generate EC key error: %s", err)
+	}
+
+	enc := base64.RawURLEncoding
+	jwks := map[string]interface{}{
+		"keys": []map[string]string{
+			{"kty": "oct", "kid": "hs", "alg": "HS256", "k": enc.EncodeToString(hmacSecret)},
+			{"kty": "EC", "kid": "ec", "crv": "P-256", "x": enc.EncodeToString(ecKey.X.Bytes()), "y": enc.EncodeToString(ecKey.Y.Bytes())},
+		},
+	}
+	data, err := json.Marshal(jwks)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	path := filepath.Join(t.TempDir(), "jwks.json")
+	if err := ioutil.WriteFile(path, data, 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	ks, err := LoadKeySet(path)
+	if err != nil {
+		t.Fatalf("load key set error: %s", err)
+	}
+
+	return ks, ecKey
+}
+
+func encodeSegment(t *testing.T, v interface{}) string {
+	data, err := json.Marshal(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// signHS256 signs the token with the alg in the header by HMAC SHA256 regardless of the alg
+func signHS256(t *testing.T, header map[string]string, claims map[string]interface{}) string {
+	signed := encodeSegment(t, header) + "." + encodeSegment(t, claims)
+	mac := hmac.New(sha256.New, hmacSecret)
+	_, _ = mac.Write([]byte(signed))
+
+	return signed + "." + base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// signES256 signs the token with the EC key, the signature is resized to the size if it's not zero
+func signES256(t *testing.T, key *ecdsa.PrivateKey, claims map[string]interface{}, size int) string {
+	signed := encodeSegment(t, map[string]string{"alg": "ES256", "kid": "ec"}) + "." + encodeSegment(t, claims)
+	digest := sha256.Sum256([]byte(signed))
+	r, s, err := ecdsa.Sign(rand.Reader, key, digest[:])
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sig := make([]byte, 64)
+	r.FillBytes(sig[:32])
+	s.FillBytes(sig[32:])
+	if size > 0 {
+		resized := make([]byte, size)
+		copy(resized, sig)
+		sig = resized
+	}
+
+	return signed + "." + base64.RawURLEncoding.EncodeToString(sig)
+}
+
+func validClaims() map[string]interface{} {
+	now := time.Now()
+	return map[string]interface{}{
+		"sub":   "robot",
+		"iss":   "issuer",
+		"aud":   "jobservice",
+		"exp":   now.Add(time.Hour).Unix(),
+		"nbf":   now.Add(-time.Minute).Unix(),
+		"scope": "jobs:read jobs:launch:GC* other:scope",
+	}
+}
+
+func withClaim(key string, value interface{}) map[string]interface{} {
+	c := validClaims()
+	if value == nil {
+		delete(c, key)
+	} else {
+		c[key] = value
+	}
+
+	return c
+}
+
+func TestJWTVerifierVerify(t *testing.T) {
+	ks, ecKey := testKeys(t)
+	v := NewJWTVerifier(ks, "issuer", "jobservice", "")
+	hs256 := map[string]string{"alg": "HS256", "kid": "hs"}
+	now := time.Now()
+
+	cases := []struct {
+		name    string
+		token   string
+		wantErr bool
+	}{
+		{name: "valid HS256", token: signHS256(t, hs256, validClaims())},
+		{name: "valid HS256 without kid", token: signHS256(t, map[string]string{"alg": "HS256"}, validClaims())},
+		{name: "valid ES256", token: signES256(t, ecKey, validClaims(), 0)},
+		{name: "audience in array", token: signHS256(t, hs256, withClaim("aud", []string{"other", "jobservice"}))},
+		{name: "expired in clock skew", token: signHS256(t, hs256, withClaim("exp", now.Add(-clockSkew/2).Unix()))},
+		{name: "alg mismatch with key", token: signHS256(t, map[string]string{"alg": "HS384", "kid": "hs"}, validClaims()), wantErr: true},
+		{name: "alg none", token: signHS256(t, map[string]string{"alg": "none", "kid": "hs"}, validClaims()), wantErr: true},
+		{name: "unknown kid", token: signHS256(t, map[string]string{"alg": "HS256", "kid": "other"}, validClaims()), wantErr: true},
+		{name: "kid of other key type", token: signHS256(t, map[string]string{"alg": "HS256", "kid": "ec"}, validClaims()), wantErr: true},
+		{name: "expired", token: signHS256(t, hs256, withClaim("exp", now.Add(-2*clockSkew).Unix())), wantErr: true},
+		{name: "missing exp", token: signHS256(t, hs256, withClaim("exp", nil)), wantErr: true},
+		{name: "not valid yet", token: signHS256(t, hs256, withClaim("nbf", now.Add(2*clockSkew).Unix())), wantErr: true},
+		{name: "audience mismatch", token: signHS256(t, hs256, withClaim("aud", "other")), wantErr: true},
+		{name: "missing audience", token: signHS256(t, hs256, withClaim("aud", nil)), wantErr: true},
+		{name: "issuer mismatch", token: signHS256(t, hs256, withClaim("iss", "other")), wantErr: true},
+		{name: "missing subject", token: signHS256(t, hs256, withClaim("sub", nil)), wantErr: true},
+		{name: "short ES256 signature", token: signES256(t, ecKey, validClaims(), 63), wantErr: true},
+		{name: "long ES256 signature", token: signES256(t, ecKey, validClaims(), 72), wantErr: true},
+		{name: "tampered claims", token: encodeSegment(t, hs256) + "." + encodeSegment(t, withClaim("sub", "admin")) + "." + "c2ln", wantErr: true},
+		{name: "malformed", token: "a.b", wantErr: true},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			id, err := v.Verify(c.token)
+			if c.wantErr {
+				if err == nil {
+					t.Fatalf("expect error but got identity %+v", id)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+			if id.Name != "robot" {
+				t.Errorf("expect subject robot but got %s", id.Name)
+			}
+			// The scope of other services is skipped
+			if len(id.Grants) != 2 {
+				t.Fatalf("expect 2 grants but got %d", len(id.Grants))
+			}
+			if !id.Allowed(ScopeJobsLaunch, "GC_RUN") || id.Allowed(ScopeJobsLaunch, "SCAN") {
+				t.Errorf("unexpected grants %+v", id.Grants)
+			}
+		})
+	}
+}
+
+func TestJWTVerifierWithoutIssuerAndAudience(t *testing.T) {
+	ks, _ := testKeys(t)
+	v := NewJWTVerifier(ks, "", "", "roles")
+
+	claims := withClaim("aud", nil)
+	delete(claims, "iss")
+	claims["roles"] = []string{"jobs:read"}
+
+	id, err := v.Verify(signHS256(t, map[string]string{"alg": "HS256", "kid": "hs"}, claims))
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if !id.Allowed(ScopeJobsRead, "GC") || id.Allowed(ScopeJobsLaunch, "GC") {
+		t.Errorf("unexpected grants from the custom claim %+v", id.Grants)
+	}
+}
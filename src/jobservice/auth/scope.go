@@ -0,0 +1,117 @@
+// Package auth keeps the identities of the API callers and the scopes granted to them.
+package auth
+
+import (
+	"github.com/pkg/errors"
+	"path"
+	"strings"
+)
+
+const (
+	// ScopeJobsLaunch allows launching the jobs
+	ScopeJobsLaunch = "jobs:launch"
+	// ScopeJobsRead allows reading the jobs, their logs and executions
+	ScopeJobsRead = "jobs:read"
+	// ScopeJobsStop allows stopping the jobs and managing the scheduled jobs
+	ScopeJobsStop = "jobs:stop"
+	// ScopeSystemAdmin allows managing the nodes and the periodic job policies and reading the system stats
+	ScopeSystemAdmin = "system:admin"
+	// ScopeAuditRead allows reading the audit log of the API actions on the jobs
+	ScopeAuditRead = "audit:read"
+	// ScopeAll grants all the scopes to all the jobs
+	ScopeAll = "*"
+)
+
+var knownScopes = map[string]bool{
+	ScopeJobsLaunch:  true,
+	ScopeJobsRead:    true,
+	ScopeJobsStop:    true,
+	ScopeSystemAdmin: true,
+	ScopeAuditRead:   true,
+	ScopeAll:         true,
+}
+
+// Grant is the scope restricted to the jobs with the names matched by the pattern
+type Grant struct {
+	Scope string
+	// Pattern of the job names, '*' matches all
+	Jobs string
+}
+
+// ParseGrant parses the scope in the form of '<scope>' or '<scope>:<job name pattern>',
+// e.g: 'jobs:read' or 'jobs:launch:REPLICATION*'.
+func ParseGrant(scope string) (*Grant, error) {
+	scope = strings.TrimSpace(scope)
+
+	g := &Grant{
+		Scope: scope,
+		Jobs:  "*",
+	}
+	if parts := strings.SplitN(scope, ":", 3); len(parts) == 3 {
+		g.Scope = parts[0] + ":" + parts[1]
+		g.Jobs = parts[2]
+	}
+
+	if !knownScopes[g.Scope] {
+		return nil, errors.Errorf("unknown scope: %s", scope)
+	}
+	if _, err := path.Match(g.Jobs, ""); err != nil || len(g.Jobs) == 0 {
+		return nil, errors.Errorf("invalid job name pattern of scope: %s", scope)
+	}
+
+	return g, nil
+}
+
+// ParseGrants parses all the scopes
+func ParseGrants(scopes []string) ([]*Grant, error) {
+	grants := make([]*Grant, 0, len(scopes))
+	for _, s := range scopes {
+		g, err := ParseGrant(s)
+		if err != nil {
+			return nil, err
+		}
+		grants = append(grants, g)
+	}
+
+	return grants, nil
+}
+
+// Identity is the authenticated caller with the granted scopes
+type Identity struct {
+	Name   string
+	Grants []*Grant
+}
+
+// Allowed checks if the scope is granted to the caller for the job.
+// The empty job name means all the jobs, e.g: listing the jobs, so only the
+// unrestricted scope is matched.
+func (id *Identity) Allowed(scope string, jobName string) bool {
+	for _, g := range id.Grants {
+		if g.Scope != scope && g.Scope != ScopeAll {
+			continue
+		}
+		if g.Jobs == "*" {
+			return true
+		}
+		if len(jobName) == 0 {
+			continue
+		}
+		if matched, err := path.Match(g.Jobs, jobName); err == nil && matched {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Granted checks if the scope is granted to the caller for any jobs. It's used by the requests
+// returning the results of all the jobs, then the results are filtered by Allowed for each job.
+func (id *Identity) Granted(scope string) bool {
+	for _, g := range id.Grants {
+		if g.Scope == scope || g.Scope == ScopeAll {
+			return true
+		}
+	}
+
+	return false
+}
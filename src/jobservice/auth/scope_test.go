@@ -0,0 +1,100 @@
+package auth
+
+import (
+	"testing"
+)
+
+func TestParseGrant(t *testing.T) {
+	cases := []struct {
+		name    string
+		scope   string
+		want    *Grant
+		wantErr bool
+	}{
+		{name: "plain scope", scope: "jobs:read", want: &Grant{Scope: ScopeJobsRead, Jobs: "*"}},
+		{name: "all", scope: "*", want: &Grant{Scope: ScopeAll, Jobs: "*"}},
+		{name: "pattern", scope: "jobs:launch:REPLICATION*", want: &Grant{Scope: ScopeJobsLaunch, Jobs: "REPLICATION*"}},
+		{name: "spaces", scope: " jobs:stop ", want: &Grant{Scope: ScopeJobsStop, Jobs: "*"}},
+		{name: "unknown scope", scope: "jobs:delete", wantErr: true},
+		{name: "unknown scope with pattern", scope: "jobs:delete:GC", wantErr: true},
+		{name: "empty pattern", scope: "jobs:read:", wantErr: true},
+		{name: "bad pattern", scope: "jobs:read:[", wantErr: true},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			g, err := ParseGrant(c.scope)
+			if c.wantErr {
+				if err == nil {
+					t.Fatalf("expect error but got grant %+v", g)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+			if *g != *c.want {
+				t.Errorf("expect grant %+v but got %+v", c.want, g)
+			}
+		})
+	}
+}
+
+func TestIdentityAllowed(t *testing.T) {
+	cases := []struct {
+		name    string
+		scopes  []string
+		scope   string
+		jobName string
+		want    bool
+	}{
+		{name: "granted scope", scopes: []string{"jobs:read"}, scope: ScopeJobsRead, jobName: "GC", want: true},
+		{name: "granted scope for all jobs", scopes: []string{"jobs:read"}, scope: ScopeJobsRead, want: true},
+		{name: "other scope", scopes: []string{"jobs:read"}, scope: ScopeJobsStop, jobName: "GC", want: false},
+		{name: "all scopes", scopes: []string{"*"}, scope: ScopeSystemAdmin, want: true},
+		{name: "matched pattern", scopes: []string{"jobs:launch:REPLICATION*"}, scope: ScopeJobsLaunch, jobName: "REPLICATION_PUSH", want: true},
+		{name: "unmatched pattern", scopes: []string{"jobs:launch:REPLICATION*"}, scope: ScopeJobsLaunch, jobName: "GC", want: false},
+		{name: "pattern for all jobs", scopes: []string{"jobs:read:REPLICATION*"}, scope: ScopeJobsRead, want: false},
+		{name: "one of the grants", scopes: []string{"jobs:read:GC", "jobs:read:SCAN*"}, scope: ScopeJobsRead, jobName: "SCAN_IMAGE", want: true},
+		{name: "no grants", scope: ScopeJobsRead, jobName: "GC", want: false},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			grants, err := ParseGrants(c.scopes)
+			if err != nil {
+				t.Fatalf("parse grants error: %s", err)
+			}
+			id := &Identity{Name: "tester", Grants: grants}
+			if got := id.Allowed(c.scope, c.jobName); got != c.want {
+				t.Errorf("expect allowed %v but got %v", c.want, got)
+			}
+		})
+	}
+}
+
+func TestIdentityGranted(t *testing.T) {
+	cases := []struct {
+		name   string
+		scopes []string
+		scope  string
+		want   bool
+	}{
+		{name: "pattern", scopes: []string{"jobs:read:GC*"}, scope: ScopeJobsRead, want: true},
+		{name: "all scopes", scopes: []string{"*"}, scope: ScopeJobsRead, want: true},
+		{name: "other scope", scopes: []string{"jobs:launch"}, scope: ScopeJobsRead, want: false},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			grants, err := ParseGrants(c.scopes)
+			if err != nil {
+				t.Fatalf("parse grants error: %s", err)
+			}
+			id := &Identity{Name: "tester", Grants: grants}
+			if got := id.Granted(c.scope); got != c.want {
+				t.Errorf("expect granted %v but got %v", c.want, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,140 @@
+// Package encrypt encrypts the secret job parameters kept in the backend with AES-GCM.
+//
+// The values are encrypted with random nonces. EncryptDeterministic derives the nonce from
+// the value instead, so the same value is always encrypted to the same text. It's only used
+// where the texts are compared, e.g: the unique jobs, and it reveals which texts share the
+// same value to anyone reading the backend.
+package encrypt
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"github.com/pkg/errors"
+	"strings"
+	"sync"
+)
+
+// Prefix marks the encrypted value
+const Prefix = "enc:v1:"
+
+// ErrNoKey is returned if the key is not set
+var ErrNoKey = errors.New("encryption key of secret parameters is not configured")
+
+var (
+	lock sync.RWMutex
+	// Key of AES-256
+	encKey []byte
+	// Key of generating the nonce
+	nonceKey []byte
+)
+
+// SetKey derives the keys from the secret. Empty secret unsets the keys.
+func SetKey(secret string) {
+	lock.Lock()
+	defer lock.Unlock()
+
+	if len(secret) == 0 {
+		encKey, nonceKey = nil, nil
+		return
+	}
+
+	ek := sha256.Sum256([]byte("enc:" + secret))
+	nk := sha256.Sum256([]byte("nonce:" + secret))
+	encKey, nonceKey = ek[:], nk[:]
+}
+
+// Encrypt the data with a random nonce and return the text with the Prefix
+func Encrypt(data []byte) (string, error) {
+	return seal(data, false)
+}
+
+// EncryptDeterministic encrypts the data like Encrypt but with the nonce derived from the data,
+// so the same data is encrypted to the same text to keep the uniqueness checking of the jobs working.
+func EncryptDeterministic(data []byte) (string, error) {
+	return seal(data, true)
+}
+
+func seal(data []byte, deterministic bool) (string, error) {
+	lock.RLock()
+	ek, nk := encKey, nonceKey
+	lock.RUnlock()
+
+	if ek == nil {
+		return "", ErrNoKey
+	}
+
+	gcm, err := newGCM(ek)
+	if err != nil {
+		return "", err
+	}
+
+	var nonce []byte
+	if deterministic {
+		mac := hmac.New(sha256.New, nk)
+		_, _ = mac.Write(data)
+		nonce = mac.Sum(nil)[:gcm.NonceSize()]
+	} else {
+		nonce = make([]byte, gcm.NonceSize())
+		if _, err := rand.Read(nonce); err != nil {
+			return "", errors.Wrap(err, "generate nonce")
+		}
+	}
+
+	sealed := gcm.Seal(nonce, nonce, data, nil)
+	return Prefix + base64.RawURLEncoding.EncodeToString(sealed), nil
+}
+
+// Decrypt the text returned by Encrypt or EncryptDeterministic
+func Decrypt(text string) ([]byte, error) {
+	if !strings.HasPrefix(text, Prefix) {
+		return nil, errors.New("value is not encrypted")
+	}
+
+	lock.RLock()
+	ek := encKey
+	lock.RUnlock()
+
+	if ek == nil {
+		return nil, ErrNoKey
+	}
+
+	sealed, err := base64.RawURLEncoding.DecodeString(strings.TrimPrefix(text, Prefix))
+	if err != nil {
+		return nil, errors.Wrap(err, "decode encrypted value")
+	}
+
+	gcm, err := newGCM(ek)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(sealed) < gcm.NonceSize() {
+		return nil, errors.New("encrypted value is too short")
+	}
+
+	data, err := gcm.Open(nil, sealed[:gcm.NonceSize()], sealed[gcm.NonceSize():], nil)
+	if err != nil {
+		return nil, errors.Wrap(err, "decrypt value")
+	}
+
+	return data, nil
+}
+
+// IsEncrypted checks if the value is the text returned by Encrypt or EncryptDeterministic
+func IsEncrypted(v interface{}) bool {
+	s, ok := v.(string)
+	return ok && strings.HasPrefix(s, Prefix)
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+
+	return cipher.NewGCM(block)
+}
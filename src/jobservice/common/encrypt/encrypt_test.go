@@ -0,0 +1,105 @@
+package encrypt
+
+import (
+	"encoding/base64"
+	"strings"
+	"testing"
+)
+
+func setTestKey(t *testing.T, secret string) {
+	t.Helper()
+
+	SetKey(secret)
+	t.Cleanup(func() {
+		SetKey("")
+	})
+}
+
+func TestEncryptDecrypt(t *testing.T) {
+	setTestKey(t, "secret")
+
+	for name, encryptFunc := range map[string]func([]byte) (string, error){
+		"random":        Encrypt,
+		"deterministic": EncryptDeterministic,
+	} {
+		t.Run(name, func(t *testing.T) {
+			text, err := encryptFunc([]byte("password"))
+			if err != nil {
+				t.Fatalf("encrypt error: %s", err)
+			}
+			if !IsEncrypted(text) || strings.Contains(text, "password") {
+				t.Fatalf("expect the encrypted text but got %s", text)
+			}
+
+			data, err := Decrypt(text)
+			if err != nil || string(data) != "password" {
+				t.Errorf("expect the decrypted password but got %s, %v", data, err)
+			}
+		})
+	}
+}
+
+func TestEncryptNonce(t *testing.T) {
+	setTestKey(t, "secret")
+
+	t1, _ := Encrypt([]byte("password"))
+	t2, _ := Encrypt([]byte("password"))
+	if t1 == t2 {
+		t.Error("expect the same value encrypted to the different texts with the random nonces")
+	}
+
+	d1, _ := EncryptDeterministic([]byte("password"))
+	d2, _ := EncryptDeterministic([]byte("password"))
+	if d1 != d2 {
+		t.Error("expect the same value encrypted to the same text deterministically")
+	}
+	if d3, _ := EncryptDeterministic([]byte("passw0rd")); d3 == d1 {
+		t.Error("expect the different values encrypted to the different texts")
+	}
+}
+
+func TestDecryptRejected(t *testing.T) {
+	setTestKey(t, "secret")
+
+	text, err := Encrypt([]byte("password"))
+	if err != nil {
+		t.Fatalf("encrypt error: %s", err)
+	}
+	sealed, _ := base64.RawURLEncoding.DecodeString(strings.TrimPrefix(text, Prefix))
+	sealed[len(sealed)-1] ^= 0x01
+	tampered := Prefix + base64.RawURLEncoding.EncodeToString(sealed)
+
+	cases := []struct {
+		name string
+		text string
+	}{
+		{name: "tampered", text: tampered},
+		{name: "not encrypted", text: "password"},
+		{name: "malformed", text: Prefix + "!"},
+		{name: "too short", text: Prefix + base64.RawURLEncoding.EncodeToString([]byte("short"))},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			if data, err := Decrypt(c.text); err == nil {
+				t.Errorf("expect the text rejected but got %s", data)
+			}
+		})
+	}
+
+	// Encrypted with another key
+	SetKey("another")
+	if _, err := Decrypt(text); err == nil {
+		t.Error("expect the text encrypted with another key rejected")
+	}
+}
+
+func TestNoKey(t *testing.T) {
+	setTestKey(t, "")
+
+	if _, err := Encrypt([]byte("password")); err != ErrNoKey {
+		t.Errorf("expect ErrNoKey but got %v", err)
+	}
+	if _, err := Decrypt(Prefix + "AAAA"); err != ErrNoKey {
+		t.Errorf("expect ErrNoKey but got %v", err)
+	}
+}
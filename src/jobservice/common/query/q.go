@@ -15,12 +15,32 @@ const (
 	ParamKeyCursor = "cursor"
 	// ParamKeyJobKind defines query param of job kind
 	ParamKeyJobKind = "kind"
+	// ParamKeyJobName defines query param of job name
+	ParamKeyJobName = "name"
+	// ParamKeyStatus defines query param of job status
+	ParamKeyStatus = "status"
+	// ParamKeyCaller defines query param of the caller of the audited action
+	ParamKeyCaller = "caller"
+	// ParamKeyAction defines query param of the audited action
+	ParamKeyAction = "action"
+	// ParamKeyJobID defines query param of the job ID
+	ParamKeyJobID = "job_id"
 	// ExtraParamKeyNonStoppedOnly defines extra parameter key for querying non stopped periodic executions
 	ExtraParamKeyNonStoppedOnly = "NonDeadOnly"
 	// ExtraParamKeyCursor defines extra parameter key for the cursor of fetching job stats with batches
 	ExtraParamKeyCursor = "Cursor"
 	// ExtraParamKeyKind defines extra parameter key for the job kind
 	ExtraParamKeyKind = "Kind"
+	// ExtraParamKeyJobName defines extra parameter key for the job name
+	ExtraParamKeyJobName = "JobName"
+	// ExtraParamKeyStatus defines extra parameter key for the job status
+	ExtraParamKeyStatus = "Status"
+	// ExtraParamKeyCaller defines extra parameter key for the caller of the audited action
+	ExtraParamKeyCaller = "Caller"
+	// ExtraParamKeyAction defines extra parameter key for the audited action
+	ExtraParamKeyAction = "Action"
+	// ExtraParamKeyJobID defines extra parameter key for the job ID
+	ExtraParamKeyJobID = "JobID"
 )
 
 // ExtraParameters to keep non pagination query parameters
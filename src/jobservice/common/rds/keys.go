@@ -19,11 +19,55 @@ func RedisKeyScheduled(namespace string) string {
 	return RedisNamespacePrefix(namespace) + "scheduled"
 }
 
+// RedisKeyJobs returns key of the job queue with the specified job name.
+func RedisKeyJobs(namespace, jobName string) string {
+	return RedisNamespacePrefix(namespace) + "jobs:" + jobName
+}
+
+// RedisKeyDead returns key of the dead jobs.
+func RedisKeyDead(namespace string) string {
+	return RedisNamespacePrefix(namespace) + "dead"
+}
+
 // RedisKeyLastPeriodicEnqueue returns key of timestamp if last periodic enqueue.
 func RedisKeyLastPeriodicEnqueue(namespace string) string {
 	return RedisNamespacePrefix(namespace) + "last_periodic_enqueue_h"
 }
 
+// HashTaggedNamespace wraps the namespace with the hash tag, so all the keys of the namespace are
+// hashed to the same slot of the redis cluster as required by the multiple keys commands and lua scripts.
+func HashTaggedNamespace(namespace string) string {
+	return fmt.Sprintf("{%s}", namespace)
+}
+
+// KeySlot returns the slot of the key in the redis cluster, only the hash tag is hashed if it exists.
+func KeySlot(key string) uint16 {
+	if start := strings.IndexByte(key, '{'); start >= 0 {
+		if end := strings.IndexByte(key[start+1:], '}'); end > 0 {
+			key = key[start+1 : start+1+end]
+		}
+	}
+
+	return crc16(key) % 16384
+}
+
+// crc16 is the CRC16-CCITT (XMODEM) checksum used by the redis cluster
+func crc16(s string) uint16 {
+	var crc uint16
+	for i := 0; i < len(s); i++ {
+		crc ^= uint16(s[i]) << 8
+		for j := 0; j < 8; j++ {
+			if crc&0x8000 != 0 {
+				crc = crc<<1 ^ 0x1021
+			} else {
+				crc <<= 1
+			}
+		}
+	}
+
+	return crc
+}
+
 // KeyNamespacePrefix returns the based key based on the namespace.
 func KeyNamespacePrefix(namespace string) string {
 	ns := strings.TrimSpace(namespace)
@@ -49,9 +93,14 @@ func KeyPeriodicNotification(namespace string) string {
 	return fmt.Sprintf("%s:%s", KeyPeriodicPolicy(namespace), "notifications")
 }
 
-// KeyPeriodicLock returns the key of locker under period
-func KeyPeriodicLock(namespace string) string {
-	return fmt.Sprintf("%s:%s", KeyPeriod(namespace), "lock")
+// KeyPeriodicLeader returns the key of the leader lease of the periodic enqueuer
+func KeyPeriodicLeader(namespace string) string {
+	return fmt.Sprintf("%s:%s", KeyPeriod(namespace), "leader")
+}
+
+// KeyPeriodicLeaderToken returns the key of the fencing token of the periodic enqueuer leader
+func KeyPeriodicLeaderToken(namespace string) string {
+	return fmt.Sprintf("%s:%s", KeyPeriodicLeader(namespace), "token")
 }
 
 // KeyJobStats returns the key of job stats
@@ -73,3 +122,58 @@ func KeyHookEventRetryQueue(namespace string) string {
 func KeyStatusUpdateRetryQueue(namespace string) string {
 	return fmt.Sprintf("%s%s", KeyNamespacePrefix(namespace), "status_change_events")
 }
+
+// KeyRateLimitBucket returns the key of the token bucket for the caller launching the job
+func KeyRateLimitBucket(namespace string, caller string, jobName string) string {
+	return fmt.Sprintf("%s%s:%s:%s", KeyNamespacePrefix(namespace), "rate_limit", caller, jobName)
+}
+
+// KeyRateLimitRejections returns the key of the counters of the requests rate limited in the time slot
+func KeyRateLimitRejections(namespace string, slot int64) string {
+	return fmt.Sprintf("%s%s:%d", KeyNamespacePrefix(namespace), "rate_limit_rejections", slot)
+}
+
+// KeyIdempotency returns the key of the job launched with the idempotency key by the caller
+func KeyIdempotency(namespace string, caller string, key string) string {
+	return fmt.Sprintf("%s%s:%s:%s", KeyNamespacePrefix(namespace), "idempotency", caller, key)
+}
+
+// KeyUniqueJob returns the key of the unique sign of job
+func KeyUniqueJob(namespace string, sign string) string {
+	return fmt.Sprintf("%s%s:%s", KeyNamespacePrefix(namespace), "unique_job", sign)
+}
+
+// KeyStream returns the key of the job stream of the redis streams backend
+func KeyStream(namespace string, jobName string) string {
+	return fmt.Sprintf("%s%s:%s", KeyNamespacePrefix(namespace), "streams", jobName)
+}
+
+// KeyStreamMetrics returns the key of the metrics of the job stream
+func KeyStreamMetrics(namespace string, jobName string) string {
+	return fmt.Sprintf("%s%s:%s", KeyNamespacePrefix(namespace), "stream_metrics", jobName)
+}
+
+// KeyStreamWorkerPools returns the key of the heartbeats of the stream worker pools
+func KeyStreamWorkerPools(namespace string) string {
+	return fmt.Sprintf("%s%s", KeyNamespacePrefix(namespace), "stream_worker_pools")
+}
+
+// KeyNodes returns the key of the heartbeats of the job service nodes
+func KeyNodes(namespace string) string {
+	return fmt.Sprintf("%s%s", KeyNamespacePrefix(namespace), "nodes")
+}
+
+// KeyNodeRunningJobs returns the key of the running jobs set of the specified node
+func KeyNodeRunningJobs(namespace string, nodeID string) string {
+	return fmt.Sprintf("%s:%s:%s", KeyNodes(namespace), "running", nodeID)
+}
+
+// KeyRetention returns the key of the finished jobs indexed by the time of archiving
+func KeyRetention(namespace string) string {
+	return fmt.Sprintf("%s%s", KeyNamespacePrefix(namespace), "retention")
+}
+
+// KeyStatusEvents returns the key of the stream of the job status change events
+func KeyStatusEvents(namespace string) string {
+	return fmt.Sprintf("%s%s", KeyNamespacePrefix(namespace), "status_events")
+}
@@ -5,6 +5,7 @@ import (
 	"github.com/chenxull/goGridhub/gridhub/src/jobservice/common/utils"
 	"github.com/gomodule/redigo/redis"
 	"github.com/pkg/errors"
+	"strings"
 	"time"
 )
 
@@ -151,3 +152,114 @@ func ReleaseLock(conn redis.Conn, lockerKey string, lockerID string) error {
 
 	return errors.New("locker ID mismatch")
 }
+
+var (
+	// Acquire or renew the lease, a new fencing token is generated only when the lease is newly acquired.
+	// KEYS[1]: lease key, KEYS[2]: token key
+	// ARGV[1]: owner, ARGV[2]: ttl in milliseconds
+	acquireLeaseScript = redis.NewScript(2, `
+local owner = redis.call('GET', KEYS[1])
+if not owner then
+	local token = redis.call('INCR', KEYS[2])
+	redis.call('SET', KEYS[1], ARGV[1], 'PX', ARGV[2])
+	return token
+end
+if owner == ARGV[1] then
+	redis.call('PEXPIRE', KEYS[1], ARGV[2])
+	return tonumber(redis.call('GET', KEYS[2]) or '0')
+end
+return 0
+`)
+
+	// Release the lease only if it's still held by the owner with the same token.
+	// KEYS[1]: lease key, KEYS[2]: token key
+	// ARGV[1]: owner, ARGV[2]: token
+	releaseLeaseScript = redis.NewScript(2, `
+if redis.call('GET', KEYS[1]) == ARGV[1] and redis.call('GET', KEYS[2]) == ARGV[2] then
+	return redis.call('DEL', KEYS[1])
+end
+return 0
+`)
+
+	// Run the write command only if the lease is still held by the owner with the same token.
+	// KEYS[1]: lease key, KEYS[2]: token key, KEYS[3]: the key to write
+	// ARGV[1]: owner, ARGV[2]: token, ARGV[3]: command, ARGV[4...]: command arguments
+	fencedWriteScript = redis.NewScript(3, `
+if redis.call('GET', KEYS[1]) ~= ARGV[1] or redis.call('GET', KEYS[2]) ~= ARGV[2] then
+	return redis.error_reply('FENCED lease is lost')
+end
+return redis.call(ARGV[3], KEYS[3], unpack(ARGV, 4))
+`)
+
+	// Move the scheduled job to the new run time, the zset entries are the serialized work.Job.
+	// KEYS[1]: scheduled zset
+	// ARGV[1]: current run time, ARGV[2]: job ID, ARGV[3]: new run time
+	rescheduleScript = redis.NewScript(1, `
+local jobs = redis.call('ZRANGEBYSCORE', KEYS[1], ARGV[1], ARGV[1])
+for _, raw in ipairs(jobs) do
+	local ok, j = pcall(cjson.decode, raw)
+	if ok and type(j) == 'table' and j['id'] == ARGV[2] then
+		redis.call('ZADD', KEYS[1], 'XX', ARGV[3], raw)
+		return 1
+	end
+end
+return 0
+`)
+)
+
+// fencedErrorCode is the code of the error replied by the fenced write script
+const fencedErrorCode = "FENCED "
+
+// ErrLeaseLost is returned when the lease guarding the write is not held anymore.
+var ErrLeaseLost = errors.New("lease is lost")
+
+// Lease identifies the holder of a lease and the fencing token bound to it.
+type Lease struct {
+	LeaseKey string
+	TokenKey string
+	Owner    string
+	Token    int64
+}
+
+// AcquireLease acquires or renews the lease with the specified TTL for the owner.
+// The returned fencing token increases monotonically whenever the lease changes hands.
+// A zero token is returned if the lease is held by others.
+func AcquireLease(conn redis.Conn, leaseKey string, tokenKey string, owner string, ttl time.Duration) (int64, error) {
+	return redis.Int64(acquireLeaseScript.Do(conn, leaseKey, tokenKey, owner, int64(ttl/time.Millisecond)))
+}
+
+// ReleaseLease releases the lease if it's still held by the owner with the token.
+func ReleaseLease(conn redis.Conn, lease *Lease) error {
+	_, err := releaseLeaseScript.Do(conn, lease.LeaseKey, lease.TokenKey, lease.Owner, lease.Token)
+	return err
+}
+
+// FencedDo runs the write command against the key only if the lease is still held.
+// ErrLeaseLost is returned if the lease is held by others or the token is outdated.
+func FencedDo(conn redis.Conn, lease *Lease, cmd string, key string, args ...interface{}) (interface{}, error) {
+	scriptArgs := make([]interface{}, 0, len(args)+6)
+	scriptArgs = append(scriptArgs, lease.LeaseKey, lease.TokenKey, key, lease.Owner, lease.Token, cmd)
+	scriptArgs = append(scriptArgs, args...)
+
+	reply, err := fencedWriteScript.Do(conn, scriptArgs...)
+	if err != nil {
+		// The error code is kept as the prefix, a bare code is prefixed with 'ERR' by redis
+		if strings.HasPrefix(err.Error(), fencedErrorCode) {
+			return nil, ErrLeaseLost
+		}
+		return nil, err
+	}
+
+	return reply, nil
+}
+
+// RescheduleJob moves the job in the scheduled zset from the current run time to the new one.
+// False is returned if the job is not found in the zset, e.g: it's already enqueued.
+func RescheduleJob(conn redis.Conn, key string, jobID string, runAt int64, newRunAt int64) (bool, error) {
+	moved, err := redis.Int(rescheduleScript.Do(conn, key, runAt, jobID, newRunAt))
+	if err != nil {
+		return false, err
+	}
+
+	return moved == 1, nil
+}
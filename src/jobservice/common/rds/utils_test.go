@@ -0,0 +1,128 @@
+package rds
+
+import (
+	"github.com/alicebob/miniredis/v2"
+	"github.com/gomodule/redigo/redis"
+	"testing"
+	"time"
+)
+
+const (
+	testLeaseKey = "{rds_test}:lease"
+	testTokenKey = "{rds_test}:lease_token"
+	testLeaseTTL = 30 * time.Second
+)
+
+func newTestConn(t *testing.T) (redis.Conn, *miniredis.Miniredis) {
+	t.Helper()
+
+	mr := miniredis.RunT(t)
+	conn, err := redis.Dial("tcp", mr.Addr())
+	if err != nil {
+		t.Fatalf("dial redis error: %s", err)
+	}
+	t.Cleanup(func() {
+		_ = conn.Close()
+	})
+
+	return conn, mr
+}
+
+func mustAcquire(t *testing.T, conn redis.Conn, owner string) int64 {
+	t.Helper()
+
+	token, err := AcquireLease(conn, testLeaseKey, testTokenKey, owner, testLeaseTTL)
+	if err != nil {
+		t.Fatalf("acquire lease for %s error: %s", owner, err)
+	}
+
+	return token
+}
+
+func TestAcquireLease(t *testing.T) {
+	conn, mr := newTestConn(t)
+
+	if token := mustAcquire(t, conn, "node-a"); token != 1 {
+		t.Fatalf("expect the lease acquired with token 1 but got %d", token)
+	}
+
+	// Renew keeps the token and resets the TTL
+	mr.FastForward(20 * time.Second)
+	if token := mustAcquire(t, conn, "node-a"); token != 1 {
+		t.Errorf("expect the lease renewed with token 1 but got %d", token)
+	}
+	if ttl := mr.TTL(testLeaseKey); ttl != testLeaseTTL {
+		t.Errorf("expect the lease TTL reset to %v but got %v", testLeaseTTL, ttl)
+	}
+
+	// Held by others
+	if token := mustAcquire(t, conn, "node-b"); token != 0 {
+		t.Errorf("expect no token for the lease held by others but got %d", token)
+	}
+
+	// Stolen after expiry with a new token
+	mr.FastForward(testLeaseTTL)
+	if token := mustAcquire(t, conn, "node-b"); token != 2 {
+		t.Errorf("expect the expired lease taken with token 2 but got %d", token)
+	}
+	if token := mustAcquire(t, conn, "node-a"); token != 0 {
+		t.Errorf("expect no token for the former owner but got %d", token)
+	}
+}
+
+func TestFencedDo(t *testing.T) {
+	conn, mr := newTestConn(t)
+
+	lease := &Lease{
+		LeaseKey: testLeaseKey,
+		TokenKey: testTokenKey,
+		Owner:    "node-a",
+		Token:    mustAcquire(t, conn, "node-a"),
+	}
+	if _, err := FencedDo(conn, lease, "SET", "{rds_test}:data", "a"); err != nil {
+		t.Fatalf("expect the fenced write done by the owner but got %s", err)
+	}
+
+	// node-a is paused until the lease is stolen
+	mr.FastForward(testLeaseTTL)
+	mustAcquire(t, conn, "node-b")
+	if _, err := FencedDo(conn, lease, "SET", "{rds_test}:data", "stale"); err != ErrLeaseLost {
+		t.Errorf("expect the write of the former owner fenced but got %v", err)
+	}
+
+	// Re-acquired by node-a, the write with the stale token is still fenced
+	mr.FastForward(testLeaseTTL)
+	if token := mustAcquire(t, conn, "node-a"); token != 3 {
+		t.Fatalf("expect the lease re-acquired with token 3 but got %d", token)
+	}
+	if _, err := FencedDo(conn, lease, "SET", "{rds_test}:data", "stale"); err != ErrLeaseLost {
+		t.Errorf("expect the write with the stale token fenced but got %v", err)
+	}
+
+	if v, _ := mr.Get("{rds_test}:data"); v != "a" {
+		t.Errorf("expect the data written only by the lease holder but got %s", v)
+	}
+}
+
+func TestReleaseLease(t *testing.T) {
+	conn, mr := newTestConn(t)
+
+	stale := &Lease{LeaseKey: testLeaseKey, TokenKey: testTokenKey, Owner: "node-a", Token: mustAcquire(t, conn, "node-a")}
+	mr.FastForward(testLeaseTTL)
+	lease := &Lease{LeaseKey: testLeaseKey, TokenKey: testTokenKey, Owner: "node-a", Token: mustAcquire(t, conn, "node-a")}
+
+	// The stale holder can not release the current lease
+	if err := ReleaseLease(conn, stale); err != nil {
+		t.Fatalf("release lease error: %s", err)
+	}
+	if !mr.Exists(testLeaseKey) {
+		t.Fatal("expect the lease kept after released with the stale token")
+	}
+
+	if err := ReleaseLease(conn, lease); err != nil {
+		t.Fatalf("release lease error: %s", err)
+	}
+	if mr.Exists(testLeaseKey) {
+		t.Error("expect the lease released by the holder")
+	}
+}
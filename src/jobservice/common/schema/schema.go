@@ -0,0 +1,351 @@
+// Package schema validates the job parameters against the JSON schema declared by the job.
+// Only the subset of the JSON schema keywords describing the parameters is supported:
+// type, properties, required, additionalProperties (boolean), items, enum, minimum,
+// maximum, exclusiveMinimum, exclusiveMaximum, minLength, maxLength, pattern, minItems
+// and maxItems. The annotation keywords like title, description and default are kept
+// but not validated.
+package schema
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/pkg/errors"
+	"math"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+// Types of the JSON values
+const (
+	TypeObject  = "object"
+	TypeArray   = "array"
+	TypeString  = "string"
+	TypeNumber  = "number"
+	TypeInteger = "integer"
+	TypeBoolean = "boolean"
+	TypeNull    = "null"
+)
+
+// Schema of the JSON value
+type Schema struct {
+	Title       string      `json:"title,omitempty"`
+	Description string      `json:"description,omitempty"`
+	Default     interface{} `json:"default,omitempty"`
+
+	Type                 Types              `json:"type,omitempty"`
+	Enum                 []interface{}      `json:"enum,omitempty"`
+	Properties           map[string]*Schema `json:"properties,omitempty"`
+	Required             []string           `json:"required,omitempty"`
+	AdditionalProperties *bool              `json:"additionalProperties,omitempty"`
+	Items                *Schema            `json:"items,omitempty"`
+	MinItems             *int               `json:"minItems,omitempty"`
+	MaxItems             *int               `json:"maxItems,omitempty"`
+	Minimum              *float64           `json:"minimum,omitempty"`
+	Maximum              *float64           `json:"maximum,omitempty"`
+	ExclusiveMinimum     *float64           `json:"exclusiveMinimum,omitempty"`
+	ExclusiveMaximum     *float64           `json:"exclusiveMaximum,omitempty"`
+	MinLength            *int               `json:"minLength,omitempty"`
+	MaxLength            *int               `json:"maxLength,omitempty"`
+	Pattern              string             `json:"pattern,omitempty"`
+
+	pattern *regexp.Regexp
+}
+
+// Types is the single type or the list of types allowed
+type Types []string
+
+// UnmarshalJSON accepts both the string and the list of strings
+func (t *Types) UnmarshalJSON(data []byte) error {
+	var single string
+	if err := json.Unmarshal(data, &single); err == nil {
+		*t = Types{single}
+		return nil
+	}
+
+	var list []string
+	if err := json.Unmarshal(data, &list); err != nil {
+		return errors.Errorf("type should be string or array of strings: %s", data)
+	}
+	*t = list
+
+	return nil
+}
+
+// MarshalJSON writes the single type as string
+func (t Types) MarshalJSON() ([]byte, error) {
+	if len(t) == 1 {
+		return json.Marshal(t[0])
+	}
+
+	return json.Marshal([]string(t))
+}
+
+// Parse the JSON schema
+func Parse(data []byte) (*Schema, error) {
+	s := &Schema{}
+	if err := json.Unmarshal(data, s); err != nil {
+		return nil, errors.Wrap(err, "parse parameters schema")
+	}
+
+	if err := s.compile("$"); err != nil {
+		return nil, err
+	}
+
+	return s, nil
+}
+
+// compile checks the keywords and compiles the patterns
+func (s *Schema) compile(path string) error {
+	for _, t := range s.Type {
+		switch t {
+		case TypeObject, TypeArray, TypeString, TypeNumber, TypeInteger, TypeBoolean, TypeNull:
+		default:
+			return errors.Errorf("%s: unknown type '%s' in schema", path, t)
+		}
+	}
+
+	if len(s.Pattern) > 0 {
+		p, err := regexp.Compile(s.Pattern)
+		if err != nil {
+			return errors.Wrapf(err, "%s: invalid pattern in schema", path)
+		}
+		s.pattern = p
+	}
+
+	for name, prop := range s.Properties {
+		if prop == nil {
+			return errors.Errorf("%s.%s: empty schema", path, name)
+		}
+		if err := prop.compile(fmt.Sprintf("%s.%s", path, name)); err != nil {
+			return err
+		}
+	}
+
+	if s.Items != nil {
+		if err := s.Items.compile(fmt.Sprintf("%s[]", path)); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// ValidationError keeps all the violations of the schema
+type ValidationError struct {
+	Violations []string
+}
+
+// Error implements error
+func (ve *ValidationError) Error() string {
+	return fmt.Sprintf("parameters do not match the schema: %s", strings.Join(ve.Violations, "; "))
+}
+
+// Validate the value against the schema.
+// The parameters map should be passed as map[string]interface{}.
+func (s *Schema) Validate(v interface{}) error {
+	violations := s.validate("$", v, nil)
+	if len(violations) > 0 {
+		return &ValidationError{Violations: violations}
+	}
+
+	return nil
+}
+
+func (s *Schema) validate(path string, v interface{}, violations []string) []string {
+	if len(s.Type) > 0 && !s.matchType(v) {
+		return append(violations, fmt.Sprintf("%s: expect %s but got %s", path, strings.Join(s.Type, " or "), typeOf(v)))
+	}
+
+	if len(s.Enum) > 0 {
+		matched := false
+		for _, e := range s.Enum {
+			if equal(e, v) {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			violations = append(violations, fmt.Sprintf("%s: value %v is not one of %v", path, v, s.Enum))
+		}
+	}
+
+	switch value := v.(type) {
+	case map[string]interface{}:
+		violations = s.validateObject(path, value, violations)
+	case []interface{}:
+		violations = s.validateArray(path, value, violations)
+	case string:
+		violations = s.validateString(path, value, violations)
+	default:
+		if n, ok := toFloat(v); ok {
+			violations = s.validateNumber(path, n, violations)
+		}
+	}
+
+	return violations
+}
+
+func (s *Schema) validateObject(path string, value map[string]interface{}, violations []string) []string {
+	for _, name := range s.Required {
+		if _, ok := value[name]; !ok {
+			violations = append(violations, fmt.Sprintf("%s.%s: required", path, name))
+		}
+	}
+
+	// Sort the keys to report the violations in the stable order
+	keys := make([]string, 0, len(value))
+	for k := range value {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	for _, k := range keys {
+		prop, ok := s.Properties[k]
+		if !ok {
+			if s.AdditionalProperties != nil && !*s.AdditionalProperties {
+				violations = append(violations, fmt.Sprintf("%s.%s: not allowed", path, k))
+			}
+			continue
+		}
+		violations = prop.validate(fmt.Sprintf("%s.%s", path, k), value[k], violations)
+	}
+
+	return violations
+}
+
+func (s *Schema) validateArray(path string, value []interface{}, violations []string) []string {
+	if s.MinItems != nil && len(value) < *s.MinItems {
+		violations = append(violations, fmt.Sprintf("%s: should have at least %d items", path, *s.MinItems))
+	}
+	if s.MaxItems != nil && len(value) > *s.MaxItems {
+		violations = append(violations, fmt.Sprintf("%s: should have at most %d items", path, *s.MaxItems))
+	}
+
+	if s.Items != nil {
+		for i, item := range value {
+			violations = s.Items.validate(fmt.Sprintf("%s[%d]", path, i), item, violations)
+		}
+	}
+
+	return violations
+}
+
+func (s *Schema) validateString(path string, value string, violations []string) []string {
+	length := utf8.RuneCountInString(value)
+	if s.MinLength != nil && length < *s.MinLength {
+		violations = append(violations, fmt.Sprintf("%s: should have at least %d characters", path, *s.MinLength))
+	}
+	if s.MaxLength != nil && length > *s.MaxLength {
+		violations = append(violations, fmt.Sprintf("%s: should have at most %d characters", path, *s.MaxLength))
+	}
+	if s.pattern != nil && !s.pattern.MatchString(value) {
+		violations = append(violations, fmt.Sprintf("%s: should match pattern %s", path, s.Pattern))
+	}
+
+	return violations
+}
+
+func (s *Schema) validateNumber(path string, value float64, violations []string) []string {
+	if s.Minimum != nil && value < *s.Minimum {
+		violations = append(violations, fmt.Sprintf("%s: should be >= %v", path, *s.Minimum))
+	}
+	if s.Maximum != nil && value > *s.Maximum {
+		violations = append(violations, fmt.Sprintf("%s: should be <= %v", path, *s.Maximum))
+	}
+	if s.ExclusiveMinimum != nil && value <= *s.ExclusiveMinimum {
+		violations = append(violations, fmt.Sprintf("%s: should be > %v", path, *s.ExclusiveMinimum))
+	}
+	if s.ExclusiveMaximum != nil && value >= *s.ExclusiveMaximum {
+		violations = append(violations, fmt.Sprintf("%s: should be < %v", path, *s.ExclusiveMaximum))
+	}
+
+	return violations
+}
+
+func (s *Schema) matchType(v interface{}) bool {
+	actual := typeOf(v)
+	for _, t := range s.Type {
+		if t == actual {
+			return true
+		}
+		// Integer is also a number
+		if t == TypeNumber && actual == TypeInteger {
+			return true
+		}
+	}
+
+	return false
+}
+
+// typeOf returns the JSON type of the value
+func typeOf(v interface{}) string {
+	switch v.(type) {
+	case nil:
+		return TypeNull
+	case map[string]interface{}:
+		return TypeObject
+	case []interface{}:
+		return TypeArray
+	case string:
+		return TypeString
+	case bool:
+		return TypeBoolean
+	}
+
+	if n, ok := toFloat(v); ok {
+		if n == math.Trunc(n) && !math.IsInf(n, 0) {
+			return TypeInteger
+		}
+		return TypeNumber
+	}
+
+	return reflect.TypeOf(v).String()
+}
+
+// toFloat converts the numeric value to float64
+func toFloat(v interface{}) (float64, bool) {
+	switch n := v.(type) {
+	case float64:
+		return n, true
+	case float32:
+		return float64(n), true
+	case int:
+		return float64(n), true
+	case int8:
+		return float64(n), true
+	case int16:
+		return float64(n), true
+	case int32:
+		return float64(n), true
+	case int64:
+		return float64(n), true
+	case uint:
+		return float64(n), true
+	case uint8:
+		return float64(n), true
+	case uint16:
+		return float64(n), true
+	case uint32:
+		return float64(n), true
+	case uint64:
+		return float64(n), true
+	case json.Number:
+		f, err := n.Float64()
+		return f, err == nil
+	}
+
+	return 0, false
+}
+
+// equal compares the JSON values, the numbers are compared by the values
+func equal(a, b interface{}) bool {
+	if x, ok := toFloat(a); ok {
+		y, ok := toFloat(b)
+		return ok && x == y
+	}
+
+	return reflect.DeepEqual(a, b)
+}
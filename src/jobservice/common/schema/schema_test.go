@@ -0,0 +1,194 @@
+package schema
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const testSchema = `{
+	"type": "object",
+	"required": ["image", "mode"],
+	"additionalProperties": false,
+	"properties": {
+		"image": {"type": "string", "minLength": 3, "maxLength": 64, "pattern": "^[a-z0-9/._-]+(:[a-z0-9._-]+)?$"},
+		"mode": {"enum": ["fast", "full", 3]},
+		"retries": {"type": "integer", "minimum": 0, "maximum": 5},
+		"ratio": {"type": "number", "exclusiveMinimum": 0, "exclusiveMaximum": 1},
+		"dry_run": {"type": "boolean"},
+		"note": {"type": ["string", "null"]},
+		"tags": {"type": "array", "minItems": 1, "maxItems": 2, "items": {"type": "string", "minLength": 1}},
+		"target": {
+			"type": "object",
+			"required": ["registry"],
+			"properties": {
+				"registry": {"type": "string"},
+				"port": {"type": "integer", "minimum": 1, "maximum": 65535}
+			}
+		}
+	}
+}`
+
+func TestValidate(t *testing.T) {
+	s, err := Parse([]byte(testSchema))
+	if err != nil {
+		t.Fatalf("parse schema error: %s", err)
+	}
+
+	cases := []struct {
+		name       string
+		params     string
+		violations []string
+	}{
+		{
+			name:   "valid",
+			params: `{"image": "library/busybox:1.0", "mode": "fast", "retries": 5, "ratio": 0.5, "dry_run": true, "note": null, "tags": ["a", "b"], "target": {"registry": "r", "port": 443}}`,
+		},
+		{
+			name:   "number in enum",
+			params: `{"image": "abc", "mode": 3.0}`,
+		},
+		{
+			name:       "not object",
+			params:     `["image"]`,
+			violations: []string{"$: expect object but got array"},
+		},
+		{
+			name:       "required",
+			params:     `{"image": "abc"}`,
+			violations: []string{"$.mode: required"},
+		},
+		{
+			name:       "additional property",
+			params:     `{"image": "abc", "mode": "full", "extra": 1}`,
+			violations: []string{"$.extra: not allowed"},
+		},
+		{
+			name:   "type mismatch",
+			params: `{"image": 1, "mode": "full", "retries": 1.5, "dry_run": "yes", "note": 1}`,
+			violations: []string{
+				"$.dry_run: expect boolean but got string",
+				"$.image: expect string but got integer",
+				"$.note: expect string or null but got integer",
+				"$.retries: expect integer but got number",
+			},
+		},
+		{
+			name:       "enum",
+			params:     `{"image": "abc", "mode": "slow"}`,
+			violations: []string{"$.mode: value slow is not one of [fast full 3]"},
+		},
+		{
+			name:       "minimum",
+			params:     `{"image": "abc", "mode": "fast", "retries": -1, "ratio": 0}`,
+			violations: []string{"$.ratio: should be > 0", "$.retries: should be >= 0"},
+		},
+		{
+			name:       "maximum",
+			params:     `{"image": "abc", "mode": "fast", "retries": 6, "ratio": 1}`,
+			violations: []string{"$.ratio: should be < 1", "$.retries: should be <= 5"},
+		},
+		{
+			name:   "string length",
+			params: `{"image": "ab", "mode": "fast"}`,
+			violations: []string{
+				"$.image: should have at least 3 characters",
+			},
+		},
+		{
+			name:       "pattern",
+			params:     `{"image": "Library/Busybox", "mode": "fast"}`,
+			violations: []string{"$.image: should match pattern ^[a-z0-9/._-]+(:[a-z0-9._-]+)?$"},
+		},
+		{
+			name:   "nested object",
+			params: `{"image": "abc", "mode": "fast", "target": {"port": 0}}`,
+			violations: []string{
+				"$.target.registry: required",
+				"$.target.port: should be >= 1",
+			},
+		},
+		{
+			name:   "array",
+			params: `{"image": "abc", "mode": "fast", "tags": ["a", "", 1]}`,
+			violations: []string{
+				"$.tags: should have at most 2 items",
+				"$.tags[1]: should have at least 1 characters",
+				"$.tags[2]: expect string but got integer",
+			},
+		},
+		{
+			name:       "empty array",
+			params:     `{"image": "abc", "mode": "fast", "tags": []}`,
+			violations: []string{"$.tags: should have at least 1 items"},
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			var params interface{}
+			if err := json.Unmarshal([]byte(c.params), &params); err != nil {
+				t.Fatalf("unmarshal parameters error: %s", err)
+			}
+
+			err := s.Validate(params)
+			if len(c.violations) == 0 {
+				if err != nil {
+					t.Errorf("expect no error but got %s", err)
+				}
+				return
+			}
+
+			ve, ok := err.(*ValidationError)
+			if !ok {
+				t.Fatalf("expect validation error but got %v", err)
+			}
+			if !reflect.DeepEqual(ve.Violations, c.violations) {
+				t.Errorf("expect violations %q but got %q", c.violations, ve.Violations)
+			}
+		})
+	}
+}
+
+func TestParseRejected(t *testing.T) {
+	cases := []struct {
+		name   string
+		schema string
+		err    string
+	}{
+		{name: "malformed JSON", schema: `{"type": `, err: "parse parameters schema"},
+		{name: "invalid type keyword", schema: `{"type": 1}`, err: "type should be string or array of strings"},
+		{name: "unknown type", schema: `{"type": "map"}`, err: "$: unknown type 'map' in schema"},
+		{name: "unknown nested type", schema: `{"properties": {"a": {"type": ["string", "float"]}}}`, err: "$.a: unknown type 'float' in schema"},
+		{name: "invalid pattern", schema: `{"properties": {"a": {"pattern": "("}}}`, err: "$.a: invalid pattern in schema"},
+		{name: "invalid items pattern", schema: `{"items": {"pattern": "[a-"}}`, err: "$[]: invalid pattern in schema"},
+		{name: "empty property schema", schema: `{"properties": {"a": null}}`, err: "$.a: empty schema"},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			_, err := Parse([]byte(c.schema))
+			if err == nil || !strings.Contains(err.Error(), c.err) {
+				t.Errorf("expect error containing %q but got %v", c.err, err)
+			}
+		})
+	}
+}
+
+func TestTypesJSON(t *testing.T) {
+	for _, raw := range []string{`"string"`, `["string","null"]`} {
+		var types Types
+		if err := json.Unmarshal([]byte(raw), &types); err != nil {
+			t.Fatalf("unmarshal %s error: %s", raw, err)
+		}
+
+		data, err := json.Marshal(types)
+		if err != nil {
+			t.Fatalf("marshal %v error: %s", types, err)
+		}
+		if string(data) != raw {
+			t.Errorf("expect %s but got %s", raw, data)
+		}
+	}
+}
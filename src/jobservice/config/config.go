@@ -3,17 +3,23 @@ package config
 import (
 	"errors"
 	"fmt"
+	"github.com/chenxull/goGridhub/gridhub/src/common/secret"
+	"github.com/chenxull/goGridhub/gridhub/src/jobservice/auth"
+	"github.com/chenxull/goGridhub/gridhub/src/jobservice/common/schema"
 	"github.com/chenxull/goGridhub/gridhub/src/jobservice/common/utils"
 	yaml "gopkg.in/yaml.v2"
 	"io/ioutil"
 	"net/url"
+	"path"
 	"strconv"
 	"strings"
+	"sync"
 )
 
 const (
 	jobServiceProtocol                   = "JOB_SERVICE_PROTOCOL"
 	jobServicePort                       = "JOB_SERVICE_PORT"
+	jobServiceGRPCPort                   = "JOB_SERVICE_GRPC_PORT"
 	jobServiceHTTPCert                   = "JOB_SERVICE_HTTPS_CERT"
 	jobServiceHTTPKey                    = "JOB_SERVICE_HTTPS_KEY"
 	jobServiceWorkerPoolBackend          = "JOB_SERVICE_POOL_BACKEND"
@@ -22,6 +28,7 @@ const (
 	jobServiceRedisNamespace             = "JOB_SERVICE_POOL_REDIS_NAMESPACE"
 	jobServiceRedisIdleConnTimeoutSecond = "JOB_SERVICE_POOL_REDIS_CONN_IDLE_TIMEOUT_SECOND"
 	jobServiceAuthSecret                 = "JOBSERVICE_SECRET"
+	jobServiceParametersKey              = "JOB_SERVICE_PARAMETERS_KEY"
 	coreURL                              = "CORE_URL"
 
 	// JobServiceProtocolHTTPS points to the 'https' protocol
@@ -31,12 +38,34 @@ const (
 
 	// JobServicePoolBackendRedis represents redis backend
 	JobServicePoolBackendRedis = "redis"
+	// JobServicePoolBackendRedisStreams represents redis streams backend
+	JobServicePoolBackendRedisStreams = "redis_streams"
+	// JobServicePoolBackendMemory represents in-memory backend for the single node deployment and testing
+	JobServicePoolBackendMemory = "memory"
+
+	// ArchiveSinkFile archives the job stats to the file
+	ArchiveSinkFile = "file"
+	// ArchiveSinkDatabase archives the job stats to the database
+	ArchiveSinkDatabase = "database"
+
+	// AuditSinkFile appends the audit log to the file
+	AuditSinkFile = "file"
+	// AuditSinkDatabase appends the audit log to the database
+	AuditSinkDatabase = "database"
+
+	// Kinds of the jobs matched by the retention rules
+	RetentionKindGeneric   = "Generic"
+	RetentionKindScheduled = "Scheduled"
+	RetentionKindPeriodic  = "Periodic"
+	RetentionKindExecution = "Execution"
 
 	// secret of UI
 	uiAuthSecret = "CORE_SECRET"
 
 	// redis protocol schema
 	redisSchema = "redis://"
+	// redis protocol schema with TLS
+	redisTLSSchema = "rediss://"
 )
 
 //DefaultConfig is the default configuration reference
@@ -47,11 +76,38 @@ type Configuration struct {
 	Port        uint         `yaml:"port"`
 	HTTPSConfig *HTTPSConfig `yaml:"https_config,omitempty"`
 	PoolConfig  *PoolConfig  `yaml:"worker_pool,omitempty"`
+	// Port of serving the gRPC API alongside the HTTP API with the same protocol, 0 means no gRPC API
+	GRPCPort uint `yaml:"grpc_port,omitempty"`
 	// Job logger configurations
 	JobLoggerConfigs []*LoggerConfig `yaml:"job_loggers,omitempty"`
 
 	// Logger configurations
 	LoggerConfigs []*LoggerConfig `yaml:"loggers,omitempty"`
+
+	// Rate limits of launching jobs
+	RateLimitConfig *RateLimitConfig `yaml:"rate_limit,omitempty"`
+
+	// Admission control of launching jobs
+	AdmissionConfig *AdmissionConfig `yaml:"admission,omitempty"`
+
+	// Idempotency of launching jobs
+	IdempotencyConfig *IdempotencyConfig `yaml:"idempotency,omitempty"`
+
+	// Jobs run by the external executables
+	PluginConfigs []*PluginConfig `yaml:"plugins,omitempty"`
+
+	// Retention and archival of the job stats
+	RetentionConfig *RetentionConfig `yaml:"retention,omitempty"`
+
+	// API credentials besides the shared secret
+	AuthConfig *AuthConfig `yaml:"auth,omitempty"`
+
+	// Audit log of the API actions on the jobs
+	AuditConfig *AuditConfig `yaml:"audit,omitempty"`
+
+	// Where the configuration is loaded from, kept for reloading
+	filePath  string
+	detectEnv bool
 }
 
 type HTTPSConfig struct {
@@ -66,15 +122,64 @@ type RedisPoolConfig struct {
 	// is zero, then idle connections are not closed. Applications should set
 	// the timeout to a value less than the server's timeout.
 	IdleTimeoutSecond int64 `yaml:"idle_timeout_second"`
+
+	// Only one of the redis URL, the sentinel and the cluster is configured
+	Sentinel *RedisSentinelConfig `yaml:"sentinel,omitempty"`
+	Cluster  *RedisClusterConfig  `yaml:"cluster,omitempty"`
+	// ACL username and password of the redis server, the ones in the redis URL are preferred
+	Username string          `yaml:"username,omitempty"`
+	Password string          `yaml:"password,omitempty"`
+	TLS      *RedisTLSConfig `yaml:"tls,omitempty"`
+}
+
+// RedisSentinelConfig keeps the settings of discovering the master by the redis sentinels
+type RedisSentinelConfig struct {
+	MasterName string `yaml:"master_name"`
+	// Addresses of the sentinels in the form of 'host:port'
+	Addrs []string `yaml:"addrs"`
+	// ACL username and password of the sentinels, which may differ from the ones of the master
+	Username string `yaml:"username,omitempty"`
+	Password string `yaml:"password,omitempty"`
+	DB       int    `yaml:"db,omitempty"`
+}
+
+// RedisClusterConfig keeps the settings of the redis cluster.
+// All the keys of the namespace are hashed to the same slot, so the master owning the slot is used.
+type RedisClusterConfig struct {
+	// Addresses of the seed nodes in the form of 'host:port'
+	Addrs []string `yaml:"addrs"`
+}
+
+// RedisTLSConfig keeps the settings of connecting the redis servers with TLS
+type RedisTLSConfig struct {
+	// CA certificate of verifying the servers, the system CAs are used if it's empty
+	CAFile string `yaml:"ca_file,omitempty"`
+	// Client certificate and key if the servers require them
+	CertFile           string `yaml:"cert_file,omitempty"`
+	KeyFile            string `yaml:"key_file,omitempty"`
+	ServerName         string `yaml:"server_name,omitempty"`
+	InsecureSkipVerify bool   `yaml:"insecure_skip_verify,omitempty"`
+}
+
+// IsURLMode returns true if the single redis server is dialed with the redis URL
+func (rc *RedisPoolConfig) IsURLMode() bool {
+	return rc.Sentinel == nil && rc.Cluster == nil
 }
 
 type PoolConfig struct {
-	// Worker concurrency
+	// Worker concurrency, changing it at runtime is only applied to the redis streams and
+	// memory backends, the worker pool of the redis backend requires restart
 	WorkerCount  uint             `yaml:"workers"`
 	Backend      string           `yaml:"backend"`
 	RedisPoolCfg *RedisPoolConfig `yaml:"redis_pool,omitempty"`
 }
 
+// IsRedisBackend returns true if the backend is based on redis
+func (pc *PoolConfig) IsRedisBackend() bool {
+	return pc.Backend == JobServicePoolBackendRedis ||
+		pc.Backend == JobServicePoolBackendRedisStreams
+}
+
 // CustomizedSettings keeps the customized settings of logger
 type CustomizedSettings map[string]interface{}
 
@@ -91,7 +196,152 @@ type LoggerConfig struct {
 	Sweeper  *LogSweeperConfig  `yaml:"sweeper"`
 }
 
+// RateLimitConfig keeps the token bucket settings of launching jobs
+type RateLimitConfig struct {
+	// Applied if no rule is matched, no limit if it's not set
+	Default *RateLimitRule `yaml:"default,omitempty"`
+	// The first matched rule is applied
+	Rules []*RateLimitRule `yaml:"rules,omitempty"`
+}
+
+// RateLimitRule keeps the token bucket settings for the matched caller and job name
+type RateLimitRule struct {
+	// Pattern of the caller identity, empty or '*' matches all
+	Caller string `yaml:"caller"`
+	// Pattern of the job name, empty or '*' matches all
+	Job string `yaml:"job"`
+	// Tokens refilled per second
+	Rate float64 `yaml:"rate"`
+	// Max tokens of the bucket
+	Burst uint `yaml:"burst"`
+}
+
+// AdmissionConfig keeps the thresholds of admitting the generic jobs
+type AdmissionConfig struct {
+	// Max pending jobs in the queue of each job type, 0 means no limit
+	MaxQueueDepth int64 `yaml:"max_queue_depth"`
+	// Override the max queue depth for the specified job types
+	QueueDepths map[string]int64 `yaml:"queue_depths,omitempty"`
+	// Max ratio of the used memory to the 'maxmemory' of redis, 0 means no limit
+	MaxMemoryUsage float64 `yaml:"max_memory_usage"`
+	// Defer the low priority jobs with this delay instead of rejecting them, 0 means rejecting
+	DeferSeconds uint64 `yaml:"defer_seconds"`
+}
+
+// IdempotencyConfig keeps the settings of the idempotency keys of launching jobs
+type IdempotencyConfig struct {
+	// How long the idempotency key is kept, 0 means the default 24 hours
+	WindowSeconds uint `yaml:"window_seconds"`
+}
+
+// PluginConfig keeps the settings of the job run by an external executable
+type PluginConfig struct {
+	// Name of the job
+	Name string `yaml:"name"`
+	// Path of the executable
+	Path string   `yaml:"path"`
+	Args []string `yaml:"args,omitempty"`
+	// Extra environment variables in the form of "key=value"
+	Env      []string `yaml:"env,omitempty"`
+	MaxFails uint     `yaml:"max_fails"`
+	Retry    bool     `yaml:"retry"`
+	// Ask the executable to validate the parameters before launching the job
+	Validate bool `yaml:"validate"`
+	// JSON schema of the parameters
+	ParametersSchema string `yaml:"parameters_schema,omitempty"`
+	// Keys of the secret parameters
+	SecretParameters []string `yaml:"secret_parameters,omitempty"`
+	// How long to wait for the executable exiting after the stop command, 0 means the default 10 seconds
+	StopGraceSeconds uint `yaml:"stop_grace_seconds"`
+}
+
+// RetentionConfig keeps the settings of how long the stats of the finished jobs are kept
+type RetentionConfig struct {
+	// The first matched rule is applied, the default retention is used if no rule is matched
+	Rules []*RetentionRule `yaml:"rules,omitempty"`
+	// Archive the expired job stats before removing them, no archival if it's not set
+	Archive *ArchiveConfig `yaml:"archive,omitempty"`
+	// Interval of sweeping the expired job stats, 0 means the default 60 seconds
+	SweepIntervalSeconds uint `yaml:"sweep_interval_seconds"`
+}
+
+// RetentionRule keeps the retention of the matched jobs
+type RetentionRule struct {
+	// Pattern of the job name, empty or '*' matches all
+	Job string `yaml:"job"`
+	// Kind of the job: Generic, Scheduled, Periodic or Execution (the execution of the periodic job), empty matches all
+	Kind string `yaml:"kind"`
+	// Final status of the job: Success, Error or Stopped, empty matches all
+	Status string `yaml:"status"`
+	// Seconds of keeping the job stats
+	KeepSeconds uint `yaml:"keep_seconds"`
+}
+
+// ArchiveConfig keeps the settings of the sink of the archived job stats
+type ArchiveConfig struct {
+	// Sink type: file or database
+	Sink string `yaml:"sink"`
+	// Path of the file keeping the archived job stats if the sink is file
+	Path string `yaml:"path,omitempty"`
+	// PostgreSQL connection string if the sink is database
+	DSN string `yaml:"dsn,omitempty"`
+}
+
+// AuditConfig keeps the settings of the sink of the audit log
+type AuditConfig struct {
+	// Sink type: file or database
+	Sink string `yaml:"sink"`
+	// Path of the file keeping the audit log if the sink is file
+	Path string `yaml:"path,omitempty"`
+	// PostgreSQL connection string if the sink is database
+	DSN string `yaml:"dsn,omitempty"`
+}
+
+// AuthConfig keeps the named API credentials with the scoped permissions.
+// The scope is in the form of '<scope>' or '<scope>:<job name pattern>', e.g: 'jobs:launch:REPLICATION*'.
+type AuthConfig struct {
+	// Static bearer tokens
+	Credentials []*CredentialConfig `yaml:"credentials,omitempty"`
+	// JWT bearer tokens, not accepted if it's not set
+	JWT *JWTConfig `yaml:"jwt,omitempty"`
+}
+
+// CredentialConfig keeps the static token of the named caller
+type CredentialConfig struct {
+	Name string `yaml:"name"`
+	// The token in the env variable is preferred
+	Token    string   `yaml:"token,omitempty"`
+	TokenEnv string   `yaml:"token_env,omitempty"`
+	Scopes   []string `yaml:"scopes"`
+}
+
+// GetToken returns the token of the credential
+func (cc *CredentialConfig) GetToken() string {
+	if !utils.IsEmptyStr(cc.TokenEnv) {
+		if token := utils.ReadEnv(cc.TokenEnv); !utils.IsEmptyStr(token) {
+			return token
+		}
+	}
+
+	return cc.Token
+}
+
+// JWTConfig keeps the settings of validating the JWT bearer tokens
+type JWTConfig struct {
+	// Path of the local JWKS file with the keys of verifying the token signatures
+	JWKSFile string `yaml:"jwks_file"`
+	// Expected 'iss' claim, not checked if it's empty
+	Issuer string `yaml:"issuer,omitempty"`
+	// Expected 'aud' claim, not checked if it's empty
+	Audience string `yaml:"audience,omitempty"`
+	// Claim keeping the scopes, default is 'scope'
+	ScopesClaim string `yaml:"scopes_claim,omitempty"`
+}
+
 func (c *Configuration) Load(yamlFilePath string, detectEnv bool) error {
+	c.filePath = yamlFilePath
+	c.detectEnv = detectEnv
+
 	if !utils.IsEmptyStr(yamlFilePath) {
 		data, err := ioutil.ReadFile(yamlFilePath)
 		if err != nil {
@@ -112,7 +362,7 @@ func (c *Configuration) Load(yamlFilePath string, detectEnv bool) error {
 					c.PoolConfig.RedisPoolCfg.RedisURL = redisURL
 				}
 			} else {
-				if !strings.HasPrefix(redisAddress, redisSchema) {
+				if !strings.HasPrefix(redisAddress, redisSchema) && !strings.HasPrefix(redisAddress, redisTLSSchema) {
 					c.PoolConfig.RedisPoolCfg.RedisURL = fmt.Sprintf("%s%s", redisSchema, redisAddress)
 				}
 			}
@@ -135,6 +385,13 @@ func (c *Configuration) loadEnvs() {
 		}
 	}
 
+	gp := utils.ReadEnv(jobServiceGRPCPort)
+	if !utils.IsEmptyStr(gp) {
+		if po, err := strconv.Atoi(gp); err == nil {
+			c.GRPCPort = uint(po)
+		}
+	}
+
 	// Only when protocol is https
 	if c.Protocol == JobServiceProtocolHTTPS {
 		cert := utils.ReadEnv(jobServiceHTTPCert)
@@ -178,7 +435,7 @@ func (c *Configuration) loadEnvs() {
 		}
 	}
 
-	if c.PoolConfig != nil && c.PoolConfig.Backend == JobServicePoolBackendRedis {
+	if c.PoolConfig != nil && c.PoolConfig.IsRedisBackend() {
 		redisURL := utils.ReadEnv(jobServiceRedisURL)
 		if !utils.IsEmptyStr(redisURL) {
 			if c.PoolConfig.RedisPoolCfg == nil {
@@ -210,9 +467,37 @@ func (c *Configuration) loadEnvs() {
 	}
 }
 
-// GetAuthSecret get the auth secret from the env
+var (
+	authSecrets     *secret.Store
+	authSecretsErr  error
+	authSecretsOnce sync.Once
+)
+
+// AuthSecrets returns the store of the active auth secrets loaded from the file in the env
+// JOBSERVICE_SECRET_FILE, or the static auth secret in the env JOBSERVICE_SECRET if the file is not set
+func AuthSecrets() (*secret.Store, error) {
+	authSecretsOnce.Do(func() {
+		authSecrets, authSecretsErr = secret.NewStoreFromEnv(jobServiceAuthSecret)
+	})
+
+	return authSecrets, authSecretsErr
+}
+
+// GetAuthSecret get the current auth secret
 func GetAuthSecret() string {
-	return utils.ReadEnv(jobServiceAuthSecret)
+	store, err := AuthSecrets()
+	if err != nil {
+		return ""
+	}
+
+	return store.Current()
+}
+
+// GetParametersKey get the dedicated key of encrypting the secret job parameters from the env.
+// The auth secret is never used as it's rotated, which would make the encrypted parameters unreadable,
+// so the secret parameters are rejected if the key is not set.
+func GetParametersKey() string {
+	return utils.ReadEnv(jobServiceParametersKey)
 }
 
 // GetCoreURL get the core url from the env
@@ -225,6 +510,59 @@ func GetUIAuthSecret() string {
 	return utils.ReadEnv(uiAuthSecret)
 }
 
+func (rc *RedisPoolConfig) validate() error {
+	if rc.Sentinel != nil && rc.Cluster != nil {
+		return errors.New("redis sentinel and redis cluster can not be configured together")
+	}
+
+	if rc.IsURLMode() {
+		if utils.IsEmptyStr(rc.RedisURL) {
+			return errors.New("URL of redis worker is empty")
+		}
+
+		if !strings.HasPrefix(rc.RedisURL, redisSchema) && !strings.HasPrefix(rc.RedisURL, redisTLSSchema) {
+			return errors.New("invalid redis URL")
+		}
+
+		if _, err := url.Parse(rc.RedisURL); err != nil {
+			return fmt.Errorf("invalid redis URL: %s", err.Error())
+		}
+	} else if !utils.IsEmptyStr(rc.RedisURL) {
+		return errors.New("redis URL can not be configured with redis sentinel or redis cluster")
+	}
+
+	if rc.Sentinel != nil {
+		if utils.IsEmptyStr(rc.Sentinel.MasterName) {
+			return errors.New("master name of redis sentinel is required")
+		}
+		if len(rc.Sentinel.Addrs) == 0 {
+			return errors.New("addresses of redis sentinels are required")
+		}
+		if rc.Sentinel.DB < 0 {
+			return errors.New("database of redis sentinel should not be negative")
+		}
+	}
+
+	if rc.Cluster != nil && len(rc.Cluster.Addrs) == 0 {
+		return errors.New("addresses of redis cluster nodes are required")
+	}
+
+	if rc.TLS != nil {
+		if !utils.IsEmptyStr(rc.TLS.CAFile) && !utils.FileExists(rc.TLS.CAFile) {
+			return fmt.Errorf("CA file %s of redis TLS does not exist", rc.TLS.CAFile)
+		}
+		if utils.IsEmptyStr(rc.TLS.CertFile) != utils.IsEmptyStr(rc.TLS.KeyFile) {
+			return errors.New("certificate and key of redis TLS should be configured together")
+		}
+		if !utils.IsEmptyStr(rc.TLS.CertFile) &&
+			(!utils.FileExists(rc.TLS.CertFile) || !utils.FileExists(rc.TLS.KeyFile)) {
+			return errors.New("certificate or key file of redis TLS does not exist")
+		}
+	}
+
+	return nil
+}
+
 func (c *Configuration) validate() error {
 	if c.Protocol != JobServiceProtocolHTTPS &&
 		c.Protocol != JobServiceProtocolHTTP {
@@ -237,6 +575,15 @@ func (c *Configuration) validate() error {
 		return fmt.Errorf("port number should be a none zero integer and less or equal 65535, but current is %d", c.Port)
 	}
 
+	if c.GRPCPort != 0 {
+		if !utils.IsValidPort(c.GRPCPort) {
+			return fmt.Errorf("gRPC port number should be less or equal 65535, but current is %d", c.GRPCPort)
+		}
+		if c.GRPCPort == c.Port {
+			return fmt.Errorf("gRPC port %d should not be the same as the port of the HTTP API", c.GRPCPort)
+		}
+	}
+
 	if c.Protocol == JobServiceProtocolHTTPS {
 		if c.HTTPSConfig == nil {
 			return fmt.Errorf("certificate must be configured if serve with protocol %s", c.Protocol)
@@ -254,29 +601,163 @@ func (c *Configuration) validate() error {
 		return errors.New("no worker worker is configured")
 	}
 
-	if c.PoolConfig.Backend != JobServicePoolBackendRedis {
+	if !c.PoolConfig.IsRedisBackend() && c.PoolConfig.Backend != JobServicePoolBackendMemory {
 		return fmt.Errorf("worker worker backend %s does not support", c.PoolConfig.Backend)
 	}
 
 	// When backend is redis
-	if c.PoolConfig.Backend == JobServicePoolBackendRedis {
+	if c.PoolConfig.IsRedisBackend() {
 		if c.PoolConfig.RedisPoolCfg == nil {
 			return fmt.Errorf("redis worker must be configured when backend is set to '%s'", c.PoolConfig.Backend)
 		}
-		if utils.IsEmptyStr(c.PoolConfig.RedisPoolCfg.RedisURL) {
-			return errors.New("URL of redis worker is empty")
+		if err := c.PoolConfig.RedisPoolCfg.validate(); err != nil {
+			return err
 		}
 
-		if !strings.HasPrefix(c.PoolConfig.RedisPoolCfg.RedisURL, redisSchema) {
-			return errors.New("invalid redis URL")
+		if utils.IsEmptyStr(c.PoolConfig.RedisPoolCfg.Namespace) {
+			return errors.New("namespace of redis worker is required")
 		}
+	}
 
-		if _, err := url.Parse(c.PoolConfig.RedisPoolCfg.RedisURL); err != nil {
-			return fmt.Errorf("invalid redis URL: %s", err.Error())
+	if c.RateLimitConfig != nil {
+		// Copy the rules to not append the default one to the backing array of the configured rules
+		rules := make([]*RateLimitRule, 0, len(c.RateLimitConfig.Rules)+1)
+		rules = append(rules, c.RateLimitConfig.Rules...)
+		if c.RateLimitConfig.Default != nil {
+			rules = append(rules, c.RateLimitConfig.Default)
+		}
+		for _, r := range rules {
+			if r == nil || r.Rate <= 0 || r.Burst == 0 {
+				return errors.New("rate and burst of rate limit rule should be positive")
+			}
+			if _, err := path.Match(r.Caller, ""); err != nil {
+				return fmt.Errorf("invalid caller pattern of rate limit rule: %s", r.Caller)
+			}
+			if _, err := path.Match(r.Job, ""); err != nil {
+				return fmt.Errorf("invalid job pattern of rate limit rule: %s", r.Job)
+			}
 		}
+	}
 
-		if utils.IsEmptyStr(c.PoolConfig.RedisPoolCfg.Namespace) {
-			return errors.New("namespace of redis worker is required")
+	if c.AdmissionConfig != nil {
+		// The admission is based on the redis queues and the node heartbeats
+		if c.PoolConfig.Backend == JobServicePoolBackendMemory {
+			return errors.New("admission control is not supported by the memory backend")
+		}
+		if c.AdmissionConfig.MaxQueueDepth < 0 {
+			return errors.New("max queue depth of admission should not be negative")
+		}
+		for name, depth := range c.AdmissionConfig.QueueDepths {
+			if depth < 0 {
+				return fmt.Errorf("max queue depth of job %s should not be negative", name)
+			}
+		}
+		if c.AdmissionConfig.MaxMemoryUsage < 0 || c.AdmissionConfig.MaxMemoryUsage > 1 {
+			return errors.New("max memory usage of admission should be in [0, 1]")
+		}
+	}
+
+	plugins := make(map[string]bool, len(c.PluginConfigs))
+	for _, p := range c.PluginConfigs {
+		if p == nil || utils.IsEmptyStr(p.Name) {
+			return errors.New("name of plugin job is required")
+		}
+		if plugins[p.Name] {
+			return fmt.Errorf("plugin job %s is duplicated", p.Name)
+		}
+		plugins[p.Name] = true
+		if len(p.SecretParameters) > 0 && utils.IsEmptyStr(GetParametersKey()) {
+			return fmt.Errorf("plugin job %s declares secret parameters but %s is not set", p.Name, jobServiceParametersKey)
+		}
+		if utils.IsEmptyStr(p.Path) || !utils.FileExists(p.Path) {
+			return fmt.Errorf("executable of plugin job %s is not found: %s", p.Name, p.Path)
+		}
+		if !utils.IsEmptyStr(p.ParametersSchema) {
+			if _, err := schema.Parse([]byte(p.ParametersSchema)); err != nil {
+				return fmt.Errorf("parameters schema of plugin job %s is invalid: %s", p.Name, err)
+			}
+		}
+	}
+
+	if c.RetentionConfig != nil {
+		for _, r := range c.RetentionConfig.Rules {
+			if r == nil || r.KeepSeconds == 0 {
+				return errors.New("keep seconds of retention rule should be positive")
+			}
+			if _, err := path.Match(r.Job, ""); err != nil {
+				return fmt.Errorf("invalid job pattern of retention rule: %s", r.Job)
+			}
+			switch r.Kind {
+			case "", RetentionKindGeneric, RetentionKindScheduled, RetentionKindPeriodic, RetentionKindExecution:
+			default:
+				return fmt.Errorf("invalid job kind of retention rule: %s", r.Kind)
+			}
+			switch r.Status {
+			case "", "Success", "Error", "Stopped":
+			default:
+				return fmt.Errorf("invalid status of retention rule: %s, only final status is supported", r.Status)
+			}
+		}
+
+		if a := c.RetentionConfig.Archive; a != nil {
+			switch a.Sink {
+			case ArchiveSinkFile:
+				if utils.IsEmptyStr(a.Path) {
+					return errors.New("path of the archive file is required")
+				}
+			case ArchiveSinkDatabase:
+				if utils.IsEmptyStr(a.DSN) {
+					return errors.New("dsn of the archive database is required")
+				}
+			default:
+				return fmt.Errorf("archive sink %s is not supported, only support '%s', '%s'", a.Sink, ArchiveSinkFile, ArchiveSinkDatabase)
+			}
+			if c.PoolConfig != nil && !c.PoolConfig.IsRedisBackend() {
+				return errors.New("archive of job stats is only supported by the redis backends")
+			}
+		}
+	}
+
+	if c.AuthConfig != nil {
+		names := make(map[string]bool, len(c.AuthConfig.Credentials))
+		for _, cc := range c.AuthConfig.Credentials {
+			if cc == nil || utils.IsEmptyStr(cc.Name) {
+				return errors.New("name of API credential is required")
+			}
+			if names[cc.Name] {
+				return fmt.Errorf("API credential %s is duplicated", cc.Name)
+			}
+			names[cc.Name] = true
+			if utils.IsEmptyStr(cc.GetToken()) {
+				return fmt.Errorf("token of API credential %s is empty", cc.Name)
+			}
+			if len(cc.Scopes) == 0 {
+				return fmt.Errorf("no scopes are granted to API credential %s", cc.Name)
+			}
+			if _, err := auth.ParseGrants(cc.Scopes); err != nil {
+				return fmt.Errorf("scopes of API credential %s are invalid: %s", cc.Name, err)
+			}
+		}
+
+		if j := c.AuthConfig.JWT; j != nil {
+			if utils.IsEmptyStr(j.JWKSFile) || !utils.FileExists(j.JWKSFile) {
+				return fmt.Errorf("JWKS file of JWT is not found: %s", j.JWKSFile)
+			}
+		}
+	}
+
+	if a := c.AuditConfig; a != nil {
+		switch a.Sink {
+		case AuditSinkFile:
+			if utils.IsEmptyStr(a.Path) {
+				return errors.New("path of the audit file is required")
+			}
+		case AuditSinkDatabase:
+			if utils.IsEmptyStr(a.DSN) {
+				return errors.New("dsn of the audit database is required")
+			}
+		default:
+			return fmt.Errorf("audit sink %s is not supported, only support '%s', '%s'", a.Sink, AuditSinkFile, AuditSinkDatabase)
 		}
 	}
 
@@ -1 +1,86 @@
 package config
+
+import (
+	"testing"
+)
+
+// newTestConfig returns the valid configuration of the memory backend with the rate limits
+func newTestConfig(rateLimits *RateLimitConfig) *Configuration {
+	return &Configuration{
+		Protocol:         JobServiceProtocolHTTP,
+		Port:             9000,
+		PoolConfig:       &PoolConfig{WorkerCount: 1, Backend: JobServicePoolBackendMemory},
+		LoggerConfigs:    []*LoggerConfig{{Name: "STD_OUTPUT", Level: "INFO"}},
+		JobLoggerConfigs: []*LoggerConfig{{Name: "STD_OUTPUT", Level: "INFO"}},
+		RateLimitConfig:  rateLimits,
+	}
+}
+
+func TestValidateRateLimit(t *testing.T) {
+	cases := []struct {
+		name  string
+		rules []*RateLimitRule
+		def   *RateLimitRule
+		valid bool
+	}{
+		{name: "valid rules", rules: []*RateLimitRule{{Caller: "ci-*", Job: "SCAN", Rate: 1, Burst: 2}}, def: &RateLimitRule{Rate: 10, Burst: 20}, valid: true},
+		{name: "only default", def: &RateLimitRule{Rate: 10, Burst: 20}, valid: true},
+		{name: "zero rate", rules: []*RateLimitRule{{Rate: 0, Burst: 2}}},
+		{name: "zero burst of default", def: &RateLimitRule{Rate: 1}},
+		{name: "nil rule", rules: []*RateLimitRule{nil}},
+		{name: "invalid caller pattern", rules: []*RateLimitRule{{Caller: "[", Rate: 1, Burst: 1}}},
+		{name: "invalid job pattern", rules: []*RateLimitRule{{Job: "[", Rate: 1, Burst: 1}}},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			cfg := newTestConfig(&RateLimitConfig{Rules: c.rules, Default: c.def})
+			if err := cfg.validate(); (err == nil) != c.valid {
+				t.Errorf("expect valid %v but got error %v", c.valid, err)
+			}
+		})
+	}
+}
+
+func TestValidateRateLimitKeepsRules(t *testing.T) {
+	// The backing array has the room to append the default rule
+	backing := make([]*RateLimitRule, 2)
+	spare := &RateLimitRule{Caller: "spare", Rate: 1, Burst: 1}
+	backing[1] = spare
+
+	cfg := newTestConfig(&RateLimitConfig{
+		Rules:   backing[:1],
+		Default: &RateLimitRule{Rate: 10, Burst: 20},
+	})
+	cfg.RateLimitConfig.Rules[0] = &RateLimitRule{Caller: "ci-*", Rate: 1, Burst: 2}
+
+	if err := cfg.validate(); err != nil {
+		t.Fatalf("validate error: %s", err)
+	}
+	if backing[1] != spare || len(cfg.RateLimitConfig.Rules) != 1 {
+		t.Error("expect the configured rules and their backing array not changed by the validation")
+	}
+}
+
+func TestValidateGRPCPort(t *testing.T) {
+	cases := []struct {
+		name  string
+		port  uint
+		valid bool
+	}{
+		{name: "disabled", port: 0, valid: true},
+		{name: "valid port", port: 9001, valid: true},
+		{name: "same as HTTP port", port: 9000},
+		{name: "out of range", port: 65536},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			cfg := newTestConfig(nil)
+			cfg.GRPCPort = c.port
+			if err := cfg.validate(); (err == nil) != c.valid {
+				t.Errorf("expect valid %v but got error %v", c.valid, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,99 @@
+package config
+
+import (
+	"reflect"
+	"sync/atomic"
+)
+
+// Sections of the configuration compared when reloading, named after the yaml keys
+const (
+	SectionProtocol    = "protocol"
+	SectionPort        = "port"
+	SectionHTTPS       = "https_config"
+	SectionGRPCPort    = "grpc_port"
+	SectionWorkerCount = "worker_pool.workers"
+	SectionWorkerPool  = "worker_pool"
+	SectionJobLoggers  = "job_loggers"
+	SectionLoggers     = "loggers"
+	SectionRateLimit   = "rate_limit"
+	SectionAdmission   = "admission"
+	SectionIdempotency = "idempotency"
+	SectionPlugins     = "plugins"
+	SectionRetention   = "retention"
+	SectionAuth        = "auth"
+	SectionAudit       = "audit"
+)
+
+// current keeps the configuration in effect once the reloaded one is applied
+var current atomic.Value
+
+// Current returns the configuration in effect, it's the DefaultConfig until a reloaded one is applied.
+// The returned configuration is not changed, the applied one replaces it as a whole.
+func Current() *Configuration {
+	if c, ok := current.Load().(*Configuration); ok {
+		return c
+	}
+
+	return DefaultConfig
+}
+
+// SetCurrent replaces the configuration in effect atomically
+func SetCurrent(c *Configuration) {
+	current.Store(c)
+}
+
+// FilePath returns the path of the yaml file the configuration is loaded from
+func (c *Configuration) FilePath() string {
+	return c.filePath
+}
+
+// Reload loads a new configuration from the same yaml file and envs.
+// The new configuration is validated and the current one is not changed.
+func (c *Configuration) Reload() (*Configuration, error) {
+	nc := &Configuration{}
+	if err := nc.Load(c.filePath, c.detectEnv); err != nil {
+		return nil, err
+	}
+
+	return nc, nil
+}
+
+// Changes returns the sections changed in the new configuration
+func (c *Configuration) Changes(nc *Configuration) []string {
+	changes := make([]string, 0)
+	changed := func(section string, o interface{}, n interface{}) {
+		if !reflect.DeepEqual(o, n) {
+			changes = append(changes, section)
+		}
+	}
+
+	changed(SectionProtocol, c.Protocol, nc.Protocol)
+	changed(SectionPort, c.Port, nc.Port)
+	changed(SectionHTTPS, c.HTTPSConfig, nc.HTTPSConfig)
+	changed(SectionGRPCPort, c.GRPCPort, nc.GRPCPort)
+
+	var oldCount, newCount uint
+	var oldPool, newPool PoolConfig
+	if c.PoolConfig != nil {
+		oldPool = *c.PoolConfig
+	}
+	if nc.PoolConfig != nil {
+		newPool = *nc.PoolConfig
+	}
+	oldCount, oldPool.WorkerCount = oldPool.WorkerCount, 0
+	newCount, newPool.WorkerCount = newPool.WorkerCount, 0
+	changed(SectionWorkerCount, oldCount, newCount)
+	changed(SectionWorkerPool, oldPool, newPool)
+
+	changed(SectionJobLoggers, c.JobLoggerConfigs, nc.JobLoggerConfigs)
+	changed(SectionLoggers, c.LoggerConfigs, nc.LoggerConfigs)
+	changed(SectionRateLimit, c.RateLimitConfig, nc.RateLimitConfig)
+	changed(SectionAdmission, c.AdmissionConfig, nc.AdmissionConfig)
+	changed(SectionIdempotency, c.IdempotencyConfig, nc.IdempotencyConfig)
+	changed(SectionPlugins, c.PluginConfigs, nc.PluginConfigs)
+	changed(SectionRetention, c.RetentionConfig, nc.RetentionConfig)
+	changed(SectionAuth, c.AuthConfig, nc.AuthConfig)
+	changed(SectionAudit, c.AuditConfig, nc.AuditConfig)
+
+	return changes
+}
@@ -0,0 +1,47 @@
+package config
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestChanges(t *testing.T) {
+	cases := []struct {
+		name     string
+		change   func(c *Configuration)
+		sections []string
+	}{
+		{name: "nothing", change: func(c *Configuration) {}, sections: []string{}},
+		{name: "protocol", change: func(c *Configuration) { c.Protocol = JobServiceProtocolHTTPS }, sections: []string{SectionProtocol}},
+		{name: "port", change: func(c *Configuration) { c.Port = 9001 }, sections: []string{SectionPort}},
+		{name: "https", change: func(c *Configuration) { c.HTTPSConfig = &HTTPSConfig{Cert: "cert", Key: "key"} }, sections: []string{SectionHTTPS}},
+		{name: "grpc port", change: func(c *Configuration) { c.GRPCPort = 9090 }, sections: []string{SectionGRPCPort}},
+		{name: "worker count", change: func(c *Configuration) {
+			c.PoolConfig = &PoolConfig{WorkerCount: 2, Backend: JobServicePoolBackendMemory}
+		}, sections: []string{SectionWorkerCount}},
+		{name: "worker pool", change: func(c *Configuration) {
+			c.PoolConfig = &PoolConfig{WorkerCount: 1, Backend: JobServicePoolBackendRedis}
+		}, sections: []string{SectionWorkerPool}},
+		{name: "rate limit", change: func(c *Configuration) {
+			c.RateLimitConfig = &RateLimitConfig{Default: &RateLimitRule{Rate: 1, Burst: 1}}
+		}, sections: []string{SectionRateLimit}},
+		{
+			name: "servers",
+			change: func(c *Configuration) {
+				c.Port = 9001
+				c.GRPCPort = 9090
+			},
+			sections: []string{SectionPort, SectionGRPCPort},
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			cfg, nc := newTestConfig(nil), newTestConfig(nil)
+			c.change(nc)
+			if sections := cfg.Changes(nc); !reflect.DeepEqual(sections, c.sections) {
+				t.Errorf("expect the changes %v but got %v", c.sections, sections)
+			}
+		})
+	}
+}
@@ -1,15 +1,28 @@
 package core
 
 import (
+	"crypto/sha256"
+	"encoding/json"
 	"fmt"
+	"github.com/chenxull/goGridhub/gridhub/src/jobservice/admission"
+	"github.com/chenxull/goGridhub/gridhub/src/jobservice/common/encrypt"
 	"github.com/chenxull/goGridhub/gridhub/src/jobservice/common/query"
+	"github.com/chenxull/goGridhub/gridhub/src/jobservice/common/schema"
 	"github.com/chenxull/goGridhub/gridhub/src/jobservice/common/utils"
 	"github.com/chenxull/goGridhub/gridhub/src/jobservice/errs"
+	"github.com/chenxull/goGridhub/gridhub/src/jobservice/hook"
+	"github.com/chenxull/goGridhub/gridhub/src/jobservice/idempotency"
 	"github.com/chenxull/goGridhub/gridhub/src/jobservice/job"
+	"github.com/chenxull/goGridhub/gridhub/src/jobservice/logger"
 	"github.com/chenxull/goGridhub/gridhub/src/jobservice/mgt"
+	"github.com/chenxull/goGridhub/gridhub/src/jobservice/node"
+	"github.com/chenxull/goGridhub/gridhub/src/jobservice/period"
+	"github.com/chenxull/goGridhub/gridhub/src/jobservice/retention"
 	"github.com/chenxull/goGridhub/gridhub/src/jobservice/worker"
 	"github.com/pkg/errors"
-	"github.com/robfig/cron"
+	"sort"
+	"sync"
+	"time"
 )
 
 // basicController implement the core interface and provides related job handle methods.
@@ -19,32 +32,156 @@ type basicController struct {
 	backendWorker worker.Interface
 	//Refer the job stats manager
 	manager mgt.Manager
+	//Refer the node registry
+	nodes node.Registry
+	//Refer the admission controller, nil means admitting all the jobs
+	admission admission.Controller
+	//Refer the idempotency key store
+	idempotency idempotency.Store
+	//Cache the compiled parameters schemas of the jobs
+	schemas *sync.Map
+	//Refer the sink of the archived jobs, nil means the archival is disabled
+	archive retention.Sink
+	//Refer the log of the status change events
+	events hook.EventLog
+	//Refer the porter of the periodic job policies, nil means it's not supported by the backend
+	porter period.PolicyPorter
 }
 
 //NewController is constructor of basic
-func NewController(backendWorker worker.Interface, mgr mgt.Manager) Interface {
+func NewController(
+	backendWorker worker.Interface,
+	mgr mgt.Manager,
+	nodes node.Registry,
+	admitter admission.Controller,
+	idemStore idempotency.Store,
+	archive retention.Sink,
+	events hook.EventLog,
+	porter period.PolicyPorter,
+) Interface {
 	return &basicController{
 		backendWorker: backendWorker,
 		manager:       mgr,
+		nodes:         nodes,
+		admission:     admitter,
+		idempotency:   idemStore,
+		schemas:       new(sync.Map),
+		archive:       archive,
+		events:        events,
+		porter:        porter,
 	}
 }
 
 //LaunchJob is implementation of same method in core interface
-func (bc *basicController) LaunchJob(req *job.Request) (res *job.Stats, err error) {
+func (bc *basicController) LaunchJob(req *job.Request) (*job.Stats, error) {
 	if err := validJobReq(req); err != nil {
 		return nil, errs.BadRequestError(err)
 	}
 
+	if utils.IsEmptyStr(req.IdempotencyKey) || bc.idempotency == nil {
+		res, err := bc.launchJob(req)
+		if err != nil {
+			return nil, err
+		}
+		return res.Redacted(), nil
+	}
+
+	// Take the digest before the request is changed by the launching
+	digest, err := requestDigest(req)
+	if err != nil {
+		return nil, err
+	}
+
+	record, err := bc.idempotency.Reserve(req.Caller, req.IdempotencyKey, digest)
+	if err != nil {
+		return nil, err
+	}
+
+	if record != nil {
+		// Repeated submission
+		if record.Digest != digest {
+			return nil, errs.ConflictError(fmt.Sprintf("request with idempotency key %s", req.IdempotencyKey))
+		}
+
+		if utils.IsEmptyStr(record.JobID) {
+			return nil, errs.ConflictError(fmt.Sprintf("launching job with idempotency key %s", req.IdempotencyKey))
+		}
+
+		existing, err := bc.manager.GetJob(record.JobID)
+		if err != nil {
+			return nil, err
+		}
+		return existing.Redacted(), nil
+	}
+
+	res, err := bc.launchJob(req)
+	if err != nil {
+		// Let the client retry with the same key
+		if er := bc.idempotency.Release(req.Caller, req.IdempotencyKey); er != nil {
+			logger.Errorf("release idempotency key %s error: %s", req.IdempotencyKey, er)
+		}
+
+		return nil, err
+	}
+
+	if err := bc.idempotency.Commit(req.Caller, req.IdempotencyKey, digest, res.Info.JobID); err != nil {
+		// The job has been launched, only log the error
+		logger.Errorf("commit idempotency key %s of job %s error: %s", req.IdempotencyKey, res.Info.JobID, err)
+	}
+
+	return res.Redacted(), nil
+}
+
+func (bc *basicController) launchJob(req *job.Request) (res *job.Stats, err error) {
 	//Validate job name
 	jobType, isKnowJob := bc.backendWorker.IsKnownJob(req.Job.Name)
 	if !isKnowJob {
 		return nil, errs.BadRequestError(errors.Errorf("job with name '%s' is unknown", req.Job.Name))
 	}
 
+	// Validate parameters against the schema declared by the job
+	paramsSchema, err := bc.parametersSchema(req.Job.Name, jobType)
+	if err != nil {
+		return nil, err
+	}
+	if paramsSchema != nil {
+		if err := paramsSchema.Validate(map[string]interface{}(req.Job.Parameters)); err != nil {
+			return nil, errs.BadRequestError(err)
+		}
+	}
+
 	// Validate parameters
 	if err := bc.backendWorker.ValidateJobParameters(jobType, req.Job.Parameters); err != nil {
 		return nil, errs.BadRequestError(err)
 	}
+
+	// Encrypt the secret parameters before they're kept in the backend.
+	// The unique sign is computed with the sealed parameters of the unique job.
+	if keys := secretKeys(jobType, req.Job.Metadata); len(keys) > 0 {
+		sealed, err := job.SealSecrets(req.Job.Parameters, keys, req.Job.Metadata.IsUnique)
+		if err != nil {
+			if errors.Cause(err) == encrypt.ErrNoKey {
+				return nil, errs.BadRequestError(errors.Wrap(err, "secret parameters are not supported"))
+			}
+			return nil, err
+		}
+		req.Job.Parameters = sealed
+	}
+
+	// Check the system pressure before enqueuing the generic job
+	if req.Job.Metadata.JobKind == job.KindGeneric && bc.admission != nil {
+		deferred, err := bc.admission.Admit(req.Job.Name, req.Job.Metadata.Priority)
+		if err != nil {
+			return nil, err
+		}
+
+		if deferred > 0 {
+			// Run it later as a scheduled job
+			req.Job.Metadata.JobKind = job.KindScheduled
+			req.Job.Metadata.ScheduleDelay = deferred
+		}
+	}
+
 	//Enqueue job regarding of the kind
 	switch req.Job.Metadata.JobKind {
 	case job.KindScheduled:
@@ -52,7 +189,7 @@ func (bc *basicController) LaunchJob(req *job.Request) (res *job.Stats, err erro
 			req.Job.Name,
 			req.Job.Parameters,
 			req.Job.Metadata.ScheduleDelay,
-			req.Job.Metadata.IsUnique,
+			uniqueOptions(req.Job.Metadata),
 			req.Job.StatusHook,
 		)
 	case job.KindPeriodic:
@@ -60,6 +197,7 @@ func (bc *basicController) LaunchJob(req *job.Request) (res *job.Stats, err erro
 			req.Job.Name,
 			req.Job.Parameters,
 			req.Job.Metadata.Cron,
+			scheduleSpec(req.Job.Metadata, time.Now().Unix()),
 			req.Job.Metadata.IsUnique,
 			req.Job.StatusHook,
 		)
@@ -67,7 +205,7 @@ func (bc *basicController) LaunchJob(req *job.Request) (res *job.Stats, err erro
 		res, err = bc.backendWorker.Enqueue(
 			req.Job.Name,
 			req.Job.Parameters,
-			req.Job.Metadata.IsUnique,
+			uniqueOptions(req.Job.Metadata),
 			req.Job.StatusHook,
 		)
 	}
@@ -85,7 +223,13 @@ func (bc *basicController) GetJob(jobID string) (*job.Stats, error) {
 	if utils.IsEmptyStr(jobID) {
 		return nil, errs.BadRequestError(errors.New("empty job ID"))
 	}
-	return bc.manager.GetJob(jobID)
+
+	res, err := bc.manager.GetJob(jobID)
+	if err != nil {
+		return nil, err
+	}
+
+	return res.Redacted(), nil
 }
 
 func (bc *basicController) StopJob(jobID string) error {
@@ -129,7 +273,7 @@ func (bc *basicController) GetPeriodicExecutions(periodicJobID string, query *qu
 		return nil, 0, errs.BadRequestError(errors.New("nil periodic job ID"))
 	}
 
-	return bc.manager.GetPeriodicExecution(periodicJobID, query)
+	return redact(bc.manager.GetPeriodicExecution(periodicJobID, query))
 }
 
 func (bc *basicController) GetJobs(q *query.Parameter) ([]*job.Stats, int64, error) {
@@ -143,10 +287,242 @@ func (bc *basicController) GetJobs(q *query.Parameter) ([]*job.Stats, int64, err
 	}
 
 	if onlyScheduledJobs {
-		return bc.manager.GetScheduledJobs(q)
+		return redact(bc.manager.GetScheduledJobs(q))
+	}
+
+	return redact(bc.manager.GetJobs(q))
+}
+
+// GetScheduledJobs is implementation of same method in core interface.
+func (bc *basicController) GetScheduledJobs(q *query.Parameter) ([]*job.Stats, int64, error) {
+	return redact(bc.manager.GetScheduledJobs(q))
+}
+
+// ScheduledJobAction is implementation of same method in core interface.
+func (bc *basicController) ScheduledJobAction(jobID string, req *job.ScheduleActionRequest) (*job.Stats, error) {
+	if utils.IsEmptyStr(jobID) {
+		return nil, errs.BadRequestError(errors.New("empty job ID"))
+	}
+	if req == nil {
+		return nil, errs.BadRequestError(errors.New("empty action request"))
+	}
+
+	st, err := bc.manager.GetJob(jobID)
+	if err != nil {
+		return nil, err
+	}
+	if st.Info.JobKind != job.KindScheduled {
+		return nil, errs.BadRequestError(errors.Errorf("job %s is not a scheduled job", jobID))
+	}
+
+	now := time.Now().Unix()
+	switch req.Action {
+	case job.ScheduleActionReschedule:
+		if req.RunAt <= now {
+			return nil, errs.BadRequestError(errors.Errorf("run_at %d is not a future time", req.RunAt))
+		}
+		err = bc.backendWorker.RescheduleJob(jobID, req.RunAt)
+	case job.ScheduleActionRun:
+		// The due job is picked up by the worker in its next polling round
+		err = bc.backendWorker.RescheduleJob(jobID, now)
+	case job.ScheduleActionCancel:
+		if job.Status(st.Info.Status) != job.ScheduledStatus {
+			return nil, errs.StatusMismatchError(st.Info.Status, job.ScheduledStatus.String())
+		}
+		err = bc.backendWorker.StopJob(jobID)
+	default:
+		return nil, errs.BadRequestError(errors.Errorf("unknown action %s of the scheduled job", req.Action))
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return bc.GetJob(jobID)
+}
+
+// GetArchivedJobs is implementation of same method in core interface.
+func (bc *basicController) GetArchivedJobs(q *query.Parameter) ([]*retention.Record, int64, error) {
+	if bc.archive == nil {
+		return nil, 0, errs.BadRequestError(errors.New("archival of job stats is not enabled"))
+	}
+
+	records, total, err := bc.archive.List(q)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	for _, r := range records {
+		r.Job = r.Job.Redacted()
+	}
+
+	return records, total, nil
+}
+
+// GetArchivedJob is implementation of same method in core interface.
+func (bc *basicController) GetArchivedJob(jobID string) (*retention.Record, error) {
+	if utils.IsEmptyStr(jobID) {
+		return nil, errs.BadRequestError(errors.New("empty job ID"))
+	}
+
+	if bc.archive == nil {
+		return nil, errs.BadRequestError(errors.New("archival of job stats is not enabled"))
+	}
+
+	r, err := bc.archive.Get(jobID)
+	if err != nil {
+		return nil, err
 	}
+	r.Job = r.Job.Redacted()
 
-	return bc.manager.GetJobs(q)
+	return r, nil
+}
+
+// ExportPeriodicPolicies is implementation of same method in core interface.
+func (bc *basicController) ExportPeriodicPolicies() (*period.PolicyExport, error) {
+	if bc.porter == nil {
+		return nil, errs.BadRequestError(errors.New("export of periodic job policies is not supported"))
+	}
+
+	return bc.porter.Export()
+}
+
+// ImportPeriodicPolicies is implementation of same method in core interface.
+func (bc *basicController) ImportPeriodicPolicies(data *period.PolicyExport, dryRun bool) (*period.PolicyImportReport, error) {
+	if bc.porter == nil {
+		return nil, errs.BadRequestError(errors.New("import of periodic job policies is not supported"))
+	}
+
+	return bc.porter.Import(data, dryRun)
+}
+
+// GetStatusEvents is implementation of same method in core interface.
+func (bc *basicController) GetStatusEvents(lastEventID string, wait time.Duration) ([]*hook.StatusEvent, string, error) {
+	if bc.events == nil {
+		return nil, "", errs.BadRequestError(errors.New("status change events are not supported"))
+	}
+
+	// Start from the new events
+	if utils.IsEmptyStr(lastEventID) {
+		latest, err := bc.events.Latest()
+		if err != nil {
+			return nil, "", err
+		}
+		lastEventID = latest
+	}
+
+	evts, err := bc.events.Read(lastEventID, wait)
+	if err != nil {
+		return nil, "", err
+	}
+	if len(evts) > 0 {
+		lastEventID = evts[len(evts)-1].ID
+	}
+
+	return evts, lastEventID, nil
+}
+
+// GetJobTypes is implementation of same method in core interface.
+func (bc *basicController) GetJobTypes() ([]*job.TypeInfo, error) {
+	known := bc.backendWorker.KnownJobs()
+
+	names := make([]string, 0, len(known))
+	for name := range known {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	types := make([]*job.TypeInfo, 0, len(names))
+	for _, name := range names {
+		info := &job.TypeInfo{
+			Name: name,
+		}
+		if j, ok := known[name].(job.Interface); ok {
+			info.MaxFails = j.MaxFalis()
+			info.ShouldRetry = j.ShouldRetry()
+		}
+
+		paramsSchema, err := bc.parametersSchema(name, known[name])
+		if err != nil {
+			// Still list the job type without the invalid schema
+			logger.Errorf("get parameters schema of job %s error: %s", name, err)
+		}
+		if paramsSchema != nil {
+			info.ParametersSchema = json.RawMessage(known[name].(job.SchemaProvider).ParametersSchema())
+		}
+
+		types = append(types, info)
+	}
+
+	return types, nil
+}
+
+// parametersSchema returns the compiled schema declared by the job, nil if no schema is declared
+func (bc *basicController) parametersSchema(name string, jobType interface{}) (*schema.Schema, error) {
+	provider, ok := jobType.(job.SchemaProvider)
+	if !ok {
+		return nil, nil
+	}
+
+	if s, ok := bc.schemas.Load(name); ok {
+		return s.(*schema.Schema), nil
+	}
+
+	raw := provider.ParametersSchema()
+	if utils.IsEmptyStr(raw) {
+		return nil, nil
+	}
+
+	s, err := schema.Parse([]byte(raw))
+	if err != nil {
+		return nil, errors.Wrapf(err, "invalid parameters schema of job %s", name)
+	}
+	bc.schemas.Store(name, s)
+
+	return s, nil
+}
+
+// GetNodes is implementation of same method in core interface.
+func (bc *basicController) GetNodes() ([]*node.Info, error) {
+	return bc.nodes.Nodes()
+}
+
+// EvictNode is implementation of same method in core interface.
+func (bc *basicController) EvictNode(nodeID string, action string) (*node.ActionResult, error) {
+	if utils.IsEmptyStr(nodeID) {
+		return nil, errs.BadRequestError(errors.New("empty node ID"))
+	}
+
+	if action != node.ActionFail && action != node.ActionRequeue {
+		return nil, errs.BadRequestError(errors.Errorf("node action '%s' is not supported, only support '%s','%s'", action, node.ActionFail, node.ActionRequeue))
+	}
+
+	return bc.nodes.Evict(nodeID, action, bc.backendWorker.RequeueJob)
+}
+
+// secretKeys returns the keys of the secret parameters declared by the job and marked in the request
+func secretKeys(jobType interface{}, meta *job.Metadata) []string {
+	keys := make([]string, 0)
+	if provider, ok := jobType.(job.SecretProvider); ok {
+		keys = append(keys, provider.SecretParameters()...)
+	}
+	if meta != nil {
+		keys = append(keys, meta.SecretKeys...)
+	}
+
+	return keys
+}
+
+// redact the secret parameters of the listed jobs
+func redact(list []*job.Stats, total int64, err error) ([]*job.Stats, int64, error) {
+	if err != nil {
+		return nil, 0, err
+	}
+
+	for i, st := range list {
+		list[i] = st.Redacted()
+	}
+
+	return list, total, nil
 }
 
 func validJobReq(req *job.Request) error {
@@ -171,20 +547,77 @@ func validJobReq(req *job.Request) error {
 			job.KindScheduled,
 			job.KindPeriodic)
 	}
+	if !utils.IsEmptyStr(req.Job.Metadata.Priority) &&
+		req.Job.Metadata.Priority != job.PriorityHigh &&
+		req.Job.Metadata.Priority != job.PriorityNormal &&
+		req.Job.Metadata.Priority != job.PriorityLow {
+		return errors.Errorf(
+			"job priority '%s' is not supported, only support '%s','%s','%s'",
+			req.Job.Metadata.Priority,
+			job.PriorityHigh,
+			job.PriorityNormal,
+			job.PriorityLow)
+	}
+
+	for _, k := range req.Job.Metadata.UniqueKeys {
+		if utils.IsEmptyStr(k) {
+			return errors.New("empty key is not allowed in 'unique_keys'")
+		}
+	}
+
 	if req.Job.Metadata.JobKind == job.KindScheduled &&
 		req.Job.Metadata.ScheduleDelay == 0 {
 		return errors.Errorf("'schedule_delay' must be specified for %s job", job.KindScheduled)
 	}
 
 	if req.Job.Metadata.JobKind == job.KindPeriodic {
-		if utils.IsEmptyStr(req.Job.Metadata.Cron) {
-			return fmt.Errorf("'cron_spec' must be specified for the %s job", job.KindPeriodic)
+		if utils.IsEmptyStr(req.Job.Metadata.Cron) && req.Job.Metadata.Schedule == nil {
+			return fmt.Errorf("'cron_spec' or 'schedule' must be specified for the %s job", job.KindPeriodic)
 		}
 
-		if _, err := cron.Parse(req.Job.Metadata.Cron); err != nil {
-			return fmt.Errorf("'cron_spec' is not correctly set: %s: %s", req.Job.Metadata.Cron, err)
+		if _, err := period.NewSchedule(req.Job.Metadata.Cron, scheduleSpec(req.Job.Metadata, time.Now().Unix())); err != nil {
+			return fmt.Errorf("'cron_spec' or 'schedule' is not correctly set: %s", err)
 		}
+	} else if req.Job.Metadata.Schedule != nil {
+		return fmt.Errorf("'schedule' is only supported by the %s job", job.KindPeriodic)
 	}
 
 	return nil
 }
+
+// scheduleSpec returns the schedule of the periodic job, the interval without start time starts from now
+func scheduleSpec(meta *job.Metadata, now int64) *job.ScheduleSpec {
+	spec := meta.Schedule
+	if spec == nil || spec.Interval == nil || spec.Interval.StartAt > 0 {
+		return spec
+	}
+
+	interval := *spec.Interval
+	interval.StartAt = now
+	copied := *spec
+	copied.Interval = &interval
+
+	return &copied
+}
+
+// requestDigest returns the digest of the job request body
+func requestDigest(req *job.Request) (string, error) {
+	rawJSON, err := json.Marshal(req.Job)
+	if err != nil {
+		return "", err
+	}
+
+	return fmt.Sprintf("%x", sha256.Sum256(rawJSON)), nil
+}
+
+// uniqueOptions returns the unique options of the job, nil if the job is not unique
+func uniqueOptions(meta *job.Metadata) *job.UniqueOptions {
+	if !meta.IsUnique {
+		return nil
+	}
+
+	return &job.UniqueOptions{
+		Keys: meta.UniqueKeys,
+		TTL:  meta.UniqueTTL,
+	}
+}
@@ -0,0 +1,97 @@
+package core
+
+import (
+	"context"
+	"github.com/chenxull/goGridhub/gridhub/src/jobservice/env"
+	"github.com/chenxull/goGridhub/gridhub/src/jobservice/errs"
+	"github.com/chenxull/goGridhub/gridhub/src/jobservice/inmem"
+	"github.com/chenxull/goGridhub/gridhub/src/jobservice/job"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeJob does nothing, the jobs are only launched but not run in the tests
+type fakeJob struct{}
+
+func (fj *fakeJob) MaxFalis() uint { return 0 }
+
+func (fj *fakeJob) ShouldRetry() bool { return false }
+
+func (fj *fakeJob) Validate(params job.Parameters) error { return nil }
+
+func (fj *fakeJob) Run(ctx job.Context, params job.Parameters) error { return nil }
+
+// newTestController returns the controller based on the in-memory backend with the fake jobs registered
+func newTestController(t *testing.T, jobNames ...string) (Interface, *inmem.Backend) {
+	t.Helper()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+
+	b := inmem.NewBackend(&env.Context{
+		SystemContext: ctx,
+		WG:            new(sync.WaitGroup),
+		ErrorChan:     make(chan error, 1),
+	}, 1, nil, time.Hour)
+
+	jobs := make(map[string]interface{}, len(jobNames))
+	for _, name := range jobNames {
+		jobs[name] = (*fakeJob)(nil)
+	}
+	if err := b.Worker.RegisterJobs(jobs); err != nil {
+		t.Fatalf("register jobs error: %s", err)
+	}
+
+	return NewController(b.Worker, b.Manager, b.Registry, nil, b.Idempotency, nil, b.EventLog, nil), b
+}
+
+func launchRequest(name string, params job.Parameters, key string) *job.Request {
+	return &job.Request{
+		Job: &job.RequestBody{
+			Name:       name,
+			Parameters: params,
+			Metadata:   &job.Metadata{JobKind: job.KindGeneric},
+		},
+		Caller:         "alice",
+		IdempotencyKey: key,
+	}
+}
+
+func TestLaunchJobReplayed(t *testing.T) {
+	ctl, b := newTestController(t, "SCAN")
+
+	first, err := ctl.LaunchJob(launchRequest("SCAN", job.Parameters{"image": "a"}, "k1"))
+	if err != nil {
+		t.Fatalf("launch job error: %s", err)
+	}
+
+	replayed, err := ctl.LaunchJob(launchRequest("SCAN", job.Parameters{"image": "a"}, "k1"))
+	if err != nil {
+		t.Fatalf("replay launching error: %s", err)
+	}
+	if replayed.Info.JobID != first.Info.JobID {
+		t.Errorf("expect the replayed launching returns job %s but got %s", first.Info.JobID, replayed.Info.JobID)
+	}
+
+	// Same key with the different request
+	if _, err := ctl.LaunchJob(launchRequest("SCAN", job.Parameters{"image": "b"}, "k1")); !errs.IsConflictError(err) {
+		t.Errorf("expect conflict of reusing the key but got %v", err)
+	}
+
+	// The key is being launched by another request
+	if r, err := b.Idempotency.Reserve("alice", "k2", "other"); err != nil || r != nil {
+		t.Fatalf("reserve key error: %v, %v", r, err)
+	}
+	if _, err := ctl.LaunchJob(launchRequest("SCAN", job.Parameters{"image": "a"}, "k2")); !errs.IsConflictError(err) {
+		t.Errorf("expect conflict of the key being launched but got %v", err)
+	}
+
+	// The key is released if the launching is failed
+	if _, err := ctl.LaunchJob(launchRequest("UNKNOWN", nil, "k3")); !errs.IsBadRequestError(err) {
+		t.Fatalf("expect bad request of the unknown job but got %v", err)
+	}
+	if r, err := b.Idempotency.Reserve("alice", "k3", "other"); err != nil || r != nil {
+		t.Errorf("expect the key released after the failed launching but got %v, %v", r, err)
+	}
+}
@@ -2,8 +2,13 @@ package core
 
 import (
 	"github.com/chenxull/goGridhub/gridhub/src/jobservice/common/query"
+	"github.com/chenxull/goGridhub/gridhub/src/jobservice/hook"
 	"github.com/chenxull/goGridhub/gridhub/src/jobservice/job"
+	"github.com/chenxull/goGridhub/gridhub/src/jobservice/node"
+	"github.com/chenxull/goGridhub/gridhub/src/jobservice/period"
+	"github.com/chenxull/goGridhub/gridhub/src/jobservice/retention"
 	"github.com/chenxull/goGridhub/gridhub/src/jobservice/worker"
+	"time"
 )
 
 type Interface interface {
@@ -19,4 +24,25 @@ type Interface interface {
 	// Get the periodic executions for the specified periodic job.
 	GetPeriodicExecutions(periodicJobID string, query *query.Parameter) ([]*job.Stats, int64, error)
 	GetJobs(query *query.Parameter) ([]*job.Stats, int64, error)
+	// GetScheduledJobs returns the jobs waiting in the scheduled queue.
+	GetScheduledJobs(query *query.Parameter) ([]*job.Stats, int64, error)
+	// ScheduledJobAction reschedules, runs immediately or cancels the scheduled job and returns its latest stats.
+	ScheduledJobAction(jobID string, req *job.ScheduleActionRequest) (*job.Stats, error)
+	// GetArchivedJobs returns the archived jobs matched with the query from the latest archived one.
+	GetArchivedJobs(query *query.Parameter) ([]*retention.Record, int64, error)
+	// GetArchivedJob returns the archived job with the ID.
+	GetArchivedJob(jobID string) (*retention.Record, error)
+	// GetJobTypes returns the registered job types with their parameters schemas.
+	GetJobTypes() ([]*job.TypeInfo, error)
+	// GetNodes returns the live nodes with their running jobs and load.
+	GetNodes() ([]*node.Info, error)
+	// EvictNode handles the orphaned jobs of the dead node with the action 'fail' or 'requeue'.
+	EvictNode(nodeID string, action string) (*node.ActionResult, error)
+	// GetStatusEvents returns the status change events after the event ID, empty ID means the new events from now on.
+	// It blocks at most the wait duration if there are no new events, the ID of the last read event is returned.
+	GetStatusEvents(lastEventID string, wait time.Duration) ([]*hook.StatusEvent, string, error)
+	// ExportPeriodicPolicies returns all the periodic job policies with their numeric IDs in the versioned format.
+	ExportPeriodicPolicies() (*period.PolicyExport, error)
+	// ImportPeriodicPolicies imports the exported policies and skips the conflicting ones, nothing is changed in the dry run.
+	ImportPeriodicPolicies(data *period.PolicyExport, dryRun bool) (*period.PolicyImportReport, error)
 }
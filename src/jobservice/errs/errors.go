@@ -6,6 +6,7 @@ import (
 	"github.com/chenxull/goGridhub/gridhub/src/jobservice/common/query"
 )
 
+// The codes are part of the API, the new codes are only appended to keep the existing ones stable
 const (
 	// ReadRequestBodyErrorCode is code for the error of reading http request body error
 	ReadRequestBodyErrorCode = 10000 + iota
@@ -41,6 +42,32 @@ const (
 	GetPeriodicExecutionErrorCode
 	// StatusMismatchErrorCode is code for the error of mismatching status
 	StatusMismatchErrorCode
+	// GetNodesErrorCode is code for the error of getting nodes
+	GetNodesErrorCode
+	// NodeActionErrorCode is code for the error of doing node action
+	NodeActionErrorCode
+	// RateLimitedErrorCode is code for the error of too many requests
+	RateLimitedErrorCode
+	// OverloadedErrorCode is code for the error of rejecting jobs as the system is under pressure
+	OverloadedErrorCode
+	// GetJobTypesErrorCode is code for the error of getting job types
+	GetJobTypesErrorCode
+	// GetArchivedJobsErrorCode is code for the error of getting archived jobs
+	GetArchivedJobsErrorCode
+	// ScheduledJobActionErrorCode is code for the error of doing scheduled job action
+	ScheduledJobActionErrorCode
+	// ForbiddenErrorCode is code for the error of accessing without the required scope
+	ForbiddenErrorCode
+	// GetStatusEventsErrorCode is code for the error of getting status change events
+	GetStatusEventsErrorCode
+	// InternalErrorCode is code for the unexpected errors not covered by the codes above
+	InternalErrorCode
+	// GetAuditLogsErrorCode is code for the error of getting audit logs
+	GetAuditLogsErrorCode
+	// ExportPoliciesErrorCode is code for the error of exporting periodic job policies
+	ExportPoliciesErrorCode
+	// ImportPoliciesErrorCode is code for the error of importing periodic job policies
+	ImportPoliciesErrorCode
 )
 
 type baseError struct {
@@ -136,6 +163,56 @@ func GetPeriodicExecutionError(err error) error {
 	return New(GetPeriodicExecutionErrorCode, "failed to get periodic executions", err.Error())
 }
 
+// GetNodesError is error for the case of getting nodes failed
+func GetNodesError(err error) error {
+	return New(GetNodesErrorCode, "failed to get nodes", err.Error())
+}
+
+// GetJobTypesError is error for the case of getting job types failed
+func GetJobTypesError(err error) error {
+	return New(GetJobTypesErrorCode, "failed to get job types", err.Error())
+}
+
+// GetArchivedJobsError is error for the case of getting archived jobs failed
+func GetArchivedJobsError(err error) error {
+	return New(GetArchivedJobsErrorCode, "failed to get archived jobs", err.Error())
+}
+
+// ScheduledJobActionError is error for the case of doing scheduled job action failed
+func ScheduledJobActionError(err error) error {
+	return New(ScheduledJobActionErrorCode, "scheduled job action failed with error", err.Error())
+}
+
+// GetStatusEventsError is error for the case of getting status change events failed
+func GetStatusEventsError(err error) error {
+	return New(GetStatusEventsErrorCode, "get status change events failed with error", err.Error())
+}
+
+// GetAuditLogsError is error for the case of getting audit logs failed
+func GetAuditLogsError(err error) error {
+	return New(GetAuditLogsErrorCode, "failed to get audit logs", err.Error())
+}
+
+// ExportPoliciesError is error for the case of exporting periodic job policies failed
+func ExportPoliciesError(err error) error {
+	return New(ExportPoliciesErrorCode, "failed to export periodic job policies", err.Error())
+}
+
+// ImportPoliciesError is error for the case of importing periodic job policies failed
+func ImportPoliciesError(err error) error {
+	return New(ImportPoliciesErrorCode, "failed to import periodic job policies", err.Error())
+}
+
+// NodeActionError is error for the case of doing node action failed
+func NodeActionError(err error) error {
+	return New(NodeActionErrorCode, "node action failed with error", err.Error())
+}
+
+// RateLimitedError is error for the case of launching jobs too frequently
+func RateLimitedError(caller string, jobName string) error {
+	return New(RateLimitedErrorCode, "too many requests", fmt.Sprintf("rate limit of launching job %s by %s is exceeded", jobName, caller))
+}
+
 // objectNotFound is designed for the case of no object found
 type objectNotFoundError struct {
 	baseError
@@ -155,6 +232,8 @@ func NoObjectFoundError(object string) error {
 // conflictError is designed for the case of resource conflicting
 type conflictError struct {
 	baseError
+	// The existing resource
+	data interface{}
 }
 
 // ConflictError is error for the case of resource conflicting
@@ -165,7 +244,29 @@ func ConflictError(object string) error {
 			Err:         "conflict",
 			Description: fmt.Sprintf("the submitting resource is conflicted with existing one %s", object),
 		},
+		nil,
+	}
+}
+
+// ConflictErrorWithData is error for the case of resource conflicting with the existing one attached
+func ConflictErrorWithData(object string, data interface{}) error {
+	return conflictError{
+		baseError{
+			Code:        ResourceConflictsErrorCode,
+			Err:         "conflict",
+			Description: fmt.Sprintf("the submitting resource is conflicted with existing one %s", object),
+		},
+		data,
+	}
+}
+
+// ConflictData returns the existing resource attached to the conflict error
+func ConflictData(err error) interface{} {
+	if e, ok := err.(conflictError); ok {
+		return e.data
 	}
+
+	return nil
 }
 
 // badRequestError is designed for the case of bad request
@@ -187,6 +288,9 @@ func BadRequestError(object interface{}) error {
 // statusMismatchError is designed for the case of job status update mismatching
 type statusMismatchError struct {
 	baseError
+	// The current status of the job and the status it's being set to
+	current string
+	target  string
 }
 
 // StatusMismatchError returns the error of job status mismatching
@@ -197,6 +301,43 @@ func StatusMismatchError(current, target string) error {
 			Err:         "mismatch job status",
 			Description: fmt.Sprintf("current %s, setting to %s", current, target),
 		},
+		current,
+		target,
+	}
+}
+
+// overloadedError is designed for the case of rejecting jobs as the system is under pressure
+type overloadedError struct {
+	baseError
+}
+
+// OverloadedError returns the error of rejecting jobs as the system is under pressure
+func OverloadedError(reason string) error {
+	return overloadedError{
+		baseError{
+			Code:        OverloadedErrorCode,
+			Err:         "system is overloaded",
+			Description: reason,
+		},
+	}
+}
+
+// forbiddenError is designed for the case of the caller without the required scope
+type forbiddenError struct {
+	baseError
+}
+
+// ForbiddenError returns the error of the caller without the required scope on the job
+func ForbiddenError(caller string, scope string, jobName string) error {
+	if len(jobName) == 0 {
+		jobName = "*"
+	}
+	return forbiddenError{
+		baseError{
+			Code:        ForbiddenErrorCode,
+			Err:         "forbidden",
+			Description: fmt.Sprintf("scope %s of job %s is not granted to caller %s", scope, jobName, caller),
+		},
 	}
 }
 
@@ -235,3 +376,21 @@ func IsStatusMismatchError(err error) bool {
 	_, ok := err.(statusMismatchError)
 	return ok
 }
+
+// IsOverloadedError returns true if the error is overloadedError
+func IsOverloadedError(err error) bool {
+	if err == nil {
+		return false
+	}
+	_, ok := err.(overloadedError)
+	return ok
+}
+
+// IsForbiddenError returns true if the error is forbiddenError
+func IsForbiddenError(err error) bool {
+	if err == nil {
+		return false
+	}
+	_, ok := err.(forbiddenError)
+	return ok
+}
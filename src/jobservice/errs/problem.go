@@ -0,0 +1,168 @@
+package errs
+
+import (
+	"net/http"
+)
+
+const (
+	// ProblemContentType is the media type of the problem details defined in RFC 7807
+	ProblemContentType = "application/problem+json"
+	// ProblemTypePrefix is the prefix of the problem type URI, followed by the name of the error code
+	ProblemTypePrefix = "urn:jobservice:error:"
+)
+
+// codeNames are the stable machine-readable names of the error codes
+var codeNames = map[uint16]string{
+	ReadRequestBodyErrorCode:       "read_request_body",
+	HandleJSONDataErrorCode:        "handle_json_data",
+	MissingBackendHandlerErrorCode: "missing_backend_handler",
+	LaunchJobErrorCode:             "launch_job",
+	CheckStatsErrorCode:            "check_stats",
+	GetJobStatsErrorCode:           "get_job_stats",
+	StopJobErrorCode:               "stop_job",
+	RetryJobErrorCode:              "retry_job",
+	UnknownActionNameErrorCode:     "unknown_action",
+	GetJobLogErrorCode:             "get_job_log",
+	NoObjectFoundErrorCode:         "not_found",
+	UnAuthorizedErrorCode:          "unauthorized",
+	ResourceConflictsErrorCode:     "conflict",
+	BadRequestErrorCode:            "bad_request",
+	GetJobsErrorCode:               "get_jobs",
+	GetPeriodicExecutionErrorCode:  "get_periodic_executions",
+	StatusMismatchErrorCode:        "status_mismatch",
+	GetNodesErrorCode:              "get_nodes",
+	NodeActionErrorCode:            "node_action",
+	RateLimitedErrorCode:           "rate_limited",
+	OverloadedErrorCode:            "overloaded",
+	GetJobTypesErrorCode:           "get_job_types",
+	GetArchivedJobsErrorCode:       "get_archived_jobs",
+	ScheduledJobActionErrorCode:    "scheduled_job_action",
+	ForbiddenErrorCode:             "forbidden",
+	GetStatusEventsErrorCode:       "get_status_events",
+	InternalErrorCode:              "internal",
+	GetAuditLogsErrorCode:          "get_audit_logs",
+	ExportPoliciesErrorCode:        "export_policies",
+	ImportPoliciesErrorCode:        "import_policies",
+}
+
+// codeStatus are the HTTP status codes of the client side errors, others are server side errors
+var codeStatus = map[uint16]int{
+	UnknownActionNameErrorCode: http.StatusNotImplemented,
+	NoObjectFoundErrorCode:     http.StatusNotFound,
+	UnAuthorizedErrorCode:      http.StatusUnauthorized,
+	ResourceConflictsErrorCode: http.StatusConflict,
+	BadRequestErrorCode:        http.StatusBadRequest,
+	StatusMismatchErrorCode:    http.StatusConflict,
+	RateLimitedErrorCode:       http.StatusTooManyRequests,
+	OverloadedErrorCode:        http.StatusServiceUnavailable,
+	ForbiddenErrorCode:         http.StatusForbidden,
+}
+
+// statusCodes are the error codes of the errors not in the form of baseError
+var statusCodes = map[int]uint16{
+	http.StatusBadRequest:         BadRequestErrorCode,
+	http.StatusUnauthorized:       UnAuthorizedErrorCode,
+	http.StatusForbidden:          ForbiddenErrorCode,
+	http.StatusNotFound:           NoObjectFoundErrorCode,
+	http.StatusConflict:           ResourceConflictsErrorCode,
+	http.StatusTooManyRequests:    RateLimitedErrorCode,
+	http.StatusNotImplemented:     UnknownActionNameErrorCode,
+	http.StatusServiceUnavailable: OverloadedErrorCode,
+}
+
+// Problem is the problem details of the error returned by the API, see RFC 7807.
+// The code and the error name are the extension members identifying the error for the machines.
+type Problem struct {
+	Type     string `json:"type"`
+	Title    string `json:"title"`
+	Status   int    `json:"status"`
+	Detail   string `json:"detail,omitempty"`
+	Instance string `json:"instance,omitempty"`
+
+	// Numeric code of the error
+	Code uint16 `json:"code"`
+	// Stable name of the error code, e.g: not_found
+	Name string `json:"error"`
+	// The current status of the job and the target one of the status mismatch error
+	CurrentStatus string `json:"current_status,omitempty"`
+	TargetStatus  string `json:"target_status,omitempty"`
+	// The existing resource of the conflict error, it's attached by the caller as it may need to be redacted
+	Existing interface{} `json:"existing,omitempty"`
+}
+
+// CodeName returns the stable name of the error code, empty if the code is unknown
+func CodeName(code uint16) string {
+	return codeNames[code]
+}
+
+// HTTPStatus returns the HTTP status code of the error, the fallback is returned for the server side errors
+func HTTPStatus(err error, fallback int) int {
+	if be, ok := asBaseError(err); ok {
+		if status, ok := codeStatus[be.Code]; ok {
+			return status
+		}
+	}
+
+	return fallback
+}
+
+// NewProblem returns the problem details of the error. The status of the known client side errors
+// overrides the given one, the error not in the form of baseError is coded by the status.
+func NewProblem(err error, status int, instance string) *Problem {
+	status = HTTPStatus(err, status)
+
+	p := &Problem{
+		Status:   status,
+		Instance: instance,
+	}
+
+	if be, ok := asBaseError(err); ok {
+		p.Code = be.Code
+		p.Title = be.Err
+		p.Detail = be.Description
+	} else {
+		p.Code = InternalErrorCode
+		if code, ok := statusCodes[status]; ok {
+			p.Code = code
+		}
+		p.Title = http.StatusText(status)
+		if err != nil {
+			p.Detail = err.Error()
+		}
+	}
+
+	p.Name = CodeName(p.Code)
+	if len(p.Name) == 0 {
+		p.Name = codeNames[InternalErrorCode]
+	}
+	p.Type = ProblemTypePrefix + p.Name
+
+	if e, ok := err.(statusMismatchError); ok {
+		p.CurrentStatus = e.current
+		p.TargetStatus = e.target
+	}
+
+	return p
+}
+
+// asBaseError extracts the baseError of the errors defined in this package
+func asBaseError(err error) (baseError, bool) {
+	switch e := err.(type) {
+	case baseError:
+		return e, true
+	case objectNotFoundError:
+		return e.baseError, true
+	case conflictError:
+		return e.baseError, true
+	case badRequestError:
+		return e.baseError, true
+	case statusMismatchError:
+		return e.baseError, true
+	case overloadedError:
+		return e.baseError, true
+	case forbiddenError:
+		return e.baseError, true
+	}
+
+	return baseError{}, false
+}
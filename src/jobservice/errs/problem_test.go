@@ -0,0 +1,62 @@
+package errs
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+)
+
+func TestNewProblem(t *testing.T) {
+	cases := []struct {
+		name     string
+		err      error
+		fallback int
+		status   int
+		code     uint16
+		title    string
+		typ      string
+	}{
+		{name: "not found", err: NoObjectFoundError("job-1"), fallback: http.StatusInternalServerError, status: http.StatusNotFound, code: NoObjectFoundErrorCode, title: "object is not found", typ: "urn:jobservice:error:not_found"},
+		{name: "conflict", err: ConflictErrorWithData("job-1", "existing"), fallback: http.StatusInternalServerError, status: http.StatusConflict, code: ResourceConflictsErrorCode, title: "conflict", typ: "urn:jobservice:error:conflict"},
+		{name: "bad request", err: BadRequestError("missing name"), fallback: http.StatusInternalServerError, status: http.StatusBadRequest, code: BadRequestErrorCode, title: "bad request", typ: "urn:jobservice:error:bad_request"},
+		{name: "unauthorized", err: UnauthorizedError(errors.New("no token")), fallback: http.StatusInternalServerError, status: http.StatusUnauthorized, code: UnAuthorizedErrorCode, title: "unauthorized", typ: "urn:jobservice:error:unauthorized"},
+		{name: "forbidden", err: ForbiddenError("alice", "jobs:launch", "demo"), fallback: http.StatusInternalServerError, status: http.StatusForbidden, code: ForbiddenErrorCode, title: "forbidden", typ: "urn:jobservice:error:forbidden"},
+		{name: "status mismatch", err: StatusMismatchError("Success", "Stopped"), fallback: http.StatusInternalServerError, status: http.StatusConflict, code: StatusMismatchErrorCode, title: "mismatch job status", typ: "urn:jobservice:error:status_mismatch"},
+		{name: "rate limited", err: RateLimitedError("alice", "demo"), fallback: http.StatusInternalServerError, status: http.StatusTooManyRequests, code: RateLimitedErrorCode, title: "too many requests", typ: "urn:jobservice:error:rate_limited"},
+		{name: "overloaded", err: OverloadedError("queue is full"), fallback: http.StatusInternalServerError, status: http.StatusServiceUnavailable, code: OverloadedErrorCode, title: "system is overloaded", typ: "urn:jobservice:error:overloaded"},
+		{name: "unknown action", err: UnknownActionNameError(errors.New("pause")), fallback: http.StatusInternalServerError, status: http.StatusNotImplemented, code: UnknownActionNameErrorCode, typ: "urn:jobservice:error:unknown_action"},
+		// Server side errors keep the given status
+		{name: "launch job", err: LaunchJobError(errors.New("redis is down")), fallback: http.StatusInternalServerError, status: http.StatusInternalServerError, code: LaunchJobErrorCode, typ: "urn:jobservice:error:launch_job"},
+		{name: "get jobs", err: GetJobsError(nil, errors.New("redis is down")), fallback: http.StatusInternalServerError, status: http.StatusInternalServerError, code: GetJobsErrorCode, typ: "urn:jobservice:error:get_jobs"},
+		// Unknown errors
+		{name: "unknown error", err: errors.New("boom"), fallback: http.StatusInternalServerError, status: http.StatusInternalServerError, code: InternalErrorCode, title: "Internal Server Error", typ: "urn:jobservice:error:internal"},
+		{name: "unknown error with client status", err: errors.New("bad payload"), fallback: http.StatusBadRequest, status: http.StatusBadRequest, code: BadRequestErrorCode, title: "Bad Request", typ: "urn:jobservice:error:bad_request"},
+		{name: "unknown error with unmapped status", err: errors.New("teapot"), fallback: http.StatusTeapot, status: http.StatusTeapot, code: InternalErrorCode, title: "I'm a teapot", typ: "urn:jobservice:error:internal"},
+		{name: "unknown code", err: New(9999, "strange", ""), fallback: http.StatusInternalServerError, status: http.StatusInternalServerError, code: 9999, title: "strange", typ: "urn:jobservice:error:internal"},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			p := NewProblem(c.err, c.fallback, "/api/v1/jobs")
+			if p.Status != c.status || p.Code != c.code || p.Type != c.typ {
+				t.Errorf("expect status %d, code %d, type %s but got %d, %d, %s", c.status, c.code, c.typ, p.Status, p.Code, p.Type)
+			}
+			if len(c.title) > 0 && p.Title != c.title {
+				t.Errorf("expect title %q but got %q", c.title, p.Title)
+			}
+			if len(p.Title) == 0 {
+				t.Error("expect a non empty title")
+			}
+			if p.Instance != "/api/v1/jobs" {
+				t.Errorf("expect the instance kept but got %s", p.Instance)
+			}
+		})
+	}
+}
+
+func TestNewProblemStatusMismatch(t *testing.T) {
+	p := NewProblem(StatusMismatchError("Success", "Stopped"), http.StatusInternalServerError, "")
+	if p.CurrentStatus != "Success" || p.TargetStatus != "Stopped" {
+		t.Errorf("expect the current and target status attached but got %s, %s", p.CurrentStatus, p.TargetStatus)
+	}
+}
@@ -0,0 +1,224 @@
+package hook
+
+import (
+	"github.com/chenxull/goGridhub/gridhub/src/jobservice/env"
+	"github.com/chenxull/goGridhub/gridhub/src/jobservice/errs"
+	"github.com/chenxull/goGridhub/gridhub/src/jobservice/job"
+	"github.com/chenxull/goGridhub/gridhub/src/jobservice/logger"
+	"github.com/pkg/errors"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// Max number of the recent events kept by the fan-out for the subscribers
+	fanoutBufferSize = 1000
+	// Block duration of each reading of the fan-out reader
+	fanoutReadWait = 5 * time.Second
+	// Waiting a short while if reading the event log failed
+	fanoutRetryInterval = 2 * time.Second
+)
+
+// fanoutEventLog shares one blocking reader of the event log among all the subscribers of the node.
+// The subscribers waiting for the new events are served from the recent events read by the reader,
+// so they do not hold the connections of the shared pool while waiting.
+type fanoutEventLog struct {
+	log EventLog
+	// Protect the following fields
+	lock sync.RWMutex
+	// The consecutive events read by the reader after the since ID, the oldest first
+	recent []*StatusEvent
+	since  string
+	// ID of the latest event read by the reader, empty if the reader is not ready
+	lastID string
+	// Closed and replaced once new events are read to wake up the subscribers
+	updated chan struct{}
+}
+
+// NewFanoutEventLog wraps the event log with a single reader started in the background.
+// The appending and the non blocking reading are passed to the wrapped event log.
+func NewFanoutEventLog(ctx *env.Context, log EventLog) EventLog {
+	fl := &fanoutEventLog{
+		log:     log,
+		recent:  make([]*StatusEvent, 0),
+		updated: make(chan struct{}),
+	}
+
+	ctx.WG.Add(1)
+	go fl.loopRead(ctx)
+
+	return fl
+}
+
+// Append is implementation of EventLog.Append
+func (fl *fanoutEventLog) Append(change *job.StatusChange) (string, error) {
+	return fl.log.Append(change)
+}
+
+// Latest is implementation of EventLog.Latest
+func (fl *fanoutEventLog) Latest() (string, error) {
+	return fl.log.Latest()
+}
+
+// Read is implementation of EventLog.Read
+func (fl *fanoutEventLog) Read(lastID string, wait time.Duration) ([]*StatusEvent, error) {
+	if !eventIDPattern.MatchString(lastID) {
+		return nil, errs.BadRequestError(errors.Errorf("invalid event ID: %s", lastID))
+	}
+
+	if wait <= 0 {
+		return fl.log.Read(lastID, 0)
+	}
+
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+
+	for {
+		evts, covered, updated := fl.fromRecent(lastID)
+		if !covered {
+			// Resuming from the events not kept in the recent events, or the reader is not ready
+			evts, err := fl.log.Read(lastID, 0)
+			if err != nil || len(evts) > 0 {
+				return evts, err
+			}
+		} else if len(evts) > 0 {
+			return evts, nil
+		}
+
+		select {
+		case <-updated:
+		case <-timer.C:
+			return nil, nil
+		}
+	}
+}
+
+// fromRecent returns the recent events after the ID. False is returned if the events after the ID
+// are not all kept in the recent events. The returned channel is closed once new events are read.
+func (fl *fanoutEventLog) fromRecent(lastID string) ([]*StatusEvent, bool, <-chan struct{}) {
+	fl.lock.RLock()
+	defer fl.lock.RUnlock()
+
+	if len(fl.lastID) == 0 {
+		return nil, false, fl.updated
+	}
+
+	if compareEventID(lastID, fl.lastID) >= 0 {
+		// No new events
+		return nil, true, fl.updated
+	}
+
+	if compareEventID(lastID, fl.since) < 0 {
+		return nil, false, fl.updated
+	}
+
+	evts := make([]*StatusEvent, 0)
+	for _, evt := range fl.recent {
+		if compareEventID(evt.ID, lastID) > 0 {
+			evts = append(evts, evt)
+			if len(evts) >= MaxReadEvents {
+				break
+			}
+		}
+	}
+
+	return evts, true, fl.updated
+}
+
+// loopRead reads the new events from the event log and wakes up the subscribers
+func (fl *fanoutEventLog) loopRead(ctx *env.Context) {
+	defer func() {
+		logger.Info("Status event fan-out reader is stopped")
+		ctx.WG.Done()
+	}()
+
+	lastID := ""
+	for {
+		select {
+		case <-ctx.SystemContext.Done():
+			return
+		default:
+		}
+
+		if len(lastID) == 0 {
+			latest, err := fl.log.Latest()
+			if err != nil {
+				logger.Errorf("get latest status event error: %s", err)
+				fl.sleep(ctx)
+				continue
+			}
+			lastID = latest
+			fl.append(nil, lastID)
+		}
+
+		evts, err := fl.log.Read(lastID, fanoutReadWait)
+		if err != nil {
+			logger.Errorf("read status events error: %s", err)
+			fl.sleep(ctx)
+			continue
+		}
+		if len(evts) > 0 {
+			lastID = evts[len(evts)-1].ID
+			fl.append(evts, lastID)
+		}
+	}
+}
+
+// append the new events to the recent events and wake up the subscribers
+func (fl *fanoutEventLog) append(evts []*StatusEvent, lastID string) {
+	fl.lock.Lock()
+	defer fl.lock.Unlock()
+
+	if len(fl.lastID) == 0 {
+		// All the events after it are read from now on
+		fl.since = lastID
+	}
+	fl.recent = append(fl.recent, evts...)
+	if n := len(fl.recent) - fanoutBufferSize; n > 0 {
+		fl.since = fl.recent[n-1].ID
+		fl.recent = append(make([]*StatusEvent, 0, fanoutBufferSize), fl.recent[n:]...)
+	}
+	fl.lastID = lastID
+
+	close(fl.updated)
+	fl.updated = make(chan struct{})
+}
+
+func (fl *fanoutEventLog) sleep(ctx *env.Context) {
+	select {
+	case <-time.After(fanoutRetryInterval):
+	case <-ctx.SystemContext.Done():
+	}
+}
+
+// compareEventID compares the stream entry IDs in the form of '<milliseconds>-<sequence>',
+// the missing sequence is treated as 0.
+func compareEventID(a string, b string) int {
+	am, as := splitEventID(a)
+	bm, bs := splitEventID(b)
+	switch {
+	case am < bm:
+		return -1
+	case am > bm:
+		return 1
+	case as < bs:
+		return -1
+	case as > bs:
+		return 1
+	default:
+		return 0
+	}
+}
+
+func splitEventID(id string) (uint64, uint64) {
+	parts := strings.SplitN(id, "-", 2)
+	ms, _ := strconv.ParseUint(parts[0], 10, 64)
+	var seq uint64
+	if len(parts) == 2 {
+		seq, _ = strconv.ParseUint(parts[1], 10, 64)
+	}
+
+	return ms, seq
+}
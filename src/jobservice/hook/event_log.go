@@ -0,0 +1,170 @@
+package hook
+
+import (
+	"encoding/json"
+	"github.com/chenxull/goGridhub/gridhub/src/jobservice/common/rds"
+	"github.com/chenxull/goGridhub/gridhub/src/jobservice/errs"
+	"github.com/chenxull/goGridhub/gridhub/src/jobservice/job"
+	"github.com/gomodule/redigo/redis"
+	"github.com/pkg/errors"
+	"regexp"
+	"time"
+)
+
+const (
+	// MaxLoggedEvents is the max number of the status change events kept in the event log,
+	// the oldest ones are trimmed
+	MaxLoggedEvents = 10000
+	// MaxReadEvents is the max number of the events returned by one reading
+	MaxReadEvents = 100
+)
+
+// Stream entry ID in the form of '<milliseconds>-<sequence>'
+var eventIDPattern = regexp.MustCompile(`^\d+(-\d+)?$`)
+
+// StatusEvent is the status change event kept in the event log
+type StatusEvent struct {
+	// ID of the event for resuming the reading
+	ID     string            `json:"id"`
+	Change *job.StatusChange `json:"data"`
+}
+
+// EventLog keeps the recent status change events of all the jobs, including the ones without hooks.
+// It's shared by the nodes, so the subscribers can read the events of the jobs run by any node.
+type EventLog interface {
+	// Append the status change and return the ID of the event
+	Append(change *job.StatusChange) (string, error)
+
+	// Latest returns the ID of the latest event, reading after it returns the new events from now on
+	Latest() (string, error)
+
+	// Read the events after the ID, at most MaxReadEvents are returned.
+	// It blocks at most the wait duration if there are no new events, 0 means no blocking.
+	Read(lastID string, wait time.Duration) ([]*StatusEvent, error)
+}
+
+// redisEventLog keeps the events in the redis stream
+type redisEventLog struct {
+	namespace string
+	pool      *redis.Pool
+}
+
+// NewEventLog is constructor of the event log based on the redis stream
+func NewEventLog(ns string, pool *redis.Pool) EventLog {
+	return &redisEventLog{
+		namespace: ns,
+		pool:      pool,
+	}
+}
+
+// Append is implementation of EventLog.Append
+func (rl *redisEventLog) Append(change *job.StatusChange) (string, error) {
+	rawJSON, err := json.Marshal(change)
+	if err != nil {
+		return "", err
+	}
+
+	conn := rl.pool.Get()
+	defer func() {
+		_ = conn.Close()
+	}()
+
+	return redis.String(conn.Do("XADD", rds.KeyStatusEvents(rl.namespace), "MAXLEN", "~", MaxLoggedEvents, "*", "change", rawJSON))
+}
+
+// Latest is implementation of EventLog.Latest
+func (rl *redisEventLog) Latest() (string, error) {
+	conn := rl.pool.Get()
+	defer func() {
+		_ = conn.Close()
+	}()
+
+	entries, err := redis.Values(conn.Do("XREVRANGE", rds.KeyStatusEvents(rl.namespace), "+", "-", "COUNT", 1))
+	if err != nil {
+		return "", err
+	}
+	if len(entries) == 0 {
+		return "0-0", nil
+	}
+
+	evts, err := parseEvents(entries)
+	if err != nil {
+		return "", err
+	}
+
+	return evts[0].ID, nil
+}
+
+// Read is implementation of EventLog.Read
+func (rl *redisEventLog) Read(lastID string, wait time.Duration) ([]*StatusEvent, error) {
+	if !eventIDPattern.MatchString(lastID) {
+		return nil, errs.BadRequestError(errors.Errorf("invalid event ID: %s", lastID))
+	}
+
+	conn := rl.pool.Get()
+	defer func() {
+		_ = conn.Close()
+	}()
+
+	args := []interface{}{"COUNT", MaxReadEvents}
+	if wait > 0 {
+		args = append(args, "BLOCK", int64(wait/time.Millisecond))
+	}
+	args = append(args, "STREAMS", rds.KeyStatusEvents(rl.namespace), lastID)
+
+	replies, err := redis.Values(conn.Do("XREAD", args...))
+	if err != nil {
+		if err == redis.ErrNil {
+			// No new events
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	// Only one stream is read
+	for _, r := range replies {
+		sr, err := redis.Values(r, nil)
+		if err != nil || len(sr) != 2 {
+			return nil, errors.New("malformed reply of XREAD")
+		}
+		entries, err := redis.Values(sr[1], nil)
+		if err != nil {
+			return nil, err
+		}
+
+		return parseEvents(entries)
+	}
+
+	return nil, nil
+}
+
+// parseEvents parses the stream entries in the form of [id, [field, value...]]
+func parseEvents(entries []interface{}) ([]*StatusEvent, error) {
+	evts := make([]*StatusEvent, 0, len(entries))
+	for _, e := range entries {
+		entry, err := redis.Values(e, nil)
+		if err != nil || len(entry) != 2 {
+			return nil, errors.New("malformed status event entry")
+		}
+
+		id, err := redis.String(entry[0], nil)
+		if err != nil {
+			return nil, err
+		}
+		fields, err := redis.StringMap(entry[1], nil)
+		if err != nil {
+			return nil, err
+		}
+
+		change := &job.StatusChange{}
+		if err := json.Unmarshal([]byte(fields["change"]), change); err != nil {
+			return nil, errors.Wrapf(err, "malformed status event %s", id)
+		}
+		evts = append(evts, &StatusEvent{
+			ID:     id,
+			Change: change,
+		})
+	}
+
+	return evts, nil
+}
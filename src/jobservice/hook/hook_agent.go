@@ -3,7 +3,9 @@ package hook
 import (
 	"context"
 	"encoding/json"
+	"fmt"
 	"github.com/chenxull/goGridhub/gridhub/src/jobservice/common/rds"
+	"github.com/chenxull/goGridhub/gridhub/src/jobservice/common/utils"
 	"github.com/chenxull/goGridhub/gridhub/src/jobservice/env"
 	"github.com/chenxull/goGridhub/gridhub/src/jobservice/job"
 	"github.com/chenxull/goGridhub/gridhub/src/jobservice/lcm"
@@ -299,3 +301,39 @@ func extractJobID(data *job.StatusChange) (string, job.Status, error) {
 
 	return "", "", errors.New("malform job status change data")
 }
+
+// NewCallback returns the job hook callback which appends the status change events to the event log
+// and triggers the hooks via the agent if the hook URL is registered
+func NewCallback(agent Agent, events EventLog) job.HookCallback {
+	return func(URL string, change *job.StatusChange) error {
+		// Never send the secret parameters out
+		if change.Metadata != nil && job.HasSecrets(change.Metadata.Parameters) {
+			redacted := *change
+			redacted.Metadata = (&job.Stats{Info: change.Metadata}).Redacted().Info
+			change = &redacted
+		}
+
+		if events != nil {
+			if _, err := events.Append(change); err != nil {
+				// The hook is still sent
+				logger.Errorf("Append status change event of job %s error: %s", change.JobID, err)
+			}
+		}
+
+		if utils.IsEmptyStr(URL) {
+			return nil
+		}
+
+		msg := fmt.Sprintf("status change: job=%s, status=%s", change.JobID, change.Status)
+		if !utils.IsEmptyStr(change.CheckIn) {
+			msg = fmt.Sprintf("%s, check_in=%s", msg, change.CheckIn)
+		}
+		evt := &Event{
+			URL:       URL,
+			Message:   msg,
+			Data:      change,
+			Timestamp: time.Now().Unix(),
+		}
+		return agent.Trigger(evt)
+	}
+}
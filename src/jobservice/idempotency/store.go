@@ -0,0 +1,142 @@
+package idempotency
+
+import (
+	"encoding/json"
+	"github.com/chenxull/goGridhub/gridhub/src/jobservice/common/rds"
+	"github.com/gomodule/redigo/redis"
+	"github.com/pkg/errors"
+	"time"
+)
+
+// DefaultWindow is the default duration of keeping the idempotency keys
+const DefaultWindow = 24 * time.Hour
+
+// ReservationLease is the duration of keeping the key reserved before the launched job is committed,
+// so the key left by the crash between the reserving and the committing can be retried soon
+const ReservationLease = 30 * time.Second
+
+// Lease returns the duration of keeping the reserved key, which is not longer than the window
+func Lease(window time.Duration) time.Duration {
+	if window < ReservationLease {
+		return window
+	}
+
+	return ReservationLease
+}
+
+// Record keeps the job launched with the idempotency key.
+type Record struct {
+	// Digest of the launching request
+	Digest string `json:"digest"`
+	// Empty if the job is still being launched
+	JobID string `json:"job_id,omitempty"`
+}
+
+// Store keeps the mapping from the idempotency keys to the launched jobs.
+type Store interface {
+	// Reserve the idempotency key before launching the job.
+	//
+	// Arguments:
+	//   caller string: the caller who submits the request
+	//   key string   : the idempotency key
+	//   digest string: digest of the launching request
+	//
+	// Returns:
+	//   The existing record if the key has been reserved before, the reservation is kept in the lease
+	//   Non nil error if any issues meet
+	Reserve(caller string, key string, digest string) (*Record, error)
+
+	// Commit binds the launched job to the reserved key, the key is kept in the whole window from now on
+	Commit(caller string, key string, digest string, jobID string) error
+
+	// Release the reserved key if launching job failed, then the client can retry with the same key
+	Release(caller string, key string) error
+}
+
+// redisStore is the default implementation of Store based on redis
+type redisStore struct {
+	namespace string
+	pool      *redis.Pool
+	window    time.Duration
+}
+
+// NewStore is constructor of redisStore
+func NewStore(namespace string, pool *redis.Pool, window time.Duration) Store {
+	if window <= 0 {
+		window = DefaultWindow
+	}
+
+	return &redisStore{
+		namespace: namespace,
+		pool:      pool,
+		window:    window,
+	}
+}
+
+// Reserve is implementation of Store.Reserve
+func (rs *redisStore) Reserve(caller string, key string, digest string) (*Record, error) {
+	rawJSON, err := json.Marshal(&Record{Digest: digest})
+	if err != nil {
+		return nil, err
+	}
+
+	conn := rs.pool.Get()
+	defer func() {
+		_ = conn.Close()
+	}()
+
+	k := rds.KeyIdempotency(rs.namespace, caller, key)
+	reply, err := conn.Do("SET", k, rawJSON, "PX", int64(Lease(rs.window)/time.Millisecond), "NX")
+	if err != nil {
+		return nil, err
+	}
+	if reply != nil {
+		// Reserved
+		return nil, nil
+	}
+
+	raw, err := redis.Bytes(conn.Do("GET", k))
+	if err != nil {
+		if err == redis.ErrNil {
+			// Expired just now, let the client retry
+			return nil, errors.Errorf("idempotency key %s is expired", key)
+		}
+		return nil, err
+	}
+
+	r := &Record{}
+	if err := json.Unmarshal(raw, r); err != nil {
+		return nil, errors.Wrap(err, "decode idempotency record")
+	}
+
+	return r, nil
+}
+
+// Commit is implementation of Store.Commit
+func (rs *redisStore) Commit(caller string, key string, digest string, jobID string) error {
+	rawJSON, err := json.Marshal(&Record{Digest: digest, JobID: jobID})
+	if err != nil {
+		return err
+	}
+
+	conn := rs.pool.Get()
+	defer func() {
+		_ = conn.Close()
+	}()
+
+	_, err = conn.Do("SET", rds.KeyIdempotency(rs.namespace, caller, key), rawJSON, "PX", int64(rs.window/time.Millisecond))
+
+	return err
+}
+
+// Release is implementation of Store.Release
+func (rs *redisStore) Release(caller string, key string) error {
+	conn := rs.pool.Get()
+	defer func() {
+		_ = conn.Close()
+	}()
+
+	_, err := conn.Do("DEL", rds.KeyIdempotency(rs.namespace, caller, key))
+
+	return err
+}
@@ -0,0 +1,95 @@
+package idempotency
+
+import (
+	"github.com/alicebob/miniredis/v2"
+	"github.com/chenxull/goGridhub/gridhub/src/jobservice/common/rds"
+	"github.com/gomodule/redigo/redis"
+	"testing"
+	"time"
+)
+
+const testNamespace = "{idempotency_test}"
+
+func newTestStore(t *testing.T, window time.Duration) (Store, *miniredis.Miniredis) {
+	t.Helper()
+
+	mr := miniredis.RunT(t)
+	pool := &redis.Pool{
+		Dial: func() (redis.Conn, error) {
+			return redis.Dial("tcp", mr.Addr())
+		},
+	}
+	t.Cleanup(func() {
+		_ = pool.Close()
+	})
+
+	return NewStore(testNamespace, pool, window), mr
+}
+
+func TestRedisStoreReserve(t *testing.T) {
+	rs, mr := newTestStore(t, time.Hour)
+	key := rds.KeyIdempotency(testNamespace, "alice", "k1")
+
+	r, err := rs.Reserve("alice", "k1", "d1")
+	if err != nil || r != nil {
+		t.Fatalf("expect the key reserved but got %v, %v", r, err)
+	}
+	if ttl := mr.TTL(key); ttl != ReservationLease {
+		t.Errorf("expect the reservation kept in %v but got %v", ReservationLease, ttl)
+	}
+
+	// Being launched
+	r, err = rs.Reserve("alice", "k1", "d1")
+	if err != nil || r == nil || r.Digest != "d1" || len(r.JobID) > 0 {
+		t.Fatalf("expect the reservation without job but got %v, %v", r, err)
+	}
+
+	// The keys are separated by the callers
+	if r, err := rs.Reserve("bob", "k1", "d1"); err != nil || r != nil {
+		t.Errorf("expect the key of another caller reserved but got %v, %v", r, err)
+	}
+
+	// Crashed before committing, the key can be retried after the lease
+	mr.FastForward(ReservationLease)
+	if r, err := rs.Reserve("alice", "k1", "d1"); err != nil || r != nil {
+		t.Fatalf("expect the key left by the crash reserved again but got %v, %v", r, err)
+	}
+}
+
+func TestRedisStoreCommit(t *testing.T) {
+	rs, mr := newTestStore(t, time.Hour)
+	key := rds.KeyIdempotency(testNamespace, "alice", "k1")
+
+	if _, err := rs.Reserve("alice", "k1", "d1"); err != nil {
+		t.Fatalf("reserve key error: %s", err)
+	}
+	if err := rs.Commit("alice", "k1", "d1", "job-1"); err != nil {
+		t.Fatalf("commit key error: %s", err)
+	}
+	if ttl := mr.TTL(key); ttl != time.Hour {
+		t.Errorf("expect the committed key kept in the window but got %v", ttl)
+	}
+
+	// Replayed after the lease
+	mr.FastForward(ReservationLease)
+	r, err := rs.Reserve("alice", "k1", "d1")
+	if err != nil || r == nil || r.JobID != "job-1" {
+		t.Fatalf("expect the committed job but got %v, %v", r, err)
+	}
+
+	if err := rs.Release("alice", "k1"); err != nil {
+		t.Fatalf("release key error: %s", err)
+	}
+	if r, err := rs.Reserve("alice", "k1", "d2"); err != nil || r != nil {
+		t.Errorf("expect the released key reserved again but got %v, %v", r, err)
+	}
+}
+
+func TestLease(t *testing.T) {
+	if lease := Lease(time.Second); lease != time.Second {
+		t.Errorf("expect the lease not longer than the window but got %v", lease)
+	}
+	if lease := Lease(DefaultWindow); lease != ReservationLease {
+		t.Errorf("expect lease %v but got %v", ReservationLease, lease)
+	}
+}
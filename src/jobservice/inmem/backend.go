@@ -0,0 +1,82 @@
+// Package inmem provides the job service backend running in the process memory.
+// All the components share one Store, no redis is required. The data is lost
+// once the process exits, so it's designed for the single node deployment and
+// for testing the jobs.
+//
+// The rate limiter and the idempotency store are kept in memory as well. The admission
+// control is not supported as it's based on the redis queues and the node heartbeats,
+// the configuration with admission is rejected for this backend.
+package inmem
+
+import (
+	"github.com/chenxull/goGridhub/gridhub/src/jobservice/config"
+	"github.com/chenxull/goGridhub/gridhub/src/jobservice/env"
+	"github.com/chenxull/goGridhub/gridhub/src/jobservice/hook"
+	"github.com/chenxull/goGridhub/gridhub/src/jobservice/idempotency"
+	"github.com/chenxull/goGridhub/gridhub/src/jobservice/lcm"
+	"github.com/chenxull/goGridhub/gridhub/src/jobservice/mgt"
+	"github.com/chenxull/goGridhub/gridhub/src/jobservice/node"
+	"github.com/chenxull/goGridhub/gridhub/src/jobservice/ratelimit"
+	"github.com/chenxull/goGridhub/gridhub/src/jobservice/worker"
+	"github.com/pkg/errors"
+	"time"
+)
+
+// Backend bundles the in-memory components which implement the same interfaces as the redis ones.
+type Backend struct {
+	Store      *Store
+	HookAgent  hook.Agent
+	Controller lcm.Controller
+	Manager    mgt.Manager
+	Worker     worker.Interface
+	Registry   node.Registry
+
+	// Latest status change events for the event stream
+	EventLog hook.EventLog
+
+	Limiter     ratelimit.Limiter
+	Idempotency idempotency.Store
+}
+
+// NewBackend creates the in-memory components sharing the same store
+func NewBackend(ctx *env.Context, workerCount uint, rateLimits *config.RateLimitConfig, idemWindow time.Duration) *Backend {
+	if workerCount == 0 {
+		workerCount = defaultWorkerCount
+	}
+
+	store := NewStore()
+	hookAgent := NewAgent(ctx)
+	eventLog := NewEventLog()
+	ctl := NewController(ctx, store, hook.NewCallback(hookAgent, eventLog))
+
+	return &Backend{
+		Store:       store,
+		HookAgent:   hookAgent,
+		Controller:  ctl,
+		Manager:     NewManager(ctx.SystemContext, store),
+		Worker:      NewWorker(ctx, workerCount, store, ctl),
+		Registry:    NewRegistry(ctx.SystemContext, workerCount, store),
+		EventLog:    eventLog,
+		Limiter:     NewLimiter(rateLimits),
+		Idempotency: NewIdempotencyStore(idemWindow),
+	}
+}
+
+// Start the worker, the life cycle controller, the hook agent and the registry.
+// Non blocking call
+func (b *Backend) Start() error {
+	if err := b.Worker.Start(); err != nil {
+		return errors.Errorf("start memory worker error: %s", err)
+	}
+
+	if err := b.Controller.Serve(); err != nil {
+		return errors.Errorf("start life cycle controller error: %s", err)
+	}
+
+	b.HookAgent.Attach(b.Controller)
+	if err := b.HookAgent.Serve(); err != nil {
+		return errors.Errorf("start hook agent error: %s", err)
+	}
+
+	return b.Registry.Serve()
+}
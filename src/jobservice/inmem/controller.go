@@ -0,0 +1,93 @@
+package inmem
+
+import (
+	"context"
+	"github.com/chenxull/goGridhub/gridhub/src/jobservice/env"
+	"github.com/chenxull/goGridhub/gridhub/src/jobservice/job"
+	"github.com/chenxull/goGridhub/gridhub/src/jobservice/lcm"
+	"github.com/chenxull/goGridhub/gridhub/src/jobservice/logger"
+	"github.com/pkg/errors"
+	"sync"
+	"time"
+)
+
+// Interval of clearing the expired job stats
+const clearExpiredInterval = 5 * time.Minute
+
+// memoryController is the implementation of lcm.Controller based on the in-memory store
+type memoryController struct {
+	context  context.Context
+	store    *Store
+	callback job.HookCallback
+	wg       *sync.WaitGroup
+}
+
+// NewController is constructor of memoryController
+func NewController(ctx *env.Context, store *Store, callback job.HookCallback) lcm.Controller {
+	return &memoryController{
+		context:  ctx.SystemContext,
+		store:    store,
+		callback: callback,
+		wg:       ctx.WG,
+	}
+}
+
+// Serve is implementation of lcm.Controller.Serve
+func (mc *memoryController) Serve() error {
+	mc.wg.Add(1)
+	go mc.loopForClearExpired()
+
+	logger.Info("Expired job stats clearing loop is started")
+
+	return nil
+}
+
+// New is implementation of lcm.Controller.New
+func (mc *memoryController) New(stats *job.Stats) (job.Tracker, error) {
+	if stats == nil {
+		return nil, errors.New("nil stats when creating job tracker")
+	}
+
+	if err := stats.Validate(); err != nil {
+		return nil, errors.Errorf("error occurred when creating job tracker: %s", err)
+	}
+
+	t := NewTrackerWithStats(mc.context, stats, mc.store, mc.callback)
+	if err := t.Save(); err != nil {
+		return nil, err
+	}
+
+	return t, nil
+}
+
+// Track is implementation of lcm.Controller.Track
+func (mc *memoryController) Track(jobID string) (job.Tracker, error) {
+	t := NewTrackerWithID(mc.context, jobID, mc.store, mc.callback)
+	if err := t.Load(); err != nil {
+		return nil, err
+	}
+
+	return t, nil
+}
+
+// loopForClearExpired removes the expired job stats periodically as redis does with the TTL
+func (mc *memoryController) loopForClearExpired() {
+	defer func() {
+		logger.Info("Expired job stats clearing loop is stopped")
+		mc.wg.Done()
+	}()
+
+	ticker := time.NewTicker(clearExpiredInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			if n := mc.store.clearExpired(); n > 0 {
+				logger.Debugf("%d expired job stats are cleared", n)
+			}
+		case <-mc.context.Done():
+			return
+		}
+	}
+}
@@ -0,0 +1,105 @@
+package inmem
+
+import (
+	"fmt"
+	"github.com/chenxull/goGridhub/gridhub/src/jobservice/errs"
+	"github.com/chenxull/goGridhub/gridhub/src/jobservice/job"
+	"github.com/chenxull/goGridhub/gridhub/src/jobservice/lcm"
+	"github.com/chenxull/goGridhub/gridhub/src/jobservice/worker/cworker"
+	"sync"
+	"time"
+)
+
+// uniqueSign is the reserved sign with the bound job
+type uniqueSign struct {
+	// Empty means the job is being enqueued
+	jobID    string
+	expireAt time.Time
+}
+
+// memoryDeDuplicator is the implementation of cworker.DeDuplicator based on the process memory
+type memoryDeDuplicator struct {
+	ctl   lcm.Controller
+	lock  sync.Mutex
+	signs map[string]*uniqueSign
+}
+
+// NewDeDuplicator is constructor of memoryDeDuplicator
+func NewDeDuplicator(ctl lcm.Controller) cworker.DeDuplicator {
+	return &memoryDeDuplicator{
+		ctl:   ctl,
+		signs: make(map[string]*uniqueSign),
+	}
+}
+
+// MustUnique is implementation of cworker.DeDuplicator.MustUnique
+func (md *memoryDeDuplicator) MustUnique(jobName string, params job.Parameters, opts *job.UniqueOptions) (string, error) {
+	sign, err := cworker.UniqueSign(jobName, params, opts)
+	if err != nil {
+		return "", err
+	}
+
+	md.lock.Lock()
+	defer md.lock.Unlock()
+
+	existing, ok := md.signs[sign]
+	if ok && existing.expireAt.After(time.Now()) {
+		if len(existing.jobID) == 0 {
+			return "", errs.ConflictError(fmt.Sprintf("unique job %s being enqueued", jobName))
+		}
+
+		t, err := md.ctl.Track(existing.jobID)
+		if err != nil && !errs.IsObjectNotFoundError(err) {
+			return "", err
+		}
+
+		// The sign is taken over if the bound job is gone or done
+		if err == nil && !job.Status(t.Job().Info.Status).Final() {
+			return "", errs.ConflictErrorWithData(fmt.Sprintf("unique job %s:%s", jobName, existing.jobID), t.Job())
+		}
+	}
+
+	md.signs[sign] = &uniqueSign{
+		expireAt: time.Now().Add(ttl(opts)),
+	}
+
+	return sign, nil
+}
+
+// Bind is implementation of cworker.DeDuplicator.Bind
+func (md *memoryDeDuplicator) Bind(sign string, jobID string, opts *job.UniqueOptions) error {
+	md.lock.Lock()
+	defer md.lock.Unlock()
+
+	md.signs[sign] = &uniqueSign{
+		jobID:    jobID,
+		expireAt: time.Now().Add(ttl(opts)),
+	}
+	md.clearExpired()
+
+	return nil
+}
+
+// DelUniqueSign is implementation of cworker.DeDuplicator.DelUniqueSign
+func (md *memoryDeDuplicator) DelUniqueSign(sign string) error {
+	md.lock.Lock()
+	defer md.lock.Unlock()
+
+	delete(md.signs, sign)
+
+	return nil
+}
+
+// clearExpired should be called with the lock held
+func (md *memoryDeDuplicator) clearExpired() {
+	now := time.Now()
+	for sign, us := range md.signs {
+		if !us.expireAt.After(now) {
+			delete(md.signs, sign)
+		}
+	}
+}
+
+func ttl(opts *job.UniqueOptions) time.Duration {
+	return time.Duration(cworker.UniqueTTL(opts)) * time.Second
+}
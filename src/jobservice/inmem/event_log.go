@@ -0,0 +1,120 @@
+package inmem
+
+import (
+	"encoding/json"
+	"github.com/chenxull/goGridhub/gridhub/src/jobservice/errs"
+	"github.com/chenxull/goGridhub/gridhub/src/jobservice/hook"
+	"github.com/chenxull/goGridhub/gridhub/src/jobservice/job"
+	"github.com/pkg/errors"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// loggedEvent keeps the serialized status change, so the later changes of the job stats are not leaked
+type loggedEvent struct {
+	seq     uint64
+	rawJSON []byte
+}
+
+// memoryEventLog is the implementation of hook.EventLog keeping the latest events in memory.
+// The ID of the event is the sequence number of it.
+type memoryEventLog struct {
+	lock   sync.Mutex
+	seq    uint64
+	events []*loggedEvent
+	// Closed and replaced once new event is appended to wake up the readers
+	appended chan struct{}
+}
+
+// NewEventLog is constructor of memoryEventLog
+func NewEventLog() hook.EventLog {
+	return &memoryEventLog{
+		events:   make([]*loggedEvent, 0),
+		appended: make(chan struct{}),
+	}
+}
+
+// Append is implementation of hook.EventLog.Append
+func (ml *memoryEventLog) Append(change *job.StatusChange) (string, error) {
+	rawJSON, err := json.Marshal(change)
+	if err != nil {
+		return "", err
+	}
+
+	ml.lock.Lock()
+	defer ml.lock.Unlock()
+
+	ml.seq++
+	if len(ml.events) >= hook.MaxLoggedEvents {
+		ml.events = ml.events[1:]
+	}
+	ml.events = append(ml.events, &loggedEvent{
+		seq:     ml.seq,
+		rawJSON: rawJSON,
+	})
+
+	close(ml.appended)
+	ml.appended = make(chan struct{})
+
+	return strconv.FormatUint(ml.seq, 10), nil
+}
+
+// Latest is implementation of hook.EventLog.Latest
+func (ml *memoryEventLog) Latest() (string, error) {
+	ml.lock.Lock()
+	defer ml.lock.Unlock()
+
+	return strconv.FormatUint(ml.seq, 10), nil
+}
+
+// Read is implementation of hook.EventLog.Read
+func (ml *memoryEventLog) Read(lastID string, wait time.Duration) ([]*hook.StatusEvent, error) {
+	last, err := strconv.ParseUint(lastID, 10, 64)
+	if err != nil {
+		return nil, errs.BadRequestError(errors.Errorf("invalid event ID: %s", lastID))
+	}
+
+	evts, appended, err := ml.after(last)
+	if err != nil || len(evts) > 0 || wait <= 0 {
+		return evts, err
+	}
+
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+
+	select {
+	case <-appended:
+		evts, _, err = ml.after(last)
+		return evts, err
+	case <-timer.C:
+		return nil, nil
+	}
+}
+
+// after returns the events after the sequence number and the channel notifying the next appending
+func (ml *memoryEventLog) after(last uint64) ([]*hook.StatusEvent, chan struct{}, error) {
+	ml.lock.Lock()
+	defer ml.lock.Unlock()
+
+	evts := make([]*hook.StatusEvent, 0)
+	for _, e := range ml.events {
+		if e.seq <= last {
+			continue
+		}
+
+		change := &job.StatusChange{}
+		if err := json.Unmarshal(e.rawJSON, change); err != nil {
+			return nil, nil, err
+		}
+		evts = append(evts, &hook.StatusEvent{
+			ID:     strconv.FormatUint(e.seq, 10),
+			Change: change,
+		})
+		if len(evts) >= hook.MaxReadEvents {
+			break
+		}
+	}
+
+	return evts, ml.appended, nil
+}
@@ -0,0 +1,194 @@
+package inmem
+
+import (
+	"context"
+	"github.com/chenxull/goGridhub/gridhub/src/jobservice/env"
+	"github.com/chenxull/goGridhub/gridhub/src/jobservice/hook"
+	"github.com/chenxull/goGridhub/gridhub/src/jobservice/job"
+	"github.com/chenxull/goGridhub/gridhub/src/jobservice/lcm"
+	"github.com/chenxull/goGridhub/gridhub/src/jobservice/logger"
+	"github.com/pkg/errors"
+	"sync"
+	"time"
+)
+
+const (
+	// Influenced by the worker number setting
+	maxEventChanBuffer = 1024
+	// Max concurrent client handlers
+	maxHandlers = 5
+	// The max time for expiring the retrying events
+	// 180 days
+	maxEventExpireTime = 3600 * 24 * 180
+	// The oldest events are dropped if the retrying queue is full
+	maxRetryingEvents = 10240
+	// Interval of resending the failed events
+	retryInterval = 5 * time.Second
+)
+
+// memoryAgent is the implementation of hook.Agent which keeps the retrying events in memory
+type memoryAgent struct {
+	context context.Context
+	client  hook.Client
+	ctl     lcm.Controller
+	events  chan *hook.Event
+	tokens  chan bool
+	wg      *sync.WaitGroup
+	// Protect the retrying queue
+	lock     sync.Mutex
+	retrying []*hook.Event
+}
+
+// NewAgent is constructor of memoryAgent
+func NewAgent(ctx *env.Context) hook.Agent {
+	tks := make(chan bool, maxHandlers)
+	// Put tokens
+	for i := 0; i < maxHandlers; i++ {
+		tks <- true
+	}
+
+	return &memoryAgent{
+		context:  ctx.SystemContext,
+		client:   hook.NewClient(ctx.SystemContext),
+		events:   make(chan *hook.Event, maxEventChanBuffer),
+		tokens:   tks,
+		wg:       ctx.WG,
+		retrying: make([]*hook.Event, 0),
+	}
+}
+
+// Trigger is implementation of hook.Agent.Trigger
+func (ma *memoryAgent) Trigger(evt *hook.Event) error {
+	if evt == nil {
+		return errors.New("nil event")
+	}
+
+	if err := evt.Validate(); err != nil {
+		return err
+	}
+
+	ma.events <- evt
+
+	return nil
+}
+
+// Attach is implementation of hook.Agent.Attach
+func (ma *memoryAgent) Attach(ctl lcm.Controller) {
+	ma.ctl = ctl
+}
+
+// Serve is implementation of hook.Agent.Serve
+func (ma *memoryAgent) Serve() error {
+	if ma.ctl == nil {
+		return errors.New("nil life cycle controller of hook agent")
+	}
+
+	ma.wg.Add(1)
+	go ma.loopRetry()
+	logger.Info("Hook event retrying loop is started")
+
+	ma.wg.Add(1)
+	go ma.serve()
+	logger.Info("Memory hook agent is started")
+
+	return nil
+}
+
+func (ma *memoryAgent) serve() {
+	defer func() {
+		logger.Info("Memory hook agent is stopped")
+		ma.wg.Done()
+	}()
+
+	for {
+		select {
+		case evt := <-ma.events:
+			<-ma.tokens
+
+			go func(evt *hook.Event) {
+				defer func() {
+					ma.tokens <- true // return token
+				}()
+
+				if err := ma.client.SendEvent(evt); err != nil {
+					logger.Errorf("Send hook event '%s' to '%s' failed with error: %s; push to the queue for retrying later", evt.Message, evt.URL, err)
+					ma.pushForRetry(evt)
+				}
+			}(evt)
+		case <-ma.context.Done():
+			return
+		}
+	}
+}
+
+func (ma *memoryAgent) pushForRetry(evt *hook.Event) {
+	if evt.Timestamp > 0 && time.Now().Unix()-evt.Timestamp >= maxEventExpireTime {
+		// Expired, do not need to push back to the retry queue
+		logger.Warningf("Event is expired: %s", evt.Message)
+		return
+	}
+
+	ma.lock.Lock()
+	defer ma.lock.Unlock()
+
+	if len(ma.retrying) >= maxRetryingEvents {
+		logger.Warningf("Hook event retrying queue is full, drop the oldest event: %s", ma.retrying[0].Message)
+		ma.retrying = ma.retrying[1:]
+	}
+	ma.retrying = append(ma.retrying, evt)
+}
+
+func (ma *memoryAgent) loopRetry() {
+	defer func() {
+		logger.Info("Hook event retrying loop exit")
+		ma.wg.Done()
+	}()
+
+	ticker := time.NewTicker(retryInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			for _, evt := range ma.popAll() {
+				if err := ma.reSend(evt); err != nil {
+					logger.Errorf("Resend hook event error: %s", err)
+				}
+			}
+		case <-ma.context.Done():
+			return
+		}
+	}
+}
+
+// reSend puts the event back to the sending queue if it's not outdated
+func (ma *memoryAgent) reSend(evt *hook.Event) error {
+	if evt.Data == nil || len(evt.Data.JobID) == 0 {
+		return errors.New("malform job status change data")
+	}
+
+	t, err := ma.ctl.Track(evt.Data.JobID)
+	if err != nil {
+		return err
+	}
+
+	// The event is outdated if the job has moved on or checked in again
+	diff := job.Status(evt.Data.Status).Compare(job.Status(t.Job().Info.Status))
+	if diff > 0 ||
+		(diff == 0 && t.Job().Info.CheckIn == evt.Data.CheckIn) {
+		ma.events <- evt
+		return nil
+	}
+
+	return errors.Errorf("outdated hook event: %s, latest job status: %s", evt.Message, t.Job().Info.Status)
+}
+
+func (ma *memoryAgent) popAll() []*hook.Event {
+	ma.lock.Lock()
+	defer ma.lock.Unlock()
+
+	evts := ma.retrying
+	ma.retrying = make([]*hook.Event, 0)
+
+	return evts
+}
@@ -0,0 +1,91 @@
+package inmem
+
+import (
+	"github.com/chenxull/goGridhub/gridhub/src/jobservice/idempotency"
+	"sync"
+	"time"
+)
+
+// idemEntry is the idempotency record with its expiration
+type idemEntry struct {
+	record   idempotency.Record
+	expireAt time.Time
+}
+
+// memoryIdempotencyStore is the implementation of idempotency.Store based on the process memory
+type memoryIdempotencyStore struct {
+	window  time.Duration
+	lock    sync.Mutex
+	entries map[string]*idemEntry
+}
+
+// NewIdempotencyStore is constructor of memoryIdempotencyStore
+func NewIdempotencyStore(window time.Duration) idempotency.Store {
+	if window <= 0 {
+		window = idempotency.DefaultWindow
+	}
+
+	return &memoryIdempotencyStore{
+		window:  window,
+		entries: make(map[string]*idemEntry),
+	}
+}
+
+// Reserve is implementation of idempotency.Store.Reserve
+func (ms *memoryIdempotencyStore) Reserve(caller string, key string, digest string) (*idempotency.Record, error) {
+	ms.lock.Lock()
+	defer ms.lock.Unlock()
+
+	now := time.Now()
+	ms.purge(now)
+
+	k := idemKey(caller, key)
+	if e, ok := ms.entries[k]; ok {
+		r := e.record
+		return &r, nil
+	}
+
+	ms.entries[k] = &idemEntry{
+		record:   idempotency.Record{Digest: digest},
+		expireAt: now.Add(idempotency.Lease(ms.window)),
+	}
+
+	// Reserved
+	return nil, nil
+}
+
+// Commit is implementation of idempotency.Store.Commit
+func (ms *memoryIdempotencyStore) Commit(caller string, key string, digest string, jobID string) error {
+	ms.lock.Lock()
+	defer ms.lock.Unlock()
+
+	ms.entries[idemKey(caller, key)] = &idemEntry{
+		record:   idempotency.Record{Digest: digest, JobID: jobID},
+		expireAt: time.Now().Add(ms.window),
+	}
+
+	return nil
+}
+
+// Release is implementation of idempotency.Store.Release
+func (ms *memoryIdempotencyStore) Release(caller string, key string) error {
+	ms.lock.Lock()
+	defer ms.lock.Unlock()
+
+	delete(ms.entries, idemKey(caller, key))
+
+	return nil
+}
+
+// purge drops the expired records
+func (ms *memoryIdempotencyStore) purge(now time.Time) {
+	for k, e := range ms.entries {
+		if now.After(e.expireAt) {
+			delete(ms.entries, k)
+		}
+	}
+}
+
+func idemKey(caller string, key string) string {
+	return caller + ":" + key
+}
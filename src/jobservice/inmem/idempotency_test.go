@@ -0,0 +1,47 @@
+package inmem
+
+import (
+	"github.com/chenxull/goGridhub/gridhub/src/jobservice/idempotency"
+	"testing"
+	"time"
+)
+
+func TestMemoryIdempotencyStore(t *testing.T) {
+	ms := NewIdempotencyStore(time.Hour).(*memoryIdempotencyStore)
+
+	if r, err := ms.Reserve("alice", "k1", "d1"); err != nil || r != nil {
+		t.Fatalf("expect the key reserved but got %v, %v", r, err)
+	}
+	e := ms.entries[idemKey("alice", "k1")]
+	if left := time.Until(e.expireAt); left > idempotency.ReservationLease {
+		t.Errorf("expect the reservation kept in %v but got %v", idempotency.ReservationLease, left)
+	}
+
+	// Being launched
+	if r, err := ms.Reserve("alice", "k1", "d1"); err != nil || r == nil || len(r.JobID) > 0 {
+		t.Fatalf("expect the reservation without job but got %v, %v", r, err)
+	}
+
+	// Crashed before committing, the key can be retried after the lease
+	e.expireAt = time.Now().Add(-time.Millisecond)
+	if r, err := ms.Reserve("alice", "k1", "d1"); err != nil || r != nil {
+		t.Fatalf("expect the expired reservation reserved again but got %v, %v", r, err)
+	}
+
+	if err := ms.Commit("alice", "k1", "d1", "job-1"); err != nil {
+		t.Fatalf("commit key error: %s", err)
+	}
+	if left := time.Until(ms.entries[idemKey("alice", "k1")].expireAt); left <= idempotency.ReservationLease {
+		t.Errorf("expect the committed key kept in the window but got %v", left)
+	}
+	if r, err := ms.Reserve("alice", "k1", "d1"); err != nil || r == nil || r.JobID != "job-1" {
+		t.Fatalf("expect the committed job but got %v, %v", r, err)
+	}
+
+	if err := ms.Release("alice", "k1"); err != nil {
+		t.Fatalf("release key error: %s", err)
+	}
+	if r, err := ms.Reserve("alice", "k1", "d2"); err != nil || r != nil {
+		t.Errorf("expect the released key reserved again but got %v, %v", r, err)
+	}
+}
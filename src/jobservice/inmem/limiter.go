@@ -0,0 +1,119 @@
+package inmem
+
+import (
+	"github.com/chenxull/goGridhub/gridhub/src/jobservice/config"
+	"github.com/chenxull/goGridhub/gridhub/src/jobservice/ratelimit"
+	"math"
+	"sync"
+	"time"
+)
+
+// bucket is the token bucket of the caller and job
+type bucket struct {
+	tokens float64
+	ts     time.Time
+}
+
+// memoryLimiter is the implementation of ratelimit.Limiter based on the process memory,
+// it follows the same token bucket algorithm as the redis one.
+type memoryLimiter struct {
+	*ratelimit.Rules
+	lock    sync.Mutex
+	buckets map[string]*bucket
+	// The counts of the rejections keyed by the time slot and then "caller:job"
+	rejections map[int64]map[string]int64
+}
+
+// NewLimiter is constructor of memoryLimiter
+func NewLimiter(cfg *config.RateLimitConfig) ratelimit.Limiter {
+	return &memoryLimiter{
+		Rules:      ratelimit.NewRules(cfg),
+		buckets:    make(map[string]*bucket),
+		rejections: make(map[int64]map[string]int64),
+	}
+}
+
+// Take is implementation of ratelimit.Limiter.Take
+func (ml *memoryLimiter) Take(caller string, jobName string) (bool, time.Duration, error) {
+	rule := ml.Match(caller, jobName)
+	if rule == nil {
+		// No limit
+		return true, 0, nil
+	}
+
+	ml.lock.Lock()
+	defer ml.lock.Unlock()
+
+	now := time.Now()
+	burst := float64(rule.Burst)
+	ml.purge(now)
+
+	key := caller + ":" + jobName
+	b, ok := ml.buckets[key]
+	if !ok {
+		b = &bucket{tokens: burst, ts: now}
+		ml.buckets[key] = b
+	} else if now.After(b.ts) {
+		b.tokens = math.Min(burst, b.tokens+now.Sub(b.ts).Seconds()*rule.Rate)
+	}
+	b.ts = now
+
+	if b.tokens >= 1 {
+		b.tokens--
+		return true, 0, nil
+	}
+
+	ml.reject(now, key)
+	wait := time.Duration(math.Ceil((1 - b.tokens) * 1000 / rule.Rate))
+
+	return false, wait * time.Millisecond, nil
+}
+
+// Rejections is implementation of ratelimit.Limiter.Rejections
+func (ml *memoryLimiter) Rejections() (map[string]int64, error) {
+	ml.lock.Lock()
+	defer ml.lock.Unlock()
+
+	ml.purge(time.Now())
+
+	values := make(map[string]int64)
+	for _, counts := range ml.rejections {
+		for k, v := range counts {
+			values[k] += v
+		}
+	}
+
+	return values, nil
+}
+
+// reject counts the rejection in the current time slot, the same way as the redis one
+func (ml *memoryLimiter) reject(now time.Time, key string) {
+	slot := ratelimit.RejectionSlotOf(now)
+	counts, ok := ml.rejections[slot]
+	if !ok {
+		counts = make(map[string]int64)
+		ml.rejections[slot] = counts
+	}
+
+	if _, ok := counts[key]; !ok && len(counts) >= ratelimit.MaxRejectionPairs {
+		key = ratelimit.OtherRejections
+	}
+	counts[key]++
+}
+
+// purge drops the buckets not touched for a while, they are full again after refilling.
+// The rejections out of the recent time slots are dropped too.
+func (ml *memoryLimiter) purge(now time.Time) {
+	for k, b := range ml.buckets {
+		if now.Sub(b.ts) > time.Hour {
+			delete(ml.buckets, k)
+		}
+	}
+
+	oldest := ratelimit.RejectionSlotOf(now) - ratelimit.RejectionSlots + 1
+	for slot := range ml.rejections {
+		if slot < oldest {
+			delete(ml.rejections, slot)
+		}
+	}
+}
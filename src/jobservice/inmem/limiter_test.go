@@ -0,0 +1,79 @@
+package inmem
+
+import (
+	"fmt"
+	"github.com/chenxull/goGridhub/gridhub/src/jobservice/config"
+	"github.com/chenxull/goGridhub/gridhub/src/jobservice/ratelimit"
+	"testing"
+	"time"
+)
+
+func TestMemoryLimiterTake(t *testing.T) {
+	ml := NewLimiter(&config.RateLimitConfig{
+		Rules: []*config.RateLimitRule{
+			{Caller: "fast", Rate: 200, Burst: 1},
+		},
+		Default: &config.RateLimitRule{Rate: 1, Burst: 2},
+	})
+
+	for i, expected := range []bool{true, true, false} {
+		ok, wait, err := ml.Take("alice", "SCAN")
+		if err != nil || ok != expected {
+			t.Fatalf("take %d: expect %v but got %v, %v", i, expected, ok, err)
+		}
+		if !ok && (wait <= 0 || wait > time.Second) {
+			t.Errorf("expect wait in (0, 1s] but got %v", wait)
+		}
+	}
+
+	if ok, _, _ := ml.Take("fast", "SCAN"); !ok {
+		t.Error("expect the first take allowed")
+	}
+	if ok, _, _ := ml.Take("fast", "SCAN"); ok {
+		t.Error("expect the take beyond burst rejected")
+	}
+	time.Sleep(20 * time.Millisecond)
+	if ok, _, _ := ml.Take("fast", "SCAN"); !ok {
+		t.Error("expect the take allowed after refilling")
+	}
+
+	rejections, err := ml.Rejections()
+	if err != nil {
+		t.Fatalf("get rejections error: %s", err)
+	}
+	if rejections["alice:SCAN"] != 1 || rejections["fast:SCAN"] != 1 || len(rejections) != 2 {
+		t.Errorf("expect rejections map[alice:SCAN:1 fast:SCAN:1] but got %v", rejections)
+	}
+}
+
+func TestMemoryLimiterRejections(t *testing.T) {
+	ml := NewLimiter(&config.RateLimitConfig{
+		Default: &config.RateLimitRule{Rate: 0.001, Burst: 1},
+	}).(*memoryLimiter)
+
+	now := time.Now()
+	current := ratelimit.RejectionSlotOf(now)
+	counts := make(map[string]int64, ratelimit.MaxRejectionPairs)
+	for i := 0; i < ratelimit.MaxRejectionPairs; i++ {
+		counts[fmt.Sprintf("caller-%d:SCAN", i)] = 1
+	}
+	ml.rejections[current] = counts
+	ml.rejections[current-1] = map[string]int64{"caller-0:SCAN": 5}
+	ml.rejections[current-ratelimit.RejectionSlots] = map[string]int64{"caller-0:SCAN": 100}
+
+	for _, caller := range []string{"caller-0", "new", "caller-0", "new"} {
+		_, _, _ = ml.Take(caller, "SCAN")
+	}
+
+	rejections, err := ml.Rejections()
+	if err != nil {
+		t.Fatalf("get rejections error: %s", err)
+	}
+	if rejections["caller-0:SCAN"] != 7 || rejections[ratelimit.OtherRejections] != 1 || rejections["new:SCAN"] != 0 {
+		t.Errorf("expect the expired slot dropped and the new pair counted under %s but got %d, %d, %d",
+			ratelimit.OtherRejections, rejections["caller-0:SCAN"], rejections[ratelimit.OtherRejections], rejections["new:SCAN"])
+	}
+	if _, ok := ml.rejections[current-ratelimit.RejectionSlots]; ok {
+		t.Error("expect the rejections out of the recent slots purged")
+	}
+}
@@ -0,0 +1,168 @@
+package inmem
+
+import (
+	"context"
+	"github.com/chenxull/goGridhub/gridhub/src/jobservice/common/query"
+	"github.com/chenxull/goGridhub/gridhub/src/jobservice/common/utils"
+	"github.com/chenxull/goGridhub/gridhub/src/jobservice/errs"
+	"github.com/chenxull/goGridhub/gridhub/src/jobservice/job"
+	"github.com/chenxull/goGridhub/gridhub/src/jobservice/mgt"
+	"github.com/pkg/errors"
+)
+
+// memoryManager is the implementation of mgt.Manager based on the in-memory store
+type memoryManager struct {
+	ctx   context.Context
+	store *Store
+}
+
+// NewManager is constructor of memoryManager
+func NewManager(ctx context.Context, store *Store) mgt.Manager {
+	return &memoryManager{
+		ctx:   ctx,
+		store: store,
+	}
+}
+
+// GetJobs is implementation of mgt.Manager.GetJobs.
+// The cursor is kept compatible with the redis manager, 0 is returned when all the jobs are fetched.
+// The cursor is the position among the jobs filtered by name if the name filter is set.
+func (mm *memoryManager) GetJobs(q *query.Parameter) ([]*job.Stats, int64, error) {
+	cursor, count := int64(0), int64(query.DefaultPageSize)
+	if q != nil {
+		if q.PageSize > 0 {
+			count = int64(q.PageSize)
+		}
+
+		if cur, ok := q.Extras.Get(query.ExtraParamKeyCursor); ok {
+			cursor = cur.(int64)
+		}
+	}
+
+	all := filterByName(mm.store.list(), mgt.JobNameFilter(q))
+	total := int64(len(all))
+	if cursor < 0 || cursor >= total {
+		return []*job.Stats{}, 0, nil
+	}
+
+	next := cursor + count
+	if next >= total {
+		return all[cursor:], 0, nil
+	}
+
+	return all[cursor:next], next, nil
+}
+
+// GetPeriodicExecution is implementation of mgt.Manager.GetPeriodicExecution
+func (mm *memoryManager) GetPeriodicExecution(pID string, q *query.Parameter) ([]*job.Stats, int64, error) {
+	if utils.IsEmptyStr(pID) {
+		return nil, 0, errors.New("nil periodic job ID")
+	}
+
+	p, err := mm.store.get(pID)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	if p.Info.JobKind != job.KindPeriodic {
+		return nil, 0, errors.Errorf("only periodic job has executions: %s kind is received", p.Info.JobKind)
+	}
+
+	nonStoppedOnly := false
+	if q != nil {
+		if v, ok := q.Extras.Get(query.ExtraParamKeyNonStoppedOnly); ok {
+			nonStoppedOnly, _ = v.(bool)
+		}
+	}
+
+	eIDs := mm.store.periodicExecutions(pID, nonStoppedOnly)
+	results := make([]*job.Stats, 0)
+	for _, eID := range paginate(eIDs, q) {
+		e, err := mm.store.get(eID)
+		if err != nil {
+			// Expired just now
+			continue
+		}
+		results = append(results, e)
+	}
+
+	return results, int64(len(eIDs)), nil
+}
+
+// GetScheduledJobs is implementation of mgt.Manager.GetScheduledJobs
+func (mm *memoryManager) GetScheduledJobs(q *query.Parameter) ([]*job.Stats, int64, error) {
+	ids := make([]string, 0)
+	scheduled := make(map[string]*job.Stats)
+	for _, s := range filterByName(mm.store.list(), mgt.JobNameFilter(q)) {
+		if job.Status(s.Info.Status) == job.ScheduledStatus {
+			ids = append(ids, s.Info.JobID)
+			scheduled[s.Info.JobID] = s
+		}
+	}
+
+	results := make([]*job.Stats, 0)
+	for _, id := range paginate(ids, q) {
+		results = append(results, scheduled[id])
+	}
+
+	return results, int64(len(ids)), nil
+}
+
+// GetJob is implementation of mgt.Manager.GetJob
+func (mm *memoryManager) GetJob(jobID string) (*job.Stats, error) {
+	if utils.IsEmptyStr(jobID) {
+		return nil, errs.BadRequestError("empty job ID")
+	}
+
+	return mm.store.get(jobID)
+}
+
+// SaveJob is implementation of mgt.Manager.SaveJob
+func (mm *memoryManager) SaveJob(j *job.Stats) error {
+	if j == nil {
+		return errs.BadRequestError("nil saving job stats")
+	}
+
+	return NewTrackerWithStats(mm.ctx, j, mm.store, nil).Save()
+}
+
+// filterByName keeps the jobs with the name, empty name keeps all the jobs
+func filterByName(list []*job.Stats, name string) []*job.Stats {
+	if len(name) == 0 {
+		return list
+	}
+
+	filtered := make([]*job.Stats, 0, len(list))
+	for _, st := range list {
+		if st.Info.JobName == name {
+			filtered = append(filtered, st)
+		}
+	}
+
+	return filtered
+}
+
+// paginate returns the items of the page specified by the query parameters
+func paginate(ids []string, q *query.Parameter) []string {
+	var pageNumber, pageSize uint = 1, query.DefaultPageSize
+	if q != nil {
+		if q.PageNumber > 0 {
+			pageNumber = q.PageNumber
+		}
+		if q.PageSize > 0 {
+			pageSize = q.PageSize
+		}
+	}
+
+	start := (pageNumber - 1) * pageSize
+	if start >= uint(len(ids)) {
+		return []string{}
+	}
+
+	end := start + pageSize
+	if end > uint(len(ids)) {
+		end = uint(len(ids))
+	}
+
+	return ids[start:end]
+}
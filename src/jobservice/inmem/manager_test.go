@@ -0,0 +1,79 @@
+package inmem
+
+import (
+	"context"
+	"fmt"
+	"github.com/chenxull/goGridhub/gridhub/src/jobservice/common/query"
+	"github.com/chenxull/goGridhub/gridhub/src/jobservice/job"
+	"testing"
+)
+
+func namedQuery(name string, pageNumber, pageSize uint, cursor int64) *query.Parameter {
+	q := &query.Parameter{
+		PageNumber: pageNumber,
+		PageSize:   pageSize,
+		Extras:     make(query.ExtraParameters),
+	}
+	q.Extras.Set(query.ExtraParamKeyJobName, name)
+	q.Extras.Set(query.ExtraParamKeyCursor, cursor)
+
+	return q
+}
+
+func TestMemoryManagerFilteredByName(t *testing.T) {
+	m := NewManager(context.Background(), NewStore())
+	for i := 0; i < 10; i++ {
+		name, status := "demo", job.PendingStatus
+		if i%2 == 0 {
+			name = "sample"
+		}
+		if i < 6 {
+			status = job.ScheduledStatus
+		}
+		err := m.SaveJob(&job.Stats{
+			Info: &job.StatsInfo{
+				JobID:   fmt.Sprintf("job-%02d", i),
+				JobName: name,
+				JobKind: job.KindGeneric,
+				Status:  status.String(),
+			},
+		})
+		if err != nil {
+			t.Fatalf("save job error: %s", err)
+		}
+	}
+
+	// The pages are cut from the jobs named sample: 00, 02, 04, 06, 08
+	cases := []struct {
+		cursor int64
+		ids    []string
+		next   int64
+	}{
+		{cursor: 0, ids: []string{"job-00", "job-02"}, next: 2},
+		{cursor: 2, ids: []string{"job-04", "job-06"}, next: 4},
+		{cursor: 4, ids: []string{"job-08"}, next: 0},
+	}
+	for _, c := range cases {
+		jobs, next, err := m.GetJobs(namedQuery("sample", 0, 2, c.cursor))
+		if err != nil {
+			t.Fatalf("get jobs error: %s", err)
+		}
+		if next != c.next || len(jobs) != len(c.ids) {
+			t.Fatalf("cursor %d: expect %d jobs and the next cursor %d but got %d and %d", c.cursor, len(c.ids), c.next, len(jobs), next)
+		}
+		for i, st := range jobs {
+			if st.Info.JobID != c.ids[i] {
+				t.Errorf("cursor %d: expect %s but got %s", c.cursor, c.ids[i], st.Info.JobID)
+			}
+		}
+	}
+
+	// The scheduled ones named sample: 00, 02, 04
+	jobs, total, err := m.GetScheduledJobs(namedQuery("sample", 2, 2, 0))
+	if err != nil {
+		t.Fatalf("get scheduled jobs error: %s", err)
+	}
+	if total != 3 || len(jobs) != 1 || jobs[0].Info.JobID != "job-04" {
+		t.Errorf("expect the second page with job-04 of 3 scheduled jobs but got %d of %d", len(jobs), total)
+	}
+}
@@ -0,0 +1,133 @@
+package inmem
+
+import (
+	"github.com/gocraft/work"
+	"sync"
+)
+
+// scheduledJob is the job waiting to be moved to the pending queue
+type scheduledJob struct {
+	runAt int64
+	job   *work.Job
+}
+
+// queue keeps the pending jobs and the scheduled jobs in memory
+type queue struct {
+	lock      sync.Mutex
+	pending   []*work.Job
+	scheduled []*scheduledJob
+	// Notify the consumers that new jobs are pending
+	notify chan bool
+}
+
+func newQueue() *queue {
+	return &queue{
+		pending:   make([]*work.Job, 0),
+		scheduled: make([]*scheduledJob, 0),
+		notify:    make(chan bool, 1),
+	}
+}
+
+// push the job to the tail of the pending queue
+func (q *queue) push(j *work.Job) {
+	q.lock.Lock()
+	q.pending = append(q.pending, j)
+	q.lock.Unlock()
+
+	q.signal()
+}
+
+// pushAt puts the job to the scheduled queue to run at the specified time
+func (q *queue) pushAt(j *work.Job, runAt int64) {
+	q.lock.Lock()
+	defer q.lock.Unlock()
+
+	q.scheduled = append(q.scheduled, &scheduledJob{
+		runAt: runAt,
+		job:   j,
+	})
+}
+
+// pop the head of the pending queue, nil is returned if no jobs are pending
+func (q *queue) pop() *work.Job {
+	q.lock.Lock()
+	defer q.lock.Unlock()
+
+	if len(q.pending) == 0 {
+		return nil
+	}
+
+	j := q.pending[0]
+	q.pending[0] = nil
+	q.pending = q.pending[1:]
+
+	// Wake up the other consumers
+	if len(q.pending) > 0 {
+		q.signal()
+	}
+
+	return j
+}
+
+// moveDue moves the due scheduled jobs to the pending queue
+func (q *queue) moveDue(now int64) int {
+	q.lock.Lock()
+
+	left := make([]*scheduledJob, 0, len(q.scheduled))
+	moved := 0
+	for _, sj := range q.scheduled {
+		if sj.runAt <= now {
+			q.pending = append(q.pending, sj.job)
+			moved++
+			continue
+		}
+		left = append(left, sj)
+	}
+	q.scheduled = left
+
+	q.lock.Unlock()
+
+	if moved > 0 {
+		q.signal()
+	}
+
+	return moved
+}
+
+// removeScheduled removes the scheduled job, false is returned if it's not found
+func (q *queue) removeScheduled(jobID string, runAt int64) bool {
+	q.lock.Lock()
+	defer q.lock.Unlock()
+
+	for i, sj := range q.scheduled {
+		if sj.job.ID == jobID && sj.runAt == runAt {
+			q.scheduled = append(q.scheduled[:i], q.scheduled[i+1:]...)
+			return true
+		}
+	}
+
+	return false
+}
+
+// reschedule moves the scheduled job to the new run time, false is returned if it's not found
+func (q *queue) reschedule(jobID string, runAt int64, newRunAt int64) bool {
+	q.lock.Lock()
+	defer q.lock.Unlock()
+
+	for _, sj := range q.scheduled {
+		if sj.job.ID == jobID && sj.runAt == runAt {
+			sj.runAt = newRunAt
+			return true
+		}
+	}
+
+	return false
+}
+
+func (q *queue) signal() {
+	select {
+	case q.notify <- true:
+	default:
+		// Already notified
+	}
+}
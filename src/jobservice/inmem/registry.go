@@ -0,0 +1,105 @@
+package inmem
+
+import (
+	"context"
+	"fmt"
+	"github.com/chenxull/goGridhub/gridhub/src/jobservice/common/utils"
+	"github.com/chenxull/goGridhub/gridhub/src/jobservice/errs"
+	"github.com/chenxull/goGridhub/gridhub/src/jobservice/job"
+	"github.com/chenxull/goGridhub/gridhub/src/jobservice/node"
+	"sync"
+	"time"
+)
+
+// memoryRegistry is the implementation of node.Registry for the single node deployment.
+// The only member is the current node, which is always healthy while serving.
+type memoryRegistry struct {
+	nodeID      string
+	startedAt   int64
+	concurrency uint
+	store       *Store
+	// Protect the concurrency
+	lock sync.RWMutex
+}
+
+// NewRegistry is constructor of memoryRegistry
+func NewRegistry(ctx context.Context, concurrency uint, store *Store) node.Registry {
+	nodeID, _ := ctx.Value(utils.NodeID).(string)
+
+	return &memoryRegistry{
+		nodeID:      nodeID,
+		concurrency: concurrency,
+		store:       store,
+	}
+}
+
+// Serve is implementation of node.Registry.Serve
+func (mr *memoryRegistry) Serve() error {
+	mr.startedAt = time.Now().Unix()
+
+	return nil
+}
+
+// Nodes is implementation of node.Registry.Nodes
+func (mr *memoryRegistry) Nodes() ([]*node.Info, error) {
+	return []*node.Info{mr.info()}, nil
+}
+
+// Get is implementation of node.Registry.Get
+func (mr *memoryRegistry) Get(nodeID string) (*node.Info, error) {
+	if utils.IsEmptyStr(nodeID) {
+		return nil, errs.BadRequestError("empty node ID")
+	}
+
+	if nodeID != mr.nodeID {
+		return nil, errs.NoObjectFoundError(fmt.Sprintf("node %s", nodeID))
+	}
+
+	return mr.info(), nil
+}
+
+// Evict is implementation of node.Registry.Evict
+func (mr *memoryRegistry) Evict(nodeID string, action string, requeue node.RequeueFunc) (*node.ActionResult, error) {
+	if _, err := mr.Get(nodeID); err != nil {
+		return nil, err
+	}
+
+	// The current node can not be dead
+	return nil, errs.ConflictError(fmt.Sprintf("alive node %s", nodeID))
+}
+
+// SetConcurrency is implementation of node.Registry.SetConcurrency
+func (mr *memoryRegistry) SetConcurrency(concurrency uint) {
+	mr.lock.Lock()
+	defer mr.lock.Unlock()
+
+	mr.concurrency = concurrency
+}
+
+func (mr *memoryRegistry) info() *node.Info {
+	running := make([]string, 0)
+	for _, s := range mr.store.list() {
+		if s.Info.NodeID == mr.nodeID && job.Status(s.Info.Status) == job.RunningStatus {
+			running = append(running, s.Info.JobID)
+		}
+	}
+
+	mr.lock.RLock()
+	concurrency := mr.concurrency
+	mr.lock.RUnlock()
+
+	var load float64
+	if concurrency > 0 {
+		load = float64(len(running)) / float64(concurrency)
+	}
+
+	return &node.Info{
+		NodeID:      mr.nodeID,
+		StartedAt:   mr.startedAt,
+		HeartbeatAt: time.Now().Unix(),
+		Concurrency: concurrency,
+		RunningJobs: running,
+		Load:        load,
+		Status:      node.StatusHealthy,
+	}
+}
@@ -0,0 +1,256 @@
+package inmem
+
+import (
+	"context"
+	"fmt"
+	"github.com/chenxull/goGridhub/gridhub/src/jobservice/common/utils"
+	"github.com/chenxull/goGridhub/gridhub/src/jobservice/job"
+	"github.com/chenxull/goGridhub/gridhub/src/jobservice/lcm"
+	"github.com/chenxull/goGridhub/gridhub/src/jobservice/logger"
+	"github.com/chenxull/goGridhub/gridhub/src/jobservice/period"
+	"github.com/gocraft/work"
+	"github.com/pkg/errors"
+	"sync"
+	"time"
+)
+
+const (
+	// Same as the redis periodic enqueuer
+	enqueuerSleep   = 2 * time.Minute
+	enqueuerHorizon = 4 * time.Minute
+)
+
+// scheduledPolicy keeps the policy with its scheduling progress
+type scheduledPolicy struct {
+	policy    *period.Policy
+	numericID int64
+	// The executions before this time (unix seconds) have been scheduled
+	scheduledTo int64
+}
+
+// memoryScheduler is the implementation of period.Scheduler based on the process memory.
+// The current node is always the leader.
+type memoryScheduler struct {
+	context context.Context
+	nodeID  string
+	ctl     lcm.Controller
+	store   *Store
+	queue   *queue
+	// Protect the fields below
+	lock          sync.Mutex
+	policies      map[string]*scheduledPolicy
+	lastNumericID int64
+	// For stop
+	stopChan chan bool
+}
+
+// newScheduler is constructor of memoryScheduler
+func newScheduler(ctx context.Context, ctl lcm.Controller, store *Store, q *queue) *memoryScheduler {
+	nodeID, _ := ctx.Value(utils.NodeID).(string)
+
+	return &memoryScheduler{
+		context:  ctx,
+		nodeID:   nodeID,
+		ctl:      ctl,
+		store:    store,
+		queue:    q,
+		policies: make(map[string]*scheduledPolicy),
+		stopChan: make(chan bool, 1),
+	}
+}
+
+// Start is implementation of period.Scheduler.Start
+// Blocking call
+func (ms *memoryScheduler) Start() error {
+	defer func() {
+		logger.Info("Memory scheduler is stopped")
+	}()
+
+	logger.Info("Memory scheduler is started")
+
+	ticker := time.NewTicker(enqueuerSleep)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			ms.enqueue()
+		case <-ms.stopChan:
+			return nil
+		case <-ms.context.Done():
+			return nil
+		}
+	}
+}
+
+// Stop is implementation of period.Scheduler.Stop
+func (ms *memoryScheduler) Stop() error {
+	select {
+	case ms.stopChan <- true:
+	default:
+		// Already stopped
+	}
+
+	return nil
+}
+
+// Schedule is implementation of period.Scheduler.Schedule
+func (ms *memoryScheduler) Schedule(p *period.Policy) (int64, error) {
+	if p == nil {
+		return -1, errors.New("bad policy object: nil")
+	}
+
+	if err := p.Validate(); err != nil {
+		return -1, err
+	}
+
+	ms.lock.Lock()
+	defer ms.lock.Unlock()
+
+	// Keep the numeric ID unique as it's used to find the policy
+	pid := time.Now().Unix()
+	if pid <= ms.lastNumericID {
+		pid = ms.lastNumericID + 1
+	}
+	ms.lastNumericID = pid
+
+	sp := &scheduledPolicy{
+		policy:    p,
+		numericID: pid,
+	}
+	ms.policies[p.ID] = sp
+	ms.scheduleNextJobs(sp)
+
+	return pid, nil
+}
+
+// UnSchedule is implementation of period.Scheduler.UnSchedule
+func (ms *memoryScheduler) UnSchedule(policyID string) error {
+	if utils.IsEmptyStr(policyID) {
+		return errors.New("bad periodic job ID: nil")
+	}
+
+	tracker, err := ms.ctl.Track(policyID)
+	if err != nil {
+		return err
+	}
+
+	ms.lock.Lock()
+	_, ok := ms.policies[policyID]
+	delete(ms.policies, policyID)
+	ms.lock.Unlock()
+
+	if !ok {
+		return errors.Errorf("no valid periodic job policy found: %s", policyID)
+	}
+
+	if err := tracker.Expire(); err != nil {
+		logger.Error(err)
+	}
+
+	err = tracker.Stop()
+
+	// Stop the executions which are not done
+	for _, eID := range ms.store.periodicExecutions(policyID, true) {
+		eTracker, er := ms.ctl.Track(eID)
+		if er != nil {
+			logger.Errorf("Track execution %s error: %s", eID, er)
+			continue
+		}
+
+		e := eTracker.Job()
+		if job.ScheduledStatus == job.Status(e.Info.Status) {
+			ms.queue.removeScheduled(policyID, e.Info.RunAt)
+		}
+
+		if job.RunningStatus.Compare(job.Status(e.Info.Status)) >= 0 {
+			if er := eTracker.Stop(); er != nil {
+				logger.Errorf("Stop execution %s error: %s", eID, er)
+			}
+		}
+	}
+
+	return err
+}
+
+// Leader is implementation of period.Scheduler.Leader
+func (ms *memoryScheduler) Leader() (*period.Leader, error) {
+	return &period.Leader{
+		NodeID: ms.nodeID,
+	}, nil
+}
+
+// enqueue schedules the executions of all the policies in the next time slots
+func (ms *memoryScheduler) enqueue() {
+	ms.lock.Lock()
+	defer ms.lock.Unlock()
+
+	for _, sp := range ms.policies {
+		ms.scheduleNextJobs(sp)
+	}
+}
+
+// scheduleNextJobs should be called with the lock held
+func (ms *memoryScheduler) scheduleNextJobs(sp *scheduledPolicy) {
+	p := sp.policy
+	schedule, err := p.Timing()
+	if err != nil {
+		logger.Errorf("Invalid schedule in periodic policy %s %s: %s", p.JobName, p.ID, err)
+		return
+	}
+
+	nowTime := time.Unix(time.Now().Unix(), 0)
+	from := nowTime
+	if sp.scheduledTo > nowTime.Unix() {
+		from = time.Unix(sp.scheduledTo, 0)
+	}
+	horizon := nowTime.Add(enqueuerHorizon)
+
+	// The zero time means no more runs, e.g: all the one-off runs are passed
+	for t := schedule.Next(from); !t.IsZero() && t.Before(horizon); t = schedule.Next(t) {
+		epoch := t.Unix()
+
+		args := make(map[string]interface{}, len(p.JobParameters)+1)
+		for k, v := range p.JobParameters {
+			args[k] = v
+		}
+		args[period.PeriodicExecutionMark] = fmt.Sprintf("%d", epoch)
+
+		execution := createExecution(p, epoch)
+		if _, err := ms.ctl.New(execution); err != nil {
+			logger.Errorf("Save stats data of job execution '%s' error: %s", execution.Info.JobID, err)
+			return
+		}
+
+		ms.queue.pushAt(&work.Job{
+			Name:       p.JobName,
+			ID:         p.ID,
+			EnqueuedAt: epoch,
+			Args:       args,
+		}, epoch)
+		sp.scheduledTo = epoch
+
+		logger.Debugf("Scheduled execution for periodic job %s:%s at %d", p.JobName, p.ID, epoch)
+	}
+}
+
+// createExecution creates execution object
+func createExecution(p *period.Policy, runAt int64) *job.Stats {
+	eID := fmt.Sprintf("%s@%d", p.ID, runAt)
+
+	return &job.Stats{
+		Info: &job.StatsInfo{
+			JobID:         eID,
+			JobName:       p.JobName,
+			WebHookURL:    p.WebHookURL,
+			CronSpec:      p.CronSpec,
+			UpstreamJobID: p.ID,
+			RunAt:         runAt,
+			Status:        job.ScheduledStatus.String(),
+			JobKind:       job.KindScheduled, // For periodic job execution, it should be set to 'scheduled'
+			EnqueueTime:   time.Now().Unix(),
+			RefLink:       fmt.Sprintf("/api/v1/jobs/%s", eID),
+			Parameters:    p.JobParameters,
+		},
+	}
+}
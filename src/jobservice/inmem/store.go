@@ -0,0 +1,334 @@
+package inmem
+
+import (
+	"fmt"
+	"github.com/chenxull/goGridhub/gridhub/src/jobservice/common/utils"
+	"github.com/chenxull/goGridhub/gridhub/src/jobservice/errs"
+	"github.com/chenxull/goGridhub/gridhub/src/jobservice/job"
+	"github.com/pkg/errors"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Store keeps the job stats and the relations between the periodic jobs and their executions
+// in the process memory. It's shared by all the in-memory components.
+type Store struct {
+	lock sync.RWMutex
+	// key is the job ID
+	jobs map[string]*job.StatsInfo
+	// key is the job ID, value is the expire time (unix seconds)
+	expireAt map[string]int64
+	// key is the periodic job ID, value is the executions with their run time
+	// The score of the execution is set to -1 when it's done
+	executions map[string]map[string]int64
+}
+
+// NewStore is constructor of Store
+func NewStore() *Store {
+	return &Store{
+		jobs:       make(map[string]*job.StatsInfo),
+		expireAt:   make(map[string]int64),
+		executions: make(map[string]map[string]int64),
+	}
+}
+
+// save the job stats.
+// The existing job is not rolled back if its status is ahead, e.g: the job has been picked up
+// by the worker before the stats is saved. False is returned in that case.
+func (s *Store) save(info *job.StatsInfo) bool {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if existing, ok := s.jobs[info.JobID]; ok && !s.expired(info.JobID) {
+		if job.Status(existing.Status).Compare(job.Status(info.Status)) > 0 {
+			return false
+		}
+	}
+
+	copied := *info
+	s.jobs[info.JobID] = &copied
+	delete(s.expireAt, info.JobID)
+
+	if !utils.IsEmptyStr(info.UpstreamJobID) {
+		eIDs, ok := s.executions[info.UpstreamJobID]
+		if !ok {
+			eIDs = make(map[string]int64)
+			s.executions[info.UpstreamJobID] = eIDs
+		}
+		if _, ok := eIDs[info.JobID]; !ok {
+			eIDs[info.JobID] = info.RunAt
+		}
+	}
+
+	return true
+}
+
+// get a copy of the job stats
+func (s *Store) get(jobID string) (*job.Stats, error) {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+
+	info, ok := s.jobs[jobID]
+	if !ok || s.expired(jobID) {
+		return nil, errs.NoObjectFoundError(jobID)
+	}
+
+	copied := *info
+	return &job.Stats{Info: &copied}, nil
+}
+
+// update the properties of the job with the same field names used in the redis hash
+func (s *Store) update(jobID string, fieldAndValues ...interface{}) error {
+	if len(fieldAndValues)%2 != 0 {
+		return errors.New("mismatch fields and values")
+	}
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	info, ok := s.jobs[jobID]
+	if !ok || s.expired(jobID) {
+		return errs.NoObjectFoundError(jobID)
+	}
+
+	// Update a copy to avoid partial changes
+	copied := *info
+	for i := 0; i < len(fieldAndValues); i += 2 {
+		field, ok := fieldAndValues[i].(string)
+		if !ok {
+			return errors.Errorf("non string field: %v", fieldAndValues[i])
+		}
+		if err := setField(&copied, field, fieldAndValues[i+1]); err != nil {
+			return err
+		}
+	}
+	copied.UpdateTime = time.Now().Unix()
+	s.jobs[jobID] = &copied
+
+	return nil
+}
+
+// status returns the current status of the job
+func (s *Store) status(jobID string) (job.Status, error) {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+
+	info, ok := s.jobs[jobID]
+	if !ok || s.expired(jobID) {
+		return "", errs.NoObjectFoundError(jobID)
+	}
+
+	return job.Status(info.Status), nil
+}
+
+// compareAndSet switches the status only if the target status is not behind the current one
+func (s *Store) compareAndSet(jobID string, target job.Status) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	info, ok := s.jobs[jobID]
+	if !ok || s.expired(jobID) {
+		return errs.NoObjectFoundError(jobID)
+	}
+
+	current := job.Status(info.Status)
+	diff := current.Compare(target)
+	if diff > 0 {
+		return errs.StatusMismatchError(current.String(), target.String())
+	}
+	if diff == 0 {
+		// Desired matches actual
+		return nil
+	}
+
+	copied := *info
+	copied.Status = target.String()
+	copied.UpdateTime = time.Now().Unix()
+	s.jobs[jobID] = &copied
+
+	return nil
+}
+
+// expire the job stats after the specified seconds
+func (s *Store) expire(jobID string, seconds int64) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if _, ok := s.jobs[jobID]; !ok {
+		return errs.NoObjectFoundError(jobID)
+	}
+	s.expireAt[jobID] = time.Now().Unix() + seconds
+
+	return nil
+}
+
+// executionDone marks the execution of the periodic job done
+func (s *Store) executionDone(pID string, eID string) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	eIDs, ok := s.executions[pID]
+	if !ok {
+		return errs.NoObjectFoundError(pID)
+	}
+	if _, ok := eIDs[eID]; ok {
+		eIDs[eID] = -1
+	}
+
+	return nil
+}
+
+// periodicExecutions returns the IDs of the executions ordered by run time desc.
+// Only the not done executions are returned if nonStoppedOnly is set.
+func (s *Store) periodicExecutions(pID string, nonStoppedOnly bool) []string {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+
+	type execution struct {
+		id    string
+		score int64
+	}
+	list := make([]*execution, 0)
+	for eID, score := range s.executions[pID] {
+		if nonStoppedOnly && score < 0 {
+			continue
+		}
+		if _, ok := s.jobs[eID]; !ok || s.expired(eID) {
+			continue
+		}
+		list = append(list, &execution{id: eID, score: score})
+	}
+	sort.Slice(list, func(i, j int) bool {
+		if list[i].score == list[j].score {
+			return list[i].id > list[j].id
+		}
+		return list[i].score > list[j].score
+	})
+
+	ids := make([]string, 0, len(list))
+	for _, e := range list {
+		ids = append(ids, e.id)
+	}
+
+	return ids
+}
+
+// list the copies of all the job stats ordered by job ID
+func (s *Store) list() []*job.Stats {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+
+	ids := make([]string, 0, len(s.jobs))
+	for id := range s.jobs {
+		if !s.expired(id) {
+			ids = append(ids, id)
+		}
+	}
+	sort.Strings(ids)
+
+	results := make([]*job.Stats, 0, len(ids))
+	for _, id := range ids {
+		copied := *s.jobs[id]
+		results = append(results, &job.Stats{Info: &copied})
+	}
+
+	return results
+}
+
+// clearExpired removes the expired job stats
+func (s *Store) clearExpired() int {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	count := 0
+	for id := range s.expireAt {
+		if !s.expired(id) {
+			continue
+		}
+
+		if info, ok := s.jobs[id]; ok && !utils.IsEmptyStr(info.UpstreamJobID) {
+			if eIDs, ok := s.executions[info.UpstreamJobID]; ok {
+				delete(eIDs, id)
+			}
+		}
+		delete(s.executions, id)
+		delete(s.jobs, id)
+		delete(s.expireAt, id)
+		count++
+	}
+
+	return count
+}
+
+// expired should be called with the lock held
+func (s *Store) expired(jobID string) bool {
+	at, ok := s.expireAt[jobID]
+	return ok && at <= time.Now().Unix()
+}
+
+// setField sets the property of the stats by the field name used in the redis hash
+func setField(info *job.StatsInfo, field string, value interface{}) error {
+	v := fmt.Sprintf("%v", value)
+
+	switch field {
+	case "id":
+		info.JobID = v
+	case "name":
+		info.JobName = v
+	case "kind":
+		info.JobKind = v
+	case "unique":
+		b, err := strconv.ParseBool(v)
+		if err != nil {
+			return err
+		}
+		info.IsUnique = b
+	case "status":
+		info.Status = v
+	case "ref_link":
+		info.RefLink = v
+	case "cron_spec":
+		info.CronSpec = v
+	case "web_hook_url":
+		info.WebHookURL = v
+	case "check_in":
+		info.CheckIn = v
+	case "upstream_job_id":
+		info.UpstreamJobID = v
+	case "node_id":
+		info.NodeID = v
+	case "enqueue_time", "update_time", "run_at", "check_in_at", "die_at", "numeric_policy_id", "revision":
+		n, err := strconv.ParseInt(v, 10, 64)
+		if err != nil {
+			return errors.Wrapf(err, "parse field %s", field)
+		}
+		switch field {
+		case "enqueue_time":
+			info.EnqueueTime = n
+		case "update_time":
+			info.UpdateTime = n
+		case "run_at":
+			info.RunAt = n
+		case "check_in_at":
+			info.CheckInAt = n
+		case "die_at":
+			info.DieAt = n
+		case "numeric_policy_id":
+			info.NumericPID = n
+		case "revision":
+			info.Revision = n
+		}
+	case "parameters":
+		params, ok := value.(job.Parameters)
+		if !ok {
+			return errors.Errorf("parameters should be job.Parameters but got %T", value)
+		}
+		info.Parameters = params
+	default:
+		return errors.Errorf("unknown field of job stats: %s", field)
+	}
+
+	return nil
+}
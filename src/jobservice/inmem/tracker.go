@@ -0,0 +1,318 @@
+package inmem
+
+import (
+	"context"
+	"github.com/chenxull/goGridhub/gridhub/src/jobservice/common/utils"
+	"github.com/chenxull/goGridhub/gridhub/src/jobservice/errs"
+	"github.com/chenxull/goGridhub/gridhub/src/jobservice/job"
+	"github.com/chenxull/goGridhub/gridhub/src/jobservice/logger"
+	"github.com/pkg/errors"
+	"time"
+)
+
+const (
+	// Try best to keep the job stats data but anyway clear it after a reasonable time
+	statDataExpireTime = 7 * 24 * 3600
+	// 1 hour to discard the job stats of success jobs
+	statDataExpireTimeForSuccess = 3600
+)
+
+// memoryTracker implements job.Tracker interface based on the in-memory store
+type memoryTracker struct {
+	context  context.Context
+	store    *Store
+	jobID    string
+	jobStats *job.Stats
+	callback job.HookCallback
+}
+
+// NewTrackerWithID builds a tracker with the provided job ID
+func NewTrackerWithID(ctx context.Context, jobID string, store *Store, callback job.HookCallback) job.Tracker {
+	return &memoryTracker{
+		context:  ctx,
+		store:    store,
+		jobID:    jobID,
+		callback: callback,
+	}
+}
+
+// NewTrackerWithStats builds a tracker with the provided job stats
+func NewTrackerWithStats(ctx context.Context, stats *job.Stats, store *Store, callback job.HookCallback) job.Tracker {
+	return &memoryTracker{
+		context:  ctx,
+		store:    store,
+		jobID:    stats.Info.JobID,
+		jobStats: stats,
+		callback: callback,
+	}
+}
+
+// Save is implementation of job.Tracker.Save
+func (mt *memoryTracker) Save() error {
+	if mt.jobStats == nil {
+		return errors.New("nil job stats to save")
+	}
+
+	now := time.Now().Unix()
+	mt.jobStats.Info.UpdateTime = now
+	mt.jobStats.Info.Revision = now
+	if !mt.store.save(mt.jobStats.Info) {
+		// The job is already moving on
+		return nil
+	}
+
+	// Periodic job is kept until it's unscheduled
+	if mt.jobStats.Info.JobKind != job.KindPeriodic {
+		var expireTime int64 = statDataExpireTime
+		if mt.jobStats.Info.JobKind == job.KindScheduled {
+			if future := mt.jobStats.Info.RunAt - now; future > 0 {
+				expireTime += future
+			}
+		}
+
+		return mt.store.expire(mt.jobID, expireTime)
+	}
+
+	return nil
+}
+
+// Load is implementation of job.Tracker.Load
+func (mt *memoryTracker) Load() error {
+	stats, err := mt.store.get(mt.jobID)
+	if err != nil {
+		return err
+	}
+	mt.jobStats = stats
+
+	return nil
+}
+
+// Job is implementation of job.Tracker.Job
+func (mt *memoryTracker) Job() *job.Stats {
+	return mt.jobStats
+}
+
+// Update is implementation of job.Tracker.Update
+func (mt *memoryTracker) Update(fieldAndValues ...interface{}) error {
+	if len(fieldAndValues) == 0 {
+		return errors.New("no properties specified to update")
+	}
+
+	return mt.store.update(mt.jobID, fieldAndValues...)
+}
+
+// NumericID is implementation of job.Tracker.NumericID
+func (mt *memoryTracker) NumericID() (int64, error) {
+	if mt.jobStats.Info.NumericPID > 0 {
+		return mt.jobStats.Info.NumericPID, nil
+	}
+
+	return -1, errors.Errorf("numeric ID not found for job: %s", mt.jobID)
+}
+
+// PeriodicExecutionDone is implementation of job.Tracker.PeriodicExecutionDone
+func (mt *memoryTracker) PeriodicExecutionDone() error {
+	if utils.IsEmptyStr(mt.jobStats.Info.UpstreamJobID) {
+		return errors.Errorf("%s is not periodic job execution", mt.jobID)
+	}
+
+	return mt.store.executionDone(mt.jobStats.Info.UpstreamJobID, mt.jobID)
+}
+
+// CheckIn is implementation of job.Tracker.CheckIn
+func (mt *memoryTracker) CheckIn(message string) error {
+	if utils.IsEmptyStr(message) {
+		return errors.New("check in error: empty message")
+	}
+
+	now := time.Now().Unix()
+	current := job.Status(mt.jobStats.Info.Status)
+
+	mt.refresh(current, message)
+	err := mt.fireHookEvent(current, message)
+	if er := mt.Update(
+		"check_in", message,
+		"check_in_at", now,
+	); er != nil {
+		err = er
+	}
+
+	return err
+}
+
+// UpdateStatusWithRetry is implementation of job.Tracker.UpdateStatusWithRetry.
+// Nothing needs to be retried as the memory is always available.
+func (mt *memoryTracker) UpdateStatusWithRetry(targetStatus job.Status) error {
+	return mt.store.compareAndSet(mt.jobID, targetStatus)
+}
+
+// Status is implementation of job.Tracker.Status
+func (mt *memoryTracker) Status() (job.Status, error) {
+	return mt.store.status(mt.jobID)
+}
+
+// Expire is implementation of job.Tracker.Expire
+func (mt *memoryTracker) Expire() error {
+	return mt.store.expire(mt.jobID, statDataExpireTime)
+}
+
+// Reschedule is implementation of job.Tracker.Reschedule
+func (mt *memoryTracker) Reschedule(runAt int64) error {
+	if err := mt.store.update(mt.jobID, "run_at", runAt); err != nil {
+		return err
+	}
+	mt.jobStats.Info.RunAt = runAt
+
+	var expireTime int64 = statDataExpireTime
+	if future := runAt - time.Now().Unix(); future > 0 {
+		expireTime += future
+	}
+
+	return mt.store.expire(mt.jobID, expireTime)
+}
+
+// Run is implementation of job.Tracker.Run
+func (mt *memoryTracker) Run() error {
+	err := mt.store.compareAndSet(mt.jobID, job.RunningStatus)
+	if !errs.IsStatusMismatchError(err) {
+		mt.refresh(job.RunningStatus)
+		if er := mt.attachNode(); err == nil && er != nil {
+			err = er
+		}
+		if er := mt.fireHookEvent(job.RunningStatus); err == nil && er != nil {
+			return er
+		}
+	}
+
+	return err
+}
+
+// Stop is implementation of job.Tracker.Stop
+func (mt *memoryTracker) Stop() error {
+	err := mt.UpdateStatusWithRetry(job.StoppedStatus)
+	if !errs.IsStatusMismatchError(err) {
+		mt.refresh(job.StoppedStatus)
+		if er := mt.retain(job.StoppedStatus); er != nil {
+			logger.Errorf("Retain stat data for the stopped job `%s` failed with error: %s", mt.jobID, er)
+		}
+		if er := mt.fireHookEvent(job.StoppedStatus); err == nil && er != nil {
+			return er
+		}
+	}
+
+	return err
+}
+
+// Fail is implementation of job.Tracker.Fail
+func (mt *memoryTracker) Fail() error {
+	err := mt.UpdateStatusWithRetry(job.ErrorStatus)
+	if !errs.IsStatusMismatchError(err) {
+		mt.refresh(job.ErrorStatus)
+		if er := mt.retain(job.ErrorStatus); er != nil {
+			logger.Errorf("Retain stat data for the failed job `%s` failed with error: %s", mt.jobID, er)
+		}
+		if er := mt.fireHookEvent(job.ErrorStatus); err == nil && er != nil {
+			return er
+		}
+	}
+
+	return err
+}
+
+// Succeed is implementation of job.Tracker.Succeed
+func (mt *memoryTracker) Succeed() error {
+	err := mt.UpdateStatusWithRetry(job.SuccessStatus)
+	if !errs.IsStatusMismatchError(err) {
+		mt.refresh(job.SuccessStatus)
+		// Expire the stat data of the successful job
+		if er := mt.retain(job.SuccessStatus); er != nil {
+			logger.Errorf("Expire stat data for the success job `%s` failed with error: %s", mt.jobID, er)
+		}
+
+		if er := mt.fireHookEvent(job.SuccessStatus); err == nil && er != nil {
+			return er
+		}
+	}
+
+	return err
+}
+
+// Reset is implementation of job.Tracker.Reset
+func (mt *memoryTracker) Reset() error {
+	now := time.Now().Unix()
+	err := mt.Update(
+		"status", job.PendingStatus.String(),
+		"revision", now,
+	)
+	if err == nil {
+		mt.refresh(job.PendingStatus)
+		mt.jobStats.Info.Revision = now
+	}
+
+	return err
+}
+
+// retain sets the expiry of the stats of the finished job based on the retention.
+// The archival is not supported by the memory backend.
+func (mt *memoryTracker) retain(status job.Status) error {
+	if mt.jobStats.Info.JobKind == job.KindPeriodic {
+		return nil
+	}
+
+	if r := job.GetRetention(); r != nil {
+		if keep := int64(r.KeepFor(mt.jobStats.Info).Seconds()); keep > 0 {
+			return mt.store.expire(mt.jobID, keep)
+		}
+	}
+
+	if status == job.SuccessStatus {
+		return mt.store.expire(mt.jobID, statDataExpireTimeForSuccess)
+	}
+
+	return nil
+}
+
+// refresh the job stats in mem
+func (mt *memoryTracker) refresh(targetStatus job.Status, checkIn ...string) {
+	now := time.Now().Unix()
+
+	mt.jobStats.Info.Status = targetStatus.String()
+	if len(checkIn) > 0 {
+		mt.jobStats.Info.CheckIn = checkIn[0]
+		mt.jobStats.Info.CheckInAt = now
+	}
+	mt.jobStats.Info.UpdateTime = now
+}
+
+func (mt *memoryTracker) fireHookEvent(status job.Status, checkIn ...string) error {
+	// The status change is always reported to the callback for the event stream,
+	// the hook is only sent if the hook URL is registered
+	change := &job.StatusChange{
+		JobID:    mt.jobID,
+		Status:   status.String(),
+		Metadata: mt.jobStats.Info,
+	}
+	if len(checkIn) > 0 {
+		change.CheckIn = checkIn[0]
+	}
+
+	if mt.callback != nil {
+		return mt.callback(mt.jobStats.Info.WebHookURL, change)
+	}
+
+	return nil
+}
+
+// attachNode records the node which is running the job.
+// The running jobs of the node are calculated from the stats, so no extra set is kept.
+func (mt *memoryTracker) attachNode() error {
+	nodeID, ok := mt.context.Value(utils.NodeID).(string)
+	if !ok || utils.IsEmptyStr(nodeID) {
+		// Not running in a job service node, e.g: testing
+		return nil
+	}
+
+	mt.jobStats.Info.NodeID = nodeID
+
+	return mt.Update("node_id", nodeID)
+}
@@ -0,0 +1,446 @@
+package inmem
+
+import (
+	"github.com/chenxull/goGridhub/gridhub/src/jobservice/common/utils"
+	"github.com/chenxull/goGridhub/gridhub/src/jobservice/env"
+	"github.com/chenxull/goGridhub/gridhub/src/jobservice/errs"
+	"github.com/chenxull/goGridhub/gridhub/src/jobservice/job"
+	"github.com/chenxull/goGridhub/gridhub/src/jobservice/lcm"
+	"github.com/chenxull/goGridhub/gridhub/src/jobservice/logger"
+	"github.com/chenxull/goGridhub/gridhub/src/jobservice/period"
+	"github.com/chenxull/goGridhub/gridhub/src/jobservice/worker"
+	"github.com/chenxull/goGridhub/gridhub/src/jobservice/worker/base"
+	"github.com/chenxull/goGridhub/gridhub/src/jobservice/worker/cworker"
+	"github.com/gocraft/work"
+	"github.com/pkg/errors"
+	"reflect"
+	"sort"
+	"sync"
+	"time"
+)
+
+const (
+	workerPoolStatusHealthy      = "Healthy"
+	defaultWorkerCount      uint = 10
+	// Interval of moving the due scheduled jobs to the pending queue
+	scheduleInterval = time.Second
+)
+
+// memoryWorker is the worker implementation based on the in-process queue.
+// The jobs are lost if the process exits, so it's only for the single node deployment and testing.
+type memoryWorker struct {
+	context     *env.Context
+	nodeID      string
+	concurrency uint
+	startedAt   int64
+	// Protect the concurrency
+	lock sync.Mutex
+	// Each message retires an idle consumer
+	retire chan struct{}
+
+	queue        *queue
+	scheduler    *memoryScheduler
+	ctl          lcm.Controller
+	deDuplicator cworker.DeDuplicator
+	// key is name of known job
+	// value is the type of known job
+	knownJobs *sync.Map
+	// key is name of known job
+	// value is the *base.RegisteredJob
+	jobs *sync.Map
+}
+
+// NewWorker is constructor of the in-memory worker
+func NewWorker(ctx *env.Context, workerCount uint, store *Store, ctl lcm.Controller) worker.Interface {
+	wc := defaultWorkerCount
+	if workerCount > 0 {
+		wc = workerCount
+	}
+
+	nodeID, ok := ctx.SystemContext.Value(utils.NodeID).(string)
+	if !ok {
+		nodeID = utils.GenerateNodeID()
+	}
+
+	q := newQueue()
+	return &memoryWorker{
+		context:      ctx,
+		nodeID:       nodeID,
+		concurrency:  wc,
+		startedAt:    time.Now().Unix(),
+		queue:        q,
+		scheduler:    newScheduler(ctx.SystemContext, ctl, store, q),
+		ctl:          ctl,
+		deDuplicator: NewDeDuplicator(ctl),
+		knownJobs:    new(sync.Map),
+		jobs:         new(sync.Map),
+		retire:       make(chan struct{}),
+	}
+}
+
+// Start to serve
+func (w *memoryWorker) Start() error {
+	if w.context == nil || w.context.SystemContext == nil {
+		// report and exit
+		return errors.New("missing context")
+	}
+
+	if w.ctl == nil {
+		return errors.New("missing job life cycle controller")
+	}
+
+	// Start the periodic scheduler
+	w.context.WG.Add(1)
+	go func() {
+		defer func() {
+			w.context.WG.Done()
+		}()
+		//Blocking call
+		if err := w.scheduler.Start(); err != nil {
+			w.context.ErrorChan <- err
+		}
+	}()
+
+	w.context.WG.Add(1)
+	go w.loopSchedule()
+
+	for i := uint(0); i < w.concurrency; i++ {
+		w.context.WG.Add(1)
+		go w.consume()
+	}
+
+	logger.Infof("memory worker is started with %d consumers", w.concurrency)
+	return nil
+}
+
+// RegisterJobs is used to register multiple jobs to worker.
+func (w *memoryWorker) RegisterJobs(jobs map[string]interface{}) error {
+	if jobs == nil || len(jobs) == 0 {
+		return nil
+	}
+
+	for name, j := range jobs {
+		if err := w.registerJob(name, j); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Enqueue is implementation of worker.Interface.Enqueue
+func (w *memoryWorker) Enqueue(jobName string, params job.Parameters, unique *job.UniqueOptions, webHook string) (res *job.Stats, err error) {
+	if unique != nil {
+		sign, err := w.deDuplicator.MustUnique(jobName, params, unique)
+		if err != nil {
+			return nil, err
+		}
+		defer base.BindUniqueSign(w.deDuplicator, sign, unique, &res)
+	}
+
+	j := base.NewJob(jobName, params)
+	stats := base.GenerateResult(j, job.KindGeneric, unique != nil, params, webHook)
+	// Save the stats before queuing as the job may be picked up at once
+	if _, err := w.ctl.New(stats); err != nil {
+		return nil, err
+	}
+	w.queue.push(j)
+
+	return stats, nil
+}
+
+// Schedule is implementation of worker.Interface.Schedule
+func (w *memoryWorker) Schedule(jobName string, params job.Parameters, runAfterSeconds uint64, unique *job.UniqueOptions, webHook string) (res *job.Stats, err error) {
+	if unique != nil {
+		sign, err := w.deDuplicator.MustUnique(jobName, params, unique)
+		if err != nil {
+			return nil, err
+		}
+		defer base.BindUniqueSign(w.deDuplicator, sign, unique, &res)
+	}
+
+	j := base.NewJob(jobName, params)
+	stats := base.GenerateResult(j, job.KindScheduled, unique != nil, params, webHook)
+	stats.Info.RunAt = j.EnqueuedAt + int64(runAfterSeconds)
+	stats.Info.Status = job.ScheduledStatus.String()
+	if _, err := w.ctl.New(stats); err != nil {
+		return nil, err
+	}
+	w.queue.pushAt(j, stats.Info.RunAt)
+
+	return stats, nil
+}
+
+// PeriodicallyEnqueue is implementation of worker.Interface.PeriodicallyEnqueue
+func (w *memoryWorker) PeriodicallyEnqueue(jobName string, params job.Parameters, cronSetting string, schedule *job.ScheduleSpec, isUnique bool, webHook string) (*job.Stats, error) {
+	p := &period.Policy{
+		ID:            utils.MakeIdentifier(),
+		JobName:       jobName,
+		CronSpec:      cronSetting,
+		JobParameters: params,
+		WebHookURL:    webHook,
+		Schedule:      schedule,
+	}
+
+	id, err := w.scheduler.Schedule(p)
+	if err != nil {
+		return nil, err
+	}
+
+	return p.JobStats(id), nil
+}
+
+// Stats is implementation of worker.Interface.Stats
+func (w *memoryWorker) Stats() (*worker.Stats, error) {
+	res := &worker.Stats{
+		Pools: []*worker.StatsData{
+			{
+				WorkerPoolID: w.nodeID,
+				StartedAt:    w.startedAt,
+				HeartbeatAt:  time.Now().Unix(),
+				JobNames:     w.jobNames(),
+				Concurrency:  w.currentConcurrency(),
+				Status:       workerPoolStatusHealthy,
+			},
+		},
+	}
+
+	// The current node is always the leader
+	leader, err := w.scheduler.Leader()
+	if err != nil {
+		logger.Errorf("get leader of periodic enqueuer error: %s", err)
+	} else if leader != nil {
+		res.Leader = &worker.LeaderData{
+			NodeID:       leader.NodeID,
+			FencingToken: leader.Token,
+			ExpireAt:     leader.ExpireAt,
+		}
+	}
+
+	return res, nil
+}
+
+// IsKnownJob is implementation of worker.Interface.IsKnownJob
+func (w *memoryWorker) IsKnownJob(name string) (interface{}, bool) {
+	return w.knownJobs.Load(name)
+}
+
+// KnownJobs is implementation of worker.Interface.KnownJobs
+func (w *memoryWorker) KnownJobs() map[string]interface{} {
+	return base.KnownJobs(w.knownJobs)
+}
+
+// ValidateJobParameters is implementation of worker.Interface.ValidateJobParameters
+func (w *memoryWorker) ValidateJobParameters(jobType interface{}, params job.Parameters) error {
+	return base.ValidateJobParameters(jobType, params)
+}
+
+// StopJob is implementation of worker.Interface.StopJob
+func (w *memoryWorker) StopJob(jobID string) error {
+	if utils.IsEmptyStr(jobID) {
+		return errors.New("empty job ID to stop")
+	}
+
+	t, err := w.ctl.Track(jobID)
+	if err != nil {
+		return err
+	}
+	if job.RunningStatus.Compare(job.Status(t.Job().Info.Status)) < 0 {
+		return errs.StatusMismatchError(t.Job().Info.Status, job.StoppedStatus.String())
+	}
+
+	switch t.Job().Info.JobKind {
+	case job.KindGeneric:
+		// The job left in the queue will be skipped by the runner as the job is stopped
+		return t.Stop()
+	case job.KindScheduled:
+		// delete the scheduled job in the queue if it's not pending yet
+		if !w.queue.removeScheduled(jobID, t.Job().Info.RunAt) {
+			logger.Errorf("scheduled job %s (run at = %d) is not found in the queue to stop, is it already running?", jobID, t.Job().Info.RunAt)
+		}
+		return t.Stop()
+	case job.KindPeriodic:
+		return w.scheduler.UnSchedule(jobID)
+	default:
+		return errors.Errorf("job kind %s is not supported", t.Job().Info.JobKind)
+	}
+}
+
+// RescheduleJob moves the scheduled job which is not pending yet to run at the specified time
+func (w *memoryWorker) RescheduleJob(jobID string, runAt int64) error {
+	if utils.IsEmptyStr(jobID) {
+		return errors.New("empty job ID to reschedule")
+	}
+
+	t, err := w.ctl.Track(jobID)
+	if err != nil {
+		return err
+	}
+
+	info := t.Job().Info
+	if info.JobKind != job.KindScheduled {
+		return errors.Errorf("job kind %s is not supported to reschedule", info.JobKind)
+	}
+	if job.Status(info.Status) != job.ScheduledStatus {
+		return errs.StatusMismatchError(info.Status, job.ScheduledStatus.String())
+	}
+
+	if !w.queue.reschedule(jobID, info.RunAt, runAt) {
+		return errs.StatusMismatchError(job.PendingStatus.String(), job.ScheduledStatus.String())
+	}
+
+	return t.Reschedule(runAt)
+}
+
+// RetryJob puts the failed job back to the queue with the same job ID
+func (w *memoryWorker) RetryJob(jobID string) error {
+	return base.RetryJob(w.ctl, jobID, w.push)
+}
+
+// RequeueJob puts the orphaned job back to the queue with the same job ID
+func (w *memoryWorker) RequeueJob(jobID string) error {
+	return base.RequeueJob(w.ctl, jobID, w.push)
+}
+
+func (w *memoryWorker) push(j *work.Job) error {
+	w.queue.push(j)
+	return nil
+}
+
+// Resize is implementation of worker.Resizer
+func (w *memoryWorker) Resize(concurrency uint) error {
+	if concurrency == 0 {
+		return errors.New("concurrency should be greater than 0")
+	}
+
+	w.lock.Lock()
+	defer w.lock.Unlock()
+
+	if concurrency > w.concurrency {
+		for i := w.concurrency; i < concurrency; i++ {
+			w.context.WG.Add(1)
+			go w.consume()
+		}
+	} else if retired := w.concurrency - concurrency; retired > 0 {
+		// Retire the consumers once they're idle, don't block the caller
+		go func() {
+			for i := uint(0); i < retired; i++ {
+				select {
+				case w.retire <- struct{}{}:
+				case <-w.context.SystemContext.Done():
+					return
+				}
+			}
+		}()
+	}
+
+	logger.Infof("memory worker is resized from %d to %d consumers", w.concurrency, concurrency)
+	w.concurrency = concurrency
+
+	return nil
+}
+
+// currentConcurrency returns the number of the consumers
+func (w *memoryWorker) currentConcurrency() uint {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+
+	return w.concurrency
+}
+
+// consume takes the pending jobs from the queue and runs them
+func (w *memoryWorker) consume() {
+	defer func() {
+		w.context.WG.Done()
+	}()
+
+	for {
+		select {
+		case <-w.retire:
+			return
+		default:
+		}
+
+		j := w.queue.pop()
+		if j == nil {
+			select {
+			case <-w.queue.notify:
+				continue
+			case <-w.retire:
+				return
+			case <-w.context.SystemContext.Done():
+				return
+			}
+		}
+
+		w.process(j)
+	}
+}
+
+func (w *memoryWorker) process(j *work.Job) {
+	v, ok := w.jobs.Load(j.Name)
+	if !ok {
+		logger.Errorf("job %s:%s is not registered, discard it", j.Name, j.ID)
+		return
+	}
+	rj := v.(*base.RegisteredJob)
+
+	if err := rj.Runner.Run(j); err != nil {
+		w.retry(rj, j, err)
+	}
+}
+
+// retry puts the failed job to the scheduled queue with backoff if the retry is allowed
+func (w *memoryWorker) retry(rj *base.RegisteredJob, j *work.Job, err error) {
+	if retryAt, ok := rj.Fail(j, err); ok {
+		w.queue.pushAt(j, retryAt)
+	}
+}
+
+// loopSchedule moves the due scheduled jobs to the pending queue
+func (w *memoryWorker) loopSchedule() {
+	defer func() {
+		logger.Info("Memory worker is stopped")
+		w.context.WG.Done()
+	}()
+
+	ticker := time.NewTicker(scheduleInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			w.queue.moveDue(time.Now().Unix())
+		case <-w.context.SystemContext.Done():
+			if err := w.scheduler.Stop(); err != nil {
+				logger.Errorf("stop scheduler error: %s", err)
+			}
+			return
+		}
+	}
+}
+
+func (w *memoryWorker) jobNames() []string {
+	names := make([]string, 0)
+	w.jobs.Range(func(k, v interface{}) bool {
+		names = append(names, k.(string))
+		return true
+	})
+	sort.Strings(names)
+
+	return names
+}
+
+// registerJob is used to register the job to the worker.
+// j is the type of the job
+func (w *memoryWorker) registerJob(name string, j interface{}) (err error) {
+	if err := base.CheckRegistration(w.knownJobs, name, j); err != nil {
+		return err
+	}
+
+	w.jobs.Store(name, base.NewRegisteredJob(j, w.context, w.ctl))
+	// Keep the name of registered jobs as known jobs for future validation
+	w.knownJobs.Store(name, j)
+	logger.Infof("Register job %s with name %s", reflect.TypeOf(j).String(), name)
+
+	return nil
+}
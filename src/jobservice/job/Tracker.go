@@ -18,6 +18,8 @@ const (
 	statDataExpireTime = 7 * 24 * 3600
 	// 1 hour to discard the job stats of success jobs
 	statDataExpireTimeForSuccess = 3600
+	// Keep the stats to be archived a while longer in case the sweeper is behind
+	archiveGraceTime = 24 * 3600
 )
 
 // Tracker is designed to track the life cycle of the job described by the stats
@@ -57,6 +59,9 @@ type Tracker interface {
 	// Expire the job stats data
 	Expire() error
 
+	// Update the run time of the scheduled job
+	Reschedule(runAt int64) error
+
 	// Switch status to running
 	Run() error
 
@@ -173,6 +178,9 @@ func (bt *basicTracker) Save() (err error) {
 		"web_hook_url", stats.Info.WebHookURL,
 		"numeric_policy_id", stats.Info.NumericPID,
 	)
+	if !utils.IsEmptyStr(stats.Info.NodeID) {
+		args = append(args, "node_id", stats.Info.NodeID)
+	}
 	if stats.Info.CheckInAt > 0 && !utils.IsEmptyStr(stats.Info.CheckIn) {
 		args = append(args,
 			"check_in", stats.Info.CheckIn,
@@ -192,6 +200,11 @@ func (bt *basicTracker) Save() (err error) {
 			args = append(args, "parameters", string(bytes))
 		}
 	}
+	if stats.Info.Schedule != nil {
+		if bytes, err := json.Marshal(stats.Info.Schedule); err == nil {
+			args = append(args, "schedule", string(bytes))
+		}
+	}
 	// Set update timestamp
 	args = append(args, "update_time", time.Now().Unix())
 	// Set the first revision
@@ -313,12 +326,30 @@ func (bt *basicTracker) Expire() error {
 	return bt.expire(statDataExpireTime)
 }
 
+// Reschedule updates the run time of the scheduled job and keeps the stats until it runs
+func (bt *basicTracker) Reschedule(runAt int64) error {
+	if err := bt.Update("run_at", runAt); err != nil {
+		return err
+	}
+	bt.jobStats.Info.RunAt = runAt
+
+	var expireTime int64 = statDataExpireTime
+	if future := runAt - time.Now().Unix(); future > 0 {
+		expireTime += future
+	}
+
+	return bt.expire(expireTime)
+}
+
 // Run job
 // Either one is failed, the final return will be marked as failed.
 func (bt *basicTracker) Run() error {
 	err := bt.compareAndSet(RunningStatus)
 	if !errs.IsStatusMismatchError(err) {
 		bt.refresh(RunningStatus)
+		if er := bt.attachNode(); err == nil && er != nil {
+			err = er
+		}
 		if er := bt.fireHookEvent(RunningStatus); err == nil && er != nil {
 			return er
 		}
@@ -330,6 +361,10 @@ func (bt *basicTracker) Stop() error {
 	err := bt.UpdateStatusWithRetry(StoppedStatus)
 	if !errs.IsStatusMismatchError(err) {
 		bt.refresh(StoppedStatus)
+		bt.detachNode()
+		if er := bt.retain(StoppedStatus); err == nil && er != nil {
+			err = errors.Wrap(er, "retain job stats")
+		}
 		if er := bt.fireHookEvent(StoppedStatus); err == nil && er != nil {
 			return er
 		}
@@ -341,6 +376,10 @@ func (bt *basicTracker) Fail() error {
 	err := bt.UpdateStatusWithRetry(ErrorStatus)
 	if !errs.IsStatusMismatchError(err) {
 		bt.refresh(ErrorStatus)
+		bt.detachNode()
+		if er := bt.retain(ErrorStatus); err == nil && er != nil {
+			err = errors.Wrap(er, "retain job stats")
+		}
 		if er := bt.fireHookEvent(ErrorStatus); err == nil && er != nil {
 			return er
 		}
@@ -355,10 +394,10 @@ func (bt *basicTracker) Succeed() error {
 	err := bt.UpdateStatusWithRetry(SuccessStatus)
 	if !errs.IsStatusMismatchError(err) {
 		bt.refresh(SuccessStatus)
+		bt.detachNode()
 		// Expire the stat data of the successful job
-		if er := bt.expire(statDataExpireTimeForSuccess); er != nil {
-			// Only logged
-			//logger.Errorf("Expire stat data for the success job `%s` failed with error: %s", bt.jobID, er)
+		if er := bt.retain(SuccessStatus); err == nil && er != nil {
+			err = errors.Wrap(er, "retain job stats")
 		}
 
 		if er := bt.fireHookEvent(SuccessStatus); err == nil && er != nil {
@@ -471,11 +510,8 @@ func (bt *basicTracker) refresh(targetStatus Status, checkIn ...string) {
 }
 
 func (bt *basicTracker) fireHookEvent(status Status, checkIn ...string) error {
-	// Check if hook URL is registered
-	if utils.IsEmptyStr(bt.jobStats.Info.WebHookURL) {
-		// Do nothing
-		return nil
-	}
+	// The status change is always reported to the callback for the event stream,
+	// the hook is only sent if the hook URL is registered
 	change := &StatusChange{
 		JobID:    bt.jobID,
 		Status:   status.String(),
@@ -492,6 +528,117 @@ func (bt *basicTracker) fireHookEvent(status Status, checkIn ...string) error {
 	return nil
 }
 
+// attachNode records the node which is running the job and adds the job
+// to the running job set of that node.
+func (bt *basicTracker) attachNode() error {
+	nodeID, ok := bt.context.Value(utils.NodeID).(string)
+	if !ok || utils.IsEmptyStr(nodeID) {
+		// Not running in a job service node, e.g: testing
+		return nil
+	}
+
+	conn := bt.pool.Get()
+	defer func() {
+		_ = conn.Close()
+	}()
+
+	key := rds.KeyJobStats(bt.namespace, bt.jobID)
+	if err := conn.Send("MULTI"); err != nil {
+		return err
+	}
+	if err := conn.Send("HMSET", key, "node_id", nodeID, "update_time", time.Now().Unix()); err != nil {
+		return err
+	}
+	if err := conn.Send("SADD", rds.KeyNodeRunningJobs(bt.namespace, nodeID), bt.jobID); err != nil {
+		return err
+	}
+
+	if _, err := conn.Do("EXEC"); err != nil {
+		return err
+	}
+	bt.jobStats.Info.NodeID = nodeID
+
+	return nil
+}
+
+// detachNode removes the job from the running job set of the node which ran it.
+// The node ID is kept in the job stats for future querying.
+func (bt *basicTracker) detachNode() {
+	if utils.IsEmptyStr(bt.jobStats.Info.NodeID) {
+		return
+	}
+
+	conn := bt.pool.Get()
+	defer func() {
+		closeConn(conn)
+	}()
+
+	if _, err := conn.Do("SREM", rds.KeyNodeRunningJobs(bt.namespace, bt.jobStats.Info.NodeID), bt.jobID); err != nil {
+		//todo logger.Errorf("Remove job %s from the running set of node %s error: %s", bt.jobID, bt.jobStats.Info.NodeID, err)
+	}
+}
+
+// retain sets the expiry of the stats of the finished job based on the retention.
+// The job is indexed for the sweeper if the expired stats should be archived.
+func (bt *basicTracker) retain(status Status) error {
+	// The periodic job is kept until it's removed
+	if bt.jobStats.Info.JobKind == KindPeriodic {
+		return nil
+	}
+
+	r := GetRetention()
+	var keep int64
+	if r != nil {
+		keep = int64(r.KeepFor(bt.jobStats.Info).Seconds())
+	}
+
+	if keep <= 0 {
+		// Default retention
+		if r == nil || !r.Archived() {
+			if status == SuccessStatus {
+				return bt.expire(statDataExpireTimeForSuccess)
+			}
+			return nil
+		}
+
+		keep = statDataExpireTime
+		if status == SuccessStatus {
+			keep = statDataExpireTimeForSuccess
+		}
+	}
+
+	if !r.Archived() {
+		return bt.expire(keep)
+	}
+
+	conn := bt.pool.Get()
+	defer func() {
+		_ = conn.Close()
+	}()
+
+	if err := conn.Send("MULTI"); err != nil {
+		return err
+	}
+	if err := conn.Send("ZADD", rds.KeyRetention(bt.namespace), time.Now().Unix()+keep, bt.jobID); err != nil {
+		return err
+	}
+	if err := conn.Send("EXPIRE", rds.KeyJobStats(bt.namespace, bt.jobID), keep+archiveGraceTime); err != nil {
+		return err
+	}
+	replies, err := redis.Values(conn.Do("EXEC"))
+	if err != nil {
+		return err
+	}
+	// The failed command in the transaction is replied with the error
+	for _, reply := range replies {
+		if e, ok := reply.(redis.Error); ok {
+			return e
+		}
+	}
+
+	return nil
+}
+
 func (bt *basicTracker) expire(expireTime int64) error {
 	conn := bt.pool.Get()
 	defer func() {
@@ -603,9 +750,18 @@ func (bt *basicTracker) retrieve() error {
 				res.Info.Parameters = params
 			}
 			break
+		case "schedule":
+			spec := &ScheduleSpec{}
+			if err := json.Unmarshal([]byte(value), spec); err == nil {
+				res.Info.Schedule = spec
+			}
+			break
 		case "revision":
 			res.Info.Revision = parseInt64(value)
 			break
+		case "node_id":
+			res.Info.NodeID = value
+			break
 		default:
 			break
 		}
@@ -11,3 +11,18 @@ type Interface interface {
 	// The related arguments will be injected by the workerpool.
 	Run(ctx Context, params Parameters) error
 }
+
+// Factory is implemented by the job which keeps its settings in the registered reference,
+// e.g: the out-of-process plugin job. The worker runs the job returned by New instead of
+// creating a new zero value of the registered type.
+type Factory interface {
+	// New job instance to run
+	New() Interface
+}
+
+// SchemaProvider is implemented by the job declaring the JSON schema of its parameters.
+// The parameters are validated against the schema before launching the job.
+type SchemaProvider interface {
+	// ParametersSchema returns the JSON schema of the parameters
+	ParametersSchema() string
+}
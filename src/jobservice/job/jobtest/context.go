@@ -0,0 +1,89 @@
+package jobtest
+
+import (
+	"context"
+	"github.com/chenxull/goGridhub/gridhub/src/jobservice/job"
+	"github.com/chenxull/goGridhub/gridhub/src/jobservice/logger"
+	"github.com/pkg/errors"
+)
+
+// fakeContext implements job.Context for running the job in the harness
+type fakeContext struct {
+	sysContext context.Context
+	properties map[string]interface{}
+	tracker    job.Tracker
+	logger     logger.Interface
+	// Called after each check in with the number of the check ins
+	onCheckIn func(count int)
+	rec       *recorder
+}
+
+// Build implements job.Context
+func (fc *fakeContext) Build(t job.Tracker) (job.Context, error) {
+	if t == nil {
+		return nil, errors.New("nil job tracker")
+	}
+
+	jContext := &fakeContext{
+		sysContext: fc.sysContext,
+		properties: make(map[string]interface{}, len(fc.properties)),
+		tracker:    t,
+		logger:     fc.logger,
+		onCheckIn:  fc.onCheckIn,
+		rec:        fc.rec,
+	}
+	for k, v := range fc.properties {
+		jContext.properties[k] = v
+	}
+
+	return jContext, nil
+}
+
+// Get implements job.Context
+func (fc *fakeContext) Get(prop string) (interface{}, bool) {
+	v, ok := fc.properties[prop]
+	return v, ok
+}
+
+// SystemContext implements job.Context
+func (fc *fakeContext) SystemContext() context.Context {
+	return fc.sysContext
+}
+
+// Checkin implements job.Context
+func (fc *fakeContext) Checkin(status string) error {
+	err := fc.tracker.CheckIn(status)
+
+	count := fc.rec.checkIn(status)
+	if fc.onCheckIn != nil {
+		fc.onCheckIn(count)
+	}
+
+	return err
+}
+
+// OPCommand implements job.Context, same as the default context
+func (fc *fakeContext) OPCommand() (job.OPCommand, bool) {
+	latest, err := fc.tracker.Status()
+	if err != nil {
+		return job.NilCommand, false
+	}
+
+	if job.StoppedStatus == latest {
+		fc.rec.opCommand(job.StopCommand)
+		return job.StopCommand, true
+	}
+
+	fc.rec.opCommand(job.NilCommand)
+	return job.NilCommand, false
+}
+
+// GetLogger returns the logger capturing the logs
+func (fc *fakeContext) GetLogger() logger.Interface {
+	return fc.logger
+}
+
+// Tracker implements job.Context
+func (fc *fakeContext) Tracker() job.Tracker {
+	return fc.tracker
+}
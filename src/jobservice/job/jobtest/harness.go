@@ -0,0 +1,201 @@
+// Package jobtest provides a harness for testing the implementations of job.Interface
+// without the job service and redis.
+//
+// The job is run by the job runner of the worker with a fake job context and the life cycle
+// controller of the in-memory backend. As the worker does, the job is created from the type
+// of the given one unless it implements job.Factory. The check ins, the logs written via the
+// context logger, the status transitions and the OP commands returned to the job are captured:
+//
+//	h := jobtest.NewHarness(jobtest.WithProperty("key", "value"), jobtest.StopAtCheckIn(2))
+//	res := h.Run(&MyJob{}, job.Parameters{"image": "library/busybox"})
+//	res.AssertStatus(t, job.StoppedStatus)
+package jobtest
+
+import (
+	"context"
+	"fmt"
+	"github.com/chenxull/goGridhub/gridhub/src/jobservice/common/utils"
+	"github.com/chenxull/goGridhub/gridhub/src/jobservice/env"
+	"github.com/chenxull/goGridhub/gridhub/src/jobservice/inmem"
+	"github.com/chenxull/goGridhub/gridhub/src/jobservice/job"
+	"github.com/chenxull/goGridhub/gridhub/src/jobservice/lcm"
+	"github.com/chenxull/goGridhub/gridhub/src/jobservice/runner"
+	"github.com/gocraft/work"
+	"github.com/pkg/errors"
+	"sync"
+	"time"
+)
+
+// Name of the node running the jobs in the harness
+const nodeID = "jobtest"
+
+// Option customizes the harness
+type Option func(h *Harness)
+
+// WithProperty sets the property which can be got from the job context
+func WithProperty(key string, value interface{}) Option {
+	return func(h *Harness) {
+		h.properties[key] = value
+	}
+}
+
+// WithSystemContext sets the system context returned by the job context
+func WithSystemContext(ctx context.Context) Option {
+	return func(h *Harness) {
+		h.sysContext = ctx
+	}
+}
+
+// StopAtCheckIn injects the stop signal once the job checks in the nth time.
+// The job gets the stop command from the next calling of OPCommand.
+func StopAtCheckIn(n int) Option {
+	return func(h *Harness) {
+		h.stopAtCheckIn = n
+	}
+}
+
+// Harness runs the job with the fake context and captures what happened
+type Harness struct {
+	sysContext    context.Context
+	properties    map[string]interface{}
+	stopAtCheckIn int
+	store         *inmem.Store
+	// Protect the running job
+	lock    sync.Mutex
+	running *job.Stats
+	rec     *recorder
+}
+
+// NewHarness is constructor of Harness
+func NewHarness(options ...Option) *Harness {
+	h := &Harness{
+		sysContext: context.Background(),
+		properties: make(map[string]interface{}),
+		store:      inmem.NewStore(),
+	}
+
+	for _, opt := range options {
+		opt(h)
+	}
+
+	return h
+}
+
+// Run the job with the parameters and return the result after the job exits.
+// The job is not run if the parameters are invalid, the validation error is
+// returned in the result with the pending status.
+func (h *Harness) Run(j job.Interface, params job.Parameters) *Result {
+	ctx := context.WithValue(h.sysContext, utils.NodeID, nodeID)
+	rec := newRecorder()
+	res := &Result{}
+	defer func() {
+		rec.fill(res)
+	}()
+
+	stats := &job.Stats{
+		Info: &job.StatsInfo{
+			JobID:       utils.MakeIdentifier(),
+			JobName:     fmt.Sprintf("%T", j),
+			JobKind:     job.KindGeneric,
+			Status:      job.PendingStatus.String(),
+			EnqueueTime: time.Now().Unix(),
+			Parameters:  params,
+		},
+	}
+	res.Stats = stats
+
+	envCtx := &env.Context{
+		SystemContext: ctx,
+		WG:            &sync.WaitGroup{},
+	}
+	ctl := &recordingController{
+		Controller: inmem.NewController(envCtx, h.store, nil),
+		rec:        rec,
+	}
+	if _, err := ctl.New(stats); err != nil {
+		res.Err = err
+		return res
+	}
+
+	h.lock.Lock()
+	h.running = stats
+	h.rec = rec
+	h.lock.Unlock()
+
+	defer func() {
+		h.lock.Lock()
+		h.running = nil
+		h.rec = nil
+		h.lock.Unlock()
+
+		if t, err := ctl.Track(stats.Info.JobID); err == nil {
+			res.Status = job.Status(t.Job().Info.Status)
+			res.Stats = t.Job()
+		}
+	}()
+
+	if err := j.Validate(params); err != nil {
+		res.Err = errors.Wrap(err, "validate job parameters")
+		return res
+	}
+
+	envCtx.JobContext = &fakeContext{
+		sysContext: ctx,
+		properties: h.properties,
+		logger:     &recordingLogger{rec: rec},
+		onCheckIn: func(count int) {
+			if h.stopAtCheckIn > 0 && count == h.stopAtCheckIn {
+				if er := h.Stop(); er != nil {
+					rec.log(LevelError, fmt.Sprintf("jobtest: inject stop signal error: %s", er))
+				}
+			}
+		},
+		rec: rec,
+	}
+
+	// Run the job as the worker does
+	res.Err = runner.NewRedisJob(j, envCtx, ctl).Run(&work.Job{
+		Name:       stats.Info.JobName,
+		ID:         stats.Info.JobID,
+		EnqueuedAt: stats.Info.EnqueueTime,
+		Args:       params,
+	})
+
+	return res
+}
+
+// Stop injects the stop signal to the running job as the job service does.
+// It can be called from another goroutine while the job is running.
+func (h *Harness) Stop() error {
+	h.lock.Lock()
+	stats, rec := h.running, h.rec
+	h.lock.Unlock()
+
+	if stats == nil {
+		return errors.New("no job is running")
+	}
+
+	t := inmem.NewTrackerWithID(h.sysContext, stats.Info.JobID, h.store, nil)
+	if err := t.Load(); err != nil {
+		return err
+	}
+
+	return (&recordingTracker{Tracker: t, rec: rec}).Stop()
+}
+
+// recordingController wraps the in-memory life cycle controller to capture the status
+// transitions done via the trackers of the job
+type recordingController struct {
+	lcm.Controller
+	rec *recorder
+}
+
+// Track implements lcm.Controller
+func (rc *recordingController) Track(jobID string) (job.Tracker, error) {
+	t, err := rc.Controller.Track(jobID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &recordingTracker{Tracker: t, rec: rc.rec}, nil
+}
@@ -0,0 +1,153 @@
+package jobtest
+
+import (
+	"fmt"
+	"github.com/chenxull/goGridhub/gridhub/src/jobservice/job"
+	"github.com/chenxull/goGridhub/gridhub/src/jobservice/logger"
+	"github.com/pkg/errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+// testJob behaves as the parameters say, the settings are not kept in the instance
+// as the runner creates a new instance to run
+type testJob struct{}
+
+func (tj *testJob) MaxFalis() uint {
+	return 1
+}
+
+func (tj *testJob) ShouldRetry() bool {
+	return false
+}
+
+func (tj *testJob) Validate(params job.Parameters) error {
+	if _, ok := params["mode"]; !ok {
+		return errors.New("missing mode")
+	}
+
+	return nil
+}
+
+func (tj *testJob) Run(ctx job.Context, params job.Parameters) error {
+	switch params["mode"] {
+	case "fail":
+		return errors.New("failed")
+	case "panic":
+		panic("boom")
+	case "loop":
+		for i := 1; ; i++ {
+			if cmd, ok := ctx.OPCommand(); ok && cmd.IsStop() {
+				logOf(ctx).Info("stopped")
+				return nil
+			}
+			if err := ctx.Checkin(fmt.Sprintf("step %d", i)); err != nil {
+				return err
+			}
+			if i > 10 {
+				return errors.New("not stopped")
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+	default:
+		v, _ := ctx.Get("greeting")
+		logOf(ctx).Infof("%v", v)
+		return ctx.Checkin("done")
+	}
+}
+
+// logOf returns the logger of the job context
+func logOf(ctx job.Context) logger.Interface {
+	return ctx.(interface{ GetLogger() logger.Interface }).GetLogger()
+}
+
+func TestHarnessRun(t *testing.T) {
+	cases := []struct {
+		name        string
+		options     []Option
+		params      job.Parameters
+		status      job.Status
+		err         string
+		transitions []job.Status
+		checkIns    []string
+		logs        []string
+	}{
+		{
+			name:        "success",
+			options:     []Option{WithProperty("greeting", "hello")},
+			params:      job.Parameters{"mode": "succeed"},
+			status:      job.SuccessStatus,
+			transitions: []job.Status{job.RunningStatus, job.SuccessStatus},
+			checkIns:    []string{"done"},
+			logs:        []string{"INFO: hello"},
+		},
+		{
+			name:        "failure",
+			params:      job.Parameters{"mode": "fail"},
+			status:      job.ErrorStatus,
+			err:         "failed",
+			transitions: []job.Status{job.RunningStatus, job.ErrorStatus},
+		},
+		{
+			name:        "runtime error",
+			params:      job.Parameters{"mode": "panic"},
+			status:      job.ErrorStatus,
+			err:         "runtime error: boom",
+			transitions: []job.Status{job.RunningStatus, job.ErrorStatus},
+		},
+		{
+			name:        "stopped",
+			options:     []Option{StopAtCheckIn(2)},
+			params:      job.Parameters{"mode": "loop"},
+			status:      job.StoppedStatus,
+			transitions: []job.Status{job.RunningStatus, job.StoppedStatus},
+			checkIns:    []string{"step 1", "step 2"},
+			logs:        []string{"INFO: stopped"},
+		},
+		{
+			name:   "invalid parameters",
+			params: job.Parameters{},
+			status: job.PendingStatus,
+			err:    "validate job parameters: missing mode",
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			res := NewHarness(c.options...).Run(&testJob{}, c.params)
+
+			res.AssertStatus(t, c.status)
+			res.AssertTransitions(t, c.transitions...)
+			res.AssertCheckIns(t, c.checkIns...)
+
+			switch {
+			case len(c.err) == 0 && res.Err != nil:
+				t.Errorf("expect no error but got %s", res.Err)
+			case len(c.err) > 0 && (res.Err == nil || !strings.Contains(res.Err.Error(), c.err)):
+				t.Errorf("expect error %q but got %v", c.err, res.Err)
+			}
+
+			logs := make([]string, 0)
+			for _, l := range res.Logs {
+				logs = append(logs, l.String())
+			}
+			if strings.Join(logs, "\n") != strings.Join(c.logs, "\n") {
+				t.Errorf("expect logs %v but got %v", c.logs, logs)
+			}
+
+			if res.Stopped() != (c.status == job.StoppedStatus) {
+				t.Errorf("expect stopped %v but got %v", c.status == job.StoppedStatus, res.Stopped())
+			}
+			if res.Stats == nil || job.Status(res.Stats.Info.Status) != c.status {
+				t.Errorf("expect stats with status %s but got %+v", c.status, res.Stats)
+			}
+		})
+	}
+}
+
+func TestHarnessStopWithoutRunningJob(t *testing.T) {
+	if err := NewHarness().Stop(); err == nil {
+		t.Error("expect error of stopping without running job but got nil")
+	}
+}
@@ -0,0 +1,141 @@
+package jobtest
+
+import (
+	"fmt"
+	"github.com/chenxull/goGridhub/gridhub/src/jobservice/job"
+	"sync"
+)
+
+// Levels of the captured logs
+const (
+	LevelDebug   = "DEBUG"
+	LevelInfo    = "INFO"
+	LevelWarning = "WARNING"
+	LevelError   = "ERROR"
+	LevelFatal   = "FATAL"
+)
+
+// LogEntry is the log written by the job via the logger of the context
+type LogEntry struct {
+	Level   string
+	Message string
+}
+
+// String returns the entry in the format of "LEVEL: message"
+func (le LogEntry) String() string {
+	return fmt.Sprintf("%s: %s", le.Level, le.Message)
+}
+
+// recorder captures what happened during running the job
+type recorder struct {
+	lock        sync.Mutex
+	checkIns    []string
+	logs        []LogEntry
+	transitions []job.Status
+	opCommands  []job.OPCommand
+}
+
+func newRecorder() *recorder {
+	return &recorder{
+		checkIns:    make([]string, 0),
+		logs:        make([]LogEntry, 0),
+		transitions: make([]job.Status, 0),
+		opCommands:  make([]job.OPCommand, 0),
+	}
+}
+
+func (r *recorder) checkIn(message string) int {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	r.checkIns = append(r.checkIns, message)
+
+	return len(r.checkIns)
+}
+
+func (r *recorder) log(level string, message string) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	r.logs = append(r.logs, LogEntry{Level: level, Message: message})
+}
+
+func (r *recorder) transit(status job.Status) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	r.transitions = append(r.transitions, status)
+}
+
+func (r *recorder) opCommand(cmd job.OPCommand) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	r.opCommands = append(r.opCommands, cmd)
+}
+
+// fill the result with the copies of the captured data
+func (r *recorder) fill(res *Result) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	res.CheckIns = append([]string{}, r.checkIns...)
+	res.Logs = append([]LogEntry{}, r.logs...)
+	res.Transitions = append([]job.Status{}, r.transitions...)
+	res.OPCommands = append([]job.OPCommand{}, r.opCommands...)
+}
+
+// recordingLogger implements logger.Interface and captures the logs
+type recordingLogger struct {
+	rec *recorder
+}
+
+// Debug implements logger.Interface
+func (rl *recordingLogger) Debug(v ...interface{}) {
+	rl.rec.log(LevelDebug, fmt.Sprint(v...))
+}
+
+// Debugf implements logger.Interface
+func (rl *recordingLogger) Debugf(format string, v ...interface{}) {
+	rl.rec.log(LevelDebug, fmt.Sprintf(format, v...))
+}
+
+// Info implements logger.Interface
+func (rl *recordingLogger) Info(v ...interface{}) {
+	rl.rec.log(LevelInfo, fmt.Sprint(v...))
+}
+
+// Infof implements logger.Interface
+func (rl *recordingLogger) Infof(format string, v ...interface{}) {
+	rl.rec.log(LevelInfo, fmt.Sprintf(format, v...))
+}
+
+// Warning implements logger.Interface
+func (rl *recordingLogger) Warning(v ...interface{}) {
+	rl.rec.log(LevelWarning, fmt.Sprint(v...))
+}
+
+// Warningf implements logger.Interface
+func (rl *recordingLogger) Warningf(format string, v ...interface{}) {
+	rl.rec.log(LevelWarning, fmt.Sprintf(format, v...))
+}
+
+// Error implements logger.Interface
+func (rl *recordingLogger) Error(v ...interface{}) {
+	rl.rec.log(LevelError, fmt.Sprint(v...))
+}
+
+// Errorf implements logger.Interface
+func (rl *recordingLogger) Errorf(format string, v ...interface{}) {
+	rl.rec.log(LevelError, fmt.Sprintf(format, v...))
+}
+
+// Fatal implements logger.Interface, the process is not exited
+func (rl *recordingLogger) Fatal(v ...interface{}) {
+	rl.rec.log(LevelFatal, fmt.Sprint(v...))
+}
+
+// Fatalf implements logger.Interface, the process is not exited
+func (rl *recordingLogger) Fatalf(format string, v ...interface{}) {
+	rl.rec.log(LevelFatal, fmt.Sprintf(format, v...))
+}
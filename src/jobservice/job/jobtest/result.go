@@ -0,0 +1,71 @@
+package jobtest
+
+import (
+	"github.com/chenxull/goGridhub/gridhub/src/jobservice/job"
+	"reflect"
+	"testing"
+)
+
+// Result keeps what happened during running the job
+type Result struct {
+	// The final status of the job
+	Status job.Status
+	// The error returned by the job or the runtime error
+	Err error
+	// The latest stats of the job
+	Stats *job.Stats
+	// The check in messages in order
+	CheckIns []string
+	// The logs written via the logger of the job context
+	Logs []LogEntry
+	// The status transitions in order, e.g: Running, Success
+	Transitions []job.Status
+	// The OP commands returned to the job in order
+	OPCommands []job.OPCommand
+}
+
+// Stopped returns true if the job got the stop command
+func (r *Result) Stopped() bool {
+	for _, cmd := range r.OPCommands {
+		if cmd.IsStop() {
+			return true
+		}
+	}
+
+	return false
+}
+
+// AssertStatus fails the test if the final status is not the expected one
+func (r *Result) AssertStatus(t testing.TB, expected job.Status) {
+	t.Helper()
+
+	if r.Status != expected {
+		t.Errorf("expect final status %s but got %s, error: %v", expected, r.Status, r.Err)
+	}
+}
+
+// AssertTransitions fails the test if the status transitions are not the expected ones
+func (r *Result) AssertTransitions(t testing.TB, expected ...job.Status) {
+	t.Helper()
+
+	if len(expected) == 0 && len(r.Transitions) == 0 {
+		return
+	}
+
+	if !reflect.DeepEqual(r.Transitions, expected) {
+		t.Errorf("expect status transitions %v but got %v", expected, r.Transitions)
+	}
+}
+
+// AssertCheckIns fails the test if the check in messages are not the expected ones
+func (r *Result) AssertCheckIns(t testing.TB, expected ...string) {
+	t.Helper()
+
+	if len(expected) == 0 && len(r.CheckIns) == 0 {
+		return
+	}
+
+	if !reflect.DeepEqual(r.CheckIns, expected) {
+		t.Errorf("expect check ins %v but got %v", expected, r.CheckIns)
+	}
+}
@@ -0,0 +1,54 @@
+package jobtest
+
+import (
+	"github.com/chenxull/goGridhub/gridhub/src/jobservice/job"
+)
+
+// recordingTracker wraps the in-memory tracker to capture the status transitions
+type recordingTracker struct {
+	job.Tracker
+	rec *recorder
+}
+
+// UpdateStatusWithRetry implements job.Tracker
+func (rt *recordingTracker) UpdateStatusWithRetry(targetStatus job.Status) error {
+	return rt.transit(func() error {
+		return rt.Tracker.UpdateStatusWithRetry(targetStatus)
+	})
+}
+
+// Run implements job.Tracker
+func (rt *recordingTracker) Run() error {
+	return rt.transit(rt.Tracker.Run)
+}
+
+// Stop implements job.Tracker
+func (rt *recordingTracker) Stop() error {
+	return rt.transit(rt.Tracker.Stop)
+}
+
+// Fail implements job.Tracker
+func (rt *recordingTracker) Fail() error {
+	return rt.transit(rt.Tracker.Fail)
+}
+
+// Succeed implements job.Tracker
+func (rt *recordingTracker) Succeed() error {
+	return rt.transit(rt.Tracker.Succeed)
+}
+
+// Reset implements job.Tracker
+func (rt *recordingTracker) Reset() error {
+	return rt.transit(rt.Tracker.Reset)
+}
+
+// transit records the status if it's changed by the switching
+func (rt *recordingTracker) transit(switching func() error) error {
+	before, _ := rt.Tracker.Status()
+	err := switching()
+	if after, er := rt.Tracker.Status(); er == nil && after != before {
+		rt.rec.transit(after)
+	}
+
+	return err
+}
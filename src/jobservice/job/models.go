@@ -1,6 +1,7 @@
 package job
 
 import (
+	"encoding/json"
 	"github.com/chenxull/goGridhub/gridhub/src/jobservice/common/utils"
 	"github.com/pkg/errors"
 )
@@ -10,6 +11,11 @@ type Parameters map[string]interface{}
 
 type Request struct {
 	Job *RequestBody `json:"job"`
+
+	// Caller who submits the request, set by the API server
+	Caller string `json:"-"`
+	// Key to avoid launching the same job twice, set by the API server
+	IdempotencyKey string `json:"-"`
 }
 
 type RequestBody struct {
@@ -25,6 +31,23 @@ type Metadata struct {
 	ScheduleDelay uint64 `json:"schedule_delay,omitempty"`
 	Cron          string `json:"cron_spec,omitempty"`
 	IsUnique      bool   `json:"unique"`
+	Priority      string `json:"priority,omitempty"`
+	// Parameter keys defining the uniqueness, empty means all the parameters
+	UniqueKeys []string `json:"unique_keys,omitempty"`
+	// Seconds of keeping the uniqueness, 0 means the default 24 hours
+	UniqueTTL uint64 `json:"unique_ttl,omitempty"`
+	// Parameter keys of the secrets besides the ones declared by the job
+	SecretKeys []string `json:"secret_keys,omitempty"`
+	// Interval, one-off runs and blackout windows of the periodic job
+	Schedule *ScheduleSpec `json:"schedule,omitempty"`
+}
+
+// UniqueOptions defines how the uniqueness of the job is checked.
+type UniqueOptions struct {
+	// Parameter keys defining the uniqueness, empty means all the parameters
+	Keys []string
+	// Seconds of keeping the uniqueness
+	TTL uint64
 }
 
 // Stats keeps the result of job launching.
@@ -51,6 +74,18 @@ type StatsInfo struct {
 	NumericPID    int64      `json:"numeric_policy_id,omitempty"` // The numeric policy ID of the periodic job
 	Parameters    Parameters `json:"parameters,omitempty"`
 	Revision      int64      `json:"revision,omitempty"` // For differentiating the each retry of the same job
+	NodeID        string     `json:"node_id,omitempty"`  // The node which runs the job
+	// Interval, one-off runs and blackout windows of the periodic job
+	Schedule *ScheduleSpec `json:"schedule,omitempty"`
+}
+
+// TypeInfo describes the registered job type
+type TypeInfo struct {
+	Name        string `json:"name"`
+	MaxFails    uint   `json:"max_fails"`
+	ShouldRetry bool   `json:"should_retry"`
+	// JSON schema of the parameters, empty if the job does not declare it
+	ParametersSchema json.RawMessage `json:"parameters_schema,omitempty"`
 }
 
 // ActionRequest defines for triggering job action like stop/cancel.
@@ -58,6 +93,23 @@ type ActionRequest struct {
 	Action string `json:"action"`
 }
 
+// Actions supported by the scheduled jobs
+const (
+	// ScheduleActionReschedule moves the scheduled job to the new run time
+	ScheduleActionReschedule = "reschedule"
+	// ScheduleActionRun runs the scheduled job immediately
+	ScheduleActionRun = "run"
+	// ScheduleActionCancel cancels the scheduled job before it runs
+	ScheduleActionCancel = "cancel"
+)
+
+// ScheduleActionRequest defines for triggering the action of the scheduled job.
+type ScheduleActionRequest struct {
+	Action string `json:"action"`
+	// Unix timestamp of the new run time, required by the action 'reschedule'
+	RunAt int64 `json:"run_at,omitempty"`
+}
+
 // StatusChange is designed for reporting the status change via hook.
 type StatusChange struct {
 	JobID    string     `json:"job_id"`
@@ -101,8 +153,8 @@ func (st *Stats) Validate() error {
 	}
 
 	if st.Info.JobKind == KindPeriodic {
-		if utils.IsEmptyStr(st.Info.CronSpec) {
-			return errors.New("missing cron spec for periodic job")
+		if utils.IsEmptyStr(st.Info.CronSpec) && st.Info.Schedule == nil {
+			return errors.New("missing cron spec or schedule for periodic job")
 		}
 	}
 
@@ -0,0 +1,414 @@
+// Package plugin runs the jobs with the external executables, so the jobs can be shipped
+// in separate binaries and upgraded independently of the job service.
+// See protocol.go for the messages exchanged with the executable.
+package plugin
+
+import (
+	"bufio"
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"github.com/chenxull/goGridhub/gridhub/src/jobservice/config"
+	"github.com/chenxull/goGridhub/gridhub/src/jobservice/job"
+	"github.com/chenxull/goGridhub/gridhub/src/jobservice/logger"
+	"github.com/pkg/errors"
+	"io"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// Interval of checking the stop command of the running job
+	checkStopInterval = 2 * time.Second
+	// Wait for the executable exiting after sending the stop command
+	defaultStopGrace = 10 * time.Second
+	// Timeout of validating the parameters
+	validateTimeout = 30 * time.Second
+	// Max size of one message line
+	maxMessageSize = 1024 * 1024
+	// Max number of the executables validating the parameters at the same time
+	maxValidatingProcesses = 4
+	// Max number of the validation results kept, the results are dropped once it's reached
+	maxValidationResults = 1024
+)
+
+// Job implements job.Interface by launching the external executable for each run
+type Job struct {
+	cfg *config.PluginConfig
+	// Limit the executables validating the parameters
+	validating chan struct{}
+	// Results of validating the parameters keyed by the identity of the executable and
+	// the digest of the parameters, so the same parameters are not validated by the same
+	// executable again. The results of the replaced executable are not matched any more.
+	lock      sync.Mutex
+	validated map[string]error
+}
+
+// NewJob is constructor of Job
+func NewJob(cfg *config.PluginConfig) *Job {
+	return &Job{
+		cfg:        cfg,
+		validating: make(chan struct{}, maxValidatingProcesses),
+		validated:  make(map[string]error),
+	}
+}
+
+// New implements job.Factory. The job keeps no state of the runs, so it's shared.
+func (j *Job) New() job.Interface {
+	return j
+}
+
+// MaxFalis implements job.Interface
+func (j *Job) MaxFalis() uint {
+	return j.cfg.MaxFails
+}
+
+// ShouldRetry implements job.Interface
+func (j *Job) ShouldRetry() bool {
+	return j.cfg.Retry
+}
+
+// ParametersSchema implements job.SchemaProvider
+func (j *Job) ParametersSchema() string {
+	return j.cfg.ParametersSchema
+}
+
+// SecretParameters implements job.SecretProvider
+func (j *Job) SecretParameters() []string {
+	return j.cfg.SecretParameters
+}
+
+// Validate implements job.Interface.
+// The parameters are validated by the executable only if it's enabled in the config.
+// The result is kept for the same parameters and executable to avoid launching the executable for each job.
+func (j *Job) Validate(params job.Parameters) error {
+	if !j.cfg.Validate {
+		return nil
+	}
+
+	identity, err := j.executableIdentity()
+	if err != nil {
+		return err
+	}
+	digest, err := paramsDigest(params)
+	if err != nil {
+		return err
+	}
+	key := identity + ":" + digest
+	if ok, err := j.validationResult(key); ok {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), validateTimeout)
+	defer cancel()
+
+	select {
+	case j.validating <- struct{}{}:
+		defer func() {
+			<-j.validating
+		}()
+	case <-ctx.Done():
+		return errors.Errorf("validate parameters with plugin %s: too many validations in progress", j.cfg.Name)
+	}
+
+	p, err := j.start(ctx)
+	if err != nil {
+		return err
+	}
+
+	if err := p.enc.send(&Message{Type: MessageValidate, Params: params}); err != nil {
+		_ = p.cmd.Process.Kill()
+	}
+	// Nothing else is sent for validating
+	p.closeInput()
+
+	var result *Message
+	for msg := range p.messages {
+		if msg.Type == MessageResult {
+			result = msg
+		}
+	}
+
+	waitErr := p.cmd.Wait()
+	if result != nil {
+		if len(result.Error) > 0 {
+			err = errors.New(result.Error)
+		}
+		j.keepValidationResult(key, err)
+		return err
+	}
+	if waitErr != nil {
+		return errors.Wrapf(waitErr, "validate parameters with plugin %s", j.cfg.Name)
+	}
+
+	return nil
+}
+
+// validationResult returns the kept result of validating the parameters with the key
+func (j *Job) validationResult(key string) (bool, error) {
+	j.lock.Lock()
+	defer j.lock.Unlock()
+
+	err, ok := j.validated[key]
+	return ok, err
+}
+
+// keepValidationResult keeps the result of validating the parameters with the key
+func (j *Job) keepValidationResult(key string, err error) {
+	j.lock.Lock()
+	defer j.lock.Unlock()
+
+	if len(j.validated) >= maxValidationResults {
+		j.validated = make(map[string]error)
+	}
+	j.validated[key] = err
+}
+
+// Run implements job.Interface
+func (j *Job) Run(ctx job.Context, params job.Parameters) error {
+	sysCtx := ctx.SystemContext()
+	if sysCtx == nil {
+		sysCtx = context.Background()
+	}
+	logFn := logFunc(ctx)
+
+	p, err := j.start(sysCtx)
+	if err != nil {
+		return err
+	}
+
+	run := &Message{
+		Type:    MessageRun,
+		JobName: j.cfg.Name,
+		Params:  params,
+	}
+	if t := ctx.Tracker(); t != nil && t.Job() != nil {
+		run.JobID = t.Job().Info.JobID
+	}
+	if err := p.enc.send(run); err != nil {
+		_ = p.cmd.Process.Kill()
+		p.closeInput()
+	}
+
+	grace := defaultStopGrace
+	if j.cfg.StopGraceSeconds > 0 {
+		grace = time.Duration(j.cfg.StopGraceSeconds) * time.Second
+	}
+
+	ticker := time.NewTicker(checkStopInterval)
+	defer ticker.Stop()
+
+	var (
+		result  *Message
+		stopped bool
+		kill    <-chan time.Time
+	)
+
+	// All the calls of the job context are done in this goroutine
+	messages := p.messages
+	for messages != nil {
+		select {
+		case msg, ok := <-messages:
+			if !ok {
+				messages = nil
+				break
+			}
+
+			switch msg.Type {
+			case MessageCheckIn:
+				if err := ctx.Checkin(msg.Message); err != nil {
+					logFn("ERROR", fmt.Sprintf("check in of plugin %s error: %s", j.cfg.Name, err))
+				}
+			case MessageLog:
+				logFn(msg.Level, msg.Message)
+			case MessageResult:
+				result = msg
+			default:
+				logFn("WARNING", fmt.Sprintf("unknown message from plugin %s: %s", j.cfg.Name, msg.Type))
+			}
+		case <-ticker.C:
+			if stopped {
+				break
+			}
+			if cmd, ok := ctx.OPCommand(); ok && cmd.IsStop() {
+				stopped = true
+				logFn("INFO", fmt.Sprintf("send stop command to plugin %s", j.cfg.Name))
+				if err := p.enc.send(&Message{Type: MessageStop}); err != nil {
+					_ = p.cmd.Process.Kill()
+				}
+				kill = time.After(grace)
+			}
+		case <-kill:
+			logFn("WARNING", fmt.Sprintf("plugin %s is not exited in %s after stop command, kill it", j.cfg.Name, grace))
+			_ = p.cmd.Process.Kill()
+		}
+	}
+
+	// No more commands after the outputs are closed
+	p.closeInput()
+	waitErr := p.cmd.Wait()
+
+	// The status of the stopped job is handled by the runner
+	if stopped {
+		return nil
+	}
+
+	if result != nil {
+		if len(result.Error) > 0 {
+			return errors.New(result.Error)
+		}
+		return nil
+	}
+
+	if waitErr != nil {
+		return errors.Wrapf(waitErr, "run plugin %s", j.cfg.Name)
+	}
+
+	return nil
+}
+
+// process is the running executable
+type process struct {
+	cmd   *exec.Cmd
+	stdin io.WriteCloser
+	enc   *encoder
+	// Messages from the stdout and stderr, closed after both of them are closed
+	messages <-chan *Message
+}
+
+// closeInput closes the stdin of the executable, then it gets EOF when reading the messages
+func (p *process) closeInput() {
+	if err := p.stdin.Close(); err != nil {
+		logger.Debugf("close stdin of plugin error: %s", err)
+	}
+}
+
+// executableIdentity identifies the executable with its path, size and modification time,
+// so the upgraded executable is told from the previous one.
+func (j *Job) executableIdentity() (string, error) {
+	path, err := exec.LookPath(j.cfg.Path)
+	if err != nil {
+		return "", errors.Wrapf(err, "find plugin %s", j.cfg.Name)
+	}
+	fi, err := os.Stat(path)
+	if err != nil {
+		return "", errors.Wrapf(err, "find plugin %s", j.cfg.Name)
+	}
+
+	return fmt.Sprintf("%s:%d:%d", path, fi.Size(), fi.ModTime().UnixNano()), nil
+}
+
+// start the executable and read the messages from it
+func (j *Job) start(ctx context.Context) (*process, error) {
+	cmd := exec.CommandContext(ctx, j.cfg.Path, j.cfg.Args...)
+	cmd.Env = append(os.Environ(), j.cfg.Env...)
+	cmd.Env = append(cmd.Env, fmt.Sprintf("%s=%s", ProtocolVersionEnv, ProtocolVersion))
+
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return nil, err
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		_ = stdin.Close()
+		return nil, err
+	}
+	stderr, err := cmd.StderrPipe()
+	if err != nil {
+		_ = stdin.Close()
+		_ = stdout.Close()
+		return nil, err
+	}
+
+	// The pipes are closed by the command if it's failed to start
+	if err := cmd.Start(); err != nil {
+		return nil, errors.Wrapf(err, "start plugin %s", j.cfg.Name)
+	}
+
+	messages := make(chan *Message)
+	wg := &sync.WaitGroup{}
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		readLines(stdout, func(line string) {
+			msg := &Message{}
+			if err := json.Unmarshal([]byte(line), msg); err != nil {
+				// Treat the non protocol output as log
+				msg = &Message{Type: MessageLog, Level: "INFO", Message: line}
+			}
+			messages <- msg
+		})
+	}()
+	go func() {
+		defer wg.Done()
+		readLines(stderr, func(line string) {
+			messages <- &Message{Type: MessageLog, Level: "INFO", Message: line}
+		})
+	}()
+	go func() {
+		wg.Wait()
+		close(messages)
+	}()
+
+	return &process{
+		cmd:      cmd,
+		stdin:    stdin,
+		enc:      &encoder{w: stdin},
+		messages: messages,
+	}, nil
+}
+
+// paramsDigest returns the digest of the parameters, the keys are sorted when marshaling
+func paramsDigest(params job.Parameters) (string, error) {
+	rawJSON, err := json.Marshal(params)
+	if err != nil {
+		return "", err
+	}
+
+	return fmt.Sprintf("%x", sha256.Sum256(rawJSON)), nil
+}
+
+func readLines(r io.Reader, handle func(line string)) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxMessageSize)
+	for scanner.Scan() {
+		if line := strings.TrimSpace(scanner.Text()); len(line) > 0 {
+			handle(line)
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		logger.Errorf("read output of plugin error: %s", err)
+		// Drain the left output to avoid blocking the executable
+		_, _ = io.Copy(ioutil.Discard, r)
+	}
+}
+
+// logFunc returns the func writing the log with the job logger if it's available,
+// otherwise the service logger is used.
+func logFunc(ctx job.Context) func(level string, message string) {
+	debug, info, warning, errorf := logger.Debug, logger.Info, logger.Warning, logger.Error
+	if lg, ok := ctx.(interface{ GetLogger() logger.Interface }); ok {
+		if l := lg.GetLogger(); l != nil {
+			debug, info, warning, errorf = l.Debug, l.Info, l.Warning, l.Error
+		}
+	}
+
+	return func(level string, message string) {
+		switch strings.ToUpper(level) {
+		case "DEBUG":
+			debug(message)
+		case "WARNING", "WARN":
+			warning(message)
+		case "ERROR", "FATAL":
+			errorf(message)
+		default:
+			info(message)
+		}
+	}
+}
@@ -0,0 +1,235 @@
+package plugin
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/chenxull/goGridhub/gridhub/src/jobservice/config"
+	"github.com/chenxull/goGridhub/gridhub/src/jobservice/job"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// The test binary is launched as the plugin executable with this environment variable
+const helperEnv = "GO_WANT_PLUGIN_HELPER"
+
+// TestHelperProcess is not a real test, it's the plugin executable speaking the stdio protocol.
+// Each validation appends a line to the file in the environment variable HELPER_CALLS.
+func TestHelperProcess(t *testing.T) {
+	if os.Getenv(helperEnv) != "1" {
+		return
+	}
+	defer os.Exit(0)
+
+	out := json.NewEncoder(os.Stdout)
+	in := bufio.NewScanner(os.Stdin)
+	for in.Scan() {
+		msg := &Message{}
+		if err := json.Unmarshal(in.Bytes(), msg); err != nil {
+			fmt.Fprintf(os.Stderr, "malformed message: %s\n", in.Text())
+			os.Exit(2)
+		}
+
+		switch msg.Type {
+		case MessageValidate:
+			if f, err := os.OpenFile(os.Getenv("HELPER_CALLS"), os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600); err == nil {
+				_, _ = f.WriteString("validate\n")
+				_ = f.Close()
+			}
+			if _, ok := msg.Params["image"]; !ok {
+				_ = out.Encode(&Message{Type: MessageResult, Error: "missing image"})
+				return
+			}
+			_ = out.Encode(&Message{Type: MessageResult})
+			return
+		case MessageRun:
+			_ = out.Encode(&Message{Type: MessageCheckIn, Message: "started " + msg.JobID})
+			_ = out.Encode(&Message{Type: MessageLog, Level: "INFO", Message: "running " + msg.JobName})
+			fmt.Fprintln(os.Stderr, "progress from stderr")
+			switch msg.Params["mode"] {
+			case "fail":
+				_ = out.Encode(&Message{Type: MessageResult, Error: "boom"})
+				return
+			case "crash":
+				os.Exit(3)
+			case "stop", "hang":
+				// Wait for the stop command
+				continue
+			}
+			_ = out.Encode(&Message{Type: MessageResult})
+			return
+		case MessageStop:
+			if os.Getenv("HELPER_IGNORE_STOP") == "1" {
+				// Never exits until it's killed
+				time.Sleep(time.Hour)
+			}
+			_ = out.Encode(&Message{Type: MessageLog, Level: "INFO", Message: "stopped"})
+			return
+		}
+	}
+}
+
+// helperJob returns the plugin job launching the test binary as the executable
+func helperJob(t *testing.T, env ...string) (*Job, string) {
+	t.Helper()
+
+	calls := filepath.Join(t.TempDir(), "calls")
+	return NewJob(&config.PluginConfig{
+		Name:             "helper",
+		Path:             os.Args[0],
+		Args:             []string{"-test.run=TestHelperProcess", "--"},
+		Env:              append([]string{helperEnv + "=1", "HELPER_CALLS=" + calls}, env...),
+		Validate:         true,
+		StopGraceSeconds: 1,
+	}), calls
+}
+
+func validations(t *testing.T, calls string) int {
+	t.Helper()
+
+	data, err := ioutil.ReadFile(calls)
+	if os.IsNotExist(err) {
+		return 0
+	}
+	if err != nil {
+		t.Fatalf("read calls error: %s", err)
+	}
+
+	return strings.Count(string(data), "validate")
+}
+
+func TestValidate(t *testing.T) {
+	j, calls := helperJob(t)
+
+	if err := j.Validate(job.Parameters{"image": "library/demo"}); err != nil {
+		t.Fatalf("expect the parameters valid but got %s", err)
+	}
+	if err := j.Validate(job.Parameters{"tag": "latest"}); err == nil || err.Error() != "missing image" {
+		t.Fatalf("expect the parameters rejected by the plugin but got %v", err)
+	}
+
+	// Both of the results are kept
+	_ = j.Validate(job.Parameters{"image": "library/demo"})
+	if err := j.Validate(job.Parameters{"tag": "latest"}); err == nil {
+		t.Error("expect the kept result of the invalid parameters")
+	}
+	if n := validations(t, calls); n != 2 {
+		t.Errorf("expect the plugin launched 2 times but got %d", n)
+	}
+}
+
+func TestValidateUpgradedPlugin(t *testing.T) {
+	// Copy the test binary as the executable to upgrade it
+	data, err := ioutil.ReadFile(os.Args[0])
+	if err != nil {
+		t.Fatalf("read test binary error: %s", err)
+	}
+	path := filepath.Join(t.TempDir(), "plugin")
+	if err := ioutil.WriteFile(path, data, 0700); err != nil {
+		t.Fatalf("write plugin error: %s", err)
+	}
+
+	j, calls := helperJob(t)
+	j.cfg.Path = path
+
+	params := job.Parameters{"image": "library/demo"}
+	_ = j.Validate(params)
+	_ = j.Validate(params)
+	if n := validations(t, calls); n != 1 {
+		t.Fatalf("expect the result kept for the same executable but got %d launches", n)
+	}
+
+	// Upgraded in place
+	later := time.Now().Add(time.Minute)
+	if err := os.Chtimes(path, later, later); err != nil {
+		t.Fatalf("touch plugin error: %s", err)
+	}
+	_ = j.Validate(params)
+	if n := validations(t, calls); n != 2 {
+		t.Errorf("expect the upgraded executable launched to validate again but got %d launches", n)
+	}
+
+	// Missing executable
+	j.cfg.Path = filepath.Join(t.TempDir(), "missing")
+	if err := j.Validate(params); err == nil {
+		t.Error("expect the error of the missing executable")
+	}
+}
+
+// fakeContext records the check-ins and sends the stop command once asked
+type fakeContext struct {
+	lock     sync.Mutex
+	checkIns []string
+	stop     bool
+}
+
+func (fc *fakeContext) Build(tracker job.Tracker) (job.Context, error) { return fc, nil }
+
+func (fc *fakeContext) Get(prop string) (interface{}, bool) { return nil, false }
+
+func (fc *fakeContext) SystemContext() context.Context { return context.Background() }
+
+func (fc *fakeContext) Checkin(status string) error {
+	fc.lock.Lock()
+	defer fc.lock.Unlock()
+
+	fc.checkIns = append(fc.checkIns, status)
+	return nil
+}
+
+func (fc *fakeContext) OPCommand() (job.OPCommand, bool) {
+	fc.lock.Lock()
+	defer fc.lock.Unlock()
+
+	return job.StopCommand, fc.stop
+}
+
+func (fc *fakeContext) Tracker() job.Tracker { return nil }
+
+func TestRun(t *testing.T) {
+	cases := []struct {
+		name    string
+		mode    string
+		env     []string
+		stop    bool
+		errText string
+		// Min duration of the run
+		minDuration time.Duration
+	}{
+		{name: "succeed", mode: "succeed"},
+		{name: "fail", mode: "fail", errText: "boom"},
+		{name: "crash", mode: "crash", errText: "run plugin helper"},
+		{name: "stop", mode: "stop", stop: true},
+		// Killed after the stop grace
+		{name: "kill", mode: "hang", env: []string{"HELPER_IGNORE_STOP=1"}, stop: true, minDuration: checkStopInterval + time.Second},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			j, _ := helperJob(t, c.env...)
+			ctx := &fakeContext{stop: c.stop}
+
+			start := time.Now()
+			err := j.Run(ctx, job.Parameters{"mode": c.mode})
+			if len(c.errText) == 0 && err != nil {
+				t.Fatalf("expect the run succeeded but got %s", err)
+			}
+			if len(c.errText) > 0 && (err == nil || !strings.Contains(err.Error(), c.errText)) {
+				t.Fatalf("expect the error %q but got %v", c.errText, err)
+			}
+			if elapsed := time.Since(start); elapsed < c.minDuration {
+				t.Errorf("expect the run lasting at least %s but got %s", c.minDuration, elapsed)
+			}
+
+			if len(ctx.checkIns) != 1 || ctx.checkIns[0] != "started " {
+				t.Errorf("expect the check in of the plugin but got %v", ctx.checkIns)
+			}
+		})
+	}
+}
@@ -0,0 +1,74 @@
+package plugin
+
+import (
+	"encoding/json"
+	"github.com/chenxull/goGridhub/gridhub/src/jobservice/job"
+	"io"
+	"sync"
+)
+
+// The executable talks with the job service with the line delimited JSON messages.
+// The job service writes the messages to the stdin of the executable:
+//
+//	{"type": "validate", "params": {...}}
+//	{"type": "run", "job_id": "...", "job_name": "...", "params": {...}}
+//	{"type": "stop"}
+//
+// The executable writes the messages to the stdout:
+//
+//	{"type": "checkin", "message": "..."}
+//	{"type": "log", "level": "INFO", "message": "..."}
+//	{"type": "result", "error": "..."}
+//
+// The executable should exit after writing the result. The output of stderr is
+// logged as the job logs. Exit with zero code without the result means success.
+const (
+	// ProtocolVersion is the version of the protocol, it's passed to the executable
+	// with the environment variable ProtocolVersionEnv
+	ProtocolVersion = "1"
+	// ProtocolVersionEnv is the environment variable keeping the protocol version
+	ProtocolVersionEnv = "JOB_PLUGIN_PROTOCOL_VERSION"
+
+	// MessageValidate asks the executable to validate the parameters and exit
+	MessageValidate = "validate"
+	// MessageRun asks the executable to run the job
+	MessageRun = "run"
+	// MessageStop asks the executable to stop the running job and exit
+	MessageStop = "stop"
+	// MessageCheckIn reports the detailed status of the running job
+	MessageCheckIn = "checkin"
+	// MessageLog writes the log of the running job
+	MessageLog = "log"
+	// MessageResult reports the result, the empty error means success
+	MessageResult = "result"
+)
+
+// Message exchanged with the executable
+type Message struct {
+	Type    string         `json:"type"`
+	JobID   string         `json:"job_id,omitempty"`
+	JobName string         `json:"job_name,omitempty"`
+	Params  job.Parameters `json:"params,omitempty"`
+	Level   string         `json:"level,omitempty"`
+	Message string         `json:"message,omitempty"`
+	Error   string         `json:"error,omitempty"`
+}
+
+// encoder writes the messages to the executable
+type encoder struct {
+	lock sync.Mutex
+	w    io.Writer
+}
+
+func (e *encoder) send(msg *Message) error {
+	data, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+
+	e.lock.Lock()
+	defer e.lock.Unlock()
+
+	_, err = e.w.Write(append(data, '\n'))
+	return err
+}
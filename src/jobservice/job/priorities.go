@@ -0,0 +1,10 @@
+package job
+
+const (
+	// PriorityHigh : the job is always admitted even if the system is under pressure
+	PriorityHigh = "high"
+	// PriorityNormal : the default priority, the job is rejected if the system is under pressure
+	PriorityNormal = "normal"
+	// PriorityLow : the job is deferred or rejected if the system is under pressure
+	PriorityLow = "low"
+)
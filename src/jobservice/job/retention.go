@@ -0,0 +1,36 @@
+package job
+
+import (
+	"sync"
+	"time"
+)
+
+// Retention decides how long the stats of the jobs in the final status are kept
+type Retention interface {
+	// KeepFor returns how long the stats of the finished job are kept, 0 means the default
+	KeepFor(info *StatsInfo) time.Duration
+
+	// Archived returns true if the expired stats are archived by the sweeper before being removed
+	Archived() bool
+}
+
+var (
+	retentionLock sync.RWMutex
+	retention     Retention
+)
+
+// SetRetention sets the retention of the job stats, nil means the default one
+func SetRetention(r Retention) {
+	retentionLock.Lock()
+	defer retentionLock.Unlock()
+
+	retention = r
+}
+
+// GetRetention returns the retention of the job stats, nil if it's not set
+func GetRetention() Retention {
+	retentionLock.RLock()
+	defer retentionLock.RUnlock()
+
+	return retention
+}
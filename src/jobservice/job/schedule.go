@@ -0,0 +1,28 @@
+package job
+
+// ScheduleSpec defines the calendar of the periodic job as an alternative of the cron spec.
+// The run times are generated by either the cron spec, the interval or the one-off run list,
+// and the runs falling in any of the blackout windows are skipped.
+type ScheduleSpec struct {
+	Interval *IntervalSpec `json:"interval,omitempty"`
+	// Unix timestamps of the one-off runs
+	RunList   []int64           `json:"run_list,omitempty"`
+	Blackouts []*BlackoutWindow `json:"blackouts,omitempty"`
+}
+
+// IntervalSpec runs the job with the fixed interval from the start time,
+// e.g: every 90 minutes starting at 08:00.
+type IntervalSpec struct {
+	EverySeconds uint64 `json:"every_seconds"`
+	// Unix timestamp of the first run, 0 means the time of scheduling
+	StartAt int64 `json:"start_at,omitempty"`
+}
+
+// BlackoutWindow is the recurring time window in which no runs are enqueued.
+type BlackoutWindow struct {
+	// Cron spec of the window start, e.g: "0 0 9 * * 1-5" is 09:00 of the weekdays
+	Start           string `json:"start"`
+	DurationSeconds uint64 `json:"duration_seconds"`
+	// IANA name of the time zone evaluating the cron spec, empty means the local time zone
+	TimeZone string `json:"time_zone,omitempty"`
+}
@@ -0,0 +1,121 @@
+package job
+
+import (
+	"encoding/json"
+	"github.com/chenxull/goGridhub/gridhub/src/jobservice/common/encrypt"
+	"github.com/pkg/errors"
+)
+
+// RedactedValue replaces the secret parameters in the API responses, logs and hook payloads
+const RedactedValue = "******"
+
+// SecretProvider is implemented by the job declaring the parameters which are secrets.
+// The secret parameters are encrypted at rest and only decrypted for running the job.
+type SecretProvider interface {
+	// SecretParameters returns the keys of the secret parameters
+	SecretParameters() []string
+}
+
+// SealSecrets returns a copy of the parameters with the values of the secret keys encrypted.
+// The deterministic encryption is required if the sealed parameters are compared, e.g: the unique jobs.
+func SealSecrets(params Parameters, keys []string, deterministic bool) (Parameters, error) {
+	if len(params) == 0 || len(keys) == 0 {
+		return params, nil
+	}
+
+	sealed := make(Parameters, len(params))
+	for k, v := range params {
+		sealed[k] = v
+	}
+
+	for _, k := range keys {
+		v, ok := sealed[k]
+		if !ok || encrypt.IsEncrypted(v) {
+			continue
+		}
+
+		// Keep the type of the value
+		data, err := json.Marshal(v)
+		if err != nil {
+			return nil, errors.Wrapf(err, "encrypt parameter %s", k)
+		}
+		encryptFunc := encrypt.Encrypt
+		if deterministic {
+			encryptFunc = encrypt.EncryptDeterministic
+		}
+		text, err := encryptFunc(data)
+		if err != nil {
+			return nil, errors.Wrapf(err, "encrypt parameter %s", k)
+		}
+		sealed[k] = text
+	}
+
+	return sealed, nil
+}
+
+// OpenSecrets returns a copy of the parameters with the encrypted values decrypted
+func OpenSecrets(params Parameters) (Parameters, error) {
+	if !HasSecrets(params) {
+		return params, nil
+	}
+
+	opened := make(Parameters, len(params))
+	for k, v := range params {
+		if !encrypt.IsEncrypted(v) {
+			opened[k] = v
+			continue
+		}
+
+		data, err := encrypt.Decrypt(v.(string))
+		if err != nil {
+			return nil, errors.Wrapf(err, "decrypt parameter %s", k)
+		}
+		var value interface{}
+		if err := json.Unmarshal(data, &value); err != nil {
+			return nil, errors.Wrapf(err, "decrypt parameter %s", k)
+		}
+		opened[k] = value
+	}
+
+	return opened, nil
+}
+
+// RedactSecrets returns a copy of the parameters with the encrypted values redacted
+func RedactSecrets(params Parameters) Parameters {
+	if !HasSecrets(params) {
+		return params
+	}
+
+	redacted := make(Parameters, len(params))
+	for k, v := range params {
+		if encrypt.IsEncrypted(v) {
+			v = RedactedValue
+		}
+		redacted[k] = v
+	}
+
+	return redacted
+}
+
+// HasSecrets checks if the parameters contain the encrypted values
+func HasSecrets(params Parameters) bool {
+	for _, v := range params {
+		if encrypt.IsEncrypted(v) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Redacted returns a copy of the stats with the secret parameters redacted
+func (st *Stats) Redacted() *Stats {
+	if st == nil || st.Info == nil || !HasSecrets(st.Info.Parameters) {
+		return st
+	}
+
+	info := *st.Info
+	info.Parameters = RedactSecrets(info.Parameters)
+
+	return &Stats{Info: &info}
+}
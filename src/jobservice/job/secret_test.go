@@ -0,0 +1,113 @@
+package job
+
+import (
+	"encoding/base64"
+	"github.com/chenxull/goGridhub/gridhub/src/jobservice/common/encrypt"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestSealOpenSecrets(t *testing.T) {
+	encrypt.SetKey("secret")
+	t.Cleanup(func() {
+		encrypt.SetKey("")
+	})
+
+	params := Parameters{
+		"user":     "alice",
+		"password": "p@ss",
+		"token":    map[string]interface{}{"value": "t0k", "ttl": float64(60)},
+	}
+	keys := []string{"password", "token", "missing"}
+
+	for _, deterministic := range []bool{false, true} {
+		sealed, err := SealSecrets(params, keys, deterministic)
+		if err != nil {
+			t.Fatalf("seal secrets error: %s", err)
+		}
+		if sealed["user"] != "alice" || !encrypt.IsEncrypted(sealed["password"]) || !encrypt.IsEncrypted(sealed["token"]) {
+			t.Fatalf("expect only the secret parameters encrypted but got %v", sealed)
+		}
+		if _, ok := sealed["missing"]; ok {
+			t.Error("expect the missing secret parameter not added")
+		}
+		if params["password"] != "p@ss" {
+			t.Error("expect the original parameters not changed")
+		}
+
+		// Sealed ones are kept
+		again, err := SealSecrets(sealed, keys, deterministic)
+		if err != nil || again["password"] != sealed["password"] {
+			t.Errorf("expect the sealed parameter kept but got %v, %v", again["password"], err)
+		}
+
+		opened, err := OpenSecrets(sealed)
+		if err != nil {
+			t.Fatalf("open secrets error: %s", err)
+		}
+		if !reflect.DeepEqual(opened, params) {
+			t.Errorf("expect the opened parameters %v but got %v", params, opened)
+		}
+	}
+
+	// The unique sign is computed with the deterministically sealed parameters
+	s1, _ := SealSecrets(params, keys, true)
+	s2, _ := SealSecrets(params, keys, true)
+	if !reflect.DeepEqual(s1, s2) {
+		t.Error("expect the same parameters sealed to the same values deterministically")
+	}
+	r1, _ := SealSecrets(params, keys, false)
+	r2, _ := SealSecrets(params, keys, false)
+	if r1["password"] == r2["password"] {
+		t.Error("expect the same parameters sealed to the different values randomly")
+	}
+}
+
+func TestOpenTamperedSecrets(t *testing.T) {
+	encrypt.SetKey("secret")
+	t.Cleanup(func() {
+		encrypt.SetKey("")
+	})
+
+	sealed, err := SealSecrets(Parameters{"password": "p@ss"}, []string{"password"}, false)
+	if err != nil {
+		t.Fatalf("seal secrets error: %s", err)
+	}
+	data, _ := base64.RawURLEncoding.DecodeString(strings.TrimPrefix(sealed["password"].(string), encrypt.Prefix))
+	data[len(data)-1] ^= 0x01
+	sealed["password"] = encrypt.Prefix + base64.RawURLEncoding.EncodeToString(data)
+
+	if _, err := OpenSecrets(sealed); err == nil || !strings.Contains(err.Error(), "decrypt parameter password") {
+		t.Errorf("expect the tampered parameter rejected but got %v", err)
+	}
+}
+
+func TestRedactSecrets(t *testing.T) {
+	encrypt.SetKey("secret")
+	t.Cleanup(func() {
+		encrypt.SetKey("")
+	})
+
+	plain := Parameters{"user": "alice"}
+	if redacted := RedactSecrets(plain); !reflect.DeepEqual(redacted, plain) {
+		t.Errorf("expect the parameters without secrets kept but got %v", redacted)
+	}
+
+	sealed, err := SealSecrets(Parameters{"user": "alice", "password": "p@ss"}, []string{"password"}, false)
+	if err != nil {
+		t.Fatalf("seal secrets error: %s", err)
+	}
+	redacted := RedactSecrets(sealed)
+	if redacted["user"] != "alice" || redacted["password"] != RedactedValue {
+		t.Errorf("expect only the password redacted but got %v", redacted)
+	}
+	if !encrypt.IsEncrypted(sealed["password"]) {
+		t.Error("expect the sealed parameters not changed")
+	}
+
+	st := &Stats{Info: &StatsInfo{JobID: "job-1", Parameters: sealed}}
+	if r := st.Redacted(); r == st || r.Info.Parameters["password"] != RedactedValue || st.Info.Parameters["password"] == RedactedValue {
+		t.Errorf("expect a redacted copy of the stats but got %v", r.Info.Parameters)
+	}
+}
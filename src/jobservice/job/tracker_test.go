@@ -0,0 +1,95 @@
+package job
+
+import (
+	"context"
+	"github.com/alicebob/miniredis/v2"
+	"github.com/chenxull/goGridhub/gridhub/src/jobservice/common/rds"
+	"github.com/gomodule/redigo/redis"
+	"strings"
+	"testing"
+	"time"
+)
+
+const testNamespace = "{job_test}"
+
+// archivedRetention keeps the stats of all the jobs for one hour and archives them
+type archivedRetention struct{}
+
+func (archivedRetention) KeepFor(info *StatsInfo) time.Duration { return time.Hour }
+
+func (archivedRetention) Archived() bool { return true }
+
+func newRunningTracker(t *testing.T, jobID string) (Tracker, *miniredis.Miniredis) {
+	t.Helper()
+
+	mr := miniredis.RunT(t)
+	pool := &redis.Pool{
+		Dial: func() (redis.Conn, error) {
+			return redis.Dial("tcp", mr.Addr())
+		},
+	}
+	t.Cleanup(func() {
+		_ = pool.Close()
+	})
+
+	tracker := NewBasicTrackerWithStats(context.Background(), &Stats{
+		Info: &StatsInfo{
+			JobID:       jobID,
+			JobName:     "demo",
+			JobKind:     KindGeneric,
+			Status:      RunningStatus.String(),
+			EnqueueTime: time.Now().Unix(),
+		},
+	}, testNamespace, pool, nil)
+	if err := tracker.Save(); err != nil {
+		t.Fatalf("save job stats error: %s", err)
+	}
+
+	return tracker, mr
+}
+
+func TestSucceedRetain(t *testing.T) {
+	tracker, mr := newRunningTracker(t, "job-1")
+
+	if err := tracker.Succeed(); err != nil {
+		t.Fatalf("succeed job error: %s", err)
+	}
+	if ttl := mr.TTL(rds.KeyJobStats(testNamespace, "job-1")); ttl != statDataExpireTimeForSuccess*time.Second {
+		t.Errorf("expect the stats of the successful job kept %ds but got %v", statDataExpireTimeForSuccess, ttl)
+	}
+}
+
+func TestFinishedRetainError(t *testing.T) {
+	SetRetention(archivedRetention{})
+	t.Cleanup(func() {
+		SetRetention(nil)
+	})
+
+	cases := []struct {
+		status Status
+		finish func(tracker Tracker) error
+	}{
+		{status: SuccessStatus, finish: Tracker.Succeed},
+		{status: ErrorStatus, finish: Tracker.Fail},
+		{status: StoppedStatus, finish: Tracker.Stop},
+	}
+
+	for _, c := range cases {
+		t.Run(c.status.String(), func(t *testing.T) {
+			tracker, mr := newRunningTracker(t, "job-1")
+			// Break the retention index
+			if err := mr.Set(rds.KeyRetention(testNamespace), "broken"); err != nil {
+				t.Fatalf("set retention index error: %s", err)
+			}
+
+			err := c.finish(tracker)
+			if err == nil || !strings.Contains(err.Error(), "retain job stats") {
+				t.Fatalf("expect the retention error returned but got %v", err)
+			}
+			// The status is changed anyway
+			if status, err := tracker.Status(); err != nil || status != c.status {
+				t.Errorf("expect the status %s but got %s, %v", c.status, status, err)
+			}
+		})
+	}
+}
@@ -1,4 +1,5 @@
 package job
 
-// HookCallback defines a callback to trigger when hook events happened
+// HookCallback defines a callback to trigger when hook events happened.
+// It's called for every status change, the hook URL is empty if no hook is registered.
 type HookCallback func(hookURL string, change *StatusChange) error
@@ -175,16 +175,8 @@ func Init(ctx context.Context) error {
 	// For sweepers
 	sOptions := make([]Option, 0)
 
-	for _, lc := range config.DefaultConfig.LoggerConfigs {
-		// Inject logger depth here for FILE and STD logger to avoid configuring it in the yaml
-		// For logger of job service itself, the depth should be 6
-		if lc.Name == NameFile || lc.Name == NameStdOutput {
-			if lc.Settings == nil {
-				lc.Settings = map[string]interface{}{}
-			}
-			lc.Settings["depth"] = 6
-		}
-		options = append(options, BackendOption(lc.Name, lc.Level, lc.Settings))
+	for _, lc := range config.Current().LoggerConfigs {
+		options = append(options, serviceLoggerOption(lc))
 		if lc.Sweeper != nil {
 			sOptions = append(sOptions, SweeperOption(lc.Name, lc.Sweeper.Duration, lc.Sweeper.Settings))
 		}
@@ -200,7 +192,7 @@ func Init(ctx context.Context) error {
 
 	jOptions := make([]Option, 0)
 	// Append configured sweepers in job loggers if existing
-	for _, lc := range config.DefaultConfig.JobLoggerConfigs {
+	for _, lc := range config.Current().JobLoggerConfigs {
 		jOptions = append(jOptions, BackendOption(lc.Name, lc.Level, lc.Settings))
 		if lc.Sweeper != nil {
 			sOptions = append(sOptions, SweeperOption(lc.Name, lc.Sweeper.Duration, lc.Sweeper.Settings))
@@ -233,3 +225,42 @@ func Init(ctx context.Context) error {
 
 	return nil
 }
+
+// Reload the loggers of job service itself with the new configurations.
+// The job loggers, the log data getter and the sweepers are not changed.
+func Reload(loggerConfigs []*config.LoggerConfig) error {
+	options := make([]Option, 0, len(loggerConfigs))
+	for _, lc := range loggerConfigs {
+		options = append(options, serviceLoggerOption(lc))
+	}
+
+	// Drop the cached singleton backends, otherwise the new settings are not applied
+	for _, lc := range loggerConfigs {
+		singletons.Delete(lc.Name)
+	}
+
+	lg, err := GetLogger(options...)
+	if err != nil {
+		return err
+	}
+	singletons.Store(systemKeyServiceLogger, lg)
+
+	return nil
+}
+
+// serviceLoggerOption returns the backend option of the logger of job service itself
+func serviceLoggerOption(lc *config.LoggerConfig) Option {
+	// Copy the settings to keep the configuration comparable when reloading
+	settings := make(map[string]interface{}, len(lc.Settings)+1)
+	for k, v := range lc.Settings {
+		settings[k] = v
+	}
+
+	// Inject logger depth here for FILE and STD logger to avoid configuring it in the yaml
+	// For logger of job service itself, the depth should be 6
+	if lc.Name == NameFile || lc.Name == NameStdOutput {
+		settings["depth"] = 6
+	}
+
+	return BackendOption(lc.Name, lc.Level, settings)
+}
@@ -13,6 +13,7 @@ import (
 	"github.com/chenxull/goGridhub/gridhub/src/jobservice/job/impl"
 	"github.com/chenxull/goGridhub/gridhub/src/jobservice/logger"
 	"github.com/chenxull/goGridhub/gridhub/src/jobservice/runtime"
+	"os"
 )
 
 func main() {
@@ -34,19 +35,31 @@ func main() {
 	ctx, cancel := context.WithCancel(vCtx)
 	defer cancel()
 
+	// Export or import the periodic job policies, e.g: 'jobservice -c config.yml policies export -o policies.json'
+	if flag.Arg(0) == "policies" {
+		if err := runtime.JobService.RunPoliciesCommand(ctx, config.DefaultConfig, flag.Args()[1:], os.Stdout); err != nil {
+			fmt.Fprintf(os.Stderr, "policies: %s\n", err)
+			os.Exit(1)
+		}
+		return
+	}
+
 	//todo Initialize logger
 	//if err := logger.Init(ctx); err != nil {
 	//	panic(err)
 	//}
 
 	runtime.JobService.SetJobContextInitializer(func(ctx context.Context) (job.Context, error) {
-		secret := config.GetAuthSecret()
-		if utils.IsEmptyStr(secret) {
+		secrets, err := config.AuthSecrets()
+		if err != nil {
+			return nil, err
+		}
+		if utils.IsEmptyStr(secrets.Current()) {
 			return nil, errors.New("empty auth secret")
 		}
 		coreURL := config.GetCoreURL()
 		configURL := coreURL + common.CoreConfigPath
-		cfgMgr := comcfg.NewRESTCfgManager(configURL, secret)
+		cfgMgr := comcfg.NewRESTCfgManagerWithSecrets(configURL, secrets)
 		jobCtx := impl.NewContext(ctx, cfgMgr)
 
 		if err := jobCtx.Init(); err != nil {
@@ -20,7 +20,7 @@ import (
 // Manager  defies the related operations to handle the management of job stats
 type Manager interface {
 
-	//Get the stats data of all kinds of jobs
+	//Get the stats data of all kinds of jobs, the jobs are filtered by the job name in the query if it's set
 	GetJobs(q *query.Parameter) ([]*job.Stats, int64, error)
 
 	// Get the executions of the specified periodic job by pagination
@@ -37,11 +37,11 @@ type Manager interface {
 	// Get the scheduled jobs
 	//
 	// Arguments:
-	//   q *query.Parameter: query parameters
+	//   q *query.Parameter: query parameters, the jobs are filtered by the job name in it if it's set
 	//
 	// Returns:
 	//   The matched job stats list,
-	//   The total number of the executions,
+	//   The total number of the matched jobs,
 	//   Non nil error if any issues meet.
 	GetScheduledJobs(q *query.Parameter) ([]*job.Stats, int64, error)
 
@@ -65,6 +65,9 @@ type Manager interface {
 	SaveJob(job *job.Stats) error
 }
 
+// scheduledPageSize is the fixed page size of listing the scheduled jobs by the work client
+const scheduledPageSize = 20
+
 // basicManager is the default implementation of @manager,
 type basicManager struct {
 	//system context  稍微复杂一点的结构体都需要context 来携带上下文信息
@@ -89,7 +92,8 @@ func NewManager(ctx context.Context, ns string, pool *redis.Pool) Manager {
 
 // GetJobs is implementation of Manager.GetJobs
 // Because of the hash set used to keep the job stats, we can not support a standard pagination.
-// A cursor is used to fetch the jobs with several batches.
+// A cursor is used to fetch the jobs with several batches. If the jobs are filtered by name, the
+// scanning goes on until the page is filled or completed, so the page may be more than the page size.
 func (bm *basicManager) GetJobs(q *query.Parameter) ([]*job.Stats, int64, error) {
 	cursor, count := int64(0), query.DefaultPageSize
 	if q != nil {
@@ -101,14 +105,38 @@ func (bm *basicManager) GetJobs(q *query.Parameter) ([]*job.Stats, int64, error)
 			cursor = cur.(int64)
 		}
 	}
-	pattern := rds.KeyJobStats(bm.namespace, "*")
-	args := []interface{}{cursor, "MATCH", pattern, "COUNT", count}
+	name := JobNameFilter(q)
 
 	conn := bm.pool.Get()
 	defer func() {
 		_ = conn.Close()
 	}()
 
+	results := make([]*job.Stats, 0)
+	for {
+		batch, nextCur, err := bm.scanJobs(conn, cursor, count)
+		if err != nil {
+			return nil, 0, err
+		}
+
+		for _, st := range batch {
+			if len(name) == 0 || st.Info.JobName == name {
+				results = append(results, st)
+			}
+		}
+
+		cursor = nextCur
+		if len(name) == 0 || cursor == 0 || uint(len(results)) >= count {
+			return results, cursor, nil
+		}
+	}
+}
+
+// scanJobs scans the batch of the job stats from the cursor, the cursor of the next batch is returned
+func (bm *basicManager) scanJobs(conn redis.Conn, cursor int64, count uint) ([]*job.Stats, int64, error) {
+	pattern := rds.KeyJobStats(bm.namespace, "*")
+	args := []interface{}{cursor, "MATCH", pattern, "COUNT", count}
+
 	values, err := redis.Values(conn.Do("SCAN", args...))
 	if err != nil {
 		return nil, 0, err
@@ -116,7 +144,7 @@ func (bm *basicManager) GetJobs(q *query.Parameter) ([]*job.Stats, int64, error)
 	if len(values) != 2 {
 		return nil, 0, errors.New("malform scan results")
 	}
-	nextCur, err := strconv.ParseUint(string(values[0].([]byte)), 10, 8)
+	nextCur, err := strconv.ParseUint(string(values[0].([]byte)), 10, 64)
 	if err != nil {
 		return nil, 0, err
 	}
@@ -139,8 +167,8 @@ func (bm *basicManager) GetJobs(q *query.Parameter) ([]*job.Stats, int64, error)
 			}
 		}
 	}
-	return results, int64(nextCur), nil
 
+	return results, int64(nextCur), nil
 }
 
 func (bm *basicManager) GetPeriodicExecution(pID string, q *query.Parameter) (results []*job.Stats, total int64, err error) {
@@ -231,7 +259,16 @@ func (bm *basicManager) GetScheduledJobs(q *query.Parameter) ([]*job.Stats, int6
 		page = q.PageNumber
 	}
 
-	sJobs, total, err := bm.client.ScheduledJobs(page)
+	var (
+		sJobs []*work.ScheduledJob
+		total int64
+		err   error
+	)
+	if name := JobNameFilter(q); len(name) > 0 {
+		sJobs, total, err = bm.scheduledJobsNamed(name, page)
+	} else {
+		sJobs, total, err = bm.client.ScheduledJobs(page)
+	}
 	if err != nil {
 		return nil, 0, err
 	}
@@ -259,6 +296,54 @@ func (bm *basicManager) GetScheduledJobs(q *query.Parameter) ([]*job.Stats, int6
 	return res, total, nil
 }
 
+// scheduledJobsNamed returns the page of the scheduled jobs with the name and the total count of them.
+// All the scheduled jobs are read to filter them by name before the paginating.
+func (bm *basicManager) scheduledJobsNamed(name string, page uint) ([]*work.ScheduledJob, int64, error) {
+	matched := make([]*work.ScheduledJob, 0)
+	for p := uint(1); ; p++ {
+		sJobs, total, err := bm.client.ScheduledJobs(p)
+		if err != nil {
+			return nil, 0, err
+		}
+
+		for _, sJob := range sJobs {
+			if sJob.Name == name {
+				matched = append(matched, sJob)
+			}
+		}
+
+		if len(sJobs) == 0 || int64(p*scheduledPageSize) >= total {
+			break
+		}
+	}
+
+	start := (page - 1) * scheduledPageSize
+	if start >= uint(len(matched)) {
+		return []*work.ScheduledJob{}, int64(len(matched)), nil
+	}
+	end := start + scheduledPageSize
+	if end > uint(len(matched)) {
+		end = uint(len(matched))
+	}
+
+	return matched[start:end], int64(len(matched)), nil
+}
+
+// JobNameFilter returns the job name filter in the query, empty means the jobs are not filtered by name
+func JobNameFilter(q *query.Parameter) string {
+	if q == nil || q.Extras == nil {
+		return ""
+	}
+
+	v, ok := q.Extras.Get(query.ExtraParamKeyJobName)
+	if !ok {
+		return ""
+	}
+	name, _ := v.(string)
+
+	return name
+}
+
 func (bm *basicManager) GetJob(jobID string) (*job.Stats, error) {
 	if utils.IsEmptyStr(jobID) {
 		return nil, errs.BadRequestError("empty job ID")
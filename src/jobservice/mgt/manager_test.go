@@ -0,0 +1,144 @@
+package mgt
+
+import (
+	"context"
+	"fmt"
+	"github.com/alicebob/miniredis/v2"
+	"github.com/chenxull/goGridhub/gridhub/src/jobservice/common/query"
+	"github.com/chenxull/goGridhub/gridhub/src/jobservice/job"
+	"github.com/gocraft/work"
+	"github.com/gomodule/redigo/redis"
+	"testing"
+	"time"
+)
+
+const testNamespace = "{mgt_test}"
+
+func newTestManager(t *testing.T) (Manager, *redis.Pool) {
+	t.Helper()
+
+	mr := miniredis.RunT(t)
+	pool := &redis.Pool{
+		Dial: func() (redis.Conn, error) {
+			return redis.Dial("tcp", mr.Addr())
+		},
+	}
+	t.Cleanup(func() {
+		_ = pool.Close()
+	})
+
+	return NewManager(context.Background(), testNamespace, pool), pool
+}
+
+func saveJob(t *testing.T, m Manager, id, name string, status job.Status) {
+	t.Helper()
+
+	err := m.SaveJob(&job.Stats{
+		Info: &job.StatsInfo{
+			JobID:       id,
+			JobName:     name,
+			JobKind:     job.KindGeneric,
+			Status:      status.String(),
+			EnqueueTime: time.Now().Unix(),
+		},
+	})
+	if err != nil {
+		t.Fatalf("save job %s error: %s", id, err)
+	}
+}
+
+func namedQuery(name string, pageSize uint, cursor int64) *query.Parameter {
+	q := &query.Parameter{
+		PageSize: pageSize,
+		Extras:   make(query.ExtraParameters),
+	}
+	q.Extras.Set(query.ExtraParamKeyJobName, name)
+	q.Extras.Set(query.ExtraParamKeyCursor, cursor)
+
+	return q
+}
+
+func TestGetJobsFilteredByName(t *testing.T) {
+	m, _ := newTestManager(t)
+	for i := 0; i < 30; i++ {
+		name := "demo"
+		if i%6 == 0 {
+			name = "sample"
+		}
+		saveJob(t, m, fmt.Sprintf("job-%02d", i), name, job.PendingStatus)
+	}
+
+	const pageSize = 2
+	seen := make(map[string]bool)
+	var cursor int64
+	for pages := 0; ; pages++ {
+		if pages > 30 {
+			t.Fatal("expect the pages completed with the cursor 0")
+		}
+
+		jobs, next, err := m.GetJobs(namedQuery("sample", pageSize, cursor))
+		if err != nil {
+			t.Fatalf("get jobs error: %s", err)
+		}
+		for _, st := range jobs {
+			if st.Info.JobName != "sample" {
+				t.Errorf("expect only the jobs named sample but got %s", st.Info.JobName)
+			}
+			seen[st.Info.JobID] = true
+		}
+
+		if next == 0 {
+			break
+		}
+		// The page can only be partial when the scanning is completed
+		if len(jobs) < pageSize {
+			t.Errorf("expect a page of at least %d jobs before the last one but got %d", pageSize, len(jobs))
+		}
+		cursor = next
+	}
+
+	if len(seen) != 5 {
+		t.Errorf("expect 5 jobs named sample but got %d", len(seen))
+	}
+}
+
+func TestGetScheduledJobsFilteredByName(t *testing.T) {
+	m, pool := newTestManager(t)
+	enqueuer := work.NewEnqueuer(testNamespace, pool)
+	for i := 0; i < 25; i++ {
+		name := "demo"
+		if i%5 == 0 {
+			name = "sample"
+		}
+		sJob, err := enqueuer.EnqueueIn(name, int64(3600+i), nil)
+		if err != nil {
+			t.Fatalf("schedule job error: %s", err)
+		}
+		saveJob(t, m, sJob.ID, name, job.ScheduledStatus)
+	}
+
+	q := namedQuery("sample", 0, 0)
+	q.PageNumber = 1
+	jobs, total, err := m.GetScheduledJobs(q)
+	if err != nil {
+		t.Fatalf("get scheduled jobs error: %s", err)
+	}
+	if total != 5 || len(jobs) != 5 {
+		t.Fatalf("expect 5 scheduled jobs named sample in total but got %d of %d", len(jobs), total)
+	}
+	for _, st := range jobs {
+		if st.Info.JobName != "sample" {
+			t.Errorf("expect only the jobs named sample but got %s", st.Info.JobName)
+		}
+	}
+
+	q.PageNumber = 2
+	if jobs, total, err = m.GetScheduledJobs(q); err != nil || total != 5 || len(jobs) != 0 {
+		t.Errorf("expect the empty page out of the range but got %d of %d, %v", len(jobs), total, err)
+	}
+
+	// Not filtered
+	if _, total, err = m.GetScheduledJobs(&query.Parameter{PageNumber: 1}); err != nil || total != 25 {
+		t.Errorf("expect 25 scheduled jobs in total but got %d, %v", total, err)
+	}
+}
@@ -0,0 +1,44 @@
+package node
+
+const (
+	// StatusHealthy : the node is sending heartbeats
+	StatusHealthy = "Healthy"
+	// StatusDead : the node has not sent heartbeat for a while
+	StatusDead = "Dead"
+
+	// ActionFail marks the orphaned jobs of the dead node as failed
+	ActionFail = "fail"
+	// ActionRequeue puts the orphaned jobs of the dead node back to the queue
+	ActionRequeue = "requeue"
+)
+
+// Info represents the membership and load of one job service node.
+type Info struct {
+	NodeID      string   `json:"node_id"`
+	StartedAt   int64    `json:"started_at"`
+	HeartbeatAt int64    `json:"heartbeat_at"`
+	Concurrency uint     `json:"concurrency"`
+	RunningJobs []string `json:"running_jobs"`
+	Load        float64  `json:"load"` // running jobs / concurrency
+	Status      string   `json:"status"`
+}
+
+// heartbeat is the data reported by the node periodically
+type heartbeat struct {
+	StartedAt   int64 `json:"started_at"`
+	HeartbeatAt int64 `json:"heartbeat_at"`
+	Concurrency uint  `json:"concurrency"`
+}
+
+// ActionRequest defines for triggering node action like fail/requeue.
+type ActionRequest struct {
+	Action string `json:"action"`
+}
+
+// ActionResult keeps the result of the node action.
+type ActionResult struct {
+	NodeID string   `json:"node_id"`
+	Action string   `json:"action"`
+	Jobs   []string `json:"jobs"`
+	Failed []string `json:"failed,omitempty"`
+}
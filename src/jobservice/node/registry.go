@@ -0,0 +1,360 @@
+package node
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/chenxull/goGridhub/gridhub/src/jobservice/common/rds"
+	"github.com/chenxull/goGridhub/gridhub/src/jobservice/common/utils"
+	"github.com/chenxull/goGridhub/gridhub/src/jobservice/env"
+	"github.com/chenxull/goGridhub/gridhub/src/jobservice/errs"
+	"github.com/chenxull/goGridhub/gridhub/src/jobservice/job"
+	"github.com/chenxull/goGridhub/gridhub/src/jobservice/lcm"
+	"github.com/chenxull/goGridhub/gridhub/src/jobservice/logger"
+	"github.com/gomodule/redigo/redis"
+	"github.com/pkg/errors"
+	"sort"
+	"sync"
+	"time"
+)
+
+const (
+	// Interval of reporting heartbeat
+	heartbeatInterval = 10 * time.Second
+	// The node is treated as dead if no heartbeat is received in this duration
+	nodeDeadTime = 3 * heartbeatInterval
+)
+
+// Registry keeps the membership of the job service cluster.
+// Each node reports its heartbeat to the registry and the running jobs of
+// the node are tracked by the job tracker with the node ID.
+type Registry interface {
+	// Serve starts the heartbeat loop of the current node
+	// Non blocking call
+	Serve() error
+
+	// Nodes returns all the known nodes with their running jobs and load
+	Nodes() ([]*Info, error)
+
+	// Get the info of the specified node
+	Get(nodeID string) (*Info, error)
+
+	// Evict the specified dead node from the cluster.
+	// The orphaned jobs of the node are marked as failed or put back to the queue by the action,
+	// the node is removed only after all the orphaned jobs are handled.
+	//
+	// Arguments:
+	//   nodeID string       : ID of the node
+	//   action string       : 'fail' or 'requeue'
+	//   requeue RequeueFunc : puts the orphaned job back to the queue, required by the 'requeue' action
+	//
+	// Returns:
+	//   The result with the IDs of the handled orphaned jobs
+	//   Non nil error if any issues meet
+	Evict(nodeID string, action string, requeue RequeueFunc) (*ActionResult, error)
+
+	// SetConcurrency updates the concurrency of the current node reported in the heartbeat
+	SetConcurrency(concurrency uint)
+}
+
+// RequeueFunc puts the orphaned job back to the queue with the same job ID
+type RequeueFunc func(jobID string) error
+
+// basicRegistry is the default implementation of Registry based on redis
+type basicRegistry struct {
+	context     context.Context
+	namespace   string
+	pool        *redis.Pool
+	ctl         lcm.Controller
+	wg          *sync.WaitGroup
+	nodeID      string
+	concurrency uint
+	startedAt   int64
+	// Protect the concurrency
+	lock sync.RWMutex
+}
+
+// NewRegistry is constructor of basicRegistry
+func NewRegistry(ctx *env.Context, ns string, pool *redis.Pool, concurrency uint, ctl lcm.Controller) Registry {
+	nodeID := ctx.SystemContext.Value(utils.NodeID)
+	if nodeID == nil {
+		// Must be failed
+		panic("missing node ID in the system context of node registry")
+	}
+
+	return &basicRegistry{
+		context:     ctx.SystemContext,
+		namespace:   ns,
+		pool:        pool,
+		ctl:         ctl,
+		wg:          ctx.WG,
+		nodeID:      nodeID.(string),
+		concurrency: concurrency,
+		startedAt:   time.Now().Unix(),
+	}
+}
+
+// Serve is implementation of Registry.Serve
+func (br *basicRegistry) Serve() error {
+	// Report the first heartbeat to make sure the node is visible
+	if err := br.heartbeat(); err != nil {
+		return err
+	}
+
+	br.wg.Add(1)
+	go br.loopHeartbeat()
+
+	logger.Infof("Node %s is registered", br.nodeID)
+
+	return nil
+}
+
+// Nodes is implementation of Registry.Nodes
+func (br *basicRegistry) Nodes() ([]*Info, error) {
+	conn := br.pool.Get()
+	defer func() {
+		_ = conn.Close()
+	}()
+
+	hbs, err := redis.StringMap(conn.Do("HGETALL", rds.KeyNodes(br.namespace)))
+	if err != nil {
+		return nil, err
+	}
+
+	nodes := make([]*Info, 0, len(hbs))
+	for nodeID, raw := range hbs {
+		info, err := br.toInfo(conn, nodeID, raw)
+		if err != nil {
+			logger.Errorf("malformed heartbeat of node %s: %s", nodeID, err)
+			continue
+		}
+
+		nodes = append(nodes, info)
+	}
+
+	sort.Slice(nodes, func(i, j int) bool {
+		return nodes[i].NodeID < nodes[j].NodeID
+	})
+
+	return nodes, nil
+}
+
+// Get is implementation of Registry.Get
+func (br *basicRegistry) Get(nodeID string) (*Info, error) {
+	if utils.IsEmptyStr(nodeID) {
+		return nil, errs.BadRequestError("empty node ID")
+	}
+
+	conn := br.pool.Get()
+	defer func() {
+		_ = conn.Close()
+	}()
+
+	raw, err := redis.String(conn.Do("HGET", rds.KeyNodes(br.namespace), nodeID))
+	if err != nil {
+		if err == redis.ErrNil {
+			return nil, errs.NoObjectFoundError(fmt.Sprintf("node %s", nodeID))
+		}
+
+		return nil, err
+	}
+
+	return br.toInfo(conn, nodeID, raw)
+}
+
+// Evict is implementation of Registry.Evict
+func (br *basicRegistry) Evict(nodeID string, action string, requeue RequeueFunc) (*ActionResult, error) {
+	if action == ActionRequeue && requeue == nil {
+		return nil, errors.New("missing requeue func to requeue the orphaned jobs")
+	}
+
+	info, err := br.Get(nodeID)
+	if err != nil {
+		return nil, err
+	}
+
+	if info.Status != StatusDead {
+		return nil, errs.ConflictError(fmt.Sprintf("alive node %s", nodeID))
+	}
+
+	res := HandleOrphans(br.ctl, info, action, requeue)
+	// Keep the node for the next try if any jobs are not handled
+	if len(res.Failed) > 0 {
+		return res, nil
+	}
+
+	conn := br.pool.Get()
+	defer func() {
+		_ = conn.Close()
+	}()
+
+	if err := conn.Send("MULTI"); err != nil {
+		return nil, err
+	}
+	if err := conn.Send("HDEL", rds.KeyNodes(br.namespace), nodeID); err != nil {
+		return nil, err
+	}
+	if err := conn.Send("DEL", rds.KeyNodeRunningJobs(br.namespace, nodeID)); err != nil {
+		return nil, err
+	}
+	if _, err := conn.Do("EXEC"); err != nil {
+		return nil, err
+	}
+
+	logger.Infof("Node %s is evicted with %d orphaned jobs handled by action %s", nodeID, len(res.Jobs), action)
+
+	return res, nil
+}
+
+// HandleOrphans marks the orphaned jobs of the node as failed or puts them back to the queue by the action.
+// The jobs failed to handle are kept in the Failed of the result.
+func HandleOrphans(ctl lcm.Controller, info *Info, action string, requeue RequeueFunc) *ActionResult {
+	res := &ActionResult{
+		NodeID: info.NodeID,
+		Action: action,
+		Jobs:   make([]string, 0),
+	}
+	for _, jID := range info.RunningJobs {
+		t, err := ctl.Track(jID)
+		if err != nil {
+			if !errs.IsObjectNotFoundError(err) {
+				logger.Errorf("track orphaned job %s of node %s error: %s", jID, info.NodeID, err)
+				res.Failed = append(res.Failed, jID)
+			}
+			continue
+		}
+
+		// Only the jobs not in the final status are orphaned
+		if job.Status(t.Job().Info.Status).Final() {
+			continue
+		}
+
+		if action == ActionRequeue {
+			// Put back directly without failing it to avoid firing the error hook event
+			err = requeue(jID)
+		} else {
+			err = t.Fail()
+		}
+		if err != nil {
+			logger.Errorf("%s orphaned job %s of node %s error: %s", action, jID, info.NodeID, err)
+			res.Failed = append(res.Failed, jID)
+			continue
+		}
+
+		res.Jobs = append(res.Jobs, jID)
+	}
+
+	return res
+}
+
+func (br *basicRegistry) loopHeartbeat() {
+	defer func() {
+		br.unregister()
+		logger.Info("Node heartbeat loop is stopped")
+		br.wg.Done()
+	}()
+
+	ticker := time.NewTicker(heartbeatInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			if err := br.heartbeat(); err != nil {
+				logger.Errorf("report heartbeat of node %s error: %s", br.nodeID, err)
+			}
+		case <-br.context.Done():
+			return
+		}
+	}
+}
+
+// SetConcurrency is implementation of Registry.SetConcurrency
+func (br *basicRegistry) SetConcurrency(concurrency uint) {
+	br.lock.Lock()
+	defer br.lock.Unlock()
+
+	br.concurrency = concurrency
+}
+
+func (br *basicRegistry) currentConcurrency() uint {
+	br.lock.RLock()
+	defer br.lock.RUnlock()
+
+	return br.concurrency
+}
+
+func (br *basicRegistry) heartbeat() error {
+	hb := &heartbeat{
+		StartedAt:   br.startedAt,
+		HeartbeatAt: time.Now().Unix(),
+		Concurrency: br.currentConcurrency(),
+	}
+	rawJSON, err := json.Marshal(hb)
+	if err != nil {
+		return err
+	}
+
+	conn := br.pool.Get()
+	defer func() {
+		_ = conn.Close()
+	}()
+
+	_, err = conn.Do("HSET", rds.KeyNodes(br.namespace), br.nodeID, rawJSON)
+
+	return err
+}
+
+// unregister removes the current node from the registry when exiting gracefully.
+// The node is kept if it still has running jobs, then it can be evicted later.
+func (br *basicRegistry) unregister() {
+	conn := br.pool.Get()
+	defer func() {
+		_ = conn.Close()
+	}()
+
+	num, err := redis.Int64(conn.Do("SCARD", rds.KeyNodeRunningJobs(br.namespace, br.nodeID)))
+	if err != nil {
+		logger.Errorf("get running jobs of node %s error: %s", br.nodeID, err)
+		return
+	}
+
+	if num > 0 {
+		logger.Warningf("Node %s exits with %d running jobs", br.nodeID, num)
+		return
+	}
+
+	if _, err := conn.Do("HDEL", rds.KeyNodes(br.namespace), br.nodeID); err != nil {
+		logger.Errorf("unregister node %s error: %s", br.nodeID, err)
+	}
+}
+
+func (br *basicRegistry) toInfo(conn redis.Conn, nodeID string, raw string) (*Info, error) {
+	hb := &heartbeat{}
+	if err := json.Unmarshal([]byte(raw), hb); err != nil {
+		return nil, errors.Wrap(err, "decode node heartbeat")
+	}
+
+	jobs, err := redis.Strings(conn.Do("SMEMBERS", rds.KeyNodeRunningJobs(br.namespace, nodeID)))
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(jobs)
+
+	info := &Info{
+		NodeID:      nodeID,
+		StartedAt:   hb.StartedAt,
+		HeartbeatAt: hb.HeartbeatAt,
+		Concurrency: hb.Concurrency,
+		RunningJobs: jobs,
+		Status:      StatusHealthy,
+	}
+	if hb.Concurrency > 0 {
+		info.Load = float64(len(jobs)) / float64(hb.Concurrency)
+	}
+	if time.Unix(hb.HeartbeatAt, 0).Add(nodeDeadTime).Before(time.Now()) {
+		info.Status = StatusDead
+	}
+
+	return info, nil
+}
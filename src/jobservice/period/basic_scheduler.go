@@ -57,6 +57,11 @@ func (bs *basicScheduler) Stop() error {
 
 }
 
+// Leader returns the current leader of the periodic enqueuer
+func (bs *basicScheduler) Leader() (*Leader, error) {
+	return bs.enqueuer.elector.leader()
+}
+
 func (bs *basicScheduler) Schedule(p *Policy) (int64, error) {
 	if p == nil {
 		return -1, errors.New("bad policy object: nil")
@@ -70,8 +75,11 @@ func (bs *basicScheduler) Schedule(p *Policy) (int64, error) {
 	defer func() {
 		_ = conn.Close()
 	}()
-	// Do the 1st round of enqueuing
-	bs.enqueuer.scheduleNextJobs(p, conn)
+	// Do the 1st round of enqueuing.
+	// It's not fenced as the policy may be scheduled by any node, not only the leader. It's safe as the
+	// executions are identified by the policy ID and the run time, so the same executions scheduled by
+	// the leader later are the same members of the scheduled job queue and the same stats, no duplicates.
+	bs.enqueuer.scheduleNextJobs(p, conn, nil)
 	// Serialize data
 	rawJSON, err := p.Serialize()
 	if err != nil {
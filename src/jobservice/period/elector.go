@@ -0,0 +1,227 @@
+package period
+
+import (
+	"context"
+	"github.com/chenxull/goGridhub/gridhub/src/jobservice/common/rds"
+	"github.com/chenxull/goGridhub/gridhub/src/jobservice/logger"
+	"github.com/gomodule/redigo/redis"
+	"sync"
+	"time"
+)
+
+const (
+	// The lease of leader expires if it's not renewed in this duration
+	leaderLeaseTTL = 30 * time.Second
+	// Renew the lease or campaign for leader periodically
+	leaderRenewInterval = leaderLeaseTTL / 3
+	// Leave some margin for the clock drift between the node and redis
+	leaderLeaseMargin = 2 * time.Second
+)
+
+// Leader represents the current leader of the periodic enqueuer.
+type Leader struct {
+	NodeID   string `json:"node_id"`
+	Token    int64  `json:"fencing_token"`
+	ExpireAt int64  `json:"expire_at"`
+}
+
+// elector elects one leader from all the nodes to schedule the periodic jobs.
+// The leadership is a lease in redis bound with a fencing token which is increased
+// every time the lease changes hands. All the writes done by the leader are fenced
+// by the token, so a node which lost its lease can not enqueue any more.
+type elector struct {
+	namespace string
+	context   context.Context
+	pool      *redis.Pool
+	nodeID    string
+	// For stop
+	stopChan chan bool
+	// Protect the lease fields below
+	lock sync.RWMutex
+	// Nil if the node is not the leader
+	lease *rds.Lease
+	// The local deadline of the lease
+	deadline time.Time
+}
+
+func newElector(ctx context.Context, namespace string, pool *redis.Pool, nodeID string) *elector {
+	return &elector{
+		namespace: namespace,
+		context:   ctx,
+		pool:      pool,
+		nodeID:    nodeID,
+		stopChan:  make(chan bool, 1),
+	}
+}
+
+// Non blocking call
+func (el *elector) start() {
+	el.campaign()
+
+	go el.loop()
+	logger.Info("Periodic enqueuer leader elector is started")
+}
+
+func (el *elector) loop() {
+	defer func() {
+		el.resign()
+		logger.Info("Periodic enqueuer leader elector is stopped")
+	}()
+
+	ticker := time.NewTicker(leaderRenewInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			el.campaign()
+		case <-el.stopChan:
+			return
+		case <-el.context.Done():
+			return
+		}
+	}
+}
+
+// campaign acquires the lease if no one holds it or renews the lease held by the current node
+func (el *elector) campaign() {
+	conn := el.pool.Get()
+	defer func() {
+		_ = conn.Close()
+	}()
+
+	// Take the local deadline before talking to redis to be conservative
+	deadline := time.Now().Add(leaderLeaseTTL - leaderLeaseMargin)
+	token, err := rds.AcquireLease(
+		conn,
+		rds.KeyPeriodicLeader(el.namespace),
+		rds.KeyPeriodicLeaderToken(el.namespace),
+		el.nodeID,
+		leaderLeaseTTL,
+	)
+
+	el.lock.Lock()
+	defer el.lock.Unlock()
+
+	if err != nil {
+		// Keep the lease until the local deadline as it may be still valid
+		logger.Errorf("campaign for periodic enqueuer leader error: %s", err)
+		if el.lease != nil && time.Now().After(el.deadline) {
+			logger.Warningf("Node %s lost the periodic enqueuer leadership (token=%d): lease expired", el.nodeID, el.lease.Token)
+			el.lease = nil
+		}
+		return
+	}
+
+	if token <= 0 {
+		if el.lease != nil {
+			logger.Warningf("Node %s lost the periodic enqueuer leadership (token=%d): lease is held by others", el.nodeID, el.lease.Token)
+			el.lease = nil
+		}
+		return
+	}
+
+	if el.lease == nil || el.lease.Token != token {
+		logger.Infof("Node %s becomes the periodic enqueuer leader with fencing token %d", el.nodeID, token)
+	}
+	el.lease = &rds.Lease{
+		LeaseKey: rds.KeyPeriodicLeader(el.namespace),
+		TokenKey: rds.KeyPeriodicLeaderToken(el.namespace),
+		Owner:    el.nodeID,
+		Token:    token,
+	}
+	el.deadline = deadline
+}
+
+// current returns the lease if the current node is the leader
+func (el *elector) current() (*rds.Lease, bool) {
+	el.lock.RLock()
+	defer el.lock.RUnlock()
+
+	if el.lease == nil || time.Now().After(el.deadline) {
+		return nil, false
+	}
+
+	return el.lease, true
+}
+
+// lost drops the lease once a fenced write is rejected
+func (el *elector) lost(lease *rds.Lease) {
+	el.lock.Lock()
+	defer el.lock.Unlock()
+
+	if el.lease != nil && el.lease.Token == lease.Token {
+		logger.Warningf("Node %s lost the periodic enqueuer leadership (token=%d): write is fenced", el.nodeID, lease.Token)
+		el.lease = nil
+	}
+}
+
+// resign releases the lease to let other nodes take over quickly
+func (el *elector) resign() {
+	lease, ok := el.current()
+	if !ok {
+		return
+	}
+
+	conn := el.pool.Get()
+	defer func() {
+		_ = conn.Close()
+	}()
+
+	if err := rds.ReleaseLease(conn, lease); err != nil {
+		logger.Errorf("release periodic enqueuer leader lease error: %s", err)
+	}
+
+	el.lost(lease)
+}
+
+// leader returns the current leader of the periodic enqueuer from the backend
+func (el *elector) leader() (*Leader, error) {
+	conn := el.pool.Get()
+	defer func() {
+		_ = conn.Close()
+	}()
+
+	if err := conn.Send("MULTI"); err != nil {
+		return nil, err
+	}
+	if err := conn.Send("GET", rds.KeyPeriodicLeader(el.namespace)); err != nil {
+		return nil, err
+	}
+	if err := conn.Send("GET", rds.KeyPeriodicLeaderToken(el.namespace)); err != nil {
+		return nil, err
+	}
+	if err := conn.Send("PTTL", rds.KeyPeriodicLeader(el.namespace)); err != nil {
+		return nil, err
+	}
+
+	values, err := redis.Values(conn.Do("EXEC"))
+	if err != nil {
+		return nil, err
+	}
+
+	nodeID, err := redis.String(values[0], nil)
+	if err != nil {
+		if err == redis.ErrNil {
+			// No leader now
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	token, err := redis.Int64(values[1], nil)
+	if err != nil {
+		return nil, err
+	}
+
+	ttl, err := redis.Int64(values[2], nil)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Leader{
+		NodeID:   nodeID,
+		Token:    token,
+		ExpireAt: time.Now().Add(time.Duration(ttl) * time.Millisecond).Unix(),
+	}, nil
+}
@@ -0,0 +1,106 @@
+package period
+
+import (
+	"context"
+	"github.com/alicebob/miniredis/v2"
+	"github.com/chenxull/goGridhub/gridhub/src/jobservice/common/rds"
+	"github.com/gomodule/redigo/redis"
+	"testing"
+)
+
+const testNamespace = "{period_test}"
+
+func newTestPool(t *testing.T) (*redis.Pool, *miniredis.Miniredis) {
+	t.Helper()
+
+	mr := miniredis.RunT(t)
+	pool := &redis.Pool{
+		Dial: func() (redis.Conn, error) {
+			return redis.Dial("tcp", mr.Addr())
+		},
+	}
+	t.Cleanup(func() {
+		_ = pool.Close()
+	})
+
+	return pool, mr
+}
+
+// stealLease lets another node take the expired lease of the leader
+func stealLease(t *testing.T, pool *redis.Pool, mr *miniredis.Miniredis, nodeID string) int64 {
+	t.Helper()
+
+	mr.FastForward(leaderLeaseTTL)
+
+	conn := pool.Get()
+	defer func() {
+		_ = conn.Close()
+	}()
+
+	token, err := rds.AcquireLease(conn, rds.KeyPeriodicLeader(testNamespace), rds.KeyPeriodicLeaderToken(testNamespace), nodeID, leaderLeaseTTL)
+	if err != nil || token == 0 {
+		t.Fatalf("expect the lease taken by %s but got token %d, %v", nodeID, token, err)
+	}
+
+	return token
+}
+
+func TestElectorCampaign(t *testing.T) {
+	pool, mr := newTestPool(t)
+	el := newElector(context.Background(), testNamespace, pool, "node-a")
+
+	el.campaign()
+	lease, ok := el.current()
+	if !ok || lease.Token != 1 {
+		t.Fatalf("expect node-a becomes the leader with token 1 but got %v, %v", lease, ok)
+	}
+
+	leader, err := el.leader()
+	if err != nil || leader == nil || leader.NodeID != "node-a" || leader.Token != 1 {
+		t.Fatalf("expect the leader node-a with token 1 but got %v, %v", leader, err)
+	}
+
+	// The local deadline is not passed, the lease held by others is found by the next campaign
+	token := stealLease(t, pool, mr, "node-b")
+	el.campaign()
+	if _, ok := el.current(); ok {
+		t.Error("expect node-a steps down once the lease is held by others")
+	}
+	if leader, err := el.leader(); err != nil || leader.NodeID != "node-b" || leader.Token != token {
+		t.Errorf("expect the leader node-b with token %d but got %v, %v", token, leader, err)
+	}
+}
+
+func TestElectorStepDownOnLeaseLost(t *testing.T) {
+	pool, mr := newTestPool(t)
+	e := &enqueuer{
+		namespace: testNamespace,
+		context:   context.Background(),
+		pool:      pool,
+		nodeID:    "node-a",
+		elector:   newElector(context.Background(), testNamespace, pool, "node-a"),
+	}
+
+	e.elector.campaign()
+	lease, ok := e.elector.current()
+	if !ok {
+		t.Fatal("expect node-a becomes the leader")
+	}
+
+	// node-a is paused and believes it's still the leader
+	stealLease(t, pool, mr, "node-b")
+	if _, ok := e.elector.current(); !ok {
+		t.Fatal("expect node-a keeps the lease until the local deadline")
+	}
+
+	// The fenced write is rejected with ErrLeaseLost and the leadership is dropped
+	if e.shouldEnqueue(lease) {
+		t.Error("expect no enqueuing once the lease is lost")
+	}
+	if _, ok := e.elector.current(); ok {
+		t.Error("expect node-a steps down on the lost lease")
+	}
+	if mr.Exists(rds.RedisKeyLastPeriodicEnqueue(testNamespace)) {
+		t.Error("expect the last enqueue timestamp not written by the former leader")
+	}
+}
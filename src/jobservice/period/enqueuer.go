@@ -10,7 +10,6 @@ import (
 	"github.com/chenxull/goGridhub/gridhub/src/jobservice/logger"
 	"github.com/gocraft/work"
 	"github.com/gomodule/redigo/redis"
-	"github.com/robfig/cron"
 	"math/rand"
 	"time"
 )
@@ -24,6 +23,19 @@ const (
 	PeriodicExecutionMark = "_job_kind_periodic_"
 )
 
+// Remove the stats of the execution if it's not put to the scheduled job queue,
+// the execution queued by the new leader with the same ID is kept.
+// KEYS[1]: scheduled zset; KEYS[2]: execution stats; KEYS[3]: executions of the upstream job
+// ARGV[1]: serialized job; ARGV[2]: execution ID
+var discardExecutionScript = redis.NewScript(3, `
+if redis.call('ZSCORE', KEYS[1], ARGV[1]) then
+  return 0
+end
+redis.call('DEL', KEYS[2])
+redis.call('ZREM', KEYS[3], ARGV[2])
+return 1
+`)
+
 type enqueuer struct {
 	namespace   string
 	context     context.Context
@@ -32,6 +44,8 @@ type enqueuer struct {
 	ctl         lcm.Controller
 	// Diff with other nodes
 	nodeID string
+	// Elect the only node to do enqueuing
+	elector *elector
 	// Track the error of enqueuing
 	lastEnqueueErr error
 	// For stop
@@ -53,6 +67,7 @@ func newEnqueuer(ctx context.Context, namespace string, pool *redis.Pool, ctl lc
 		ctl:         ctl,
 		stopChan:    make(chan bool, 1),
 		nodeID:      nodeID.(string),
+		elector:     newElector(ctx, namespace, pool, nodeID.(string)),
 	}
 }
 
@@ -63,6 +78,9 @@ func (e *enqueuer) start() error {
 		return err
 	}
 
+	// Campaign for the leader before the first round
+	e.elector.start()
+
 	go e.loop()
 	logger.Info("Periodic enqueuer is started")
 
@@ -82,6 +100,7 @@ func (e *enqueuer) loop() {
 	for {
 		select {
 		case <-e.stopChan:
+			e.elector.stopChan <- true
 			e.policyStore.stopChan <- true
 			return
 		case <-timer.C:
@@ -96,58 +115,60 @@ func (e *enqueuer) loop() {
 }
 
 // checkAndEnqueue checks if it should do enqueue and
+// only the leader node can do the enqueuing
 func (e *enqueuer) checkAndEnqueue() (isHit bool) {
-	if isHit = e.shouldEnqueue(); isHit {
-		e.enqueue()
+	lease, isLeader := e.elector.current()
+	if !isLeader {
+		return false
+	}
+
+	if isHit = e.shouldEnqueue(lease); isHit {
+		e.enqueue(lease)
 	}
 	return
 }
 
-// nextTurn returns the next check time slot by applying
-// priorities to balance the workloads across multiple nodes
+// nextTurn returns the next check time slot.
+// The leader enqueues with the regular interval, and the node which did not enqueue
+// checks more frequently to take over in time once the leadership is changed.
 func (e *enqueuer) nextTurn(isHit bool, enqErr bool) time.Duration {
-	base := enqueuerSleep
-	if isHit {
-		// Down the hit priority by adding more waiting time
-		base = base + time.Duration(3)*time.Second
-		if enqErr {
-			// Downgrade the priority if the node has occurred error when enqueuing
-			base = base + time.Duration(5)*time.Second
-		}
-	} else {
-		// Upgrade the priority of hitting in the next turn
-		base = base - time.Duration(3)*time.Second
+	if !isHit || enqErr {
+		// Add random waiting time [0,5)
+		return leaderRenewInterval + time.Duration(rand.Intn(5))*time.Second
 	}
 
-	// Add random waiting time [0,8)
-	base = base + time.Duration(rand.Intn(5))*time.Second
-
-	return base
+	return enqueuerSleep
 }
 
-func (e *enqueuer) enqueue() {
+func (e *enqueuer) enqueue(lease *rds.Lease) {
 	conn := e.pool.Get()
 	defer func() {
 		_ = conn.Close()
 	}()
 
 	//Reset error track
+	e.lastEnqueueErr = nil
 	e.policyStore.Iterate(func(id string, p *Policy) bool {
-		e.scheduleNextJobs(p, conn)
-		return true
+		e.scheduleNextJobs(p, conn, lease)
+		// Stop once the leadership is lost
+		_, isLeader := e.elector.current()
+		return isLeader
 	})
 }
 
-// scheduleNextJobs schedules job for next time slots based on the policy
-func (e *enqueuer) scheduleNextJobs(p *Policy, conn redis.Conn) {
+// scheduleNextJobs schedules job for next time slots based on the policy.
+// If lease is provided, the scheduled jobs are written with the fencing token of the leader.
+// The stats of the execution are saved before queuing it, so the stats exist once it's picked up.
+func (e *enqueuer) scheduleNextJobs(p *Policy, conn redis.Conn, lease *rds.Lease) {
 	nowTime := time.Unix(time.Now().Unix(), 0)
 	horizon := nowTime.Add(enqueuerHorizon)
-	schedule, err := cron.Parse(p.CronSpec)
+	schedule, err := p.Timing()
 	if err != nil {
 		e.lastEnqueueErr = err
-		logger.Errorf("Invalid corn spec in periodic policy %s %s: %s", p.JobName, p.ID, err)
+		logger.Errorf("Invalid schedule in periodic policy %s %s: %s", p.JobName, p.ID, err)
 	} else {
-		for t := schedule.Next(nowTime); t.Before(horizon); t = schedule.Next(t) {
+		// The zero time means no more runs, e.g: all the one-off runs are passed
+		for t := schedule.Next(nowTime); !t.IsZero() && t.Before(horizon); t = schedule.Next(t) {
 			epoch := t.Unix()
 
 			// Clone parameters
@@ -177,7 +198,29 @@ func (e *enqueuer) scheduleNextJobs(p *Policy, conn redis.Conn) {
 			}
 
 			//Put job to the scheduled job queue
-			_, err = conn.Do("ZADD", rds.RedisKeyScheduled(e.namespace), epoch, rawJSON)
+			if lease != nil {
+				_, err = rds.FencedDo(conn, lease, "ZADD", rds.RedisKeyScheduled(e.namespace), epoch, rawJSON)
+			} else {
+				_, err = conn.Do("ZADD", rds.RedisKeyScheduled(e.namespace), epoch, rawJSON)
+			}
+			if err == rds.ErrLeaseLost {
+				// The new leader will take over the execution, discard the saved stats
+				// to avoid leaving an execution never run with the scheduled status
+				e.lastEnqueueErr = err
+				e.elector.lost(lease)
+				logger.Errorf("Put the execution of the periodic job '%s' to the scheduled job queue error: %s", p.ID, err)
+				if _, err := discardExecutionScript.Do(
+					conn,
+					rds.RedisKeyScheduled(e.namespace),
+					rds.KeyJobStats(e.namespace, execution.Info.JobID),
+					rds.KeyUpstreamJobAndExecutions(e.namespace, p.ID),
+					rawJSON,
+					execution.Info.JobID,
+				); err != nil {
+					logger.Errorf("Discard the stats of execution '%s' error: %s", execution.Info.JobID, err)
+				}
+				break
+			}
 			if err != nil {
 				e.lastEnqueueErr = err
 				logger.Errorf("Put the execution of the periodic job '%s' to the scheduled job queue error: %s", p.ID, err)
@@ -219,23 +262,12 @@ func (e *enqueuer) createExecution(p *Policy, runAt int64) *job.Stats {
 		},
 	}
 }
-func (e *enqueuer) shouldEnqueue() bool {
+func (e *enqueuer) shouldEnqueue(lease *rds.Lease) bool {
 	conn := e.pool.Get()
 	defer func() {
 		_ = conn.Close()
 	}()
-	// Acquired a lock before doing checking
-	lockKey := rds.KeyPeriodicLock(e.namespace)
-	if err := rds.AcquireLock(conn, lockKey, e.nodeID, 30); err != nil {
-		logger.Errorf("acquire lock for periodic enqueuing error: %s", err)
-		return false
-	}
-	//Acquired lock
-	defer func() {
-		if err := rds.ReleaseLock(conn, lockKey, e.nodeID); err != nil {
-			logger.Errorf("release lock for periodic enqueuing error: %s", err)
-		}
-	}()
+
 	shouldEnq := false
 	lastEnqueue, err := redis.Int64(conn.Do("GET", rds.RedisKeyLastPeriodicEnqueue(e.namespace)))
 	if err != nil {
@@ -251,9 +283,13 @@ func (e *enqueuer) shouldEnqueue() bool {
 		shouldEnq = lastEnqueue < (time.Now().Unix() - int64(enqueuerSleep/time.Minute)*60)
 	}
 	if shouldEnq {
-		// Set last periodic enqueue timestamp
-		if _, err := conn.Do("SET", rds.RedisKeyLastPeriodicEnqueue(e.namespace), time.Now().Unix()); err != nil {
+		// Set last periodic enqueue timestamp with the fencing token of the leader
+		if _, err := rds.FencedDo(conn, lease, "SET", rds.RedisKeyLastPeriodicEnqueue(e.namespace), time.Now().Unix()); err != nil {
 			logger.Errorf("set last periodic enqueue timestamp error: %s", err)
+			if err == rds.ErrLeaseLost {
+				e.elector.lost(lease)
+				return false
+			}
 		}
 
 		// Anyway the action should be enforced
@@ -0,0 +1,285 @@
+package period
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/chenxull/goGridhub/gridhub/src/jobservice/common/rds"
+	"github.com/chenxull/goGridhub/gridhub/src/jobservice/errs"
+	"github.com/chenxull/goGridhub/gridhub/src/jobservice/job"
+	"github.com/gomodule/redigo/redis"
+	"github.com/pkg/errors"
+	"time"
+)
+
+// PolicyExportVersion is the version of the format of the exported periodic job policies
+const PolicyExportVersion = 1
+
+// Results of importing the periodic job policy
+const (
+	// ImportResultImported means the policy is imported
+	ImportResultImported = "imported"
+	// ImportResultWouldImport means the policy is imported if it's not the dry run
+	ImportResultWouldImport = "would_import"
+	// ImportResultConflict means the policy is skipped as it conflicts with the existing one
+	ImportResultConflict = "conflict"
+	// ImportResultInvalid means the policy is skipped as it's invalid
+	ImportResultInvalid = "invalid"
+	// ImportResultFailed means the policy is failed to import
+	ImportResultFailed = "failed"
+)
+
+// ExportedPolicy is the periodic job policy with the numeric ID which is the score of it in the policy set
+type ExportedPolicy struct {
+	NumericID int64   `json:"numeric_id"`
+	Policy    *Policy `json:"policy"`
+}
+
+// PolicyExport is the versioned document of the periodic job policies exported from the namespace.
+// The secret job parameters are kept encrypted, so the importing job service should share the same
+// parameters key to run the policies.
+type PolicyExport struct {
+	Version    int               `json:"version"`
+	Namespace  string            `json:"namespace"`
+	ExportedAt int64             `json:"exported_at"`
+	Policies   []*ExportedPolicy `json:"policies"`
+}
+
+// PolicyImportResult is the result of importing one periodic job policy
+type PolicyImportResult struct {
+	ID        string `json:"id"`
+	JobName   string `json:"job_name"`
+	NumericID int64  `json:"numeric_id"`
+	Result    string `json:"result"`
+	Reason    string `json:"reason,omitempty"`
+}
+
+// PolicyImportReport is the report of importing the periodic job policies
+type PolicyImportReport struct {
+	DryRun    bool   `json:"dry_run"`
+	Namespace string `json:"namespace"`
+	// Count of the imported policies or the ones would be imported in the dry run
+	Imported  int                   `json:"imported"`
+	Conflicts int                   `json:"conflicts"`
+	Invalid   int                   `json:"invalid"`
+	Failed    int                   `json:"failed"`
+	Results   []*PolicyImportResult `json:"results"`
+}
+
+// PolicyPorter exports and imports the periodic job policies of the namespace, e.g: for migrating
+// job service between the redis instances
+type PolicyPorter interface {
+	// Export all the periodic job policies with their numeric IDs
+	Export() (*PolicyExport, error)
+
+	// Import the policies into the namespace, the ones conflicting with the existing policies or jobs
+	// are skipped. Nothing is changed in the dry run and the report tells what would be done.
+	Import(data *PolicyExport, dryRun bool) (*PolicyImportReport, error)
+}
+
+// redisPolicyPorter exports and imports the policies kept under KeyPeriodicPolicy
+type redisPolicyPorter struct {
+	context   context.Context
+	namespace string
+	pool      *redis.Pool
+}
+
+// NewPolicyPorter is constructor of the porter of the periodic job policies kept in redis
+func NewPolicyPorter(ctx context.Context, ns string, pool *redis.Pool) PolicyPorter {
+	return &redisPolicyPorter{
+		context:   ctx,
+		namespace: ns,
+		pool:      pool,
+	}
+}
+
+// Export is implementation of PolicyPorter.Export
+func (rp *redisPolicyPorter) Export() (*PolicyExport, error) {
+	conn := rp.pool.Get()
+	defer func() {
+		_ = conn.Close()
+	}()
+
+	entries, err := rp.policies(conn)
+	if err != nil {
+		return nil, err
+	}
+
+	return &PolicyExport{
+		Version:    PolicyExportVersion,
+		Namespace:  rp.namespace,
+		ExportedAt: time.Now().Unix(),
+		Policies:   entries,
+	}, nil
+}
+
+// Import is implementation of PolicyPorter.Import
+func (rp *redisPolicyPorter) Import(data *PolicyExport, dryRun bool) (*PolicyImportReport, error) {
+	if data == nil {
+		return nil, errs.BadRequestError("nil policy export")
+	}
+	if data.Version != PolicyExportVersion {
+		return nil, errs.BadRequestError(errors.Errorf("policy export version %d is not supported, only support %d", data.Version, PolicyExportVersion))
+	}
+
+	conn := rp.pool.Get()
+	defer func() {
+		_ = conn.Close()
+	}()
+
+	existing, err := rp.policies(conn)
+	if err != nil {
+		return nil, err
+	}
+	// The policy IDs and the numeric IDs taken in the namespace
+	ids := make(map[string]bool, len(existing))
+	numericIDs := make(map[int64]string, len(existing))
+	for _, e := range existing {
+		ids[e.Policy.ID] = true
+		numericIDs[e.NumericID] = e.Policy.ID
+	}
+
+	report := &PolicyImportReport{
+		DryRun:    dryRun,
+		Namespace: rp.namespace,
+		Results:   make([]*PolicyImportResult, 0, len(data.Policies)),
+	}
+	for _, ep := range data.Policies {
+		res := &PolicyImportResult{}
+		report.Results = append(report.Results, res)
+
+		if ep == nil || ep.Policy == nil {
+			res.Result, res.Reason = ImportResultInvalid, "missing policy"
+			report.Invalid++
+			continue
+		}
+		res.ID, res.JobName, res.NumericID = ep.Policy.ID, ep.Policy.JobName, ep.NumericID
+
+		if err := ep.Policy.Validate(); err != nil {
+			res.Result, res.Reason = ImportResultInvalid, err.Error()
+			report.Invalid++
+			continue
+		}
+		if ep.NumericID <= 0 {
+			res.Result, res.Reason = ImportResultInvalid, "numeric ID should be positive"
+			report.Invalid++
+			continue
+		}
+
+		if reason, err := rp.conflict(conn, ep, ids, numericIDs); err != nil {
+			res.Result, res.Reason = ImportResultFailed, err.Error()
+			report.Failed++
+			continue
+		} else if len(reason) > 0 {
+			res.Result, res.Reason = ImportResultConflict, reason
+			report.Conflicts++
+			continue
+		}
+
+		// Taken by this one, the following duplicates in the same export are conflicts
+		ids[ep.Policy.ID] = true
+		numericIDs[ep.NumericID] = ep.Policy.ID
+
+		if dryRun {
+			res.Result = ImportResultWouldImport
+			report.Imported++
+			continue
+		}
+
+		if err := rp.save(conn, ep); err != nil {
+			res.Result, res.Reason = ImportResultFailed, err.Error()
+			report.Failed++
+			continue
+		}
+		res.Result = ImportResultImported
+		report.Imported++
+	}
+
+	return report, nil
+}
+
+// policies returns all the policies of the namespace with their numeric IDs
+func (rp *redisPolicyPorter) policies(conn redis.Conn) ([]*ExportedPolicy, error) {
+	values, err := redis.Values(conn.Do("ZRANGE", rds.KeyPeriodicPolicy(rp.namespace), 0, -1, "WITHSCORES"))
+	if err != nil {
+		return nil, err
+	}
+
+	entries := make([]*ExportedPolicy, 0, len(values)/2)
+	for i := 0; i+1 < len(values); i += 2 {
+		rawPolicy, err := redis.Bytes(values[i], nil)
+		if err != nil {
+			return nil, err
+		}
+		score, err := redis.Int64(values[i+1], nil)
+		if err != nil {
+			return nil, err
+		}
+
+		p := &Policy{}
+		if err := p.DeSerialize(rawPolicy); err != nil {
+			return nil, errors.Wrapf(err, "malformed periodic job policy with numeric ID %d", score)
+		}
+		entries = append(entries, &ExportedPolicy{
+			NumericID: score,
+			Policy:    p,
+		})
+	}
+
+	return entries, nil
+}
+
+// conflict returns the reason if the policy conflicts with the existing policies or jobs
+func (rp *redisPolicyPorter) conflict(conn redis.Conn, ep *ExportedPolicy, ids map[string]bool, numericIDs map[int64]string) (string, error) {
+	if ids[ep.Policy.ID] {
+		return "policy with the same ID exists", nil
+	}
+	if id, ok := numericIDs[ep.NumericID]; ok {
+		return fmt.Sprintf("numeric ID is taken by policy %s", id), nil
+	}
+
+	exists, err := redis.Bool(conn.Do("EXISTS", rds.KeyJobStats(rp.namespace, ep.Policy.ID)))
+	if err != nil {
+		return "", err
+	}
+	if exists {
+		return "job with the same ID exists", nil
+	}
+
+	return "", nil
+}
+
+// save the periodic job stats and the policy, the policy is synced to the policy stores of all the nodes
+// and picked up by the periodic enqueuer in its next round
+func (rp *redisPolicyPorter) save(conn redis.Conn, ep *ExportedPolicy) error {
+	p := ep.Policy
+	if err := job.NewBasicTrackerWithStats(rp.context, p.JobStats(ep.NumericID), rp.namespace, rp.pool, nil).Save(); err != nil {
+		return errors.Wrap(err, "save periodic job stats")
+	}
+
+	rawJSON, err := p.Serialize()
+	if err != nil {
+		return err
+	}
+	msgJSON, err := json.Marshal(&message{
+		Event: changeEventSchedule,
+		Data:  p,
+	})
+	if err != nil {
+		return err
+	}
+
+	// Save to redis db and publish notification via redis transaction
+	if err := conn.Send("MULTI"); err != nil {
+		return err
+	}
+	if err := conn.Send("ZADD", rds.KeyPeriodicPolicy(rp.namespace), ep.NumericID, rawJSON); err != nil {
+		return err
+	}
+	if err := conn.Send("PUBLISH", rds.KeyPeriodicNotification(rp.namespace), msgJSON); err != nil {
+		return err
+	}
+	_, err = conn.Do("EXEC")
+
+	return err
+}
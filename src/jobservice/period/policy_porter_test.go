@@ -0,0 +1,152 @@
+package period
+
+import (
+	"context"
+	"github.com/chenxull/goGridhub/gridhub/src/jobservice/common/rds"
+	"github.com/chenxull/goGridhub/gridhub/src/jobservice/job"
+	"reflect"
+	"testing"
+)
+
+func testPolicies() []*ExportedPolicy {
+	return []*ExportedPolicy{
+		{
+			NumericID: 3,
+			Policy: &Policy{
+				ID:            "p1",
+				JobName:       "demo",
+				CronSpec:      "0 0 * * * *",
+				JobParameters: map[string]interface{}{"image": "library/demo"},
+				WebHookURL:    "http://hook.local/p1",
+			},
+		},
+		{
+			NumericID: 7,
+			Policy: &Policy{
+				ID:      "p2",
+				JobName: "sample",
+				Schedule: &job.ScheduleSpec{
+					Interval: &job.IntervalSpec{EverySeconds: 5400, StartAt: 1700000000},
+				},
+			},
+		},
+	}
+}
+
+func mustImport(t *testing.T, porter PolicyPorter, policies []*ExportedPolicy, dryRun bool) *PolicyImportReport {
+	t.Helper()
+
+	report, err := porter.Import(&PolicyExport{Version: PolicyExportVersion, Policies: policies}, dryRun)
+	if err != nil {
+		t.Fatalf("import policies error: %s", err)
+	}
+
+	return report
+}
+
+func TestPolicyPorterRoundTrip(t *testing.T) {
+	srcPool, _ := newTestPool(t)
+	dstPool, dst := newTestPool(t)
+	src := NewPolicyPorter(context.Background(), "{period_src}", srcPool)
+	target := NewPolicyPorter(context.Background(), "{period_dst}", dstPool)
+
+	if report := mustImport(t, src, testPolicies(), false); report.Imported != 2 {
+		t.Fatalf("expect 2 policies imported into the source but got %+v", report)
+	}
+
+	exported, err := src.Export()
+	if err != nil {
+		t.Fatalf("export policies error: %s", err)
+	}
+	if exported.Version != PolicyExportVersion || exported.Namespace != "{period_src}" || !reflect.DeepEqual(exported.Policies, testPolicies()) {
+		t.Fatalf("expect the exported policies same as the source ones but got %+v", exported)
+	}
+
+	// Dry run changes nothing
+	report := mustImport(t, target, exported.Policies, true)
+	if !report.DryRun || report.Imported != 2 || report.Results[0].Result != ImportResultWouldImport {
+		t.Errorf("expect 2 policies would be imported but got %+v", report)
+	}
+	if dst.Exists(rds.KeyPeriodicPolicy("{period_dst}")) {
+		t.Fatal("expect nothing imported in the dry run")
+	}
+
+	report, err = target.Import(exported, false)
+	if err != nil || report.Imported != 2 || report.Conflicts+report.Invalid+report.Failed != 0 {
+		t.Fatalf("expect 2 policies imported but got %+v, %v", report, err)
+	}
+
+	imported, err := target.Export()
+	if err != nil {
+		t.Fatalf("export imported policies error: %s", err)
+	}
+	if !reflect.DeepEqual(imported.Policies, exported.Policies) {
+		t.Errorf("expect the imported policies same as the exported ones but got %+v", imported.Policies)
+	}
+	// The periodic jobs are tracked
+	for _, id := range []string{"p1", "p2"} {
+		if !dst.Exists(rds.KeyJobStats("{period_dst}", id)) {
+			t.Errorf("expect the stats of periodic job %s saved", id)
+		}
+	}
+}
+
+func TestPolicyPorterImportConflicts(t *testing.T) {
+	pool, mr := newTestPool(t)
+	porter := NewPolicyPorter(context.Background(), testNamespace, pool)
+
+	existing := testPolicies()[:1]
+	mustImport(t, porter, existing, false)
+	// A job not scheduled by any policy
+	mr.HSet(rds.KeyJobStats(testNamespace, "job-1"), "id", "job-1")
+
+	policies := []*ExportedPolicy{
+		// Same ID with the different schedule
+		{NumericID: 10, Policy: &Policy{ID: "p1", JobName: "demo", CronSpec: "0 */5 * * * *"}},
+		{NumericID: 3, Policy: &Policy{ID: "p3", JobName: "demo", CronSpec: "0 0 * * * *"}},
+		{NumericID: 11, Policy: &Policy{ID: "job-1", JobName: "demo", CronSpec: "0 0 * * * *"}},
+		{NumericID: 12, Policy: &Policy{ID: "p4", JobName: "demo", CronSpec: "0 0 * * * *"}},
+		// Duplicated in the same export
+		{NumericID: 13, Policy: &Policy{ID: "p4", JobName: "demo", CronSpec: "0 0 * * * *"}},
+		{NumericID: 14, Policy: &Policy{ID: "p5", CronSpec: "0 0 * * * *"}},
+		{NumericID: 0, Policy: &Policy{ID: "p6", JobName: "demo", CronSpec: "0 0 * * * *"}},
+		nil,
+	}
+	expected := []string{
+		ImportResultConflict,
+		ImportResultConflict,
+		ImportResultConflict,
+		ImportResultImported,
+		ImportResultConflict,
+		ImportResultInvalid,
+		ImportResultInvalid,
+		ImportResultInvalid,
+	}
+
+	report := mustImport(t, porter, policies, false)
+	if report.Imported != 1 || report.Conflicts != 4 || report.Invalid != 3 || report.Failed != 0 {
+		t.Errorf("expect 1 imported, 4 conflicts and 3 invalid but got %+v", report)
+	}
+	for i, res := range report.Results {
+		if res.Result != expected[i] {
+			t.Errorf("policy %d: expect %s but got %s (%s)", i, expected[i], res.Result, res.Reason)
+		}
+	}
+
+	// The existing policy is kept
+	exported, err := porter.Export()
+	if err != nil {
+		t.Fatalf("export policies error: %s", err)
+	}
+	if !reflect.DeepEqual(exported.Policies, []*ExportedPolicy{existing[0], policies[3]}) {
+		t.Errorf("expect the existing policy kept and p4 imported but got %+v", exported.Policies)
+	}
+	if mr.Exists(rds.KeyJobStats(testNamespace, "p3")) {
+		t.Error("expect no stats saved for the conflicting policy")
+	}
+
+	// Unsupported version
+	if _, err := porter.Import(&PolicyExport{Version: PolicyExportVersion + 1}, false); err == nil {
+		t.Error("expect the unsupported version rejected")
+	}
+}
@@ -7,10 +7,12 @@ import (
 	"fmt"
 	"github.com/chenxull/goGridhub/gridhub/src/jobservice/common/rds"
 	"github.com/chenxull/goGridhub/gridhub/src/jobservice/common/utils"
+	"github.com/chenxull/goGridhub/gridhub/src/jobservice/job"
 	"github.com/chenxull/goGridhub/gridhub/src/jobservice/logger"
 	"github.com/gomodule/redigo/redis"
 	"github.com/robfig/cron"
 	"sync"
+	"time"
 )
 
 const (
@@ -29,6 +31,8 @@ type Policy struct {
 	CronSpec      string                 `json:"cron_spec"`
 	JobParameters map[string]interface{} `json:"job_params,omitempty"`
 	WebHookURL    string                 `json:"web_hook_url,omitempty"`
+	// Interval, one-off runs and blackout windows of the policy
+	Schedule *job.ScheduleSpec `json:"schedule,omitempty"`
 }
 
 // Serialize the policy to raw data.
@@ -57,13 +61,40 @@ func (p *Policy) Validate() error {
 		}
 	}
 
-	if _, err := cron.Parse(p.CronSpec); err != nil {
+	if _, err := p.Timing(); err != nil {
 		return err
 	}
 
 	return nil
 }
 
+// Timing returns the schedule generating the run times of the policy
+func (p *Policy) Timing() (cron.Schedule, error) {
+	return NewSchedule(p.CronSpec, p.Schedule)
+}
+
+// JobStats builds the stats of the periodic job scheduled by the policy with the numeric ID
+func (p *Policy) JobStats(numericID int64) *job.Stats {
+	now := time.Now().Unix()
+
+	return &job.Stats{
+		Info: &job.StatsInfo{
+			JobID:       p.ID,
+			JobName:     p.JobName,
+			Status:      job.ScheduledStatus.String(),
+			JobKind:     job.KindPeriodic,
+			CronSpec:    p.CronSpec,
+			Schedule:    p.Schedule,
+			WebHookURL:  p.WebHookURL,
+			NumericPID:  numericID,
+			EnqueueTime: now,
+			UpdateTime:  now,
+			RefLink:     fmt.Sprintf("/api/v1/jobs/%s", p.ID),
+			Parameters:  p.JobParameters,
+		},
+	}
+}
+
 // policyStore is in-memory cache for the periodic job policies.
 type policyStore struct {
 	// k-v pair and key is the policy ID
@@ -0,0 +1,204 @@
+package period
+
+import (
+	"github.com/chenxull/goGridhub/gridhub/src/jobservice/common/utils"
+	"github.com/chenxull/goGridhub/gridhub/src/jobservice/job"
+	"github.com/pkg/errors"
+	"github.com/robfig/cron"
+	"sort"
+	"time"
+)
+
+const (
+	// MinInterval is the minimum interval of the interval schedule
+	MinInterval = 60 * time.Second
+	// Max runs skipped in the blackout windows when looking for the next run
+	maxBlackoutSkips = 10000
+)
+
+// NewSchedule returns the schedule generating the run times of the periodic job.
+// Exactly one of the cron spec, the interval and the one-off run list should be set.
+func NewSchedule(cronSpec string, spec *job.ScheduleSpec) (cron.Schedule, error) {
+	if spec == nil {
+		spec = &job.ScheduleSpec{}
+	}
+
+	bases := 0
+	var base cron.Schedule
+	if !utils.IsEmptyStr(cronSpec) {
+		bases++
+		s, err := cron.Parse(cronSpec)
+		if err != nil {
+			return nil, err
+		}
+		base = s
+	}
+	if spec.Interval != nil {
+		bases++
+		s, err := newIntervalSchedule(spec.Interval)
+		if err != nil {
+			return nil, err
+		}
+		base = s
+	}
+	if len(spec.RunList) > 0 {
+		bases++
+		base = newRunListSchedule(spec.RunList)
+	}
+
+	if bases == 0 {
+		return nil, errors.New("one of the cron spec, interval and run list must be specified")
+	}
+	if bases > 1 {
+		return nil, errors.New("only one of the cron spec, interval and run list can be specified")
+	}
+
+	if len(spec.Blackouts) == 0 {
+		return base, nil
+	}
+
+	bs := &blackoutSchedule{
+		base:    base,
+		windows: make([]*blackout, 0, len(spec.Blackouts)),
+	}
+	for _, w := range spec.Blackouts {
+		b, err := newBlackout(w)
+		if err != nil {
+			return nil, err
+		}
+		bs.windows = append(bs.windows, b)
+	}
+
+	return bs, nil
+}
+
+// intervalSchedule runs with the fixed interval from the start time
+type intervalSchedule struct {
+	start int64
+	every int64
+}
+
+func newIntervalSchedule(spec *job.IntervalSpec) (*intervalSchedule, error) {
+	if time.Duration(spec.EverySeconds)*time.Second < MinInterval {
+		return nil, errors.Errorf("interval %ds is less than the minimum %s", spec.EverySeconds, MinInterval)
+	}
+	if spec.StartAt <= 0 {
+		return nil, errors.New("missing start time of the interval schedule")
+	}
+
+	return &intervalSchedule{
+		start: spec.StartAt,
+		every: int64(spec.EverySeconds),
+	}, nil
+}
+
+// Next implements cron.Schedule
+func (is *intervalSchedule) Next(t time.Time) time.Time {
+	now := t.Unix()
+	if now < is.start {
+		return time.Unix(is.start, 0)
+	}
+
+	return time.Unix(is.start+((now-is.start)/is.every+1)*is.every, 0)
+}
+
+// runListSchedule runs at the listed times only once
+type runListSchedule struct {
+	runs []int64
+}
+
+func newRunListSchedule(runs []int64) *runListSchedule {
+	sorted := make([]int64, len(runs))
+	copy(sorted, runs)
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i] < sorted[j]
+	})
+
+	return &runListSchedule{
+		runs: sorted,
+	}
+}
+
+// Next implements cron.Schedule, the zero time is returned if all the runs are passed
+func (rs *runListSchedule) Next(t time.Time) time.Time {
+	now := t.Unix()
+	i := sort.Search(len(rs.runs), func(i int) bool {
+		return rs.runs[i] > now
+	})
+	if i == len(rs.runs) {
+		return time.Time{}
+	}
+
+	return time.Unix(rs.runs[i], 0)
+}
+
+// blackout is the parsed blackout window
+type blackout struct {
+	start    cron.Schedule
+	duration time.Duration
+	location *time.Location
+}
+
+func newBlackout(w *job.BlackoutWindow) (*blackout, error) {
+	if w == nil {
+		return nil, errors.New("nil blackout window")
+	}
+
+	start, err := cron.Parse(w.Start)
+	if err != nil {
+		return nil, errors.Wrapf(err, "blackout window start %s", w.Start)
+	}
+	if w.DurationSeconds == 0 {
+		return nil, errors.Errorf("missing duration of the blackout window %s", w.Start)
+	}
+
+	loc := time.Local
+	if !utils.IsEmptyStr(w.TimeZone) {
+		if loc, err = time.LoadLocation(w.TimeZone); err != nil {
+			return nil, errors.Wrapf(err, "blackout window time zone %s", w.TimeZone)
+		}
+	}
+
+	return &blackout{
+		start:    start,
+		duration: time.Duration(w.DurationSeconds) * time.Second,
+		location: loc,
+	}, nil
+}
+
+// covers checks if the time is in the window, the window started within the duration before it
+func (b *blackout) covers(t time.Time) bool {
+	t = t.In(b.location)
+	started := b.start.Next(t.Add(-b.duration))
+
+	return !started.IsZero() && !started.After(t)
+}
+
+// blackoutSchedule skips the runs of the base schedule falling in the blackout windows
+type blackoutSchedule struct {
+	base    cron.Schedule
+	windows []*blackout
+}
+
+// Next implements cron.Schedule
+func (bs *blackoutSchedule) Next(t time.Time) time.Time {
+	for i := 0; i < maxBlackoutSkips; i++ {
+		t = bs.base.Next(t)
+		if t.IsZero() || !bs.blackedOut(t) {
+			return t
+		}
+	}
+
+	// Too many runs are skipped, it's the schedule in the blackout all the time
+	return time.Time{}
+}
+
+func (bs *blackoutSchedule) blackedOut(t time.Time) bool {
+	for _, w := range bs.windows {
+		if w.covers(t) {
+			return true
+		}
+	}
+
+	return false
+}
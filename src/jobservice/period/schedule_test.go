@@ -0,0 +1,213 @@
+package period
+
+import (
+	"github.com/chenxull/goGridhub/gridhub/src/jobservice/job"
+	"strings"
+	"testing"
+	"time"
+	// Embed the time zone database to not depend on the one of the host
+	_ "time/tzdata"
+)
+
+func mustTime(t *testing.T, value string) time.Time {
+	t.Helper()
+
+	tm, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		t.Fatalf("parse time %s error: %s", value, err)
+	}
+
+	return tm
+}
+
+func TestScheduleNext(t *testing.T) {
+	cases := []struct {
+		name     string
+		cronSpec string
+		spec     *job.ScheduleSpec
+		from     string
+		// The next runs in order, empty means the zero time
+		expected []string
+	}{
+		{
+			name:     "interval before start",
+			spec:     &job.ScheduleSpec{Interval: &job.IntervalSpec{EverySeconds: 5400, StartAt: 1700000000}},
+			from:     "2023-11-14T00:00:00Z",
+			expected: []string{"2023-11-14T22:13:20Z", "2023-11-14T23:43:20Z", "2023-11-15T01:13:20Z"},
+		},
+		{
+			name:     "interval at run",
+			spec:     &job.ScheduleSpec{Interval: &job.IntervalSpec{EverySeconds: 60, StartAt: 1700000000}},
+			from:     "2023-11-14T22:13:20Z",
+			expected: []string{"2023-11-14T22:14:20Z", "2023-11-14T22:15:20Z"},
+		},
+		{
+			name:     "run list in order",
+			spec:     &job.ScheduleSpec{RunList: []int64{1700003600, 1700000000, 1700007200}},
+			from:     "2023-11-14T22:13:20Z",
+			expected: []string{"2023-11-14T23:13:20Z", "2023-11-15T00:13:20Z", ""},
+		},
+		{
+			name:     "window start is covered",
+			cronSpec: "0 */30 * * * *",
+			spec: &job.ScheduleSpec{Blackouts: []*job.BlackoutWindow{
+				{Start: "0 0 9 * * *", DurationSeconds: 3600, TimeZone: "UTC"},
+			}},
+			from:     "2024-01-15T08:10:00Z",
+			expected: []string{"2024-01-15T08:30:00Z", "2024-01-15T10:00:00Z", "2024-01-15T10:30:00Z"},
+		},
+		{
+			name:     "window end is not covered",
+			cronSpec: "0 0 * * * *",
+			spec: &job.ScheduleSpec{Blackouts: []*job.BlackoutWindow{
+				{Start: "0 0 9 * * *", DurationSeconds: 7199, TimeZone: "UTC"},
+			}},
+			from:     "2024-01-15T08:30:00Z",
+			expected: []string{"2024-01-15T11:00:00Z"},
+		},
+		{
+			name:     "window in time zone",
+			cronSpec: "0 0 * * * *",
+			spec: &job.ScheduleSpec{Blackouts: []*job.BlackoutWindow{
+				{Start: "0 0 9 * * *", DurationSeconds: 3600, TimeZone: "Asia/Shanghai"},
+			}},
+			from:     "2024-01-15T00:30:00Z",
+			expected: []string{"2024-01-15T02:00:00Z"},
+		},
+		{
+			name:     "windows overlapping",
+			cronSpec: "0 0 * * * *",
+			spec: &job.ScheduleSpec{Blackouts: []*job.BlackoutWindow{
+				{Start: "0 0 9 * * *", DurationSeconds: 3600, TimeZone: "UTC"},
+				{Start: "0 30 9 * * *", DurationSeconds: 3600, TimeZone: "UTC"},
+			}},
+			from:     "2024-01-15T08:30:00Z",
+			expected: []string{"2024-01-15T11:00:00Z"},
+		},
+		{
+			name:     "window before DST starts",
+			cronSpec: "0 0 * * * *",
+			spec: &job.ScheduleSpec{Blackouts: []*job.BlackoutWindow{
+				// 01:30 EST is 06:30 UTC
+				{Start: "0 30 1 * * *", DurationSeconds: 3600, TimeZone: "America/New_York"},
+			}},
+			from:     "2024-03-10T06:10:00Z",
+			expected: []string{"2024-03-10T08:00:00Z"},
+		},
+		{
+			name:     "window after DST starts",
+			cronSpec: "0 0 * * * *",
+			spec: &job.ScheduleSpec{Blackouts: []*job.BlackoutWindow{
+				// 01:30 EDT is 05:30 UTC
+				{Start: "0 30 1 * * *", DurationSeconds: 3600, TimeZone: "America/New_York"},
+			}},
+			from:     "2024-03-11T05:10:00Z",
+			expected: []string{"2024-03-11T07:00:00Z"},
+		},
+		{
+			name:     "window after DST ends",
+			cronSpec: "0 0 * * * *",
+			spec: &job.ScheduleSpec{Blackouts: []*job.BlackoutWindow{
+				// 09:00 EST is 14:00 UTC
+				{Start: "0 0 9 * * *", DurationSeconds: 3600, TimeZone: "America/New_York"},
+			}},
+			from:     "2024-11-04T12:30:00Z",
+			expected: []string{"2024-11-04T13:00:00Z", "2024-11-04T15:00:00Z"},
+		},
+		{
+			name:     "runs out in window",
+			spec:     &job.ScheduleSpec{RunList: []int64{1705309200}, Blackouts: []*job.BlackoutWindow{{Start: "0 0 9 * * *", DurationSeconds: 3600, TimeZone: "UTC"}}},
+			from:     "2024-01-15T00:00:00Z",
+			expected: []string{""},
+		},
+		{
+			name:     "always in window",
+			cronSpec: "0 * * * * *",
+			spec: &job.ScheduleSpec{Blackouts: []*job.BlackoutWindow{
+				{Start: "0 0 * * * *", DurationSeconds: 3600, TimeZone: "UTC"},
+			}},
+			from:     "2024-01-15T00:00:00Z",
+			expected: []string{""},
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			s, err := NewSchedule(c.cronSpec, c.spec)
+			if err != nil {
+				t.Fatalf("new schedule error: %s", err)
+			}
+
+			next := mustTime(t, c.from)
+			for i, expected := range c.expected {
+				next = s.Next(next)
+				if len(expected) == 0 {
+					if !next.IsZero() {
+						t.Fatalf("run %d: expect zero time but got %s", i, next.UTC().Format(time.RFC3339))
+					}
+					return
+				}
+				if !next.Equal(mustTime(t, expected)) {
+					t.Fatalf("run %d: expect %s but got %s", i, expected, next.UTC().Format(time.RFC3339))
+				}
+			}
+		})
+	}
+}
+
+func TestBlackoutCovers(t *testing.T) {
+	b, err := newBlackout(&job.BlackoutWindow{Start: "0 0 9 * * *", DurationSeconds: 1800, TimeZone: "Europe/Berlin"})
+	if err != nil {
+		t.Fatalf("new blackout error: %s", err)
+	}
+
+	cases := []struct {
+		at      string
+		covered bool
+	}{
+		{at: "2024-07-01T06:59:59Z", covered: false},
+		// 09:00 CEST
+		{at: "2024-07-01T07:00:00Z", covered: true},
+		{at: "2024-07-01T07:29:59Z", covered: true},
+		{at: "2024-07-01T07:30:00Z", covered: false},
+		// 09:00 CET
+		{at: "2024-01-15T07:00:00Z", covered: false},
+		{at: "2024-01-15T08:00:00Z", covered: true},
+		// Same instant in the other time zone
+		{at: "2024-01-15T03:15:00-05:00", covered: true},
+	}
+
+	for _, c := range cases {
+		if covered := b.covers(mustTime(t, c.at)); covered != c.covered {
+			t.Errorf("%s: expect covered %v but got %v", c.at, c.covered, covered)
+		}
+	}
+}
+
+func TestNewScheduleRejected(t *testing.T) {
+	cases := []struct {
+		name     string
+		cronSpec string
+		spec     *job.ScheduleSpec
+		err      string
+	}{
+		{name: "no base", err: "must be specified"},
+		{name: "multiple bases", cronSpec: "0 0 * * * *", spec: &job.ScheduleSpec{RunList: []int64{1}}, err: "only one of"},
+		{name: "short interval", spec: &job.ScheduleSpec{Interval: &job.IntervalSpec{EverySeconds: 59, StartAt: 1}}, err: "less than the minimum"},
+		{name: "missing start", spec: &job.ScheduleSpec{Interval: &job.IntervalSpec{EverySeconds: 60}}, err: "missing start time"},
+		{name: "invalid cron", cronSpec: "0 0 *", err: "fields"},
+		{name: "nil window", cronSpec: "0 0 * * * *", spec: &job.ScheduleSpec{Blackouts: []*job.BlackoutWindow{nil}}, err: "nil blackout window"},
+		{name: "invalid window start", cronSpec: "0 0 * * * *", spec: &job.ScheduleSpec{Blackouts: []*job.BlackoutWindow{{Start: "x", DurationSeconds: 1}}}, err: "blackout window start"},
+		{name: "zero duration", cronSpec: "0 0 * * * *", spec: &job.ScheduleSpec{Blackouts: []*job.BlackoutWindow{{Start: "0 0 9 * * *"}}}, err: "missing duration"},
+		{name: "unknown time zone", cronSpec: "0 0 * * * *", spec: &job.ScheduleSpec{Blackouts: []*job.BlackoutWindow{{Start: "0 0 9 * * *", DurationSeconds: 1, TimeZone: "Mars/Base"}}}, err: "time zone Mars/Base"},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			_, err := NewSchedule(c.cronSpec, c.spec)
+			if err == nil || !strings.Contains(err.Error(), c.err) {
+				t.Errorf("expect error containing %q but got %v", c.err, err)
+			}
+		})
+	}
+}
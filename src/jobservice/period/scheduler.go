@@ -9,4 +9,8 @@ type Scheduler interface {
 	Schedule(policy *Policy) (int64, error)
 
 	UnSchedule(policyID string) error
+
+	// Leader returns the node which is scheduling the periodic jobs now.
+	// Nil is returned if no leader is elected.
+	Leader() (*Leader, error)
 }
@@ -0,0 +1,176 @@
+package ratelimit
+
+import (
+	"github.com/chenxull/goGridhub/gridhub/src/jobservice/common/rds"
+	"github.com/chenxull/goGridhub/gridhub/src/jobservice/config"
+	"github.com/chenxull/goGridhub/gridhub/src/jobservice/logger"
+	"github.com/gomodule/redigo/redis"
+	"time"
+)
+
+const (
+	// RejectionSlot is the time slot in which the rejections are counted together
+	RejectionSlot = time.Hour
+	// RejectionSlots is the number of the recent time slots of which the rejections are kept
+	RejectionSlots = 24
+	// MaxRejectionPairs is the max number of the caller and job pairs counted in a time slot,
+	// the rejections of the other pairs are counted under OtherRejections
+	MaxRejectionPairs = 10000
+	// OtherRejections is the field of the rejections not counted by the caller and job pair
+	OtherRejections = "*:*"
+)
+
+// Refill the bucket and take one token if possible.
+// KEYS[1]: bucket key; KEYS[2]: rejections key of the current time slot
+// ARGV[1]: rate (tokens/second); ARGV[2]: burst; ARGV[3]: now (ms); ARGV[4]: rejection field;
+// ARGV[5]: max rejection fields; ARGV[6]: field of the other rejections; ARGV[7]: rejections TTL (ms)
+// Returns 0 if the token is taken, otherwise the milliseconds to wait for the next token.
+var takeTokenScript = redis.NewScript(2, `
+local rate = tonumber(ARGV[1])
+local burst = tonumber(ARGV[2])
+local now = tonumber(ARGV[3])
+
+local tokens = burst
+local ts = now
+local bucket = redis.call('HMGET', KEYS[1], 'tokens', 'ts')
+if bucket[1] and bucket[2] then
+  tokens = tonumber(bucket[1])
+  ts = tonumber(bucket[2])
+  if now > ts then
+    tokens = math.min(burst, tokens + (now - ts) * rate / 1000)
+  end
+end
+
+local wait = 0
+if tokens >= 1 then
+  tokens = tokens - 1
+else
+  wait = math.ceil((1 - tokens) * 1000 / rate)
+  local field = ARGV[4]
+  if redis.call('HEXISTS', KEYS[2], field) == 0 and redis.call('HLEN', KEYS[2]) >= tonumber(ARGV[5]) then
+    field = ARGV[6]
+  end
+  redis.call('HINCRBY', KEYS[2], field, 1)
+  redis.call('PEXPIRE', KEYS[2], ARGV[7])
+end
+
+-- Keep the tokens in the fixed-point notation, the exponent of the tiny tokens is not parsed by all the interpreters
+redis.call('HMSET', KEYS[1], 'tokens', string.format('%.12f', tokens), 'ts', now)
+-- The bucket is full again after this duration, no need to keep it
+redis.call('PEXPIRE', KEYS[1], math.ceil(burst * 1000 / rate) + 1000)
+
+return wait
+`)
+
+// Limiter limits the rate of launching jobs by the caller.
+type Limiter interface {
+	// Take one token from the bucket of the caller and job.
+	//
+	// Arguments:
+	//   caller string : identity of the authenticated caller
+	//   jobName string: name of the job to launch
+	//
+	// Returns:
+	//   true if the request is allowed
+	//   the duration to wait before retrying if it's not allowed
+	//   non nil error if any issues meet
+	Take(caller string, jobName string) (bool, time.Duration, error)
+
+	// Rejections returns the counts of the requests rejected in the recent RejectionSlots time slots,
+	// keyed by "caller:job". The pairs beyond MaxRejectionPairs in a slot are counted under OtherRejections.
+	Rejections() (map[string]int64, error)
+
+	// Reload the rate limit rules
+	Reload(cfg *config.RateLimitConfig)
+}
+
+// redisLimiter is a token bucket limiter based on redis to share the buckets across nodes
+type redisLimiter struct {
+	namespace string
+	pool      *redis.Pool
+	*Rules
+}
+
+// NewLimiter is constructor of redisLimiter
+func NewLimiter(namespace string, pool *redis.Pool, cfg *config.RateLimitConfig) Limiter {
+	return &redisLimiter{
+		namespace: namespace,
+		pool:      pool,
+		Rules:     NewRules(cfg),
+	}
+}
+
+// Take is implementation of Limiter.Take
+func (rl *redisLimiter) Take(caller string, jobName string) (bool, time.Duration, error) {
+	rule := rl.Match(caller, jobName)
+	if rule == nil {
+		// No limit
+		return true, 0, nil
+	}
+
+	conn := rl.pool.Get()
+	defer func() {
+		_ = conn.Close()
+	}()
+
+	now := time.Now()
+	wait, err := redis.Int64(takeTokenScript.Do(
+		conn,
+		rds.KeyRateLimitBucket(rl.namespace, caller, jobName),
+		rds.KeyRateLimitRejections(rl.namespace, RejectionSlotOf(now)),
+		rule.Rate,
+		rule.Burst,
+		now.UnixNano()/int64(time.Millisecond),
+		caller+":"+jobName,
+		MaxRejectionPairs,
+		OtherRejections,
+		int64((RejectionSlots+1)*RejectionSlot/time.Millisecond),
+	))
+	if err != nil {
+		// Fail open, the rate limiting should not block the service
+		logger.Errorf("take rate limit token for %s:%s error: %s", caller, jobName, err)
+		return true, 0, err
+	}
+
+	if wait > 0 {
+		return false, time.Duration(wait) * time.Millisecond, nil
+	}
+
+	return true, 0, nil
+}
+
+// Rejections is implementation of Limiter.Rejections
+func (rl *redisLimiter) Rejections() (map[string]int64, error) {
+	conn := rl.pool.Get()
+	defer func() {
+		_ = conn.Close()
+	}()
+
+	current := RejectionSlotOf(time.Now())
+	for slot := current - RejectionSlots + 1; slot <= current; slot++ {
+		if err := conn.Send("HGETALL", rds.KeyRateLimitRejections(rl.namespace, slot)); err != nil {
+			return nil, err
+		}
+	}
+	if err := conn.Flush(); err != nil {
+		return nil, err
+	}
+
+	rejections := make(map[string]int64)
+	for i := 0; i < RejectionSlots; i++ {
+		values, err := redis.Int64Map(conn.Receive())
+		if err != nil {
+			return nil, err
+		}
+		for k, v := range values {
+			rejections[k] += v
+		}
+	}
+
+	return rejections, nil
+}
+
+// RejectionSlotOf returns the time slot of the rejections at the time
+func RejectionSlotOf(t time.Time) int64 {
+	return t.UnixNano() / int64(RejectionSlot)
+}
@@ -0,0 +1,153 @@
+package ratelimit
+
+import (
+	"fmt"
+	"github.com/alicebob/miniredis/v2"
+	"github.com/chenxull/goGridhub/gridhub/src/jobservice/common/rds"
+	"github.com/chenxull/goGridhub/gridhub/src/jobservice/config"
+	"github.com/gomodule/redigo/redis"
+	"testing"
+	"time"
+)
+
+const testNamespace = "{ratelimit_test}"
+
+// newTestLimiter returns the limiter based on the in-process redis running the token bucket script
+func newTestLimiter(t *testing.T, cfg *config.RateLimitConfig) (*redisLimiter, *miniredis.Miniredis) {
+	t.Helper()
+
+	mr := miniredis.RunT(t)
+	pool := &redis.Pool{
+		Dial: func() (redis.Conn, error) {
+			return redis.Dial("tcp", mr.Addr())
+		},
+	}
+	t.Cleanup(func() {
+		_ = pool.Close()
+	})
+
+	return NewLimiter(testNamespace, pool, cfg).(*redisLimiter), mr
+}
+
+func TestRedisLimiterTake(t *testing.T) {
+	cfg := &config.RateLimitConfig{
+		Rules: []*config.RateLimitRule{
+			{Caller: "ci-*", Job: "SCAN", Rate: 1, Burst: 2},
+			{Caller: "fast", Rate: 200, Burst: 1},
+		},
+	}
+
+	cases := []struct {
+		name    string
+		caller  string
+		job     string
+		takes   int
+		allowed int
+		// Sleep before the last take to refill the bucket
+		refill time.Duration
+	}{
+		{name: "burst is allowed", caller: "ci-1", job: "SCAN", takes: 2, allowed: 2},
+		{name: "beyond burst is rejected", caller: "ci-1", job: "SCAN", takes: 4, allowed: 2},
+		{name: "buckets are separated by caller", caller: "ci-2", job: "SCAN", takes: 3, allowed: 2},
+		{name: "bucket is refilled", caller: "fast", job: "GC", takes: 3, allowed: 2, refill: 20 * time.Millisecond},
+		{name: "no matched rule", caller: "other", job: "SCAN", takes: 5, allowed: 5},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			rl, mr := newTestLimiter(t, cfg)
+
+			allowed := 0
+			for i := 0; i < c.takes; i++ {
+				if i == c.takes-1 && c.refill > 0 {
+					time.Sleep(c.refill)
+				}
+
+				ok, wait, err := rl.Take(c.caller, c.job)
+				if err != nil {
+					t.Fatalf("take token error: %s", err)
+				}
+				if ok {
+					allowed++
+					continue
+				}
+
+				rule := rl.Match(c.caller, c.job)
+				if wait <= 0 || wait > time.Duration(float64(time.Second)/rule.Rate)+time.Millisecond {
+					t.Errorf("expect wait in (0, %v] but got %v", time.Duration(float64(time.Second)/rule.Rate), wait)
+				}
+			}
+
+			if allowed != c.allowed {
+				t.Errorf("expect %d allowed but got %d", c.allowed, allowed)
+			}
+
+			if rule := rl.Match(c.caller, c.job); rule != nil {
+				if ttl := mr.TTL(rds.KeyRateLimitBucket(testNamespace, c.caller, c.job)); ttl <= 0 {
+					t.Errorf("expect bucket with TTL but got %v", ttl)
+				}
+			}
+		})
+	}
+}
+
+func TestRedisLimiterRejections(t *testing.T) {
+	cfg := &config.RateLimitConfig{
+		Default: &config.RateLimitRule{Rate: 0.001, Burst: 1},
+	}
+
+	t.Run("counted by caller and job", func(t *testing.T) {
+		rl, mr := newTestLimiter(t, cfg)
+
+		for i := 0; i < 3; i++ {
+			_, _, _ = rl.Take("alice", "SCAN")
+		}
+		_, _, _ = rl.Take("bob", "GC")
+		_, _, _ = rl.Take("bob", "GC")
+
+		// Counted in the previous slots
+		previous := rds.KeyRateLimitRejections(testNamespace, RejectionSlotOf(time.Now())-1)
+		mr.HSet(previous, "alice:SCAN", "5")
+		// Out of the recent slots
+		expired := rds.KeyRateLimitRejections(testNamespace, RejectionSlotOf(time.Now())-RejectionSlots)
+		mr.HSet(expired, "alice:SCAN", "100")
+
+		rejections, err := rl.Rejections()
+		if err != nil {
+			t.Fatalf("get rejections error: %s", err)
+		}
+		if rejections["alice:SCAN"] != 7 || rejections["bob:GC"] != 1 || len(rejections) != 2 {
+			t.Errorf("expect rejections map[alice:SCAN:7 bob:GC:1] but got %v", rejections)
+		}
+
+		current := rds.KeyRateLimitRejections(testNamespace, RejectionSlotOf(time.Now()))
+		if ttl := mr.TTL(current); ttl <= RejectionSlots*RejectionSlot {
+			t.Errorf("expect rejections TTL longer than the recent slots but got %v", ttl)
+		}
+	})
+
+	t.Run("bounded pairs", func(t *testing.T) {
+		rl, mr := newTestLimiter(t, cfg)
+
+		current := rds.KeyRateLimitRejections(testNamespace, RejectionSlotOf(time.Now()))
+		for i := 0; i < MaxRejectionPairs; i++ {
+			mr.HSet(current, fmt.Sprintf("caller-%d:SCAN", i), "1")
+		}
+
+		// Take the tokens first
+		_, _, _ = rl.Take("caller-0", "SCAN")
+		_, _, _ = rl.Take("new", "SCAN")
+		// Rejected
+		_, _, _ = rl.Take("caller-0", "SCAN")
+		_, _, _ = rl.Take("new", "SCAN")
+
+		rejections, err := rl.Rejections()
+		if err != nil {
+			t.Fatalf("get rejections error: %s", err)
+		}
+		if rejections["caller-0:SCAN"] != 2 || rejections[OtherRejections] != 1 || rejections["new:SCAN"] != 0 {
+			t.Errorf("expect the new pair counted under %s but got %d, %d, %d", OtherRejections,
+				rejections["caller-0:SCAN"], rejections[OtherRejections], rejections["new:SCAN"])
+		}
+	})
+}
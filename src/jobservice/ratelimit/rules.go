@@ -0,0 +1,61 @@
+package ratelimit
+
+import (
+	"github.com/chenxull/goGridhub/gridhub/src/jobservice/config"
+	"path"
+	"sync"
+)
+
+// Rules keeps the rate limit rules which can be reloaded at runtime.
+// It's shared by the limiters of the different backends.
+type Rules struct {
+	// Protect the rules
+	lock  sync.RWMutex
+	rules []*config.RateLimitRule
+	def   *config.RateLimitRule
+}
+
+// NewRules is constructor of Rules
+func NewRules(cfg *config.RateLimitConfig) *Rules {
+	r := &Rules{}
+	r.Reload(cfg)
+
+	return r
+}
+
+// Reload is implementation of Limiter.Reload
+func (r *Rules) Reload(cfg *config.RateLimitConfig) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	r.rules = nil
+	r.def = nil
+	if cfg != nil {
+		r.rules = cfg.Rules
+		r.def = cfg.Default
+	}
+}
+
+// Match returns the first matched rule or the default one, nil means no limit
+func (r *Rules) Match(caller string, jobName string) *config.RateLimitRule {
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+
+	for _, rule := range r.rules {
+		if matchPattern(rule.Caller, caller) && matchPattern(rule.Job, jobName) {
+			return rule
+		}
+	}
+
+	return r.def
+}
+
+func matchPattern(pattern string, s string) bool {
+	if len(pattern) == 0 || pattern == "*" {
+		return true
+	}
+
+	// The pattern has been validated when loading the configurations
+	matched, _ := path.Match(pattern, s)
+	return matched
+}
@@ -0,0 +1,166 @@
+package retention
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"github.com/chenxull/goGridhub/gridhub/src/jobservice/common/query"
+	"github.com/chenxull/goGridhub/gridhub/src/jobservice/errs"
+	_ "github.com/lib/pq" // register pgsql driver
+	"github.com/pkg/errors"
+	"strings"
+)
+
+const createArchiveTable = `CREATE TABLE IF NOT EXISTS job_archive (
+	job_id VARCHAR(64) PRIMARY KEY,
+	job_name VARCHAR(256) NOT NULL,
+	status VARCHAR(32) NOT NULL,
+	kind VARCHAR(32) NOT NULL,
+	update_time BIGINT NOT NULL,
+	archived_at BIGINT NOT NULL,
+	stats TEXT NOT NULL
+);
+CREATE INDEX IF NOT EXISTS idx_job_archive_archived_at ON job_archive (archived_at);`
+
+// dbSink keeps the archived job stats in the PostgreSQL table 'job_archive'
+type dbSink struct {
+	db *sql.DB
+}
+
+// NewDatabaseSink is constructor of the sink based on PostgreSQL
+func NewDatabaseSink(dsn string) (Sink, error) {
+	db, err := sql.Open("postgres", dsn)
+	if err != nil {
+		return nil, errors.Wrap(err, "open archive database")
+	}
+
+	if _, err := db.Exec(createArchiveTable); err != nil {
+		_ = db.Close()
+		return nil, errors.Wrap(err, "create archive table")
+	}
+
+	return &dbSink{
+		db: db,
+	}, nil
+}
+
+// Archive implements Sink
+func (ds *dbSink) Archive(records []*Record) error {
+	tx, err := ds.db.Begin()
+	if err != nil {
+		return err
+	}
+
+	for _, r := range records {
+		data, err := json.Marshal(r.Job)
+		if err != nil {
+			_ = tx.Rollback()
+			return err
+		}
+
+		info := r.Job.Info
+		if _, err := tx.Exec(
+			`INSERT INTO job_archive (job_id, job_name, status, kind, update_time, archived_at, stats)
+			VALUES ($1, $2, $3, $4, $5, $6, $7)
+			ON CONFLICT (job_id) DO UPDATE SET
+			status = EXCLUDED.status, update_time = EXCLUDED.update_time, archived_at = EXCLUDED.archived_at, stats = EXCLUDED.stats`,
+			info.JobID, info.JobName, info.Status, KindOf(info), info.UpdateTime, r.ArchivedAt, string(data),
+		); err != nil {
+			_ = tx.Rollback()
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// Get implements Sink
+func (ds *dbSink) Get(jobID string) (*Record, error) {
+	row := ds.db.QueryRow(`SELECT archived_at, stats FROM job_archive WHERE job_id = $1`, jobID)
+
+	r, err := scanRecord(row)
+	if err == sql.ErrNoRows {
+		return nil, errs.NoObjectFoundError(jobID)
+	}
+
+	return r, err
+}
+
+// List implements Sink
+func (ds *dbSink) List(q *query.Parameter) ([]*Record, int64, error) {
+	f := newFilter(q)
+
+	conditions := make([]string, 0)
+	args := make([]interface{}, 0)
+	if len(f.name) > 0 {
+		args = append(args, f.name)
+		conditions = append(conditions, fmt.Sprintf("job_name = $%d", len(args)))
+	}
+	if len(f.status) > 0 {
+		args = append(args, f.status)
+		conditions = append(conditions, fmt.Sprintf("status = $%d", len(args)))
+	}
+
+	where := ""
+	if len(conditions) > 0 {
+		where = "WHERE " + strings.Join(conditions, " AND ")
+	}
+
+	var total int64
+	if err := ds.db.QueryRow(fmt.Sprintf(`SELECT COUNT(*) FROM job_archive %s`, where), args...).Scan(&total); err != nil {
+		return nil, 0, err
+	}
+
+	pageNumber, pageSize := page(q)
+	args = append(args, pageSize, (pageNumber-1)*pageSize)
+	rows, err := ds.db.Query(
+		fmt.Sprintf(`SELECT archived_at, stats FROM job_archive %s ORDER BY archived_at DESC, job_id LIMIT $%d OFFSET $%d`, where, len(args)-1, len(args)),
+		args...,
+	)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer func() {
+		_ = rows.Close()
+	}()
+
+	records := make([]*Record, 0)
+	for rows.Next() {
+		r, err := scanRecord(rows)
+		if err != nil {
+			return nil, 0, err
+		}
+		records = append(records, r)
+	}
+
+	return records, total, rows.Err()
+}
+
+// Close implements Sink
+func (ds *dbSink) Close() error {
+	return ds.db.Close()
+}
+
+// rowScanner is implemented by both sql.Row and sql.Rows
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanRecord(row rowScanner) (*Record, error) {
+	var (
+		archivedAt int64
+		data       string
+	)
+	if err := row.Scan(&archivedAt, &data); err != nil {
+		return nil, err
+	}
+
+	r := &Record{
+		ArchivedAt: archivedAt,
+	}
+	if err := json.Unmarshal([]byte(data), &r.Job); err != nil {
+		return nil, err
+	}
+
+	return r, nil
+}
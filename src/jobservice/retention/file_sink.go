@@ -0,0 +1,185 @@
+package retention
+
+import (
+	"bufio"
+	"encoding/json"
+	"github.com/chenxull/goGridhub/gridhub/src/jobservice/common/query"
+	"github.com/chenxull/goGridhub/gridhub/src/jobservice/errs"
+	"github.com/pkg/errors"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// Max size of one archived record in the file
+const maxRecordSize = 4 * 1024 * 1024
+
+// fileSink appends the archived job stats to the file as JSON lines
+type fileSink struct {
+	path string
+	lock sync.Mutex
+	file *os.File
+}
+
+// NewFileSink is constructor of the sink based on the file
+func NewFileSink(path string) (Sink, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, errors.Wrap(err, "create archive directory")
+	}
+
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0600)
+	if err != nil {
+		return nil, errors.Wrap(err, "open archive file")
+	}
+
+	return &fileSink{
+		path: path,
+		file: f,
+	}, nil
+}
+
+// Archive implements Sink
+func (fs *fileSink) Archive(records []*Record) error {
+	buf := make([]byte, 0)
+	for _, r := range records {
+		data, err := json.Marshal(r)
+		if err != nil {
+			return err
+		}
+		buf = append(buf, data...)
+		buf = append(buf, '\n')
+	}
+
+	fs.lock.Lock()
+	defer fs.lock.Unlock()
+
+	if _, err := fs.file.Write(buf); err != nil {
+		return err
+	}
+
+	return fs.file.Sync()
+}
+
+// Get implements Sink
+func (fs *fileSink) Get(jobID string) (*Record, error) {
+	var found *Record
+	err := fs.scan(func(r *Record) {
+		// The latest one wins if the job is archived more than once
+		if r.Job.Info.JobID == jobID {
+			found = r
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if found == nil {
+		return nil, errs.NoObjectFoundError(jobID)
+	}
+
+	return found, nil
+}
+
+// List implements Sink
+func (fs *fileSink) List(q *query.Parameter) ([]*Record, int64, error) {
+	f := newFilter(q)
+
+	// Count the matched records first to get the page from the end of the file
+	var total int64
+	if err := fs.scan(func(r *Record) {
+		if f.match(r.Job.Info) {
+			total++
+		}
+	}); err != nil {
+		return nil, 0, err
+	}
+
+	pageNumber, pageSize := page(q)
+	// Index range of the page in the file order
+	to := total - int64((pageNumber-1)*pageSize)
+	from := to - int64(pageSize)
+	if from < 0 {
+		from = 0
+	}
+
+	records := make([]*Record, 0)
+	if to <= 0 {
+		return records, total, nil
+	}
+
+	var index int64
+	if err := fs.scan(func(r *Record) {
+		if !f.match(r.Job.Info) {
+			return
+		}
+		if index >= from && index < to {
+			records = append(records, r)
+		}
+		index++
+	}); err != nil {
+		return nil, 0, err
+	}
+
+	// Latest first
+	for i, j := 0, len(records)-1; i < j; i, j = i+1, j-1 {
+		records[i], records[j] = records[j], records[i]
+	}
+
+	return records, total, nil
+}
+
+// Close implements Sink
+func (fs *fileSink) Close() error {
+	fs.lock.Lock()
+	defer fs.lock.Unlock()
+
+	return fs.file.Close()
+}
+
+// scan all the records in the file
+func (fs *fileSink) scan(handle func(r *Record)) error {
+	f, err := os.Open(fs.path)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = f.Close()
+	}()
+
+	reader := bufio.NewReaderSize(f, 64*1024)
+	for {
+		line, err := readLine(reader)
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		r := &Record{}
+		// Skip the broken line, e.g: the last line partially written
+		if err := json.Unmarshal(line, r); err != nil || r.Job == nil || r.Job.Info == nil {
+			continue
+		}
+		handle(r)
+	}
+}
+
+// readLine reads one line without the line break
+func readLine(reader *bufio.Reader) ([]byte, error) {
+	line := make([]byte, 0)
+	for {
+		part, isPrefix, err := reader.ReadLine()
+		if err != nil {
+			return nil, err
+		}
+		if len(line)+len(part) > maxRecordSize {
+			return nil, errors.New("archived record is too large")
+		}
+		line = append(line, part...)
+		if !isPrefix {
+			return line, nil
+		}
+	}
+}
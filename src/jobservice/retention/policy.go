@@ -0,0 +1,64 @@
+// Package retention controls how long the stats of the finished jobs are kept and
+// archives the expired stats to the configured sink before removing them.
+package retention
+
+import (
+	"github.com/chenxull/goGridhub/gridhub/src/jobservice/common/utils"
+	"github.com/chenxull/goGridhub/gridhub/src/jobservice/config"
+	"github.com/chenxull/goGridhub/gridhub/src/jobservice/job"
+	"path"
+	"time"
+)
+
+// policy implements job.Retention with the configured rules
+type policy struct {
+	rules    []*config.RetentionRule
+	archived bool
+}
+
+// NewPolicy is constructor of the retention policy
+func NewPolicy(cfg *config.RetentionConfig) job.Retention {
+	return &policy{
+		rules:    cfg.Rules,
+		archived: cfg.Archive != nil,
+	}
+}
+
+// KeepFor implements job.Retention
+func (p *policy) KeepFor(info *job.StatsInfo) time.Duration {
+	kind := KindOf(info)
+	for _, r := range p.rules {
+		if matchPattern(r.Job, info.JobName) &&
+			(utils.IsEmptyStr(r.Kind) || r.Kind == kind) &&
+			(utils.IsEmptyStr(r.Status) || r.Status == info.Status) {
+			return time.Duration(r.KeepSeconds) * time.Second
+		}
+	}
+
+	return 0
+}
+
+// Archived implements job.Retention
+func (p *policy) Archived() bool {
+	return p.archived
+}
+
+// KindOf returns the kind of the job matched by the retention rules,
+// the execution of the periodic job is differentiated from the generic job.
+func KindOf(info *job.StatsInfo) string {
+	if !utils.IsEmptyStr(info.UpstreamJobID) {
+		return config.RetentionKindExecution
+	}
+
+	return info.JobKind
+}
+
+// matchPattern checks if the value matches the pattern, empty or '*' matches all
+func matchPattern(pattern string, value string) bool {
+	if utils.IsEmptyStr(pattern) || pattern == "*" {
+		return true
+	}
+
+	matched, err := path.Match(pattern, value)
+	return err == nil && matched
+}
@@ -0,0 +1,86 @@
+package retention
+
+import (
+	"github.com/chenxull/goGridhub/gridhub/src/jobservice/common/query"
+	"github.com/chenxull/goGridhub/gridhub/src/jobservice/common/utils"
+	"github.com/chenxull/goGridhub/gridhub/src/jobservice/config"
+	"github.com/chenxull/goGridhub/gridhub/src/jobservice/job"
+	"github.com/pkg/errors"
+)
+
+// Sink keeps the archived job stats
+type Sink interface {
+	// Archive the stats of the expired jobs
+	Archive(records []*Record) error
+
+	// Get the archived job by the ID
+	Get(jobID string) (*Record, error)
+
+	// List the archived jobs matched with the query from the latest archived one.
+	// The job name and status in the extra parameters are used as the filters.
+	List(q *query.Parameter) ([]*Record, int64, error)
+
+	// Close the sink
+	Close() error
+}
+
+// Record is the archived job stats
+type Record struct {
+	// Unix timestamp of archiving the job
+	ArchivedAt int64      `json:"archived_at"`
+	Job        *job.Stats `json:"stats"`
+}
+
+// NewSink creates the sink with the configuration
+func NewSink(cfg *config.ArchiveConfig) (Sink, error) {
+	switch cfg.Sink {
+	case config.ArchiveSinkFile:
+		return NewFileSink(cfg.Path)
+	case config.ArchiveSinkDatabase:
+		return NewDatabaseSink(cfg.DSN)
+	default:
+		return nil, errors.Errorf("archive sink %s is not supported", cfg.Sink)
+	}
+}
+
+// filter keeps the conditions of listing the archived jobs
+type filter struct {
+	name   string
+	status string
+}
+
+func newFilter(q *query.Parameter) *filter {
+	f := &filter{}
+	if q == nil || q.Extras == nil {
+		return f
+	}
+
+	if v, ok := q.Extras.Get(query.ExtraParamKeyJobName); ok {
+		f.name, _ = v.(string)
+	}
+	if v, ok := q.Extras.Get(query.ExtraParamKeyStatus); ok {
+		f.status, _ = v.(string)
+	}
+
+	return f
+}
+
+func (f *filter) match(info *job.StatsInfo) bool {
+	return (utils.IsEmptyStr(f.name) || f.name == info.JobName) &&
+		(utils.IsEmptyStr(f.status) || f.status == info.Status)
+}
+
+// page returns the page number and size of the query
+func page(q *query.Parameter) (uint, uint) {
+	pageNumber, pageSize := uint(1), query.DefaultPageSize
+	if q != nil {
+		if q.PageNumber > 0 {
+			pageNumber = q.PageNumber
+		}
+		if q.PageSize > 0 {
+			pageSize = q.PageSize
+		}
+	}
+
+	return pageNumber, pageSize
+}
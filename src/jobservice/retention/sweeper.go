@@ -0,0 +1,182 @@
+package retention
+
+import (
+	"context"
+	"github.com/chenxull/goGridhub/gridhub/src/jobservice/common/rds"
+	"github.com/chenxull/goGridhub/gridhub/src/jobservice/common/utils"
+	"github.com/chenxull/goGridhub/gridhub/src/jobservice/env"
+	"github.com/chenxull/goGridhub/gridhub/src/jobservice/errs"
+	"github.com/chenxull/goGridhub/gridhub/src/jobservice/job"
+	"github.com/chenxull/goGridhub/gridhub/src/jobservice/lcm"
+	"github.com/chenxull/goGridhub/gridhub/src/jobservice/logger"
+	"github.com/gomodule/redigo/redis"
+	"sync"
+	"time"
+)
+
+const (
+	// DefaultSweepInterval is the default interval of sweeping the expired job stats
+	DefaultSweepInterval = 60 * time.Second
+	// Max jobs archived in one batch
+	sweepBatchSize = 100
+)
+
+// Sweeper archives the expired job stats to the sink and then removes them from redis.
+// The sweepers of all the nodes work together, each expired job is claimed by only one of them.
+type Sweeper struct {
+	context   context.Context
+	namespace string
+	pool      *redis.Pool
+	ctl       lcm.Controller
+	sink      Sink
+	interval  time.Duration
+	wg        *sync.WaitGroup
+}
+
+// NewSweeper is constructor of Sweeper
+func NewSweeper(ctx *env.Context, ns string, pool *redis.Pool, ctl lcm.Controller, sink Sink, interval time.Duration) *Sweeper {
+	if interval <= 0 {
+		interval = DefaultSweepInterval
+	}
+
+	return &Sweeper{
+		context:   ctx.SystemContext,
+		namespace: ns,
+		pool:      pool,
+		ctl:       ctl,
+		sink:      sink,
+		interval:  interval,
+		wg:        ctx.WG,
+	}
+}
+
+// Start the sweeping loop, non blocking call
+func (s *Sweeper) Start() {
+	s.wg.Add(1)
+	go func() {
+		defer func() {
+			if err := s.sink.Close(); err != nil {
+				logger.Errorf("close archive sink error: %s", err)
+			}
+			logger.Info("Retention sweeper is stopped")
+			s.wg.Done()
+		}()
+
+		ticker := time.NewTicker(s.interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C:
+				for {
+					n, err := s.sweep()
+					if err != nil {
+						logger.Errorf("sweep expired job stats error: %s", err)
+					}
+					// Continue if the batch is full
+					if err != nil || n < sweepBatchSize {
+						break
+					}
+				}
+			case <-s.context.Done():
+				return
+			}
+		}
+	}()
+
+	logger.Info("Retention sweeper is started")
+}
+
+// sweep one batch of the expired jobs and return the number of the claimed ones
+func (s *Sweeper) sweep() (int, error) {
+	conn := s.pool.Get()
+	defer func() {
+		_ = conn.Close()
+	}()
+
+	key := rds.KeyRetention(s.namespace)
+	now := time.Now().Unix()
+	ids, err := redis.Strings(conn.Do("ZRANGEBYSCORE", key, "-inf", now, "LIMIT", 0, sweepBatchSize))
+	if err != nil {
+		return 0, err
+	}
+
+	claimed := 0
+	records := make([]*Record, 0, len(ids))
+	for _, id := range ids {
+		// Claim the job, it may be claimed by other nodes
+		n, err := redis.Int(conn.Do("ZREM", key, id))
+		if err != nil {
+			return claimed, err
+		}
+		if n == 0 {
+			continue
+		}
+		claimed++
+
+		t, err := s.ctl.Track(id)
+		if err != nil {
+			if !errs.IsObjectNotFoundError(err) {
+				logger.Errorf("load expired job %s error: %s", id, err)
+				s.putBack(conn, id, now)
+			}
+			continue
+		}
+
+		// The job is retried, it's indexed again when it's finished
+		if !job.Status(t.Job().Info.Status).Final() {
+			continue
+		}
+
+		records = append(records, &Record{
+			ArchivedAt: now,
+			Job:        t.Job(),
+		})
+	}
+
+	if len(records) == 0 {
+		return claimed, nil
+	}
+
+	if err := s.sink.Archive(records); err != nil {
+		for _, r := range records {
+			s.putBack(conn, r.Job.Info.JobID, now)
+		}
+		return claimed, err
+	}
+
+	for _, r := range records {
+		if err := s.remove(conn, r.Job.Info); err != nil {
+			logger.Errorf("remove archived job %s error: %s", r.Job.Info.JobID, err)
+		}
+	}
+
+	logger.Debugf("%d expired jobs are archived", len(records))
+
+	return claimed, nil
+}
+
+// putBack indexes the job again to retry archiving it in the next round
+func (s *Sweeper) putBack(conn redis.Conn, jobID string, now int64) {
+	if _, err := conn.Do("ZADD", rds.KeyRetention(s.namespace), now+int64(s.interval.Seconds()), jobID); err != nil {
+		logger.Errorf("put back expired job %s error: %s", jobID, err)
+	}
+}
+
+// remove the stats of the archived job
+func (s *Sweeper) remove(conn redis.Conn, info *job.StatsInfo) error {
+	if err := conn.Send("MULTI"); err != nil {
+		return err
+	}
+	if err := conn.Send("DEL", rds.KeyJobStats(s.namespace, info.JobID)); err != nil {
+		return err
+	}
+	if !utils.IsEmptyStr(info.UpstreamJobID) {
+		if err := conn.Send("ZREM", rds.KeyUpstreamJobAndExecutions(s.namespace, info.UpstreamJobID), info.JobID); err != nil {
+			return err
+		}
+	}
+
+	_, err := conn.Do("EXEC")
+	return err
+}
@@ -145,8 +145,14 @@ func (rj *RedisJob) Run(j *work.Job) (err error) {
 	if err = tracker.Run(); err != nil {
 		return
 	}
+	// Decrypt the secret parameters only for running the job
+	params, err := job.OpenSecrets(j.Args)
+	if err != nil {
+		return
+	}
+
 	//Run the job
-	if err = runningJob.Run(execContext, j.Args); err != nil {
+	if err = runningJob.Run(execContext, params); err != nil {
 		return
 	}
 	//Handle retry
@@ -7,6 +7,11 @@ import (
 
 // Wrap returns a new job.Interface based on the wrapped job handler reference.
 func Wrap(j interface{}) job.Interface {
+	// The job carrying the settings creates the instance by itself
+	if f, ok := j.(job.Factory); ok {
+		return f.New()
+	}
+
 	theType := reflect.TypeOf(j)
 
 	if theType.Kind() == reflect.Ptr {
@@ -2,18 +2,30 @@ package runtime
 
 import (
 	"context"
-	"fmt"
+	"github.com/chenxull/goGridhub/gridhub/src/jobservice/admission"
 	"github.com/chenxull/goGridhub/gridhub/src/jobservice/api"
+	"github.com/chenxull/goGridhub/gridhub/src/jobservice/audit"
+	"github.com/chenxull/goGridhub/gridhub/src/jobservice/common/encrypt"
+	"github.com/chenxull/goGridhub/gridhub/src/jobservice/common/rds"
 	"github.com/chenxull/goGridhub/gridhub/src/jobservice/common/utils"
 	"github.com/chenxull/goGridhub/gridhub/src/jobservice/config"
 	"github.com/chenxull/goGridhub/gridhub/src/jobservice/core"
 	"github.com/chenxull/goGridhub/gridhub/src/jobservice/env"
 	"github.com/chenxull/goGridhub/gridhub/src/jobservice/hook"
+	"github.com/chenxull/goGridhub/gridhub/src/jobservice/idempotency"
+	"github.com/chenxull/goGridhub/gridhub/src/jobservice/inmem"
 	"github.com/chenxull/goGridhub/gridhub/src/jobservice/job"
+	"github.com/chenxull/goGridhub/gridhub/src/jobservice/job/plugin"
 	"github.com/chenxull/goGridhub/gridhub/src/jobservice/lcm"
 	"github.com/chenxull/goGridhub/gridhub/src/jobservice/logger"
 	"github.com/chenxull/goGridhub/gridhub/src/jobservice/mgt"
+	"github.com/chenxull/goGridhub/gridhub/src/jobservice/node"
+	"github.com/chenxull/goGridhub/gridhub/src/jobservice/period"
+	"github.com/chenxull/goGridhub/gridhub/src/jobservice/ratelimit"
+	"github.com/chenxull/goGridhub/gridhub/src/jobservice/retention"
 	"github.com/chenxull/goGridhub/gridhub/src/jobservice/worker"
+	"github.com/chenxull/goGridhub/gridhub/src/jobservice/worker/cworker"
+	"github.com/chenxull/goGridhub/gridhub/src/jobservice/worker/sworker"
 	"github.com/gomodule/redigo/redis"
 	"github.com/pkg/errors"
 	"os"
@@ -63,50 +75,94 @@ func (bs *Bootstrap) LoadAndRun(ctx context.Context, cancel context.CancelFunc)
 	// 通过这个引用，就可用访问其方法
 	cfg := config.DefaultConfig
 
+	// Key of encrypting the secret job parameters, the secret parameters are rejected without it
+	parametersKey := config.GetParametersKey()
+	if utils.IsEmptyStr(parametersKey) {
+		logger.Warning("Parameters key is not configured, the secret job parameters are not supported")
+	}
+	encrypt.SetKey(parametersKey)
+
 	var (
 		// worker 工作框架对象
 		backendWorker worker.Interface
 		// 获取 job 先关信息
 		manager mgt.Manager
+		// 集群节点信息
+		nodeRegistry node.Registry
+		// 提交 job 的限流器
+		limiter ratelimit.Limiter
+		// 提交 job 的准入控制
+		admitter admission.Controller
+		// 提交 job 的幂等键
+		idemStore idempotency.Store
+		// 归档 job 的存储
+		archive retention.Sink
+		// job 状态变更事件
+		eventLog hook.EventLog
+		// 周期 job 策略的导入导出
+		porter period.PolicyPorter
 	)
+
+	// How long the stats of the finished jobs are kept
+	if cfg.RetentionConfig != nil {
+		job.SetRetention(retention.NewPolicy(cfg.RetentionConfig))
+	}
+	// The jobs run by the external executables, they're registered before starting the worker
+	// as the worker pool (queue sampler or consumer groups) is built from the registered jobs.
+	pluginJobs := make(map[string]interface{}, len(cfg.PluginConfigs))
+	for _, pc := range cfg.PluginConfigs {
+		pluginJobs[pc.Name] = plugin.NewJob(pc)
+	}
+
+	// How long the idempotency keys are kept
+	idemWindow := idempotency.DefaultWindow
+	if cfg.IdempotencyConfig != nil && cfg.IdempotencyConfig.WindowSeconds > 0 {
+		idemWindow = time.Duration(cfg.IdempotencyConfig.WindowSeconds) * time.Second
+	}
+
 	// 启动redis
-	if cfg.PoolConfig.Backend == config.JobServicePoolBackendRedis {
+	if cfg.PoolConfig.IsRedisBackend() {
 		// Number of workers
 		workerNum := cfg.PoolConfig.WorkerCount
 
 		// Add {} to namespace to void slot issue
-		namespace := fmt.Sprintf("{%s}", cfg.PoolConfig.RedisPoolCfg.Namespace)
+		namespace := rds.HashTaggedNamespace(cfg.PoolConfig.RedisPoolCfg.Namespace)
 		// Get redis connection pool
-		redisPool := bs.getRedisPool(cfg.PoolConfig.RedisPoolCfg)
+		redisPool, er := bs.getRedisPool(cfg.PoolConfig.RedisPoolCfg, namespace)
+		if er != nil {
+			return errors.Errorf("create redis pool error: %s", er)
+		}
 
 		manager = mgt.NewManager(ctx, namespace, redisPool)
 		//todo create hook agent ,it's a singleton object
 
 		hookAgent := hook.NewAgent(rootContext, namespace, redisPool)
-		hookCallback := func(URL string, change *job.StatusChange) error {
-			msg := fmt.Sprintf("status change: job=%s, status=%s", change.JobID, change.Status)
-			if !utils.IsEmptyStr(change.CheckIn) {
-				msg = fmt.Sprintf("%s, check_in=%s", msg, change.CheckIn)
-			}
-			evt := &hook.Event{
-				URL:       URL,
-				Message:   msg,
-				Data:      change,
-				Timestamp: time.Now().Unix(),
-			}
-			return hookAgent.Trigger(evt)
-		}
+		// Keep the status change events for the event stream,
+		// the subscribers of the node share one reader of the events
+		eventLog = hook.NewFanoutEventLog(rootContext, hook.NewEventLog(namespace, redisPool))
 		// Create job life cycle management controller
-		lcmCtl := lcm.NewController(rootContext, namespace, redisPool, hookCallback)
+		lcmCtl := lcm.NewController(rootContext, namespace, redisPool, hook.NewCallback(hookAgent, eventLog))
 
 		// Start the backend worker
-		backendWorker, err = bs.loadAndRunRedisWorkerPool(
-			rootContext,
-			namespace,
-			workerNum,
-			redisPool,
-			lcmCtl,
-		)
+		if cfg.PoolConfig.Backend == config.JobServicePoolBackendRedisStreams {
+			backendWorker, err = bs.loadAndRunRedisStreamsWorkerPool(
+				rootContext,
+				namespace,
+				workerNum,
+				redisPool,
+				lcmCtl,
+				pluginJobs,
+			)
+		} else {
+			backendWorker, err = bs.loadAndRunRedisWorkerPool(
+				rootContext,
+				namespace,
+				workerNum,
+				redisPool,
+				lcmCtl,
+				pluginJobs,
+			)
+		}
 		if err != nil {
 			return errors.Errorf("load and run worker error: %s", err)
 
@@ -123,13 +179,64 @@ func (bs *Bootstrap) LoadAndRun(ctx context.Context, cancel context.CancelFunc)
 		if err = hookAgent.Serve(); err != nil {
 			return errors.Errorf("start hook agent error: %s", err)
 		}
+
+		// Register the current node to the cluster
+		nodeRegistry = node.NewRegistry(rootContext, namespace, redisPool, workerNum, lcmCtl)
+		if err = nodeRegistry.Serve(); err != nil {
+			return errors.Errorf("start node registry error: %s", err)
+		}
+
+		// Limit the rate of launching jobs, no limit if it's not configured.
+		// The limiter is always created to apply the rate limits reloaded at runtime.
+		limiter = ratelimit.NewLimiter(namespace, redisPool, cfg.RateLimitConfig)
+
+		// Admission control is enabled if it's configured
+		if cfg.AdmissionConfig != nil {
+			admitter = admission.NewController(namespace, redisPool, cfg.PoolConfig.Backend, cfg.AdmissionConfig)
+		}
+
+		idemStore = idempotency.NewStore(namespace, redisPool, idemWindow)
+
+		// Export and import the periodic job policies kept in redis
+		porter = period.NewPolicyPorter(ctx, namespace, redisPool)
+
+		// Archive the expired job stats if it's configured
+		if cfg.RetentionConfig != nil && cfg.RetentionConfig.Archive != nil {
+			if archive, err = retention.NewSink(cfg.RetentionConfig.Archive); err != nil {
+				return errors.Errorf("create archive sink error: %s", err)
+			}
+			interval := time.Duration(cfg.RetentionConfig.SweepIntervalSeconds) * time.Second
+			retention.NewSweeper(rootContext, namespace, redisPool, lcmCtl, archive, interval).Start()
+		}
+	} else if cfg.PoolConfig.Backend == config.JobServicePoolBackendMemory {
+		// Single node without redis, the admission control is not supported
+		backend := inmem.NewBackend(rootContext, cfg.PoolConfig.WorkerCount, cfg.RateLimitConfig, idemWindow)
+		if err = backend.Worker.RegisterJobs(pluginJobs); err != nil {
+			return errors.Errorf("register jobs error: %s", err)
+		}
+		if err = backend.Start(); err != nil {
+			return errors.Errorf("load and run memory backend error: %s", err)
+		}
+
+		backendWorker = backend.Worker
+		manager = backend.Manager
+		nodeRegistry = backend.Registry
+		eventLog = backend.EventLog
+		limiter = backend.Limiter
+		idemStore = backend.Idempotency
 	} else {
 		return errors.Errorf("worker backend '%s' is not supported", cfg.PoolConfig.Backend)
 	}
 
 	// Initialize controller
-	ctl := core.NewController(backendWorker, manager)
-	apiServer := bs.createAPIServer(ctx, cfg, ctl)
+	ctl := core.NewController(backendWorker, manager, nodeRegistry, admitter, idemStore, archive, eventLog, porter)
+	apiServer, grpcServer, err := bs.createAPIServer(ctx, cfg, ctl, limiter)
+	if err != nil {
+		return errors.Errorf("create API server error: %s", err)
+	}
+
+	// Apply the reloadable changes of the configuration at runtime
+	newReloader(rootContext, backendWorker, nodeRegistry, limiter).Start()
 
 	//Listen to the system signals
 	sig := make(chan os.Signal, 1)
@@ -141,6 +248,9 @@ func (bs *Bootstrap) LoadAndRun(ctx context.Context, cancel context.CancelFunc)
 			if er := apiServer.Stop(); er != nil {
 				logger.Error(er)
 			}
+			if grpcServer != nil {
+				grpcServer.Stop()
+			}
 			// Notify others who're listening to the system context
 			cancel()
 		}()
@@ -153,6 +263,15 @@ func (bs *Bootstrap) LoadAndRun(ctx context.Context, cancel context.CancelFunc)
 		}
 	}(rootContext.ErrorChan)
 	node := ctx.Value(utils.NodeID)
+	if grpcServer != nil {
+		go func() {
+			logger.Infof("gRPC server is serving at %d with [%s] mode at node [%s]", cfg.GRPCPort, cfg.Protocol, node)
+			if er := grpcServer.Start(); er != nil {
+				// Shut down the service as the API server does
+				rootContext.ErrorChan <- er
+			}
+		}()
+	}
 	logger.Infof("API server is serving at %d with [%s] mode at node [%s]", cfg.Port, cfg.Protocol, node)
 	if er := apiServer.Start(); er != nil {
 		if !terminated {
@@ -170,12 +289,30 @@ func (bs *Bootstrap) LoadAndRun(ctx context.Context, cancel context.CancelFunc)
 	return
 }
 
-//Load and run the API server
-func (bs *Bootstrap) createAPIServer(ctx context.Context, cfg *config.Configuration, ctl core.Interface) *api.Server {
+//Load and run the API server, the gRPC server sharing the authenticator and the audit sink
+//with it is also returned if the gRPC port is configured
+func (bs *Bootstrap) createAPIServer(ctx context.Context, cfg *config.Configuration, ctl core.Interface, limiter ratelimit.Limiter) (*api.Server, *api.GRPCServer, error) {
+	var authProvider api.Authenticator = &api.SecretAuthenticator{}
+	if cfg.AuthConfig != nil {
+		ca, err := api.NewCredentialAuthenticator(cfg.AuthConfig)
+		if err != nil {
+			return nil, nil, err
+		}
+		authProvider = ca
+	}
+
+	// Audit the job actions if it's configured
+	var auditor audit.Sink
+	if cfg.AuditConfig != nil {
+		sink, err := audit.NewSink(cfg.AuditConfig)
+		if err != nil {
+			return nil, nil, errors.Errorf("create audit sink error: %s", err)
+		}
+		auditor = sink
+	}
 
-	authProvider := &api.SecretAuthenticator{}
-	handler := api.NewDefaultHandler(ctl)
-	router := api.NewBaseRouter(handler, authProvider)
+	handler := api.NewDefaultHandler(ctl, auditor)
+	router := api.NewBaseRouter(handler, authProvider, limiter, ctl, auditor)
 	serverConfig := api.ServerConfig{
 		Protocol: cfg.Protocol,
 		Port:     cfg.Port,
@@ -184,7 +321,19 @@ func (bs *Bootstrap) createAPIServer(ctx context.Context, cfg *config.Configurat
 		serverConfig.Cert = cfg.HTTPSConfig.Cert
 		serverConfig.Key = cfg.HTTPSConfig.Key
 	}
-	return api.NewServer(ctx, router, serverConfig)
+
+	var grpcServer *api.GRPCServer
+	if cfg.GRPCPort != 0 {
+		grpcConfig := serverConfig
+		grpcConfig.Port = cfg.GRPCPort
+		gs, err := api.NewGRPCServer(ctl, authProvider, limiter, auditor, grpcConfig)
+		if err != nil {
+			return nil, nil, errors.Errorf("create gRPC server error: %s", err)
+		}
+		grpcServer = gs
+	}
+
+	return api.NewServer(ctx, router, serverConfig), grpcServer, nil
 }
 
 // Load and run the worker worker
@@ -194,24 +343,53 @@ func (bs *Bootstrap) loadAndRunRedisWorkerPool(
 	workers uint,
 	redisPool *redis.Pool,
 	lcmCtl lcm.Controller,
+	jobs map[string]interface{},
+) (worker.Interface, error) {
+	redisWorker := cworker.NewWorker(ctx, ns, workers, redisPool, lcmCtl)
+	// Register the jobs before starting
+	if err := redisWorker.RegisterJobs(jobs); err != nil {
+		return nil, errors.Errorf("register jobs error: %s", err)
+	}
+	if err := redisWorker.Start(); err != nil {
+		return nil, err
+	}
+
+	return redisWorker, nil
+}
+
+// Load and run the worker based on redis streams
+func (bs *Bootstrap) loadAndRunRedisStreamsWorkerPool(
+	ctx *env.Context,
+	ns string,
+	workers uint,
+	redisPool *redis.Pool,
+	lcmCtl lcm.Controller,
+	jobs map[string]interface{},
 ) (worker.Interface, error) {
+	streamsWorker := sworker.NewWorker(ctx, ns, workers, redisPool, lcmCtl)
+	// Register the jobs before starting
+	if err := streamsWorker.RegisterJobs(jobs); err != nil {
+		return nil, errors.Errorf("register jobs error: %s", err)
+	}
+	if err := streamsWorker.Start(); err != nil {
+		return nil, err
+	}
 
+	return streamsWorker, nil
 }
 
-// Get a redis connection pool
-func (bs *Bootstrap) getRedisPool(redisPoolConfig *config.RedisPoolConfig) *redis.Pool {
+// Get a redis connection pool of the standalone server, the sentinel master or the cluster node owning the namespace
+func (bs *Bootstrap) getRedisPool(redisPoolConfig *config.RedisPoolConfig, namespace string) (*redis.Pool, error) {
+	dialer, err := newRedisDialer(redisPoolConfig, namespace)
+	if err != nil {
+		return nil, err
+	}
+
 	return &redis.Pool{
 		MaxIdle:     6,
 		Wait:        true,
 		IdleTimeout: time.Duration(redisPoolConfig.IdleTimeoutSecond) * time.Second,
-		Dial: func() (redis.Conn, error) {
-			return redis.DialURL(
-				redisPoolConfig.RedisURL,
-				redis.DialConnectTimeout(dialConnectionTimeout),
-				redis.DialReadTimeout(dialReadTimeout),
-				redis.DialWriteTimeout(dialWriteTimeout),
-			)
-		},
+		Dial:        dialer.Dial,
 		TestOnBorrow: func(c redis.Conn, t time.Time) error {
 			if time.Since(t) < time.Minute {
 				return nil
@@ -220,5 +398,5 @@ func (bs *Bootstrap) getRedisPool(redisPoolConfig *config.RedisPoolConfig) *redi
 			_, err := c.Do("PING")
 			return err
 		},
-	}
+	}, nil
 }
@@ -0,0 +1,124 @@
+package runtime
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"github.com/chenxull/goGridhub/gridhub/src/jobservice/common/rds"
+	"github.com/chenxull/goGridhub/gridhub/src/jobservice/common/utils"
+	"github.com/chenxull/goGridhub/gridhub/src/jobservice/config"
+	"github.com/chenxull/goGridhub/gridhub/src/jobservice/period"
+	"github.com/pkg/errors"
+	"io"
+	"io/ioutil"
+	"os"
+)
+
+// Sub commands of the policies command
+const (
+	policiesCmdExport = "export"
+	policiesCmdImport = "import"
+)
+
+// RunPoliciesCommand exports or imports the periodic job policies kept in the redis of the configuration
+// without starting the job service:
+//
+//	export [-namespace ns] [-o file]
+//	import -f file [-namespace ns] [-dry-run]
+//
+// The namespace in the configuration is used if it's not specified. The export is written to the
+// output (stdout by default) and the report of the import is always written to the out.
+func (bs *Bootstrap) RunPoliciesCommand(ctx context.Context, cfg *config.Configuration, args []string, out io.Writer) error {
+	if cfg.PoolConfig == nil || !cfg.PoolConfig.IsRedisBackend() {
+		return errors.New("periodic job policies are only kept by the redis backend")
+	}
+	if len(args) == 0 {
+		return errors.Errorf("missing sub command, only support '%s' and '%s'", policiesCmdExport, policiesCmdImport)
+	}
+
+	flags := flag.NewFlagSet("policies "+args[0], flag.ContinueOnError)
+	namespace := flags.String("namespace", cfg.PoolConfig.RedisPoolCfg.Namespace, "Namespace of the policies")
+
+	switch args[0] {
+	case policiesCmdExport:
+		output := flags.String("o", "", "File the policies are exported to, stdout if it's not specified")
+		if err := flags.Parse(args[1:]); err != nil {
+			return err
+		}
+
+		porter, err := bs.newPolicyPorter(ctx, cfg, *namespace)
+		if err != nil {
+			return err
+		}
+		data, err := porter.Export()
+		if err != nil {
+			return errors.Wrap(err, "export periodic job policies")
+		}
+
+		if !utils.IsEmptyStr(*output) {
+			f, err := os.OpenFile(*output, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0600)
+			if err != nil {
+				return err
+			}
+			defer func() {
+				_ = f.Close()
+			}()
+			out = f
+		}
+
+		return writeIndentJSON(out, data)
+	case policiesCmdImport:
+		input := flags.String("f", "", "File of the exported policies")
+		dryRun := flags.Bool("dry-run", false, "Report the result without importing the policies")
+		if err := flags.Parse(args[1:]); err != nil {
+			return err
+		}
+		if utils.IsEmptyStr(*input) {
+			return errors.New("missing the file of the exported policies")
+		}
+
+		raw, err := ioutil.ReadFile(*input)
+		if err != nil {
+			return err
+		}
+		data := &period.PolicyExport{}
+		if err := json.Unmarshal(raw, data); err != nil {
+			return errors.Wrapf(err, "malformed policy export %s", *input)
+		}
+
+		porter, err := bs.newPolicyPorter(ctx, cfg, *namespace)
+		if err != nil {
+			return err
+		}
+		report, err := porter.Import(data, *dryRun)
+		if err != nil {
+			return errors.Wrap(err, "import periodic job policies")
+		}
+
+		return writeIndentJSON(out, report)
+	default:
+		return errors.Errorf("unknown sub command '%s', only support '%s' and '%s'", args[0], policiesCmdExport, policiesCmdImport)
+	}
+}
+
+// newPolicyPorter creates the porter of the policies in the namespace
+func (bs *Bootstrap) newPolicyPorter(ctx context.Context, cfg *config.Configuration, ns string) (period.PolicyPorter, error) {
+	// Add {} to namespace to void slot issue
+	namespace := rds.HashTaggedNamespace(ns)
+	redisPool, err := bs.getRedisPool(cfg.PoolConfig.RedisPoolCfg, namespace)
+	if err != nil {
+		return nil, errors.Errorf("create redis pool error: %s", err)
+	}
+
+	return period.NewPolicyPorter(ctx, namespace, redisPool), nil
+}
+
+func writeIndentJSON(w io.Writer, object interface{}) error {
+	data, err := json.MarshalIndent(object, "", "  ")
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(append(data, '\n'))
+
+	return err
+}
@@ -0,0 +1,342 @@
+package runtime
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"github.com/chenxull/goGridhub/gridhub/src/jobservice/common/rds"
+	"github.com/chenxull/goGridhub/gridhub/src/jobservice/common/utils"
+	"github.com/chenxull/goGridhub/gridhub/src/jobservice/config"
+	"github.com/gomodule/redigo/redis"
+	"github.com/pkg/errors"
+	"io/ioutil"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// Default port of the redis server without the port in the URL
+const defaultRedisPort = "6379"
+
+// redisDialer dials the redis server with the redis URL, the master discovered by the sentinels
+// or the node of the redis cluster owning the slot of the namespace.
+// The master or the node is discovered again on each dialing, so the new connections follow the
+// failover of the sentinels and the slot migration of the cluster.
+type redisDialer struct {
+	cfg *config.RedisPoolConfig
+	// Slot of the hash tagged namespace in the redis cluster
+	slot uint16
+	// Timeouts and TLS settings
+	options []redis.DialOption
+}
+
+// newRedisDialer is constructor of redisDialer
+func newRedisDialer(cfg *config.RedisPoolConfig, namespace string) (*redisDialer, error) {
+	d := &redisDialer{
+		cfg:  cfg,
+		slot: rds.KeySlot(namespace),
+		options: []redis.DialOption{
+			redis.DialConnectTimeout(dialConnectionTimeout),
+			redis.DialReadTimeout(dialReadTimeout),
+			redis.DialWriteTimeout(dialWriteTimeout),
+		},
+	}
+
+	if cfg.TLS != nil {
+		tlsConfig, err := redisTLSConfig(cfg.TLS)
+		if err != nil {
+			return nil, err
+		}
+		d.options = append(d.options,
+			redis.DialUseTLS(true),
+			redis.DialTLSConfig(tlsConfig),
+			redis.DialTLSSkipVerify(cfg.TLS.InsecureSkipVerify),
+		)
+	}
+
+	return d, nil
+}
+
+// Dial a new connection, it's used as the dial function of the redis pool
+func (d *redisDialer) Dial() (redis.Conn, error) {
+	switch {
+	case d.cfg.Sentinel != nil:
+		return d.dialSentinelMaster()
+	case d.cfg.Cluster != nil:
+		return d.dialClusterNode()
+	default:
+		return d.dialURL()
+	}
+}
+
+func (d *redisDialer) dialURL() (redis.Conn, error) {
+	u, err := url.Parse(d.cfg.RedisURL)
+	if err != nil {
+		return nil, err
+	}
+
+	options := d.options
+	if u.Scheme == "rediss" && d.cfg.TLS == nil {
+		options = append(options[:len(options):len(options)], redis.DialUseTLS(true))
+	}
+
+	// The credentials in the URL are preferred
+	username, password := d.cfg.Username, d.cfg.Password
+	if u.User != nil {
+		if p, ok := u.User.Password(); ok {
+			username, password = u.User.Username(), p
+		}
+	}
+
+	db := 0
+	if p := strings.TrimPrefix(u.Path, "/"); len(p) > 0 {
+		if db, err = strconv.Atoi(p); err != nil {
+			return nil, fmt.Errorf("invalid database in redis URL: %s", p)
+		}
+	}
+
+	addr := u.Host
+	if _, _, err := net.SplitHostPort(addr); err != nil {
+		addr = net.JoinHostPort(addr, defaultRedisPort)
+	}
+
+	conn, err := redis.Dial("tcp", addr, options...)
+	if err != nil {
+		return nil, err
+	}
+
+	return setupRedisConn(conn, username, password, db)
+}
+
+// dialSentinelMaster dials the master reported by the first available sentinel
+func (d *redisDialer) dialSentinelMaster() (redis.Conn, error) {
+	sc := d.cfg.Sentinel
+
+	var lastErr error
+	for _, sentinel := range sc.Addrs {
+		addr, err := d.masterAddr(sentinel)
+		if err != nil {
+			lastErr = err
+			continue
+		}
+
+		conn, err := redis.Dial("tcp", addr, d.options...)
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		if conn, err = setupRedisConn(conn, d.cfg.Username, d.cfg.Password, sc.DB); err != nil {
+			lastErr = err
+			continue
+		}
+
+		// The reported master may be demoted by an ongoing failover
+		role, err := redis.Values(conn.Do("ROLE"))
+		if err == nil && len(role) > 0 {
+			if r, _ := redis.String(role[0], nil); r == "master" {
+				return conn, nil
+			}
+		}
+		_ = conn.Close()
+		lastErr = fmt.Errorf("redis %s reported by sentinel %s is not master", addr, sentinel)
+	}
+
+	return nil, errors.Wrapf(lastErr, "dial master %s of redis sentinel", sc.MasterName)
+}
+
+// masterAddr asks the sentinel for the address of the master
+func (d *redisDialer) masterAddr(sentinel string) (string, error) {
+	sc := d.cfg.Sentinel
+
+	conn, err := redis.Dial("tcp", sentinel, d.options...)
+	if err != nil {
+		return "", err
+	}
+	if conn, err = setupRedisConn(conn, sc.Username, sc.Password, 0); err != nil {
+		return "", err
+	}
+	defer func() {
+		_ = conn.Close()
+	}()
+
+	values, err := redis.Strings(conn.Do("SENTINEL", "get-master-addr-by-name", sc.MasterName))
+	if err != nil {
+		if err == redis.ErrNil {
+			return "", fmt.Errorf("master %s is unknown to sentinel %s", sc.MasterName, sentinel)
+		}
+		return "", err
+	}
+	if len(values) != 2 {
+		return "", fmt.Errorf("malformed master address from sentinel %s: %v", sentinel, values)
+	}
+
+	return net.JoinHostPort(values[0], values[1]), nil
+}
+
+// dialClusterNode dials the master node owning the slot of the namespace
+func (d *redisDialer) dialClusterNode() (redis.Conn, error) {
+	var lastErr error
+	for _, seed := range d.cfg.Cluster.Addrs {
+		addr, err := d.slotOwner(seed)
+		if err != nil {
+			lastErr = err
+			continue
+		}
+
+		conn, err := redis.Dial("tcp", addr, d.options...)
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		if conn, err = setupRedisConn(conn, d.cfg.Username, d.cfg.Password, 0); err != nil {
+			lastErr = err
+			continue
+		}
+
+		return &clusterConn{Conn: conn}, nil
+	}
+
+	return nil, errors.Wrapf(lastErr, "dial node of redis cluster owning slot %d", d.slot)
+}
+
+// slotOwner asks the seed node for the address of the master owning the slot of the namespace
+func (d *redisDialer) slotOwner(seed string) (string, error) {
+	conn, err := redis.Dial("tcp", seed, d.options...)
+	if err != nil {
+		return "", err
+	}
+	if conn, err = setupRedisConn(conn, d.cfg.Username, d.cfg.Password, 0); err != nil {
+		return "", err
+	}
+	defer func() {
+		_ = conn.Close()
+	}()
+
+	ranges, err := redis.Values(conn.Do("CLUSTER", "SLOTS"))
+	if err != nil {
+		return "", err
+	}
+
+	// Each range is in the form of [start, end, [master host, port, ...], [replica host, port, ...]...]
+	for _, r := range ranges {
+		info, err := redis.Values(r, nil)
+		if err != nil || len(info) < 3 {
+			continue
+		}
+		start, _ := redis.Int64(info[0], nil)
+		end, _ := redis.Int64(info[1], nil)
+		if int64(d.slot) < start || int64(d.slot) > end {
+			continue
+		}
+
+		master, err := redis.Values(info[2], nil)
+		if err != nil || len(master) < 2 {
+			return "", fmt.Errorf("malformed cluster slots from node %s", seed)
+		}
+		host, _ := redis.String(master[0], nil)
+		port, _ := redis.Int64(master[1], nil)
+		if utils.IsEmptyStr(host) {
+			// The node may report the empty host for itself
+			host, _, _ = net.SplitHostPort(seed)
+		}
+
+		return net.JoinHostPort(host, strconv.FormatInt(port, 10)), nil
+	}
+
+	return "", fmt.Errorf("slot %d is not served by redis cluster", d.slot)
+}
+
+// setupRedisConn authenticates the connection and selects the database, the connection is closed if it fails.
+// The username is sent for the ACL users of redis 6.
+func setupRedisConn(conn redis.Conn, username string, password string, db int) (redis.Conn, error) {
+	if len(password) > 0 {
+		args := []interface{}{password}
+		if len(username) > 0 {
+			args = []interface{}{username, password}
+		}
+		if _, err := conn.Do("AUTH", args...); err != nil {
+			_ = conn.Close()
+			return nil, errors.Wrap(err, "redis auth")
+		}
+	}
+
+	if db > 0 {
+		if _, err := conn.Do("SELECT", db); err != nil {
+			_ = conn.Close()
+			return nil, errors.Wrap(err, "redis select database")
+		}
+	}
+
+	return conn, nil
+}
+
+func redisTLSConfig(tc *config.RedisTLSConfig) (*tls.Config, error) {
+	tlsConfig := &tls.Config{
+		ServerName: tc.ServerName,
+	}
+
+	if !utils.IsEmptyStr(tc.CAFile) {
+		pem, err := ioutil.ReadFile(tc.CAFile)
+		if err != nil {
+			return nil, errors.Wrap(err, "read CA file of redis TLS")
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, errors.New("no certificate in CA file of redis TLS")
+		}
+		tlsConfig.RootCAs = pool
+	}
+
+	if !utils.IsEmptyStr(tc.CertFile) {
+		cert, err := tls.LoadX509KeyPair(tc.CertFile, tc.KeyFile)
+		if err != nil {
+			return nil, errors.Wrap(err, "load certificate of redis TLS")
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+
+	return tlsConfig, nil
+}
+
+// clusterConn is the connection to the node of the redis cluster.
+// It's discarded by the pool once the slot is moved to another node or the cluster is down,
+// then the new connection is dialed to the current owner of the slot.
+type clusterConn struct {
+	redis.Conn
+	err error
+}
+
+// Do is implementation of redis.Conn.Do
+func (c *clusterConn) Do(commandName string, args ...interface{}) (interface{}, error) {
+	reply, err := c.Conn.Do(commandName, args...)
+	c.check(err)
+
+	return reply, err
+}
+
+// Receive is implementation of redis.Conn.Receive
+func (c *clusterConn) Receive() (interface{}, error) {
+	reply, err := c.Conn.Receive()
+	c.check(err)
+
+	return reply, err
+}
+
+// Err is implementation of redis.Conn.Err
+func (c *clusterConn) Err() error {
+	if c.err != nil {
+		return c.err
+	}
+
+	return c.Conn.Err()
+}
+
+func (c *clusterConn) check(err error) {
+	if e, ok := err.(redis.Error); ok {
+		msg := string(e)
+		if strings.HasPrefix(msg, "MOVED ") || strings.HasPrefix(msg, "ASK ") || strings.HasPrefix(msg, "CLUSTERDOWN") {
+			c.err = e
+		}
+	}
+}
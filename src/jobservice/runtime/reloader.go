@@ -0,0 +1,171 @@
+package runtime
+
+import (
+	"github.com/chenxull/goGridhub/gridhub/src/jobservice/config"
+	"github.com/chenxull/goGridhub/gridhub/src/jobservice/env"
+	"github.com/chenxull/goGridhub/gridhub/src/jobservice/logger"
+	"github.com/chenxull/goGridhub/gridhub/src/jobservice/node"
+	"github.com/chenxull/goGridhub/gridhub/src/jobservice/ratelimit"
+	"github.com/chenxull/goGridhub/gridhub/src/jobservice/worker"
+	"github.com/pkg/errors"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+)
+
+// How often the configuration file is checked for changes
+const configCheckInterval = 10 * time.Second
+
+// ReloadReport tells which changed sections of the reloaded configuration are applied
+// and which ones require restart
+type ReloadReport struct {
+	Applied         []string
+	RestartRequired []string
+}
+
+// reloader reloads the configuration once the yaml file is modified or the SIGHUP is received,
+// and applies the sections which can be changed at runtime: the loggers, the worker
+// concurrency and the rate limits. The other changes take effect after restart.
+// The worker concurrency is only applied to the worker implementing worker.Resizer, the
+// worker pool of the redis backend has the fixed concurrency and requires restart.
+// The applied sections are kept in a copy of the current configuration which replaces
+// the current one as a whole, the current one is never changed in place.
+type reloader struct {
+	context  *env.Context
+	worker   worker.Interface
+	registry node.Registry
+	limiter  ratelimit.Limiter
+	modTime  time.Time
+}
+
+// newReloader is constructor of reloader, the limiter is nil if the rate limiting is not supported
+func newReloader(ctx *env.Context, w worker.Interface, registry node.Registry, limiter ratelimit.Limiter) *reloader {
+	return &reloader{
+		context:  ctx,
+		worker:   w,
+		registry: registry,
+		limiter:  limiter,
+	}
+}
+
+// Start to watch the configuration file and the SIGHUP signal
+// Non blocking call
+func (r *reloader) Start() {
+	r.modTime, _ = r.fileModTime()
+
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+
+	r.context.WG.Add(1)
+	go func() {
+		ticker := time.NewTicker(configCheckInterval)
+		defer func() {
+			ticker.Stop()
+			signal.Stop(hup)
+			r.context.WG.Done()
+		}()
+
+		for {
+			select {
+			case <-hup:
+				logger.Info("SIGHUP is received, reload the configuration")
+				_, _ = r.reload()
+			case <-ticker.C:
+				modTime, err := r.fileModTime()
+				if err != nil || modTime.Equal(r.modTime) {
+					continue
+				}
+				r.modTime = modTime
+				logger.Infof("Configuration file %s is modified, reload it", config.Current().FilePath())
+				_, _ = r.reload()
+			case <-r.context.SystemContext.Done():
+				return
+			}
+		}
+	}()
+
+	logger.Info("Configuration reloader is started")
+}
+
+// reload the configuration and apply the changes, the current configuration is kept if the new one is invalid
+func (r *reloader) reload() (*ReloadReport, error) {
+	cfg := config.Current()
+	nc, err := cfg.Reload()
+	if err != nil {
+		logger.Errorf("reload configuration error: %s, the current configuration is kept", err)
+		return nil, err
+	}
+
+	report := &ReloadReport{
+		Applied:         make([]string, 0),
+		RestartRequired: make([]string, 0),
+	}
+	// The sections applied to the running components are set to the next configuration
+	next := *cfg
+	for _, section := range cfg.Changes(nc) {
+		applied, err := r.apply(&next, nc, section)
+		if err != nil {
+			logger.Errorf("apply the reloaded configuration %s error: %s", section, err)
+		}
+		if applied {
+			report.Applied = append(report.Applied, section)
+		} else {
+			report.RestartRequired = append(report.RestartRequired, section)
+		}
+	}
+	config.SetCurrent(&next)
+
+	logger.Infof("Configuration is reloaded, applied: %v, requires restart: %v", report.Applied, report.RestartRequired)
+
+	return report, nil
+}
+
+// apply the section of the new configuration to the running components and the next configuration.
+// It returns false if the section can not be changed at runtime.
+func (r *reloader) apply(next *config.Configuration, nc *config.Configuration, section string) (bool, error) {
+	switch section {
+	case config.SectionLoggers:
+		if err := logger.Reload(nc.LoggerConfigs); err != nil {
+			return false, err
+		}
+		next.LoggerConfigs = nc.LoggerConfigs
+	case config.SectionWorkerCount:
+		if next.PoolConfig == nil || nc.PoolConfig == nil {
+			return false, nil
+		}
+		resizer, ok := r.worker.(worker.Resizer)
+		if !ok {
+			logger.Warningf("Worker of the %s backend can not be resized at runtime, restart to apply %s", next.PoolConfig.Backend, section)
+			return false, nil
+		}
+		count := nc.PoolConfig.WorkerCount
+		if err := resizer.Resize(count); err != nil {
+			return false, errors.Wrap(err, "resize worker")
+		}
+		r.registry.SetConcurrency(count)
+		// Copy the pool config shared with the current configuration
+		pool := *next.PoolConfig
+		pool.WorkerCount = count
+		next.PoolConfig = &pool
+	case config.SectionRateLimit:
+		if r.limiter == nil {
+			return false, nil
+		}
+		r.limiter.Reload(nc.RateLimitConfig)
+		next.RateLimitConfig = nc.RateLimitConfig
+	default:
+		return false, nil
+	}
+
+	return true, nil
+}
+
+func (r *reloader) fileModTime() (time.Time, error) {
+	info, err := os.Stat(config.Current().FilePath())
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	return info.ModTime(), nil
+}
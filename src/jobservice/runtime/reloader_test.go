@@ -0,0 +1,79 @@
+package runtime
+
+import (
+	"context"
+	"fmt"
+	"github.com/chenxull/goGridhub/gridhub/src/jobservice/config"
+	"github.com/chenxull/goGridhub/gridhub/src/jobservice/env"
+	"github.com/chenxull/goGridhub/gridhub/src/jobservice/inmem"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+)
+
+const testConfig = `
+protocol: "http"
+port: 9000
+grpc_port: %s
+worker_pool:
+  workers: %s
+  backend: "memory"
+loggers:
+  - name: "STD_OUTPUT"
+    level: "INFO"
+job_loggers:
+  - name: "STD_OUTPUT"
+    level: "INFO"
+`
+
+func writeTestConfig(t *testing.T, path string, grpcPort, workers string) {
+	t.Helper()
+
+	data := []byte(fmt.Sprintf(testConfig, grpcPort, workers))
+	if err := os.WriteFile(path, data, 0600); err != nil {
+		t.Fatalf("write configuration error: %s", err)
+	}
+}
+
+func TestReloadRestartRequired(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yml")
+	writeTestConfig(t, path, "9090", "1")
+
+	cfg := &config.Configuration{}
+	if err := cfg.Load(path, false); err != nil {
+		t.Fatalf("load configuration error: %s", err)
+	}
+	config.SetCurrent(cfg)
+	t.Cleanup(func() {
+		config.SetCurrent(config.DefaultConfig)
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	b := inmem.NewBackend(&env.Context{
+		SystemContext: ctx,
+		WG:            &sync.WaitGroup{},
+		ErrorChan:     make(chan error, 1),
+	}, 1, nil, time.Hour)
+
+	writeTestConfig(t, path, "9091", "2")
+	report, err := newReloader(nil, b.Worker, b.Registry, b.Limiter).reload()
+	if err != nil {
+		t.Fatalf("reload configuration error: %s", err)
+	}
+
+	if !reflect.DeepEqual(report.Applied, []string{config.SectionWorkerCount}) {
+		t.Errorf("expect the worker count applied but got %v", report.Applied)
+	}
+	if !reflect.DeepEqual(report.RestartRequired, []string{config.SectionGRPCPort}) {
+		t.Errorf("expect the gRPC port requires restart but got %v", report.RestartRequired)
+	}
+
+	// The gRPC server keeps listening on the old port until restart
+	if current := config.Current(); current.GRPCPort != 9090 || current.PoolConfig.WorkerCount != 2 {
+		t.Errorf("expect gRPC port 9090 and 2 workers in effect but got %d and %d", current.GRPCPort, current.PoolConfig.WorkerCount)
+	}
+}
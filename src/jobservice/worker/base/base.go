@@ -0,0 +1,271 @@
+// Package base provides the helpers shared by the worker backends, e.g: registering the jobs,
+// building the job stats and putting the failed jobs back to the queue.
+package base
+
+import (
+	"fmt"
+	"github.com/chenxull/goGridhub/gridhub/src/jobservice/common/utils"
+	"github.com/chenxull/goGridhub/gridhub/src/jobservice/env"
+	"github.com/chenxull/goGridhub/gridhub/src/jobservice/errs"
+	"github.com/chenxull/goGridhub/gridhub/src/jobservice/job"
+	"github.com/chenxull/goGridhub/gridhub/src/jobservice/lcm"
+	"github.com/chenxull/goGridhub/gridhub/src/jobservice/logger"
+	"github.com/chenxull/goGridhub/gridhub/src/jobservice/period"
+	"github.com/chenxull/goGridhub/gridhub/src/jobservice/runner"
+	"github.com/gocraft/work"
+	"github.com/gomodule/redigo/redis"
+	"github.com/pkg/errors"
+	"math"
+	"math/rand"
+	"reflect"
+	"sync"
+	"time"
+)
+
+const (
+	// DefaultMaxFails is same as the default max fails of gocraft/work
+	DefaultMaxFails uint = 4
+	// Max times of pinging the redis server before starting the worker
+	pingRedisMaxTimes = 10
+)
+
+// RegisteredJob keeps the runner and the retry options of the registered job
+type RegisteredJob struct {
+	Runner      *runner.RedisJob
+	MaxFails    uint
+	ShouldRetry bool
+}
+
+// NewRegisteredJob wraps the job with the runner, the default max fails is used if the job does not set it
+func NewRegisteredJob(j interface{}, ctx *env.Context, ctl lcm.Controller) *RegisteredJob {
+	theJ := runner.Wrap(j)
+	maxFails := theJ.MaxFalis()
+	if maxFails == 0 {
+		maxFails = DefaultMaxFails
+	}
+
+	return &RegisteredJob{
+		Runner:      runner.NewRedisJob(j, ctx, ctl),
+		MaxFails:    maxFails,
+		ShouldRetry: theJ.ShouldRetry(),
+	}
+}
+
+// Fail records the failure of the run on the job and returns the time to retry it.
+// False is returned if the job is dead and should not be retried.
+func (rj *RegisteredJob) Fail(j *work.Job, err error) (int64, bool) {
+	now := time.Now().Unix()
+	j.Fails++
+	j.FailedAt = now
+	j.LastErr = err.Error()
+
+	if !rj.ShouldRetry || uint(j.Fails) >= rj.MaxFails {
+		logger.Warningf("Job %s:%s is dead after %d failures", j.Name, j.ID, j.Fails)
+		return 0, false
+	}
+
+	return now + Backoff(j.Fails), true
+}
+
+// CheckRegistration checks if the job can be registered with the name among the known jobs.
+// The name is 1:1 mapped to the job implementation except the job carrying its settings, e.g: the plugin jobs.
+func CheckRegistration(knownJobs *sync.Map, name string, j interface{}) (err error) {
+	if utils.IsEmptyStr(name) || j == nil {
+		return errors.New("job can not be registered with empty name or nil interface")
+	}
+
+	//j must be job.Interface
+	if _, ok := j.(job.Interface); !ok {
+		return errors.Errorf("job must implement the job.Interface :%s", reflect.TypeOf(j).String())
+	}
+
+	//1:1 constraint
+	if jInList, ok := knownJobs.Load(name); ok {
+		return fmt.Errorf("job name %s has been already registered with %s", name, reflect.TypeOf(jInList).String())
+	}
+
+	//Same job implementation can be only registered with on name
+	//except the job carrying its settings, e.g: the plugin jobs
+	knownJobs.Range(func(jName interface{}, jInList interface{}) bool {
+		if _, ok := j.(job.Factory); ok {
+			return false
+		}
+		jobImpl := reflect.TypeOf(j).String()
+		if reflect.TypeOf(jInList).String() == jobImpl {
+			err = errors.Errorf("job %s has been already registered with name %s", jobImpl, jName)
+			return false
+		}
+		return true
+	})
+
+	return
+}
+
+// KnownJobs returns the known jobs keyed by the job names
+func KnownJobs(knownJobs *sync.Map) map[string]interface{} {
+	jobs := make(map[string]interface{})
+	knownJobs.Range(func(name interface{}, j interface{}) bool {
+		jobs[name.(string)] = j
+		return true
+	})
+
+	return jobs
+}
+
+// ValidateJobParameters validates the parameters of the known job
+func ValidateJobParameters(jobType interface{}, params job.Parameters) error {
+	if jobType == nil {
+		return errors.New("nil job type")
+	}
+
+	theJ := runner.Wrap(jobType)
+	return theJ.Validate(params)
+}
+
+// NewJob builds the job with a new ID
+func NewJob(jobName string, params job.Parameters) *work.Job {
+	args := make(map[string]interface{}, len(params))
+	for k, v := range params {
+		args[k] = v
+	}
+
+	return &work.Job{
+		Name:       jobName,
+		ID:         utils.MakeIdentifier(),
+		EnqueuedAt: time.Now().Unix(),
+		Args:       args,
+	}
+}
+
+// Backoff returns the seconds to wait before the next retry, same as gocraft/work
+func Backoff(fails int64) int64 {
+	return int64(math.Pow(float64(fails), 4)) + 15 + (rand.Int63n(30) * (fails + 1))
+}
+
+// GenerateResult builds the stats of the enqueued job
+func GenerateResult(
+	j *work.Job,
+	jobKind string,
+	isUnique bool,
+	jobParameters job.Parameters,
+	webHook string,
+) *job.Stats {
+	return &job.Stats{
+		Info: &job.StatsInfo{
+			JobID:       j.ID,
+			JobName:     j.Name,
+			JobKind:     jobKind,
+			IsUnique:    isUnique,
+			Status:      job.PendingStatus.String(),
+			EnqueueTime: j.EnqueuedAt,
+			UpdateTime:  time.Now().Unix(),
+			RefLink:     fmt.Sprintf("/api/v1/jobs/%s", j.ID),
+			Parameters:  jobParameters,
+			WebHookURL:  webHook,
+		},
+	}
+}
+
+// Ping the redis server until it's connected or timeout
+func Ping(pool *redis.Pool) error {
+	conn := pool.Get()
+	defer func() {
+		_ = conn.Close()
+	}()
+
+	var err error
+	for count := 1; count <= pingRedisMaxTimes; count++ {
+		if _, err = conn.Do("ping"); err == nil {
+			return nil
+		}
+		time.Sleep(time.Duration(count+4) * time.Second)
+	}
+	return fmt.Errorf("connect to redis server timeout: %s", err.Error())
+}
+
+// SignBinder binds or releases the reserved unique sign of the job
+type SignBinder interface {
+	// Bind the enqueued job to the reserved sign
+	Bind(sign string, jobID string, opts *job.UniqueOptions) error
+
+	// DelUniqueSign releases the reserved sign
+	DelUniqueSign(sign string) error
+}
+
+// BindUniqueSign binds the enqueued job to the reserved unique sign, or releases the sign if enqueuing failed.
+// It's deferred by the enqueuing with the pointer to the result.
+func BindUniqueSign(binder SignBinder, sign string, unique *job.UniqueOptions, res **job.Stats) {
+	if *res == nil {
+		if err := binder.DelUniqueSign(sign); err != nil {
+			logger.Errorf("release unique sign %s error: %s", sign, err)
+		}
+		return
+	}
+
+	if err := binder.Bind(sign, (*res).Info.JobID, unique); err != nil {
+		// The job is enqueued, only log the error
+		logger.Errorf("bind unique sign %s to job %s error: %s", sign, (*res).Info.JobID, err)
+	}
+}
+
+// RetryJob puts the failed job back to the queue with the same job ID by the push func
+func RetryJob(ctl lcm.Controller, jobID string, push func(j *work.Job) error) error {
+	return putBack(ctl, jobID, func(status job.Status) bool {
+		return status == job.ErrorStatus
+	}, push)
+}
+
+// RequeueJob puts the orphaned job of the dead node back to the queue with the same job ID by the push func.
+// The job is not marked as failed before, so no error hook event is fired.
+func RequeueJob(ctl lcm.Controller, jobID string, push func(j *work.Job) error) error {
+	return putBack(ctl, jobID, func(status job.Status) bool {
+		return !status.Final()
+	}, push)
+}
+
+// putBack resets the job to pending and pushes it back to the queue if its status is accepted
+func putBack(ctl lcm.Controller, jobID string, accept func(status job.Status) bool, push func(j *work.Job) error) error {
+	if utils.IsEmptyStr(jobID) {
+		return errors.New("empty job ID to put back")
+	}
+
+	t, err := ctl.Track(jobID)
+	if err != nil {
+		return err
+	}
+
+	info := t.Job().Info
+	if !accept(job.Status(info.Status)) {
+		return errs.StatusMismatchError(info.Status, job.PendingStatus.String())
+	}
+
+	if info.JobKind == job.KindPeriodic {
+		return errs.BadRequestError(errors.Errorf("periodic job %s can not be put back to the queue", jobID))
+	}
+
+	// Reset the status to pending before pushing to avoid the job being picked up with the old status
+	if err := t.Reset(); err != nil {
+		return err
+	}
+
+	if err := push(rebuildJob(info)); err != nil {
+		return err
+	}
+
+	logger.Infof("Job %s:%s is put back to the queue", info.JobName, jobID)
+
+	return nil
+}
+
+// rebuildJob rebuilds the job in the queue with the same ID
+func rebuildJob(info *job.StatsInfo) *work.Job {
+	j := NewJob(info.JobName, info.Parameters)
+	j.ID = info.JobID
+	// The job ID used in the queue is the ID of the periodic job (policy) for the execution
+	if !utils.IsEmptyStr(info.UpstreamJobID) {
+		j.ID = info.UpstreamJobID
+		j.Args[period.PeriodicExecutionMark] = fmt.Sprintf("%d", info.RunAt)
+	}
+
+	return j
+}
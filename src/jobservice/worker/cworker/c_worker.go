@@ -2,14 +2,17 @@ package cworker
 
 import (
 	"fmt"
+	"github.com/chenxull/goGridhub/gridhub/src/jobservice/common/rds"
 	"github.com/chenxull/goGridhub/gridhub/src/jobservice/common/utils"
 	"github.com/chenxull/goGridhub/gridhub/src/jobservice/env"
+	"github.com/chenxull/goGridhub/gridhub/src/jobservice/errs"
 	"github.com/chenxull/goGridhub/gridhub/src/jobservice/job"
 	"github.com/chenxull/goGridhub/gridhub/src/jobservice/lcm"
 	"github.com/chenxull/goGridhub/gridhub/src/jobservice/logger"
 	"github.com/chenxull/goGridhub/gridhub/src/jobservice/period"
 	"github.com/chenxull/goGridhub/gridhub/src/jobservice/runner"
 	"github.com/chenxull/goGridhub/gridhub/src/jobservice/worker"
+	"github.com/chenxull/goGridhub/gridhub/src/jobservice/worker/base"
 	"github.com/gocraft/work"
 	"github.com/gomodule/redigo/redis"
 	"github.com/pkg/errors"
@@ -25,7 +28,6 @@ var (
 const (
 	workerPoolStatusHealthy      = "Healthy"
 	workerPoolStatusDead         = "Dead"
-	pingRedisMaxTimes            = 10
 	defaultWorkerCount      uint = 10
 )
 
@@ -38,8 +40,9 @@ type basicWorker struct {
 	client    *work.Client
 	context   *env.Context
 
-	scheduler period.Scheduler
-	ctl       lcm.Controller
+	scheduler    period.Scheduler
+	ctl          lcm.Controller
+	deDuplicator DeDuplicator
 	// key is name of known job
 	// value is the type of known job
 	knownJobs *sync.Map
@@ -68,15 +71,16 @@ func NewWorker(ctx *env.Context, namespace string, workerCount uint, redisPool *
 	}
 
 	return &basicWorker{
-		namespace: namespace,
-		redisPool: redisPool,
-		pool:      work.NewWorkerPool(workerContext{}, wc, namespace, redisPool),
-		enqueuer:  work.NewEnqueuer(namespace, redisPool),
-		client:    work.NewClient(namespace, redisPool),
-		scheduler: period.NewScheduler(ctx.SystemContext, namespace, redisPool, ctl),
-		ctl:       ctl,
-		context:   ctx,
-		knownJobs: new(sync.Map),
+		namespace:    namespace,
+		redisPool:    redisPool,
+		pool:         work.NewWorkerPool(workerContext{}, wc, namespace, redisPool),
+		enqueuer:     work.NewEnqueuer(namespace, redisPool),
+		client:       work.NewClient(namespace, redisPool),
+		scheduler:    period.NewScheduler(ctx.SystemContext, namespace, redisPool, ctl),
+		ctl:          ctl,
+		deDuplicator: NewDeDuplicator(namespace, redisPool, ctl),
+		context:      ctx,
+		knownJobs:    new(sync.Map),
 	}
 }
 
@@ -100,7 +104,7 @@ func (w *basicWorker) Start() error {
 	}
 
 	// Test the redis connection
-	if err := w.ping(); err != nil {
+	if err := base.Ping(w.redisPool); err != nil {
 		return err
 	}
 
@@ -149,50 +153,47 @@ func (w *basicWorker) RegisterJobs(jobs map[string]interface{}) error {
 	return nil
 }
 
-func (w *basicWorker) Enqueue(jobName string, params job.Parameters, isUnique bool, webHook string) (*job.Stats, error) {
-	var (
-		j   *work.Job
-		err error
-	)
-
+func (w *basicWorker) Enqueue(jobName string, params job.Parameters, unique *job.UniqueOptions, webHook string) (res *job.Stats, err error) {
 	// 检查job是否唯一
-	if isUnique {
-		if j, err = w.enqueuer.EnqueueUnique(jobName, params); err != nil {
-			return nil, err
-		}
-	} else {
-		// Enqueue job
-		if j, err = w.enqueuer.Enqueue(jobName, params); err != nil {
+	if unique != nil {
+		sign, err := w.deDuplicator.MustUnique(jobName, params, unique)
+		if err != nil {
 			return nil, err
 		}
+		defer base.BindUniqueSign(w.deDuplicator, sign, unique, &res)
+	}
+
+	// Enqueue job
+	j, err := w.enqueuer.Enqueue(jobName, params)
+	if err != nil {
+		return nil, err
 	}
 	// avoid backend worker bug
 	if j == nil {
 		return nil, fmt.Errorf("job '%s' can not be enqueued, please check the job metatdata", jobName)
 	}
 
-	return generateResult(j, job.KindGeneric, isUnique, params, webHook), nil
+	return base.GenerateResult(j, job.KindGeneric, unique != nil, params, webHook), nil
 }
 
-func (w *basicWorker) Schedule(jobName string, params job.Parameters, runAfterSeconds uint64, isUnique bool, webHook string) (*job.Stats, error) {
-	var (
-		j   *work.ScheduledJob
-		err error
-	)
-	if isUnique {
-		if j, err = w.enqueuer.EnqueueUniqueIn(jobName, int64(runAfterSeconds), params); err != nil {
-			return nil, err
-		}
-	} else {
-		if j, err = w.enqueuer.EnqueueIn(jobName, int64(runAfterSeconds), params); err != nil {
+func (w *basicWorker) Schedule(jobName string, params job.Parameters, runAfterSeconds uint64, unique *job.UniqueOptions, webHook string) (res *job.Stats, err error) {
+	if unique != nil {
+		sign, err := w.deDuplicator.MustUnique(jobName, params, unique)
+		if err != nil {
 			return nil, err
 		}
+		defer base.BindUniqueSign(w.deDuplicator, sign, unique, &res)
+	}
+
+	j, err := w.enqueuer.EnqueueIn(jobName, int64(runAfterSeconds), params)
+	if err != nil {
+		return nil, err
 	}
 	// avoid backend worker bug
 	if j == nil {
 		return nil, fmt.Errorf("job '%s' can not be enqueued, please check the job metatdata", jobName)
 	}
-	res := generateResult(j.Job, job.KindScheduled, isUnique, params, webHook)
+	res = base.GenerateResult(j.Job, job.KindScheduled, unique != nil, params, webHook)
 	res.Info.RunAt = j.RunAt
 	res.Info.Status = job.ScheduledStatus.String()
 
@@ -200,7 +201,7 @@ func (w *basicWorker) Schedule(jobName string, params job.Parameters, runAfterSe
 }
 
 // 自己实现了周期性任务队列，调度逻辑都自己实现
-func (w *basicWorker) PeriodicallyEnqueue(jobName string, params job.Parameters, cronSetting string, isUnique bool, webHook string) (*job.Stats, error) {
+func (w *basicWorker) PeriodicallyEnqueue(jobName string, params job.Parameters, cronSetting string, schedule *job.ScheduleSpec, isUnique bool, webHook string) (*job.Stats, error) {
 
 	p := &period.Policy{
 		ID:            utils.MakeIdentifier(),
@@ -208,29 +209,15 @@ func (w *basicWorker) PeriodicallyEnqueue(jobName string, params job.Parameters,
 		CronSpec:      cronSetting,
 		JobParameters: params,
 		WebHookURL:    webHook,
+		Schedule:      schedule,
 	}
 
 	id, err := w.scheduler.Schedule(p)
 	if err != nil {
 		return nil, err
 	}
-	res := &job.Stats{
-		Info: &job.StatsInfo{
-			JobID:       p.ID,
-			JobName:     jobName,
-			Status:      job.ScheduledStatus.String(),
-			JobKind:     job.KindPeriodic,
-			CronSpec:    cronSetting,
-			WebHookURL:  webHook,
-			NumericPID:  id,
-			EnqueueTime: time.Now().Unix(),
-			UpdateTime:  time.Now().Unix(),
-			RefLink:     fmt.Sprintf("/api/v1/jobs/%s", p.ID),
-			Parameters:  params,
-		},
-	}
 
-	return res, nil
+	return p.JobStats(id), nil
 }
 
 // Info of worker
@@ -267,25 +254,39 @@ func (w *basicWorker) Stats() (*worker.Stats, error) {
 		return nil, errors.New("failed to get stats of worker pools")
 	}
 
-	return &worker.Stats{
+	res := &worker.Stats{
 		Pools: stats,
-	}, nil
+	}
+
+	// Attach the leader of the periodic enqueuer
+	leader, err := w.scheduler.Leader()
+	if err != nil {
+		logger.Errorf("get leader of periodic enqueuer error: %s", err)
+	} else if leader != nil {
+		res.Leader = &worker.LeaderData{
+			NodeID:       leader.NodeID,
+			FencingToken: leader.Token,
+			ExpireAt:     leader.ExpireAt,
+		}
+	}
+
+	return res, nil
 }
 
 func (w *basicWorker) IsKnownJob(name string) (interface{}, bool) {
 	return w.knownJobs.Load(name)
 }
 
-func (w *basicWorker) ValidateJobParameters(jobType interface{}, params job.Parameters) error {
-	if jobType == nil {
-		return errors.New("nil job type")
-	}
+// KnownJobs is implementation of worker.Interface.KnownJobs
+func (w *basicWorker) KnownJobs() map[string]interface{} {
+	return base.KnownJobs(w.knownJobs)
+}
 
-	theJ := runner.Wrap(jobType)
-	return theJ.Validate(params)
+func (w *basicWorker) ValidateJobParameters(jobType interface{}, params job.Parameters) error {
+	return base.ValidateJobParameters(jobType, params)
 }
 
-//Stop will stop the job
+// Stop will stop the job
 func (w *basicWorker) StopJob(jobID string) error {
 	if utils.IsEmptyStr(jobID) {
 		return errors.New("empty job ID to stop")
@@ -297,7 +298,7 @@ func (w *basicWorker) StopJob(jobID string) error {
 		return err
 	}
 	if job.RunningStatus.Compare(job.Status(t.Job().Info.Status)) < 0 {
-		return errors.Errorf("mismatch job status for stopping job: %s, job status %s is behind %s", jobID, t.Job().Info.Status, job.RunningStatus)
+		return errs.StatusMismatchError(t.Job().Info.Status, job.StoppedStatus.String())
 	}
 
 	switch t.Job().Info.JobKind {
@@ -318,56 +319,73 @@ func (w *basicWorker) StopJob(jobID string) error {
 	}
 }
 
-func (w *basicWorker) RetryJob(jobID string) error {
-	return errors.New("not implemented")
-}
+// RescheduleJob moves the scheduled job which is not enqueued yet to run at the specified time
+func (w *basicWorker) RescheduleJob(jobID string, runAt int64) error {
+	if utils.IsEmptyStr(jobID) {
+		return errors.New("empty job ID to reschedule")
+	}
+
+	t, err := w.ctl.Track(jobID)
+	if err != nil {
+		return err
+	}
+
+	info := t.Job().Info
+	if info.JobKind != job.KindScheduled {
+		return errors.Errorf("job kind %s is not supported to reschedule", info.JobKind)
+	}
+	if job.Status(info.Status) != job.ScheduledStatus {
+		return errs.StatusMismatchError(info.Status, job.ScheduledStatus.String())
+	}
 
-func (w *basicWorker) ping() error {
 	conn := w.redisPool.Get()
 	defer func() {
 		_ = conn.Close()
 	}()
 
-	var err error
-	for count := 1; count <= pingRedisMaxTimes; count++ {
-		if _, err = conn.Do("ping"); err == nil {
-			return nil
-		}
-		time.Sleep(time.Duration(count+4) * time.Second)
+	moved, err := rds.RescheduleJob(conn, rds.RedisKeyScheduled(w.namespace), jobID, info.RunAt, runAt)
+	if err != nil {
+		return err
+	}
+	if !moved {
+		return errs.StatusMismatchError(job.PendingStatus.String(), job.ScheduledStatus.String())
 	}
-	return fmt.Errorf("connect to redis server timeout: %s", err.Error())
 
+	return t.Reschedule(runAt)
 }
 
-// RegisterJob is used to register the job to the worker.
-// j is the type of the job
-func (w *basicWorker) registerJob(name string, j interface{}) (err error) {
-	if utils.IsEmptyStr(name) || j == nil {
-		return errors.New("job can not be registered with empty name or nil interface")
-	}
+// RetryJob puts the failed job back to the queue with the same job ID
+func (w *basicWorker) RetryJob(jobID string) error {
+	return base.RetryJob(w.ctl, jobID, w.push)
+}
 
-	//j must be job.Interface
-	if _, ok := j.(job.Interface); !ok {
-		return errors.Errorf("job must implement the job.Interface :%s", reflect.TypeOf(j).String())
-	}
+// RequeueJob puts the orphaned job of the dead node back to the queue with the same job ID
+func (w *basicWorker) RequeueJob(jobID string) error {
+	return base.RequeueJob(w.ctl, jobID, w.push)
+}
 
-	//1:1 constraint
-	if jInList, ok := w.knownJobs.Load(name); ok {
-		return fmt.Errorf("job name %s has been already registered with %s", name, reflect.TypeOf(jInList).String())
+// push the job to the queue
+func (w *basicWorker) push(j *work.Job) error {
+	rawJSON, err := utils.SerializeJob(j)
+	if err != nil {
+		return err
 	}
 
-	//Same job implementation can be only registered with on name
-	w.knownJobs.Range(func(jName interface{}, jInList interface{}) bool {
-		jobImpl := reflect.TypeOf(j).String()
-		if reflect.TypeOf(jInList).String() == jobImpl {
-			err = errors.Errorf("job %s has been already registered with name %s", jobImpl, jName)
-			return false
-		}
-		return true
-	})
-	// Something happened in the range
-	if err != nil {
-		return
+	conn := w.redisPool.Get()
+	defer func() {
+		_ = conn.Close()
+	}()
+
+	_, err = conn.Do("LPUSH", rds.RedisKeyJobs(w.namespace, j.Name), rawJSON)
+
+	return err
+}
+
+// RegisterJob is used to register the job to the worker.
+// j is the type of the job
+func (w *basicWorker) registerJob(name string, j interface{}) (err error) {
+	if err := base.CheckRegistration(w.knownJobs, name, j); err != nil {
+		return err
 	}
 
 	//Wrap job
@@ -392,26 +410,3 @@ func (w *basicWorker) registerJob(name string, j interface{}) (err error) {
 
 	return nil
 }
-
-func generateResult(
-	j *work.Job,
-	jobKind string,
-	isUnique bool,
-	jobParameters job.Parameters,
-	webHook string,
-) *job.Stats {
-	return &job.Stats{
-		Info: &job.StatsInfo{
-			JobID:       j.ID,
-			JobName:     j.Name,
-			JobKind:     jobKind,
-			IsUnique:    isUnique,
-			Status:      job.PendingStatus.String(),
-			EnqueueTime: j.EnqueuedAt,
-			UpdateTime:  time.Now().Unix(),
-			RefLink:     fmt.Sprintf("/api/v1/jobs/%s", j.ID),
-			Parameters:  jobParameters,
-			WebHookURL:  webHook,
-		},
-	}
-}
@@ -1 +1,215 @@
 package cworker
+
+import (
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"github.com/chenxull/goGridhub/gridhub/src/jobservice/common/rds"
+	"github.com/chenxull/goGridhub/gridhub/src/jobservice/errs"
+	"github.com/chenxull/goGridhub/gridhub/src/jobservice/job"
+	"github.com/chenxull/goGridhub/gridhub/src/jobservice/lcm"
+	"github.com/gomodule/redigo/redis"
+	"time"
+)
+
+// The unique sign is kept in this duration if no TTL is specified
+const defaultUniqueTTL = 24 * time.Hour
+
+// The stats of the job bound to the sign are saved right after the binding, the sign bound longer than
+// this duration to a job without stats is stale, e.g: the stats expired or failed to save
+const staleSignGrace = 10 * time.Second
+
+// The reserved sign is kept in this duration before the job is enqueued and bound to it, so the sign
+// left by the crash between the reserving and the binding does not block the same job for the whole TTL
+const reservedSignTTL = staleSignGrace
+
+// Set the sign to the new value only if it's not changed.
+// KEYS[1]: sign key; ARGV[1]: old value; ARGV[2]: new value; ARGV[3]: ttl (seconds)
+var takeoverSignScript = redis.NewScript(1, `
+if redis.call('GET', KEYS[1]) == ARGV[1] then
+  redis.call('SET', KEYS[1], ARGV[2], 'EX', ARGV[3])
+  return 1
+end
+return 0
+`)
+
+// DeDuplicator checks the uniqueness of the job among the queued and running jobs.
+// The uniqueness is kept by a sign built from the job name and the unique parameters,
+// the sign is released when the bound job reaches the final status or the TTL expires.
+type DeDuplicator interface {
+	// MustUnique reserves the unique sign of the job before enqueuing.
+	//
+	// Arguments:
+	//   jobName string            : name of the job
+	//   params job.Parameters     : parameters of the job
+	//   opts *job.UniqueOptions   : options of the uniqueness
+	//
+	// Returns:
+	//   The reserved sign
+	//   Conflict error with the existing job stats if the job is duplicated
+	MustUnique(jobName string, params job.Parameters, opts *job.UniqueOptions) (string, error)
+
+	// Bind the enqueued job to the reserved sign
+	Bind(sign string, jobID string, opts *job.UniqueOptions) error
+
+	// DelUniqueSign releases the reserved sign
+	DelUniqueSign(sign string) error
+}
+
+// redisDeDuplicator is the default implementation of DeDuplicator based on redis
+type redisDeDuplicator struct {
+	namespace string
+	pool      *redis.Pool
+	ctl       lcm.Controller
+}
+
+// NewDeDuplicator is constructor of redisDeDuplicator
+func NewDeDuplicator(namespace string, pool *redis.Pool, ctl lcm.Controller) DeDuplicator {
+	return &redisDeDuplicator{
+		namespace: namespace,
+		pool:      pool,
+		ctl:       ctl,
+	}
+}
+
+// MustUnique is implementation of DeDuplicator.MustUnique
+func (rd *redisDeDuplicator) MustUnique(jobName string, params job.Parameters, opts *job.UniqueOptions) (string, error) {
+	sign, err := UniqueSign(jobName, params, opts)
+	if err != nil {
+		return "", err
+	}
+
+	conn := rd.pool.Get()
+	defer func() {
+		_ = conn.Close()
+	}()
+
+	key := rds.KeyUniqueJob(rd.namespace, sign)
+	ttl := UniqueTTL(opts)
+	// Empty value means the job is being enqueued, the full TTL is set by the binding
+	reply, err := conn.Do("SET", key, "", "EX", reservedTTL(ttl), "NX")
+	if err != nil {
+		return "", err
+	}
+	if reply != nil {
+		return sign, nil
+	}
+
+	jobID, err := redis.String(conn.Do("GET", key))
+	if err != nil {
+		if err == redis.ErrNil {
+			// Released just now
+			return "", errs.ConflictError(fmt.Sprintf("unique job %s, please retry", jobName))
+		}
+		return "", err
+	}
+
+	if len(jobID) == 0 {
+		return "", errs.ConflictError(fmt.Sprintf("unique job %s being enqueued", jobName))
+	}
+
+	t, err := rd.ctl.Track(jobID)
+	if err != nil {
+		if !errs.IsObjectNotFoundError(err) {
+			return "", err
+		}
+
+		// The stats of the job may be not saved yet
+		stale, err := rd.stale(conn, key, ttl)
+		if err != nil {
+			return "", err
+		}
+		if !stale {
+			return "", errs.ConflictError(fmt.Sprintf("unique job %s:%s", jobName, jobID))
+		}
+	} else if !job.Status(t.Job().Info.Status).Final() {
+		return "", errs.ConflictErrorWithData(fmt.Sprintf("unique job %s:%s", jobName, jobID), t.Job())
+	}
+
+	// The bound job is done or gone, take over the sign if it's not changed by others
+	ok, err := redis.Bool(takeoverSignScript.Do(conn, key, jobID, "", reservedTTL(ttl)))
+	if err != nil {
+		return "", err
+	}
+	if !ok {
+		return "", errs.ConflictError(fmt.Sprintf("unique job %s, please retry", jobName))
+	}
+
+	return sign, nil
+}
+
+// stale checks if the sign is bound longer than the grace duration, the TTL is reset by the binding
+func (rd *redisDeDuplicator) stale(conn redis.Conn, key string, ttl uint64) (bool, error) {
+	left, err := redis.Int64(conn.Do("TTL", key))
+	if err != nil {
+		return false, err
+	}
+	if left < 0 {
+		// Released or expired just now
+		return true, nil
+	}
+
+	return time.Duration(int64(ttl)-left)*time.Second > staleSignGrace, nil
+}
+
+// reservedTTL returns the TTL in seconds of the sign reserved before the binding, which is not longer than the TTL of the uniqueness
+func reservedTTL(ttl uint64) uint64 {
+	if reserved := uint64(reservedSignTTL / time.Second); reserved < ttl {
+		return reserved
+	}
+
+	return ttl
+}
+
+// Bind is implementation of DeDuplicator.Bind, the sign is kept in the TTL of the uniqueness from now on
+func (rd *redisDeDuplicator) Bind(sign string, jobID string, opts *job.UniqueOptions) error {
+	conn := rd.pool.Get()
+	defer func() {
+		_ = conn.Close()
+	}()
+
+	_, err := conn.Do("SET", rds.KeyUniqueJob(rd.namespace, sign), jobID, "EX", UniqueTTL(opts))
+
+	return err
+}
+
+// DelUniqueSign is implementation of DeDuplicator.DelUniqueSign
+func (rd *redisDeDuplicator) DelUniqueSign(sign string) error {
+	conn := rd.pool.Get()
+	defer func() {
+		_ = conn.Close()
+	}()
+
+	_, err := conn.Do("DEL", rds.KeyUniqueJob(rd.namespace, sign))
+
+	return err
+}
+
+// UniqueSign builds the sign from the job name and the unique parameters
+func UniqueSign(jobName string, params job.Parameters, opts *job.UniqueOptions) (string, error) {
+	uniqueParams := params
+	if opts != nil && len(opts.Keys) > 0 {
+		uniqueParams = make(job.Parameters, len(opts.Keys))
+		for _, k := range opts.Keys {
+			// Missing key is kept as null
+			uniqueParams[k] = params[k]
+		}
+	}
+
+	// The keys of map are sorted when marshaling
+	rawJSON, err := json.Marshal(uniqueParams)
+	if err != nil {
+		return "", err
+	}
+
+	return fmt.Sprintf("%x", sha256.Sum256(append([]byte(jobName+":"), rawJSON...))), nil
+}
+
+// UniqueTTL returns the TTL of the sign in seconds
+func UniqueTTL(opts *job.UniqueOptions) uint64 {
+	if opts != nil && opts.TTL > 0 {
+		return opts.TTL
+	}
+
+	return uint64(defaultUniqueTTL / time.Second)
+}
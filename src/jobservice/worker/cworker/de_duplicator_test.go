@@ -0,0 +1,105 @@
+package cworker
+
+import (
+	"github.com/alicebob/miniredis/v2"
+	"github.com/chenxull/goGridhub/gridhub/src/jobservice/common/rds"
+	"github.com/chenxull/goGridhub/gridhub/src/jobservice/errs"
+	"github.com/chenxull/goGridhub/gridhub/src/jobservice/job"
+	"github.com/chenxull/goGridhub/gridhub/src/jobservice/lcm"
+	"github.com/gomodule/redigo/redis"
+	"testing"
+	"time"
+)
+
+const testNamespace = "{cworker_test}"
+
+// missingController tracks no jobs, as if their stats are not saved
+type missingController struct {
+	lcm.Controller
+}
+
+func (mc *missingController) Track(jobID string) (job.Tracker, error) {
+	return nil, errs.NoObjectFoundError(jobID)
+}
+
+func newTestDeDuplicator(t *testing.T) (DeDuplicator, *miniredis.Miniredis) {
+	t.Helper()
+
+	mr := miniredis.RunT(t)
+	pool := &redis.Pool{
+		Dial: func() (redis.Conn, error) {
+			return redis.Dial("tcp", mr.Addr())
+		},
+	}
+	t.Cleanup(func() {
+		_ = pool.Close()
+	})
+
+	return NewDeDuplicator(testNamespace, pool, &missingController{}), mr
+}
+
+func TestMustUniqueReservedSign(t *testing.T) {
+	dd, mr := newTestDeDuplicator(t)
+	params := job.Parameters{"image": "busybox"}
+	opts := &job.UniqueOptions{TTL: 3600}
+
+	sign, err := dd.MustUnique("SCAN", params, opts)
+	if err != nil {
+		t.Fatalf("reserve sign error: %s", err)
+	}
+	key := rds.KeyUniqueJob(testNamespace, sign)
+	if ttl := mr.TTL(key); ttl != reservedSignTTL {
+		t.Errorf("expect the reserved sign kept in %v but got %v", reservedSignTTL, ttl)
+	}
+
+	if _, err := dd.MustUnique("SCAN", params, opts); !errs.IsConflictError(err) {
+		t.Fatalf("expect conflict while the job is being enqueued but got %v", err)
+	}
+
+	// Crashed before binding the job, the reserved sign is released after its TTL
+	mr.FastForward(reservedSignTTL)
+	if _, err := dd.MustUnique("SCAN", params, opts); err != nil {
+		t.Fatalf("expect the sign left by the crash released but got %v", err)
+	}
+}
+
+func TestMustUniqueBoundSign(t *testing.T) {
+	dd, mr := newTestDeDuplicator(t)
+	params := job.Parameters{"image": "busybox"}
+	opts := &job.UniqueOptions{TTL: 3600}
+
+	sign, err := dd.MustUnique("SCAN", params, opts)
+	if err != nil {
+		t.Fatalf("reserve sign error: %s", err)
+	}
+	if err := dd.Bind(sign, "job-1", opts); err != nil {
+		t.Fatalf("bind sign error: %s", err)
+	}
+	key := rds.KeyUniqueJob(testNamespace, sign)
+	if ttl := mr.TTL(key); ttl != time.Hour {
+		t.Errorf("expect the bound sign kept in the TTL of uniqueness but got %v", ttl)
+	}
+
+	// The stats of the bound job may be not saved yet
+	if _, err := dd.MustUnique("SCAN", params, opts); !errs.IsConflictError(err) {
+		t.Fatalf("expect conflict in the grace duration but got %v", err)
+	}
+
+	// The bound job without stats is stale after the grace duration
+	mr.FastForward(staleSignGrace + time.Second)
+	if _, err := dd.MustUnique("SCAN", params, opts); err != nil {
+		t.Fatalf("expect the stale sign taken over but got %v", err)
+	}
+	if ttl := mr.TTL(key); ttl != reservedSignTTL {
+		t.Errorf("expect the taken over sign kept in %v but got %v", reservedSignTTL, ttl)
+	}
+}
+
+func TestReservedTTL(t *testing.T) {
+	if ttl := reservedTTL(5); ttl != 5 {
+		t.Errorf("expect the reserved TTL not longer than the uniqueness but got %d", ttl)
+	}
+	if ttl := reservedTTL(3600); ttl != uint64(reservedSignTTL/time.Second) {
+		t.Errorf("expect reserved TTL %v but got %d", reservedSignTTL, ttl)
+	}
+}
@@ -12,9 +12,11 @@ type Interface interface {
 	RegisterJobs(jobs map[string]interface{}) error
 
 	//Enqueue
-	Enqueue(jobName string, params job.Parameters, isUnique bool, webHook string) (*job.Stats, error)
-	Schedule(jobName string, params job.Parameters, runAfterSeconds uint64, isUnique bool, webHook string) (*job.Stats, error)
-	PeriodicallyEnqueue(jobName string, params job.Parameters, cronSetting string, isUnique bool, webHook string) (*job.Stats, error)
+	// The job is unique if unique options are provided
+	Enqueue(jobName string, params job.Parameters, unique *job.UniqueOptions, webHook string) (*job.Stats, error)
+	Schedule(jobName string, params job.Parameters, runAfterSeconds uint64, unique *job.UniqueOptions, webHook string) (*job.Stats, error)
+	// The schedule spec provides the interval, one-off runs and blackout windows besides the cron setting
+	PeriodicallyEnqueue(jobName string, params job.Parameters, cronSetting string, schedule *job.ScheduleSpec, isUnique bool, webHook string) (*job.Stats, error)
 
 	// Return the status info of the worker.
 	Stats() (*Stats, error)
@@ -22,6 +24,9 @@ type Interface interface {
 	// Check if the job has been already registered.
 	IsKnownJob(name string) (interface{}, bool)
 
+	// Return all the registered jobs keyed by the job names
+	KnownJobs() map[string]interface{}
+
 	// Validate the parameters of the known job
 	ValidateJobParameters(jobType interface{}, params job.Parameters) error
 
@@ -29,4 +34,16 @@ type Interface interface {
 	StopJob(jobID string) error
 
 	RetryJob(jobID string) error
+
+	// RequeueJob puts the orphaned job of the dead node back to the queue with the same job ID
+	RequeueJob(jobID string) error
+
+	// Move the scheduled job to run at the specified unix time
+	RescheduleJob(jobID string, runAt int64) error
+}
+
+// Resizer is implemented by the worker which can change its concurrency without restart
+type Resizer interface {
+	// Resize the consumers to the concurrency, the retired consumers exit after finishing their running jobs
+	Resize(concurrency uint) error
 }
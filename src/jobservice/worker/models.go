@@ -2,7 +2,9 @@ package worker
 
 // Stats represents the healthy and status of all the running worker pools.
 type Stats struct {
-	Pools []*StatsData `json:"worker_pools"`
+	Pools   []*StatsData   `json:"worker_pools"`
+	Leader  *LeaderData    `json:"periodic_leader,omitempty"`
+	Streams []*StreamStats `json:"streams,omitempty"`
 }
 
 // StatsData represents the healthy and status of the worker worker.
@@ -14,3 +16,21 @@ type StatsData struct {
 	Concurrency  uint     `json:"concurrency"`
 	Status       string   `json:"status"`
 }
+
+// LeaderData represents the node which is scheduling the periodic jobs.
+type LeaderData struct {
+	NodeID       string `json:"node_id"`
+	FencingToken int64  `json:"fencing_token"`
+	ExpireAt     int64  `json:"expire_at"`
+}
+
+// StreamStats represents the metrics of the job stream of the redis streams backend.
+type StreamStats struct {
+	JobName   string `json:"job_name"`
+	Length    int64  `json:"length"`
+	Pending   int64  `json:"pending"`
+	Consumers int64  `json:"consumers"`
+	Processed int64  `json:"processed"`
+	Failed    int64  `json:"failed"`
+	Reclaimed int64  `json:"reclaimed"`
+}
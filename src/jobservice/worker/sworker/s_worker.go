@@ -0,0 +1,956 @@
+package sworker
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/chenxull/goGridhub/gridhub/src/jobservice/common/rds"
+	"github.com/chenxull/goGridhub/gridhub/src/jobservice/common/utils"
+	"github.com/chenxull/goGridhub/gridhub/src/jobservice/env"
+	"github.com/chenxull/goGridhub/gridhub/src/jobservice/errs"
+	"github.com/chenxull/goGridhub/gridhub/src/jobservice/job"
+	"github.com/chenxull/goGridhub/gridhub/src/jobservice/lcm"
+	"github.com/chenxull/goGridhub/gridhub/src/jobservice/logger"
+	"github.com/chenxull/goGridhub/gridhub/src/jobservice/period"
+	"github.com/chenxull/goGridhub/gridhub/src/jobservice/worker"
+	"github.com/chenxull/goGridhub/gridhub/src/jobservice/worker/base"
+	"github.com/chenxull/goGridhub/gridhub/src/jobservice/worker/cworker"
+	"github.com/gocraft/work"
+	"github.com/gomodule/redigo/redis"
+	"github.com/pkg/errors"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	workerPoolStatusHealthy      = "Healthy"
+	workerPoolStatusDead         = "Dead"
+	defaultWorkerCount      uint = 10
+
+	// Block duration of reading the streams
+	readBlockTime = 2 * time.Second
+	// Waiting a short while if any errors occurred
+	shortLoopInterval = 5 * time.Second
+	// Interval of moving the due scheduled jobs to the streams
+	scheduleInterval = time.Second
+	// Max jobs moved in one round
+	scheduleBatchSize = 100
+	// Interval of reporting heartbeat and reclaiming the pending messages
+	reclaimInterval = 10 * time.Second
+	// The pool is treated as dead if no heartbeat is received in this duration
+	poolDeadTime = 3 * reclaimInterval
+	// The message is reclaimed if it's not touched in this duration
+	reclaimIdleTime = time.Minute
+	// Interval of touching the message being processed
+	keepaliveInterval = reclaimIdleTime / 4
+	// Max pending entries checked in one round
+	pendingBatchSize = 100
+)
+
+// poolHeartbeat is the heartbeat of the stream worker pool
+type poolHeartbeat struct {
+	StartedAt   int64    `json:"started_at"`
+	HeartbeatAt int64    `json:"heartbeat_at"`
+	JobNames    []string `json:"job_names"`
+	Concurrency uint     `json:"concurrency"`
+}
+
+// streamWorker is the worker implementation based on redis streams with consumer groups.
+// Each job type has its own stream, the messages are acknowledged after the job is done,
+// so the jobs of the crashed nodes are reclaimed and run again (at-least-once delivery).
+// The scheduled jobs and the periodic executions share the scheduled zset with the
+// gocraft/work backend, so all the nodes in the same namespace should use the same backend.
+type streamWorker struct {
+	namespace   string
+	redisPool   *redis.Pool
+	client      *work.Client
+	context     *env.Context
+	nodeID      string
+	concurrency uint
+	startedAt   int64
+	// 1 if the worker is started
+	started int32
+	// Protect the concurrency
+	lock sync.Mutex
+	// Each message retires an idle consumer
+	retire chan struct{}
+
+	scheduler    period.Scheduler
+	ctl          lcm.Controller
+	deDuplicator cworker.DeDuplicator
+	// key is name of known job
+	// value is the type of known job
+	knownJobs *sync.Map
+	// key is name of known job
+	// value is the *base.RegisteredJob
+	jobs *sync.Map
+	// The reclaimed messages waiting to be processed
+	reclaimed chan *message
+}
+
+// NewWorker is constructor of the redis streams worker
+func NewWorker(ctx *env.Context, namespace string, workerCount uint, redisPool *redis.Pool, ctl lcm.Controller) worker.Interface {
+	wc := defaultWorkerCount
+	if workerCount > 0 {
+		wc = workerCount
+	}
+
+	nodeID, ok := ctx.SystemContext.Value(utils.NodeID).(string)
+	if !ok {
+		nodeID = utils.GenerateNodeID()
+	}
+
+	return &streamWorker{
+		namespace:    namespace,
+		redisPool:    redisPool,
+		client:       work.NewClient(namespace, redisPool),
+		context:      ctx,
+		nodeID:       nodeID,
+		concurrency:  wc,
+		startedAt:    time.Now().Unix(),
+		scheduler:    period.NewScheduler(ctx.SystemContext, namespace, redisPool, ctl),
+		ctl:          ctl,
+		deDuplicator: cworker.NewDeDuplicator(namespace, redisPool, ctl),
+		knownJobs:    new(sync.Map),
+		jobs:         new(sync.Map),
+		reclaimed:    make(chan *message, wc),
+		retire:       make(chan struct{}),
+	}
+}
+
+// Start to serve
+func (w *streamWorker) Start() error {
+	if w.redisPool == nil {
+		return errors.New("missing redis pool")
+	}
+
+	if utils.IsEmptyStr(w.namespace) {
+		return errors.New("missing namespace")
+	}
+
+	if w.context == nil || w.context.SystemContext == nil {
+		// report and exit
+		return errors.New("missing context")
+	}
+
+	if w.ctl == nil {
+		return errors.New("missing job life cycle controller")
+	}
+
+	// Test the redis connection
+	if err := base.Ping(w.redisPool); err != nil {
+		return err
+	}
+
+	atomic.StoreInt32(&w.started, 1)
+	if err := w.createGroups(); err != nil {
+		return err
+	}
+
+	// Start the periodic scheduler
+	w.context.WG.Add(1)
+	go func() {
+		defer func() {
+			w.context.WG.Done()
+		}()
+		//Blocking call
+		if err := w.scheduler.Start(); err != nil {
+			w.context.ErrorChan <- err
+		}
+	}()
+	// Listen to the system signal
+	w.context.WG.Add(1)
+	go func() {
+		defer func() {
+			w.context.WG.Done()
+			logger.Infof("Stream worker is stopped")
+		}()
+		<-w.context.SystemContext.Done()
+		if err := w.scheduler.Stop(); err != nil {
+			logger.Errorf("stop scheduler error: %s", err)
+		}
+	}()
+
+	w.context.WG.Add(2)
+	go w.loopSchedule()
+	go w.loopReclaim()
+
+	for i := uint(0); i < w.concurrency; i++ {
+		w.context.WG.Add(1)
+		go w.consume()
+	}
+
+	logger.Infof("redis streams worker is started with %d consumers", w.concurrency)
+	return nil
+}
+
+// RegisterJobs is used to register multiple jobs to worker.
+func (w *streamWorker) RegisterJobs(jobs map[string]interface{}) error {
+	if jobs == nil || len(jobs) == 0 {
+		return nil
+	}
+
+	for name, j := range jobs {
+		if err := w.registerJob(name, j); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Enqueue is implementation of worker.Interface.Enqueue
+func (w *streamWorker) Enqueue(jobName string, params job.Parameters, unique *job.UniqueOptions, webHook string) (res *job.Stats, err error) {
+	if unique != nil {
+		sign, err := w.deDuplicator.MustUnique(jobName, params, unique)
+		if err != nil {
+			return nil, err
+		}
+		defer base.BindUniqueSign(w.deDuplicator, sign, unique, &res)
+	}
+
+	j := base.NewJob(jobName, params)
+	rawJSON, err := utils.SerializeJob(j)
+	if err != nil {
+		return nil, err
+	}
+
+	conn := w.redisPool.Get()
+	defer func() {
+		_ = conn.Close()
+	}()
+
+	if _, err := conn.Do("XADD", rds.KeyStream(w.namespace, jobName), "*", jobField, rawJSON); err != nil {
+		return nil, err
+	}
+
+	return base.GenerateResult(j, job.KindGeneric, unique != nil, params, webHook), nil
+}
+
+// Schedule is implementation of worker.Interface.Schedule
+func (w *streamWorker) Schedule(jobName string, params job.Parameters, runAfterSeconds uint64, unique *job.UniqueOptions, webHook string) (res *job.Stats, err error) {
+	if unique != nil {
+		sign, err := w.deDuplicator.MustUnique(jobName, params, unique)
+		if err != nil {
+			return nil, err
+		}
+		defer base.BindUniqueSign(w.deDuplicator, sign, unique, &res)
+	}
+
+	j := base.NewJob(jobName, params)
+	rawJSON, err := utils.SerializeJob(j)
+	if err != nil {
+		return nil, err
+	}
+
+	conn := w.redisPool.Get()
+	defer func() {
+		_ = conn.Close()
+	}()
+
+	runAt := j.EnqueuedAt + int64(runAfterSeconds)
+	if _, err := conn.Do("ZADD", rds.RedisKeyScheduled(w.namespace), runAt, rawJSON); err != nil {
+		return nil, err
+	}
+
+	res = base.GenerateResult(j, job.KindScheduled, unique != nil, params, webHook)
+	res.Info.RunAt = runAt
+	res.Info.Status = job.ScheduledStatus.String()
+
+	return res, nil
+}
+
+// PeriodicallyEnqueue is implementation of worker.Interface.PeriodicallyEnqueue
+func (w *streamWorker) PeriodicallyEnqueue(jobName string, params job.Parameters, cronSetting string, schedule *job.ScheduleSpec, isUnique bool, webHook string) (*job.Stats, error) {
+	p := &period.Policy{
+		ID:            utils.MakeIdentifier(),
+		JobName:       jobName,
+		CronSpec:      cronSetting,
+		JobParameters: params,
+		WebHookURL:    webHook,
+		Schedule:      schedule,
+	}
+
+	id, err := w.scheduler.Schedule(p)
+	if err != nil {
+		return nil, err
+	}
+
+	return p.JobStats(id), nil
+}
+
+// Stats is implementation of worker.Interface.Stats
+func (w *streamWorker) Stats() (*worker.Stats, error) {
+	conn := w.redisPool.Get()
+	defer func() {
+		_ = conn.Close()
+	}()
+
+	hbs, err := redis.StringMap(conn.Do("HGETALL", rds.KeyStreamWorkerPools(w.namespace)))
+	if err != nil {
+		return nil, err
+	}
+
+	stats := make([]*worker.StatsData, 0, len(hbs))
+	for poolID, raw := range hbs {
+		hb := &poolHeartbeat{}
+		if err := json.Unmarshal([]byte(raw), hb); err != nil {
+			logger.Errorf("malformed heartbeat of stream worker pool %s: %s", poolID, err)
+			continue
+		}
+
+		status := workerPoolStatusHealthy
+		if time.Unix(hb.HeartbeatAt, 0).Add(poolDeadTime).Before(time.Now()) {
+			status = workerPoolStatusDead
+		}
+		stats = append(stats, &worker.StatsData{
+			WorkerPoolID: poolID,
+			StartedAt:    hb.StartedAt,
+			HeartbeatAt:  hb.HeartbeatAt,
+			JobNames:     hb.JobNames,
+			Concurrency:  hb.Concurrency,
+			Status:       status,
+		})
+	}
+
+	if len(stats) == 0 {
+		return nil, errors.New("failed to get stats of worker pools")
+	}
+
+	res := &worker.Stats{
+		Pools: stats,
+	}
+
+	// Attach the leader of the periodic enqueuer
+	leader, err := w.scheduler.Leader()
+	if err != nil {
+		logger.Errorf("get leader of periodic enqueuer error: %s", err)
+	} else if leader != nil {
+		res.Leader = &worker.LeaderData{
+			NodeID:       leader.NodeID,
+			FencingToken: leader.Token,
+			ExpireAt:     leader.ExpireAt,
+		}
+	}
+
+	// Attach the metrics of the streams
+	for _, name := range w.jobNames() {
+		ss, err := w.streamStats(conn, name)
+		if err != nil {
+			logger.Errorf("get stats of stream %s error: %s", name, err)
+			continue
+		}
+		res.Streams = append(res.Streams, ss)
+	}
+
+	return res, nil
+}
+
+// IsKnownJob is implementation of worker.Interface.IsKnownJob
+func (w *streamWorker) IsKnownJob(name string) (interface{}, bool) {
+	return w.knownJobs.Load(name)
+}
+
+// KnownJobs is implementation of worker.Interface.KnownJobs
+func (w *streamWorker) KnownJobs() map[string]interface{} {
+	return base.KnownJobs(w.knownJobs)
+}
+
+// ValidateJobParameters is implementation of worker.Interface.ValidateJobParameters
+func (w *streamWorker) ValidateJobParameters(jobType interface{}, params job.Parameters) error {
+	return base.ValidateJobParameters(jobType, params)
+}
+
+// StopJob is implementation of worker.Interface.StopJob
+func (w *streamWorker) StopJob(jobID string) error {
+	if utils.IsEmptyStr(jobID) {
+		return errors.New("empty job ID to stop")
+	}
+
+	t, err := w.ctl.Track(jobID)
+	if err != nil {
+		return err
+	}
+	if job.RunningStatus.Compare(job.Status(t.Job().Info.Status)) < 0 {
+		return errs.StatusMismatchError(t.Job().Info.Status, job.StoppedStatus.String())
+	}
+
+	switch t.Job().Info.JobKind {
+	case job.KindGeneric:
+		// The message left in the stream will be skipped by the runner as the job is stopped
+		return t.Stop()
+	case job.KindScheduled:
+		// delete the scheduled job in the zset if it's not moved to the stream yet
+		if err := w.client.DeleteScheduledJob(t.Job().Info.RunAt, jobID); err != nil {
+			logger.Errorf("scheduled job %s (run at = %d) is not found in the queue to stop, is it already running?", jobID, t.Job().Info.RunAt)
+		}
+		return t.Stop()
+	case job.KindPeriodic:
+		return w.scheduler.UnSchedule(jobID)
+	default:
+		return errors.Errorf("job kind %s is not supported", t.Job().Info.JobKind)
+	}
+}
+
+// RescheduleJob moves the scheduled job which is not moved to the stream yet to run at the specified time
+func (w *streamWorker) RescheduleJob(jobID string, runAt int64) error {
+	if utils.IsEmptyStr(jobID) {
+		return errors.New("empty job ID to reschedule")
+	}
+
+	t, err := w.ctl.Track(jobID)
+	if err != nil {
+		return err
+	}
+
+	info := t.Job().Info
+	if info.JobKind != job.KindScheduled {
+		return errors.Errorf("job kind %s is not supported to reschedule", info.JobKind)
+	}
+	if job.Status(info.Status) != job.ScheduledStatus {
+		return errs.StatusMismatchError(info.Status, job.ScheduledStatus.String())
+	}
+
+	conn := w.redisPool.Get()
+	defer func() {
+		_ = conn.Close()
+	}()
+
+	moved, err := rds.RescheduleJob(conn, rds.RedisKeyScheduled(w.namespace), jobID, info.RunAt, runAt)
+	if err != nil {
+		return err
+	}
+	if !moved {
+		return errs.StatusMismatchError(job.PendingStatus.String(), job.ScheduledStatus.String())
+	}
+
+	return t.Reschedule(runAt)
+}
+
+// RetryJob puts the failed job back to the stream with the same job ID
+func (w *streamWorker) RetryJob(jobID string) error {
+	return base.RetryJob(w.ctl, jobID, w.push)
+}
+
+// RequeueJob puts the orphaned job of the dead node back to the stream with the same job ID
+func (w *streamWorker) RequeueJob(jobID string) error {
+	return base.RequeueJob(w.ctl, jobID, w.push)
+}
+
+// push the job to the stream
+func (w *streamWorker) push(j *work.Job) error {
+	rawJSON, err := utils.SerializeJob(j)
+	if err != nil {
+		return err
+	}
+
+	conn := w.redisPool.Get()
+	defer func() {
+		_ = conn.Close()
+	}()
+
+	_, err = conn.Do("XADD", rds.KeyStream(w.namespace, j.Name), "*", jobField, rawJSON)
+
+	return err
+}
+
+// Resize is implementation of worker.Resizer
+func (w *streamWorker) Resize(concurrency uint) error {
+	if concurrency == 0 {
+		return errors.New("concurrency should be greater than 0")
+	}
+
+	w.lock.Lock()
+	defer w.lock.Unlock()
+
+	if concurrency > w.concurrency {
+		for i := w.concurrency; i < concurrency; i++ {
+			w.context.WG.Add(1)
+			go w.consume()
+		}
+	} else if retired := w.concurrency - concurrency; retired > 0 {
+		// Retire the consumers once they're idle, don't block the caller
+		go func() {
+			for i := uint(0); i < retired; i++ {
+				select {
+				case w.retire <- struct{}{}:
+				case <-w.context.SystemContext.Done():
+					return
+				}
+			}
+		}()
+	}
+
+	logger.Infof("redis streams worker is resized from %d to %d consumers", w.concurrency, concurrency)
+	w.concurrency = concurrency
+
+	return nil
+}
+
+// currentConcurrency returns the number of the consumers
+func (w *streamWorker) currentConcurrency() uint {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+
+	return w.concurrency
+}
+
+// consume reads the messages from the streams and runs the jobs
+func (w *streamWorker) consume() {
+	defer func() {
+		w.context.WG.Done()
+	}()
+
+	for {
+		// Process the reclaimed messages first
+		select {
+		case m := <-w.reclaimed:
+			w.process(m)
+			continue
+		case <-w.retire:
+			return
+		case <-w.context.SystemContext.Done():
+			return
+		default:
+		}
+
+		msgs, err := w.read()
+		if err != nil {
+			logger.Errorf("read job streams error: %s", err)
+			if strings.HasPrefix(err.Error(), "NOGROUP") {
+				if er := w.createGroups(); er != nil {
+					logger.Errorf("create consumer groups error: %s", er)
+				}
+			}
+
+			select {
+			case <-time.After(shortLoopInterval):
+			case <-w.context.SystemContext.Done():
+				return
+			}
+			continue
+		}
+
+		for _, m := range msgs {
+			w.process(m)
+		}
+	}
+}
+
+func (w *streamWorker) read() ([]*message, error) {
+	names := w.jobNames()
+	if len(names) == 0 {
+		// Nothing to read
+		time.Sleep(readBlockTime)
+		return nil, nil
+	}
+
+	streams := make([]string, 0, len(names))
+	for _, name := range names {
+		streams = append(streams, rds.KeyStream(w.namespace, name))
+	}
+
+	conn := w.redisPool.Get()
+	defer func() {
+		_ = conn.Close()
+	}()
+
+	return readGroup(conn, w.nodeID, streams, 1, readBlockTime)
+}
+
+// process runs the job of the message and acknowledges it
+func (w *streamWorker) process(m *message) {
+	if m.job == nil {
+		logger.Errorf("malformed message %s in stream %s is discarded", m.id, m.stream)
+		w.ack(m)
+		return
+	}
+
+	v, ok := w.jobs.Load(m.job.Name)
+	if !ok {
+		logger.Errorf("job %s of message %s is not registered, discard it", m.job.Name, m.id)
+		w.ack(m)
+		return
+	}
+	rj := v.(*base.RegisteredJob)
+
+	if len(m.reclaimedFrom) > 0 {
+		w.recover(m)
+	}
+
+	// Keep the message alive when running the job
+	done := make(chan bool)
+	go w.keepalive(m, done)
+
+	err := rj.Runner.Run(m.job)
+	close(done)
+
+	if err != nil {
+		w.incr(m.job.Name, "failed")
+		w.retry(rj, m.job, err)
+	} else {
+		w.incr(m.job.Name, "processed")
+	}
+
+	w.ack(m)
+}
+
+// recover resets the job reclaimed from the dead consumer
+func (w *streamWorker) recover(m *message) {
+	jID := m.job.ID
+	if epoch, ok := m.job.Args[period.PeriodicExecutionMark]; ok {
+		jID = fmt.Sprintf("%s@%s", jID, epoch)
+	}
+
+	t, err := w.ctl.Track(jID)
+	if err != nil {
+		// Let the runner handle it
+		return
+	}
+
+	conn := w.redisPool.Get()
+	defer func() {
+		_ = conn.Close()
+	}()
+
+	// The job is not running on the dead node any more
+	if _, err := conn.Do("SREM", rds.KeyNodeRunningJobs(w.namespace, m.reclaimedFrom), jID); err != nil {
+		logger.Errorf("detach reclaimed job %s from node %s error: %s", jID, m.reclaimedFrom, err)
+	}
+
+	if job.Status(t.Job().Info.Status) == job.RunningStatus {
+		if err := t.Reset(); err != nil {
+			logger.Errorf("reset reclaimed job %s error: %s", jID, err)
+			return
+		}
+	}
+
+	logger.Infof("Job %s:%s is reclaimed from %s", m.job.Name, jID, m.reclaimedFrom)
+}
+
+// retry puts the failed job to the scheduled zset with backoff if the retry is allowed
+func (w *streamWorker) retry(rj *base.RegisteredJob, j *work.Job, err error) {
+	retryAt, ok := rj.Fail(j, err)
+	if !ok {
+		return
+	}
+
+	rawJSON, er := utils.SerializeJob(j)
+	if er != nil {
+		logger.Errorf("serialize job %s:%s for retrying error: %s", j.Name, j.ID, er)
+		return
+	}
+
+	conn := w.redisPool.Get()
+	defer func() {
+		_ = conn.Close()
+	}()
+
+	if _, er := conn.Do("ZADD", rds.RedisKeyScheduled(w.namespace), retryAt, rawJSON); er != nil {
+		logger.Errorf("put job %s:%s back for retrying error: %s", j.Name, j.ID, er)
+	}
+}
+
+func (w *streamWorker) keepalive(m *message, done <-chan bool) {
+	ticker := time.NewTicker(keepaliveInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			conn := w.redisPool.Get()
+			if err := touch(conn, m.stream, w.nodeID, m.id); err != nil {
+				logger.Errorf("touch message %s in stream %s error: %s", m.id, m.stream, err)
+			}
+			_ = conn.Close()
+		case <-done:
+			return
+		}
+	}
+}
+
+func (w *streamWorker) ack(m *message) {
+	conn := w.redisPool.Get()
+	defer func() {
+		_ = conn.Close()
+	}()
+
+	if err := ack(conn, m.stream, m.id); err != nil {
+		logger.Errorf("ack message %s in stream %s error: %s", m.id, m.stream, err)
+	}
+}
+
+// loopSchedule moves the due scheduled jobs to the streams
+func (w *streamWorker) loopSchedule() {
+	defer func() {
+		logger.Info("Stream worker schedule loop is stopped")
+		w.context.WG.Done()
+	}()
+
+	ticker := time.NewTicker(scheduleInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			if err := w.moveScheduled(); err != nil {
+				logger.Errorf("move scheduled jobs to streams error: %s", err)
+			}
+		case <-w.context.SystemContext.Done():
+			return
+		}
+	}
+}
+
+// moveScheduled moves the due jobs from the scheduled zset to their streams one by one,
+// so the keys of each move are declared to the script as required by the redis cluster.
+func (w *streamWorker) moveScheduled() error {
+	conn := w.redisPool.Get()
+	defer func() {
+		_ = conn.Close()
+	}()
+
+	scheduled := rds.RedisKeyScheduled(w.namespace)
+	for {
+		now := time.Now().Unix()
+		entries, err := redis.ByteSlices(conn.Do("ZRANGEBYSCORE", scheduled, "-inf", now, "LIMIT", 0, scheduleBatchSize))
+		if err != nil {
+			return err
+		}
+
+		for _, raw := range entries {
+			j, err := utils.DeSerializeJob(raw)
+			if err != nil || utils.IsEmptyStr(j.Name) {
+				// Keep it in the dead zset for troubleshooting instead of blocking the due jobs
+				logger.Errorf("malformed scheduled job is moved to the dead jobs: %s", raw)
+				if _, err := deadLetterScript.Do(conn, scheduled, rds.RedisKeyDead(w.namespace), raw, now); err != nil {
+					return err
+				}
+				continue
+			}
+
+			if _, err := moveScheduledScript.Do(conn, scheduled, rds.KeyStream(w.namespace, j.Name), raw); err != nil {
+				return err
+			}
+		}
+
+		if len(entries) < scheduleBatchSize {
+			return nil
+		}
+	}
+}
+
+// loopReclaim reports the heartbeat and reclaims the messages of the dead consumers
+func (w *streamWorker) loopReclaim() {
+	defer func() {
+		w.unregister()
+		logger.Info("Stream worker reclaim loop is stopped")
+		w.context.WG.Done()
+	}()
+
+	w.heartbeat()
+
+	ticker := time.NewTicker(reclaimInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			w.heartbeat()
+			w.reclaim()
+		case <-w.context.SystemContext.Done():
+			return
+		}
+	}
+}
+
+func (w *streamWorker) reclaim() {
+	conn := w.redisPool.Get()
+	defer func() {
+		_ = conn.Close()
+	}()
+
+	for _, name := range w.jobNames() {
+		stream := rds.KeyStream(w.namespace, name)
+		entries, err := pending(conn, stream, pendingBatchSize)
+		if err != nil {
+			logger.Errorf("get pending messages of stream %s error: %s", stream, err)
+			continue
+		}
+
+		for _, e := range entries {
+			// Only take what can be processed soon
+			if len(w.reclaimed) >= cap(w.reclaimed) {
+				return
+			}
+
+			if e.idle < reclaimIdleTime {
+				continue
+			}
+
+			msgs, err := claim(conn, stream, w.nodeID, reclaimIdleTime, e.id)
+			if err != nil {
+				logger.Errorf("claim message %s of stream %s error: %s", e.id, stream, err)
+				continue
+			}
+
+			for _, m := range msgs {
+				m.reclaimedFrom = e.consumer
+				w.incr(name, "reclaimed")
+				w.reclaimed <- m
+			}
+		}
+	}
+}
+
+func (w *streamWorker) heartbeat() {
+	hb := &poolHeartbeat{
+		StartedAt:   w.startedAt,
+		HeartbeatAt: time.Now().Unix(),
+		JobNames:    w.jobNames(),
+		Concurrency: w.currentConcurrency(),
+	}
+	rawJSON, err := json.Marshal(hb)
+	if err != nil {
+		logger.Errorf("marshal heartbeat of stream worker error: %s", err)
+		return
+	}
+
+	conn := w.redisPool.Get()
+	defer func() {
+		_ = conn.Close()
+	}()
+
+	if _, err := conn.Do("HSET", rds.KeyStreamWorkerPools(w.namespace), w.nodeID, rawJSON); err != nil {
+		logger.Errorf("report heartbeat of stream worker error: %s", err)
+	}
+}
+
+func (w *streamWorker) unregister() {
+	conn := w.redisPool.Get()
+	defer func() {
+		_ = conn.Close()
+	}()
+
+	if _, err := conn.Do("HDEL", rds.KeyStreamWorkerPools(w.namespace), w.nodeID); err != nil {
+		logger.Errorf("unregister stream worker error: %s", err)
+	}
+}
+
+func (w *streamWorker) streamStats(conn redis.Conn, jobName string) (*worker.StreamStats, error) {
+	stream := rds.KeyStream(w.namespace, jobName)
+	ss := &worker.StreamStats{
+		JobName: jobName,
+	}
+
+	length, err := redis.Int64(conn.Do("XLEN", stream))
+	if err != nil {
+		return nil, err
+	}
+	ss.Length = length
+
+	groups, err := redis.Values(conn.Do("XINFO", "GROUPS", stream))
+	if err != nil {
+		return nil, err
+	}
+	for _, g := range groups {
+		fields, err := redis.Values(g, nil)
+		if err != nil {
+			return nil, err
+		}
+
+		info := make(map[string]interface{}, len(fields)/2)
+		for i := 0; i+1 < len(fields); i += 2 {
+			k, _ := redis.String(fields[i], nil)
+			info[k] = fields[i+1]
+		}
+
+		if name, _ := redis.String(info["name"], nil); name != consumerGroup {
+			continue
+		}
+		ss.Pending, _ = redis.Int64(info["pending"], nil)
+		ss.Consumers, _ = redis.Int64(info["consumers"], nil)
+	}
+
+	metrics, err := redis.Int64Map(conn.Do("HGETALL", rds.KeyStreamMetrics(w.namespace, jobName)))
+	if err != nil {
+		return nil, err
+	}
+	ss.Processed = metrics["processed"]
+	ss.Failed = metrics["failed"]
+	ss.Reclaimed = metrics["reclaimed"]
+
+	return ss, nil
+}
+
+func (w *streamWorker) incr(jobName string, metric string) {
+	conn := w.redisPool.Get()
+	defer func() {
+		_ = conn.Close()
+	}()
+
+	if _, err := conn.Do("HINCRBY", rds.KeyStreamMetrics(w.namespace, jobName), metric, 1); err != nil {
+		logger.Errorf("increase metric %s of stream %s error: %s", metric, jobName, err)
+	}
+}
+
+func (w *streamWorker) createGroups() error {
+	for _, name := range w.jobNames() {
+		if err := w.createGroup(name); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// createGroup creates the consumer group of the job stream
+func (w *streamWorker) createGroup(jobName string) error {
+	conn := w.redisPool.Get()
+	defer func() {
+		_ = conn.Close()
+	}()
+
+	if err := createGroup(conn, rds.KeyStream(w.namespace, jobName)); err != nil {
+		return errors.Wrap(err, fmt.Sprintf("create consumer group of job %s", jobName))
+	}
+
+	return nil
+}
+
+// jobNames returns the sorted names of the registered jobs
+func (w *streamWorker) jobNames() []string {
+	names := make([]string, 0)
+	w.jobs.Range(func(k, v interface{}) bool {
+		names = append(names, k.(string))
+		return true
+	})
+	sort.Strings(names)
+
+	return names
+}
+
+// registerJob is used to register the job to the worker.
+// j is the type of the job
+func (w *streamWorker) registerJob(name string, j interface{}) (err error) {
+	if err := base.CheckRegistration(w.knownJobs, name, j); err != nil {
+		return err
+	}
+
+	w.jobs.Store(name, base.NewRegisteredJob(j, w.context, w.ctl))
+	// The consumer group is created by the Start for the jobs registered before starting.
+	// The started flag is checked after storing the job, so the job is covered by either of them.
+	if atomic.LoadInt32(&w.started) == 1 {
+		if err := w.createGroup(name); err != nil {
+			w.jobs.Delete(name)
+			return err
+		}
+	}
+	// Keep the name of registered jobs as known jobs for future validation
+	w.knownJobs.Store(name, j)
+	logger.Infof("Register job %s with name %s", reflect.TypeOf(j).String(), name)
+
+	return nil
+}
@@ -0,0 +1,205 @@
+package sworker
+
+import (
+	"context"
+	"github.com/alicebob/miniredis/v2"
+	"github.com/chenxull/goGridhub/gridhub/src/jobservice/common/rds"
+	"github.com/chenxull/goGridhub/gridhub/src/jobservice/common/utils"
+	"github.com/chenxull/goGridhub/gridhub/src/jobservice/env"
+	"github.com/chenxull/goGridhub/gridhub/src/jobservice/job"
+	"github.com/chenxull/goGridhub/gridhub/src/jobservice/lcm"
+	"github.com/gomodule/redigo/redis"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+const testNamespace = "{sworker_test}"
+
+// runs counts the runs of the testJob
+var runs int32
+
+type testJob struct{}
+
+func (tj *testJob) MaxFalis() uint { return 0 }
+
+func (tj *testJob) ShouldRetry() bool { return false }
+
+func (tj *testJob) Validate(params job.Parameters) error { return nil }
+
+func (tj *testJob) Run(ctx job.Context, params job.Parameters) error {
+	atomic.AddInt32(&runs, 1)
+	return nil
+}
+
+// newTestWorker returns the worker with the testJob registered, it's not started
+// so the consumers and the loops are driven by the test.
+func newTestWorker(t *testing.T) (*streamWorker, *redis.Pool, *miniredis.Miniredis) {
+	t.Helper()
+
+	mr := miniredis.RunT(t)
+	pool := &redis.Pool{
+		Dial: func() (redis.Conn, error) {
+			return redis.Dial("tcp", mr.Addr())
+		},
+	}
+	t.Cleanup(func() {
+		_ = pool.Close()
+	})
+
+	ctx, cancel := context.WithCancel(context.WithValue(context.Background(), utils.NodeID, "node-a"))
+	t.Cleanup(cancel)
+	envCtx := &env.Context{
+		SystemContext: ctx,
+		WG:            new(sync.WaitGroup),
+		ErrorChan:     make(chan error, 1),
+	}
+
+	w := NewWorker(envCtx, testNamespace, 1, pool, lcm.NewController(envCtx, testNamespace, pool, nil)).(*streamWorker)
+	if err := w.RegisterJobs(map[string]interface{}{"demo": (*testJob)(nil)}); err != nil {
+		t.Fatalf("register jobs error: %s", err)
+	}
+	if err := w.createGroups(); err != nil {
+		t.Fatalf("create consumer groups error: %s", err)
+	}
+
+	return w, pool, mr
+}
+
+// enqueue the demo job and save its stats as the controller does
+func enqueue(t *testing.T, w *streamWorker) string {
+	t.Helper()
+
+	res, err := w.Enqueue("demo", job.Parameters{"image": "library/demo"}, nil, "")
+	if err != nil {
+		t.Fatalf("enqueue job error: %s", err)
+	}
+	if _, err := w.ctl.New(res); err != nil {
+		t.Fatalf("save job stats error: %s", err)
+	}
+
+	return res.Info.JobID
+}
+
+func mustStats(t *testing.T, w *streamWorker, pool *redis.Pool) (length, pending int64) {
+	t.Helper()
+
+	conn := pool.Get()
+	defer func() {
+		_ = conn.Close()
+	}()
+
+	ss, err := w.streamStats(conn, "demo")
+	if err != nil {
+		t.Fatalf("get stream stats error: %s", err)
+	}
+
+	return ss.Length, ss.Pending
+}
+
+func mustStatus(t *testing.T, w *streamWorker, jobID string) job.Status {
+	t.Helper()
+
+	tracker, err := w.ctl.Track(jobID)
+	if err != nil {
+		t.Fatalf("track job %s error: %s", jobID, err)
+	}
+
+	return job.Status(tracker.Job().Info.Status)
+}
+
+func TestEnqueueAck(t *testing.T) {
+	w, pool, _ := newTestWorker(t)
+	before := atomic.LoadInt32(&runs)
+
+	jobID := enqueue(t, w)
+	if length, pending := mustStats(t, w, pool); length != 1 || pending != 0 {
+		t.Fatalf("expect 1 queued message but got length %d, pending %d", length, pending)
+	}
+
+	msgs, err := w.read()
+	if err != nil || len(msgs) != 1 || msgs[0].job == nil || msgs[0].job.ID != jobID {
+		t.Fatalf("expect the message of job %s read but got %v, %v", jobID, msgs, err)
+	}
+	if length, pending := mustStats(t, w, pool); length != 1 || pending != 1 {
+		t.Fatalf("expect 1 delivered message but got length %d, pending %d", length, pending)
+	}
+
+	w.process(msgs[0])
+	if atomic.LoadInt32(&runs) != before+1 {
+		t.Error("expect the job run once")
+	}
+	if status := mustStatus(t, w, jobID); status != job.SuccessStatus {
+		t.Errorf("expect the job succeeded but got %s", status)
+	}
+	// Acknowledged and removed
+	if length, pending := mustStats(t, w, pool); length != 0 || pending != 0 {
+		t.Errorf("expect the message acknowledged but got length %d, pending %d", length, pending)
+	}
+}
+
+func TestReclaimDeadConsumer(t *testing.T) {
+	w, pool, mr := newTestWorker(t)
+	now := time.Now()
+	mr.SetTime(now)
+	stream := rds.KeyStream(testNamespace, "demo")
+
+	jobID := enqueue(t, w)
+
+	// The dead node reads the message and crashes when running the job
+	conn := pool.Get()
+	defer func() {
+		_ = conn.Close()
+	}()
+	msgs, err := readGroup(conn, "node-dead", []string{stream}, 1, time.Millisecond)
+	if err != nil || len(msgs) != 1 {
+		t.Fatalf("expect the message read by the dead node but got %v, %v", msgs, err)
+	}
+	tracker, err := w.ctl.Track(jobID)
+	if err != nil {
+		t.Fatalf("track job error: %s", err)
+	}
+	if err := tracker.Run(); err != nil {
+		t.Fatalf("run job error: %s", err)
+	}
+	if _, err := conn.Do("SADD", rds.KeyNodeRunningJobs(testNamespace, "node-dead"), jobID); err != nil {
+		t.Fatalf("attach job to the dead node error: %s", err)
+	}
+
+	// Not idle long enough
+	w.reclaim()
+	if len(w.reclaimed) != 0 {
+		t.Fatal("expect the message not reclaimed before the idle timeout")
+	}
+
+	mr.SetTime(now.Add(reclaimIdleTime + time.Second))
+	w.reclaim()
+	if len(w.reclaimed) != 1 {
+		t.Fatalf("expect the message reclaimed after the idle timeout but got %d", len(w.reclaimed))
+	}
+	m := <-w.reclaimed
+	if m.reclaimedFrom != "node-dead" || m.job == nil || m.job.ID != jobID {
+		t.Fatalf("expect the message of job %s reclaimed from node-dead but got %+v", jobID, m)
+	}
+	entries, err := pending(conn, stream, 10)
+	if err != nil || len(entries) != 1 || entries[0].consumer != "node-a" {
+		t.Fatalf("expect the message claimed by node-a but got %v, %v", entries, err)
+	}
+
+	w.process(m)
+	if status := mustStatus(t, w, jobID); status != job.SuccessStatus {
+		t.Errorf("expect the reclaimed job succeeded but got %s", status)
+	}
+	if running, _ := redis.Int64(conn.Do("SCARD", rds.KeyNodeRunningJobs(testNamespace, "node-dead"))); running != 0 {
+		t.Errorf("expect the job detached from the dead node but got %d running jobs", running)
+	}
+	if length, pending := mustStats(t, w, pool); length != 0 || pending != 0 {
+		t.Errorf("expect the reclaimed message acknowledged but got length %d, pending %d", length, pending)
+	}
+
+	metrics, err := redis.Int64Map(conn.Do("HGETALL", rds.KeyStreamMetrics(testNamespace, "demo")))
+	if err != nil || metrics["reclaimed"] != 1 || metrics["processed"] != 1 {
+		t.Errorf("expect 1 reclaimed and processed message but got %v, %v", metrics, err)
+	}
+}
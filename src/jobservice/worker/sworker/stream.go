@@ -0,0 +1,206 @@
+package sworker
+
+import (
+	"github.com/chenxull/goGridhub/gridhub/src/jobservice/common/utils"
+	"github.com/gocraft/work"
+	"github.com/gomodule/redigo/redis"
+	"github.com/pkg/errors"
+	"strings"
+	"time"
+)
+
+const (
+	// Name of the consumer group shared by all the nodes
+	consumerGroup = "jobservice"
+	// Field of the stream entry keeping the job
+	jobField = "job"
+)
+
+// Move the due job from the scheduled zset to its stream if it's not moved by others.
+// The zset is shared with the periodic enqueuer, so the entries are the serialized work.Job.
+// KEYS[1]: scheduled zset; KEYS[2]: job stream; ARGV[1]: serialized job
+var moveScheduledScript = redis.NewScript(2, `
+if redis.call('ZREM', KEYS[1], ARGV[1]) == 1 then
+  redis.call('XADD', KEYS[2], '*', 'job', ARGV[1])
+  return 1
+end
+return 0
+`)
+
+// Move the malformed entry from the scheduled zset to the dead zset.
+// KEYS[1]: scheduled zset; KEYS[2]: dead zset; ARGV[1]: entry; ARGV[2]: now
+var deadLetterScript = redis.NewScript(2, `
+if redis.call('ZREM', KEYS[1], ARGV[1]) == 1 then
+  redis.call('ZADD', KEYS[2], ARGV[2], ARGV[1])
+  return 1
+end
+return 0
+`)
+
+// message is the job entry read from the stream
+type message struct {
+	stream string
+	id     string
+	job    *work.Job
+	// Not nil if the message is reclaimed from the dead consumer
+	reclaimedFrom string
+}
+
+// pendingEntry is the entry delivered but not acknowledged yet
+type pendingEntry struct {
+	id       string
+	consumer string
+	idle     time.Duration
+}
+
+// createGroup creates the consumer group of the stream if it's not existing
+func createGroup(conn redis.Conn, stream string) error {
+	_, err := conn.Do("XGROUP", "CREATE", stream, consumerGroup, "0", "MKSTREAM")
+	if err != nil && strings.HasPrefix(err.Error(), "BUSYGROUP") {
+		return nil
+	}
+
+	return err
+}
+
+// readGroup reads the new messages from the streams for the consumer
+func readGroup(conn redis.Conn, consumer string, streams []string, count int, block time.Duration) ([]*message, error) {
+	args := []interface{}{
+		"GROUP", consumerGroup, consumer,
+		"COUNT", count,
+		"BLOCK", int64(block / time.Millisecond),
+		"STREAMS",
+	}
+	for _, s := range streams {
+		args = append(args, s)
+	}
+	for range streams {
+		args = append(args, ">")
+	}
+
+	replies, err := redis.Values(conn.Do("XREADGROUP", args...))
+	if err != nil {
+		if err == redis.ErrNil {
+			// Timeout
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	msgs := make([]*message, 0)
+	for _, r := range replies {
+		sr, err := redis.Values(r, nil)
+		if err != nil || len(sr) != 2 {
+			return nil, errors.New("malformed reply of XREADGROUP")
+		}
+
+		stream, err := redis.String(sr[0], nil)
+		if err != nil {
+			return nil, err
+		}
+
+		entries, err := parseEntries(stream, sr[1])
+		if err != nil {
+			return nil, err
+		}
+		msgs = append(msgs, entries...)
+	}
+
+	return msgs, nil
+}
+
+// pending returns the pending entries of the stream
+func pending(conn redis.Conn, stream string, count int) ([]*pendingEntry, error) {
+	replies, err := redis.Values(conn.Do("XPENDING", stream, consumerGroup, "-", "+", count))
+	if err != nil {
+		return nil, err
+	}
+
+	entries := make([]*pendingEntry, 0, len(replies))
+	for _, r := range replies {
+		values, err := redis.Values(r, nil)
+		if err != nil || len(values) < 3 {
+			return nil, errors.New("malformed reply of XPENDING")
+		}
+
+		id, _ := redis.String(values[0], nil)
+		consumer, _ := redis.String(values[1], nil)
+		idle, _ := redis.Int64(values[2], nil)
+		entries = append(entries, &pendingEntry{
+			id:       id,
+			consumer: consumer,
+			idle:     time.Duration(idle) * time.Millisecond,
+		})
+	}
+
+	return entries, nil
+}
+
+// claim takes over the message if it's still idle for the min idle time
+func claim(conn redis.Conn, stream string, consumer string, minIdle time.Duration, id string) ([]*message, error) {
+	reply, err := conn.Do("XCLAIM", stream, consumerGroup, consumer, int64(minIdle/time.Millisecond), id)
+	if err != nil {
+		return nil, err
+	}
+
+	return parseEntries(stream, reply)
+}
+
+// touch resets the idle time of the message being processed to avoid it being reclaimed
+func touch(conn redis.Conn, stream string, consumer string, id string) error {
+	_, err := conn.Do("XCLAIM", stream, consumerGroup, consumer, 0, id, "JUSTID")
+	return err
+}
+
+// ack acknowledges and removes the message
+func ack(conn redis.Conn, stream string, id string) error {
+	if err := conn.Send("MULTI"); err != nil {
+		return err
+	}
+	if err := conn.Send("XACK", stream, consumerGroup, id); err != nil {
+		return err
+	}
+	if err := conn.Send("XDEL", stream, id); err != nil {
+		return err
+	}
+
+	_, err := conn.Do("EXEC")
+	return err
+}
+
+func parseEntries(stream string, reply interface{}) ([]*message, error) {
+	entries, err := redis.Values(reply, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	msgs := make([]*message, 0, len(entries))
+	for _, e := range entries {
+		values, err := redis.Values(e, nil)
+		if err != nil || len(values) != 2 {
+			return nil, errors.New("malformed stream entry")
+		}
+
+		id, err := redis.String(values[0], nil)
+		if err != nil {
+			return nil, err
+		}
+
+		m := &message{
+			stream: stream,
+			id:     id,
+		}
+
+		// The fields may be nil if the entry has been deleted
+		fields, _ := redis.StringMap(values[1], nil)
+		if raw, ok := fields[jobField]; ok {
+			if j, err := utils.DeSerializeJob([]byte(raw)); err == nil {
+				m.job = j
+			}
+		}
+
+		msgs = append(msgs, m)
+	}
+
+	return msgs, nil
+}